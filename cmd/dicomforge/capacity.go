@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// runCapacity implements the "capacity" subcommand: a storage growth
+// projection of a workload profile at a given activity, and optionally a
+// representative day of that workload generated as DICOM files.
+func runCapacity(args []string) error {
+	fs := flag.NewFlagSet("capacity", flag.ContinueOnError)
+	profile := fs.String("profile", "hospital", "Workload profile: a preset (hospital, imaging-center, screening) or weighted modalities (e.g., 'CT:30,MR:20,CR:50')")
+	studiesPerDay := fs.Float64("studies-per-day", 0, "Number of studies per day (required)")
+	days := fs.Int("days", 365, "Length of the projection, in days")
+	growth := fs.Float64("growth", 0, "Yearly growth of the activity, in percent (e.g., 5)")
+	interval := fs.Int("interval", 30, "Days between the lines of the projection")
+	generateDay := fs.String("generate-day", "", "Generate a representative day of the workload into this directory")
+	scale := fs.Float64("scale", 1, "With --generate-day, fraction of the day's studies to generate (e.g., 0.1)")
+	date := fs.String("date", "", "With --generate-day, StudyDate of the generated studies, YYYYMMDD (default: today)")
+	seed := fs.Int64("seed", 0, "Seed for reproducibility (optional)")
+	workers := fs.Int("workers", 0, "Number of parallel workers (default: CPU cores)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	workload, err := dicom.ParseWorkload(*profile)
+	if err != nil {
+		return fmt.Errorf("--profile: %w", err)
+	}
+	if *studiesPerDay <= 0 {
+		return fmt.Errorf("--studies-per-day must be > 0")
+	}
+	if *days <= 0 || *interval <= 0 {
+		return fmt.Errorf("--days and --interval must be > 0")
+	}
+	if *growth <= -100 {
+		return fmt.Errorf("--growth must be > -100")
+	}
+	if *scale <= 0 || *scale > 1 {
+		return fmt.Errorf("--scale must be > 0 and <= 1")
+	}
+	if *date == "" {
+		*date = time.Now().Format("20060102")
+	} else if _, err := time.Parse("20060102", *date); err != nil {
+		return fmt.Errorf("--date must be YYYYMMDD, got %q", *date)
+	}
+
+	report, err := dicom.ProjectCapacity(dicom.CapacityOptions{
+		Workload:      workload,
+		StudiesPerDay: *studiesPerDay,
+		Days:          *days,
+		AnnualGrowth:  *growth / 100,
+		Interval:      *interval,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("dicomforge capacity")
+	fmt.Println("===================")
+	fmt.Printf("Profile: %s, %g studies/day, %+g%%/year over %d days\n\n", *profile, *studiesPerDay, *growth, *days)
+	fmt.Printf("%-9s %8s %12s %13s %11s %11s\n", "Modality", "Share", "Studies/day", "Images/study", "Size/study", "Size/day")
+	for _, m := range report.Modalities {
+		fmt.Printf("%-9s %7.1f%% %12.1f %13d %11s %11s\n", m.Modality, 100*m.StudiesPerDay / *studiesPerDay, m.StudiesPerDay,
+			m.Footprint.ImagesPerStudy, util.FormatSize(m.Footprint.BytesPerStudy()), util.FormatSize(m.BytesPerDay))
+	}
+	fmt.Printf("%-9s %8s %12g %13s %11s %11s\n\n", "Total", "100.0%", *studiesPerDay, "", "", util.FormatSize(report.BytesPerDay))
+
+	fmt.Printf("%-6s %12s %14s %12s\n", "Day", "Studies", "Images", "Storage")
+	for _, p := range report.Points {
+		fmt.Printf("%-6d %12d %14d %12s\n", p.Day, p.Studies, p.Images, util.FormatSize(p.Bytes))
+	}
+	fmt.Println("\nSizes are uncompressed, without replicas or backups.")
+
+	if *generateDay == "" {
+		return nil
+	}
+
+	tags, err := util.ParseTagFlags([]string{"StudyDate=" + *date})
+	if err != nil {
+		return err
+	}
+	studies := max(int(math.Round(*studiesPerDay**scale)), 1)
+	runs, err := dicom.DayOptions(dicom.GeneratorOptions{
+		OutputDir:  *generateDay,
+		Seed:       *seed,
+		Workers:    *workers,
+		CustomTags: tags,
+		Quiet:      true,
+	}, workload, studies)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nGenerating a representative day: %d studies on %s\n", studies, *date)
+	for _, opts := range runs {
+		size := int64(opts.NumStudies) * dicom.Footprint(opts.Modality).BytesPerStudy()
+		fmt.Printf("  %s: %d studies, %d images, about %s -> %s\n", opts.Modality, opts.NumStudies, opts.NumImages, util.FormatSize(size), opts.OutputDir)
+		generatedFiles, err := dicom.GenerateDICOMSeries(opts)
+		if err != nil {
+			return fmt.Errorf("generating %s studies: %w", opts.Modality, err)
+		}
+		if _, err := dicom.OrganizeFiles(opts.OutputDir, generatedFiles, dicom.OrganizeOptions{Fanout: opts.ShardFanout, UIDRoot: opts.UIDRoot, Quiet: true}); err != nil {
+			return fmt.Errorf("creating DICOMDIR: %w", err)
+		}
+	}
+
+	fmt.Println("\n✓ Representative day complete!")
+	fmt.Printf("  Import directories: %s/*\n", *generateDay)
+	return nil
+}
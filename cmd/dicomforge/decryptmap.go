@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mrsinham/dicomforge/internal/dicom"
+)
+
+// runDecryptMap implements the "decrypt-map" subcommand: it prints the
+// original->generated identity map written by --pseudonym-map as CSV.
+func runDecryptMap(args []string) error {
+	fs := flag.NewFlagSet("decrypt-map", flag.ContinueOnError)
+	mapPath := fs.String("map", "", "Encrypted pseudonym map written by --pseudonym-map (required)")
+	keyFile := fs.String("key-file", "", "File holding the key the map was encrypted with (required)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *mapPath == "" || *keyFile == "" {
+		return fmt.Errorf("--map and --key-file are required")
+	}
+
+	key, err := readKeyFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	data, err := dicom.ReadPseudonymMap(*mapPath, key)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// readKeyFile reads a pseudonym map key: the content of the file, without
+// surrounding whitespace.
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return key, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// runDeidChallenge implements the "deid-challenge" subcommand: studies
+// written twice, identified and de-identified, with the ground truth that
+// pairs them, to benchmark de-identification QA tooling.
+func runDeidChallenge(args []string) error {
+	fs := flag.NewFlagSet("deid-challenge", flag.ContinueOnError)
+	numImages := fs.Int("num-images", 0, "Total number of images (required)")
+	totalSize := fs.String("total-size", "", "Total size of the identified files (e.g., '100MB') (required)")
+	outputDir := fs.String("output", "dicom_deid_challenge", "Output directory")
+	seed := fs.Int64("seed", 0, "Seed for reproducibility (optional)")
+	numStudies := fs.Int("num-studies", 1, "Number of studies")
+	numPatients := fs.Int("num-patients", 1, "Number of patients (studies are distributed among patients)")
+	modality := fs.String("modality", "MR", "Imaging modality: MR, CT, CR, DX, US, MG, RF")
+	leakRate := fs.Float64("leak-rate", 0.2, "Fraction of de-identified files that keep one identifier (0-1)")
+	workers := fs.Int("workers", 0, "Number of parallel workers (default: CPU cores)")
+	uidRoot := fs.String("uid-root", "", "Root of the generated and replacement UIDs (default: "+util.DefaultUIDRoot+")")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *numImages <= 0 {
+		return fmt.Errorf("--num-images must be > 0")
+	}
+	if *totalSize == "" {
+		return fmt.Errorf("--total-size is required")
+	}
+	if *leakRate < 0 || *leakRate > 1 {
+		return fmt.Errorf("--leak-rate must be between 0 and 1")
+	}
+
+	modalityUpper := strings.ToUpper(*modality)
+	if !modalities.IsValid(modalityUpper) {
+		return fmt.Errorf("invalid modality %q, valid options: %v", *modality, modalities.AllModalities())
+	}
+
+	opts := dicom.GeneratorOptions{
+		NumImages:   *numImages,
+		TotalSize:   *totalSize,
+		OutputDir:   filepath.Join(*outputDir, dicom.DeidIdentifiedDir),
+		Seed:        *seed,
+		NumStudies:  *numStudies,
+		NumPatients: *numPatients,
+		Workers:     *workers,
+		Modality:    modalities.Modality(modalityUpper),
+		UIDRoot:     *uidRoot,
+	}
+
+	fmt.Println("dicomforge deid-challenge")
+	fmt.Println("=========================")
+
+	generatedFiles, err := dicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		return fmt.Errorf("generating DICOM series: %w", err)
+	}
+	if _, err := dicom.OrganizeFiles(opts.OutputDir, generatedFiles, dicom.OrganizeOptions{Fanout: opts.ShardFanout, UIDRoot: opts.UIDRoot}); err != nil {
+		return fmt.Errorf("creating DICOMDIR: %w", err)
+	}
+
+	answers, err := dicom.BuildDeidChallenge(*outputDir, dicom.DeidChallengeOptions{
+		Seed:     *seed,
+		LeakRate: *leakRate,
+		UIDRoot:  *uidRoot,
+	})
+	if err != nil {
+		return fmt.Errorf("de-identifying: %w", err)
+	}
+
+	leaks := 0
+	for _, f := range answers.Files {
+		if len(f.Leaks) > 0 {
+			leaks++
+		}
+	}
+
+	fmt.Println("\n✓ De-identification challenge complete!")
+	fmt.Printf("  Identified:    %s\n", filepath.Join(*outputDir, dicom.DeidIdentifiedDir))
+	fmt.Printf("  De-identified: %s (%d files, %d with a leak)\n", filepath.Join(*outputDir, dicom.DeidDeidentifiedDir), len(answers.Files), leaks)
+	fmt.Printf("  Answers:       %s\n", filepath.Join(*outputDir, dicom.DeidAnswersFile))
+	return nil
+}
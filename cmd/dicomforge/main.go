@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mrsinham/dicomforge/cmd/dicomforge/wizard"
 	"github.com/mrsinham/dicomforge/internal/dicom"
 	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
 	"github.com/mrsinham/dicomforge/internal/dicom/edgecases"
 	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/dicom/variability"
+	"github.com/mrsinham/dicomforge/internal/objectstore"
 	"github.com/mrsinham/dicomforge/internal/util"
 )
 
@@ -35,21 +44,187 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Check for priors subcommand
+	if len(os.Args) > 1 && os.Args[1] == "priors" {
+		if err := runPriors(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for serve subcommand
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := runServe(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for receive subcommand
+	if len(os.Args) > 1 && os.Args[1] == "receive" {
+		if err := runReceive(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for stow subcommand
+	if len(os.Args) > 1 && os.Args[1] == "stow" {
+		if err := runStow(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for mpps subcommand
+	if len(os.Args) > 1 && os.Args[1] == "mpps" {
+		if err := runMPPS(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for decrypt-map subcommand
+	if len(os.Args) > 1 && os.Args[1] == "decrypt-map" {
+		if err := runDecryptMap(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for deid-challenge subcommand
+	if len(os.Args) > 1 && os.Args[1] == "deid-challenge" {
+		if err := runDeidChallenge(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for reroot subcommand
+	if len(os.Args) > 1 && os.Args[1] == "reroot" {
+		if err := runReroot(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for anonymize subcommand
+	if len(os.Args) > 1 && os.Args[1] == "anonymize" {
+		if err := runAnonymize(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for split subcommand
+	if len(os.Args) > 1 && os.Args[1] == "split" {
+		if err := runSplit(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Check for merge subcommand
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		if err := runMerge(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	// Check for capacity subcommand
+	if len(os.Args) > 1 && os.Args[1] == "capacity" {
+		if err := runCapacity(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Define command-line flags
-	numImages := flag.Int("num-images", 0, "Number of images/slices to generate (required)")
+	numImages := flag.Int("num-images", 0, "Number of images/slices to generate (default: typical count per series for the modality)")
 	totalSize := flag.String("total-size", "", "Total size (e.g., '100MB', '1GB') (required)")
+	fileSize := flag.String("file-size", "", "Size of each image file (e.g., '512KB'), instead of --total-size")
+	exactSize := flag.Bool("exact-size", false, "Pad the instances to --total-size (within 1%) instead of rounding the images down")
 	outputDir := flag.String("output", "dicom_series", "Output directory")
 	seed := flag.Int64("seed", 0, "Seed for reproducibility (optional, auto-generated if not specified)")
 	numStudies := flag.Int("num-studies", 1, "Number of studies to generate")
+	studiesRange := flag.String("studies-range", "", "Random number of studies (e.g., '10-20'), instead of --num-studies")
+	studiesPerPatient := flag.String("studies-per-patient", "", "Random number of studies per patient (e.g., '1-4'), instead of --num-studies")
 	studyDescriptions := flag.String("study-descriptions", "", "Comma-separated study descriptions (must match --num-studies count)")
 	numPatients := flag.Int("num-patients", 1, "Number of patients (studies are distributed among patients)")
+	fromCSV := flag.String("from-csv", "", "CSV file with one study per row (PatientID, PatientName, AccessionNumber, StudyDate, ...)")
+	pseudonymMap := flag.String("pseudonym-map", "", "With --from-csv, replace the CSV identities with generated ones and write the encrypted original->generated map to this file")
+	pseudonymKeyFile := flag.String("pseudonym-key-file", "", "File holding the key that encrypts the --pseudonym-map")
+	deidentified := flag.Bool("deidentified", false, "Write the instances already de-identified with the PS3.15 Basic Profile: pseudonyms, dummy dates, PatientIdentityRemoved")
 	workers := flag.Int("workers", 0, fmt.Sprintf("Number of parallel workers (default: %d = CPU cores)", runtime.NumCPU()))
 
 	// Modality selection
-	modality := flag.String("modality", "MR", "Imaging modality: MR, CT, CR, DX, US, MG (default: MR)")
+	modality := flag.String("modality", "MR", "Imaging modality: MR, CT, CR, DX, US, MG, RF (default: MR)")
+	phantom := flag.Bool("phantom", false, "Render a Shepp-Logan head phantom, sliced along each series, instead of noise")
+	pattern := flag.String("pattern", "", "Render a test pattern instead of noise: gradient, checkerboard, bars or smpte")
+	annotations := flag.Bool("annotations", false, "Burn series description and number, study date, instance number and slice location into the image corners")
+	qrCode := flag.Bool("qr-code", false, "Burn a QR code of the SOPInstanceUID into the bottom right corner of each image")
+	cineFrames := flag.Int("cine-frames", 0, "Frames per US instance: multi-frame cine loops (requires --modality US)")
+	frameTimeVector := flag.Bool("frame-time-vector", false, "With --cine-frames, give each frame its own interval (FrameTimeVector)")
+	jpegQualitySweep := flag.String("jpeg-quality-sweep", "", "Encode every series as JPEG Baseline once per quality (e.g., '50,75,90'), each in its own series")
+	usColor := flag.String("us-color", "", "Color US images with a color Doppler box: RGB, YBR_FULL_422 or PALETTE_COLOR (requires --modality US)")
+	colorByPlane := flag.Bool("color-by-plane", false, "With --us-color RGB, store the color samples plane by plane (PlanarConfiguration 1)")
+	photometric := flag.String("photometric", "", "Force the photometric interpretation of images: MONOCHROME1, MONOCHROME2, or MIXED to alternate per series")
+	transferSyntax := flag.String("transfer-syntax", "", "Transfer syntax of the images: implicit-le, explicit-le (default) or jpeg-baseline, by name or UID")
+	jpegQuality := flag.Int("jpeg-quality", dicom.DefaultJPEGQuality, "Quality of JPEG Baseline images (1-100)")
+	transferSyntaxMix := flag.String("transfer-syntax-mix", "", "Mix transfer syntaxes across instances by weight (e.g., 'jpeg-baseline:30,explicit-le:70'), instead of --transfer-syntax")
 
 	// Multi-series support
 	seriesPerStudy := flag.String("series-per-study", "1", "Number of series per study (e.g., '3' or '2-5' for random range)")
+	imagesPerSeries := flag.String("images-per-series", "", "Random number of images per series (e.g., '20-40'), instead of splitting --num-images")
+	localizer := flag.Bool("localizer", false, "Start each MR/CT study with a localizer series (MR 3-plane, CT topogram) the other series reference")
 
 	// Categorization options
 	institution := flag.String("institution", "", "Institution name (random if not specified)")
@@ -57,6 +232,17 @@ func main() {
 	bodyPart := flag.String("body-part", "", "Body part examined (random per modality if not specified)")
 	priority := flag.String("priority", "ROUTINE", "Exam priority: HIGH, ROUTINE, LOW")
 	variedMetadata := flag.Bool("varied-metadata", false, "Generate varied institutions/physicians across studies")
+	fractionalSeconds := flag.Bool("fractional-seconds", false, "Write times with microseconds (HHMMSS.FFFFFF)")
+	charset := flag.String("charset", "", "SpecificCharacterSet of the files: latin1 (ISO_IR 100) or utf8 (ISO_IR 192); default: ISO_IR 192 for non-ASCII text only")
+	uidRoot := flag.String("uid-root", "", "Root of the generated UIDs, e.g. an organization's '1.2.826.0.1.3680043.10.1234' (default: "+util.DefaultUIDRoot+")")
+	groupLengths := flag.Bool("group-lengths", false, "Write a retired group length element (gggg,0000) before each group")
+	risIDs := flag.Bool("ris-ids", false, "Add RIS/EMR linkage IDs per study: AdmissionID (visit), placer and filler order numbers")
+
+	// Cohort demographics options
+	sexRatio := flag.String("sex-ratio", "", "Fraction of male patients, e.g. 0.48 or 48% (enables cohort distributions)")
+	agePyramid := flag.String("age-pyramid", "", "Age distribution: hospital, pediatric, uniform or bands like '0-17:10,18-64:50,65-99:40'")
+	nameLocales := flag.String("name-locales", "", "Name locale weights (en, fr, ja, ko, zh), e.g. 'en:80,fr:20'")
+	ageProfileFlag := flag.String("age-profile", "", "Patient age group: neonate, pediatric, adult or geriatric (ages, weight, size, protocols)")
 
 	// Custom tag options
 	var tagFlags []string
@@ -64,26 +250,93 @@ func main() {
 		tagFlags = append(tagFlags, s)
 		return nil
 	})
+	var privateTagFlags []string
+	flag.Func("private-tag", "Add a private tag to every instance: 'CREATOR,GGGG,EE,VR=Value' (repeatable)", func(s string) error {
+		privateTagFlags = append(privateTagFlags, s)
+		return nil
+	})
+	siemensCSA := flag.Bool("siemens-csa", false, "Write Siemens CSA Image/Series headers (0029,1010/1020) on MR images of Siemens scanners")
+	vendorTags := flag.String("vendor-tags", "", "Write vendor private tags consistent with the scanner: ge,philips (or 'all')")
 
 	// Edge case options
 	edgeCasePercentage := flag.Int("edge-cases", 0, "Percentage of patients with edge case variations (0-100)")
 	edgeCaseTypes := flag.String("edge-case-types", "special-chars,long-names,missing-tags,old-dates,varied-ids",
-		"Comma-separated edge case types to enable")
+		"Comma-separated edge case types to enable (escaping is opt-in)")
+
+	// Variability options
+	variabilityPercentage := flag.Int("variability", 0, "Percentage of studies with a randomized style: description casing, missing optional tags, DS precision (0-100)")
+	numericJitter := flag.Int("numeric-jitter", 0, "Percentage by which dose and exposure values vary from instance to instance within a series (0-50)")
 
 	// Corruption options
-	corruptTypes := flag.String("corrupt", "", "Inject vendor-specific corruption: siemens-csa,ge-private,philips-private,malformed-lengths (or 'all')")
+	corruptTypes := flag.String("corrupt", "", "Inject vendor-specific corruption: siemens-csa,ge-private,philips-private,malformed-lengths,sop-class-mismatch,empty-required,odd-lengths,invalid-uids,legacy-groups,shared-series-uid,mixed-patient-study,charset-mismatch,truncated (or 'all', all but truncated)")
+	truncateAt := flag.String("truncate-at", "", "Cut point of --corrupt truncated: mid-header, mid-pixel-data (default), a byte offset or a percentage (e.g., 50%)")
+
+	// Derived object options
+	usMeasurementSR := flag.Bool("us-sr", false, "Add a measurement SR (fetal biometry or organ lengths) per US study")
+	textSR := flag.Bool("text-sr", false, "Add a Basic Text SR report per study referencing its images")
+	rtDose := flag.Bool("rt-dose", false, "Add an RT Dose grid per CT study, aligned to its images")
+	rtPlan := flag.Bool("rt-plan", false, "Add an RT Plan and the RT Structure Set of its target per CT study")
+	gsps := flag.Bool("gsps", false, "Add a grayscale presentation state (window, zoom, annotations) per study")
+	doseSR := flag.Bool("dose-sr", false, "Add an X-Ray Radiation Dose SR (CTDIvol, DLP per acquisition) per CT study")
+	segSegments := flag.Int("seg", 0, "Add a binary segmentation (SEG) with this many segments per CT/MR study")
+	aiResults := flag.String("ai-results", "", "Insert lesions and add AI result objects: sr,sc (or 'all')")
+
+	// Export options
+	jsonExport := flag.Bool("json", false, "Also export each instance as DICOM JSON (PS3.18) into <output>/JSON/")
+	csvIndex := flag.Bool("csv-index", false, "Also write a CSV index of the instances, one row per instance with its key tags, into <output>/index.csv")
+	worklist := flag.Bool("worklist", false, "Also export a Modality Worklist item per study into <output>/WORKLIST/")
+	mpps := flag.Bool("mpps", false, "Also export the MPPS N-CREATE/N-SET messages of each study into <output>/MPPS/")
+	hl7 := flag.Bool("hl7", false, "Also export the HL7 v2 ORM order and ORU report of each study into <output>/HL7/")
+	zipOutput := flag.Bool("zip", false, "Also archive the DICOMDIR and the PT*/ST*/SE* hierarchy into <output>.zip")
+	tarOutput := flag.Bool("tar", false, "Also archive the DICOMDIR and the PT*/ST*/SE* hierarchy into <output>.tar")
+	tarGzOutput := flag.Bool("tar-gz", false, "Also archive the DICOMDIR and the PT*/ST*/SE* hierarchy into <output>.tar.gz")
+	worklistAE := flag.String("worklist-ae", dicom.DefaultWorklistStationAE, "Station AE Title of the worklist items and MPPS messages")
+	stream := flag.Bool("stream", false, "Write the instances to stdout as a multipart/related stream instead of a directory")
+	streamBoundary := flag.String("stream-boundary", "", "Boundary of the --stream parts (default: random)")
+	upload := flag.String("upload", "", "Upload the instances to object storage (s3://bucket/prefix or gs://bucket/prefix) instead of a directory")
+	uploadConcurrency := flag.Int("upload-concurrency", dicom.DefaultUploadConcurrency, "Number of objects uploaded at a time with --upload")
+	shardFanout := flag.Int("shard-fanout", dicom.DefaultShardFanout, "Largest number of entries per output directory, beyond which they are sharded into SH* subdirectories")
 
 	// Interactive wizard and config options
 	interactive := flag.Bool("interactive", false, "Launch interactive wizard")
 	flag.BoolVar(interactive, "i", false, "Launch interactive wizard (shortcut)")
-	configFile := flag.String("config", "", "Load configuration from YAML file")
-	saveConfig := flag.String("save-config", "", "Save configuration to YAML file (after generation)")
+	configFile := flag.String("config", "", "Load configuration from YAML or JSON (.json) file")
+	saveConfig := flag.String("save-config", "", "Save configuration to YAML or JSON (.json) file (after generation)")
 
 	help := flag.Bool("help", false, "Show help message")
 	showVersion := flag.Bool("version", false, "Show version")
 
 	flag.Parse()
 
+	// Streaming and uploading write nothing into the output directory
+	for _, mode := range []string{"stream", "upload"} {
+		if !isFlagSet(mode) {
+			continue
+		}
+		for _, name := range []string{"stream", "json", "csv-index", "worklist", "mpps", "hl7", "zip", "tar", "tar-gz", "config", "save-config", "interactive", "i"} {
+			if name != mode && isFlagSet(name) {
+				fmt.Fprintf(os.Stderr, "Error: --%s cannot be combined with --%s (nothing is written to disk)\n", mode, name)
+				os.Exit(1)
+			}
+		}
+	}
+	if isFlagSet("upload-concurrency") && *upload == "" {
+		fmt.Fprintf(os.Stderr, "Error: --upload-concurrency requires --upload\n")
+		os.Exit(1)
+	}
+
+	// Streaming: stdout carries the instances, everything else goes to stderr
+	streamOut := os.Stdout
+	if *stream {
+		os.Stdout = os.Stderr
+		// A reader that goes away fails the write, so the staged files
+		// are removed, instead of killing the process
+		signal.Ignore(syscall.SIGPIPE)
+	} else if *streamBoundary != "" {
+		fmt.Fprintf(os.Stderr, "Error: --stream-boundary requires --stream\n")
+		os.Exit(1)
+	}
+
 	// Handle interactive mode
 	if *interactive {
 		if err := wizard.Run(""); err != nil {
@@ -106,6 +359,13 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error converting config: %v\n", err)
 			os.Exit(1)
 		}
+		if isFlagSet("shard-fanout") {
+			if *shardFanout < 2 {
+				fmt.Fprintf(os.Stderr, "Error: --shard-fanout must be >= 2\n")
+				os.Exit(1)
+			}
+			opts.ShardFanout = *shardFanout
+		}
 
 		fmt.Println("dicomforge")
 		fmt.Println("==========")
@@ -117,7 +377,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err := dicom.OrganizeFilesIntoDICOMDIR(opts.OutputDir, generatedFiles, false); err != nil {
+		if _, err := dicom.OrganizeFiles(opts.OutputDir, generatedFiles, dicom.OrganizeOptions{Fanout: opts.ShardFanout, UIDRoot: opts.UIDRoot}); err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating DICOMDIR: %v\n", err)
 			os.Exit(1)
 		}
@@ -140,17 +400,21 @@ func main() {
 	}
 
 	// Validate required arguments
-	if *numImages <= 0 {
-		fmt.Fprintf(os.Stderr, "Error: --num-images must be > 0\n")
+	if *numImages < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --num-images must be > 0 (omit it for typical counts per series)\n")
 		printUsage()
 		os.Exit(1)
 	}
 
-	if *totalSize == "" {
-		fmt.Fprintf(os.Stderr, "Error: --total-size is required\n")
+	if *totalSize == "" && *fileSize == "" {
+		fmt.Fprintf(os.Stderr, "Error: --total-size (or --file-size) is required\n")
 		printUsage()
 		os.Exit(1)
 	}
+	if *totalSize != "" && *fileSize != "" {
+		fmt.Fprintf(os.Stderr, "Error: --total-size cannot be combined with --file-size\n")
+		os.Exit(1)
+	}
 
 	if *numStudies <= 0 {
 		fmt.Fprintf(os.Stderr, "Error: --num-studies must be > 0\n")
@@ -158,18 +422,90 @@ func main() {
 		os.Exit(1)
 	}
 
-	if *numStudies > *numImages {
-		fmt.Fprintf(os.Stderr, "Error: --num-studies cannot be greater than --num-images\n")
-		os.Exit(1)
-	}
-
 	if *numPatients <= 0 {
 		fmt.Fprintf(os.Stderr, "Error: --num-patients must be > 0\n")
 		printUsage()
 		os.Exit(1)
 	}
 
-	if *numPatients > *numStudies {
+	// Parse study count ranges; minStudies and maxStudies bound the number
+	// of studies of the run
+	var parsedStudiesRange, parsedStudiesPerPatient util.StudyRange
+	minStudies, maxStudies := *numStudies, *numStudies
+	if *studiesRange != "" && *studiesPerPatient != "" {
+		fmt.Fprintf(os.Stderr, "Error: --studies-range cannot be combined with --studies-per-patient\n")
+		os.Exit(1)
+	}
+	if (*studiesRange != "" || *studiesPerPatient != "") && isFlagSet("num-studies") {
+		fmt.Fprintf(os.Stderr, "Error: --num-studies cannot be combined with --studies-range or --studies-per-patient\n")
+		os.Exit(1)
+	}
+	if *studiesRange != "" {
+		var err error
+		parsedStudiesRange, err = util.ParseStudyRange(*studiesRange)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		minStudies, maxStudies = parsedStudiesRange.Min, parsedStudiesRange.Max
+	}
+	if *studiesPerPatient != "" {
+		var err error
+		parsedStudiesPerPatient, err = util.ParseStudyRange(*studiesPerPatient)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		minStudies, maxStudies = *numPatients*parsedStudiesPerPatient.Min, *numPatients*parsedStudiesPerPatient.Max
+	}
+
+	// Load patients and studies from CSV
+	var csvPatients []dicom.PredefinedPatient
+	if *fromCSV != "" {
+		for _, name := range []string{"num-studies", "num-patients", "studies-range", "studies-per-patient", "study-descriptions"} {
+			if isFlagSet(name) {
+				fmt.Fprintf(os.Stderr, "Error: --from-csv cannot be combined with --%s (studies and patients come from the CSV rows)\n", name)
+				os.Exit(1)
+			}
+		}
+		var err error
+		csvPatients, err = dicom.LoadPatientsCSV(*fromCSV)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		csvStudies := 0
+		for _, p := range csvPatients {
+			csvStudies += len(p.Studies)
+		}
+		minStudies, maxStudies = csvStudies, csvStudies
+		fmt.Printf("CSV: %d studies for %d patients from %s\n", csvStudies, len(csvPatients), *fromCSV)
+	}
+
+	// Pseudonymize CSV identities
+	var pseudonymKey []byte
+	if *pseudonymMap != "" || *pseudonymKeyFile != "" {
+		if *fromCSV == "" {
+			fmt.Fprintf(os.Stderr, "Error: --pseudonym-map requires --from-csv\n")
+			os.Exit(1)
+		}
+		if *pseudonymMap == "" || *pseudonymKeyFile == "" {
+			fmt.Fprintf(os.Stderr, "Error: --pseudonym-map and --pseudonym-key-file must be used together\n")
+			os.Exit(1)
+		}
+		var err error
+		if pseudonymKey, err = readKeyFile(*pseudonymKeyFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *numImages > 0 && maxStudies > *numImages {
+		fmt.Fprintf(os.Stderr, "Error: --num-studies cannot be greater than --num-images\n")
+		os.Exit(1)
+	}
+
+	if *numPatients > minStudies {
 		fmt.Fprintf(os.Stderr, "Error: --num-patients cannot be greater than --num-studies (each patient needs at least one study)\n")
 		os.Exit(1)
 	}
@@ -181,6 +517,182 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *usMeasurementSR && modalityUpper != string(modalities.US) {
+		fmt.Fprintf(os.Stderr, "Error: --us-sr requires --modality US\n")
+		os.Exit(1)
+	}
+	if *rtDose && modalityUpper != string(modalities.CT) {
+		fmt.Fprintf(os.Stderr, "Error: --rt-dose requires --modality CT\n")
+		os.Exit(1)
+	}
+	if *rtPlan && modalityUpper != string(modalities.CT) {
+		fmt.Fprintf(os.Stderr, "Error: --rt-plan requires --modality CT\n")
+		os.Exit(1)
+	}
+	if *doseSR && modalityUpper != string(modalities.CT) {
+		fmt.Fprintf(os.Stderr, "Error: --dose-sr requires --modality CT\n")
+		os.Exit(1)
+	}
+	if *cineFrames < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --cine-frames must be >= 0\n")
+		os.Exit(1)
+	}
+	if *cineFrames > 1 && modalityUpper != string(modalities.US) {
+		fmt.Fprintf(os.Stderr, "Error: --cine-frames requires --modality US\n")
+		os.Exit(1)
+	}
+	if *frameTimeVector && *cineFrames <= 1 {
+		fmt.Fprintf(os.Stderr, "Error: --frame-time-vector requires --cine-frames of 2 or more\n")
+		os.Exit(1)
+	}
+	var parsedJPEGQualitySweep []int
+	if *jpegQualitySweep != "" {
+		var err error
+		parsedJPEGQualitySweep, err = dicom.ParseJPEGQualitySweep(*jpegQualitySweep)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --jpeg-quality-sweep: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	parsedUSColor, err := dicom.ParseUSColor(*usColor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --us-color: %v\n", err)
+		os.Exit(1)
+	}
+	if parsedUSColor != "" && modalityUpper != string(modalities.US) {
+		fmt.Fprintf(os.Stderr, "Error: --us-color requires --modality US\n")
+		os.Exit(1)
+	}
+	if *colorByPlane && parsedUSColor != dicom.PhotometricRGB {
+		fmt.Fprintf(os.Stderr, "Error: --color-by-plane requires --us-color RGB\n")
+		os.Exit(1)
+	}
+	parsedPhotometric, err := dicom.ParsePhotometric(*photometric)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --photometric: %v\n", err)
+		os.Exit(1)
+	}
+	if parsedPhotometric != "" && parsedUSColor != "" {
+		fmt.Fprintf(os.Stderr, "Error: --photometric and --us-color cannot be combined\n")
+		os.Exit(1)
+	}
+	parsedPattern, err := dicom.ParsePattern(*pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --pattern: %v\n", err)
+		os.Exit(1)
+	}
+	if parsedPattern != "" && *phantom {
+		fmt.Fprintf(os.Stderr, "Error: --pattern and --phantom cannot be combined\n")
+		os.Exit(1)
+	}
+	parsedTransferSyntax, err := dicom.ParseTransferSyntax(*transferSyntax)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --transfer-syntax: %v\n", err)
+		os.Exit(1)
+	}
+	characterSet, err := util.ParseCharacterSet(*charset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --charset: %v\n", err)
+		os.Exit(1)
+	}
+	if *uidRoot != "" {
+		if err := util.ValidateUIDRoot(*uidRoot); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --uid-root: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *jpegQuality < 1 || *jpegQuality > 100 {
+		fmt.Fprintf(os.Stderr, "Error: --jpeg-quality must be between 1 and 100\n")
+		os.Exit(1)
+	}
+	if parsedJPEGQualitySweep != nil {
+		if *transferSyntax == "" {
+			parsedTransferSyntax = dicom.TransferSyntaxJPEGBaseline
+		}
+		if parsedTransferSyntax != dicom.TransferSyntaxJPEGBaseline {
+			fmt.Fprintf(os.Stderr, "Error: --jpeg-quality-sweep requires --transfer-syntax jpeg-baseline\n")
+			os.Exit(1)
+		}
+		if isFlagSet("jpeg-quality") {
+			fmt.Fprintf(os.Stderr, "Error: --jpeg-quality and --jpeg-quality-sweep cannot be combined\n")
+			os.Exit(1)
+		}
+		if *transferSyntaxMix != "" {
+			fmt.Fprintf(os.Stderr, "Error: --jpeg-quality-sweep and --transfer-syntax-mix cannot be combined\n")
+			os.Exit(1)
+		}
+		if *exactSize {
+			fmt.Fprintf(os.Stderr, "Error: --jpeg-quality-sweep cannot be combined with --exact-size\n")
+			os.Exit(1)
+		}
+	}
+	var parsedTransferSyntaxMix []dicom.TransferSyntaxShare
+	usedTransferSyntaxes := []string{parsedTransferSyntax}
+	if *transferSyntaxMix != "" {
+		if *transferSyntax != "" {
+			fmt.Fprintf(os.Stderr, "Error: --transfer-syntax and --transfer-syntax-mix cannot be combined\n")
+			os.Exit(1)
+		}
+		parsedTransferSyntaxMix, err = dicom.ParseTransferSyntaxMix(*transferSyntaxMix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --transfer-syntax-mix: %v\n", err)
+			os.Exit(1)
+		}
+		usedTransferSyntaxes = usedTransferSyntaxes[:0]
+		for _, share := range parsedTransferSyntaxMix {
+			if share.Weight > 0 {
+				usedTransferSyntaxes = append(usedTransferSyntaxes, share.UID)
+			}
+		}
+	}
+	if slices.Contains(usedTransferSyntaxes, dicom.TransferSyntaxJPEGBaseline) && (parsedUSColor == dicom.PhotometricPaletteColor || *colorByPlane) {
+		fmt.Fprintf(os.Stderr, "Error: --transfer-syntax jpeg-baseline cannot encode --us-color PALETTE_COLOR or --color-by-plane\n")
+		os.Exit(1)
+	}
+	if *segSegments < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --seg must be >= 0\n")
+		os.Exit(1)
+	}
+	if *segSegments > 0 && modalityUpper != string(modalities.CT) && modalityUpper != string(modalities.MR) {
+		fmt.Fprintf(os.Stderr, "Error: --seg requires --modality CT or MR\n")
+		os.Exit(1)
+	}
+	if *localizer && modalityUpper != string(modalities.CT) && modalityUpper != string(modalities.MR) {
+		fmt.Fprintf(os.Stderr, "Error: --localizer requires --modality CT or MR\n")
+		os.Exit(1)
+	}
+	if *siemensCSA && modalityUpper != string(modalities.MR) {
+		fmt.Fprintf(os.Stderr, "Error: --siemens-csa requires --modality MR\n")
+		os.Exit(1)
+	}
+	parsedVendorTags, err := dicom.ParseVendorTags(*vendorTags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --vendor-tags: %v\n", err)
+		os.Exit(1)
+	}
+	if *deidentified && (*siemensCSA || len(parsedVendorTags) > 0) {
+		fmt.Fprintf(os.Stderr, "Error: --deidentified cannot be combined with --siemens-csa or --vendor-tags (private tags are removed)\n")
+		os.Exit(1)
+	}
+	if *deidentified && *annotations {
+		fmt.Fprintf(os.Stderr, "Error: --deidentified cannot be combined with --annotations (the study date is burned into the pixels)\n")
+		os.Exit(1)
+	}
+	if slices.Contains(parsedVendorTags, dicom.VendorTagsGE) && modalityUpper != string(modalities.CT) && modalityUpper != string(modalities.MR) {
+		fmt.Fprintf(os.Stderr, "Error: --vendor-tags ge requires --modality CT or MR\n")
+		os.Exit(1)
+	}
+	if slices.Contains(parsedVendorTags, dicom.VendorTagsPhilips) && modalityUpper != string(modalities.MR) {
+		fmt.Fprintf(os.Stderr, "Error: --vendor-tags philips requires --modality MR\n")
+		os.Exit(1)
+	}
+
+	parsedAIResults, err := dicom.ParseAIResultTypes(*aiResults)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Parse and validate study descriptions
 	var parsedStudyDescriptions []string
 	if *studyDescriptions != "" {
@@ -189,6 +701,10 @@ func main() {
 		for i := range parsedStudyDescriptions {
 			parsedStudyDescriptions[i] = strings.TrimSpace(parsedStudyDescriptions[i])
 		}
+		if parsedStudiesRange.IsSet() || parsedStudiesPerPatient.IsSet() {
+			fmt.Fprintf(os.Stderr, "Error: --study-descriptions cannot be combined with --studies-range or --studies-per-patient\n")
+			os.Exit(1)
+		}
 		if len(parsedStudyDescriptions) != *numStudies {
 			fmt.Fprintf(os.Stderr, "Error: --study-descriptions has %d descriptions but --num-studies is %d (must match)\n",
 				len(parsedStudyDescriptions), *numStudies)
@@ -210,6 +726,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Parse images per series
+	var parsedImagesPerSeries util.ImageRange
+	if *imagesPerSeries != "" {
+		if *numImages > 0 {
+			fmt.Fprintf(os.Stderr, "Error: --images-per-series cannot be combined with --num-images\n")
+			os.Exit(1)
+		}
+		parsedImagesPerSeries, err = util.ParseImageRange(*imagesPerSeries)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Parse and validate custom tags
 	parsedTags, err := util.ParseTagFlags(tagFlags)
 	if err != nil {
@@ -222,6 +752,12 @@ func main() {
 		fmt.Printf("Custom tags: %d specified\n", len(parsedTags))
 	}
 
+	privateTags, err := util.ParsePrivateTagFlags(privateTagFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --private-tag: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Parse and validate edge case config
 	var edgeCaseConfig edgecases.Config
 	if *edgeCasePercentage > 0 {
@@ -241,6 +777,60 @@ func main() {
 		fmt.Printf("Edge cases: %d%% of patients with types %v\n", *edgeCasePercentage, types)
 	}
 
+	// Parse cohort demographics: any distribution option enables them,
+	// the others keep their hospital defaults
+	var demographics *util.Demographics
+	if *sexRatio != "" || *agePyramid != "" || *nameLocales != "" {
+		d := util.DefaultDemographics()
+		if *sexRatio != "" {
+			if d.MaleRatio, err = util.ParseSexRatio(*sexRatio); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *agePyramid != "" {
+			if d.AgePyramid, err = util.ParseAgePyramid(*agePyramid); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *nameLocales != "" {
+			if d.NameLocales, err = util.ParseNameLocales(*nameLocales); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		demographics = &d
+	}
+	ageProfile, err := util.ParseAgeProfile(*ageProfileFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if ageProfile != "" && *agePyramid != "" {
+		fmt.Fprintf(os.Stderr, "Error: --age-profile and --age-pyramid cannot be combined\n")
+		os.Exit(1)
+	}
+
+	// Validate variability config
+	variabilityConfig := variability.Config{Percentage: *variabilityPercentage}
+	if err := variabilityConfig.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if variabilityConfig.IsEnabled() {
+		fmt.Printf("Variability: %d%% of studies with a randomized style\n", variabilityConfig.Percentage)
+	}
+	if *numericJitter < 0 || *numericJitter > 50 {
+		fmt.Fprintf(os.Stderr, "Error: --numeric-jitter must be 0-50, got %d\n", *numericJitter)
+		os.Exit(1)
+	}
+
+	if *shardFanout < 2 {
+		fmt.Fprintf(os.Stderr, "Error: --shard-fanout must be >= 2\n")
+		os.Exit(1)
+	}
+
 	// Parse and validate corruption config
 	var corruptionConfig corruption.Config
 	if *corruptTypes != "" {
@@ -249,36 +839,110 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		truncation, err := corruption.ParseTruncation(*truncateAt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --truncate-at: %v\n", err)
+			os.Exit(1)
+		}
 		corruptionConfig = corruption.Config{
-			Types: types,
+			Types:      types,
+			Truncation: truncation,
 		}
 		if err := corruptionConfig.Validate(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		if slices.Contains(usedTransferSyntaxes, dicom.TransferSyntaxImplicitVRLittleEndian) && corruptionConfig.HasType(corruption.MalformedLengths) {
+			fmt.Fprintf(os.Stderr, "Error: --corrupt malformed-lengths cannot be combined with --transfer-syntax implicit-le\n")
+			os.Exit(1)
+		}
+		if *siemensCSA && corruptionConfig.HasType(corruption.SiemensCSA) {
+			fmt.Fprintf(os.Stderr, "Error: --siemens-csa cannot be combined with --corrupt siemens-csa\n")
+			os.Exit(1)
+		}
+		if slices.Contains(parsedVendorTags, dicom.VendorTagsGE) && corruptionConfig.HasType(corruption.GEPrivate) {
+			fmt.Fprintf(os.Stderr, "Error: --vendor-tags ge cannot be combined with --corrupt ge-private\n")
+			os.Exit(1)
+		}
+		if slices.Contains(parsedVendorTags, dicom.VendorTagsPhilips) && corruptionConfig.HasType(corruption.PhilipsPrivate) {
+			fmt.Fprintf(os.Stderr, "Error: --vendor-tags philips cannot be combined with --corrupt philips-private\n")
+			os.Exit(1)
+		}
+		if corruptionConfig.HasType(corruption.Truncated) && *exactSize {
+			fmt.Fprintf(os.Stderr, "Error: --corrupt truncated cannot be combined with --exact-size\n")
+			os.Exit(1)
+		}
 		fmt.Printf("Corruption: injecting %v\n", types)
 	}
+	if *truncateAt != "" && !corruptionConfig.HasType(corruption.Truncated) {
+		fmt.Fprintf(os.Stderr, "Error: --truncate-at requires --corrupt truncated\n")
+		os.Exit(1)
+	}
 
 	// Create generator options
 	opts := dicom.GeneratorOptions{
-		NumImages:         *numImages,
-		TotalSize:         *totalSize,
-		OutputDir:         *outputDir,
-		Seed:              *seed,
-		NumStudies:        *numStudies,
-		NumPatients:       *numPatients,
-		Workers:           *workers,
-		Modality:          modalities.Modality(modalityUpper),
-		SeriesPerStudy:    parsedSeriesPerStudy,
-		StudyDescriptions: parsedStudyDescriptions,
-		Institution:       *institution,
-		Department:        *department,
-		BodyPart:          *bodyPart,
-		Priority:          parsedPriority,
-		VariedMetadata:    *variedMetadata,
-		CustomTags:        parsedTags,
-		EdgeCaseConfig:    edgeCaseConfig,
-		CorruptionConfig:  corruptionConfig,
+		NumImages:          *numImages,
+		TotalSize:          *totalSize,
+		FileSize:           *fileSize,
+		ExactSize:          *exactSize,
+		OutputDir:          *outputDir,
+		Seed:               *seed,
+		NumStudies:         *numStudies,
+		StudiesRange:       parsedStudiesRange,
+		StudiesPerPatient:  parsedStudiesPerPatient,
+		NumPatients:        *numPatients,
+		Workers:            *workers,
+		ShardFanout:        *shardFanout,
+		Modality:           modalities.Modality(modalityUpper),
+		Phantom:            *phantom,
+		Pattern:            parsedPattern,
+		Annotations:        *annotations,
+		QRCode:             *qrCode,
+		CineFrames:         *cineFrames,
+		FrameTimeVector:    *frameTimeVector,
+		JPEGQualitySweep:   parsedJPEGQualitySweep,
+		USColor:            parsedUSColor,
+		ColorByPlane:       *colorByPlane,
+		Photometric:        parsedPhotometric,
+		TransferSyntax:     parsedTransferSyntax,
+		JPEGQuality:        *jpegQuality,
+		TransferSyntaxMix:  parsedTransferSyntaxMix,
+		SeriesPerStudy:     parsedSeriesPerStudy,
+		ImagesPerSeries:    parsedImagesPerSeries,
+		StudyDescriptions:  parsedStudyDescriptions,
+		Localizer:          *localizer,
+		Institution:        *institution,
+		Department:         *department,
+		BodyPart:           *bodyPart,
+		Priority:           parsedPriority,
+		VariedMetadata:     *variedMetadata,
+		FractionalSeconds:  *fractionalSeconds,
+		CharacterSet:       characterSet,
+		UIDRoot:            *uidRoot,
+		PrivateTags:        privateTags,
+		SiemensCSA:         *siemensCSA,
+		VendorTags:         parsedVendorTags,
+		Deidentified:       *deidentified,
+		GroupLengths:       *groupLengths,
+		LinkageIDs:         *risIDs,
+		Demographics:       demographics,
+		AgeProfile:         ageProfile,
+		CustomTags:         parsedTags,
+		EdgeCaseConfig:     edgeCaseConfig,
+		CorruptionConfig:   corruptionConfig,
+		VariabilityConfig:  variabilityConfig,
+		NumericJitter:      float64(*numericJitter) / 100,
+		USMeasurementSR:    *usMeasurementSR,
+		AIResults:          parsedAIResults,
+		TextSR:             *textSR,
+		RTDose:             *rtDose,
+		RTPlan:             *rtPlan,
+		SegSegments:        *segSegments,
+		GSPS:               *gsps,
+		DoseSR:             *doseSR,
+		PredefinedPatients: csvPatients,
+		PseudonymMapPath:   *pseudonymMap,
+		PseudonymKey:       pseudonymKey,
 	}
 
 	// Generate DICOM series
@@ -286,6 +950,38 @@ func main() {
 	fmt.Println("==========")
 	fmt.Println()
 
+	// Stream the instances instead of writing the directory
+	if *stream {
+		mw := dicom.NewMultipartWriter(streamOut)
+		if *streamBoundary != "" {
+			if err := mw.SetBoundary(*streamBoundary); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --stream-boundary: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("Streaming to stdout, Content-Type: %s\n\n", mw.ContentType())
+		streamedFiles, err := dicom.StreamDICOMSeries(opts, mw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error streaming DICOM series: %v\n", err)
+			os.Exit(1)
+		}
+		if err := mw.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error streaming DICOM series: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n✓ Streamed %d instances\n", len(streamedFiles))
+		return
+	}
+
+	// Upload the instances instead of writing the directory
+	if *upload != "" {
+		if err := uploadDICOMSeries(opts, *upload, *uploadConcurrency); err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading DICOM series: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	generatedFiles, err := dicom.GenerateDICOMSeries(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating DICOM series: %v\n", err)
@@ -293,11 +989,69 @@ func main() {
 	}
 
 	// Organize into DICOMDIR structure
-	if err := dicom.OrganizeFilesIntoDICOMDIR(*outputDir, generatedFiles, false); err != nil {
+	if _, err := dicom.OrganizeFiles(opts.OutputDir, generatedFiles, dicom.OrganizeOptions{Fanout: opts.ShardFanout, UIDRoot: opts.UIDRoot}); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating DICOMDIR: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Export DICOM JSON if requested
+	if *jsonExport {
+		if _, err := dicom.ExportJSON(*outputDir, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting DICOM JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Write the CSV index if requested
+	if *csvIndex {
+		if _, err := dicom.ExportCSVIndex(*outputDir, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing CSV index: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Export Modality Worklist items if requested
+	if *worklist {
+		if _, err := dicom.ExportWorklist(*outputDir, *worklistAE, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting Modality Worklist: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Export HL7 messages if requested
+	if *hl7 {
+		if _, err := dicom.ExportHL7(*outputDir, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting HL7 messages: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Export MPPS messages if requested
+	if *mpps {
+		if _, err := dicom.ExportMPPS(*outputDir, *worklistAE, *uidRoot, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting MPPS messages: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Archive the DICOM media into a ZIP file if requested
+	if *zipOutput {
+		if _, err := dicom.ExportZIP(*outputDir, dicom.ZIPPath(*outputDir), false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing ZIP archive: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Archive the DICOM media into tar files if requested
+	for _, compress := range []bool{false, true} {
+		if (compress && *tarGzOutput) || (!compress && *tarOutput) {
+			if _, err := dicom.ExportTar(*outputDir, dicom.TarPath(*outputDir, compress), compress, false); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing tar archive: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
 	// Save config if requested
 	if *saveConfig != "" {
 		state := wizard.FromGeneratorOptions(opts)
@@ -310,11 +1064,64 @@ func main() {
 
 	fmt.Println("\n✓ Generation complete!")
 	fmt.Printf("  Import directory: %s\n", *outputDir)
+	if *zipOutput {
+		fmt.Printf("  ZIP archive: %s\n", dicom.ZIPPath(*outputDir))
+	}
+	if *tarOutput {
+		fmt.Printf("  tar archive: %s\n", dicom.TarPath(*outputDir, false))
+	}
+	if *tarGzOutput {
+		fmt.Printf("  tar.gz archive: %s\n", dicom.TarPath(*outputDir, true))
+	}
+}
+
+// uploadDICOMSeries generates the instances of opts and uploads them to
+// the bucket URL, concurrency objects at a time, a prefix per study.
+func uploadDICOMSeries(opts dicom.GeneratorOptions, bucketURL string, concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("--upload-concurrency must be >= 1")
+	}
+	store, prefix, err := objectstore.Open(bucketURL, &http.Client{Timeout: 5 * time.Minute})
+	if err != nil {
+		return err
+	}
+
+	// Stop on interrupt: the uploads in progress are canceled
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	w := dicom.NewObjectWriter(ctx, store, prefix, concurrency)
+	_, err = dicom.StreamDICOMSeries(opts, w)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	objects, size := w.Uploaded()
+	elapsed := time.Since(start)
+	fmt.Printf("\n✓ Uploaded %d instances (%s) in %s\n", objects, util.FormatSize(size), elapsed.Round(time.Millisecond))
+	fmt.Printf("  Bucket: %s\n", store.URL(prefix))
+	return nil
+}
+
+// isFlagSet reports whether the named flag was set on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 func printUsage() {
 	fmt.Fprintln(os.Stderr, "\nUsage:")
-	fmt.Fprintln(os.Stderr, "  dicomforge --num-images <N> --total-size <SIZE> [options]")
+	fmt.Fprintln(os.Stderr, "  dicomforge --total-size <SIZE> [--num-images <N>] [options]")
+	fmt.Fprintln(os.Stderr, "  dicomforge --file-size <SIZE> [--num-images <N>] [options]")
 	fmt.Fprintln(os.Stderr, "\nRequired:")
 	flag.PrintDefaults()
 }
@@ -326,23 +1133,85 @@ func printHelp() {
 	fmt.Println("Generate valid DICOM series for testing medical platforms.")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  dicomforge --num-images <N> --total-size <SIZE> [options]")
+	fmt.Println("  dicomforge --total-size <SIZE> [--num-images <N>] [options]")
+	fmt.Println("  dicomforge --file-size <SIZE> [--num-images <N>] [options]")
 	fmt.Println()
 	fmt.Println("Required arguments:")
-	fmt.Println("  --num-images <N>      Number of DICOM images/slices to generate")
-	fmt.Println("  --total-size <SIZE>   Total size (e.g., '100MB', '1GB', '4.5GB')")
+	fmt.Println("  --total-size <SIZE>   Total size (e.g., '100MB', '1GB', '4.5GB'), or --file-size")
 	fmt.Println()
 	fmt.Println("Optional arguments:")
+	fmt.Println("  --num-images <N>      Number of DICOM images/slices to generate, split evenly")
+	fmt.Println("                        (default: typical count per series for the modality:")
+	fmt.Println("                        CT 100-600, MR 20-40, CR/DX 1-2, MG 4, US 1-30, RF 3-15)")
+	fmt.Println("  --file-size <SIZE>    Size of each image file (e.g., '512KB'), instead of --total-size:")
+	fmt.Println("                        images sized by 16 pixels from it, whatever their number")
+	fmt.Println("  --exact-size          Reach --total-size within 1% (or --file-size per image): images sized")
+	fmt.Println("                        by 16 pixels, then padded with a Data Set Trailing Padding element")
 	fmt.Println("  --output <DIR>        Output directory (default: 'dicom_series')")
 	fmt.Println("  --seed <N>            Seed for reproducibility (auto-generated if not specified)")
-	fmt.Println("  --modality <MOD>      Imaging modality: MR, CT, CR, DX, US, MG (default: MR)")
+	fmt.Println("  --modality <MOD>      Imaging modality: MR, CT, CR, DX, US, MG, RF (default: MR)")
+	fmt.Println("  --phantom             Render a Shepp-Logan head phantom instead of noise, its slices")
+	fmt.Println("                        spanning the head along each series, with tissue values of the")
+	fmt.Println("                        modality (Hounsfield units for CT) that fit its windows")
+	fmt.Println("  --pattern <NAME>      Render a test pattern instead of noise, windowed over the whole")
+	fmt.Println("                        pixel range, to check display calibration and scaling:")
+	fmt.Println("                        gradient     - continuous ramp and 11 steps of 10%")
+	fmt.Println("                        checkerboard - 16 squares on the smaller side")
+	fmt.Println("                        bars         - resolution bars 16 to 1 pixels wide")
+	fmt.Println("                        smpte        - SMPTE RP 133-like: grid, gray steps, 0/5% and")
+	fmt.Println("                                       95/100% squares, one-pixel line pairs")
+	fmt.Println("  --annotations         Burn scanner-like annotations into the image corners: series")
+	fmt.Println("                        description and number, study date, instance number in the")
+	fmt.Println("                        series and slice location")
+	fmt.Println("  --qr-code             Burn a QR code of the SOPInstanceUID into the bottom right corner")
+	fmt.Println("                        of each image, to check that images still match their metadata")
+	fmt.Println("                        after transcoding (modules of 2+ pixels, medium error correction)")
+	fmt.Println("  --cine-frames <N>     Frames per US instance: multi-frame cine loops with correlated")
+	fmt.Println("                        speckle and a beating cavity (requires --modality US)")
+	fmt.Println("  --frame-time-vector   With --cine-frames, jittered per-frame intervals (FrameTimeVector)")
+	fmt.Println("  --jpeg-quality-sweep <Q,...>")
+	fmt.Println("                        Encode every series as JPEG Baseline once per quality (e.g.,")
+	fmt.Println("                        '50,75,90'), same pixels, each in its own series")
+	fmt.Println("  --us-color <PI>       Color US images with a color Doppler box: RGB, YBR_FULL_422 or")
+	fmt.Println("                        PALETTE_COLOR (requires --modality US)")
+	fmt.Println("  --color-by-plane      With --us-color RGB, samples plane by plane (PlanarConfiguration 1)")
+	fmt.Println("  --photometric <PI>    Force MONOCHROME1 or MONOCHROME2 on any modality, or MIXED to")
+	fmt.Println("                        alternate them per series (pixel values are unchanged)")
+	fmt.Println("  --transfer-syntax <TS> Transfer syntax of the images: implicit-le, explicit-le")
+	fmt.Println("                        (default) or jpeg-baseline (lossy, 8-bit), by name or UID")
+	fmt.Println("  --jpeg-quality <N>    Quality of JPEG Baseline images, 1-100 (default: 90)")
+	fmt.Println("  --transfer-syntax-mix <TS:W,...>")
+	fmt.Println("                        Draw the transfer syntax of each instance by weight, mixing")
+	fmt.Println("                        them within series (e.g., 'jpeg-baseline:30,explicit-le:70')")
 	fmt.Println("  --num-studies <N>     Number of studies to generate (default: 1)")
+	fmt.Println("  --studies-range <MIN-MAX>")
+	fmt.Println("                        Random number of studies, instead of --num-studies")
+	fmt.Println("  --studies-per-patient <N|MIN-MAX>")
+	fmt.Println("                        Random number of studies per patient, instead of --num-studies")
 	fmt.Println("  --study-descriptions <LIST>")
 	fmt.Println("                        Comma-separated study descriptions (must match --num-studies)")
 	fmt.Println("                        Example: \"IRM T0,IRM M3,IRM M6\" for 3 studies")
 	fmt.Println("  --num-patients <N>    Number of patients (default: 1, studies distributed among patients)")
+	fmt.Println("  --from-csv <FILE>     One study per CSV row, with its identifying tags: PatientID (MRN),")
+	fmt.Println("                        PatientName, PatientBirthDate, PatientSex, AccessionNumber,")
+	fmt.Println("                        StudyDate, StudyDescription; rows sharing a PatientID are one patient")
+	fmt.Println("  --pseudonym-map <FILE>")
+	fmt.Println("                        With --from-csv, generate new PatientID, PatientName, birth date and")
+	fmt.Println("                        AccessionNumber, and write the encrypted original->generated map")
+	fmt.Println("  --pseudonym-key-file <FILE>")
+	fmt.Println("                        Key encrypting the map (read it with 'dicomforge decrypt-map')")
+	fmt.Println("  --deidentified        Write the instances already de-identified (PS3.15 Basic Profile):")
+	fmt.Println("                        ANONYMOUS^0001 / ANON0001 pseudonyms, dummy dates 19000101,")
+	fmt.Println("                        identifying attributes removed, PatientIdentityRemoved YES")
 	fmt.Println("  --series-per-study <N|MIN-MAX>")
 	fmt.Println("                        Series per study: '3' for fixed, '2-5' for random range (default: 1)")
+	fmt.Println("  --images-per-series <N|MIN-MAX>")
+	fmt.Println("                        Images per series: '4' for fixed, '20-40' for random range")
+	fmt.Println("                        (instead of --num-images, default: typical range of the modality)")
+	fmt.Println("  --localizer           Start each MR/CT study with a localizer series: a 3-plane")
+	fmt.Println("                        localizer for MR, a frontal topogram for CT. The other series")
+	fmt.Println("                        reference the images they cross (ReferencedImageSequence) for")
+	fmt.Println("                        scout reference lines; its images count toward --num-images")
 	fmt.Printf("  --workers <N>         Number of parallel workers (default: %d = CPU cores)\n", runtime.NumCPU())
 	fmt.Println()
 	fmt.Println("Categorization options:")
@@ -351,15 +1220,49 @@ func printHelp() {
 	fmt.Println("  --body-part <PART>    Body part examined (random per modality if not specified)")
 	fmt.Println("  --priority <PRIORITY> Exam priority: HIGH, ROUTINE, LOW (default: ROUTINE)")
 	fmt.Println("  --varied-metadata     Generate varied institutions/physicians across studies")
+	fmt.Println("  --fractional-seconds  Write times with microseconds (HHMMSS.FFFFFF)")
+	fmt.Println("  --charset <C>         SpecificCharacterSet: latin1 (ISO_IR 100) or utf8 (ISO_IR 192)")
+	fmt.Println("                        (default: ISO_IR 192 in files with non-ASCII text only)")
+	fmt.Println("  --uid-root <ROOT>     Root of the generated UIDs, e.g. your organization's")
+	fmt.Println("                        (default: " + util.DefaultUIDRoot + ")")
+	fmt.Println("  --ris-ids             Add RIS/EMR linkage IDs per study: AdmissionID (shared by the")
+	fmt.Println("                        studies of a patient on the same day), placer and filler order numbers")
+	fmt.Println("  --group-lengths       Write a retired group length element (gggg,0000) before each group,")
+	fmt.Println("                        as old toolkits did")
+	fmt.Println()
+	fmt.Println("Cohort options (any of them enables the distributions, a summary is printed):")
+	fmt.Println("  --sex-ratio <R>       Fraction of male patients, e.g. 0.48 or 48% (default: 0.5)")
+	fmt.Println("  --age-pyramid <P>     hospital, pediatric, uniform, or weighted bands")
+	fmt.Println("                        like '0-17:10,18-64:50,65-99:40' (default: hospital)")
+	fmt.Println("  --name-locales <L>    Name locale weights, e.g. 'en:80,fr:20' (default);")
+	fmt.Println("                        ja, ko and zh names have ideographic/phonetic groups")
+	fmt.Println("  --age-profile <P>     neonate, pediatric, adult or geriatric: ages at the studies,")
+	fmt.Println("                        PatientWeight/PatientSize, pediatric protocols and CT technique")
 	fmt.Println()
 	fmt.Println("Custom tags:")
 	fmt.Println("  --tag <NAME=VALUE>    Set DICOM tag value (repeatable)")
 	fmt.Println("                        Example: --tag \"InstitutionName=CHU Bordeaux\"")
+	fmt.Println("  --private-tag <SPEC>  Add a private tag to every instance (repeatable):")
+	fmt.Println("                        CREATOR,GGGG,EE,VR=VALUE, group and element in hex,")
+	fmt.Println("                        values separated by '\\', hex bytes for OB and UN")
+	fmt.Println("                        Example: --private-tag \"ACME 1.0,0029,10,LO=Fast\"")
+	fmt.Println("  --siemens-csa         Write Siemens CSA Image/Series headers (0029,1010/1020, SV10)")
+	fmt.Println("                        matching each MR image, studies on Siemens scanners (MR only)")
+	fmt.Println("  --vendor-tags <LIST>  Write GE (0009/0019/0043) and Philips (2001/2005) private tags")
+	fmt.Println("                        matching each image, studies on the vendors' scanners:")
+	fmt.Println("                        ge (CT, MR), philips (MR), comma-separated or 'all'")
 	fmt.Println()
 	fmt.Println("Edge case options:")
 	fmt.Println("  --edge-cases <N>      Percentage of patients with edge case variations (0-100)")
 	fmt.Println("  --edge-case-types <T> Comma-separated types: special-chars,long-names,")
-	fmt.Println("                        missing-tags,old-dates,varied-ids (default: all)")
+	fmt.Println("                        missing-tags,old-dates,varied-ids (default: all),")
+	fmt.Println("                        escaping (opt-in: delimiters and control characters)")
+	fmt.Println()
+	fmt.Println("Variability options:")
+	fmt.Println("  --variability <N>     Percentage of studies with a randomized style (0-100):")
+	fmt.Println("                        description casing, missing optional tags, DS precision")
+	fmt.Println("  --numeric-jitter <N>  Vary dose and exposure values (tube current, mAs, exposure")
+	fmt.Println("                        time, doses, compression force) by up to N% per instance (0-50)")
 	fmt.Println()
 	fmt.Println("Corruption options (vendor-specific private tags for robustness testing):")
 	fmt.Println("  --corrupt <TYPES>     Comma-separated corruption types (or 'all'):")
@@ -367,14 +1270,104 @@ func printHelp() {
 	fmt.Println("                        ge-private       - GE GEMS private tags")
 	fmt.Println("                        philips-private  - Philips private tags and sequences")
 	fmt.Println("                        malformed-lengths - Elements with incorrect VR lengths")
-	fmt.Println("                        all              - All corruption types")
+	fmt.Println("                        sop-class-mismatch - File meta claims another SOP class")
+	fmt.Println("                        empty-required   - Empty or whitespace-only required tags")
+	fmt.Println("                        odd-lengths      - Odd lengths without padding, zero-length OB/OW")
+	fmt.Println("                        invalid-uids     - UIDs over 64 characters or with illegal characters")
+	fmt.Println("                        legacy-groups    - Retired curves (50xx), ACR-NEMA text and in-image annotations")
+	fmt.Println("                        shared-series-uid - Same SeriesInstanceUID under several studies")
+	fmt.Println("                        mixed-patient-study - One study with instances of two patients")
+	fmt.Println("                        charset-mismatch - Text encoded in another charset than SpecificCharacterSet")
+	fmt.Println("                        truncated        - Images cut short, as by an interrupted transfer")
+	fmt.Println("                        all              - All corruption types but truncated")
+	fmt.Println("  --truncate-at <POINT> Cut point of truncated: mid-header, mid-pixel-data (default),")
+	fmt.Println("                        a byte offset (e.g., 4096) or a percentage of the file (e.g., 50%)")
+	fmt.Println()
+	fmt.Println("Derived objects:")
+	fmt.Println("  --us-sr               Add a measurement SR per US study referencing its images")
+	fmt.Println("                        (fetal biometry for PELVIS/UTERUS, organ lengths otherwise)")
+	fmt.Println("  --ai-results <TYPES>  Insert synthetic lesions and add AI results (or 'all'):")
+	fmt.Println("                        sr - TID 1500 report with outlines, diameters and confidence")
+	fmt.Println("                        sc - Secondary Capture heatmaps over the lesion slices")
+	fmt.Println("  --text-sr             Add a Basic Text SR report (findings, impression) per study")
+	fmt.Println("  --rt-dose             Add an RT Dose grid per CT study, aligned to its largest series")
+	fmt.Println("                        (requires --modality CT)")
+	fmt.Println("  --rt-plan             Add an RT Plan (beams, fractions) and an RT Structure Set")
+	fmt.Println("                        contouring its target per CT study (requires --modality CT)")
+	fmt.Println("  --gsps                Add a grayscale presentation state per study: window preset,")
+	fmt.Println("                        zoomed displayed area, label and measured circle")
+	fmt.Println("  --seg <N>             Add a binary segmentation (SEG) of the largest series with N")
+	fmt.Println("                        segments per study (requires --modality CT or MR)")
+	fmt.Println("  --dose-sr             Add an X-Ray Radiation Dose SR per CT study: localizer and one")
+	fmt.Println("                        acquisition per series with CTDIvol and DLP (requires --modality CT)")
+	fmt.Println()
+	fmt.Println("Export options:")
+	fmt.Println("  --json                Also export each instance as DICOM JSON (PS3.18, as WADO-RS")
+	fmt.Println("                        metadata) into <output>/JSON/, without pixel data")
+	fmt.Println("  --csv-index           Also write <output>/index.csv: one row per instance with its path,")
+	fmt.Println("                        patient, study, series and instance key tags and file size")
+	fmt.Println("  --worklist            Also export a Modality Worklist item (scheduled procedure step)")
+	fmt.Println("                        per study into <output>/WORKLIST/, to serve with serve --worklist")
+	fmt.Println("  --mpps                Also export the MPPS messages of each study (N-CREATE IN PROGRESS,")
+	fmt.Println("                        N-SET COMPLETED with its images) into <output>/MPPS/, to send with mpps")
+	fmt.Println("  --hl7                 Also export the HL7 v2 ORM^O01 order and ORU^R01 report of each")
+	fmt.Println("                        study into <output>/HL7/, with its accession number and UIDs")
+	fmt.Printf("  --worklist-ae <AE>    Station AE Title of the worklist items and MPPS messages (default: %s)\n", dicom.DefaultWorklistStationAE)
+	fmt.Println("  --zip                 Also archive the DICOMDIR and the PT*/ST*/SE* hierarchy into")
+	fmt.Println("                        <output>.zip, next to the output directory, for upload portals")
+	fmt.Println("  --tar, --tar-gz       Also archive them into <output>.tar or <output>.tar.gz, with the")
+	fmt.Println("                        entries of --zip, as pipeline artifacts")
+	fmt.Println("  --stream              Write the instances to stdout as a multipart/related stream of")
+	fmt.Println("                        application/dicom parts (a STOW-RS body) instead of a directory;")
+	fmt.Println("                        --output only names the run (UIDs), progress goes to stderr")
+	fmt.Println("  --upload <URL>        Upload the instances to object storage instead of a directory:")
+	fmt.Println("                        s3://bucket/prefix (AWS_* credentials, AWS_ENDPOINT_URL for")
+	fmt.Println("                        S3-compatible stores) or gs://bucket/prefix (GOOGLE_OAUTH_ACCESS_TOKEN),")
+	fmt.Println("                        as <prefix>/<study UID>/<series UID>/<SOP instance UID>.dcm")
+	fmt.Printf("  --upload-concurrency <N> Objects uploaded at a time with --upload (default: %d)\n", dicom.DefaultUploadConcurrency)
+	fmt.Println("  --stream-boundary <B> Boundary of the --stream parts, to give an uploader its Content-Type")
+	fmt.Println("                        before the stream starts (default: random)")
+	fmt.Printf("  --shard-fanout <N>    Largest number of entries per output directory (default: %d);\n", dicom.DefaultShardFanout)
+	fmt.Println("                        beyond, patients, studies, series or images are grouped into SH*")
+	fmt.Println("                        subdirectories, referenced as such by the DICOMDIR")
+	fmt.Println("  --config <FILE>       Generate the patients, studies, series and tag overrides described")
+	fmt.Println("                        in a YAML or JSON (.json) file instead of the other options")
+	fmt.Println("  --save-config <FILE>  Save the options as a YAML or JSON (.json) config file after generation")
 	fmt.Println()
 	fmt.Println("  --help                Show this help message")
 	fmt.Println()
+	fmt.Println("Subcommands:")
+	fmt.Println("  wizard                Launch the interactive wizard")
+	fmt.Println("  priors                Generate a current study plus K prior studies of one patient")
+	fmt.Println("                        (see 'dicomforge priors --help')")
+	fmt.Println("  serve                 Serve generated files as a Query/Retrieve SCP (C-ECHO, C-FIND,")
+	fmt.Println("                        C-MOVE, C-GET), Modality Worklist and DICOMweb (see 'dicomforge serve --help')")
+	fmt.Println("  receive               Receive C-STOREs as a Storage SCP into a directory, with a JSON")
+	fmt.Println("                        summary (see 'dicomforge receive --help')")
+	fmt.Println("  stow                  Upload generated files to a DICOMweb archive with STOW-RS")
+	fmt.Println("                        (see 'dicomforge stow --help')")
+	fmt.Println("  mpps                  Send the MPPS messages exported with --mpps to an MPPS SCP")
+	fmt.Println("                        (see 'dicomforge mpps --help')")
+	fmt.Println("  decrypt-map           Print a --pseudonym-map as CSV (see 'dicomforge decrypt-map --help')")
+	fmt.Println("  deid-challenge        Generate identified and de-identified versions of the same studies,")
+	fmt.Println("                        with a ground-truth answer file (see 'dicomforge deid-challenge --help')")
+	fmt.Println("  reroot                Copy a directory with all its UIDs under a new UID root")
+	fmt.Println("                        (see 'dicomforge reroot --help')")
+	fmt.Println("  anonymize             Copy a directory de-identified with the PS3.15 Basic Profile, with a")
+	fmt.Println("                        mapping file (see 'dicomforge anonymize --help')")
+	fmt.Println("  split                 Extract subsets of the series of studies into new studies")
+	fmt.Println("                        (see 'dicomforge split --help')")
+	fmt.Println("  merge                 Combine studies into a single study (see 'dicomforge merge --help')")
+	fmt.Println("  capacity              Project the storage growth of a workload and generate a representative")
+	fmt.Println("                        day of it (see 'dicomforge capacity --help')")
+	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  # Generate 10 MR images, 100MB total")
 	fmt.Println("  dicomforge --num-images 10 --total-size 100MB")
 	fmt.Println()
+	fmt.Println("  # CT studies with a typical number of slices per series (100-600)")
+	fmt.Println("  dicomforge --total-size 500MB --modality CT --num-studies 3")
+	fmt.Println()
 	fmt.Println("  # Generate CT scan with 100 slices")
 	fmt.Println("  dicomforge --num-images 100 --total-size 200MB --modality CT")
 	fmt.Println()
@@ -411,9 +1404,54 @@ func printHelp() {
 	fmt.Println("  # Generate with all corruption types for robustness testing")
 	fmt.Println("  dicomforge --num-images 10 --total-size 20MB --corrupt all")
 	fmt.Println()
+	fmt.Println("  # Generate images cut mid-header, to test partial-transfer recovery")
+	fmt.Println("  dicomforge --num-images 10 --total-size 10MB --corrupt truncated --truncate-at mid-header")
+	fmt.Println()
 	fmt.Println("  # Combine corruption with edge cases")
 	fmt.Println("  dicomforge --num-images 10 --total-size 20MB --corrupt siemens-csa --edge-cases 50")
 	fmt.Println()
+	fmt.Println("  # Generate a current MR brain study with 3 yearly priors")
+	fmt.Println("  dicomforge priors --num-images 80 --total-size 100MB --modality MR --body-part HEAD --num-priors 3")
+	fmt.Println()
+	fmt.Println("  # Serve a generated series to a PACS client, moving studies to STORESCP")
+	fmt.Println("  dicomforge serve --dir dicom_series --port 11112 --move-dest STORESCP=localhost:11113")
+	fmt.Println()
+	fmt.Println("  # Schedule the generated studies on a modality worklist, and serve it")
+	fmt.Println("  dicomforge --num-images 20 --total-size 50MB --num-studies 3 --worklist --worklist-ae CT01")
+	fmt.Println("  dicomforge serve --dir dicom_series --worklist dicom_series/WORKLIST")
+	fmt.Println()
+	fmt.Println("  # Report the acquisition of the generated studies to a RIS")
+	fmt.Println("  dicomforge --num-images 20 --total-size 50MB --num-studies 3 --mpps --worklist-ae CT01")
+	fmt.Println("  dicomforge mpps --dir dicom_series/MPPS --addr ris.example.org:104 --ae-title CT01 --called-ae RIS")
+	fmt.Println()
+	fmt.Println("  # Receive the moved studies in a disposable Storage SCP")
+	fmt.Println("  dicomforge receive --dir received --port 11113 --ae-title STORESCP")
+	fmt.Println()
+	fmt.Println("  # Upload generated studies to a DICOMweb-only archive")
+	fmt.Println("  dicomforge stow --dir dicom_series --url https://archive.example.org/dicom-web --header 'Authorization: Bearer <token>'")
+	fmt.Println()
+	fmt.Println("  # Pseudonymize CSV identities, then read back the map")
+	fmt.Println("  dicomforge --from-csv worklist.csv --total-size 100MB --pseudonym-map map.enc --pseudonym-key-file key.txt")
+	fmt.Println("  dicomforge decrypt-map --map map.enc --key-file key.txt")
+	fmt.Println()
+	fmt.Println("  # De-identification benchmark: 3 patients, 30% of de-identified files leak an identifier")
+	fmt.Println("  dicomforge deid-challenge --num-images 60 --total-size 50MB --num-studies 3 --num-patients 3 --leak-rate 0.3")
+	fmt.Println()
+	fmt.Println("  # Bring third-party sample data under your own UID root")
+	fmt.Println("  dicomforge reroot --input vendor_samples --output samples --root 1.2.826.0.1.3680043.10.1234 --map uids.csv")
+	fmt.Println()
+	fmt.Println("  # De-identify a dataset, keeping its UIDs")
+	fmt.Println("  dicomforge anonymize --input dicom_series --output anonymized --map anonymize_map.csv --retain-uids")
+	fmt.Println()
+	fmt.Println("  # Viewer test cases: series 1 and 2 in one new study, series 3 in another")
+	fmt.Println("  dicomforge split --input dicom_series --output cases --series '1,2;3'")
+	fmt.Println()
+	fmt.Println("  # Large composite study for study loading performance tests")
+	fmt.Println("  dicomforge merge --input study_a,study_b --output composite")
+	fmt.Println()
+	fmt.Println("  # PACS sizing: 3 years of a 300 studies/day hospital growing 5% a year, and 10% of a day")
+	fmt.Println("  dicomforge capacity --studies-per-day 300 --days 1095 --growth 5 --generate-day day --scale 0.1")
+	fmt.Println()
 	fmt.Println("Output:")
 	fmt.Println("  The program creates a DICOM series with:")
 	fmt.Println("  - DICOMDIR index file")
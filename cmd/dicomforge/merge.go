@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// runMerge implements the "merge" subcommand: a single study combining the
+// series of existing studies.
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	input := fs.String("input", "", "Directories of DICOM studies to merge, comma-separated (required)")
+	outputDir := fs.String("output", "", "Directory receiving the merged study (required)")
+	uidRoot := fs.String("uid-root", "", "Root of the new UIDs (default: "+util.DefaultUIDRoot+")")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" || *outputDir == "" {
+		return fmt.Errorf("--input and --output are required")
+	}
+	var inputDirs []string
+	for _, dir := range strings.Split(*input, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			inputDirs = append(inputDirs, dir)
+		}
+	}
+
+	report, err := dicom.MergeStudies(inputDirs, *outputDir, dicom.MergeOptions{UIDRoot: *uidRoot})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✓ Studies merged")
+	fmt.Printf("  %d studies merged into one study of %d series, %d files written to %s\n", report.Studies, report.Series, report.Files, *outputDir)
+	if report.Ignored > 0 {
+		fmt.Printf("  %d files ignored (not DICOM or DICOMDIR)\n", report.Ignored)
+	}
+	return nil
+}
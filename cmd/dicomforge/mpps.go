@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	"github.com/mrsinham/dicomforge/internal/dimse"
+)
+
+// runMPPS implements the "mpps" subcommand: sending the Modality Performed
+// Procedure Steps exported with --mpps to an MPPS SCP (RIS, PACS or
+// broker), as the modality would, N-CREATE then N-SET of each step.
+func runMPPS(args []string) error {
+	fs := flag.NewFlagSet("mpps", flag.ContinueOnError)
+	dir := fs.String("dir", "dicom_series/MPPS", "Directory of the MPPS messages exported with --mpps")
+	addr := fs.String("addr", "", "Address of the MPPS SCP, as host:port (required)")
+	aeTitle := fs.String("ae-title", "MODALITY", "AE title of the modality (calling AE title)")
+	calledAE := fs.String("called-ae", "ANY-SCP", "AE title of the MPPS SCP (called AE title)")
+	inProgress := fs.Bool("in-progress", false, "Only send the N-CREATE of each step, leaving it IN PROGRESS")
+	quiet := fs.Bool("quiet", false, "Do not log the steps sent")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *addr == "" {
+		return fmt.Errorf("--addr is required")
+	}
+	if _, port, err := net.SplitHostPort(*addr); err != nil || port == "" {
+		return fmt.Errorf("invalid --addr %q, expected host:port", *addr)
+	}
+	for name, ae := range map[string]string{"--ae-title": *aeTitle, "--called-ae": *calledAE} {
+		if ae == "" || len(ae) > 16 {
+			return fmt.Errorf("%s must be 1 to 16 characters", name)
+		}
+	}
+
+	steps, err := dimse.LoadPerformedProcedureSteps(*dir)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("no MPPS messages found in %s (generate them with --mpps)", *dir)
+	}
+
+	contexts := []dimse.PresentationContext{{
+		AbstractSyntax:   dimse.ModalityPerformedProcedureStepSOPClass,
+		TransferSyntaxes: []string{dimse.ExplicitVRLittleEndian, dimse.ImplicitVRLittleEndian},
+	}}
+	a, err := dimse.Dial(*addr, *aeTitle, *calledAE, contexts)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = a.Release() }()
+
+	for _, step := range steps {
+		if err := a.ReportProcedureStep(step, *inProgress); err != nil {
+			return err
+		}
+		if !*quiet {
+			fmt.Printf("  %s reported\n", step.SOPInstanceUID)
+		}
+	}
+
+	status := "created and completed"
+	if *inProgress {
+		status = "created, left in progress"
+	}
+	fmt.Printf("✓ %d performed procedure steps %s on %s\n", len(steps), status, *calledAE)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// runPriors implements the "priors" subcommand: a current study plus K prior
+// studies of the same patient, written as a single DICOMDIR tree.
+func runPriors(args []string) error {
+	fs := flag.NewFlagSet("priors", flag.ContinueOnError)
+	numImages := fs.Int("num-images", 0, "Total number of images across all studies (required)")
+	totalSize := fs.String("total-size", "", "Total size (e.g., '100MB', '1GB') (required)")
+	outputDir := fs.String("output", "dicom_priors", "Output directory")
+	seed := fs.Int64("seed", 0, "Seed for reproducibility (optional)")
+	numPriors := fs.Int("num-priors", 2, "Number of prior studies")
+	intervalMonths := fs.Int("interval-months", 12, "Months between consecutive studies")
+	intervalWeeks := fs.Int("interval-weeks", 0, "Weeks between consecutive studies, instead of --interval-months")
+	currentDate := fs.String("current-date", "", "Date of the current study, YYYYMMDD (random if not specified)")
+	modality := fs.String("modality", "MR", "Imaging modality: MR, CT, CR, DX, US, MG, RF")
+	bodyPart := fs.String("body-part", "", "Body part examined (random per modality if not specified)")
+	seriesPerStudy := fs.String("series-per-study", "1", "Number of series per study (e.g., '3' or '2-5')")
+	workers := fs.Int("workers", 0, "Number of parallel workers (default: CPU cores)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *numImages <= 0 {
+		return fmt.Errorf("--num-images must be > 0")
+	}
+	if *totalSize == "" {
+		return fmt.Errorf("--total-size is required")
+	}
+	if *numImages < *numPriors+1 {
+		return fmt.Errorf("--num-images must be at least %d (one per study)", *numPriors+1)
+	}
+	intervalMonthsSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "interval-months" {
+			intervalMonthsSet = true
+		}
+	})
+	if intervalMonthsSet && *intervalWeeks != 0 {
+		return fmt.Errorf("--interval-months cannot be combined with --interval-weeks")
+	}
+
+	modalityUpper := strings.ToUpper(*modality)
+	if !modalities.IsValid(modalityUpper) {
+		return fmt.Errorf("invalid modality %q, valid options: %v", *modality, modalities.AllModalities())
+	}
+
+	parsedSeriesPerStudy, err := util.ParseSeriesRange(*seriesPerStudy)
+	if err != nil {
+		return err
+	}
+
+	opts := dicom.GeneratorOptions{
+		NumImages:      *numImages,
+		TotalSize:      *totalSize,
+		OutputDir:      *outputDir,
+		Seed:           *seed,
+		Workers:        *workers,
+		Modality:       modalities.Modality(modalityUpper),
+		BodyPart:       strings.ToUpper(*bodyPart),
+		SeriesPerStudy: parsedSeriesPerStudy,
+	}
+
+	patient, err := dicom.BuildPriorsPatient(opts, dicom.PriorsOptions{
+		NumPriors:      *numPriors,
+		IntervalMonths: *intervalMonths,
+		IntervalWeeks:  *intervalWeeks,
+		CurrentDate:    *currentDate,
+	})
+	if err != nil {
+		return err
+	}
+	opts.PredefinedPatients = []dicom.PredefinedPatient{patient}
+
+	fmt.Println("dicomforge priors")
+	fmt.Println("=================")
+	fmt.Printf("Current study: %s (%s), %d priors\n\n", patient.Studies[0].Date, patient.Studies[0].AccessionNumber, *numPriors)
+
+	generatedFiles, err := dicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		return fmt.Errorf("generating DICOM series: %w", err)
+	}
+
+	if _, err := dicom.OrganizeFiles(opts.OutputDir, generatedFiles, dicom.OrganizeOptions{Fanout: opts.ShardFanout, UIDRoot: opts.UIDRoot}); err != nil {
+		return fmt.Errorf("creating DICOMDIR: %w", err)
+	}
+
+	fmt.Println("\n✓ Priors package complete!")
+	fmt.Printf("  Import directory: %s\n", opts.OutputDir)
+	return nil
+}
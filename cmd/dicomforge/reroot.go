@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mrsinham/dicomforge/internal/dicom"
+)
+
+// runReroot implements the "reroot" subcommand: a copy of an existing
+// directory with all its UIDs moved under a new UID root.
+func runReroot(args []string) error {
+	fs := flag.NewFlagSet("reroot", flag.ContinueOnError)
+	inputDir := fs.String("input", "", "Directory of DICOM files to re-root (required)")
+	outputDir := fs.String("output", "", "Directory receiving the re-rooted copy (required)")
+	root := fs.String("root", "", "New UID root, e.g. '1.2.826.0.1.3680043.10.1234' (required)")
+	mapFile := fs.String("map", "", "CSV file receiving the old->new UID mapping (optional)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *inputDir == "" || *outputDir == "" || *root == "" {
+		return fmt.Errorf("--input, --output and --root are required")
+	}
+
+	report, err := dicom.RerootDirectory(*inputDir, *outputDir, dicom.RerootOptions{
+		Root:    *root,
+		MapFile: *mapFile,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✓ UIDs re-rooted")
+	fmt.Printf("  %d files rewritten, %d UIDs replaced under %s\n", report.Rewritten, report.UIDs, *root)
+	if report.Copied > 0 {
+		fmt.Printf("  %d files not readable as DICOM, copied unchanged\n", report.Copied)
+	}
+	if *mapFile != "" {
+		fmt.Printf("  UID map: %s\n", *mapFile)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicomweb"
+	"github.com/mrsinham/dicomforge/internal/dimse"
+	"github.com/mrsinham/dicomforge/internal/throttle"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// runServe implements the "serve" subcommand: a Query/Retrieve SCP answering
+// C-ECHO, C-FIND, C-MOVE and C-GET over a directory of generated DICOM files,
+// optionally a Modality Worklist SCP, and a DICOMweb server over the same
+// files.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	dir := fs.String("dir", "dicom_series", "Directory of DICOM files to serve (e.g., a dicomforge output)")
+	port := fs.Int("port", 11112, "TCP port to listen on")
+	httpPort := fs.Int("http-port", 0, "TCP port of the DICOMweb (WADO-RS) server (0 = disabled)")
+	bulkData := fs.Bool("bulkdata-uri", false, "With --http-port, reference binary values in metadata with a BulkDataURI instead of inlining them")
+	worklistDir := fs.String("worklist", "", "Directory of Modality Worklist items to answer C-FIND MWL requests with (e.g., <output>/WORKLIST)")
+	aeTitle := fs.String("ae-title", "DICOMFORGE", "AE title of the server (called AE title)")
+	quiet := fs.Bool("quiet", false, "Do not log associations and requests")
+	destinations := make(map[string]string)
+	fs.Func("move-dest", "C-MOVE destination as AE=host:port (repeatable)", func(value string) error {
+		ae, addr, ok := strings.Cut(value, "=")
+		ae = strings.TrimSpace(ae)
+		if !ok || ae == "" || len(ae) > 16 {
+			return fmt.Errorf("invalid destination %q, expected AE=host:port (AE up to 16 characters)", value)
+		}
+		if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+			return fmt.Errorf("invalid destination address %q, expected host:port", addr)
+		}
+		destinations[ae] = addr
+		return nil
+	})
+
+	var policy dimse.Policy
+	fs.Func("reject-sop-class", "Reject the contexts of a SOP class UID (repeatable)", func(value string) error {
+		if !isUID(value) {
+			return fmt.Errorf("invalid UID %q", value)
+		}
+		policy.RejectSOPClasses = append(policy.RejectSOPClasses, value)
+		return nil
+	})
+	fs.Func("reject-transfer-syntax", "Never accept a transfer syntax UID (repeatable)", func(value string) error {
+		if !isUID(value) {
+			return fmt.Errorf("invalid UID %q", value)
+		}
+		policy.RejectTransferSyntaxes = append(policy.RejectTransferSyntaxes, value)
+		return nil
+	})
+	maxPDU := fs.Int("max-pdu", dimse.DefaultMaxPDULength, "Maximum PDU length announced to clients and enforced, in bytes")
+	maxContexts := fs.Int("max-contexts", 0, "Maximum number of presentation contexts accepted per association (0 = no limit)")
+	bandwidth := fs.String("bandwidth", "", "Simulated link bandwidth per direction, per second (e.g., '512KB', '2MB')")
+	latency := fs.Duration("latency", 0, "Simulated latency of each PDU sent (e.g., '200ms')")
+	jitter := fs.Duration("jitter", 0, "Random variation of the latency, +/- (e.g., '50ms')")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *port <= 0 || *port > 65535 {
+		return fmt.Errorf("--port must be between 1 and 65535")
+	}
+	if *httpPort < 0 || *httpPort > 65535 || (*httpPort != 0 && *httpPort == *port) {
+		return fmt.Errorf("--http-port must be between 1 and 65535 and differ from --port")
+	}
+	if *bulkData && *httpPort == 0 {
+		return fmt.Errorf("--bulkdata-uri requires --http-port")
+	}
+	if *aeTitle == "" || len(*aeTitle) > 16 {
+		return fmt.Errorf("--ae-title must be 1 to 16 characters")
+	}
+	if *maxPDU < 1024 {
+		return fmt.Errorf("--max-pdu must be at least 1024")
+	}
+	if *maxContexts < 0 {
+		return fmt.Errorf("--max-contexts must be >= 0")
+	}
+	policy.MaxPDULength = uint32(*maxPDU)
+	policy.MaxContexts = *maxContexts
+
+	link := throttle.Config{Latency: *latency, Jitter: *jitter}
+	if *bandwidth != "" {
+		var err error
+		if link.Bandwidth, err = util.ParseSize(*bandwidth); err != nil {
+			return fmt.Errorf("--bandwidth: %w", err)
+		}
+		if link.Bandwidth <= 0 {
+			return fmt.Errorf("--bandwidth must be > 0")
+		}
+	}
+	if *latency < 0 || *jitter < 0 {
+		return fmt.Errorf("--latency and --jitter must be >= 0")
+	}
+
+	index, err := dimse.NewIndex(*dir)
+	if err != nil {
+		return err
+	}
+	if index.Len() == 0 {
+		return fmt.Errorf("no DICOM files found in %s", *dir)
+	}
+	var worklist *dimse.Worklist
+	if *worklistDir != "" {
+		if worklist, err = dimse.LoadWorklist(*worklistDir); err != nil {
+			return err
+		}
+		if worklist.Len() == 0 {
+			return fmt.Errorf("no worklist items found in %s", *worklistDir)
+		}
+	}
+
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(*port))
+	if err != nil {
+		return err
+	}
+	l = throttle.Listener(l, link)
+	var httpListener net.Listener
+	if *httpPort != 0 {
+		if httpListener, err = net.Listen("tcp", ":"+strconv.Itoa(*httpPort)); err != nil {
+			_ = l.Close()
+			return err
+		}
+		httpListener = throttle.Listener(httpListener, link)
+	}
+
+	fmt.Println("dicomforge serve")
+	fmt.Println("================")
+	fmt.Printf("Serving %d instances from %s\n", index.Len(), *dir)
+	fmt.Printf("AE title: %s, port: %d\n", *aeTitle, *port)
+	if worklist != nil {
+		fmt.Printf("Modality Worklist: %d items from %s\n", worklist.Len(), *worklistDir)
+	}
+	if httpListener != nil {
+		fmt.Printf("DICOMweb: http://localhost:%d (WADO-RS studies, series, instances, frames, rendered, metadata and bulk data; WADO-URI at /wado)\n", *httpPort)
+	}
+	for ae, addr := range destinations {
+		fmt.Printf("C-MOVE destination: %s -> %s\n", ae, addr)
+	}
+	for _, uid := range policy.RejectSOPClasses {
+		fmt.Printf("Rejected SOP class: %s\n", uid)
+	}
+	for _, uid := range policy.RejectTransferSyntaxes {
+		fmt.Printf("Rejected transfer syntax: %s\n", uid)
+	}
+	if link.Enabled() {
+		rate := "unlimited"
+		if *bandwidth != "" {
+			rate = *bandwidth + "/s"
+		}
+		fmt.Printf("Simulated link: bandwidth %s, latency %v +/- %v\n", rate, link.Latency, link.Jitter)
+	}
+	fmt.Println("Press Ctrl+C to stop")
+
+	// Stop on interrupt: closing the listeners ends Serve
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		_ = l.Close()
+		if httpListener != nil {
+			_ = httpListener.Close()
+		}
+	}()
+
+	if httpListener != nil {
+		web := &dicomweb.Server{Index: index, Quiet: *quiet, BulkData: *bulkData}
+		go func() {
+			if err := http.Serve(httpListener, web); err != nil && !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintf(os.Stderr, "DICOMweb server stopped: %v\n", err)
+			}
+		}()
+	}
+
+	srv := &dimse.Server{AETitle: *aeTitle, Index: index, Worklist: worklist, Destinations: destinations, Policy: policy, Quiet: *quiet}
+	return srv.Serve(l)
+}
+
+// isUID reports whether value is a syntactically valid UID.
+func isUID(value string) bool {
+	if value == "" || len(value) > 64 {
+		return false
+	}
+	for _, component := range strings.Split(value, ".") {
+		if component == "" || (len(component) > 1 && component[0] == '0') {
+			return false
+		}
+		for _, c := range component {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// runSplit implements the "split" subcommand: new studies holding subsets of
+// the series of existing studies.
+func runSplit(args []string) error {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	inputDir := fs.String("input", "", "Directory of DICOM studies to split (required)")
+	outputDir := fs.String("output", "", "Directory receiving the new studies (required)")
+	uidRoot := fs.String("uid-root", "", "Root of the new UIDs (default: "+util.DefaultUIDRoot+")")
+	series := fs.String("series", "", "Series numbers of each new study, e.g. '1,2;3' (default: one study per series)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *inputDir == "" || *outputDir == "" {
+		return fmt.Errorf("--input and --output are required")
+	}
+	groups, err := util.ParseSeriesGroups(*series)
+	if err != nil {
+		return fmt.Errorf("invalid --series: %w", err)
+	}
+
+	report, err := dicom.SplitStudies(*inputDir, *outputDir, dicom.SplitOptions{Groups: groups, UIDRoot: *uidRoot})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✓ Studies split")
+	fmt.Printf("  %d new studies, %d files written to %s\n", report.Studies, report.Files, *outputDir)
+	if report.Ignored > 0 {
+		fmt.Printf("  %d files ignored (not DICOM, DICOMDIR or series in no group)\n", report.Ignored)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/dicomweb"
+	"github.com/mrsinham/dicomforge/internal/dimse"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// runStow implements the "stow" subcommand: uploading the DICOM files of a
+// directory to a DICOMweb archive with STOW-RS, for archives and cloud
+// services exposing no DIMSE interface.
+func runStow(args []string) error {
+	fs := flag.NewFlagSet("stow", flag.ContinueOnError)
+	dir := fs.String("dir", "dicom_series", "Directory of DICOM files to upload (e.g., a dicomforge output)")
+	serviceURL := fs.String("url", "", "DICOMweb service root; requests are sent to <url>/studies (required)")
+	batch := fs.Int("batch", dicomweb.DefaultStowBatchSize, "Largest number of instances per request (requests never mix studies)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Timeout of each request (0 = none)")
+	quiet := fs.Bool("quiet", false, "Do not log requests")
+	header := make(http.Header)
+	fs.Func("header", "HTTP header added to every request, as 'Name: value' (repeatable, e.g. for Authorization)", func(value string) error {
+		name, v, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("invalid header %q, expected 'Name: value'", value)
+		}
+		header.Add(name, strings.TrimSpace(v))
+		return nil
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *serviceURL == "" {
+		return fmt.Errorf("--url is required")
+	}
+	if u, err := url.Parse(*serviceURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("--url must be an http or https URL")
+	}
+	if *batch < 1 {
+		return fmt.Errorf("--batch must be >= 1")
+	}
+	if *timeout < 0 {
+		return fmt.Errorf("--timeout must be >= 0")
+	}
+
+	index, err := dimse.NewIndex(*dir)
+	if err != nil {
+		return err
+	}
+	if index.Len() == 0 {
+		return fmt.Errorf("no DICOM files found in %s", *dir)
+	}
+
+	fmt.Println("dicomforge stow")
+	fmt.Println("===============")
+	fmt.Printf("Uploading %d instances from %s to %s/studies\n", index.Len(), *dir, strings.TrimSuffix(*serviceURL, "/"))
+
+	// Stop on interrupt: the request in progress is canceled
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := &dicomweb.StowClient{
+		URL:        *serviceURL,
+		Header:     header,
+		BatchSize:  *batch,
+		HTTPClient: &http.Client{Timeout: *timeout},
+		Quiet:      *quiet,
+	}
+	start := time.Now()
+	result, err := client.Store(ctx, index.All())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ Stored %d of %d instances (%s) in %d requests, %v\n",
+		result.Stored, index.Len(), util.FormatSize(result.Bytes), result.Requests, time.Since(start).Round(time.Millisecond))
+	if result.Warnings > 0 {
+		fmt.Printf("  %d instances stored with warnings (e.g. coerced attributes)\n", result.Warnings)
+	}
+	if len(result.Failed) > 0 {
+		for _, f := range result.Failed {
+			fmt.Fprintf(os.Stderr, "  not stored: %s (%s), failure reason 0x%04X\n", f.SOPInstanceUID, f.Path, f.Reason)
+		}
+		return fmt.Errorf("%d instances not stored", len(result.Failed))
+	}
+	return nil
+}
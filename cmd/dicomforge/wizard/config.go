@@ -1,20 +1,26 @@
 package wizard
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mrsinham/dicomforge/cmd/dicomforge/wizard/types"
 	"gopkg.in/yaml.v3"
 )
 
-// Config represents the complete wizard configuration for YAML serialization.
+// Config represents the complete wizard configuration for YAML or JSON
+// serialization.
 type Config struct {
-	Global   GlobalConfigYAML    `yaml:"global"`
-	Patients []PatientConfigYAML `yaml:"patients"`
+	Global   GlobalConfigYAML    `yaml:"global" json:"global"`
+	Patients []PatientConfigYAML `yaml:"patients" json:"patients"`
 }
 
-// LoadFromYAML reads a config file and returns WizardState.
+// LoadFromYAML reads a config file and returns WizardState. Files with a
+// .json extension are parsed as JSON, others as YAML.
 func LoadFromYAML(path string) (*WizardState, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -22,18 +28,32 @@ func LoadFromYAML(path string) (*WizardState, error) {
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if isJSONConfig(path) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("parsing JSON: %w", err)
+		}
+	} else if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parsing YAML: %w", err)
 	}
 
 	return configToWizardState(&cfg), nil
 }
 
-// SaveToYAML writes WizardState to a YAML file.
+// SaveToYAML writes WizardState to a YAML file, or a JSON file when path has
+// a .json extension.
 func SaveToYAML(state *WizardState, path string) error {
 	cfg := wizardStateToConfig(state)
 
-	data, err := yaml.Marshal(cfg)
+	var data []byte
+	var err error
+	if isJSONConfig(path) {
+		data, err = json.MarshalIndent(cfg, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(cfg)
+	}
 	if err != nil {
 		return fmt.Errorf("marshaling config: %w", err)
 	}
@@ -45,7 +65,12 @@ func SaveToYAML(state *WizardState, path string) error {
 	return nil
 }
 
-// configToWizardState converts Config (YAML) to WizardState (runtime).
+// isJSONConfig reports whether a config file is in JSON rather than YAML.
+func isJSONConfig(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// configToWizardState converts Config (YAML or JSON) to WizardState (runtime).
 func configToWizardState(c *Config) *WizardState {
 	state := &WizardState{
 		Global: types.GlobalConfig{
@@ -57,6 +82,7 @@ func configToWizardState(c *Config) *WizardState {
 			NumPatients:       c.Global.NumPatients,
 			StudiesPerPatient: c.Global.StudiesPerPatient,
 			SeriesPerStudy:    c.Global.SeriesPerStudy,
+			CustomTags:        copyMap(c.Global.Tags),
 		},
 		Patients: make([]types.PatientConfig, len(c.Patients)),
 	}
@@ -103,7 +129,7 @@ func configToWizardState(c *Config) *WizardState {
 	return state
 }
 
-// wizardStateToConfig converts WizardState to Config (for YAML serialization).
+// wizardStateToConfig converts WizardState to Config (for YAML or JSON serialization).
 func wizardStateToConfig(s *WizardState) *Config {
 	cfg := &Config{
 		Global: GlobalConfigYAML{
@@ -115,6 +141,7 @@ func wizardStateToConfig(s *WizardState) *Config {
 			NumPatients:       s.Global.NumPatients,
 			StudiesPerPatient: s.Global.StudiesPerPatient,
 			SeriesPerStudy:    s.Global.SeriesPerStudy,
+			Tags:              copyMap(s.Global.CustomTags),
 		},
 		Patients: make([]PatientConfigYAML, len(s.Patients)),
 	}
@@ -175,44 +202,46 @@ func copyMap(m map[string]string) map[string]string {
 
 // GlobalConfigYAML holds global settings with YAML tags for serialization.
 type GlobalConfigYAML struct {
-	Modality          string `yaml:"modality"`
-	TotalImages       int    `yaml:"total_images"`
-	TotalSize         string `yaml:"total_size"`
-	OutputDir         string `yaml:"output"`
-	Seed              int64  `yaml:"seed,omitempty"`
-	NumPatients       int    `yaml:"num_patients,omitempty"`
-	StudiesPerPatient int    `yaml:"studies_per_patient,omitempty"`
-	SeriesPerStudy    int    `yaml:"series_per_study,omitempty"`
+	Modality          string `yaml:"modality" json:"modality"`
+	TotalImages       int    `yaml:"total_images" json:"total_images"`
+	TotalSize         string `yaml:"total_size" json:"total_size"`
+	OutputDir         string `yaml:"output" json:"output"`
+	Seed              int64  `yaml:"seed,omitempty" json:"seed,omitempty"`
+	NumPatients       int    `yaml:"num_patients,omitempty" json:"num_patients,omitempty"`
+	StudiesPerPatient int    `yaml:"studies_per_patient,omitempty" json:"studies_per_patient,omitempty"`
+	SeriesPerStudy    int    `yaml:"series_per_study,omitempty" json:"series_per_study,omitempty"`
+
+	Tags map[string]string `yaml:"tags,omitempty" json:"tags,omitempty"`
 }
 
 // PatientConfigYAML holds patient configuration with YAML tags.
 type PatientConfigYAML struct {
-	Name      string            `yaml:"name"`
-	ID        string            `yaml:"id"`
-	BirthDate string            `yaml:"birth_date"`
-	Sex       string            `yaml:"sex"`
-	Studies   []StudyConfigYAML `yaml:"studies"`
+	Name      string            `yaml:"name" json:"name"`
+	ID        string            `yaml:"id" json:"id"`
+	BirthDate string            `yaml:"birth_date" json:"birth_date"`
+	Sex       string            `yaml:"sex" json:"sex"`
+	Studies   []StudyConfigYAML `yaml:"studies" json:"studies"`
 }
 
 // StudyConfigYAML holds study configuration with YAML tags.
 type StudyConfigYAML struct {
-	Description        string             `yaml:"description"`
-	Date               string             `yaml:"date"`
-	AccessionNumber    string             `yaml:"accession"`
-	Institution        string             `yaml:"institution"`
-	Department         string             `yaml:"department"`
-	BodyPart           string             `yaml:"body_part"`
-	Priority           string             `yaml:"priority"`
-	ReferringPhysician string             `yaml:"referring_physician"`
-	CustomTags         map[string]string  `yaml:"custom_tags,omitempty"`
-	Series             []SeriesConfigYAML `yaml:"series"`
+	Description        string             `yaml:"description" json:"description"`
+	Date               string             `yaml:"date" json:"date"`
+	AccessionNumber    string             `yaml:"accession" json:"accession"`
+	Institution        string             `yaml:"institution" json:"institution"`
+	Department         string             `yaml:"department" json:"department"`
+	BodyPart           string             `yaml:"body_part" json:"body_part"`
+	Priority           string             `yaml:"priority" json:"priority"`
+	ReferringPhysician string             `yaml:"referring_physician" json:"referring_physician"`
+	CustomTags         map[string]string  `yaml:"custom_tags,omitempty" json:"custom_tags,omitempty"`
+	Series             []SeriesConfigYAML `yaml:"series" json:"series"`
 }
 
 // SeriesConfigYAML holds series configuration with YAML tags.
 type SeriesConfigYAML struct {
-	Description string            `yaml:"description"`
-	Protocol    string            `yaml:"protocol"`
-	Orientation string            `yaml:"orientation"`
-	ImageCount  int               `yaml:"images"`
-	CustomTags  map[string]string `yaml:"custom_tags,omitempty"`
+	Description string            `yaml:"description" json:"description"`
+	Protocol    string            `yaml:"protocol" json:"protocol"`
+	Orientation string            `yaml:"orientation" json:"orientation"`
+	ImageCount  int               `yaml:"images" json:"images"`
+	CustomTags  map[string]string `yaml:"custom_tags,omitempty" json:"custom_tags,omitempty"`
 }
@@ -7,6 +7,9 @@ import (
 	"testing"
 
 	"github.com/mrsinham/dicomforge/cmd/dicomforge/wizard/types"
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
 func TestLoadFromYAML_ValidConfig(t *testing.T) {
@@ -748,3 +751,194 @@ func TestSaveToYAML_InvalidPath(t *testing.T) {
 		t.Error("Expected error when saving to invalid path, got nil")
 	}
 }
+
+func TestLoadFromYAML_JSONConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "scenario.json")
+
+	content := `{
+  "global": {
+    "modality": "CT",
+    "total_size": "20MB",
+    "output": "./scenario",
+    "seed": 7,
+    "tags": {"InstitutionName": "JSON Hospital"}
+  },
+  "patients": [
+    {
+      "name": "Doe^Jane",
+      "id": "PAT001",
+      "studies": [
+        {
+          "description": "Chest CT",
+          "custom_tags": {"StationName": "CT02"},
+          "series": [
+            {"description": "Lung", "images": 4, "custom_tags": {"ProtocolName": "LUNG"}}
+          ]
+        }
+      ]
+    }
+  ]
+}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	state, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed for JSON config: %v", err)
+	}
+	if state.Global.Modality != "CT" || state.Global.Seed != 7 {
+		t.Errorf("Unexpected global config: %+v", state.Global)
+	}
+	if state.Global.CustomTags["InstitutionName"] != "JSON Hospital" {
+		t.Errorf("Expected global tag 'JSON Hospital', got %s", state.Global.CustomTags["InstitutionName"])
+	}
+
+	opts, err := ToGeneratorOptions(state)
+	if err != nil {
+		t.Fatalf("ToGeneratorOptions failed: %v", err)
+	}
+	if val, ok := opts.CustomTags.Get("InstitutionName"); !ok || val != "JSON Hospital" {
+		t.Errorf("Global tag not carried to the generator options: %v", opts.CustomTags)
+	}
+	study := opts.PredefinedPatients[0].Studies[0]
+	if val, ok := study.CustomTags.Get("StationName"); !ok || val != "CT02" {
+		t.Errorf("Study tag not carried to the generator options: %v", study.CustomTags)
+	}
+	if val, ok := study.Series[0].CustomTags.Get("ProtocolName"); !ok || val != "LUNG" {
+		t.Errorf("Series tag not carried to the generator options: %v", study.Series[0].CustomTags)
+	}
+	if opts.NumImages != 4 {
+		t.Errorf("Expected 4 images, got %d", opts.NumImages)
+	}
+}
+
+func TestLoadFromYAML_JSONUnknownField(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "typo.json")
+
+	content := `{"global": {"modality": "MR", "total_sise": "10MB"}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	if _, err := LoadFromYAML(configPath); err == nil {
+		t.Error("Expected error for unknown JSON field, got nil")
+	}
+}
+
+func TestSaveToYAML_JSONAndLoadBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "output.json")
+
+	original := &WizardState{
+		Global: types.GlobalConfig{
+			Modality:   "MR",
+			TotalSize:  "10MB",
+			OutputDir:  "./out",
+			CustomTags: map[string]string{"InstitutionName": "Saved Hospital"},
+		},
+		Patients: []types.PatientConfig{{
+			Name: "Doe^John",
+			ID:   "PAT002",
+			Studies: []types.StudyConfig{{
+				Description: "Brain MRI",
+				Series:      []types.SeriesConfig{{Description: "T1", ImageCount: 3}},
+			}},
+		}},
+	}
+	if err := SaveToYAML(original, configPath); err != nil {
+		t.Fatalf("SaveToYAML failed: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read saved config: %v", err)
+	}
+	if len(data) == 0 || data[0] != '{' {
+		t.Errorf("Expected a JSON document, got:\n%s", data)
+	}
+
+	loaded, err := LoadFromYAML(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromYAML failed: %v", err)
+	}
+	if !reflect.DeepEqual(original, loaded) {
+		t.Errorf("Roundtrip mismatch:\noriginal: %+v\nloaded:   %+v", original, loaded)
+	}
+}
+
+func TestToGeneratorOptions_UnknownTag(t *testing.T) {
+	state := &WizardState{
+		Global: types.GlobalConfig{Modality: "MR", TotalSize: "10MB"},
+		Patients: []types.PatientConfig{{
+			Studies: []types.StudyConfig{{
+				Series: []types.SeriesConfig{{ImageCount: 1, CustomTags: map[string]string{"ProtocolNmae": "T1"}}},
+			}},
+		}},
+	}
+
+	if _, err := ToGeneratorOptions(state); err == nil {
+		t.Error("Expected error for unknown series tag, got nil")
+	}
+}
+
+func TestToGeneratorOptions_WizardDates(t *testing.T) {
+	state := &WizardState{
+		Global: types.GlobalConfig{Modality: "MR", TotalSize: "1MB", OutputDir: t.TempDir(), Seed: 42},
+		Patients: []types.PatientConfig{{
+			Name:      "DOE^JANE",
+			ID:        "P001",
+			BirthDate: "1980-01-15",
+			Sex:       "F",
+			Studies: []types.StudyConfig{{
+				Description: "Brain MRI",
+				Date:        "2026-01-01",
+				Series:      []types.SeriesConfig{{Description: "T1", ImageCount: 2}},
+			}},
+		}},
+	}
+
+	opts, err := ToGeneratorOptions(state)
+	if err != nil {
+		t.Fatalf("ToGeneratorOptions failed: %v", err)
+	}
+	patient := opts.PredefinedPatients[0]
+	if patient.BirthDate != "19800115" || patient.Studies[0].Date != "20260101" {
+		t.Errorf("Expected DA dates 19800115 and 20260101, got %s and %s", patient.BirthDate, patient.Studies[0].Date)
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ds, err := dicom.ParseFile(files[0].Path, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse DICOM file: %v", err)
+	}
+	for _, want := range []struct {
+		tag   tag.Tag
+		value string
+	}{
+		{tag.StudyDate, "20260101"},
+		{tag.PatientBirthDate, "19800115"},
+	} {
+		elem, err := ds.FindElementByTag(want.tag)
+		if err != nil {
+			t.Fatalf("Missing %v: %v", want.tag, err)
+		}
+		if got := elem.Value.GetValue().([]string)[0]; got != want.value {
+			t.Errorf("Expected %v %s, got %s", want.tag, want.value, got)
+		}
+	}
+
+	// Saving the options as a config gives the wizard dates back
+	saved := FromGeneratorOptions(opts)
+	if got := saved.Patients[0].Studies[0].Date; got != "2026-01-01" {
+		t.Errorf("Expected saved study date 2026-01-01, got %s", got)
+	}
+	if got := saved.Patients[0].BirthDate; got != "1980-01-15" {
+		t.Errorf("Expected saved birth date 1980-01-15, got %s", got)
+	}
+}
@@ -1,6 +1,10 @@
 package wizard
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/mrsinham/dicomforge/cmd/dicomforge/wizard/types"
 	"github.com/mrsinham/dicomforge/internal/dicom"
 	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
@@ -27,13 +31,18 @@ func ToGeneratorOptions(s *WizardState) (dicom.GeneratorOptions, error) {
 		totalStudies = s.Global.NumPatients * s.Global.StudiesPerPatient
 	}
 
+	customTags, err := parseCustomTags(s.Global.CustomTags)
+	if err != nil {
+		return dicom.GeneratorOptions{}, fmt.Errorf("global tags: %w", err)
+	}
+
 	// Build PredefinedPatients from WizardState
 	predefined := make([]dicom.PredefinedPatient, len(s.Patients))
 	for i, p := range s.Patients {
 		patient := dicom.PredefinedPatient{
 			Name:      p.Name,
 			ID:        p.ID,
-			BirthDate: p.BirthDate,
+			BirthDate: toDA(p.BirthDate),
 			Sex:       p.Sex,
 			Studies:   make([]dicom.PredefinedStudy, len(p.Studies)),
 		}
@@ -41,7 +50,7 @@ func ToGeneratorOptions(s *WizardState) (dicom.GeneratorOptions, error) {
 		for j, st := range p.Studies {
 			study := dicom.PredefinedStudy{
 				Description:        st.Description,
-				Date:               st.Date,
+				Date:               toDA(st.Date),
 				AccessionNumber:    st.AccessionNumber,
 				Institution:        st.Institution,
 				Department:         st.Department,
@@ -50,6 +59,9 @@ func ToGeneratorOptions(s *WizardState) (dicom.GeneratorOptions, error) {
 				ReferringPhysician: st.ReferringPhysician,
 				Series:             make([]dicom.PredefinedSeries, len(st.Series)),
 			}
+			if study.CustomTags, err = parseCustomTags(st.CustomTags); err != nil {
+				return dicom.GeneratorOptions{}, fmt.Errorf("patient %d study %d tags: %w", i+1, j+1, err)
+			}
 
 			for k, ser := range st.Series {
 				study.Series[k] = dicom.PredefinedSeries{
@@ -58,6 +70,9 @@ func ToGeneratorOptions(s *WizardState) (dicom.GeneratorOptions, error) {
 					Orientation: ser.Orientation,
 					ImageCount:  ser.ImageCount,
 				}
+				if study.Series[k].CustomTags, err = parseCustomTags(ser.CustomTags); err != nil {
+					return dicom.GeneratorOptions{}, fmt.Errorf("patient %d study %d series %d tags: %w", i+1, j+1, k+1, err)
+				}
 			}
 
 			patient.Studies[j] = study
@@ -87,10 +102,48 @@ func ToGeneratorOptions(s *WizardState) (dicom.GeneratorOptions, error) {
 		NumPatients:        len(s.Patients),
 		Modality:           mod,
 		SeriesPerStudy:     seriesPerStudy,
+		CustomTags:         customTags,
 		PredefinedPatients: predefined,
 	}, nil
 }
 
+// parseCustomTags validates the tag overrides of a config file as --tag
+// flags are, returning nil when there are none.
+func parseCustomTags(tags map[string]string) (util.ParsedTags, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	flags := make([]string, 0, len(tags))
+	for name, value := range tags {
+		flags = append(flags, name+"="+value)
+	}
+	sort.Strings(flags)
+	return util.ParseTagFlags(flags)
+}
+
+// wizardDateLayout is the date format of the wizard and config files.
+const wizardDateLayout = "2006-01-02"
+
+// toDA converts a wizard date (YYYY-MM-DD) to a DICOM DA (YYYYMMDD). Other
+// values are returned unchanged, for the generator to validate.
+func toDA(date string) string {
+	t, err := time.Parse(wizardDateLayout, date)
+	if err != nil {
+		return date
+	}
+	return t.Format(util.LayoutDA)
+}
+
+// fromDA converts a DICOM DA back to a wizard date, returning other values
+// unchanged.
+func fromDA(date string) string {
+	t, err := time.Parse(util.LayoutDA, date)
+	if err != nil {
+		return date
+	}
+	return t.Format(wizardDateLayout)
+}
+
 // FromGeneratorOptions creates a WizardState from GeneratorOptions.
 // Used for --save-config to export CLI options as YAML.
 func FromGeneratorOptions(opts dicom.GeneratorOptions) *WizardState {
@@ -119,6 +172,7 @@ func FromGeneratorOptions(opts dicom.GeneratorOptions) *WizardState {
 			NumPatients:       numPatients,
 			StudiesPerPatient: studiesPerPatient,
 			SeriesPerStudy:    seriesPerStudy,
+			CustomTags:        copyMap(opts.CustomTags),
 		},
 	}
 
@@ -129,7 +183,7 @@ func FromGeneratorOptions(opts dicom.GeneratorOptions) *WizardState {
 			patient := types.PatientConfig{
 				Name:      p.Name,
 				ID:        p.ID,
-				BirthDate: p.BirthDate,
+				BirthDate: fromDA(p.BirthDate),
 				Sex:       p.Sex,
 				Studies:   make([]types.StudyConfig, len(p.Studies)),
 			}
@@ -137,13 +191,14 @@ func FromGeneratorOptions(opts dicom.GeneratorOptions) *WizardState {
 			for j, st := range p.Studies {
 				study := types.StudyConfig{
 					Description:        st.Description,
-					Date:               st.Date,
+					Date:               fromDA(st.Date),
 					AccessionNumber:    st.AccessionNumber,
 					Institution:        st.Institution,
 					Department:         st.Department,
 					BodyPart:           st.BodyPart,
 					Priority:           st.Priority,
 					ReferringPhysician: st.ReferringPhysician,
+					CustomTags:         copyMap(st.CustomTags),
 					Series:             make([]types.SeriesConfig, len(st.Series)),
 				}
 
@@ -153,6 +208,7 @@ func FromGeneratorOptions(opts dicom.GeneratorOptions) *WizardState {
 						Protocol:    ser.Protocol,
 						Orientation: ser.Orientation,
 						ImageCount:  ser.ImageCount,
+						CustomTags:  copyMap(ser.CustomTags),
 					}
 				}
 
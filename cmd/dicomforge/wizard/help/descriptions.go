@@ -17,7 +17,8 @@ CT (Computed Tomography) - Cross-sectional X-ray imaging
 CR (Computed Radiography) - Digital X-ray plates
 DX (Digital X-Ray) - Direct digital radiography
 US (Ultrasound) - Sound wave imaging
-MG (Mammography) - Breast X-ray imaging`,
+MG (Mammography) - Breast X-ray imaging
+RF (Radiofluoroscopy) - Real-time X-ray with dose tracking`,
 	},
 	"total_images": {
 		Title:       "TOTAL IMAGES",
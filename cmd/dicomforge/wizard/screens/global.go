@@ -76,6 +76,7 @@ func NewGlobalScreen(config *types.GlobalConfig) *GlobalScreen {
 					huh.NewOption("DX - Digital X-Ray", "DX"),
 					huh.NewOption("US - Ultrasound", "US"),
 					huh.NewOption("MG - Mammography", "MG"),
+					huh.NewOption("RF - Radiofluoroscopy", "RF"),
 				).
 				Value(&config.Modality),
 
@@ -121,6 +121,7 @@ func generateDefaultSeriesDescription(modality, orientation string) string {
 		"DX": "Standard",
 		"US": "Standard",
 		"MG": "Standard",
+		"RF": "Standard",
 	}
 
 	seq := modalitySequence[modality]
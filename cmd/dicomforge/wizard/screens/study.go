@@ -165,6 +165,7 @@ func generateDefaultStudyDescription(modality, bodyPart string) string {
 		"DX": "Digital X-Ray",
 		"US": "Ultrasound",
 		"MG": "Mammography",
+		"RF": "Fluoroscopy",
 	}
 
 	bodyPartNames := map[string]string{
@@ -17,6 +17,7 @@ type GlobalConfig struct {
 	NumPatients       int
 	StudiesPerPatient int
 	SeriesPerStudy    int
+	CustomTags        map[string]string // Tag overrides of every study and series
 }
 
 // PatientConfig holds configuration for a single patient.
@@ -816,7 +816,7 @@ func (w *Wizard) startGeneration() (tea.Model, tea.Cmd) {
 		}
 
 		// Organize into DICOMDIR structure (PT/ST/SE hierarchy)
-		if err := dicom.OrganizeFilesIntoDICOMDIR(opts.OutputDir, files, true); err != nil {
+		if _, err := dicom.OrganizeFiles(opts.OutputDir, files, dicom.OrganizeOptions{Fanout: opts.ShardFanout, UIDRoot: opts.UIDRoot, Quiet: true}); err != nil {
 			w.progressChan <- screens.ProgressMsg{Current: -2, Total: -2, Path: fmt.Sprintf("creating DICOMDIR: %v", err)}
 			return
 		}
@@ -328,6 +328,7 @@ func TestToGeneratorOptions_AllModalities(t *testing.T) {
 		{"CR", modalities.CR},
 		{"DX", modalities.DX},
 		{"MG", modalities.MG},
+		{"RF", modalities.RF},
 	}
 
 	for _, tc := range modalityTests {
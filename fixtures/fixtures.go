@@ -0,0 +1,224 @@
+// Package fixtures provides canonical DICOM files as byte slices, so that
+// parser unit tests (in this or other repositories) can embed byte-level
+// fixtures without running the generator.
+//
+// Fixtures are deterministic: the same function always returns the same bytes.
+package fixtures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Transfer syntaxes available for fixtures
+const (
+	ImplicitVRLittleEndian = "1.2.840.10008.1.2"
+	ExplicitVRLittleEndian = "1.2.840.10008.1.2.1"
+	ExplicitVRBigEndian    = "1.2.840.10008.1.2.2"
+)
+
+// Size of the fixture image (16-bit grayscale)
+const (
+	Rows    = 4
+	Columns = 4
+)
+
+// secondaryCaptureImageStorage is the SOP class of the fixtures
+const secondaryCaptureImageStorage = "1.2.840.10008.5.1.4.1.1.7"
+
+// corruptionSeed seeds the vendor corruption elements of the fixtures
+const corruptionSeed = 1
+
+// TransferSyntaxes returns the transfer syntaxes available for fixtures.
+func TransferSyntaxes() []string {
+	return []string{ImplicitVRLittleEndian, ExplicitVRLittleEndian, ExplicitVRBigEndian}
+}
+
+// CorruptionTypes returns the corruption types available for fixtures
+// (same names as the --corrupt flag). shared-series-uid and
+// mixed-patient-study are left out: they need several files.
+func CorruptionTypes() []string {
+	var names []string
+	for _, t := range corruption.ValidCorruptionTypes() {
+		if !multiFile(t) {
+			names = append(names, string(t))
+		}
+	}
+	return names
+}
+
+// Minimal returns a valid minimal DICOM file: a 4x4 16-bit Secondary Capture
+// image in Explicit VR Little Endian.
+func Minimal() []byte {
+	data, err := MinimalWithTransferSyntax(ExplicitVRLittleEndian)
+	if err != nil {
+		panic(fmt.Sprintf("fixtures: minimal file: %v", err))
+	}
+	return data
+}
+
+// MinimalWithTransferSyntax returns the minimal DICOM file encoded with the
+// given transfer syntax UID.
+func MinimalWithTransferSyntax(transferSyntaxUID string) ([]byte, error) {
+	if !isSupported(transferSyntaxUID) {
+		return nil, fmt.Errorf("unsupported transfer syntax %q (valid: %s)",
+			transferSyntaxUID, strings.Join(TransferSyntaxes(), ", "))
+	}
+	return write(minimalElements(transferSyntaxUID))
+}
+
+// Header returns the file header of the minimal DICOM file for the given
+// transfer syntax UID: the 128-byte preamble, the "DICM" prefix and the File
+// Meta Information group (always Explicit VR Little Endian).
+func Header(transferSyntaxUID string) ([]byte, error) {
+	data, err := MinimalWithTransferSyntax(transferSyntaxUID)
+	if err != nil {
+		return nil, err
+	}
+	// (0002,0000) UL FileMetaInformationGroupLength follows the prefix
+	const groupLengthValue = 128 + 4 + 8
+	length := binary.LittleEndian.Uint32(data[groupLengthValue : groupLengthValue+4])
+	return data[:groupLengthValue+4+int(length)], nil
+}
+
+// Corrupted returns the minimal DICOM file (Explicit VR Little Endian) with
+// the vendor corruption of the given type: "siemens-csa", "ge-private",
+// "philips-private", "malformed-lengths", "sop-class-mismatch",
+// "empty-required", "odd-lengths", "invalid-uids", "legacy-groups",
+// "charset-mismatch" or "truncated" (cut mid-pixel-data).
+func Corrupted(corruptionType string) ([]byte, error) {
+	types, err := corruption.ParseTypes(corruptionType)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) != 1 {
+		return nil, fmt.Errorf("expected a single corruption type, got %q", corruptionType)
+	}
+	if multiFile(types[0]) {
+		return nil, fmt.Errorf("%s needs several files", corruptionType)
+	}
+
+	config := corruption.Config{Types: types}
+	applicator := corruption.NewApplicator(config, rand.New(rand.NewPCG(corruptionSeed, corruptionSeed)))
+	elements := append(minimalElements(ExplicitVRLittleEndian), applicator.GenerateCorruptionElements()...)
+	elements = applicator.ApplyElementCorruption(elements)
+
+	data, err := write(elements, dicom.SkipVRVerification(), dicom.SkipValueTypeVerification())
+	if err != nil {
+		return nil, err
+	}
+	if applicator.HasMalformedLengths() {
+		corruption.PatchMalformedLengthsData(data)
+	}
+	if applicator.HasOddLengths() {
+		data, _ = corruption.PatchOddLengthsData(data)
+	}
+	if config.HasType(corruption.Truncated) {
+		n, err := corruption.TruncatedLength(data, config.Truncation)
+		if err != nil {
+			return nil, err
+		}
+		data = data[:n]
+	}
+	return data, nil
+}
+
+// multiFile reports whether a corruption type relates several files, and
+// cannot show in a single fixture.
+func multiFile(t corruption.CorruptionType) bool {
+	return t == corruption.SharedSeriesUID || t == corruption.MixedPatientStudy
+}
+
+// isSupported reports whether fixtures can be encoded with the transfer syntax.
+func isSupported(transferSyntaxUID string) bool {
+	for _, ts := range TransferSyntaxes() {
+		if ts == transferSyntaxUID {
+			return true
+		}
+	}
+	return false
+}
+
+// minimalElements returns the elements of the minimal file.
+func minimalElements(transferSyntaxUID string) []*dicom.Element {
+	studyUID := util.GenerateDeterministicUID("fixtures_study")
+	seriesUID := util.GenerateDeterministicUID("fixtures_series")
+	sopInstanceUID := util.GenerateDeterministicUID("fixtures_instance")
+
+	// Horizontal ramp, so that byte order and pixel layout are both visible
+	nativeFrame := frame.NewNativeFrame[uint16](16, Rows, Columns, Rows*Columns, 1)
+	for i := range nativeFrame.RawData {
+		nativeFrame.RawData[i] = uint16(0x0100*(i%Columns) + i)
+	}
+	pixelData := dicom.PixelDataInfo{
+		Frames: []*frame.Frame{{Encapsulated: false, NativeData: nativeFrame}},
+	}
+
+	return []*dicom.Element{
+		mustNewElement(tag.MediaStorageSOPClassUID, []string{secondaryCaptureImageStorage}),
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.TransferSyntaxUID, []string{transferSyntaxUID}),
+		mustNewElement(tag.SpecificCharacterSet, []string{"ISO_IR 100"}),
+		mustNewElement(tag.SOPClassUID, []string{secondaryCaptureImageStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.StudyDate, []string{"20240101"}),
+		mustNewElement(tag.StudyTime, []string{"120000"}),
+		mustNewElement(tag.AccessionNumber, []string{"FIXTURE"}),
+		mustNewElement(tag.Modality, []string{"OT"}),
+		mustNewElement(tag.ConversionType, []string{"WSD"}),
+		mustNewElement(tag.ReferringPhysicianName, []string{""}),
+		mustNewElement(tag.PatientName, []string{"Fixture^Minimal"}),
+		mustNewElement(tag.PatientID, []string{"FIXTURE001"}),
+		mustNewElement(tag.PatientBirthDate, []string{"19700101"}),
+		mustNewElement(tag.PatientSex, []string{"O"}),
+		mustNewElement(tag.StudyInstanceUID, []string{studyUID}),
+		mustNewElement(tag.SeriesInstanceUID, []string{seriesUID}),
+		mustNewElement(tag.StudyID, []string{"1"}),
+		mustNewElement(tag.SeriesNumber, []string{"1"}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+		mustNewElement(tag.SamplesPerPixel, []int{1}),
+		mustNewElement(tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+		mustNewElement(tag.Rows, []int{Rows}),
+		mustNewElement(tag.Columns, []int{Columns}),
+		mustNewElement(tag.BitsAllocated, []int{16}),
+		mustNewElement(tag.BitsStored, []int{16}),
+		mustNewElement(tag.HighBit, []int{15}),
+		mustNewElement(tag.PixelRepresentation, []int{0}),
+		mustNewElement(tag.PixelData, pixelData),
+	}
+}
+
+// write encodes elements, sorted by tag, as a DICOM file.
+func write(elements []*dicom.Element, opts ...dicom.WriteOption) ([]byte, error) {
+	sort.SliceStable(elements, func(i, j int) bool {
+		if elements[i].Tag.Group != elements[j].Tag.Group {
+			return elements[i].Tag.Group < elements[j].Tag.Group
+		}
+		return elements[i].Tag.Element < elements[j].Tag.Element
+	})
+
+	var buf bytes.Buffer
+	if err := dicom.Write(&buf, dicom.Dataset{Elements: elements}, opts...); err != nil {
+		return nil, fmt.Errorf("write fixture: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mustNewElement creates a DICOM element or panics (fixture values are constant).
+func mustNewElement(t tag.Tag, data any) *dicom.Element {
+	elem, err := dicom.NewElement(t, data)
+	if err != nil {
+		panic(fmt.Sprintf("fixtures: create element %v: %v", t, err))
+	}
+	return elem
+}
@@ -0,0 +1,148 @@
+package fixtures
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func parse(t *testing.T, data []byte) dicom.Dataset {
+	t.Helper()
+	ds, err := dicom.Parse(bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("parse fixture: %v", err)
+	}
+	return ds
+}
+
+func TestMinimal(t *testing.T) {
+	data := Minimal()
+	if string(data[128:132]) != "DICM" {
+		t.Fatalf("missing DICM prefix")
+	}
+	if !bytes.Equal(data, Minimal()) {
+		t.Error("Minimal() is not deterministic")
+	}
+
+	ds := parse(t, data)
+	name, err := ds.FindElementByTag(tag.PatientName)
+	if err != nil || name.Value.GetValue().([]string)[0] != "Fixture^Minimal" {
+		t.Errorf("unexpected PatientName: %v", name)
+	}
+}
+
+func TestMinimalWithTransferSyntax(t *testing.T) {
+	for _, ts := range TransferSyntaxes() {
+		t.Run(ts, func(t *testing.T) {
+			data, err := MinimalWithTransferSyntax(ts)
+			if err != nil {
+				t.Fatalf("MinimalWithTransferSyntax failed: %v", err)
+			}
+			ds := parse(t, data)
+
+			tsElem, err := ds.FindElementByTag(tag.TransferSyntaxUID)
+			if err != nil || tsElem.Value.GetValue().([]string)[0] != ts {
+				t.Errorf("TransferSyntaxUID = %v, want %s", tsElem, ts)
+			}
+
+			pixelElem, err := ds.FindElementByTag(tag.PixelData)
+			if err != nil {
+				t.Fatalf("missing PixelData: %v", err)
+			}
+			native := pixelElem.Value.GetValue().(dicom.PixelDataInfo).Frames[0].NativeData.(*frame.NativeFrame[uint16])
+			if got := native.RawData[Columns+1]; got != 0x0105 {
+				t.Errorf("pixel (1,1) = %#x, want 0x0105", got)
+			}
+
+			// Last pixel (0x030f) is the last element of the file
+			last := data[len(data)-2:]
+			want := []byte{0x0f, 0x03}
+			if ts == ExplicitVRBigEndian {
+				want = []byte{0x03, 0x0f}
+			}
+			if !bytes.Equal(last, want) {
+				t.Errorf("last pixel bytes = % x, want % x", last, want)
+			}
+		})
+	}
+
+	if _, err := MinimalWithTransferSyntax("1.2.840.10008.1.2.4.50"); err == nil {
+		t.Error("expected an error for an unsupported transfer syntax")
+	}
+}
+
+func TestHeader(t *testing.T) {
+	for _, ts := range TransferSyntaxes() {
+		header, err := Header(ts)
+		if err != nil {
+			t.Fatalf("Header(%s) failed: %v", ts, err)
+		}
+		full, _ := MinimalWithTransferSyntax(ts)
+		if !bytes.HasPrefix(full, header) || len(header) >= len(full) {
+			t.Errorf("Header(%s) is not a strict prefix of the file", ts)
+		}
+		if !bytes.Contains(header, []byte(ts)) {
+			t.Errorf("Header(%s) does not contain the transfer syntax", ts)
+		}
+		// The dataset starts right after the header, with group 0008
+		group := []byte{0x08, 0x00}
+		if ts == ExplicitVRBigEndian {
+			group = []byte{0x00, 0x08}
+		}
+		if !bytes.Equal(full[len(header):len(header)+2], group) {
+			t.Errorf("Header(%s) does not end at the start of the dataset: % x", ts, full[len(header):len(header)+4])
+		}
+	}
+}
+
+func TestCorrupted(t *testing.T) {
+	minimal := Minimal()
+	for _, name := range CorruptionTypes() {
+		t.Run(name, func(t *testing.T) {
+			data, err := Corrupted(name)
+			if err != nil {
+				t.Fatalf("Corrupted failed: %v", err)
+			}
+			if string(data[128:132]) != "DICM" {
+				t.Fatalf("missing DICM prefix")
+			}
+			if bytes.Equal(data, minimal) {
+				t.Error("corrupted fixture is identical to the minimal file")
+			}
+			again, _ := Corrupted(name)
+			if !bytes.Equal(data, again) {
+				t.Error("Corrupted() is not deterministic")
+			}
+		})
+	}
+
+	// Malformed lengths: (0070,0253) FL with a length of 7
+	data, _ := Corrupted("malformed-lengths")
+	if !bytes.Contains(data, []byte{0x70, 0x00, 0x53, 0x02, 'F', 'L', 0x07, 0x00}) {
+		t.Error("malformed-lengths fixture misses the odd-length FL element")
+	}
+
+	// Odd lengths: (0020,4000) LT with a length of 15
+	data, _ = Corrupted("odd-lengths")
+	if !bytes.Contains(data, []byte{0x20, 0x00, 0x00, 0x40, 'L', 'T', 0x0F, 0x00}) {
+		t.Error("odd-lengths fixture misses the unpadded LT element")
+	}
+
+	// SOP class mismatch: the file meta claims another class than the dataset
+	data, _ = Corrupted("sop-class-mismatch")
+	ds := parse(t, data)
+	claimed, _ := ds.FindElementByTag(tag.MediaStorageSOPClassUID)
+	actual, _ := ds.FindElementByTag(tag.SOPClassUID)
+	if claimed == nil || actual == nil || claimed.Value.String() == actual.Value.String() {
+		t.Error("sop-class-mismatch fixture has matching SOP classes")
+	}
+
+	for _, invalid := range []string{"unknown", "all", "siemens-csa,ge-private", "shared-series-uid", "mixed-patient-study"} {
+		if _, err := Corrupted(invalid); err == nil {
+			t.Errorf("Corrupted(%q) should fail", invalid)
+		}
+	}
+}
@@ -6,6 +6,7 @@ toolchain go1.24.7
 
 require (
 	github.com/cucumber/godog v0.15.1
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/suyashkumar/dicom v1.1.0
 	golang.org/x/image v0.34.0
 )
@@ -0,0 +1,290 @@
+package dicom
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// AI result object types
+const (
+	AIResultSR = "sr" // TID 1500 measurement report (Comprehensive SR)
+	AIResultSC = "sc" // Secondary Capture with heatmap overlay
+)
+
+// SecondaryCaptureImageStorage is the SOP Class UID of Secondary Capture images.
+const SecondaryCaptureImageStorage = "1.2.840.10008.5.1.4.1.1.7"
+
+// Simulated AI algorithm identification
+const (
+	aiAlgorithmName    = "DICOMFORGE LESION DETECT"
+	aiAlgorithmVersion = "1.0.0"
+)
+
+// ParseAIResultTypes parses a comma-separated list of AI result types
+// ("sr", "sc" or "all").
+func ParseAIResultTypes(s string) ([]string, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return nil, nil
+	}
+	if s == "all" {
+		return []string{AIResultSR, AIResultSC}, nil
+	}
+
+	var types []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case AIResultSR, AIResultSC:
+			types = append(types, part)
+		default:
+			return nil, fmt.Errorf("unknown AI result type %q (valid: sr, sc, all)", part)
+		}
+	}
+	return types, nil
+}
+
+// hasAIResult reports whether the given AI result type is enabled.
+func hasAIResult(types []string, t string) bool {
+	for _, enabled := range types {
+		if enabled == t {
+			return true
+		}
+	}
+	return false
+}
+
+// aiFindings converts the lesions inserted in a study into AI findings.
+func aiFindings(opts GeneratorOptions, study studyRecord) []sr.Finding {
+	series := study.series[study.lesionSeries]
+	findings := make([]sr.Finding, len(study.lesions))
+	for i, l := range study.lesions {
+		findings[i] = sr.Finding{
+			TrackingID:  l.trackingID,
+			TrackingUID: opts.uid(fmt.Sprintf("%s_study_%d_lesion_%d", opts.OutputDir, study.studyNum, i+1)),
+			Image: sr.ImageRef{
+				SOPClassUID:    series.sopClassUID,
+				SOPInstanceUID: series.instanceUIDs[l.centerSlice-1],
+			},
+			CenterX:     l.centerX,
+			CenterY:     l.centerY,
+			Radius:      l.radius,
+			DiameterMM:  2 * l.radius * study.pixelSpacing,
+			Confidence:  l.confidence,
+			Uncertainty: l.uncertainty,
+		}
+	}
+	return findings
+}
+
+// writeAIResultSR writes a Comprehensive SR with the AI findings of a study.
+func writeAIResultSR(opts GeneratorOptions, study studyRecord, seriesNumber int) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+
+	lesionSeries := study.series[study.lesionSeries]
+	doc := sr.Document{
+		Root:        sr.AIFindingsReport(aiAlgorithmName, aiAlgorithmVersion, aiFindings(opts, study)),
+		TemplateID:  "1500",
+		ContentDate: study.studyDate,
+		ContentTime: study.studyTime,
+		// Only the series the lesions were found in is evidence
+		Evidence: []sr.Evidence{lesionSeries.evidence(study.studyUID)},
+	}
+
+	elements := study.headerElements("SR", seriesUID, seriesNumber, "AI Results")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{sr.ComprehensiveSRStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+	)
+	elements = append(elements, doc.Elements()...)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "SR", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
+
+// writeAIHeatmapSCs writes one Secondary Capture per image holding the central
+// slice of a lesion: the source image with a heatmap of the AI findings
+// blended on top, weighted by detection confidence.
+func writeAIHeatmapSCs(opts GeneratorOptions, study studyRecord, seriesNumber int) ([]GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	lesionSeries := study.series[study.lesionSeries]
+
+	// One heatmap per distinct central slice, in slice order
+	var slices []int
+	seen := make(map[int]bool)
+	for _, l := range study.lesions {
+		if !seen[l.centerSlice] {
+			seen[l.centerSlice] = true
+			slices = append(slices, l.centerSlice)
+		}
+	}
+
+	var files []GeneratedFile
+	for i, slice := range slices {
+		instanceNumber := i + 1
+		sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_%d", opts.OutputDir, study.studyNum, seriesNumber, instanceNumber))
+
+		gray, width, height := readGrayscale8(lesionSeries.filePaths[slice-1])
+		nativeFrame := frame.NewNativeFrame[uint8](8, height, width, width*height, 3)
+		var comments []string
+		for _, l := range study.lesions {
+			if l.contains(slice) {
+				comments = append(comments, fmt.Sprintf("%s: confidence %.0f%%", l.trackingID, l.confidence*100))
+			}
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b := heatmapPixel(gray[y*width+x], heatAt(study.lesions, slice, float64(x), float64(y)))
+				nativeFrame.RawData[(y*width+x)*3] = r
+				nativeFrame.RawData[(y*width+x)*3+1] = g
+				nativeFrame.RawData[(y*width+x)*3+2] = b
+			}
+		}
+
+		elements := study.headerElements("OT", seriesUID, seriesNumber, "AI Heatmap")
+		elements = append(elements,
+			mustNewElement(tag.SOPClassUID, []string{SecondaryCaptureImageStorage}),
+			mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+			mustNewElement(tag.InstanceNumber, []string{util.FormatIS(instanceNumber)}),
+			mustNewElement(tag.ImageType, []string{"DERIVED", "SECONDARY"}),
+			mustNewElement(tag.ConversionType, []string{"WSD"}),
+			mustNewElement(tag.DerivationDescription, []string{aiAlgorithmName + " " + aiAlgorithmVersion + " heatmap"}),
+			mustNewElement(tag.SourceImageSequence, [][]*dicom.Element{{
+				mustNewElement(tag.ReferencedSOPClassUID, []string{lesionSeries.sopClassUID}),
+				mustNewElement(tag.ReferencedSOPInstanceUID, []string{lesionSeries.instanceUIDs[slice-1]}),
+			}}),
+			mustNewElement(tag.ImageComments, []string{strings.Join(comments, "; ")}),
+			mustNewElement(tag.BurnedInAnnotation, []string{"NO"}),
+			mustNewElement(tag.Rows, []int{height}),
+			mustNewElement(tag.Columns, []int{width}),
+			mustNewElement(tag.SamplesPerPixel, []int{3}),
+			mustNewElement(tag.PhotometricInterpretation, []string{"RGB"}),
+			mustNewElement(tag.PlanarConfiguration, []int{0}),
+			mustNewElement(tag.BitsAllocated, []int{8}),
+			mustNewElement(tag.BitsStored, []int{8}),
+			mustNewElement(tag.HighBit, []int{7}),
+			mustNewElement(tag.PixelRepresentation, []int{0}),
+			mustNewElement(tag.PixelData, dicom.PixelDataInfo{
+				Frames: []*frame.Frame{{Encapsulated: false, NativeData: nativeFrame}},
+			}),
+		)
+		sr.SortElements(elements)
+
+		path := derivedFilePath(opts, "SC", study.studyNum, seriesNumber, instanceNumber)
+		if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+			return nil, err
+		}
+		files = append(files, study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, instanceNumber))
+	}
+
+	return files, nil
+}
+
+// heatAt returns the AI heat (0-1) at a pixel: a Gaussian per lesion present
+// on the slice, scaled by the lesion's detection confidence.
+func heatAt(lesions []lesion, slice int, x, y float64) float64 {
+	heat := 0.0
+	for _, l := range lesions {
+		if !l.contains(slice) {
+			continue
+		}
+		sigma := l.radius * 0.8
+		dx, dy := x-l.centerX, y-l.centerY
+		heat = math.Max(heat, l.confidence*math.Exp(-(dx*dx+dy*dy)/(2*sigma*sigma)))
+	}
+	return heat
+}
+
+// heatmapPixel blends a "jet" colormap of heat over a grayscale value.
+func heatmapPixel(gray uint8, heat float64) (r, g, b uint8) {
+	if heat < 0.05 {
+		return gray, gray, gray
+	}
+	cr := math.Min(1, math.Max(0, 1.5-math.Abs(4*heat-3)))
+	cg := math.Min(1, math.Max(0, 1.5-math.Abs(4*heat-2)))
+	cb := math.Min(1, math.Max(0, 1.5-math.Abs(4*heat-1)))
+	alpha := 0.6 * heat
+	blend := func(c float64) uint8 {
+		return uint8(float64(gray)*(1-alpha) + c*255*alpha)
+	}
+	return blend(cr), blend(cg), blend(cb)
+}
+
+// readGrayscale8 reads the first frame of a generated image rescaled to 8 bits,
+// decoding JPEG Baseline frames. Images that cannot be parsed (e.g.,
+// intentionally corrupted) yield a black background of the size found in the
+// file, or 1x1.
+func readGrayscale8(path string) ([]uint8, int, int) {
+	ds, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		return make([]uint8, 1), 1, 1
+	}
+	elem, err := ds.FindElementByTag(tag.PixelData)
+	if err != nil {
+		return make([]uint8, 1), 1, 1
+	}
+	info := dicom.MustGetPixelDataInfo(elem.Value)
+	if len(info.Frames) == 0 {
+		return make([]uint8, 1), 1, 1
+	}
+	if info.Frames[0].Encapsulated {
+		img, err := jpeg.Decode(bytes.NewReader(info.Frames[0].EncapsulatedData.Data))
+		if err != nil {
+			return make([]uint8, 1), 1, 1
+		}
+		bounds := img.Bounds()
+		gray := image.NewGray(bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				gray.Set(x, y, img.At(x, y))
+			}
+		}
+		return gray.Pix, bounds.Dx(), bounds.Dy()
+	}
+	native := info.Frames[0].NativeData
+	width, height := native.Cols(), native.Rows()
+
+	values := make([]int, width*height)
+	switch raw := native.RawDataSlice().(type) {
+	case []uint8:
+		for i := range values {
+			values[i] = int(raw[i*native.SamplesPerPixel()])
+		}
+	case []uint16:
+		for i := range values {
+			values[i] = int(raw[i*native.SamplesPerPixel()])
+		}
+	default:
+		return make([]uint8, width*height), width, height
+	}
+
+	lo, hi := math.MaxInt, math.MinInt
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+
+	gray := make([]uint8, width*height)
+	if hi > lo {
+		for i, v := range values {
+			gray[i] = uint8((v - lo) * 255 / (hi - lo))
+		}
+	}
+	return gray, width, height
+}
@@ -0,0 +1,46 @@
+package dicom
+
+import (
+	"fmt"
+	"time"
+
+	dfimage "github.com/mrsinham/dicomforge/internal/image"
+	"github.com/suyashkumar/dicom/pkg/frame"
+)
+
+// imageAnnotations returns the annotations burnt into an image, as a scanner
+// shows them: the series description and number at the top left, the study
+// date at the top right, the instance in its series at the bottom left and
+// the slice location at the bottom right.
+func imageAnnotations(seriesDescription string, seriesNumber int, studyDate string, instance, images int, sliceLocation float64) dfimage.Annotations {
+	date := studyDate
+	if t, err := time.Parse("20060102", studyDate); err == nil {
+		date = t.Format("2006-01-02")
+	}
+	return dfimage.Annotations{
+		TopLeft:     []string{seriesDescription, fmt.Sprintf("Se: %d", seriesNumber)},
+		TopRight:    []string{date},
+		BottomLeft:  []string{fmt.Sprintf("Im: %d/%d", instance, images)},
+		BottomRight: []string{fmt.Sprintf("SL: %.1f", sliceLocation)},
+	}
+}
+
+// drawAnnotations8 burns annotations, rendered by dfimage.RenderAnnotations,
+// into the corners of a uint8 frame.
+func drawAnnotations8(nativeFrame *frame.NativeFrame[uint8], width, height int, annotations []dfimage.AnnotationBlock) {
+	for _, block := range annotations {
+		forEachOverlayPixel(block.Levels, block.Region, width, height, func(offset int, gray uint8) {
+			nativeFrame.RawData[offset] = gray
+		})
+	}
+}
+
+// drawAnnotations16 burns annotations, rendered by dfimage.RenderAnnotations,
+// into the corners of a uint16 frame, from black to white, stored values.
+func drawAnnotations16(nativeFrame *frame.NativeFrame[uint16], width, height int, annotations []dfimage.AnnotationBlock, black, white float64) {
+	for _, block := range annotations {
+		forEachOverlayPixel(block.Levels, block.Region, width, height, func(offset int, gray uint8) {
+			nativeFrame.RawData[offset] = overlayValue(gray, black, white)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package dicom
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	dfimage "github.com/mrsinham/dicomforge/internal/image"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestImageAnnotations(t *testing.T) {
+	got := imageAnnotations("T1 SAG", 2, "20240131", 7, 40, -12.25)
+	if want := []string{"T1 SAG", "Se: 2"}; !reflect.DeepEqual(got.TopLeft, want) {
+		t.Errorf("top left = %q, want %q", got.TopLeft, want)
+	}
+	if want := []string{"2024-01-31"}; !reflect.DeepEqual(got.TopRight, want) {
+		t.Errorf("top right = %q, want %q", got.TopRight, want)
+	}
+	if want := []string{"Im: 7/40"}; !reflect.DeepEqual(got.BottomLeft, want) {
+		t.Errorf("bottom left = %q, want %q", got.BottomLeft, want)
+	}
+	if want := []string{"SL: -12.2"}; !reflect.DeepEqual(got.BottomRight, want) {
+		t.Errorf("bottom right = %q, want %q", got.BottomRight, want)
+	}
+
+	// Edge case dates are shown as they are
+	if got := imageAnnotations("", 1, "1900", 1, 1, 0).TopRight; got[0] != "1900" {
+		t.Errorf("top right = %q, want the date as is", got)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	// Brightest value of the top left corner of the first CT image
+	corner := func(annotations bool) int {
+		files, err := GenerateDICOMSeries(GeneratorOptions{
+			NumImages:   1,
+			TotalSize:   "1MB",
+			Modality:    modalities.CT,
+			Annotations: annotations,
+			OutputDir:   t.TempDir(),
+			Seed:        42,
+			NumStudies:  1,
+			Quiet:       true,
+		})
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		ds, err := dicom.ParseFile(files[0].Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixelData, err := ds.FindElementByTag(tag.PixelData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		native, err := dicom.MustGetPixelDataInfo(pixelData.Value).Frames[0].GetNativeFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixels, cols := native.RawDataSlice().([]uint16), native.Cols()
+		brightest := 0
+		for y := 0; y < 30; y++ {
+			for x := 0; x < 60; x++ {
+				brightest = max(brightest, int(pixels[y*cols+x]))
+			}
+		}
+		return brightest
+	}
+
+	// Air around the body, white text once annotated
+	if got := corner(false); got > 100 {
+		t.Errorf("corner up to %d without annotations, want air", got)
+	}
+	if got := corner(true); got != huMax+1024 {
+		t.Errorf("corner up to %d with annotations, want white (%d)", got, huMax+1024)
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:  2,
+		TotalSize:  "2MB",
+		Modality:   modalities.CT,
+		QRCode:     true,
+		OutputDir:  t.TempDir(),
+		Seed:       42,
+		NumStudies: 1,
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	// The code of each image is its SOPInstanceUID, from air to white
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixelData, err := ds.FindElementByTag(tag.PixelData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		native, err := dicom.MustGetPixelDataInfo(pixelData.Value).Frames[0].GetNativeFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixels, cols, rows := native.RawDataSlice().([]uint16), native.Cols(), native.Rows()
+		blocks, err := dfimage.RenderAnnotations(cols, rows, dfimage.Annotations{QRCode: f.SOPInstanceUID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := blocks[0]
+		if code.Region.Max.X > cols || code.Region.Max.Y > rows {
+			t.Fatalf("QR code at %v out of %dx%d image", code.Region, cols, rows)
+		}
+		for i, gray := range code.Levels {
+			x, y := code.Region.Min.X+i%code.Region.Dx(), code.Region.Min.Y+i/code.Region.Dx()
+			want := uint16(huAir + 1024)
+			if gray == 255 {
+				want = huMax + 1024
+			}
+			if got := pixels[y*cols+x]; got != want {
+				t.Fatalf("%s: pixel %d, %d is %d, want %d", f.Path, x, y, got, want)
+			}
+		}
+	}
+}
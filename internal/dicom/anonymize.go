@@ -0,0 +1,528 @@
+package dicom
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// anonymizeMethod is the DeidentificationMethod written in anonymized files
+const anonymizeMethod = "DICOMFORGE BASIC APPLICATION CONFIDENTIALITY PROFILE"
+
+// Dummy values: the names of the other persons, and the dates and times of
+// the generated de-identified data
+const (
+	anonymizeDummyPerson = "ANONYMOUS"
+	anonymizeDummyDate   = "19000101"
+	anonymizeDummyTime   = "000000"
+)
+
+// Codes of the profile and its options, from CID 7050
+var (
+	basicProfileCode    = sr.Code{Value: "113100", Scheme: "DCM", Meaning: "Basic Application Confidentiality Profile"}
+	retainUIDsCode      = sr.Code{Value: "113110", Scheme: "DCM", Meaning: "Retain UIDs Option"}
+	retainFullDatesCode = sr.Code{Value: "113106", Scheme: "DCM", Meaning: "Retain Longitudinal Temporal Information Full Dates Option"}
+)
+
+// basicProfileActions are the actions of the Basic Application Level
+// Confidentiality Profile (PS3.15 Table E.1-1) on the attributes that
+// identify the patient, the staff, the institution or the visit, with
+// pseudonyms for the patient and the accession number so that the studies of
+// a patient still group together. UIDs are replaced apart, private
+// attributes, curves and overlays are removed; other attributes are kept.
+var basicProfileActions = map[tag.Tag]string{
+	// Patient
+	tag.PatientName:                      deidReplace,
+	tag.PatientID:                        deidReplace,
+	tag.PatientBirthDate:                 deidEmpty,
+	tag.PatientSex:                       deidEmpty,
+	tag.PatientBirthTime:                 deidRemove,
+	tag.PatientAge:                       deidRemove,
+	tag.PatientSize:                      deidRemove,
+	tag.PatientWeight:                    deidRemove,
+	tag.IssuerOfPatientID:                deidRemove,
+	tag.OtherPatientIDs:                  deidRemove,
+	tag.OtherPatientIDsSequence:          deidRemove,
+	tag.OtherPatientNames:                deidRemove,
+	tag.PatientBirthName:                 deidRemove,
+	tag.PatientMotherBirthName:           deidRemove,
+	tag.PatientAddress:                   deidRemove,
+	tag.PatientTelephoneNumbers:          deidRemove,
+	tag.CountryOfResidence:               deidRemove,
+	tag.RegionOfResidence:                deidRemove,
+	tag.MilitaryRank:                     deidRemove,
+	tag.BranchOfService:                  deidRemove,
+	tag.EthnicGroup:                      deidRemove,
+	tag.Occupation:                       deidRemove,
+	tag.PatientReligiousPreference:       deidRemove,
+	tag.PatientSexNeutered:               deidRemove,
+	tag.MedicalRecordLocator:             deidRemove,
+	tag.MedicalAlerts:                    deidRemove,
+	tag.Allergies:                        deidRemove,
+	tag.SmokingStatus:                    deidRemove,
+	tag.PregnancyStatus:                  deidRemove,
+	tag.LastMenstrualDate:                deidRemove,
+	tag.PatientState:                     deidRemove,
+	tag.SpecialNeeds:                     deidRemove,
+	tag.PatientTransportArrangements:     deidRemove,
+	tag.PatientInsurancePlanCodeSequence: deidRemove,
+	tag.AdditionalPatientHistory:         deidRemove,
+	tag.PatientComments:                  deidRemove,
+	tag.CurrentPatientLocation:           deidRemove,
+	tag.PatientInstitutionResidence:      deidRemove,
+	tag.ReferencedPatientSequence:        deidRemove,
+
+	// Study and visit
+	tag.AccessionNumber:        deidReplace,
+	tag.StudyID:                deidEmpty,
+	tag.StudyDate:              deidEmpty,
+	tag.StudyTime:              deidEmpty,
+	tag.ReferringPhysicianName: deidEmpty,
+	tag.PlacerOrderNumberImagingServiceRequest:   deidEmpty,
+	tag.FillerOrderNumberImagingServiceRequest:   deidEmpty,
+	tag.ReferringPhysicianAddress:                deidRemove,
+	tag.ReferringPhysicianTelephoneNumbers:       deidRemove,
+	tag.ReferringPhysicianIdentificationSequence: deidRemove,
+	tag.PhysiciansOfRecord:                       deidRemove,
+	tag.NameOfPhysiciansReadingStudy:             deidRemove,
+	tag.ConsultingPhysicianName:                  deidRemove,
+	tag.RequestingPhysician:                      deidRemove,
+	tag.StudyDescription:                         deidRemove,
+	tag.StudyComments:                            deidRemove,
+	tag.AdmissionID:                              deidRemove,
+	tag.IssuerOfAdmissionID:                      deidRemove,
+	tag.AdmittingDiagnosesDescription:            deidRemove,
+	tag.ServiceEpisodeID:                         deidRemove,
+	tag.ReasonForStudy:                           deidRemove,
+	tag.ReferencedStudySequence:                  deidRemove,
+	tag.RequestAttributesSequence:                deidRemove,
+	tag.RequestedProcedureID:                     deidRemove,
+	tag.RequestedProcedureDescription:            deidRemove,
+	tag.InterpretationAuthor:                     deidRemove,
+	tag.InterpretationText:                       deidRemove,
+	tag.ResultsComments:                          deidRemove,
+
+	// Series, equipment and procedure steps
+	tag.SeriesDescription:           deidRemove,
+	tag.SeriesDate:                  deidRemove,
+	tag.SeriesTime:                  deidRemove,
+	tag.ProtocolName:                deidRemove,
+	tag.InstitutionName:             deidRemove,
+	tag.InstitutionAddress:          deidRemove,
+	tag.InstitutionCodeSequence:     deidRemove,
+	tag.InstitutionalDepartmentName: deidRemove,
+	tag.StationName:                 deidRemove,
+	tag.DeviceSerialNumber:          deidRemove,
+	tag.DeviceUID:                   deidRemove,
+	tag.DetectorID:                  deidRemove,
+	tag.GantryID:                    deidRemove,
+	tag.PlateID:                     deidRemove,
+	tag.CassetteID:                  deidRemove,
+	tag.PerformingPhysicianName:     deidRemove,
+	tag.PerformingPhysicianIdentificationSequence: deidRemove,
+	tag.OperatorsName:                       deidRemove,
+	tag.PerformedStationName:                deidRemove,
+	tag.PerformedLocation:                   deidRemove,
+	tag.PerformedProcedureStepID:            deidRemove,
+	tag.PerformedProcedureStepStartDate:     deidRemove,
+	tag.PerformedProcedureStepStartTime:     deidRemove,
+	tag.PerformedProcedureStepDescription:   deidRemove,
+	tag.CommentsOnThePerformedProcedureStep: deidRemove,
+	tag.ScheduledProcedureStepID:            deidRemove,
+	tag.ScheduledProcedureStepDescription:   deidRemove,
+	tag.ScheduledProcedureStepStartDate:     deidRemove,
+	tag.ScheduledStationName:                deidRemove,
+	tag.ScheduledPerformingPhysicianName:    deidRemove,
+	tag.ResponsiblePerson:                   deidRemove,
+	tag.ResponsibleOrganization:             deidRemove,
+	tag.AcquisitionComments:                 deidRemove,
+	tag.DerivationDescription:               deidRemove,
+
+	// Instance
+	tag.ContentDate:                deidEmpty,
+	tag.ContentTime:                deidEmpty,
+	tag.ContentCreatorName:         deidEmpty,
+	tag.PersonName:                 deidReplace,
+	tag.VerifyingObserverName:      deidReplace,
+	tag.AcquisitionDate:            deidRemove,
+	tag.AcquisitionTime:            deidRemove,
+	tag.AcquisitionDateTime:        deidRemove,
+	tag.InstanceCreationDate:       deidRemove,
+	tag.InstanceCreationTime:       deidRemove,
+	tag.TimezoneOffsetFromUTC:      deidRemove,
+	tag.ImageComments:              deidRemove,
+	tag.FrameComments:              deidRemove,
+	tag.IdentifyingComments:        deidRemove,
+	tag.ImagePresentationComments:  deidRemove,
+	tag.ReviewerName:               deidRemove,
+	tag.TextString:                 deidRemove,
+	tag.TextValue:                  deidRemove,
+	tag.ModifiedAttributesSequence: deidRemove,
+	tag.OriginalAttributesSequence: deidRemove,
+	tag.DigitalSignaturesSequence:  deidRemove,
+	tag.DataSetTrailingPadding:     deidRemove,
+}
+
+// AnonymizeOptions configures the de-identification of an existing dataset.
+type AnonymizeOptions struct {
+	RetainUIDs  bool   // Retain UIDs Option: UIDs are kept
+	RetainDates bool   // Retain Longitudinal Temporal Information with Full Dates Option: dates and times are kept
+	Seed        int64  // Keys the replacement UIDs
+	UIDRoot     string // Root of the replacement UIDs (util.DefaultUIDRoot when empty)
+	MapFile     string // CSV file receiving the original->replacement mapping (optional)
+}
+
+// AnonymizeReport summarizes a de-identification.
+type AnonymizeReport struct {
+	Anonymized int // DICOM files written de-identified
+	Skipped    int // Files that are not readable DICOM, not copied
+	Patients   int // Distinct patients given a pseudonym
+	UIDs       int // Distinct UIDs replaced
+}
+
+// anonymizeKey is an original value of an attribute.
+type anonymizeKey struct {
+	keyword, original string
+}
+
+// anonymizer holds the replacements shared by the files of a dataset, so
+// that patients, studies and references stay consistent once de-identified.
+type anonymizer struct {
+	opts       AnonymizeOptions
+	uids       *uidMapper
+	patients   map[string]int          // Pseudonym number by PatientID (or name)
+	accessions int                     // Accession numbers replaced
+	mapping    map[anonymizeKey]string // Replacements of the patient identifiers and accession numbers
+	dummyDates bool                    // Dates and times emptied get a dummy value instead
+}
+
+// newAnonymizer returns an anonymizer applying the profile with opts.
+func newAnonymizer(opts AnonymizeOptions) *anonymizer {
+	return &anonymizer{
+		opts: opts,
+		uids: newUIDMapper(func(old string) string {
+			return util.GenerateDeterministicUIDWithRoot(opts.UIDRoot, fmt.Sprintf("anonymize:%d:%s", opts.Seed, old))
+		}),
+		patients: make(map[string]int),
+		mapping:  make(map[anonymizeKey]string),
+	}
+}
+
+// newGeneratedAnonymizer returns the anonymizer of the generated
+// de-identified instances: the UIDs, generated, are kept and the dates get
+// dummy values.
+func newGeneratedAnonymizer() *anonymizer {
+	a := newAnonymizer(AnonymizeOptions{})
+	a.dummyDates = true
+	return a
+}
+
+// AnonymizeDirectory copies the DICOM files of inputDir to outputDir, with
+// the same relative paths, de-identified with the Basic Application Level
+// Confidentiality Profile of PS3.15: basicProfileActions applied at every
+// level of the datasets, private attributes, curves and overlays removed,
+// and UIDs replaced consistently unless opts.RetainUIDs. With
+// opts.RetainDates, dates and times are kept. Files get PatientIdentityRemoved,
+// DeidentificationMethod and its code sequence; DICOMDIR files get their
+// records de-identified and offsets recomputed. Files that cannot be parsed
+// as DICOM may hold identifiers and are skipped.
+func AnonymizeDirectory(inputDir, outputDir string, opts AnonymizeOptions) (AnonymizeReport, error) {
+	if opts.UIDRoot != "" {
+		if err := util.ValidateUIDRoot(opts.UIDRoot); err != nil {
+			return AnonymizeReport{}, err
+		}
+	}
+	if err := checkOutputOutsideInput(inputDir, outputDir); err != nil {
+		return AnonymizeReport{}, err
+	}
+
+	a := newAnonymizer(opts)
+	var report AnonymizeReport
+	err := filepath.WalkDir(inputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		ds, err := dicom.ParseFile(path, nil, dicom.SkipProcessingPixelDataValue())
+		if err != nil {
+			report.Skipped++
+			return nil
+		}
+
+		rel, err := filepath.Rel(inputDir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(outputDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+
+		ds.Elements = a.deidentify(ds.Elements)
+		if !opts.RetainUIDs {
+			a.uids.rewrite(ds.Elements)
+		}
+		if sopClass, err := ds.FindElementByTag(tag.MediaStorageSOPClassUID); err == nil && deidString(sopClass) == mediaStorageDirectoryStorage {
+			err = writeDICOMDIR(dest, ds)
+		} else {
+			ds.Elements = append(ds.Elements, a.methodElements()...)
+			sr.SortElements(ds.Elements)
+			err = writeDatasetToFile(dest, ds)
+		}
+		if err != nil {
+			return fmt.Errorf("write %s: %w", dest, err)
+		}
+		report.Anonymized++
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	report.Patients = len(a.patients)
+	report.UIDs = len(a.uids.uids)
+
+	if opts.MapFile != "" {
+		if err := a.writeMap(opts.MapFile); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// deidentifyInstance applies the profile to the elements of an instance and
+// adds the attributes recording it.
+func (a *anonymizer) deidentifyInstance(elements []*dicom.Element) []*dicom.Element {
+	return append(a.deidentify(elements), a.methodElements()...)
+}
+
+// deidentifyFile rewrites a written DICOM file de-identified.
+func (a *anonymizer) deidentifyFile(path string) error {
+	ds, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	ds.Elements = a.deidentifyInstance(ds.Elements)
+	sr.SortElements(ds.Elements)
+	return writeDatasetToFile(path, ds)
+}
+
+// deidentify applies the profile to elements and to the items of their
+// sequences, returning the elements kept or replaced.
+func (a *anonymizer) deidentify(elements []*dicom.Element) []*dicom.Element {
+	var patientID, patientName string
+	for _, elem := range elements {
+		switch elem.Tag {
+		case tag.PatientID:
+			patientID = deidString(elem)
+		case tag.PatientName:
+			patientName = deidString(elem)
+		}
+	}
+
+	result := make([]*dicom.Element, 0, len(elements))
+	for _, elem := range elements {
+		if elem.Tag == tag.PatientIdentityRemoved || elem.Tag == tag.DeidentificationMethod ||
+			elem.Tag == tag.DeidentificationMethodCodeSequence || elem.Tag == tag.LongitudinalTemporalInformationModified {
+			continue
+		}
+		if removedGroup(elem.Tag) {
+			continue
+		}
+		action, ok := basicProfileActions[elem.Tag]
+		if ok && a.opts.RetainDates && isTemporal(elem.Tag) {
+			ok = false
+		}
+		if !ok {
+			if values, isSequence := elem.Value.GetValue().([]*dicom.SequenceItemValue); isSequence {
+				items := make([][]*dicom.Element, len(values))
+				for i, item := range values {
+					items[i] = a.deidentify(item.GetValue().([]*dicom.Element))
+				}
+				value, err := dicom.NewValue(items)
+				if err != nil {
+					panic(fmt.Sprintf("failed to de-identify %v: %v", elem.Tag, err))
+				}
+				copied := *elem
+				copied.Value = value
+				elem = &copied
+			}
+			result = append(result, elem)
+			continue
+		}
+
+		switch action {
+		case deidReplace:
+			result = append(result, mustNewElement(elem.Tag, []string{a.replacement(elem, patientID, patientName)}))
+		case deidEmpty:
+			var value string
+			if a.dummyDates {
+				value = dummyTemporal(elem.Tag)
+			}
+			result = append(result, mustNewElement(elem.Tag, []string{value}))
+		}
+	}
+	return result
+}
+
+// replacement returns the pseudonym of a patient identifier or accession
+// number, recorded in the mapping, or a dummy person name.
+func (a *anonymizer) replacement(elem *dicom.Element, patientID, patientName string) string {
+	original := deidString(elem)
+	if original == "" {
+		return ""
+	}
+	var value string
+	switch elem.Tag {
+	case tag.PatientID, tag.PatientName:
+		key := patientID
+		if key == "" {
+			key = patientName
+		}
+		n, ok := a.patients[key]
+		if !ok {
+			n = len(a.patients) + 1
+			a.patients[key] = n
+		}
+		value = fmt.Sprintf("ANON%04d", n)
+		if elem.Tag == tag.PatientName {
+			value = fmt.Sprintf("ANONYMOUS^%04d", n)
+		}
+	case tag.AccessionNumber:
+		key := anonymizeKey{"AccessionNumber", original}
+		if v, ok := a.mapping[key]; ok {
+			return v
+		}
+		a.accessions++
+		value = fmt.Sprintf("ANONACC%05d", a.accessions)
+	default:
+		return anonymizeDummyPerson
+	}
+	a.mapping[anonymizeKey{deidKeyword(elem.Tag), original}] = value
+	return value
+}
+
+// methodElements returns the attributes recording the de-identification of a
+// file: PatientIdentityRemoved, the method, its codes and whether dates were
+// kept.
+func (a *anonymizer) methodElements() []*dicom.Element {
+	method := anonymizeMethod
+	codes := []sr.Code{basicProfileCode}
+	if a.opts.RetainUIDs {
+		method += " RETAIN UIDS"
+		codes = append(codes, retainUIDsCode)
+	}
+	longitudinal := "REMOVED"
+	if a.opts.RetainDates {
+		method += " RETAIN DATES"
+		codes = append(codes, retainFullDatesCode)
+		longitudinal = "UNMODIFIED"
+	}
+
+	items := make([][]*dicom.Element, 0, len(codes))
+	for _, c := range codes {
+		items = append(items, []*dicom.Element{
+			mustNewElement(tag.CodeValue, []string{c.Value}),
+			mustNewElement(tag.CodingSchemeDesignator, []string{c.Scheme}),
+			mustNewElement(tag.CodeMeaning, []string{c.Meaning}),
+		})
+	}
+	return []*dicom.Element{
+		mustNewElement(tag.PatientIdentityRemoved, []string{"YES"}),
+		mustNewElement(tag.DeidentificationMethod, []string{method}),
+		mustNewElement(tag.DeidentificationMethodCodeSequence, items),
+		mustNewElement(tag.LongitudinalTemporalInformationModified, []string{longitudinal}),
+	}
+}
+
+// writeMap writes the replacements of the patient identifiers, accession
+// numbers and UIDs as CSV, sorted by attribute and original value.
+func (a *anonymizer) writeMap(path string) error {
+	keys := make([]anonymizeKey, 0, len(a.mapping)+len(a.uids.uids))
+	replacements := make(map[anonymizeKey]string, cap(keys))
+	for key, value := range a.mapping {
+		keys = append(keys, key)
+		replacements[key] = value
+	}
+	for old, uid := range a.uids.uids {
+		key := anonymizeKey{"UID", old}
+		keys = append(keys, key)
+		replacements[key] = uid
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].keyword != keys[j].keyword {
+			return keys[i].keyword < keys[j].keyword
+		}
+		return keys[i].original < keys[j].original
+	})
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create map: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"Attribute", "Original", "Replacement"})
+	for _, key := range keys {
+		_ = w.Write([]string{key.keyword, key.original, replacements[key]})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("write map: %w", err)
+	}
+	return f.Close()
+}
+
+// removedGroup reports whether the profile removes t whatever its element:
+// private attributes, curves (groups 50xx) and overlay data and comments
+// (groups 60xx).
+func removedGroup(t tag.Tag) bool {
+	switch {
+	case t.Group%2 == 1:
+		return true
+	case t.Group&0xFF00 == 0x5000:
+		return true
+	case t.Group&0xFF00 == 0x6000:
+		return t.Element == 0x3000 || t.Element == 0x4000
+	}
+	return false
+}
+
+// dummyTemporal returns the dummy value of a date or time attribute t, ""
+// for the others.
+func dummyTemporal(t tag.Tag) string {
+	info, err := tag.Find(t)
+	if err != nil || len(info.VRs) == 0 {
+		return ""
+	}
+	switch info.VRs[0] {
+	case "DA":
+		return anonymizeDummyDate
+	case "TM":
+		return anonymizeDummyTime
+	case "DT":
+		return anonymizeDummyDate + anonymizeDummyTime
+	}
+	return ""
+}
+
+// isTemporal reports whether t is a date or a time, kept by the Retain
+// Longitudinal Temporal Information with Full Dates Option.
+func isTemporal(t tag.Tag) bool {
+	info, err := tag.Find(t)
+	if err != nil || len(info.VRs) == 0 {
+		return false
+	}
+	switch info.VRs[0] {
+	case "DA", "DT", "TM":
+		return true
+	}
+	return false
+}
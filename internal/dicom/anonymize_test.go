@@ -0,0 +1,188 @@
+package dicom
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestAnonymizeDirectory(t *testing.T) {
+	inputDir := filepath.Join(t.TempDir(), "identified")
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "1MB",
+		Modality:    modalities.MR,
+		VendorTags:  []string{VendorTagsGE},
+		OutputDir:   inputDir,
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 2,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := OrganizeFilesIntoDICOMDIR(inputDir, files, false); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "notes.txt"), []byte("patient called back"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	original := make(map[string]dicom.Dataset)
+	for _, f := range hierarchyImages(inputDir) {
+		ds, err := dicom.ParseFile(f, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, _ := filepath.Rel(inputDir, f)
+		original[rel] = ds
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "anonymized")
+	mapFile := filepath.Join(t.TempDir(), "map.csv")
+	report, err := AnonymizeDirectory(inputDir, outputDir, AnonymizeOptions{MapFile: mapFile})
+	if err != nil {
+		t.Fatalf("AnonymizeDirectory failed: %v", err)
+	}
+	if report.Anonymized != len(original)+1 || report.Skipped != 1 || report.Patients != 2 {
+		t.Errorf("report = %+v, want %d files, 1 skipped, 2 patients", report, len(original)+1)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "notes.txt")); err == nil {
+		t.Error("non-DICOM file copied")
+	}
+
+	replacements := make(map[string]string)
+	f, err := os.Open(mapFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(f).ReadAll()
+	_ = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows[1:] {
+		replacements[row[0]+":"+row[1]] = row[2]
+	}
+
+	for rel, orig := range original {
+		ds, err := dicom.ParseFile(filepath.Join(outputDir, rel), nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tg := range []tag.Tag{tag.PatientID, tag.PatientName, tag.StudyInstanceUID, tag.SOPInstanceUID} {
+			want := replacements[deidKeyword(tg)+":"+elementString(orig.Elements, tg)]
+			if tg == tag.StudyInstanceUID || tg == tag.SOPInstanceUID {
+				want = replacements["UID:"+elementString(orig.Elements, tg)]
+			}
+			if got := elementString(ds.Elements, tg); want == "" || got != want {
+				t.Errorf("%s: %s = %q, want %q from the map", rel, deidKeyword(tg), got, want)
+			}
+		}
+		for _, tg := range []tag.Tag{tag.InstitutionName, tag.OperatorsName, tag.PatientAge, {Group: 0x0043, Element: 0x0010}} {
+			if _, err := ds.FindElementByTag(tg); err == nil {
+				t.Errorf("%s: %v not removed", rel, tg)
+			}
+		}
+		if got := elementString(ds.Elements, tag.PatientBirthDate); got != "" {
+			t.Errorf("%s: PatientBirthDate = %q, want it emptied", rel, got)
+		}
+		if got := elementString(ds.Elements, tag.PatientIdentityRemoved); got != "YES" {
+			t.Errorf("%s: PatientIdentityRemoved = %q", rel, got)
+		}
+	}
+
+	// Retain UIDs and dates
+	outputDir = filepath.Join(t.TempDir(), "anonymized")
+	if _, err := AnonymizeDirectory(inputDir, outputDir, AnonymizeOptions{RetainUIDs: true, RetainDates: true}); err != nil {
+		t.Fatalf("AnonymizeDirectory failed: %v", err)
+	}
+	for rel, orig := range original {
+		ds, err := dicom.ParseFile(filepath.Join(outputDir, rel), nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tg := range []tag.Tag{tag.StudyInstanceUID, tag.SOPInstanceUID, tag.StudyDate, tag.PatientBirthDate, tag.SeriesTime} {
+			if got, want := elementString(ds.Elements, tg), elementString(orig.Elements, tg); got != want {
+				t.Errorf("%s: %s = %q, want %q retained", rel, deidKeyword(tg), got, want)
+			}
+		}
+		if got := elementString(ds.Elements, tag.LongitudinalTemporalInformationModified); got != "UNMODIFIED" {
+			t.Errorf("%s: LongitudinalTemporalInformationModified = %q", rel, got)
+		}
+		elem, err := ds.FindElementByTag(tag.DeidentificationMethodCodeSequence)
+		if err != nil || len(elem.Value.GetValue().([]*dicom.SequenceItemValue)) != 3 {
+			t.Errorf("%s: DeidentificationMethodCodeSequence = %v, want the profile and 2 options", rel, elem)
+		}
+	}
+
+	if _, err := AnonymizeDirectory(inputDir, filepath.Join(inputDir, "out"), AnonymizeOptions{}); err == nil {
+		t.Error("AnonymizeDirectory should reject an output directory inside the input")
+	}
+}
+
+func TestGenerateDeidentified(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:    6,
+		TotalSize:    "1MB",
+		Modality:     modalities.CT,
+		DoseSR:       true,
+		Deidentified: true,
+		OutputDir:    t.TempDir(),
+		Seed:         42,
+		NumStudies:   2,
+		NumPatients:  2,
+		Quiet:        true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	patients := make(map[string]string)
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, name := elementString(ds.Elements, tag.PatientID), elementString(ds.Elements, tag.PatientName)
+		if !strings.HasPrefix(id, "ANON") || name != "ANONYMOUS^"+strings.TrimPrefix(id, "ANON") {
+			t.Errorf("%s: patient %q %q, want pseudonyms", f.Path, id, name)
+		}
+		if prev, ok := patients[f.PatientID]; ok && prev != id {
+			t.Errorf("%s: patient %s pseudonymized %s and %s", f.Path, f.PatientID, prev, id)
+		}
+		patients[f.PatientID] = id
+
+		for tg, want := range map[tag.Tag]string{
+			tag.StudyDate:              anonymizeDummyDate,
+			tag.PatientIdentityRemoved: "YES",
+			tag.DeidentificationMethod: anonymizeMethod,
+			tag.InstitutionName:        "",
+			tag.PatientAge:             "",
+		} {
+			if got := elementString(ds.Elements, tg); got != want {
+				t.Errorf("%s: %s = %q, want %q", f.Path, deidKeyword(tg), got, want)
+			}
+		}
+	}
+	if len(patients) != 2 {
+		t.Errorf("%d patients, want 2", len(patients))
+	}
+
+	for _, opts := range []GeneratorOptions{
+		{Annotations: true},
+		{Modality: modalities.MR, VendorTags: []string{VendorTagsGE}},
+	} {
+		opts.NumImages, opts.TotalSize, opts.Deidentified = 1, "1MB", true
+		opts.OutputDir, opts.NumStudies, opts.NumPatients, opts.Quiet = t.TempDir(), 1, 1, true
+		if _, err := GenerateDICOMSeries(opts); err == nil {
+			t.Errorf("GenerateDICOMSeries(%+v) should return error", opts)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package dicom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"sync"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+)
+
+// Buffers reused from one image to the next. On runs of many small
+// instances, per-image allocations otherwise dominate the generation time
+// and keep the garbage collector busy.
+var (
+	fileWriters = sync.Pool{New: func() any { return bufio.NewWriterSize(nil, 256<<10) }}
+	pixelBytes  = sync.Pool{New: func() any { return new([]byte) }}
+	samples8    = sync.Pool{New: func() any { return new([]uint8) }}
+	samples16   = sync.Pool{New: func() any { return new([]uint16) }}
+)
+
+// pooledSlice returns a slice of n elements from pool, with undefined
+// content, to put back in pool once its last user is done.
+func pooledSlice[T any](pool *sync.Pool, n int) *[]T {
+	s := pool.Get().(*[]T)
+	if cap(*s) < n {
+		*s = make([]T, n)
+	}
+	*s = (*s)[:n]
+	return s
+}
+
+// pooledNativeFrame returns a single-sample frame whose pixels come from
+// pool, with undefined content. The frame must not be used after put is
+// called.
+func pooledNativeFrame[I uint8 | uint16](pool *sync.Pool, bitsPerSample, rows, cols int) (f *frame.NativeFrame[I], put func()) {
+	s := pooledSlice[I](pool, rows*cols)
+	return &frame.NativeFrame[I]{
+		RawData:                 *s,
+		InternalSamplesPerPixel: 1,
+		InternalRows:            rows,
+		InternalCols:            cols,
+		InternalBitsPerSample:   bitsPerSample,
+	}, func() { pool.Put(s) }
+}
+
+// packPixelData returns the native frames of info packed into buf as the
+// value of PixelData in little endian (the byte order of the image transfer
+// syntaxes), padded to an even length. The encoder writes such unprocessed
+// data as it is, instead of boxing every sample. Frames of other types leave
+// info unchanged.
+func packPixelData(info dicom.PixelDataInfo, buf []byte) (dicom.PixelDataInfo, []byte) {
+	buf = buf[:0]
+	for _, f := range info.Frames {
+		if f.Encapsulated {
+			return info, buf
+		}
+		switch native := f.NativeData.(type) {
+		case *frame.NativeFrame[uint8]:
+			buf = append(buf, native.RawData...)
+		case *frame.NativeFrame[uint16]:
+			for _, v := range native.RawData {
+				buf = binary.LittleEndian.AppendUint16(buf, v)
+			}
+		default:
+			return info, buf
+		}
+	}
+	if len(buf)%2 != 0 {
+		buf = append(buf, 0)
+	}
+	return dicom.PixelDataInfo{IntentionallyUnprocessed: true, UnprocessedValueData: buf}, buf
+}
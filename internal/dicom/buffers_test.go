@@ -0,0 +1,48 @@
+package dicom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestPackPixelData_SameEncoding(t *testing.T) {
+	frame8 := frame.NewNativeFrame[uint8](8, 3, 3, 9, 1) // Odd length, padded
+	frame16 := frame.NewNativeFrame[uint16](16, 3, 3, 9, 1)
+	for i := range frame8.RawData {
+		frame8.RawData[i] = uint8(i * 29)
+		frame16.RawData[i] = uint16(i * 7919)
+	}
+
+	for name, frames := range map[string][]*frame.Frame{
+		"8-bit":      {{NativeData: frame8}},
+		"16-bit":     {{NativeData: frame16}},
+		"multiframe": {{NativeData: frame8}, {NativeData: frame8}, {NativeData: frame8}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			info := dicom.PixelDataInfo{Frames: frames}
+			packed, _ := packPixelData(info, nil)
+			if !packed.IntentionallyUnprocessed {
+				t.Fatal("native frames not packed")
+			}
+
+			encode := func(info dicom.PixelDataInfo) []byte {
+				var buf bytes.Buffer
+				ds := dicom.Dataset{Elements: []*dicom.Element{
+					mustNewElement(tag.TransferSyntaxUID, []string{TransferSyntaxExplicitVRLittleEndian}),
+					mustNewElement(tag.PixelData, info),
+				}}
+				if err := dicom.Write(&buf, ds, dicom.SkipVRVerification()); err != nil {
+					t.Fatal(err)
+				}
+				return buf.Bytes()
+			}
+			if !bytes.Equal(encode(packed), encode(info)) {
+				t.Error("packed pixel data encodes differently from its frames")
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+)
+
+// StudyFootprint is the typical uncompressed size of a study of a modality.
+type StudyFootprint struct {
+	ImagesPerStudy int
+	PixelsPerImage int64
+	BytesPerPixel  int64 // 1 for 8-bit modalities, 2 otherwise
+}
+
+// BytesPerStudy returns the size of a study.
+func (f StudyFootprint) BytesPerStudy() int64 {
+	return int64(f.ImagesPerStudy) * f.PixelsPerImage * f.BytesPerPixel
+}
+
+// studyFootprints are the typical studies of each modality
+var studyFootprints = map[modalities.Modality]StudyFootprint{
+	modalities.CT: {ImagesPerStudy: 400, PixelsPerImage: 512 * 512, BytesPerPixel: 2},  // Thin slice chest/abdomen
+	modalities.MR: {ImagesPerStudy: 300, PixelsPerImage: 256 * 256, BytesPerPixel: 2},  // Several sequences
+	modalities.CR: {ImagesPerStudy: 2, PixelsPerImage: 2048 * 2560, BytesPerPixel: 2},  // Two views
+	modalities.DX: {ImagesPerStudy: 2, PixelsPerImage: 2560 * 3072, BytesPerPixel: 2},  // Two views
+	modalities.US: {ImagesPerStudy: 30, PixelsPerImage: 640 * 480, BytesPerPixel: 1},   // Stills and short loops
+	modalities.MG: {ImagesPerStudy: 4, PixelsPerImage: 3328 * 4096, BytesPerPixel: 2},  // CC and MLO of both breasts
+	modalities.RF: {ImagesPerStudy: 20, PixelsPerImage: 1024 * 1024, BytesPerPixel: 2}, // Spot images
+}
+
+// Footprint returns the typical study of a modality.
+func Footprint(m modalities.Modality) StudyFootprint {
+	return studyFootprints[m]
+}
+
+// WorkloadShare is a modality with its relative share of the studies of a
+// workload profile.
+type WorkloadShare struct {
+	Modality modalities.Modality
+	Weight   float64
+}
+
+// Workload profile presets
+var workloadPresets = map[string][]WorkloadShare{
+	// General hospital: radiography first, then CT and ultrasound
+	"hospital": {
+		{Modality: modalities.CR, Weight: 35},
+		{Modality: modalities.CT, Weight: 20},
+		{Modality: modalities.US, Weight: 20},
+		{Modality: modalities.MR, Weight: 10},
+		{Modality: modalities.DX, Weight: 8},
+		{Modality: modalities.MG, Weight: 5},
+		{Modality: modalities.RF, Weight: 2},
+	},
+	// Outpatient imaging center: cross-sectional imaging
+	"imaging-center": {
+		{Modality: modalities.MR, Weight: 35},
+		{Modality: modalities.CT, Weight: 25},
+		{Modality: modalities.US, Weight: 20},
+		{Modality: modalities.MG, Weight: 10},
+		{Modality: modalities.DX, Weight: 10},
+	},
+	// Breast screening program
+	"screening": {
+		{Modality: modalities.MG, Weight: 90},
+		{Modality: modalities.US, Weight: 10},
+	},
+}
+
+// WorkloadPresets returns the names of the built-in workload profiles.
+func WorkloadPresets() []string {
+	names := make([]string, 0, len(workloadPresets))
+	for name := range workloadPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseWorkload parses a workload profile preset name ("hospital",
+// "imaging-center", "screening") or comma-separated weighted modalities
+// ("CT:30,MR:20,CR:50").
+func ParseWorkload(s string) ([]WorkloadShare, error) {
+	s = strings.TrimSpace(s)
+	if preset, ok := workloadPresets[strings.ToLower(s)]; ok {
+		return preset, nil
+	}
+
+	var workload []WorkloadShare
+	total := 0.0
+	for _, part := range strings.Split(s, ",") {
+		modality, weightStr, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid workload share %q (expected MODALITY:WEIGHT, or a preset: %s)",
+				part, strings.Join(WorkloadPresets(), ", "))
+		}
+		modality = strings.ToUpper(strings.TrimSpace(modality))
+		if !modalities.IsValid(modality) {
+			return nil, fmt.Errorf("invalid modality %q in workload share %q, valid options: %v", modality, part, modalities.AllModalities())
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight in workload share %q (expected a number >= 0)", part)
+		}
+		for _, share := range workload {
+			if share.Modality == modalities.Modality(modality) {
+				return nil, fmt.Errorf("modality %s appears twice in workload %q", modality, s)
+			}
+		}
+		workload = append(workload, WorkloadShare{Modality: modalities.Modality(modality), Weight: weight})
+		total += weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("workload %q has no positive weight", s)
+	}
+	return workload, nil
+}
+
+// CapacityOptions describes the imaging activity a storage is sized for.
+type CapacityOptions struct {
+	Workload      []WorkloadShare
+	StudiesPerDay float64 // Studies per day on the first day
+	Days          int     // Length of the projection
+	AnnualGrowth  float64 // Yearly growth of the activity (0.05 = +5% per year)
+	Interval      int     // Days between the points of the projection (0 = 30)
+}
+
+// ModalityCapacity is the daily volume of a modality on the first day.
+type ModalityCapacity struct {
+	Modality      modalities.Modality
+	Footprint     StudyFootprint
+	StudiesPerDay float64
+	BytesPerDay   int64
+}
+
+// CapacityPoint is the cumulative volume stored at the end of a day.
+type CapacityPoint struct {
+	Day     int
+	Studies int64
+	Images  int64
+	Bytes   int64
+}
+
+// CapacityReport is a storage growth projection.
+type CapacityReport struct {
+	Modalities  []ModalityCapacity
+	BytesPerDay int64 // All modalities, on the first day
+	Points      []CapacityPoint
+}
+
+// ProjectCapacity projects the storage used by a workload over time: the
+// activity of each day grows continuously by AnnualGrowth per year, studies
+// keep the typical footprint of their modality, and nothing is deleted.
+func ProjectCapacity(opts CapacityOptions) (CapacityReport, error) {
+	if len(opts.Workload) == 0 {
+		return CapacityReport{}, fmt.Errorf("empty workload")
+	}
+	if opts.StudiesPerDay <= 0 {
+		return CapacityReport{}, fmt.Errorf("studies per day must be > 0, got %g", opts.StudiesPerDay)
+	}
+	if opts.Days <= 0 {
+		return CapacityReport{}, fmt.Errorf("projection length must be > 0 days, got %d", opts.Days)
+	}
+	if opts.AnnualGrowth <= -1 {
+		return CapacityReport{}, fmt.Errorf("annual growth must be > -100%%, got %g", opts.AnnualGrowth)
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 30
+	}
+
+	totalWeight := 0.0
+	for _, share := range opts.Workload {
+		totalWeight += share.Weight
+	}
+	var report CapacityReport
+	var imagesPerDay, bytesPerDay float64
+	for _, share := range opts.Workload {
+		footprint := Footprint(share.Modality)
+		studies := opts.StudiesPerDay * share.Weight / totalWeight
+		report.Modalities = append(report.Modalities, ModalityCapacity{
+			Modality:      share.Modality,
+			Footprint:     footprint,
+			StudiesPerDay: studies,
+			BytesPerDay:   int64(math.Round(studies * float64(footprint.BytesPerStudy()))),
+		})
+		imagesPerDay += studies * float64(footprint.ImagesPerStudy)
+		bytesPerDay += studies * float64(footprint.BytesPerStudy())
+	}
+	report.BytesPerDay = int64(math.Round(bytesPerDay))
+
+	// Sum day by day: the factor of day d is (1+growth)^(d/365)
+	var studies, images, bytes float64
+	for day := 1; day <= opts.Days; day++ {
+		factor := math.Pow(1+opts.AnnualGrowth, float64(day-1)/365)
+		studies += opts.StudiesPerDay * factor
+		images += imagesPerDay * factor
+		bytes += bytesPerDay * factor
+		if day%interval == 0 || day == opts.Days {
+			report.Points = append(report.Points, CapacityPoint{
+				Day:     day,
+				Studies: int64(math.Round(studies)),
+				Images:  int64(math.Round(images)),
+				Bytes:   int64(math.Round(bytes)),
+			})
+		}
+	}
+	return report, nil
+}
+
+// maxDayBatchBytes is the largest volume generated in one run: above it,
+// CalculateDimensions would shrink the images
+const maxDayBatchBytes = 4000 << 20
+
+// DayOptions returns the generator options producing a representative day of
+// a workload: the day's studies are apportioned to the modalities of the
+// workload (largest remainder), each study a new patient with the typical
+// footprint of its modality. Every modality is generated in its own
+// subdirectory of base.OutputDir (CT, MR...), split into CT_2, CT_3... when
+// its volume exceeds what a single run can generate at full image size.
+// Other settings are taken from base.
+func DayOptions(base GeneratorOptions, workload []WorkloadShare, studies int) ([]GeneratorOptions, error) {
+	if studies <= 0 {
+		return nil, fmt.Errorf("number of studies must be > 0, got %d", studies)
+	}
+	totalWeight := 0.0
+	for _, share := range workload {
+		totalWeight += share.Weight
+	}
+	if totalWeight <= 0 {
+		return nil, fmt.Errorf("workload has no positive weight")
+	}
+
+	// Largest remainder apportionment of the studies
+	counts := make([]int, len(workload))
+	fractions := make([]float64, len(workload))
+	order := make([]int, len(workload))
+	assigned := 0
+	for i, share := range workload {
+		exact := float64(studies) * share.Weight / totalWeight
+		counts[i] = int(exact)
+		fractions[i] = exact - float64(counts[i])
+		order[i] = i
+		assigned += counts[i]
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fractions[order[a]] > fractions[order[b]] })
+	for i := 0; assigned < studies; i++ {
+		counts[order[i]]++
+		assigned++
+	}
+
+	var result []GeneratorOptions
+	for i, share := range workload {
+		footprint := Footprint(share.Modality)
+		perBatch := max(int(maxDayBatchBytes/(2*int64(footprint.ImagesPerStudy)*footprint.PixelsPerImage)), 1)
+		for batch, remaining := 1, counts[i]; remaining > 0; batch++ {
+			n := min(remaining, perBatch)
+			remaining -= n
+
+			opts := base
+			opts.Modality = share.Modality
+			opts.NumStudies = n
+			opts.NumPatients = n
+			opts.NumImages = n * footprint.ImagesPerStudy
+			// The size of a run is computed for 16-bit pixels
+			pixels := int64(opts.NumImages) * footprint.PixelsPerImage
+			opts.TotalSize = fmt.Sprintf("%dKB", (2*pixels+100*1024)/1024)
+			dir := string(share.Modality)
+			if batch > 1 {
+				dir = fmt.Sprintf("%s_%d", share.Modality, batch)
+			}
+			opts.OutputDir = filepath.Join(base.OutputDir, dir)
+			if base.Seed != 0 {
+				// Distinct patients and UIDs from one run to the other
+				opts.Seed = base.Seed + int64(len(result))
+			}
+			result = append(result, opts)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package dicom
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+)
+
+func TestParseWorkload(t *testing.T) {
+	for _, name := range WorkloadPresets() {
+		if _, err := ParseWorkload(name); err != nil {
+			t.Errorf("ParseWorkload(%q) failed: %v", name, err)
+		}
+	}
+
+	workload, err := ParseWorkload("ct:30, MR:20,CR:50")
+	if err != nil {
+		t.Fatalf("ParseWorkload failed: %v", err)
+	}
+	want := []WorkloadShare{{modalities.CT, 30}, {modalities.MR, 20}, {modalities.CR, 50}}
+	if len(workload) != len(want) {
+		t.Fatalf("got %v, want %v", workload, want)
+	}
+	for i := range want {
+		if workload[i] != want[i] {
+			t.Errorf("share %d = %v, want %v", i, workload[i], want[i])
+		}
+	}
+
+	for _, input := range []string{"", "clinic", "CT", "PT:10", "CT:-1", "CT:x", "CT:0", "CT:10,CT:20"} {
+		if _, err := ParseWorkload(input); err == nil {
+			t.Errorf("ParseWorkload(%q) should fail", input)
+		}
+	}
+}
+
+func TestProjectCapacity(t *testing.T) {
+	workload := []WorkloadShare{{modalities.CT, 1}, {modalities.CR, 3}}
+	ct, cr := Footprint(modalities.CT), Footprint(modalities.CR)
+
+	report, err := ProjectCapacity(CapacityOptions{Workload: workload, StudiesPerDay: 100, Days: 365})
+	if err != nil {
+		t.Fatalf("ProjectCapacity failed: %v", err)
+	}
+	daily := 25*ct.BytesPerStudy() + 75*cr.BytesPerStudy()
+	if report.BytesPerDay != daily {
+		t.Errorf("BytesPerDay = %d, want %d", report.BytesPerDay, daily)
+	}
+	if got := report.Modalities[1].StudiesPerDay; got != 75 {
+		t.Errorf("CR studies per day = %g, want 75", got)
+	}
+	// Every 30 days, and the last day
+	if len(report.Points) != 13 || report.Points[0].Day != 30 || report.Points[12].Day != 365 {
+		t.Fatalf("got %d points, want days 30, 60... 360 and 365", len(report.Points))
+	}
+	last := report.Points[12]
+	if last.Studies != 36500 || last.Images != 365*(25*400+75*2) || last.Bytes != 365*daily {
+		t.Errorf("last point = %+v, want 365 days of activity", last)
+	}
+
+	// 10% growth: the last day of the year is 10% busier than the first
+	report, err = ProjectCapacity(CapacityOptions{Workload: workload, StudiesPerDay: 100, Days: 366, AnnualGrowth: 0.1, Interval: 1})
+	if err != nil {
+		t.Fatalf("ProjectCapacity failed: %v", err)
+	}
+	lastDay := report.Points[365].Studies - report.Points[364].Studies
+	if lastDay != 110 {
+		t.Errorf("studies on day 366 = %d, want 110", lastDay)
+	}
+	if total := report.Points[364].Studies; total <= 36500 || total >= 36500*1.1 {
+		t.Errorf("studies over the first year = %d, want between 36500 and 40150", total)
+	}
+
+	for _, opts := range []CapacityOptions{
+		{StudiesPerDay: 100, Days: 1},
+		{Workload: workload, Days: 1},
+		{Workload: workload, StudiesPerDay: 100},
+		{Workload: workload, StudiesPerDay: 100, Days: 1, AnnualGrowth: -1},
+	} {
+		if _, err := ProjectCapacity(opts); err == nil {
+			t.Errorf("ProjectCapacity(%+v) should fail", opts)
+		}
+	}
+}
+
+func TestDayOptions(t *testing.T) {
+	workload := []WorkloadShare{{modalities.CR, 35}, {modalities.CT, 20}, {modalities.US, 20}, {modalities.MG, 25}}
+	base := GeneratorOptions{OutputDir: "day", Seed: 42, Workers: 2}
+
+	runs, err := DayOptions(base, workload, 11)
+	if err != nil {
+		t.Fatalf("DayOptions failed: %v", err)
+	}
+	// 3.85, 2.2, 2.2 and 2.75 studies: the remaining two go to CR and MG
+	want := map[modalities.Modality]int{modalities.CR: 4, modalities.CT: 2, modalities.US: 2, modalities.MG: 3}
+	seeds := make(map[int64]bool)
+	for _, opts := range runs {
+		if opts.NumStudies != want[opts.Modality] || opts.NumPatients != opts.NumStudies {
+			t.Errorf("%s: %d studies of %d patients, want %d", opts.Modality, opts.NumStudies, opts.NumPatients, want[opts.Modality])
+		}
+		if opts.NumImages != opts.NumStudies*Footprint(opts.Modality).ImagesPerStudy {
+			t.Errorf("%s: %d images for %d studies", opts.Modality, opts.NumImages, opts.NumStudies)
+		}
+		if opts.OutputDir != filepath.Join("day", string(opts.Modality)) || opts.Workers != 2 {
+			t.Errorf("%s: output %s, %d workers", opts.Modality, opts.OutputDir, opts.Workers)
+		}
+		seeds[opts.Seed] = true
+	}
+	if len(runs) != 4 || len(seeds) != 4 {
+		t.Errorf("got %d runs with %d seeds, want 4 distinct", len(runs), len(seeds))
+	}
+
+	// CT runs are split to keep full size images
+	runs, err = DayOptions(base, []WorkloadShare{{modalities.CT, 1}}, 50)
+	if err != nil {
+		t.Fatalf("DayOptions failed: %v", err)
+	}
+	perRun := int(math.Floor(maxDayBatchBytes / float64(2*400*512*512)))
+	if len(runs) != (50+perRun-1)/perRun || runs[0].NumStudies != perRun || runs[1].OutputDir != filepath.Join("day", "CT_2") {
+		t.Errorf("got %d runs of %d studies, want runs of %d", len(runs), runs[0].NumStudies, perRun)
+	}
+
+	if _, err := DayOptions(base, workload, 0); err == nil {
+		t.Error("DayOptions with no study should fail")
+	}
+}
@@ -0,0 +1,115 @@
+package dicom
+
+import (
+	"math"
+	randv2 "math/rand/v2"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	dfimage "github.com/mrsinham/dicomforge/internal/image"
+	"github.com/suyashkumar/dicom/pkg/frame"
+)
+
+// Ultrasound cine loops: acquisition frame rates (frames/s), the heart rate
+// driving the beating cavity (beats/min), and the correlation of the speckle
+// of a frame with the previous one.
+var cineFrameRates = []int{25, 30, 40, 50, 60}
+
+const (
+	cineHeartRate          = 72
+	cineSpeckleCorrelation = 0.9
+	cineFrameJitter        = 0.08 // Relative spread of the intervals of a FrameTimeVector
+)
+
+// cineFrameTimes draws the frame time (ms) of a cine loop and, when vector
+// is set, the interval of each frame since the previous one, 0 for the first.
+func cineFrameTimes(frames int, vector bool, rng *randv2.Rand) (float64, []float64) {
+	frameTime := math.Round(10000/float64(cineFrameRates[rng.IntN(len(cineFrameRates))])) / 10
+	if !vector {
+		return frameTime, nil
+	}
+	intervals := make([]float64, frames)
+	for i := 1; i < frames; i++ {
+		intervals[i] = math.Round(frameTime*(1+cineFrameJitter*(2*rng.Float64()-1))*10) / 10
+	}
+	return frameTime, intervals
+}
+
+// cineFrames8 returns the frames of an ultrasound cine loop: the radial
+// background of single frame images, a speckle pattern that decorrelates
+// slowly from frame to frame, and an anechoic cavity with a bright wall
+// contracting and expanding at the heart rate. Lesions, the text overlay and
+// the rendered annotations are drawn on every frame.
+func cineFrames8(task imageTask, annotations []dfimage.AnnotationBlock, rng *randv2.Rand) []*frame.Frame {
+	width, height := task.width, task.height
+	pixels := width * height
+	cfg := task.pixelConfig
+	valueRange := float64(cfg.MaxValue - cfg.MinValue)
+	centerX, centerY := float64(width)/2, float64(height)/2
+	maxDist := math.Sqrt(centerX*centerX + centerY*centerY)
+
+	background := make([]float64, pixels)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dist := math.Hypot(float64(x)-centerX, float64(y)-centerY)
+			background[y*width+x] = float64(cfg.BaseValue) + (1.0-dist/maxDist)*valueRange*0.3
+		}
+	}
+
+	// Unit variance uniform noise, mixed with the previous frame's speckle
+	unitNoise := func() float64 { return (rng.Float64() - 0.5) * math.Sqrt(12) }
+	speckle := make([]float64, pixels)
+	for i := range speckle {
+		speckle[i] = unitNoise()
+	}
+	innovation := math.Sqrt(1 - cineSpeckleCorrelation*cineSpeckleCorrelation)
+
+	framesPerBeat := 60000 / cineHeartRate / task.frameTime
+	radiusX, radiusY := float64(width)/6, float64(height)/5
+	wall := math.Max(2, math.Min(radiusX, radiusY)/5)
+	maxVal := float64(int(1)<<cfg.BitsStored - 1)
+
+	frames := make([]*frame.Frame, task.frames)
+	for f := range frames {
+		if f > 0 {
+			for i := range speckle {
+				speckle[i] = cineSpeckleCorrelation*speckle[i] + innovation*unitNoise()
+			}
+		}
+		scale := 1 + 0.15*math.Sin(2*math.Pi*float64(f)/framesPerBeat)
+		rx, ry := radiusX*scale, radiusY*scale
+
+		nativeFrame := frame.NewNativeFrame[uint8](8, height, width, pixels, 1)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				i := y*width + x
+				intensity := background[i] + speckle[i]*valueRange*0.12
+
+				// Elliptic distance to the cavity center, 1 on its border
+				dx, dy := (float64(x)-centerX)/rx, (float64(y)-centerY)/ry
+				r := math.Sqrt(dx*dx + dy*dy)
+				switch {
+				case r < 1:
+					intensity *= 0.15
+				case (r-1)*math.Min(rx, ry) < wall:
+					intensity += valueRange * 0.25
+				}
+				nativeFrame.RawData[i] = uint8(math.Max(0, math.Min(maxVal, intensity)))
+			}
+		}
+
+		drawLesions8(nativeFrame, width, height, task.instanceInSeries, task.lesions, valueRange)
+		drawTextOnFrame8(nativeFrame, width, height, task.textOverlay)
+		drawAnnotations8(nativeFrame, width, height, annotations)
+		frames[f] = &frame.Frame{Encapsulated: false, NativeData: nativeFrame}
+	}
+	return frames
+}
+
+// cineSOPClassUID returns the SOP class of the instances of a modality: US
+// cine loops are Ultrasound Multi-frame images.
+func cineSOPClassUID(gen modalities.Generator, frames int) string {
+	if gen.Modality() == modalities.US && frames > 1 {
+		return modalities.USMultiFrameSOPClassUID
+	}
+	return gen.SOPClassUID()
+}
@@ -0,0 +1,297 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Photometric interpretations of color ultrasound images
+const (
+	PhotometricRGB          = "RGB"
+	PhotometricYBRFull422   = "YBR_FULL_422"
+	PhotometricPaletteColor = "PALETTE COLOR"
+)
+
+// ParseUSColor parses a color ultrasound photometric interpretation: RGB,
+// YBR_FULL_422 or PALETTE COLOR (also PALETTE_COLOR), case-insensitive. ""
+// stays monochrome.
+func ParseUSColor(s string) (string, error) {
+	switch v := strings.ToUpper(strings.TrimSpace(s)); v {
+	case "", PhotometricRGB, PhotometricYBRFull422, PhotometricPaletteColor:
+		return v, nil
+	case "PALETTE_COLOR":
+		return PhotometricPaletteColor, nil
+	default:
+		return "", fmt.Errorf("invalid color mode %q (expected RGB, YBR_FULL_422 or PALETTE_COLOR)", s)
+	}
+}
+
+// colorPhotometric returns the color photometric interpretation of the images
+// of a run, "" when they are monochrome.
+func colorPhotometric(opts GeneratorOptions) string {
+	if opts.Modality != modalities.US {
+		return ""
+	}
+	return opts.USColor
+}
+
+// colorElements turns the image pixel module of elements into a color one:
+// three samples per pixel in the given photometric interpretation, by plane
+// (PlanarConfiguration 1) or by pixel, or palette indices with the Red, Green
+// and Blue Palette Color Lookup Tables. Window values, which do not apply to
+// color images, are removed.
+func colorElements(elements []*dicom.Element, photometric string, byPlane bool) []*dicom.Element {
+	samples := 3
+	if photometric == PhotometricPaletteColor {
+		samples = 1
+	}
+	result := elements[:0]
+	for _, elem := range elements {
+		switch elem.Tag {
+		case tag.WindowCenter, tag.WindowWidth, tag.PlanarConfiguration:
+			continue
+		case tag.SamplesPerPixel:
+			elem = mustNewElement(tag.SamplesPerPixel, []int{samples})
+		case tag.PhotometricInterpretation:
+			elem = mustNewElement(tag.PhotometricInterpretation, []string{photometric})
+		}
+		result = append(result, elem)
+	}
+	if photometric == PhotometricPaletteColor {
+		return append(result, paletteElements()...)
+	}
+	planar := 0
+	if byPlane {
+		planar = 1
+	}
+	return append(result, mustNewElement(tag.PlanarConfiguration, []int{planar}))
+}
+
+// Palette of PALETTE COLOR images: a gray ramp for the B-mode image followed
+// by the color Doppler scale, paletteDopplerLevels speeds towards the probe
+// then as many away from it.
+const (
+	paletteGrayEntries   = 192
+	paletteDopplerLevels = 32
+	paletteEntries       = paletteGrayEntries + 2*paletteDopplerLevels
+)
+
+// paletteColor returns the 8-bit color of a palette entry.
+func paletteColor(index int) (r, g, b uint8) {
+	if index < paletteGrayEntries {
+		v := uint8((index*255 + (paletteGrayEntries-1)/2) / (paletteGrayEntries - 1))
+		return v, v, v
+	}
+	level := (index - paletteGrayEntries) % paletteDopplerLevels
+	v := float64(level+1) / paletteDopplerLevels
+	if index >= paletteGrayEntries+paletteDopplerLevels {
+		v = -v
+	}
+	return dopplerColor(v)
+}
+
+// paletteGrayIndex returns the palette entry of an 8-bit gray level.
+func paletteGrayIndex(gray uint8) uint8 {
+	return uint8((int(gray)*(paletteGrayEntries-1) + 127) / 255)
+}
+
+// paletteDopplerIndex returns the palette entry of a flow velocity.
+func paletteDopplerIndex(v float64) uint8 {
+	level := min(int(math.Abs(v)*paletteDopplerLevels), paletteDopplerLevels-1)
+	if v < 0 {
+		level += paletteDopplerLevels
+	}
+	return uint8(paletteGrayEntries + level)
+}
+
+// paletteElements returns the Red, Green and Blue Palette Color Lookup Table
+// Descriptors and Data of PALETTE COLOR images: paletteEntries 16-bit
+// entries, the first mapped to pixel value 0.
+func paletteElements() []*dicom.Element {
+	var data [3][]byte
+	for c := range data {
+		data[c] = make([]byte, 2*paletteEntries)
+	}
+	for i := 0; i < paletteEntries; i++ {
+		r, g, b := paletteColor(i)
+		for c, v := range []uint8{r, g, b} {
+			binary.LittleEndian.PutUint16(data[c][2*i:], uint16(v)*257)
+		}
+	}
+	descriptor := []int{paletteEntries, 0, 16}
+	return []*dicom.Element{
+		mustNewElement(tag.RedPaletteColorLookupTableDescriptor, descriptor),
+		mustNewElement(tag.GreenPaletteColorLookupTableDescriptor, descriptor),
+		mustNewElement(tag.BluePaletteColorLookupTableDescriptor, descriptor),
+		mustNewElement(tag.RedPaletteColorLookupTableData, data[0]),
+		mustNewElement(tag.GreenPaletteColorLookupTableData, data[1]),
+		mustNewElement(tag.BluePaletteColorLookupTableData, data[2]),
+	}
+}
+
+// Color Doppler box, as fractions of the image, and the vessels crossing it:
+// an artery with pulsatile flow towards the probe and a vein with steady flow
+// away from it.
+const (
+	dopplerBoxLeft, dopplerBoxRight = 0.3, 0.75
+	dopplerBoxTop, dopplerBoxBottom = 0.25, 0.65
+	dopplerBoxOutline               = 200
+)
+
+type dopplerVessel struct {
+	y, slope, radius float64 // Center at the box left edge and radius, fractions of the height
+	towards          bool
+	pulsatile        bool
+}
+
+var dopplerVessels = []dopplerVessel{
+	{y: 0.35, slope: 0.12, radius: 0.03, towards: true, pulsatile: true},
+	{y: 0.55, slope: -0.05, radius: 0.04},
+}
+
+// dopplerVelocity returns the flow velocity at (x, y), normalized to [-1, 1]
+// and positive towards the probe, at the given cardiac phase (radians); ok is
+// false outside the vessels of the Doppler box.
+func dopplerVelocity(x, y, width, height int, phase float64) (v float64, ok bool) {
+	fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+	if fx < dopplerBoxLeft || fx > dopplerBoxRight || fy < dopplerBoxTop || fy > dopplerBoxBottom {
+		return 0, false
+	}
+	for _, vessel := range dopplerVessels {
+		d := math.Abs(fy-vessel.y-vessel.slope*(fx-dopplerBoxLeft)) / vessel.radius
+		if d >= 1 {
+			continue
+		}
+		// Laminar flow: parabolic profile across the vessel
+		v = 1 - d*d
+		if vessel.pulsatile {
+			v *= 0.35 + 0.65*math.Max(0, math.Sin(phase))
+		} else {
+			v *= 0.4
+		}
+		if !vessel.towards {
+			v = -v
+		}
+		return v, true
+	}
+	return 0, false
+}
+
+// dopplerColor maps a velocity to the usual color Doppler scale: red to
+// yellow towards the probe, blue to cyan away from it.
+func dopplerColor(v float64) (r, g, b uint8) {
+	speed := math.Abs(v)
+	base, highlight := uint8(90+165*speed), uint8(220*speed*speed)
+	if v >= 0 {
+		return base, highlight, 0
+	}
+	return 0, highlight, base
+}
+
+// dopplerOverlay calls paint for the pixels of the Doppler box at the given
+// cardiac phase: with the flow velocity of the pixels inside a vessel, and
+// outline set for the pixels of the box outline.
+func dopplerOverlay(width, height int, phase float64, paint func(i int, v float64, outline bool)) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if v, ok := dopplerVelocity(x, y, width, height, phase); ok {
+				paint(y*width+x, v, false)
+			}
+		}
+	}
+	left, top, right, bottom := dopplerBox(width, height)
+	for x := left; x <= right; x++ {
+		paint(top*width+x, 0, true)
+		paint(bottom*width+x, 0, true)
+	}
+	for y := top; y <= bottom; y++ {
+		paint(y*width+left, 0, true)
+		paint(y*width+right, 0, true)
+	}
+}
+
+// dopplerBox returns the columns and rows of the outline of the Doppler box
+// of a width x height image.
+func dopplerBox(width, height int) (left, top, right, bottom int) {
+	left, right = int(dopplerBoxLeft*float64(width)), min(int(dopplerBoxRight*float64(width)), width-1)
+	top, bottom = int(dopplerBoxTop*float64(height)), min(int(dopplerBoxBottom*float64(height)), height-1)
+	return left, top, right, bottom
+}
+
+// colorDopplerFrame returns an 8-bit grayscale B-mode frame as a color frame
+// with the flow of the Doppler box at the given cardiac phase, encoded in the
+// photometric interpretation: RGB by pixel or by plane, YBR_FULL_422 with
+// the chroma of each pair of pixels averaged (two bytes per pixel), or
+// PALETTE COLOR indices.
+func colorDopplerFrame(gray *frame.NativeFrame[uint8], width, height int, phase float64, photometric string, byPlane bool) *frame.NativeFrame[uint8] {
+	pixels := width * height
+	if photometric == PhotometricPaletteColor {
+		out := frame.NewNativeFrame[uint8](8, height, width, pixels, 1)
+		for i, g := range gray.RawData[:pixels] {
+			out.RawData[i] = paletteGrayIndex(g)
+		}
+		dopplerOverlay(width, height, phase, func(i int, v float64, outline bool) {
+			if outline {
+				out.RawData[i] = paletteGrayIndex(dopplerBoxOutline)
+			} else {
+				out.RawData[i] = paletteDopplerIndex(v)
+			}
+		})
+		return out
+	}
+
+	rgb := make([][3]uint8, pixels)
+	for i, g := range gray.RawData[:pixels] {
+		rgb[i] = [3]uint8{g, g, g}
+	}
+	dopplerOverlay(width, height, phase, func(i int, v float64, outline bool) {
+		if outline {
+			rgb[i] = [3]uint8{dopplerBoxOutline, dopplerBoxOutline, dopplerBoxOutline}
+		} else {
+			r, g, b := dopplerColor(v)
+			rgb[i] = [3]uint8{r, g, b}
+		}
+	})
+
+	if photometric == PhotometricYBRFull422 {
+		// Y1 Y2 Cb Cr per pair of pixels along a row
+		out := frame.NewNativeFrame[uint8](8, height, width, pixels, 2)
+		for i := 0; i+1 < pixels; i += 2 {
+			y1, cb1, cr1 := ybrFull(rgb[i])
+			y2, cb2, cr2 := ybrFull(rgb[i+1])
+			out.RawData[2*i] = y1
+			out.RawData[2*i+1] = y2
+			out.RawData[2*i+2] = uint8((int(cb1) + int(cb2) + 1) / 2)
+			out.RawData[2*i+3] = uint8((int(cr1) + int(cr2) + 1) / 2)
+		}
+		return out
+	}
+
+	out := frame.NewNativeFrame[uint8](8, height, width, pixels, 3)
+	for i, c := range rgb {
+		for s := 0; s < 3; s++ {
+			if byPlane {
+				out.RawData[s*pixels+i] = c[s]
+			} else {
+				out.RawData[3*i+s] = c[s]
+			}
+		}
+	}
+	return out
+}
+
+// ybrFull converts an RGB color to full range YCbCr (PS3.3 C.7.6.3.1.2).
+func ybrFull(c [3]uint8) (y, cb, cr uint8) {
+	r, g, b := float64(c[0]), float64(c[1]), float64(c[2])
+	clamp := func(v float64) uint8 { return uint8(math.Max(0, math.Min(255, math.Round(v)))) }
+	return clamp(0.299*r + 0.587*g + 0.114*b),
+		clamp(-0.1687*r - 0.3313*g + 0.5*b + 128),
+		clamp(0.5*r - 0.4187*g - 0.0813*b + 128)
+}
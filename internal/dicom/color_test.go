@@ -0,0 +1,138 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParseUSColor(t *testing.T) {
+	for input, want := range map[string]string{
+		"":              "",
+		"rgb":           PhotometricRGB,
+		"YBR_FULL_422":  PhotometricYBRFull422,
+		"PALETTE COLOR": PhotometricPaletteColor,
+		"palette_color": PhotometricPaletteColor,
+	} {
+		got, err := ParseUSColor(input)
+		if err != nil || got != want {
+			t.Errorf("ParseUSColor(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseUSColor("YBR_PARTIAL_420"); err == nil {
+		t.Error("ParseUSColor should reject unsupported interpretations")
+	}
+}
+
+func TestColorElements(t *testing.T) {
+	elements := colorElements([]*dicom.Element{
+		mustNewElement(tag.SamplesPerPixel, []int{1}),
+		mustNewElement(tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+		mustNewElement(tag.WindowCenter, []string{"128"}),
+		mustNewElement(tag.WindowWidth, []string{"256"}),
+	}, PhotometricRGB, true)
+
+	ds := dicom.Dataset{Elements: elements}
+	if _, err := ds.FindElementByTag(tag.WindowCenter); err == nil {
+		t.Error("WindowCenter should be removed")
+	}
+	if got := elementInts(ds, tag.SamplesPerPixel); got != 3 {
+		t.Errorf("SamplesPerPixel = %d, want 3", got)
+	}
+	if got := elementInts(ds, tag.PlanarConfiguration); got != 1 {
+		t.Errorf("PlanarConfiguration = %d, want 1", got)
+	}
+}
+
+func TestColorElements_PaletteColor(t *testing.T) {
+	ds := dicom.Dataset{Elements: colorElements([]*dicom.Element{
+		mustNewElement(tag.SamplesPerPixel, []int{1}),
+		mustNewElement(tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+	}, PhotometricPaletteColor, false)}
+
+	if got := elementInts(ds, tag.SamplesPerPixel); got != 1 {
+		t.Errorf("SamplesPerPixel = %d, want 1", got)
+	}
+	if _, err := ds.FindElementByTag(tag.PlanarConfiguration); err == nil {
+		t.Error("PALETTE COLOR images should have no PlanarConfiguration")
+	}
+	descriptor, err := ds.FindElementByTag(tag.GreenPaletteColorLookupTableDescriptor)
+	if err != nil {
+		t.Fatal("missing GreenPaletteColorLookupTableDescriptor")
+	}
+	if got := descriptor.Value.GetValue().([]int); len(got) != 3 || got[0] != paletteEntries || got[1] != 0 || got[2] != 16 {
+		t.Errorf("descriptor = %v, want [%d 0 16]", got, paletteEntries)
+	}
+	red, err := ds.FindElementByTag(tag.RedPaletteColorLookupTableData)
+	if err != nil {
+		t.Fatal("missing RedPaletteColorLookupTableData")
+	}
+	data := red.Value.GetValue().([]byte)
+	if len(data) != 2*paletteEntries {
+		t.Fatalf("red LUT of %d bytes, want %d", len(data), 2*paletteEntries)
+	}
+	entry := func(i int) int { return int(data[2*i]) | int(data[2*i+1])<<8 }
+	if entry(0) != 0 || entry(paletteGrayEntries-1) != 0xFFFF {
+		t.Errorf("gray ramp from %#x to %#x, want 0 to 0xffff", entry(0), entry(paletteGrayEntries-1))
+	}
+	if entry(paletteGrayEntries+paletteDopplerLevels-1) != 0xFFFF || entry(paletteEntries-1) != 0 {
+		t.Error("fastest flow towards the probe should be red, away from it blue")
+	}
+}
+
+func elementInts(ds dicom.Dataset, tg tag.Tag) int {
+	elem, err := ds.FindElementByTag(tg)
+	if err != nil {
+		return -1
+	}
+	return elem.Value.GetValue().([]int)[0]
+}
+
+func TestColorDopplerFrame(t *testing.T) {
+	width, height := 64, 64
+	gray := frame.NewNativeFrame[uint8](8, height, width, width*height, 1)
+	for i := range gray.RawData {
+		gray.RawData[i] = 100
+	}
+	// The artery is at its peak flow at phase pi/2
+	x := width / 2
+	y := int((0.35 + 0.12*(0.5-dopplerBoxLeft)) * float64(height))
+
+	rgb := colorDopplerFrame(gray, width, height, 1.5708, PhotometricRGB, false)
+	if len(rgb.RawData) != width*height*3 {
+		t.Fatalf("%d RGB samples, want %d", len(rgb.RawData), width*height*3)
+	}
+	if r, g, b := rgb.RawData[3*(y*width+x)], rgb.RawData[3*(y*width+x)+1], rgb.RawData[3*(y*width+x)+2]; r <= g || b != 0 {
+		t.Errorf("artery pixel = (%d, %d, %d), want red", r, g, b)
+	}
+	if got := rgb.RawData[0:3]; got[0] != 100 || got[1] != 100 || got[2] != 100 {
+		t.Errorf("background pixel = %v, want gray", got)
+	}
+
+	planes := colorDopplerFrame(gray, width, height, 1.5708, PhotometricRGB, true)
+	i := y*width + x
+	if planes.RawData[i] != rgb.RawData[3*i] || planes.RawData[width*height+i] != rgb.RawData[3*i+1] {
+		t.Error("planar samples differ from the interleaved ones")
+	}
+
+	ybr := colorDopplerFrame(gray, width, height, 1.5708, PhotometricYBRFull422, false)
+	if len(ybr.RawData) != width*height*2 {
+		t.Fatalf("%d YBR_FULL_422 bytes, want %d", len(ybr.RawData), width*height*2)
+	}
+	if got := ybr.RawData[0:4]; got[0] != 100 || got[1] != 100 || got[2] != 128 || got[3] != 128 {
+		t.Errorf("gray pixel pair = %v, want Y 100 and neutral chroma", got)
+	}
+
+	palette := colorDopplerFrame(gray, width, height, 1.5708, PhotometricPaletteColor, false)
+	if len(palette.RawData) != width*height {
+		t.Fatalf("%d palette indices, want %d", len(palette.RawData), width*height)
+	}
+	if got, want := palette.RawData[0], paletteGrayIndex(100); got != want {
+		t.Errorf("background index = %d, want %d", got, want)
+	}
+	if r, g, b := paletteColor(int(palette.RawData[y*width+x])); r <= g || b != 0 {
+		t.Errorf("artery index %d maps to (%d, %d, %d), want red", palette.RawData[y*width+x], r, g, b)
+	}
+}
@@ -23,10 +23,21 @@ func mustNewPrivateElement(t tag.Tag, rawVR string, data any) *dicom.Element {
 	}
 }
 
+// mustNewElement creates a DICOM element with a standard tag or panics.
+func mustNewElement(t tag.Tag, data any) *dicom.Element {
+	elem, err := dicom.NewElement(t, data)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create element %v: %v", t, err))
+	}
+	return elem
+}
+
 // Applicator generates corruption elements based on the configured types.
 type Applicator struct {
 	config Config
 	rng    *rand.Rand
+	shared sharedSeries
+	mixed  mixedPatients
 }
 
 // NewApplicator creates a new corruption applicator.
@@ -50,6 +61,12 @@ func (a *Applicator) GenerateCorruptionElements() []*dicom.Element {
 	if a.config.HasType(MalformedLengths) {
 		elements = append(elements, generateMalformedPlaceholders()...)
 	}
+	if a.config.HasType(OddLengths) {
+		elements = append(elements, generateOddLengthElements()...)
+	}
+	if a.config.HasType(LegacyGroups) {
+		elements = append(elements, generateLegacyElements(a.rng)...)
+	}
 
 	return elements
 }
@@ -58,3 +75,44 @@ func (a *Applicator) GenerateCorruptionElements() []*dicom.Element {
 func (a *Applicator) HasMalformedLengths() bool {
 	return a.config.HasType(MalformedLengths)
 }
+
+// HasOddLengths returns true if odd-lengths corruption is enabled.
+func (a *Applicator) HasOddLengths() bool {
+	return a.config.HasType(OddLengths)
+}
+
+// HasCharsetMismatch returns true if charset-mismatch corruption is enabled.
+func (a *Applicator) HasCharsetMismatch() bool {
+	return a.config.HasType(CharsetMismatch)
+}
+
+// ApplyElementCorruption applies the corruptions that rewrite the existing
+// elements of a file: with invalid-uids, UIDs are too long or hold illegal
+// characters; with sop-class-mismatch, MediaStorageSOPClassUID claims another
+// SOP class than SOPClassUID; with empty-required, required tags are blanked;
+// with shared-series-uid, the first series of each study takes the
+// SeriesInstanceUID of a series of the first study; with mixed-patient-study,
+// every second file of a study belongs to another patient; with
+// charset-mismatch, text is written in another encoding than the declared
+// SpecificCharacterSet. Files must be passed in generation order.
+func (a *Applicator) ApplyElementCorruption(elements []*dicom.Element) []*dicom.Element {
+	if a.config.HasType(SharedSeriesUID) {
+		elements = a.shared.apply(elements)
+	}
+	if a.config.HasType(MixedPatientStudy) {
+		elements = a.mixed.apply(elements, a.rng)
+	}
+	if a.config.HasType(InvalidUIDs) {
+		elements = applyInvalidUIDs(elements)
+	}
+	if a.config.HasType(SOPClassMismatch) {
+		elements = applySOPClassMismatch(elements, a.rng)
+	}
+	if a.config.HasType(EmptyRequired) {
+		elements = blankRequiredTags(elements, a.rng)
+	}
+	if a.config.HasType(CharsetMismatch) {
+		elements = applyCharsetMismatch(elements, a.rng)
+	}
+	return elements
+}
@@ -33,6 +33,11 @@ func TestApplicator_GenerateCorruptionElements(t *testing.T) {
 			types:    []CorruptionType{MalformedLengths},
 			minCount: 1, // FL placeholder (PixelData patched in post-processing)
 		},
+		{
+			name:     "legacy groups only",
+			types:    []CorruptionType{LegacyGroups},
+			minCount: 20, // 8 + 7 curve elements + 5 annotations
+		},
 		{
 			name:     "all types",
 			types:    AllCorruptionTypes(),
@@ -0,0 +1,98 @@
+package corruption
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// charsetMismatch is a valid SpecificCharacterSet declared by a file, and the
+// encoding its text is actually written in
+type charsetMismatch struct {
+	declared string // SpecificCharacterSet defined term
+	latin1   bool   // Text written in ISO 8859-1, UTF-8 otherwise
+}
+
+// charsetMismatches are the mismatches of charset-mismatch: UTF-8 text
+// declared as a Latin alphabet, by far the most common (software that writes
+// its strings as they are), and Latin-1 text declared as UTF-8.
+var charsetMismatches = []charsetMismatch{
+	{declared: "ISO_IR 100"},
+	{declared: "ISO_IR 100"},
+	{declared: "ISO_IR 101"},
+	{declared: "ISO_IR 192", latin1: true},
+}
+
+// accentedFamilyNames replace the family name of ASCII patient names, so
+// that the mismatch shows in every file. All are in ISO 8859-1.
+var accentedFamilyNames = []string{"Müller", "García", "Lefèvre", "Jørgensen", "Núñez", "Björk"}
+
+// textVRs are the VRs of the values that SpecificCharacterSet applies to
+var textVRs = map[string]bool{"SH": true, "LO": true, "ST": true, "LT": true, "PN": true, "UC": true, "UT": true}
+
+// applyCharsetMismatch declares a SpecificCharacterSet drawn from
+// charsetMismatches and writes the text values of elements in the other
+// encoding. An ASCII PatientName gets an accented family name, the same for
+// all the files of the patient.
+func applyCharsetMismatch(elements []*dicom.Element, rng *rand.Rand) []*dicom.Element {
+	mismatch := charsetMismatches[rng.IntN(len(charsetMismatches))]
+	encode := func(s string) string {
+		if mismatch.latin1 {
+			return toLatin1(s)
+		}
+		return s
+	}
+
+	result := make([]*dicom.Element, 0, len(elements)+1)
+	for _, elem := range elements {
+		switch {
+		case elem.Tag == tag.SpecificCharacterSet:
+			continue
+		case elem.Tag == tag.PatientName:
+			elem = mustNewElement(tag.PatientName, []string{encode(accentName(firstString(elem)))})
+		case textVRs[elem.RawValueRepresentation] && mismatch.latin1:
+			if values, ok := elem.Value.GetValue().([]string); ok {
+				encoded := make([]string, len(values))
+				for i, v := range values {
+					encoded[i] = encode(v)
+				}
+				// Private text elements too, hence the explicit VR
+				elem = mustNewPrivateElement(elem.Tag, elem.RawValueRepresentation, encoded)
+			}
+		}
+		result = append(result, elem)
+	}
+	return append(result, mustNewElement(tag.SpecificCharacterSet, []string{mismatch.declared}))
+}
+
+// accentName returns name with an accented family name when it is ASCII.
+func accentName(name string) string {
+	for _, r := range name {
+		if r > 0x7F {
+			return name
+		}
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	family := accentedFamilyNames[h.Sum32()%uint32(len(accentedFamilyNames))]
+	if i := strings.IndexByte(name, '^'); i >= 0 {
+		return family + name[i:]
+	}
+	return family
+}
+
+// toLatin1 returns s encoded in ISO 8859-1, with '?' for the characters it
+// lacks.
+func toLatin1(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return string(b)
+}
@@ -0,0 +1,73 @@
+package corruption
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestApplyCharsetMismatch(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	declared := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		elements := []*dicom.Element{
+			mustNewElement(tag.SpecificCharacterSet, []string{"ISO_IR 192"}),
+			mustNewElement(tag.PatientName, []string{"Smith^John"}),
+			mustNewElement(tag.StudyDescription, []string{"Crâne"}),
+			mustNewElement(tag.Modality, []string{"MR"}),
+		}
+		result := applyCharsetMismatch(elements, rng)
+		if len(result) != len(elements) {
+			t.Fatalf("got %d elements, want %d", len(result), len(elements))
+		}
+
+		var charset, name, description string
+		for _, elem := range result {
+			switch elem.Tag {
+			case tag.SpecificCharacterSet:
+				if charset != "" {
+					t.Fatal("SpecificCharacterSet written twice")
+				}
+				charset = firstString(elem)
+			case tag.PatientName:
+				name = firstString(elem)
+			case tag.StudyDescription:
+				description = firstString(elem)
+			}
+		}
+		declared[charset] = true
+		if !strings.HasSuffix(name, "^John") || strings.HasPrefix(name, "Smith") {
+			t.Errorf("PatientName = %q, want an accented family name", name)
+		}
+
+		// Text is valid UTF-8 unless it is declared as such
+		if utf8Text := utf8.ValidString(name) && utf8.ValidString(description); utf8Text == (charset == "ISO_IR 192") {
+			t.Errorf("%s declared with UTF-8 text %v (name %q, description %q)", charset, utf8Text, name, description)
+		}
+	}
+	for _, m := range charsetMismatches {
+		if !declared[m.declared] {
+			t.Errorf("%s never declared: %v", m.declared, declared)
+		}
+	}
+}
+
+func TestAccentName(t *testing.T) {
+	// Same patient, same name in every file
+	if accentName("Smith^John") != accentName("Smith^John") {
+		t.Error("accentName is not deterministic")
+	}
+	if got := accentName("Lefèvre^Hélène"); got != "Lefèvre^Hélène" {
+		t.Errorf("accentName kept accented name as %q", got)
+	}
+}
+
+func TestToLatin1(t *testing.T) {
+	if got := toLatin1("Müller Łukasz"); got != "M\xfcller ?ukasz" {
+		t.Errorf("toLatin1 = %q", got)
+	}
+}
@@ -0,0 +1,41 @@
+package corruption
+
+import (
+	"math/rand/v2"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// requiredTags are the Type 1 tags (and PatientID, which archives treat as
+// required) that empty-required blanks. Ingestion should reject or
+// quarantine the file rather than index it under an empty key.
+var requiredTags = []tag.Tag{
+	tag.PatientID,
+	tag.StudyInstanceUID,
+	tag.SeriesInstanceUID,
+	tag.SOPInstanceUID,
+	tag.Modality,
+}
+
+// blankValues are the empty and whitespace-only values written
+var blankValues = []string{"", " ", "    "}
+
+// blankRequiredTags replaces the value of 1-2 required tags present in
+// elements with an empty or whitespace-only value.
+func blankRequiredTags(elements []*dicom.Element, rng *rand.Rand) []*dicom.Element {
+	count := 1 + rng.IntN(2)
+	blanked := make(map[tag.Tag]string, count)
+	for _, i := range rng.Perm(len(requiredTags))[:count] {
+		blanked[requiredTags[i]] = blankValues[rng.IntN(len(blankValues))]
+	}
+
+	result := make([]*dicom.Element, len(elements))
+	for i, elem := range elements {
+		if value, ok := blanked[elem.Tag]; ok {
+			elem = mustNewElement(elem.Tag, []string{value})
+		}
+		result[i] = elem
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package corruption
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestBlankRequiredTags(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	for i := 0; i < 20; i++ {
+		elements := []*dicom.Element{
+			mustNewElement(tag.PatientID, []string{"PID123456"}),
+			mustNewElement(tag.StudyInstanceUID, []string{"1.2.3"}),
+			mustNewElement(tag.SeriesInstanceUID, []string{"1.2.3.4"}),
+			mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.4.5"}),
+			mustNewElement(tag.Modality, []string{"MR"}),
+			mustNewElement(tag.StudyDescription, []string{"BRAIN"}),
+		}
+		result := blankRequiredTags(elements, rng)
+		if len(result) != len(elements) {
+			t.Fatalf("got %d elements, want %d", len(result), len(elements))
+		}
+
+		blank := 0
+		for _, elem := range result {
+			if strings.TrimSpace(firstString(elem)) == "" {
+				blank++
+				if elem.Tag == tag.StudyDescription {
+					t.Error("StudyDescription is not a required tag")
+				}
+			}
+		}
+		if blank < 1 || blank > 2 {
+			t.Errorf("got %d blank tags, want 1-2", blank)
+		}
+	}
+}
+
+func TestBlankRequiredTags_KeepsOriginal(t *testing.T) {
+	original := mustNewElement(tag.PatientID, []string{"PID123456"})
+	elements := []*dicom.Element{original}
+	for i := 0; i < 10; i++ {
+		blankRequiredTags(elements, rand.New(rand.NewPCG(uint64(i), 0)))
+	}
+	if firstString(original) != "PID123456" {
+		t.Error("blankRequiredTags modified the original element")
+	}
+}
@@ -0,0 +1,75 @@
+package corruption
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// uidTags are the UIDs rewritten by invalid-uids. MediaStorageSOPInstanceUID
+// is rewritten like SOPInstanceUID so that the two still agree.
+var uidTags = []tag.Tag{
+	tag.MediaStorageSOPInstanceUID,
+	tag.StudyInstanceUID,
+	tag.SeriesInstanceUID,
+	tag.SOPInstanceUID,
+}
+
+// maxUIDLength is the maximum length of a UID (PS3.5 9.1)
+const maxUIDLength = 64
+
+// invalidUID returns an invalid form of uid. The form is derived from the
+// UID itself, so that all the files of a study or series get the same
+// invalid StudyInstanceUID or SeriesInstanceUID and still group together.
+func invalidUID(uid string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(uid))
+	sum := h.Sum32()
+
+	// Keep room for the illegal suffixes within 64 characters, so that each
+	// form breaks a single rule
+	short := uid
+	if len(short) > maxUIDLength-8 {
+		short = strings.TrimRight(short[:maxUIDLength-8], ".")
+	}
+
+	switch sum % 5 {
+	case 0:
+		// Too long: pad with digit components past 64 characters
+		long := uid
+		for len(long) <= maxUIDLength+8 {
+			long += "." + strings.Repeat("9", 1+int(sum%7))
+		}
+		return long
+	case 1:
+		// Letters
+		return short + ".A" + string(rune('A'+sum%26)) + "F"
+	case 2:
+		// Component with a leading zero
+		return short + ".0" + string(rune('1'+sum%9))
+	case 3:
+		// Embedded space
+		return short[:len(short)/2] + " " + short[len(short)/2:]
+	default:
+		// Empty component and trailing dot
+		return short + "..1."
+	}
+}
+
+// applyInvalidUIDs replaces the UIDs of uidTags with invalid forms.
+func applyInvalidUIDs(elements []*dicom.Element) []*dicom.Element {
+	result := make([]*dicom.Element, len(elements))
+	for i, elem := range elements {
+		result[i] = elem
+		for _, t := range uidTags {
+			if elem.Tag == t {
+				if uid := firstString(elem); uid != "" {
+					result[i] = mustNewElement(elem.Tag, []string{invalidUID(uid)})
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package corruption
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// isValidUID reports whether uid follows PS3.5 9.1.
+func isValidUID(uid string) bool {
+	if uid == "" || len(uid) > maxUIDLength {
+		return false
+	}
+	for _, component := range strings.Split(uid, ".") {
+		if component == "" || (len(component) > 1 && component[0] == '0') {
+			return false
+		}
+		for _, c := range component {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestInvalidUID(t *testing.T) {
+	forms := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		uid := fmt.Sprintf("1.2.826.0.1.3680043.8.498.%d.%d", 1000000000+i*7919, i)
+		invalid := invalidUID(uid)
+		if isValidUID(invalid) {
+			t.Errorf("invalidUID(%s) = %q is valid", uid, invalid)
+		}
+		if invalidUID(uid) != invalid {
+			t.Errorf("invalidUID(%s) is not deterministic", uid)
+		}
+		switch {
+		case len(invalid) > maxUIDLength:
+			forms["long"] = true
+		case strings.ContainsAny(invalid, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"):
+			forms["letters"] = true
+		case strings.Contains(invalid, " "):
+			forms["space"] = true
+		case strings.HasSuffix(invalid, "."):
+			forms["empty component"] = true
+		default:
+			forms["leading zero"] = true
+		}
+	}
+	if len(forms) != 5 {
+		t.Errorf("got forms %v, want all 5", forms)
+	}
+}
+
+func TestApplyInvalidUIDs(t *testing.T) {
+	elements := []*dicom.Element{
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5"}),
+		mustNewElement(tag.StudyInstanceUID, []string{"1.2.3"}),
+		mustNewElement(tag.SeriesInstanceUID, []string{"1.2.3.4"}),
+		mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.4.5"}),
+		mustNewElement(tag.SOPClassUID, []string{mrImageStorage}),
+	}
+	result := applyInvalidUIDs(elements)
+
+	for _, elem := range result {
+		uid := firstString(elem)
+		if elem.Tag == tag.SOPClassUID {
+			if uid != mrImageStorage {
+				t.Errorf("SOPClassUID = %q, want unchanged", uid)
+			}
+			continue
+		}
+		if isValidUID(uid) {
+			t.Errorf("%v = %q is valid", elem.Tag, uid)
+		}
+	}
+	if firstString(result[0]) != firstString(result[3]) {
+		t.Error("MediaStorageSOPInstanceUID and SOPInstanceUID should still agree")
+	}
+	if firstString(elements[1]) != "1.2.3" {
+		t.Error("applyInvalidUIDs modified the original element")
+	}
+}
@@ -0,0 +1,111 @@
+package corruption
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Curves (groups 5000-501E) were retired in 2004 in favour of waveforms and
+// presentation states, and the ACR-NEMA Text group (4000) long before that,
+// but old archives still hold images carrying them, alongside annotations
+// burnt into the image object itself rather than kept in a presentation
+// state. Curve groups repeat, so their elements are built with explicit VRs.
+
+// Curve data value representations (DataValueRepresentation)
+const (
+	curveUS = 0
+	curveSS = 1
+)
+
+// generateLegacyElements creates the elements of the legacy-groups
+// corruption: a polygon ROI curve in group 5000, an intensity profile curve
+// in group 5002, retired text annotations, and a graphic annotation held in
+// the image.
+func generateLegacyElements(rng *rand.Rand) []*dicom.Element {
+	elements := generateROICurve(0x5000, rng)
+	elements = append(elements, generateProfileCurve(0x5002, rng)...)
+	elements = append(elements,
+		mustNewElement(tag.ImagePresentationComments, []string{"WINDOW SET BY TECHNOLOGIST"}),
+		mustNewElement(tag.AnnotationPosition, []int{1}),
+		mustNewElement(tag.TextString, []string{"LEGACY PRINT ANNOTATION"}),
+		mustNewElement(tag.Arbitrary, []string{"ACR-NEMA 2.0 TEXT"}),
+		mustNewElement(tag.TextComments, []string{"REVIEWED"}),
+		generateGraphicAnnotation(rng),
+	)
+	return elements
+}
+
+// generateROICurve creates a closed polygon outlining a region of interest,
+// as 2D unsigned pixel coordinates.
+func generateROICurve(group uint16, rng *rand.Rand) []*dicom.Element {
+	cx, cy := 20+rng.IntN(24), 20+rng.IntN(24)
+	r := 5 + rng.IntN(10)
+	corners := [][2]int{{cx - r, cy - r}, {cx + r, cy - r}, {cx + r, cy + r}, {cx - r, cy + r}, {cx - r, cy - r}}
+
+	data := make([]byte, 0, len(corners)*4)
+	for _, c := range corners {
+		data = binary.LittleEndian.AppendUint16(data, uint16(c[0]))
+		data = binary.LittleEndian.AppendUint16(data, uint16(c[1]))
+	}
+
+	return []*dicom.Element{
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0005}, "US", []int{2}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0010}, "US", []int{len(corners)}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0020}, "CS", []string{"ROI"}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0022}, "LO", []string{"REGION OF INTEREST"}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0030}, "SH", []string{"PIXL", "PIXL"}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0103}, "US", []int{curveUS}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x2500}, "LO", []string{"ROI 1"}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x3000}, "OW", data),
+	}
+}
+
+// generateProfileCurve creates a 1D signed intensity profile.
+func generateProfileCurve(group uint16, rng *rand.Rand) []*dicom.Element {
+	const points = 32
+	data := make([]byte, 0, points*2)
+	for i := 0; i < points; i++ {
+		data = binary.LittleEndian.AppendUint16(data, uint16(int16(rng.IntN(2000)-1000)))
+	}
+
+	return []*dicom.Element{
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0005}, "US", []int{1}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0010}, "US", []int{points}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0020}, "CS", []string{"PROF"}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0022}, "LO", []string{"INTENSITY PROFILE"}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x0103}, "US", []int{curveSS}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x2500}, "LO", []string{"PROFILE 1"}),
+		mustNewPrivateElement(tag.Tag{Group: group, Element: 0x3000}, "OW", data),
+	}
+}
+
+// generateGraphicAnnotation creates a GraphicAnnotationSequence with a text
+// label and an arrow polyline, in image pixel coordinates.
+func generateGraphicAnnotation(rng *rand.Rand) *dicom.Element {
+	x, y := float64(8+rng.IntN(16)), float64(8+rng.IntN(16))
+
+	text := []*dicom.Element{
+		mustNewElement(tag.BoundingBoxAnnotationUnits, []string{"PIXEL"}),
+		mustNewElement(tag.UnformattedTextValue, []string{"L"}),
+		mustNewElement(tag.BoundingBoxTopLeftHandCorner, []float64{x, y}),
+		mustNewElement(tag.BoundingBoxBottomRightHandCorner, []float64{x + 8, y + 8}),
+		mustNewElement(tag.BoundingBoxTextHorizontalJustification, []string{"LEFT"}),
+	}
+	arrow := []*dicom.Element{
+		mustNewElement(tag.GraphicAnnotationUnits, []string{"PIXEL"}),
+		mustNewElement(tag.GraphicDimensions, []int{2}),
+		mustNewElement(tag.NumberOfGraphicPoints, []int{2}),
+		mustNewElement(tag.GraphicData, []float64{x + 10, y + 10, x + 24, y + 24}),
+		mustNewElement(tag.GraphicType, []string{"POLYLINE"}),
+		mustNewElement(tag.GraphicFilled, []string{"N"}),
+	}
+
+	return mustNewElement(tag.GraphicAnnotationSequence, [][]*dicom.Element{{
+		mustNewElement(tag.GraphicLayer, []string{"LEGACY"}),
+		mustNewElement(tag.TextObjectSequence, [][]*dicom.Element{text}),
+		mustNewElement(tag.GraphicObjectSequence, [][]*dicom.Element{arrow}),
+	}})
+}
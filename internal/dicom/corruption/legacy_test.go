@@ -0,0 +1,70 @@
+package corruption
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestGenerateLegacyElements(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	elements := generateLegacyElements(rng)
+
+	byTag := make(map[tag.Tag]*dicom.Element)
+	for _, elem := range elements {
+		byTag[elem.Tag] = elem
+	}
+
+	for _, group := range []uint16{0x5000, 0x5002} {
+		data, ok := byTag[tag.Tag{Group: group, Element: 0x3000}]
+		if !ok {
+			t.Fatalf("missing CurveData in group %04X", group)
+		}
+		if data.RawValueRepresentation != "OW" {
+			t.Errorf("group %04X CurveData VR = %s, want OW", group, data.RawValueRepresentation)
+		}
+
+		dims := byTag[tag.Tag{Group: group, Element: 0x0005}].Value.GetValue().([]int)[0]
+		points := byTag[tag.Tag{Group: group, Element: 0x0010}].Value.GetValue().([]int)[0]
+		if got, want := len(data.Value.GetValue().([]byte)), dims*points*2; got != want {
+			t.Errorf("group %04X CurveData has %d bytes, want %d (%d points of %d dimensions)", group, got, want, points, dims)
+		}
+	}
+
+	// The ROI polygon is closed
+	roi := byTag[tag.Tag{Group: 0x5000, Element: 0x3000}].Value.GetValue().([]byte)
+	if !bytes.Equal(roi[:4], roi[len(roi)-4:]) {
+		t.Errorf("ROI curve is not closed: first point %v, last point %v",
+			binary.LittleEndian.Uint32(roi[:4]), binary.LittleEndian.Uint32(roi[len(roi)-4:]))
+	}
+
+	for _, want := range []tag.Tag{tag.ImagePresentationComments, tag.TextString, tag.TextComments, tag.GraphicAnnotationSequence} {
+		if _, ok := byTag[want]; !ok {
+			t.Errorf("missing %v", want)
+		}
+	}
+}
+
+func TestGenerateLegacyElements_Writable(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	elements := append([]*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+	}, generateLegacyElements(rng)...)
+
+	var buf bytes.Buffer
+	if err := dicom.Write(&buf, dicom.Dataset{Elements: elements}, dicom.SkipVRVerification()); err != nil {
+		t.Fatalf("write legacy elements: %v", err)
+	}
+
+	ds, err := dicom.Parse(&buf, int64(buf.Len()), nil)
+	if err != nil {
+		t.Fatalf("parse legacy elements: %v", err)
+	}
+	if _, err := ds.FindElementByTag(tag.Tag{Group: 0x5002, Element: 0x3000}); err != nil {
+		t.Errorf("CurveData of group 5002 not found after round trip: %v", err)
+	}
+}
@@ -46,6 +46,16 @@ func PatchMalformedLengths(filePath string) error {
 		return fmt.Errorf("read file for malformed patching: %w", err)
 	}
 
+	if !PatchMalformedLengthsData(data) {
+		return nil
+	}
+
+	return os.WriteFile(filePath, data, 0600)
+}
+
+// PatchMalformedLengthsData applies the PatchMalformedLengths patches in place
+// to the bytes of a written DICOM file. It reports whether anything was patched.
+func PatchMalformedLengthsData(data []byte) bool {
 	patched := false
 
 	// Rewrite the placeholder (0071,0010) OB -> (0070,0253) FL with VL=7
@@ -54,11 +64,7 @@ func PatchMalformedLengths(filePath string) error {
 	// Patch PixelData (7FE0,0010) OW -> odd VL (original VL minus 1)
 	patched = patchPixelDataOddLength(data) || patched
 
-	if !patched {
-		return nil
-	}
-
-	return os.WriteFile(filePath, data, 0600)
+	return patched
 }
 
 // rewriteTagAndPatch finds an element by its original tag, rewrites it to a new tag
@@ -0,0 +1,77 @@
+package corruption
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// intruder is the patient identity written in part of the files of a study
+type intruder struct {
+	id, name, birthDate string
+}
+
+// mixedPatients gives every second file of each study the identity of
+// another patient, so that the study holds instances of two PatientIDs. It
+// keeps state across files and must see them in generation order.
+type mixedPatients struct {
+	seen      map[string]int // StudyInstanceUID → files seen
+	intruders map[string]intruder
+}
+
+// apply replaces PatientID, PatientName and PatientBirthDate with those of
+// the study's intruder in every second file of the study.
+func (m *mixedPatients) apply(elements []*dicom.Element, rng *rand.Rand) []*dicom.Element {
+	var studyUID, patientID, sex string
+	for _, elem := range elements {
+		switch elem.Tag {
+		case tag.StudyInstanceUID:
+			studyUID = firstString(elem)
+		case tag.PatientID:
+			patientID = firstString(elem)
+		case tag.PatientSex:
+			sex = firstString(elem)
+		}
+	}
+	if studyUID == "" {
+		return elements
+	}
+
+	if m.seen == nil {
+		m.seen = make(map[string]int)
+		m.intruders = make(map[string]intruder)
+	}
+	m.seen[studyUID]++
+	if m.seen[studyUID]%2 == 1 {
+		return elements
+	}
+
+	other, ok := m.intruders[studyUID]
+	if !ok {
+		other = intruder{
+			name:      util.GeneratePatientName(sex, rng),
+			birthDate: util.GenerateDate(rng, 1930, 2010),
+		}
+		for other.id == "" || other.id == patientID {
+			other.id = fmt.Sprintf("PID%06d", rng.IntN(900000)+100000)
+		}
+		m.intruders[studyUID] = other
+	}
+
+	result := make([]*dicom.Element, len(elements))
+	for i, elem := range elements {
+		switch elem.Tag {
+		case tag.PatientID:
+			elem = mustNewElement(tag.PatientID, []string{other.id})
+		case tag.PatientName:
+			elem = mustNewElement(tag.PatientName, []string{other.name})
+		case tag.PatientBirthDate:
+			elem = mustNewElement(tag.PatientBirthDate, []string{other.birthDate})
+		}
+		result[i] = elem
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package corruption
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestMixedPatients(t *testing.T) {
+	file := func(study string) []*dicom.Element {
+		return []*dicom.Element{
+			mustNewElement(tag.PatientName, []string{"Doe^Jane"}),
+			mustNewElement(tag.PatientID, []string{"PID123456"}),
+			mustNewElement(tag.PatientBirthDate, []string{"19700101"}),
+			mustNewElement(tag.PatientSex, []string{"F"}),
+			mustNewElement(tag.StudyInstanceUID, []string{study}),
+		}
+	}
+	valueOf := func(elements []*dicom.Element, tg tag.Tag) string {
+		for _, elem := range elements {
+			if elem.Tag == tg {
+				return firstString(elem)
+			}
+		}
+		return ""
+	}
+
+	applicator := NewApplicator(Config{Types: []CorruptionType{MixedPatientStudy}}, rand.New(rand.NewPCG(42, 0)))
+	intruders := make(map[string]string)
+	for i, study := range []string{"1.1", "1.1", "1.1", "1.1", "1.2", "1.2"} {
+		result := applicator.ApplyElementCorruption(file(study))
+		id := valueOf(result, tag.PatientID)
+		if i%2 == 0 {
+			if id != "PID123456" || valueOf(result, tag.PatientName) != "Doe^Jane" {
+				t.Errorf("file %d: patient changed to %s", i, id)
+			}
+			continue
+		}
+		if id == "PID123456" || valueOf(result, tag.PatientName) == "Doe^Jane" || valueOf(result, tag.PatientBirthDate) == "19700101" {
+			t.Errorf("file %d: patient identity not replaced (%s)", i, id)
+		}
+		if previous, ok := intruders[study]; ok && previous != id {
+			t.Errorf("file %d: study %s has intruders %s and %s", i, study, previous, id)
+		}
+		intruders[study] = id
+	}
+	if len(intruders) != 2 {
+		t.Errorf("got intruders for %d studies, want 2", len(intruders))
+	}
+}
@@ -0,0 +1,110 @@
+package corruption
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Values must have an even length: the writer pads odd-length strings with a
+// space (or a NUL for UIDs). Some encoders forget the padding byte, and
+// parsers differ in how they handle the odd length that results, as they do
+// for OB/OW elements with no value at all.
+
+// oddLengthComment is the placeholder value whose padding byte is removed.
+// It has 15 characters, written as 16 with the padding space.
+const oddLengthComment = "PADDING REMOVED"
+
+// generateOddLengthElements creates the elements of the odd-lengths
+// corruption: an ImageComments placeholder that PatchOddLengthsData unpads,
+// and zero-length OB and OW elements.
+func generateOddLengthElements() []*dicom.Element {
+	return []*dicom.Element{
+		mustNewElement(tag.ImageComments, []string{oddLengthComment}),
+		mustNewPrivateElement(tag.ICCProfile, "OB", []byte{}),
+		mustNewPrivateElement(tag.RedPaletteColorLookupTableData, "OW", []byte{}),
+	}
+}
+
+// PatchOddLengths removes the padding byte of odd-length values in a written
+// DICOM file (see PatchOddLengthsData).
+func PatchOddLengths(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read file for odd length patching: %w", err)
+	}
+
+	patched, ok := PatchOddLengthsData(data)
+	if !ok {
+		return nil
+	}
+
+	return os.WriteFile(filePath, patched, 0600)
+}
+
+// PatchOddLengthsData removes the padding byte of the ImageComments
+// placeholder and of SOPInstanceUID when its UID has an odd length, leaving
+// their value lengths odd. It returns the patched bytes (shorter than data)
+// and whether anything was patched.
+func PatchOddLengthsData(data []byte) ([]byte, bool) {
+	patched := false
+	var ok bool
+
+	if data, ok = removePadding(data, tag.ImageComments, "LT"); ok {
+		patched = true
+	}
+	if data, ok = removePadding(data, tag.SOPInstanceUID, "UI"); ok {
+		patched = true
+	}
+
+	return data, patched
+}
+
+// removePadding finds the first element with the given tag and VR, in the
+// short form of explicit VR or, in an Implicit VR Little Endian file, with the
+// tag alone and a 32-bit length, and, if its value ends with a padding byte,
+// removes that byte and decrements the value length.
+func removePadding(data []byte, t tag.Tag, vr string) ([]byte, bool) {
+	tagBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint16(tagBytes[0:2], t.Group)
+	binary.LittleEndian.PutUint16(tagBytes[2:4], t.Element)
+
+	start, implicit := 0, false
+	if meta, end, err := util.ParseFileMeta(data); err == nil {
+		start, implicit = end, meta.TransferSyntaxUID == implicitVRLittleEndian
+	}
+
+	for i := start; i <= len(data)-8; i++ {
+		if !bytes.Equal(data[i:i+4], tagBytes) || (!implicit && string(data[i+4:i+6]) != vr) {
+			continue
+		}
+
+		// Implicit VR: VL(4); explicit VR short form: VR(2) + VL(2)
+		var vl int
+		if implicit {
+			vl = int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		} else {
+			vl = int(binary.LittleEndian.Uint16(data[i+6 : i+8]))
+		}
+		end := i + 8 + vl
+		if vl == 0 || vl%2 != 0 || end > len(data) {
+			return data, false
+		}
+		if pad := data[end-1]; pad != ' ' && pad != 0x00 {
+			return data, false
+		}
+
+		if implicit {
+			binary.LittleEndian.PutUint32(data[i+4:i+8], uint32(vl-1))
+		} else {
+			binary.LittleEndian.PutUint16(data[i+6:i+8], uint16(vl-1))
+		}
+		return append(data[:end-1], data[end:]...), true
+	}
+	return data, false
+}
@@ -0,0 +1,134 @@
+package corruption
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// writeOddLengthFile writes a small dataset with the odd-lengths elements, in
+// Explicit VR Little Endian.
+func writeOddLengthFile(t *testing.T, sopInstanceUID string) []byte {
+	t.Helper()
+	return writeOddLengthFileWithTransferSyntax(t, sopInstanceUID, explicitVRLittleEndian)
+}
+
+// writeOddLengthFileWithTransferSyntax writes a small dataset with the
+// odd-lengths elements in the transfer syntax.
+func writeOddLengthFileWithTransferSyntax(t *testing.T, sopInstanceUID, transferSyntax string) []byte {
+	t.Helper()
+	elements := []*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{transferSyntax}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+	}
+	elements = append(elements, generateOddLengthElements()...)
+
+	var buf bytes.Buffer
+	if err := dicom.Write(&buf, dicom.Dataset{Elements: elements}, dicom.SkipVRVerification(), dicom.SkipValueTypeVerification()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// valueLength returns the short-form value length of the first element with
+// the tag, or -1.
+func valueLength(data []byte, group, element uint16) int {
+	pattern := make([]byte, 4)
+	binary.LittleEndian.PutUint16(pattern[0:2], group)
+	binary.LittleEndian.PutUint16(pattern[2:4], element)
+	i := bytes.Index(data, pattern)
+	if i < 0 {
+		return -1
+	}
+	return int(binary.LittleEndian.Uint16(data[i+6 : i+8]))
+}
+
+func TestPatchOddLengthsData(t *testing.T) {
+	data := writeOddLengthFile(t, "1.2.345") // 7 characters, padded to 8
+	patched, ok := PatchOddLengthsData(append([]byte(nil), data...))
+	if !ok {
+		t.Fatal("PatchOddLengthsData should patch")
+	}
+	if len(patched) != len(data)-2 {
+		t.Errorf("patched size = %d, want %d", len(patched), len(data)-2)
+	}
+	if vl := valueLength(patched, 0x0020, 0x4000); vl != len(oddLengthComment) {
+		t.Errorf("ImageComments VL = %d, want %d", vl, len(oddLengthComment))
+	}
+	if vl := valueLength(patched, 0x0008, 0x0018); vl != 7 {
+		t.Errorf("SOPInstanceUID VL = %d, want 7", vl)
+	}
+	if !bytes.Contains(patched, []byte("1.2.345"+"\x20\x00")) {
+		t.Error("SOPInstanceUID is not followed by the next tag")
+	}
+}
+
+func TestPatchOddLengthsData_ImplicitVR(t *testing.T) {
+	data := writeOddLengthFileWithTransferSyntax(t, "1.2.345", implicitVRLittleEndian)
+	patched, ok := PatchOddLengthsData(append([]byte(nil), data...))
+	if !ok {
+		t.Fatal("PatchOddLengthsData should patch")
+	}
+	if len(patched) != len(data)-2 {
+		t.Errorf("patched size = %d, want %d", len(patched), len(data)-2)
+	}
+	// Implicit VR: Tag(4) + VL(4)
+	for _, elem := range []struct {
+		tag  []byte
+		want int
+	}{
+		{[]byte{0x20, 0x00, 0x00, 0x40}, len(oddLengthComment)}, // ImageComments
+		{[]byte{0x08, 0x00, 0x18, 0x00}, 7},                     // SOPInstanceUID
+	} {
+		i := bytes.Index(patched, elem.tag)
+		if i < 0 {
+			t.Fatalf("missing element % X", elem.tag)
+		}
+		if vl := int(binary.LittleEndian.Uint32(patched[i+4 : i+8])); vl != elem.want {
+			t.Errorf("% X VL = %d, want %d", elem.tag, vl, elem.want)
+		}
+	}
+
+	ds, err := dicom.Parse(bytes.NewReader(patched), int64(len(patched)), nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	elem, err := ds.FindElementByTag(tag.ImageComments)
+	if err != nil || elem.Value.GetValue().([]string)[0] != oddLengthComment {
+		t.Errorf("ImageComments = %v, %v, want %q", elem, err, oddLengthComment)
+	}
+}
+
+func TestPatchOddLengthsData_EvenUID(t *testing.T) {
+	data := writeOddLengthFile(t, "1.2.3456") // Even: no padding to remove
+	patched, _ := PatchOddLengthsData(data)
+	if vl := valueLength(patched, 0x0008, 0x0018); vl != 8 {
+		t.Errorf("SOPInstanceUID VL = %d, want 8", vl)
+	}
+}
+
+func TestGenerateOddLengthElements_ZeroLength(t *testing.T) {
+	data := writeOddLengthFile(t, "1.2.3456")
+	for _, elem := range []struct {
+		tag []byte
+		vr  string
+	}{
+		{[]byte{0x28, 0x00, 0x00, 0x20}, "OB"}, // ICCProfile
+		{[]byte{0x28, 0x00, 0x01, 0x12}, "OW"}, // RedPaletteColorLookupTableData
+	} {
+		i := bytes.Index(data, elem.tag)
+		if i < 0 {
+			t.Fatalf("missing %s element", elem.vr)
+		}
+		// Long form: VR(2) + Reserved(2) + VL(4)
+		if vr := string(data[i+4 : i+6]); vr != elem.vr {
+			t.Errorf("VR = %s, want %s", vr, elem.vr)
+		}
+		if vl := binary.LittleEndian.Uint32(data[i+8 : i+12]); vl != 0 {
+			t.Errorf("%s VL = %d, want 0", elem.vr, vl)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package corruption
+
+import (
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// sharedSeries makes the first series of each study reuse the
+// SeriesInstanceUID of the first series of the first study, so that the same
+// series UID appears under several StudyInstanceUIDs. It keeps state across
+// files and must see them in generation order.
+type sharedSeries struct {
+	donorStudy, donorSeries string
+	hijacked                map[string]string // StudyInstanceUID → its series UID to replace
+}
+
+// apply replaces the SeriesInstanceUID of the file when it belongs to the
+// first series of a study other than the first one.
+func (s *sharedSeries) apply(elements []*dicom.Element) []*dicom.Element {
+	var studyUID, seriesUID string
+	for _, elem := range elements {
+		switch elem.Tag {
+		case tag.StudyInstanceUID:
+			studyUID = firstString(elem)
+		case tag.SeriesInstanceUID:
+			seriesUID = firstString(elem)
+		}
+	}
+	if studyUID == "" || seriesUID == "" {
+		return elements
+	}
+
+	if s.donorStudy == "" {
+		s.donorStudy, s.donorSeries = studyUID, seriesUID
+		s.hijacked = make(map[string]string)
+	}
+	if studyUID == s.donorStudy {
+		return elements
+	}
+	if _, ok := s.hijacked[studyUID]; !ok {
+		s.hijacked[studyUID] = seriesUID
+	}
+	if s.hijacked[studyUID] != seriesUID {
+		return elements
+	}
+
+	result := make([]*dicom.Element, len(elements))
+	for i, elem := range elements {
+		result[i] = elem
+		if elem.Tag == tag.SeriesInstanceUID {
+			result[i] = mustNewElement(tag.SeriesInstanceUID, []string{s.donorSeries})
+		}
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package corruption
+
+import (
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestSharedSeries(t *testing.T) {
+	file := func(study, series string) []*dicom.Element {
+		return []*dicom.Element{
+			mustNewElement(tag.StudyInstanceUID, []string{study}),
+			mustNewElement(tag.SeriesInstanceUID, []string{series}),
+		}
+	}
+	seriesOf := func(elements []*dicom.Element) string {
+		for _, elem := range elements {
+			if elem.Tag == tag.SeriesInstanceUID {
+				return firstString(elem)
+			}
+		}
+		return ""
+	}
+
+	// Files in generation order: (study, series) → expected series UID
+	tests := []struct {
+		study, series, want string
+	}{
+		{"1.1", "1.1.1", "1.1.1"},
+		{"1.1", "1.1.1", "1.1.1"},
+		{"1.1", "1.1.2", "1.1.2"},
+		{"1.2", "1.2.1", "1.1.1"}, // First series of the second study
+		{"1.2", "1.2.1", "1.1.1"},
+		{"1.2", "1.2.2", "1.2.2"},
+		{"1.3", "1.3.1", "1.1.1"},
+	}
+
+	applicator := NewApplicator(Config{Types: []CorruptionType{SharedSeriesUID}}, nil)
+	for i, tt := range tests {
+		if got := seriesOf(applicator.ApplyElementCorruption(file(tt.study, tt.series))); got != tt.want {
+			t.Errorf("file %d (study %s, series %s): SeriesInstanceUID = %s, want %s", i, tt.study, tt.series, got, tt.want)
+		}
+	}
+}
@@ -1,83 +1,16 @@
 package corruption
 
 import (
-	"bytes"
-	"encoding/binary"
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/dicom/csa"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
-// csaElement represents a single element in a CSA header
-type csaElement struct {
-	Name     string
-	VM       int32
-	VR       string
-	SyngoDT  int32
-	NumItems int32
-	Values   []string
-}
-
-// buildCSAHeader encodes a list of CSA elements into the "SV10" binary format
-// used by Siemens scanners.
-func buildCSAHeader(elements []csaElement) []byte {
-	var buf bytes.Buffer
-
-	// Magic bytes: "SV10" followed by 0x04, 0x03, 0x02, 0x01
-	buf.WriteString("SV10")
-	buf.Write([]byte{0x04, 0x03, 0x02, 0x01})
-
-	// binary.Write to bytes.Buffer never fails; discard errors explicitly.
-	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(elements)))
-	_ = binary.Write(&buf, binary.LittleEndian, uint32(0x4D))
-
-	for _, elem := range elements {
-		// Element name: 64 bytes, null-padded
-		name := make([]byte, 64)
-		copy(name, elem.Name)
-		buf.Write(name)
-
-		_ = binary.Write(&buf, binary.LittleEndian, elem.VM)
-
-		// VR: 4 bytes, null-padded
-		vr := make([]byte, 4)
-		copy(vr, elem.VR)
-		buf.Write(vr)
-
-		_ = binary.Write(&buf, binary.LittleEndian, elem.SyngoDT)
-		_ = binary.Write(&buf, binary.LittleEndian, elem.NumItems)
-		_ = binary.Write(&buf, binary.LittleEndian, uint32(0x4D))
-
-		// Write items
-		for i := int32(0); i < elem.NumItems; i++ {
-			var val []byte
-			if i < int32(len(elem.Values)) {
-				val = []byte(elem.Values[i])
-			}
-
-			// Item length (repeated 4 times per CSA format)
-			itemLen := uint32(len(val))
-			for j := 0; j < 4; j++ {
-				_ = binary.Write(&buf, binary.LittleEndian, itemLen)
-			}
-
-			// Item data
-			buf.Write(val)
-
-			// Pad to 4-byte boundary
-			if padding := (4 - len(val)%4) % 4; padding > 0 {
-				buf.Write(make([]byte, padding))
-			}
-		}
-	}
-
-	return buf.Bytes()
-}
-
 // generateCSAImageHeader creates a realistic CSA Image Header blob
 func generateCSAImageHeader(rng *rand.Rand) []byte {
-	elements := []csaElement{
+	elements := []csa.Element{
 		{
 			Name: "NumberOfImagesInMosaic", VM: 1, VR: "IS", SyngoDT: 6, NumItems: 1,
 			Values: []string{"1"},
@@ -126,13 +59,13 @@ func generateCSAImageHeader(rng *rand.Rand) []byte {
 		extraPadding[i] = byte(rng.IntN(256))
 	}
 
-	header := buildCSAHeader(elements)
+	header := csa.Encode(elements)
 	return append(header, extraPadding...)
 }
 
 // generateCSASeriesHeader creates a realistic CSA Series Header blob
 func generateCSASeriesHeader(rng *rand.Rand) []byte {
-	elements := []csaElement{
+	elements := []csa.Element{
 		{
 			Name: "UsedPatientWeight", VM: 1, VR: "DS", SyngoDT: 3, NumItems: 1,
 			Values: []string{"70.0"},
@@ -173,7 +106,7 @@ func generateCSASeriesHeader(rng *rand.Rand) []byte {
 		extraPadding[i] = byte(rng.IntN(256))
 	}
 
-	header := buildCSAHeader(elements)
+	header := csa.Encode(elements)
 	return append(header, extraPadding...)
 }
 
@@ -5,25 +5,6 @@ import (
 	"testing"
 )
 
-func TestBuildCSAHeader(t *testing.T) {
-	elements := []csaElement{
-		{
-			Name: "TestElement", VM: 1, VR: "IS", SyngoDT: 6, NumItems: 1,
-			Values: []string{"42"},
-		},
-	}
-
-	data := buildCSAHeader(elements)
-
-	// Verify magic bytes
-	if string(data[0:4]) != "SV10" {
-		t.Errorf("expected SV10 magic, got %q", string(data[0:4]))
-	}
-	if data[4] != 0x04 || data[5] != 0x03 || data[6] != 0x02 || data[7] != 0x01 {
-		t.Error("incorrect secondary magic bytes")
-	}
-}
-
 func TestGenerateCSAImageHeader(t *testing.T) {
 	rng := rand.New(rand.NewPCG(42, 42))
 	header := generateCSAImageHeader(rng)
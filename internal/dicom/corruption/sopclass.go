@@ -0,0 +1,78 @@
+package corruption
+
+import (
+	"math/rand/v2"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Storage SOP classes claimed by the file meta information. Real mismatches
+// come from converters and gateways that keep the meta of the original file
+// (e.g., an Enhanced MR converted to legacy MR, or images re-wrapped as
+// Secondary Capture).
+var claimedSOPClasses = []string{
+	"1.2.840.10008.5.1.4.1.1.7",     // Secondary Capture Image Storage
+	"1.2.840.10008.5.1.4.1.1.4",     // MR Image Storage
+	"1.2.840.10008.5.1.4.1.1.4.1",   // Enhanced MR Image Storage
+	"1.2.840.10008.5.1.4.1.1.2",     // CT Image Storage
+	"1.2.840.10008.5.1.4.1.1.2.1",   // Enhanced CT Image Storage
+	"1.2.840.10008.5.1.4.1.1.1",     // Computed Radiography Image Storage
+	"1.2.840.10008.5.1.4.1.1.1.1",   // Digital X-Ray Image Storage - For Presentation
+	"1.2.840.10008.5.1.4.1.1.6.1",   // Ultrasound Image Storage
+	"1.2.840.10008.5.1.4.1.1.88.33", // Comprehensive SR Storage
+}
+
+// claimedSOPClass picks a SOP class different from the actual one.
+func claimedSOPClass(actual string, rng *rand.Rand) string {
+	candidates := make([]string, 0, len(claimedSOPClasses))
+	for _, uid := range claimedSOPClasses {
+		if uid != actual {
+			candidates = append(candidates, uid)
+		}
+	}
+	return candidates[rng.IntN(len(candidates))]
+}
+
+// applySOPClassMismatch sets MediaStorageSOPClassUID in the file meta to a
+// SOP class other than the SOPClassUID of the dataset. It also sets
+// MediaStorageSOPInstanceUID from SOPInstanceUID when it is missing, so that
+// only the class disagrees. Elements without a SOPClassUID are unchanged.
+func applySOPClassMismatch(elements []*dicom.Element, rng *rand.Rand) []*dicom.Element {
+	var sopClassUID, sopInstanceUID string
+	hasMediaInstance := false
+	for _, elem := range elements {
+		switch elem.Tag {
+		case tag.SOPClassUID:
+			sopClassUID = firstString(elem)
+		case tag.SOPInstanceUID:
+			sopInstanceUID = firstString(elem)
+		case tag.MediaStorageSOPInstanceUID:
+			hasMediaInstance = true
+		}
+	}
+	if sopClassUID == "" {
+		return elements
+	}
+
+	result := make([]*dicom.Element, 0, len(elements)+2)
+	for _, elem := range elements {
+		if elem.Tag != tag.MediaStorageSOPClassUID {
+			result = append(result, elem)
+		}
+	}
+	result = append(result, mustNewElement(tag.MediaStorageSOPClassUID, []string{claimedSOPClass(sopClassUID, rng)}))
+	if !hasMediaInstance && sopInstanceUID != "" {
+		result = append(result, mustNewElement(tag.MediaStorageSOPInstanceUID, []string{sopInstanceUID}))
+	}
+	return result
+}
+
+// firstString returns the first string value of an element, or "".
+func firstString(elem *dicom.Element) string {
+	values, ok := elem.Value.GetValue().([]string)
+	if !ok || len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
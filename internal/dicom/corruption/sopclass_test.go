@@ -0,0 +1,76 @@
+package corruption
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const mrImageStorage = "1.2.840.10008.5.1.4.1.1.4"
+
+// findString returns the first string value of a tag in elements.
+func findString(elements []*dicom.Element, t tag.Tag) (string, int) {
+	value, count := "", 0
+	for _, elem := range elements {
+		if elem.Tag == t {
+			value = firstString(elem)
+			count++
+		}
+	}
+	return value, count
+}
+
+func TestApplySOPClassMismatch(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	for i := 0; i < 20; i++ {
+		elements := []*dicom.Element{
+			mustNewElement(tag.SOPClassUID, []string{mrImageStorage}),
+			mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.4"}),
+		}
+		elements = applySOPClassMismatch(elements, rng)
+
+		claimed, count := findString(elements, tag.MediaStorageSOPClassUID)
+		if count != 1 {
+			t.Fatalf("got %d MediaStorageSOPClassUID elements, want 1", count)
+		}
+		if claimed == mrImageStorage || claimed == "" {
+			t.Errorf("MediaStorageSOPClassUID = %q, want another SOP class", claimed)
+		}
+		if instance, _ := findString(elements, tag.MediaStorageSOPInstanceUID); instance != "1.2.3.4" {
+			t.Errorf("MediaStorageSOPInstanceUID = %q, want 1.2.3.4", instance)
+		}
+	}
+}
+
+func TestApplySOPClassMismatch_ReplacesMeta(t *testing.T) {
+	elements := []*dicom.Element{
+		mustNewElement(tag.MediaStorageSOPClassUID, []string{mrImageStorage}),
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4"}),
+		mustNewElement(tag.SOPClassUID, []string{mrImageStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.4"}),
+	}
+	elements = applySOPClassMismatch(elements, rand.New(rand.NewPCG(1, 1)))
+
+	if claimed, count := findString(elements, tag.MediaStorageSOPClassUID); count != 1 || claimed == mrImageStorage {
+		t.Errorf("MediaStorageSOPClassUID = %q (%d elements), want one other SOP class", claimed, count)
+	}
+	if _, count := findString(elements, tag.MediaStorageSOPInstanceUID); count != 1 {
+		t.Errorf("got %d MediaStorageSOPInstanceUID elements, want 1", count)
+	}
+}
+
+func TestApplicator_ApplyElementCorruption(t *testing.T) {
+	elements := []*dicom.Element{mustNewElement(tag.SOPClassUID, []string{mrImageStorage})}
+
+	without := NewApplicator(Config{Types: []CorruptionType{SiemensCSA}}, rand.New(rand.NewPCG(42, 42)))
+	if got := without.ApplyElementCorruption(elements); len(got) != len(elements) {
+		t.Errorf("got %d elements without sop-class-mismatch, want %d", len(got), len(elements))
+	}
+
+	with := NewApplicator(Config{Types: []CorruptionType{SOPClassMismatch}}, rand.New(rand.NewPCG(42, 42)))
+	if _, count := findString(with.ApplyElementCorruption(elements), tag.MediaStorageSOPClassUID); count != 1 {
+		t.Error("sop-class-mismatch should add MediaStorageSOPClassUID")
+	}
+}
@@ -0,0 +1,207 @@
+package corruption
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// Named truncation points: halfway between the "DICM" prefix and the
+// PixelData element, or halfway through the pixel data value, as a transfer
+// interrupted while sending the attributes or the pixels leaves the file.
+const (
+	TruncateMidHeader    = "mid-header"
+	TruncateMidPixelData = "mid-pixel-data"
+)
+
+// dicmEnd is the offset of the File Meta Information, after the 128-byte
+// preamble and the "DICM" prefix.
+const dicmEnd = 132
+
+// Truncation is the point at which the truncated corruption cuts the files:
+// a named point, a byte offset or a percentage of the file. The zero value
+// cuts the files mid-pixel-data.
+type Truncation struct {
+	Point   string  // TruncateMidHeader or TruncateMidPixelData ("" with Offset or Percent)
+	Offset  int64   // Bytes kept
+	Percent float64 // Percentage of the file kept (0-100, exclusive)
+}
+
+// ParseTruncation parses a truncation point: "mid-header", "mid-pixel-data",
+// a byte offset ("4096") or a percentage of the file ("50%"). An empty string
+// is mid-pixel-data.
+func ParseTruncation(s string) (Truncation, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "" || s == TruncateMidPixelData:
+		return Truncation{Point: TruncateMidPixelData}, nil
+	case s == TruncateMidHeader:
+		return Truncation{Point: TruncateMidHeader}, nil
+	case strings.HasSuffix(s, "%"):
+		p, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+		if err != nil || p <= 0 || p >= 100 {
+			return Truncation{}, fmt.Errorf("invalid truncation percentage %q (must be between 0%% and 100%%, exclusive)", s)
+		}
+		return Truncation{Percent: p}, nil
+	}
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset <= 0 {
+		return Truncation{}, fmt.Errorf("invalid truncation point %q (valid: %s, %s, a byte offset or a percentage)",
+			s, TruncateMidHeader, TruncateMidPixelData)
+	}
+	return Truncation{Offset: offset}, nil
+}
+
+// Validate checks the truncation point.
+func (t Truncation) Validate() error {
+	switch {
+	case t.Point != "" && t.Point != TruncateMidHeader && t.Point != TruncateMidPixelData:
+		return fmt.Errorf("unknown truncation point %q", t.Point)
+	case t.Offset < 0:
+		return fmt.Errorf("truncation offset must be >= 0, got %d", t.Offset)
+	case t.Percent < 0 || t.Percent >= 100:
+		return fmt.Errorf("truncation percentage must be between 0 and 100, got %g", t.Percent)
+	}
+	return nil
+}
+
+// String returns the truncation point as ParseTruncation reads it.
+func (t Truncation) String() string {
+	switch {
+	case t.Percent > 0:
+		return strconv.FormatFloat(t.Percent, 'g', -1, 64) + "%"
+	case t.Offset > 0:
+		return strconv.FormatInt(t.Offset, 10)
+	case t.Point != "":
+		return t.Point
+	}
+	return TruncateMidPixelData
+}
+
+// TruncatedLength returns the number of bytes of a written DICOM file kept by
+// the truncation, len(data) when the cut point is past the end of the file.
+// It returns an error when the file has no PixelData element to cut
+// mid-pixel-data.
+func TruncatedLength(data []byte, t Truncation) (int, error) {
+	n := len(data)
+	switch {
+	case t.Percent > 0:
+		n = int(float64(len(data)) * t.Percent / 100)
+	case t.Offset > 0:
+		n = int(min(t.Offset, int64(len(data))))
+	case t.Point == TruncateMidHeader:
+		end := len(data)
+		if start, _, _, err := pixelDataOffsets(data); err == nil {
+			end = start
+		}
+		if end > dicmEnd {
+			n = dicmEnd + (end-dicmEnd)/2
+		} else {
+			n = end / 2
+		}
+	default:
+		_, value, valueEnd, err := pixelDataOffsets(data)
+		if err != nil {
+			return 0, fmt.Errorf("cut mid-pixel-data: %w", err)
+		}
+		n = value + (valueEnd-value)/2
+	}
+	return max(n, 0), nil
+}
+
+// TruncateFile cuts a written DICOM file at the truncation point, as an
+// interrupted transfer would leave it.
+func TruncateFile(filePath string, t Truncation) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read file for truncation: %w", err)
+	}
+	n, err := TruncatedLength(data, t)
+	if err != nil {
+		return err
+	}
+	if n >= len(data) {
+		return nil
+	}
+	if err := os.Truncate(filePath, int64(n)); err != nil {
+		return fmt.Errorf("truncate file: %w", err)
+	}
+	return nil
+}
+
+// Transfer syntaxes whose data set encoding differs from Explicit VR Little
+// Endian, the one of the File Meta Information.
+const (
+	implicitVRLittleEndian = "1.2.840.10008.1.2"
+	explicitVRBigEndian    = "1.2.840.10008.1.2.2"
+)
+
+// undefinedLength is the value length of encapsulated pixel data.
+const undefinedLength = 0xFFFFFFFF
+
+// pixelDataOffsets finds the top-level PixelData element (7FE0,0010) in the
+// encoding of the file's transfer syntax. It returns the offsets of the
+// element, of its value and of the end of its value (the end of the file for
+// encapsulated pixel data, of undefined length). As PixelData follows all the
+// other attributes but the trailing padding, a candidate is only accepted
+// when its value runs to the end of the file or up to an element of a later
+// group, so that its tag bytes found inside an earlier value are skipped.
+func pixelDataOffsets(data []byte) (element, value, valueEnd int, err error) {
+	meta, start, err := util.ParseFileMeta(data)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	transferSyntax := meta.TransferSyntaxUID
+	var order binary.ByteOrder = binary.LittleEndian
+	tag := []byte{0xE0, 0x7F, 0x10, 0x00}
+	if transferSyntax == explicitVRBigEndian {
+		order = binary.BigEndian
+		tag = []byte{0x7F, 0xE0, 0x00, 0x10}
+	}
+	implicit := transferSyntax == implicitVRLittleEndian
+
+	// endsPixelData reports whether a value of length vl starting at offset v
+	// is the last of the data set but for elements of a later group
+	endsPixelData := func(v int, vl uint32) (int, bool) {
+		if vl == undefinedLength {
+			return len(data), true
+		}
+		end := int64(v) + int64(vl)
+		switch {
+		case end == int64(len(data)):
+			return len(data), true
+		case end+4 <= int64(len(data)) && order.Uint16(data[end:end+2]) > 0x7FE0:
+			return int(end), true
+		}
+		return 0, false
+	}
+
+	for i := start; i <= len(data)-8; i++ {
+		if !bytes.Equal(data[i:i+4], tag) {
+			continue
+		}
+		if implicit {
+			// Tag(4) + VL(4)
+			if end, ok := endsPixelData(i+8, order.Uint32(data[i+4:i+8])); ok {
+				return i, i + 8, end, nil
+			}
+			continue
+		}
+		// Tag(4) + VR(2) + Reserved(2) + VL(4)
+		if i+12 > len(data) {
+			break
+		}
+		if vr := string(data[i+4 : i+6]); vr != "OB" && vr != "OW" {
+			continue
+		}
+		if end, ok := endsPixelData(i+12, order.Uint32(data[i+8:i+12])); ok {
+			return i, i + 12, end, nil
+		}
+	}
+	return 0, 0, 0, fmt.Errorf("no PixelData element found")
+}
@@ -0,0 +1,183 @@
+package corruption
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// explicitVRLittleEndian is the transfer syntax the generated files default to.
+const explicitVRLittleEndian = "1.2.840.10008.1.2.1"
+
+// truncationFile returns a file of a 132-byte preamble and prefix, a header of
+// headerBytes starting with the File Meta Information TransferSyntaxUID and a
+// PixelData element of pixelBytes, in the encoding of transferSyntax.
+func truncationFile(transferSyntax string, headerBytes, pixelBytes int) []byte {
+	data := make([]byte, dicmEnd+headerBytes)
+	copy(data[128:], "DICM")
+	uid := []byte(transferSyntax)
+	if len(uid)%2 != 0 {
+		uid = append(uid, 0)
+	}
+	meta := []byte{0x02, 0x00, 0x10, 0x00, 'U', 'I', 0, 0}
+	binary.LittleEndian.PutUint16(meta[6:], uint16(len(uid)))
+	copy(data[dicmEnd:], append(meta, uid...))
+
+	var element []byte
+	switch transferSyntax {
+	case implicitVRLittleEndian:
+		element = binary.LittleEndian.AppendUint32([]byte{0xE0, 0x7F, 0x10, 0x00}, uint32(pixelBytes))
+	case explicitVRBigEndian:
+		element = binary.BigEndian.AppendUint32([]byte{0x7F, 0xE0, 0x00, 0x10, 'O', 'W', 0, 0}, uint32(pixelBytes))
+	default:
+		element = binary.LittleEndian.AppendUint32([]byte{0xE0, 0x7F, 0x10, 0x00, 'O', 'W', 0, 0}, uint32(pixelBytes))
+	}
+	data = append(data, element...)
+	return append(data, bytes.Repeat([]byte{0xAA}, pixelBytes)...)
+}
+
+func TestParseTruncation(t *testing.T) {
+	for input, want := range map[string]Truncation{
+		"":               {Point: TruncateMidPixelData},
+		"mid-pixel-data": {Point: TruncateMidPixelData},
+		" mid-header ":   {Point: TruncateMidHeader},
+		"4096":           {Offset: 4096},
+		"50%":            {Percent: 50},
+		"12.5 %":         {Percent: 12.5},
+	} {
+		got, err := ParseTruncation(input)
+		if err != nil || got != want {
+			t.Errorf("ParseTruncation(%q) = %+v, %v, want %+v", input, got, err, want)
+		}
+		if again, err := ParseTruncation(got.String()); err != nil || again != got {
+			t.Errorf("ParseTruncation(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+		}
+	}
+	for _, invalid := range []string{"0", "-1", "0%", "100%", "abc", "mid-trailer", "10KB"} {
+		if _, err := ParseTruncation(invalid); err == nil {
+			t.Errorf("ParseTruncation(%q) should return error", invalid)
+		}
+	}
+}
+
+func TestTruncatedLength(t *testing.T) {
+	data := truncationFile(explicitVRLittleEndian, 200, 1000) // PixelData at 332, value at 344, 1344 bytes
+	tests := []struct {
+		name       string
+		data       []byte
+		truncation Truncation
+		want       int
+	}{
+		{"mid-pixel-data", data, Truncation{Point: TruncateMidPixelData}, 844},
+		{"zero value", data, Truncation{}, 844},
+		{"mid-header", data, Truncation{Point: TruncateMidHeader}, 232},
+		{"offset", data, Truncation{Offset: 100}, 100},
+		{"offset past the end", data, Truncation{Offset: 5000}, len(data)},
+		{"percent", data, Truncation{Percent: 25}, 336},
+		{"mid-header without pixel data", data[:300], Truncation{Point: TruncateMidHeader}, 216},
+		{"big endian", truncationFile(explicitVRBigEndian, 200, 1000), Truncation{}, 844},
+		{"implicit VR", truncationFile(implicitVRLittleEndian, 200, 1000), Truncation{}, 840},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := TruncatedLength(tt.data, tt.truncation); err != nil || got != tt.want {
+				t.Errorf("TruncatedLength() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+
+	// Encapsulated pixel data: undefined length
+	encapsulated := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(encapsulated[340:], 0xFFFFFFFF)
+	if got, err := TruncatedLength(encapsulated, Truncation{}); err != nil || got != 844 {
+		t.Errorf("TruncatedLength(encapsulated) = %d, %v, want 844", got, err)
+	}
+
+	// Trailing padding after the pixel data: cut halfway through the value
+	padded := append(append([]byte(nil), data...), 0xFC, 0xFF, 0xFC, 0xFF, 'O', 'B', 0, 0, 4, 0, 0, 0, 0, 0, 0, 0)
+	if got, err := TruncatedLength(padded, Truncation{}); err != nil || got != 844 {
+		t.Errorf("TruncatedLength(trailing padding) = %d, %v, want 844", got, err)
+	}
+
+	// No PixelData element, or one followed by bytes that are not a later
+	// element: nothing to cut mid-pixel-data
+	for name, invalid := range map[string][]byte{
+		"no pixel data":    data[:300],
+		"trailing garbage": append(append([]byte(nil), data...), 0x08, 0x00, 0x16, 0x00),
+	} {
+		if got, err := TruncatedLength(invalid, Truncation{}); err == nil {
+			t.Errorf("TruncatedLength(%s) = %d, want an error", name, got)
+		}
+	}
+}
+
+func TestTruncatedLengthLongMetaElement(t *testing.T) {
+	// A File Meta Information element with a 32-bit length (UC) before the
+	// TransferSyntaxUID of a big endian file
+	data := truncationFile(explicitVRBigEndian, 200, 1000)
+	long := append([]byte{0x02, 0x00, 0x00, 0x01, 'U', 'C', 0, 0, 4, 0, 0, 0}, "ABCD"...)
+	copy(data[dicmEnd:], append(long, data[dicmEnd:dicmEnd+200-len(long)]...))
+	if got, err := TruncatedLength(data, Truncation{}); err != nil || got != 844 {
+		t.Errorf("TruncatedLength() = %d, %v, want 844", got, err)
+	}
+}
+
+func TestPixelDataOffsetsSkipsTagInValue(t *testing.T) {
+	// An OB value before the PixelData element holding the PixelData tag
+	// bytes, as an explicit element of the wrong length and as an implicit
+	// element, which an Explicit VR file cannot hold
+	data := truncationFile(explicitVRLittleEndian, 200, 1000)
+	fake := []byte{
+		0xE0, 0x7F, 0x10, 0x00, 'O', 'W', 0, 0, 0x10, 0, 0, 0,
+		0xE0, 0x7F, 0x10, 0x00, 0x10, 0, 0, 0,
+	}
+	private := binary.LittleEndian.AppendUint32([]byte{0x09, 0x00, 0x10, 0x10, 'O', 'B', 0, 0}, uint32(len(fake)))
+	copy(data[200:], append(private, fake...))
+
+	element, value, valueEnd, err := pixelDataOffsets(data)
+	if err != nil || element != 332 || value != 344 || valueEnd != len(data) {
+		t.Errorf("pixelDataOffsets() = %d, %d, %d, %v, want 332, 344, %d", element, value, valueEnd, err, len(data))
+	}
+	if got, err := TruncatedLength(data, Truncation{}); err != nil || got != 844 {
+		t.Errorf("TruncatedLength() = %d, %v, want 844", got, err)
+	}
+}
+
+func TestTruncateFile(t *testing.T) {
+	data := truncationFile(explicitVRLittleEndian, 200, 1000)
+	path := filepath.Join(t.TempDir(), "IM000001")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := TruncateFile(path, Truncation{Point: TruncateMidHeader}); err != nil {
+		t.Fatalf("TruncateFile failed: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data[:232]) {
+		t.Errorf("truncated file has %d bytes, want the first 232", len(got))
+	}
+
+	// Cut point past the end: unchanged
+	if err := TruncateFile(path, Truncation{Offset: 1 << 20}); err != nil {
+		t.Fatalf("TruncateFile failed: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Size() != 232 {
+		t.Errorf("file size = %d, want 232", info.Size())
+	}
+	if err := TruncateFile(filepath.Join(t.TempDir(), "missing"), Truncation{}); err == nil {
+		t.Error("TruncateFile should fail on a missing file")
+	}
+
+	// Mid-pixel-data without pixel data: an error, the file left unchanged
+	if err := TruncateFile(path, Truncation{}); err == nil {
+		t.Error("TruncateFile should fail on a file without pixel data")
+	}
+	if info, _ := os.Stat(path); info.Size() != 232 {
+		t.Errorf("file size = %d, want 232", info.Size())
+	}
+}
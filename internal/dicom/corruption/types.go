@@ -9,24 +9,40 @@ import (
 type CorruptionType string
 
 const (
-	SiemensCSA       CorruptionType = "siemens-csa"
-	GEPrivate        CorruptionType = "ge-private"
-	PhilipsPrivate   CorruptionType = "philips-private"
-	MalformedLengths CorruptionType = "malformed-lengths"
+	SiemensCSA        CorruptionType = "siemens-csa"
+	GEPrivate         CorruptionType = "ge-private"
+	PhilipsPrivate    CorruptionType = "philips-private"
+	MalformedLengths  CorruptionType = "malformed-lengths"
+	SOPClassMismatch  CorruptionType = "sop-class-mismatch"
+	EmptyRequired     CorruptionType = "empty-required"
+	OddLengths        CorruptionType = "odd-lengths"
+	InvalidUIDs       CorruptionType = "invalid-uids"
+	LegacyGroups      CorruptionType = "legacy-groups"
+	SharedSeriesUID   CorruptionType = "shared-series-uid"
+	MixedPatientStudy CorruptionType = "mixed-patient-study"
+	CharsetMismatch   CorruptionType = "charset-mismatch"
+	Truncated         CorruptionType = "truncated"
 )
 
-// AllCorruptionTypes returns all valid corruption types
+// AllCorruptionTypes returns the corruption types enabled by "all": every
+// type but truncated, which leaves the files unreadable.
 func AllCorruptionTypes() []CorruptionType {
-	return []CorruptionType{SiemensCSA, GEPrivate, PhilipsPrivate, MalformedLengths}
+	return []CorruptionType{SiemensCSA, GEPrivate, PhilipsPrivate, MalformedLengths, SOPClassMismatch, EmptyRequired, OddLengths, InvalidUIDs, LegacyGroups, SharedSeriesUID, MixedPatientStudy, CharsetMismatch}
+}
+
+// ValidCorruptionTypes returns all valid corruption types
+func ValidCorruptionTypes() []CorruptionType {
+	return append(AllCorruptionTypes(), Truncated)
 }
 
 // Config holds corruption generation settings
 type Config struct {
-	Types []CorruptionType
+	Types      []CorruptionType
+	Truncation Truncation // Cut point of the truncated corruption
 }
 
 // ParseTypes parses comma-separated corruption types.
-// The special value "all" enables all corruption types.
+// The special value "all" enables all corruption types but truncated.
 func ParseTypes(input string) ([]CorruptionType, error) {
 	if input == "" {
 		return nil, nil
@@ -34,7 +50,7 @@ func ParseTypes(input string) ([]CorruptionType, error) {
 
 	parts := strings.Split(input, ",")
 	valid := make(map[CorruptionType]bool)
-	for _, t := range AllCorruptionTypes() {
+	for _, t := range ValidCorruptionTypes() {
 		valid[t] = true
 	}
 
@@ -47,7 +63,7 @@ func ParseTypes(input string) ([]CorruptionType, error) {
 		}
 		t := CorruptionType(p)
 		if !valid[t] {
-			return nil, fmt.Errorf("unknown corruption type %q, valid types: %v (or 'all')", p, AllCorruptionTypes())
+			return nil, fmt.Errorf("unknown corruption type %q, valid types: %v (or 'all')", p, ValidCorruptionTypes())
 		}
 		if !seen[t] {
 			result = append(result, t)
@@ -63,7 +79,7 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("corruption enabled but no types specified")
 	}
 	valid := make(map[CorruptionType]bool)
-	for _, t := range AllCorruptionTypes() {
+	for _, t := range ValidCorruptionTypes() {
 		valid[t] = true
 	}
 	for _, t := range c.Types {
@@ -71,6 +87,9 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("unknown corruption type %q", t)
 		}
 	}
+	if c.HasType(Truncated) {
+		return c.Truncation.Validate()
+	}
 	return nil
 }
 
@@ -0,0 +1,145 @@
+// Package csa encodes and decodes Siemens CSA headers, the private elements
+// (CSA Image Header (0029,1010) and CSA Series Header (0029,1020)) in which
+// syngo MR scanners store the acquisition parameters missing from the
+// standard tags, in the "SV10" (CSA2) format.
+package csa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Magic bytes of the SV10 format
+var magic = []byte{'S', 'V', '1', '0', 0x04, 0x03, 0x02, 0x01}
+
+// delimiter is the word following the counts of the header and its elements,
+// and the third word of the item headers.
+const delimiter = 77
+
+// Element is an element of a CSA header: a named list of values of a VR.
+type Element struct {
+	Name     string   // e.g. "SliceNormalVector"
+	VM       int32    // Value multiplicity, 0 when variable
+	VR       string   // e.g. "FD"
+	SyngoDT  int32    // syngo data type of the VR, e.g. 4 for FD
+	NumItems int32    // Items written: the values, then empty items
+	Values   []string // Values, as text
+}
+
+// Encode returns a CSA header of elements in the SV10 format: the magic
+// bytes, the number of elements and 77, then for each element its name (64
+// bytes), VM, VR (4 bytes), syngo data type, number of items and 77, and for
+// each item a header of four words (length, length, 77, length) and its
+// data, padded to 4 bytes.
+func Encode(elements []Element) []byte {
+	var buf bytes.Buffer
+	buf.Write(magic)
+
+	// binary.Write to bytes.Buffer never fails; discard errors explicitly.
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(elements)))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(delimiter))
+
+	for _, elem := range elements {
+		name := make([]byte, 64)
+		copy(name, elem.Name)
+		buf.Write(name)
+		_ = binary.Write(&buf, binary.LittleEndian, elem.VM)
+		vr := make([]byte, 4)
+		copy(vr, elem.VR)
+		buf.Write(vr)
+		_ = binary.Write(&buf, binary.LittleEndian, elem.SyngoDT)
+		_ = binary.Write(&buf, binary.LittleEndian, elem.NumItems)
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(delimiter))
+
+		for i := int32(0); i < elem.NumItems; i++ {
+			var val []byte
+			if i < int32(len(elem.Values)) {
+				val = []byte(elem.Values[i])
+			}
+			itemLen := uint32(len(val))
+			_ = binary.Write(&buf, binary.LittleEndian, [4]uint32{itemLen, itemLen, delimiter, itemLen})
+			buf.Write(val)
+			if padding := (4 - len(val)%4) % 4; padding > 0 {
+				buf.Write(make([]byte, padding))
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// Decode parses a CSA header in the SV10 format. The values of the elements
+// are their items, without the NUL terminating them; bytes after the last
+// element are ignored.
+func Decode(data []byte) ([]Element, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, fmt.Errorf("not an SV10 CSA header")
+	}
+	pos := len(magic)
+	word := func() (uint32, error) {
+		if pos+4 > len(data) {
+			return 0, fmt.Errorf("truncated CSA header at byte %d", pos)
+		}
+		v := binary.LittleEndian.Uint32(data[pos:])
+		pos += 4
+		return v, nil
+	}
+	text := func(n int) (string, error) {
+		if pos+n > len(data) {
+			return "", fmt.Errorf("truncated CSA header at byte %d", pos)
+		}
+		s := data[pos : pos+n]
+		pos += n
+		if i := bytes.IndexByte(s, 0); i >= 0 {
+			s = s[:i]
+		}
+		return string(s), nil
+	}
+
+	numElements, err := word()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := word(); err != nil {
+		return nil, err
+	}
+	elements := make([]Element, 0, min(numElements, 1024))
+	for range numElements {
+		var elem Element
+		var fields [4]uint32
+		if elem.Name, err = text(64); err != nil {
+			return nil, err
+		}
+		if fields[0], err = word(); err != nil {
+			return nil, err
+		}
+		if elem.VR, err = text(4); err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(fields); i++ {
+			if fields[i], err = word(); err != nil {
+				return nil, err
+			}
+		}
+		elem.VM, elem.SyngoDT, elem.NumItems = int32(fields[0]), int32(fields[1]), int32(fields[2])
+
+		for range elem.NumItems {
+			var header [4]uint32
+			for i := range header {
+				if header[i], err = word(); err != nil {
+					return nil, err
+				}
+			}
+			itemLen := int(header[1])
+			value, err := text(itemLen)
+			if err != nil {
+				return nil, err
+			}
+			pos += (4 - itemLen%4) % 4
+			elem.Values = append(elem.Values, value)
+		}
+		elements = append(elements, elem)
+	}
+	return elements, nil
+}
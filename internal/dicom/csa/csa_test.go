@@ -0,0 +1,49 @@
+package csa
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	elements := []Element{
+		{Name: "TestElement", VM: 1, VR: "IS", SyngoDT: 6, NumItems: 1, Values: []string{"42"}},
+		{Name: "SliceNormalVector", VM: 3, VR: "FD", SyngoDT: 4, NumItems: 6, Values: []string{"0", "0", "1"}},
+		{Name: "B_value", VM: 1, VR: "IS", SyngoDT: 6},
+	}
+	data := Encode(elements)
+
+	if string(data[0:4]) != "SV10" {
+		t.Errorf("expected SV10 magic, got %q", string(data[0:4]))
+	}
+	if data[4] != 0x04 || data[5] != 0x03 || data[6] != 0x02 || data[7] != 0x01 {
+		t.Error("incorrect secondary magic bytes")
+	}
+	if n := binary.LittleEndian.Uint32(data[8:]); n != 3 {
+		t.Errorf("number of elements = %d, want 3", n)
+	}
+	// Item header of "42": length, length, 77, length
+	item := data[16+64+4+4+4+4+4:]
+	for i, want := range []uint32{2, 2, 77, 2} {
+		if got := binary.LittleEndian.Uint32(item[4*i:]); got != want {
+			t.Errorf("item header word %d = %d, want %d", i, got, want)
+		}
+	}
+
+	decoded, err := Decode(append(data, 0xFF, 0xFF))
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	elements[1].Values = append(elements[1].Values, "", "", "")
+	if !reflect.DeepEqual(decoded, elements) {
+		t.Errorf("Decode = %+v, want %+v", decoded, elements)
+	}
+
+	if _, err := Decode(data[:len(data)-30]); err == nil {
+		t.Error("Decode should reject a truncated header")
+	}
+	if _, err := Decode([]byte("SV1")); err == nil {
+		t.Error("Decode should reject data without magic")
+	}
+}
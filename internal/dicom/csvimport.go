@@ -0,0 +1,187 @@
+package dicom
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// csvColumns maps the accepted CSV column names (lowercase) to the DICOM
+// keyword they set. Keywords and the short names QA spreadsheets use are
+// both accepted.
+var csvColumns = map[string]string{
+	"patientid":        "PatientID",
+	"mrn":              "PatientID",
+	"patientname":      "PatientName",
+	"name":             "PatientName",
+	"patientbirthdate": "PatientBirthDate",
+	"birthdate":        "PatientBirthDate",
+	"patientsex":       "PatientSex",
+	"sex":              "PatientSex",
+	"accessionnumber":  "AccessionNumber",
+	"accession":        "AccessionNumber",
+	"studydate":        "StudyDate",
+	"date":             "StudyDate",
+	"studydescription": "StudyDescription",
+	"description":      "StudyDescription",
+}
+
+// LoadPatientsCSV reads predefined patients from a CSV file. See
+// ParsePatientsCSV for the format.
+func LoadPatientsCSV(path string) ([]PredefinedPatient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open CSV: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return ParsePatientsCSV(f)
+}
+
+// ParsePatientsCSV reads predefined patients from CSV data: a header row
+// followed by one row per study. Columns are PatientID (or MRN), PatientName
+// (or Name), PatientBirthDate (or BirthDate), PatientSex (or Sex),
+// AccessionNumber (or Accession), StudyDate (or Date) and StudyDescription
+// (or Description), in any order; all are optional.
+//
+// Rows sharing a PatientID are studies of the same patient, in file order;
+// rows without PatientID each get their own patient. Empty cells are
+// generated. Dates are YYYYMMDD or YYYY-MM-DD.
+func ParsePatientsCSV(r io.Reader) ([]PredefinedPatient, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("empty CSV (expected a header row)")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+
+	keywords := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff") // Excel BOM
+		keyword, ok := csvColumns[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column %q (valid: PatientID, PatientName, PatientBirthDate, PatientSex, AccessionNumber, StudyDate, StudyDescription)", name)
+		}
+		if seen[keyword] {
+			return nil, fmt.Errorf("duplicate CSV column for %s", keyword)
+		}
+		seen[keyword] = true
+		keywords[i] = keyword
+	}
+
+	var patients []PredefinedPatient
+	patientIndex := make(map[string]int) // PatientID -> index in patients
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]string, len(record))
+		for i, value := range record {
+			values[keywords[i]] = strings.TrimSpace(value)
+		}
+		row, err := csvRowPatient(values)
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %w", line, err)
+		}
+
+		idx, ok := patientIndex[row.ID]
+		if row.ID == "" || !ok {
+			patients = append(patients, row)
+			if row.ID != "" {
+				patientIndex[row.ID] = len(patients) - 1
+			}
+			continue
+		}
+		if err := mergeCSVPatient(&patients[idx], row); err != nil {
+			return nil, fmt.Errorf("CSV line %d: %w", line, err)
+		}
+	}
+
+	if len(patients) == 0 {
+		return nil, fmt.Errorf("CSV has no study rows")
+	}
+	return patients, nil
+}
+
+// csvRowPatient builds the patient of a CSV row, holding the row's study.
+func csvRowPatient(values map[string]string) (PredefinedPatient, error) {
+	birthDate, err := parseCSVDate(values["PatientBirthDate"])
+	if err != nil {
+		return PredefinedPatient{}, fmt.Errorf("invalid PatientBirthDate: %w", err)
+	}
+	studyDate, err := parseCSVDate(values["StudyDate"])
+	if err != nil {
+		return PredefinedPatient{}, fmt.Errorf("invalid StudyDate: %w", err)
+	}
+	sex := strings.ToUpper(values["PatientSex"])
+	switch sex {
+	case "", "M", "F", "O":
+	default:
+		return PredefinedPatient{}, fmt.Errorf("invalid PatientSex %q (expected M, F or O)", values["PatientSex"])
+	}
+
+	return PredefinedPatient{
+		ID:        values["PatientID"],
+		Name:      values["PatientName"],
+		BirthDate: birthDate,
+		Sex:       sex,
+		Studies: []PredefinedStudy{{
+			Description:     values["StudyDescription"],
+			Date:            studyDate,
+			AccessionNumber: values["AccessionNumber"],
+		}},
+	}, nil
+}
+
+// mergeCSVPatient adds the study of row to p, an earlier patient with the
+// same PatientID. Patient values of row must agree with those of p.
+func mergeCSVPatient(p *PredefinedPatient, row PredefinedPatient) error {
+	fields := []struct {
+		keyword    string
+		dest, from *string
+	}{
+		{"PatientName", &p.Name, &row.Name},
+		{"PatientBirthDate", &p.BirthDate, &row.BirthDate},
+		{"PatientSex", &p.Sex, &row.Sex},
+	}
+	for _, f := range fields {
+		if *f.from == "" {
+			continue
+		}
+		if *f.dest != "" && *f.dest != *f.from {
+			return fmt.Errorf("patient %s has %s %q, but %q on an earlier line", p.ID, f.keyword, *f.from, *f.dest)
+		}
+		*f.dest = *f.from
+	}
+	p.Studies = append(p.Studies, row.Studies...)
+	return nil
+}
+
+// parseCSVDate validates a YYYYMMDD or YYYY-MM-DD date and returns it as a
+// DICOM DA value. An empty date is returned as is.
+func parseCSVDate(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, layout := range []string{"20060102", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("20060102"), nil
+		}
+	}
+	return "", fmt.Errorf("%q (expected YYYYMMDD or YYYY-MM-DD)", s)
+}
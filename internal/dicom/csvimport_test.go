@@ -0,0 +1,60 @@
+package dicom
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePatientsCSV(t *testing.T) {
+	data := "\ufeffMRN, Name,Accession,Date,Sex,Description\n" +
+		"MRN001,DOE^JOHN,A1001,2024-01-15,m,CT CHEST\n" +
+		"MRN002,MARTIN^CLAIRE,A1002,20240320,F,\n" +
+		"MRN001,,A1003,20240602,,CT CHEST FOLLOW-UP\n" +
+		",,A1004,,,\n"
+
+	patients, err := ParsePatientsCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParsePatientsCSV failed: %v", err)
+	}
+
+	want := []PredefinedPatient{
+		{ID: "MRN001", Name: "DOE^JOHN", Sex: "M", Studies: []PredefinedStudy{
+			{Description: "CT CHEST", Date: "20240115", AccessionNumber: "A1001"},
+			{Description: "CT CHEST FOLLOW-UP", Date: "20240602", AccessionNumber: "A1003"},
+		}},
+		{ID: "MRN002", Name: "MARTIN^CLAIRE", Sex: "F", Studies: []PredefinedStudy{
+			{Date: "20240320", AccessionNumber: "A1002"},
+		}},
+		{Studies: []PredefinedStudy{{AccessionNumber: "A1004"}}},
+	}
+	if !reflect.DeepEqual(patients, want) {
+		t.Errorf("got %+v\nwant %+v", patients, want)
+	}
+}
+
+func TestParsePatientsCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", "empty CSV"},
+		{"no rows", "PatientID\n", "no study rows"},
+		{"unknown column", "PatientID,Modality\nP1,CT\n", `unknown CSV column "Modality"`},
+		{"duplicate column", "MRN,PatientID\nP1,P1\n", "duplicate CSV column"},
+		{"invalid date", "PatientID,StudyDate\nP1,15/01/2024\n", "CSV line 2: invalid StudyDate"},
+		{"invalid sex", "PatientID,Sex\nP1,X\n", "invalid PatientSex"},
+		{"conflicting name", "PatientID,PatientName\nP1,DOE^JOHN\nP1,DOE^JANE\n", "CSV line 3: patient P1 has PatientName"},
+		{"field count", "PatientID,StudyDate\nP1\n", "wrong number of fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePatientsCSV(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// CSVIndexFile is the name of the CSV index, inside the output directory.
+const CSVIndexFile = "index.csv"
+
+// Key tags of the CSV index, one column each after Path, named by their
+// keyword
+var csvIndexTags = []struct {
+	name string
+	tag  tag.Tag
+}{
+	{"PatientID", tag.PatientID},
+	{"PatientName", tag.PatientName},
+	{"PatientBirthDate", tag.PatientBirthDate},
+	{"PatientSex", tag.PatientSex},
+	{"StudyInstanceUID", tag.StudyInstanceUID},
+	{"StudyDate", tag.StudyDate},
+	{"StudyTime", tag.StudyTime},
+	{"StudyDescription", tag.StudyDescription},
+	{"AccessionNumber", tag.AccessionNumber},
+	{"SeriesInstanceUID", tag.SeriesInstanceUID},
+	{"SeriesNumber", tag.SeriesNumber},
+	{"SeriesDescription", tag.SeriesDescription},
+	{"Modality", tag.Modality},
+	{"BodyPartExamined", tag.BodyPartExamined},
+	{"SOPClassUID", tag.SOPClassUID},
+	{"SOPInstanceUID", tag.SOPInstanceUID},
+	{"InstanceNumber", tag.InstanceNumber},
+	{"Rows", tag.Rows},
+	{"Columns", tag.Columns},
+	{"NumberOfFrames", tag.NumberOfFrames},
+	{"TransferSyntaxUID", tag.TransferSyntaxUID},
+}
+
+// ExportCSVIndex writes index.csv into outputDir: one row per instance of
+// the PT*/ST*/SE* hierarchy, in hierarchy order, with its path relative to
+// outputDir, its key tags and its FileSize in bytes. Missing tags are empty
+// and multiple values are separated by a backslash, as in DICOM. An
+// unchanged index is kept (rerun of the same profile). It returns the
+// number of indexed instances.
+func ExportCSVIndex(outputDir string, quiet bool) (int, error) {
+	header := []string{"Path"}
+	for _, column := range csvIndexTags {
+		header = append(header, column.name)
+	}
+	header = append(header, "FileSize")
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(header)
+	imageFiles := hierarchyImages(outputDir)
+	for _, path := range imageFiles {
+		ds, err := parseDICOMTolerant(path)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s: %w", path, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return 0, err
+		}
+
+		row := []string{filepath.ToSlash(rel)}
+		for _, column := range csvIndexTags {
+			row = append(row, csvIndexValue(ds, column.tag))
+		}
+		row = append(row, strconv.FormatInt(info.Size(), 10))
+		_ = w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, fmt.Errorf("encode CSV index: %w", err)
+	}
+
+	dest := filepath.Join(outputDir, CSVIndexFile)
+	tmpPath := dest + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return 0, fmt.Errorf("write %s: %w", tmpPath, err)
+	}
+	if _, err := moveIfChanged(tmpPath, dest); err != nil {
+		return 0, err
+	}
+
+	if !quiet {
+		fmt.Printf("  Indexed %d instances into %s\n", len(imageFiles), CSVIndexFile)
+	}
+	return len(imageFiles), nil
+}
+
+// csvIndexValue returns the value of t in ds as a CSV index cell.
+func csvIndexValue(ds dicom.Dataset, t tag.Tag) string {
+	elem, err := ds.FindElementByTag(t)
+	if err != nil || elem == nil {
+		return ""
+	}
+	switch v := elem.Value.GetValue().(type) {
+	case []string:
+		return strings.TrimRight(strings.Join(v, `\`), " \x00")
+	case []int:
+		values := make([]string, len(v))
+		for i, n := range v {
+			values[i] = strconv.Itoa(n)
+		}
+		return strings.Join(values, `\`)
+	}
+	return getStringValue(ds, t)[0]
+}
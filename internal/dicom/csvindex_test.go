@@ -0,0 +1,84 @@
+package dicom
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestExportCSVIndex(t *testing.T) {
+	outputDir := t.TempDir()
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "1MB",
+		OutputDir:   outputDir,
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 2,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := OrganizeFilesIntoDICOMDIR(outputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	n, err := ExportCSVIndex(outputDir, true)
+	if err != nil {
+		t.Fatalf("ExportCSVIndex failed: %v", err)
+	}
+	if n != len(files) {
+		t.Fatalf("indexed %d instances, want %d", n, len(files))
+	}
+
+	f, err := os.Open(filepath.Join(outputDir, CSVIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != n+1 {
+		t.Fatalf("got %d rows, want a header and %d instances", len(records), n)
+	}
+	column := make(map[string]int)
+	for i, name := range records[0] {
+		column[name] = i
+	}
+	for _, name := range []string{"Path", "PatientID", "StudyInstanceUID", "SeriesInstanceUID", "SOPInstanceUID", "Rows", "TransferSyntaxUID", "FileSize"} {
+		if _, ok := column[name]; !ok {
+			t.Fatalf("no %s column in %v", name, records[0])
+		}
+	}
+
+	generated := make(map[string]GeneratedFile)
+	for _, f := range files {
+		generated[f.SOPInstanceUID] = f
+	}
+	for _, row := range records[1:] {
+		f, ok := generated[row[column["SOPInstanceUID"]]]
+		if !ok {
+			t.Fatalf("row of unknown instance %q", row[column["SOPInstanceUID"]])
+		}
+		if row[column["PatientID"]] != f.PatientID || row[column["StudyInstanceUID"]] != f.StudyUID || row[column["SeriesInstanceUID"]] != f.SeriesUID {
+			t.Errorf("row %v does not match %+v", row, f)
+		}
+		info, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(row[column["Path"]])))
+		if err != nil {
+			t.Fatalf("indexed path: %v", err)
+		}
+		if got := row[column["FileSize"]]; got != strconv.FormatInt(info.Size(), 10) {
+			t.Errorf("FileSize %s, want %d", got, info.Size())
+		}
+		if _, err := strconv.Atoi(row[column["Rows"]]); err != nil {
+			t.Errorf("Rows %q is not a number", row[column["Rows"]])
+		}
+		if row[column["TransferSyntaxUID"]] == "" {
+			t.Errorf("no TransferSyntaxUID in %v", row)
+		}
+	}
+}
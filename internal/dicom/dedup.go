@@ -0,0 +1,81 @@
+package dicom
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// OrganizeReport counts the files of a run written to the output directory,
+// and those left untouched because an identical file was already there
+// (rerun of the same deterministic profile).
+type OrganizeReport struct {
+	Written int
+	Skipped int
+}
+
+// moveIfChanged moves src to dest, unless dest already has the same content:
+// src is then removed and dest is left untouched. It reports whether dest
+// was kept.
+func moveIfChanged(src, dest string) (bool, error) {
+	same, err := sameContent(src, dest)
+	if err != nil {
+		return false, err
+	}
+	if same {
+		if err := os.Remove(src); err != nil {
+			return false, fmt.Errorf("remove duplicate %s: %w", src, err)
+		}
+		return true, nil
+	}
+	if err := os.Rename(src, dest); err != nil {
+		return false, fmt.Errorf("move file %s to %s: %w", src, dest, err)
+	}
+	return false, nil
+}
+
+// sameContent reports whether two files have the same content, comparing
+// sizes first and SHA-256 hashes second. A missing dest is not an error.
+func sameContent(src, dest string) (bool, error) {
+	destInfo, err := os.Stat(dest)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat %s: %w", dest, err)
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false, fmt.Errorf("stat %s: %w", src, err)
+	}
+	if !destInfo.Mode().IsRegular() || srcInfo.Size() != destInfo.Size() {
+		return false, nil
+	}
+
+	srcHash, err := fileHash(src)
+	if err != nil {
+		return false, err
+	}
+	destHash, err := fileHash(dest)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(srcHash, destHash), nil
+}
+
+// fileHash returns the SHA-256 hash of a file.
+func fileHash(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("hash %s: %w", path, err)
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,48 @@
+package dicom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	dest := filepath.Join(dir, "dest")
+
+	// Missing destination: moved
+	skipped, err := moveIfChanged(write("src", "abc"), dest)
+	if err != nil || skipped || read(dest) != "abc" {
+		t.Fatalf("new file: skipped=%v err=%v content=%q", skipped, err, read(dest))
+	}
+
+	// Identical content: kept, source removed
+	src := write("src", "abc")
+	skipped, err = moveIfChanged(src, dest)
+	if err != nil || !skipped {
+		t.Fatalf("identical file: skipped=%v err=%v", skipped, err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("source of an identical file should be removed")
+	}
+
+	// Same size, different content: replaced
+	skipped, err = moveIfChanged(write("src", "abd"), dest)
+	if err != nil || skipped || read(dest) != "abd" {
+		t.Errorf("changed file: skipped=%v err=%v content=%q", skipped, err, read(dest))
+	}
+}
@@ -0,0 +1,355 @@
+package dicom
+
+import (
+	"encoding/json"
+	"fmt"
+	randv2 "math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Directories of a de-identification challenge, inside the output directory
+const (
+	DeidIdentifiedDir   = "identified"
+	DeidDeidentifiedDir = "deidentified"
+	DeidAnswersFile     = "answers.json"
+)
+
+// deidMethod is the DeidentificationMethod written in de-identified files
+const deidMethod = "DICOMFORGE BASIC PROFILE SUBSET"
+
+// De-identification actions, after the action codes of PS3.15 Table E.1-1
+const (
+	deidReplace = "replaced" // D: dummy value (pseudonym)
+	deidEmpty   = "emptied"  // Z: zero-length value
+	deidRemove  = "removed"  // X: element removed
+	deidUID     = "uid"      // U: UID replaced consistently
+	deidShift   = "shifted"  // Date shifted by a per-patient offset
+	deidLeaked  = "leaked"   // Identifier deliberately left in the file
+)
+
+// deidActions are the actions applied to the attributes the generator
+// writes. Attributes not listed are kept.
+var deidActions = map[tag.Tag]string{
+	tag.PatientName:                            deidReplace,
+	tag.PatientID:                              deidReplace,
+	tag.AccessionNumber:                        deidReplace,
+	tag.PatientBirthDate:                       deidEmpty,
+	tag.StudyID:                                deidEmpty,
+	tag.ReferringPhysicianName:                 deidEmpty,
+	tag.StudyInstanceUID:                       deidUID,
+	tag.SeriesInstanceUID:                      deidUID,
+	tag.SOPInstanceUID:                         deidUID,
+	tag.MediaStorageSOPInstanceUID:             deidUID,
+	tag.FrameOfReferenceUID:                    deidUID,
+	tag.StudyDate:                              deidShift,
+	tag.SeriesDate:                             deidShift,
+	tag.AcquisitionDate:                        deidShift,
+	tag.ContentDate:                            deidShift,
+	tag.PatientAge:                             deidRemove,
+	tag.OtherPatientIDs:                        deidRemove,
+	tag.IssuerOfPatientID:                      deidRemove,
+	tag.InstitutionName:                        deidRemove,
+	tag.InstitutionAddress:                     deidRemove,
+	tag.InstitutionalDepartmentName:            deidRemove,
+	tag.StationName:                            deidRemove,
+	tag.PerformingPhysicianName:                deidRemove,
+	tag.OperatorsName:                          deidRemove,
+	tag.DeviceSerialNumber:                     deidRemove,
+	tag.RequestedProcedureDescription:          deidRemove,
+	tag.AdmissionID:                            deidRemove,
+	tag.PlacerOrderNumberImagingServiceRequest: deidRemove,
+	tag.FillerOrderNumberImagingServiceRequest: deidRemove,
+	tag.ImageComments:                          deidRemove,
+}
+
+// DeidChallengeOptions configures a de-identification challenge.
+type DeidChallengeOptions struct {
+	Seed     int64
+	LeakRate float64 // Fraction of de-identified files left with one identifier (0-1)
+	UIDRoot  string  // Root of the replacement UIDs (util.DefaultUIDRoot when empty)
+}
+
+// DeidAnswers is the ground truth of a de-identification challenge.
+type DeidAnswers struct {
+	Method string           `json:"method"`
+	Files  []DeidFileAnswer `json:"files"`
+}
+
+// DeidFileAnswer pairs an identified file with its de-identified version,
+// paths being relative to the output directory. Changes lists what
+// de-identification did to each attribute; Leaks lists the identifiers a
+// correct de-identification would not have left.
+type DeidFileAnswer struct {
+	Identified   string       `json:"identified"`
+	Deidentified string       `json:"deidentified"`
+	Changes      []DeidChange `json:"changes"`
+	Leaks        []DeidChange `json:"leaks,omitempty"`
+}
+
+// DeidChange is the action taken on one attribute.
+type DeidChange struct {
+	Tag      string `json:"tag"`
+	Keyword  string `json:"keyword"`
+	Action   string `json:"action"`
+	Original string `json:"original"`
+	Value    string `json:"value,omitempty"`
+}
+
+// deidPatient is the pseudonymous identity of an identified patient.
+type deidPatient struct {
+	id, name  string
+	dateShift int // days
+}
+
+// deidState holds the replacements shared by the files of a challenge, so
+// that studies, series and patients still group together once de-identified.
+type deidState struct {
+	seed       int64
+	uidRoot    string
+	rng        *randv2.Rand
+	patients   map[string]deidPatient
+	accessions map[string]string
+}
+
+// BuildDeidChallenge de-identifies each instance of the PT*/ST*/SE*
+// hierarchy of outputDir/identified into outputDir/deidentified, in shuffled
+// order and under neutral names, and writes the ground truth as
+// outputDir/answers.json. A LeakRate fraction of the de-identified files keep
+// one identifier (name, birth date, institution, or an ID typed into a free
+// text attribute) for QA tooling to find.
+func BuildDeidChallenge(outputDir string, opts DeidChallengeOptions) (DeidAnswers, error) {
+	if opts.LeakRate < 0 || opts.LeakRate > 1 {
+		return DeidAnswers{}, fmt.Errorf("leak rate must be between 0 and 1, got %v", opts.LeakRate)
+	}
+	if opts.UIDRoot != "" {
+		if err := util.ValidateUIDRoot(opts.UIDRoot); err != nil {
+			return DeidAnswers{}, err
+		}
+	}
+
+	identified := hierarchyImages(filepath.Join(outputDir, DeidIdentifiedDir))
+	if len(identified) == 0 {
+		return DeidAnswers{}, fmt.Errorf("no instances in %s", filepath.Join(outputDir, DeidIdentifiedDir))
+	}
+	sort.Strings(identified)
+
+	if err := os.MkdirAll(filepath.Join(outputDir, DeidDeidentifiedDir), 0755); err != nil {
+		return DeidAnswers{}, fmt.Errorf("create directory: %w", err)
+	}
+
+	state := &deidState{
+		seed:       opts.Seed,
+		uidRoot:    opts.UIDRoot,
+		rng:        randv2.New(randv2.NewPCG(uint64(opts.Seed), 0xde1d)),
+		patients:   make(map[string]deidPatient),
+		accessions: make(map[string]string),
+	}
+	order := state.rng.Perm(len(identified))
+
+	answers := DeidAnswers{Method: deidMethod}
+	for i, path := range identified {
+		ds, err := dicom.ParseFile(path, nil)
+		if err != nil {
+			return DeidAnswers{}, fmt.Errorf("parse %s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return DeidAnswers{}, err
+		}
+		answer := DeidFileAnswer{
+			Identified:   rel,
+			Deidentified: filepath.Join(DeidDeidentifiedDir, fmt.Sprintf("IM%06d", order[i])),
+		}
+		var original map[tag.Tag]string
+		ds.Elements, answer.Changes, original = state.deidentify(ds.Elements)
+		if state.rng.Float64() < opts.LeakRate {
+			ds.Elements, answer.Leaks = state.leak(ds.Elements, original)
+		}
+
+		sort.Slice(ds.Elements, func(a, b int) bool {
+			if ds.Elements[a].Tag.Group != ds.Elements[b].Tag.Group {
+				return ds.Elements[a].Tag.Group < ds.Elements[b].Tag.Group
+			}
+			return ds.Elements[a].Tag.Element < ds.Elements[b].Tag.Element
+		})
+		dest := filepath.Join(outputDir, answer.Deidentified)
+		if err := writeDatasetToFile(dest, ds); err != nil {
+			return DeidAnswers{}, fmt.Errorf("write %s: %w", dest, err)
+		}
+		answers.Files = append(answers.Files, answer)
+	}
+
+	data, err := json.MarshalIndent(answers, "", "  ")
+	if err != nil {
+		return DeidAnswers{}, fmt.Errorf("encode answers: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, DeidAnswersFile), append(data, '\n'), 0644); err != nil {
+		return DeidAnswers{}, fmt.Errorf("write answers: %w", err)
+	}
+	return answers, nil
+}
+
+// deidentify applies deidActions to the top-level elements of a file and
+// adds PatientIdentityRemoved and DeidentificationMethod. It returns the new
+// elements, the changes made, and the original values by tag.
+func (s *deidState) deidentify(elements []*dicom.Element) ([]*dicom.Element, []DeidChange, map[tag.Tag]string) {
+	original := make(map[tag.Tag]string)
+	for _, elem := range elements {
+		original[elem.Tag] = deidString(elem)
+	}
+	patient := s.patient(original[tag.PatientID], original[tag.PatientName])
+
+	var result []*dicom.Element
+	var changes []DeidChange
+	for _, elem := range elements {
+		if elem.Tag == tag.PatientIdentityRemoved || elem.Tag == tag.DeidentificationMethod {
+			continue
+		}
+		action, ok := deidActions[elem.Tag]
+		if !ok {
+			result = append(result, elem)
+			continue
+		}
+
+		value := original[elem.Tag]
+		var replacement string
+		switch action {
+		case deidReplace:
+			switch elem.Tag {
+			case tag.PatientName:
+				replacement = patient.name
+			case tag.PatientID:
+				replacement = patient.id
+			default:
+				replacement = s.accession(value)
+			}
+		case deidUID:
+			replacement = util.GenerateDeterministicUIDWithRoot(s.uidRoot, fmt.Sprintf("deid:%d:%s", s.seed, value))
+		case deidShift:
+			replacement = shiftDate(value, patient.dateShift)
+		}
+
+		changes = append(changes, DeidChange{
+			Tag:      elem.Tag.String(),
+			Keyword:  deidKeyword(elem.Tag),
+			Action:   action,
+			Original: value,
+			Value:    replacement,
+		})
+		if action != deidRemove {
+			result = append(result, mustNewElement(elem.Tag, []string{replacement}))
+		}
+	}
+
+	result = append(result,
+		mustNewElement(tag.PatientIdentityRemoved, []string{"YES"}),
+		mustNewElement(tag.DeidentificationMethod, []string{deidMethod}),
+	)
+	return result, changes, original
+}
+
+// leak puts back one identifier of the original file, picked among the
+// applicable ones.
+func (s *deidState) leak(elements []*dicom.Element, original map[tag.Tag]string) ([]*dicom.Element, []DeidChange) {
+	type leakCandidate struct {
+		tag   tag.Tag
+		value string
+	}
+	var candidates []leakCandidate
+	if v := original[tag.PatientName]; v != "" {
+		candidates = append(candidates, leakCandidate{tag.PatientName, v})
+	}
+	if v := original[tag.PatientBirthDate]; v != "" {
+		candidates = append(candidates, leakCandidate{tag.PatientBirthDate, v})
+	}
+	if v := original[tag.InstitutionName]; v != "" {
+		candidates = append(candidates, leakCandidate{tag.InstitutionName, v})
+	}
+	if v := original[tag.PatientID]; v != "" {
+		// The MRN typed by the technologist into a free text attribute
+		candidates = append(candidates, leakCandidate{tag.StudyDescription, strings.TrimSpace(original[tag.StudyDescription] + " MRN " + v)})
+		candidates = append(candidates, leakCandidate{tag.ImageComments, "PATIENT ID " + v})
+	}
+	if len(candidates) == 0 {
+		return elements, nil
+	}
+
+	c := candidates[s.rng.IntN(len(candidates))]
+	result := make([]*dicom.Element, 0, len(elements)+1)
+	for _, elem := range elements {
+		if elem.Tag != c.tag {
+			result = append(result, elem)
+		}
+	}
+	result = append(result, mustNewElement(c.tag, []string{c.value}))
+	return result, []DeidChange{{
+		Tag:      c.tag.String(),
+		Keyword:  deidKeyword(c.tag),
+		Action:   deidLeaked,
+		Original: original[c.tag],
+		Value:    c.value,
+	}}
+}
+
+// patient returns the pseudonymous identity of a patient, creating it on
+// first use.
+func (s *deidState) patient(id, name string) deidPatient {
+	key := id + "\x00" + name
+	if p, ok := s.patients[key]; ok {
+		return p
+	}
+	p := deidPatient{
+		id:        fmt.Sprintf("DEID%04d", len(s.patients)+1),
+		name:      fmt.Sprintf("ANONYMOUS^%04d", len(s.patients)+1),
+		dateShift: -(1 + s.rng.IntN(365)),
+	}
+	s.patients[key] = p
+	return p
+}
+
+// accession returns the replacement of an accession number.
+func (s *deidState) accession(value string) string {
+	if value == "" {
+		return ""
+	}
+	if a, ok := s.accessions[value]; ok {
+		return a
+	}
+	a := fmt.Sprintf("DEIDACC%05d", len(s.accessions)+1)
+	s.accessions[value] = a
+	return a
+}
+
+// shiftDate shifts a YYYYMMDD date by days. Other values are emptied.
+func shiftDate(date string, days int) string {
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, days).Format("20060102")
+}
+
+// deidString returns the first string value of an element, or "".
+func deidString(elem *dicom.Element) string {
+	if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 {
+		return strings.TrimRight(values[0], " \x00")
+	}
+	return ""
+}
+
+// deidKeyword returns the keyword of a tag, or its (gggg,eeee) form.
+func deidKeyword(t tag.Tag) string {
+	if info, err := tag.Find(t); err == nil {
+		return info.Keyword
+	}
+	return t.String()
+}
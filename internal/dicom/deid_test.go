@@ -0,0 +1,116 @@
+package dicom
+
+import (
+	randv2 "math/rand/v2"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func testDeidElements(patientID, studyUID string) []*dicom.Element {
+	return []*dicom.Element{
+		mustNewElement(tag.PatientName, []string{"DOE^JOHN"}),
+		mustNewElement(tag.PatientID, []string{patientID}),
+		mustNewElement(tag.PatientBirthDate, []string{"19650412"}),
+		mustNewElement(tag.StudyInstanceUID, []string{studyUID}),
+		mustNewElement(tag.StudyDate, []string{"20240115"}),
+		mustNewElement(tag.StudyDescription, []string{"CT CHEST"}),
+		mustNewElement(tag.InstitutionName, []string{"GENERAL HOSPITAL"}),
+		mustNewElement(tag.Modality, []string{"CT"}),
+	}
+}
+
+func newTestDeidState() *deidState {
+	return &deidState{
+		seed:       1,
+		rng:        randv2.New(randv2.NewPCG(1, 2)),
+		patients:   make(map[string]deidPatient),
+		accessions: make(map[string]string),
+	}
+}
+
+func deidValues(elements []*dicom.Element) map[tag.Tag]string {
+	values := make(map[tag.Tag]string)
+	for _, elem := range elements {
+		values[elem.Tag] = deidString(elem)
+	}
+	return values
+}
+
+func TestDeidentify(t *testing.T) {
+	s := newTestDeidState()
+	elements, changes, original := s.deidentify(testDeidElements("MRN001", "1.2.3"))
+	values := deidValues(elements)
+
+	if values[tag.PatientName] != "ANONYMOUS^0001" || values[tag.PatientID] != "DEID0001" {
+		t.Errorf("patient = %s / %s, want pseudonyms", values[tag.PatientName], values[tag.PatientID])
+	}
+	if values[tag.PatientBirthDate] != "" {
+		t.Errorf("PatientBirthDate = %q, want empty", values[tag.PatientBirthDate])
+	}
+	if _, ok := values[tag.InstitutionName]; ok {
+		t.Error("InstitutionName should be removed")
+	}
+	if values[tag.StudyInstanceUID] == "1.2.3" || values[tag.StudyInstanceUID] == "" {
+		t.Errorf("StudyInstanceUID = %q, want a new UID", values[tag.StudyInstanceUID])
+	}
+	if values[tag.StudyDate] == "20240115" || values[tag.StudyDate][:2] != "20" {
+		t.Errorf("StudyDate = %q, want a shifted date", values[tag.StudyDate])
+	}
+	if values[tag.StudyDescription] != "CT CHEST" || values[tag.Modality] != "CT" {
+		t.Error("attributes without an action should be kept")
+	}
+	if values[tag.PatientIdentityRemoved] != "YES" || values[tag.DeidentificationMethod] != deidMethod {
+		t.Error("missing PatientIdentityRemoved or DeidentificationMethod")
+	}
+	if original[tag.PatientName] != "DOE^JOHN" {
+		t.Errorf("original PatientName = %q", original[tag.PatientName])
+	}
+	if len(changes) != 6 {
+		t.Errorf("got %d changes, want 6: %+v", len(changes), changes)
+	}
+
+	// Files of the same patient and study keep the same replacements
+	again, _, _ := s.deidentify(testDeidElements("MRN001", "1.2.3"))
+	againValues := deidValues(again)
+	for _, tg := range []tag.Tag{tag.PatientID, tag.StudyInstanceUID, tag.StudyDate} {
+		if againValues[tg] != values[tg] {
+			t.Errorf("%v = %q, then %q", tg, values[tg], againValues[tg])
+		}
+	}
+
+	other, _, _ := s.deidentify(testDeidElements("MRN002", "1.2.4"))
+	if v := deidValues(other)[tag.PatientID]; v != "DEID0002" {
+		t.Errorf("second patient ID = %q, want DEID0002", v)
+	}
+}
+
+func TestDeidLeak(t *testing.T) {
+	s := newTestDeidState()
+	for i := 0; i < 20; i++ {
+		elements, _, original := s.deidentify(testDeidElements("MRN001", "1.2.3"))
+		leaked, leaks := s.leak(elements, original)
+		if len(leaks) != 1 {
+			t.Fatalf("got %d leaks, want 1", len(leaks))
+		}
+
+		l := leaks[0]
+		if l.Action != deidLeaked {
+			t.Errorf("leak action = %s", l.Action)
+		}
+		found := false
+		for _, elem := range leaked {
+			if deidKeyword(elem.Tag) == l.Keyword {
+				found = deidString(elem) == l.Value
+			}
+		}
+		if !found {
+			t.Errorf("leak %+v not in the elements", l)
+		}
+		if l.Value != "DOE^JOHN" && l.Value != "19650412" && l.Value != "GENERAL HOSPITAL" &&
+			l.Value != "CT CHEST MRN MRN001" && l.Value != "PATIENT ID MRN001" {
+			t.Errorf("unexpected leak value %q", l.Value)
+		}
+	}
+}
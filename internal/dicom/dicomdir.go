@@ -4,12 +4,12 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -30,17 +30,52 @@ type FileHierarchy struct {
 	ImageFiles []string
 }
 
+// OrganizeOptions holds the settings of the PT*/ST*/SE* hierarchy and its
+// DICOMDIR.
+type OrganizeOptions struct {
+	// At most Fanout entries per directory (0 for DefaultShardFanout): the
+	// entries of a level having more are grouped into SH* shard directories,
+	// which the DICOMDIR File IDs go through
+	Fanout int
+
+	// Root of the UIDs the files were generated under, which the DICOMDIR
+	// UID is generated under too (util.DefaultUIDRoot when empty)
+	UIDRoot string
+
+	Quiet bool // No progress output
+}
+
 // OrganizeFilesIntoDICOMDIR organizes DICOM files into PT*/ST*/SE* hierarchy and creates DICOMDIR
 func OrganizeFilesIntoDICOMDIR(outputDir string, files []GeneratedFile, quiet bool) error {
+	_, err := OrganizeFiles(outputDir, files, OrganizeOptions{Quiet: quiet})
+	return err
+}
+
+// OrganizeFiles organizes DICOM files into PT*/ST*/SE* hierarchy and creates
+// DICOMDIR. Files identical to the ones already in the output directory
+// (rerun of the same profile) are left untouched and reported as skipped, so
+// that refreshing fixtures is idempotent.
+func OrganizeFiles(outputDir string, files []GeneratedFile, opts OrganizeOptions) (OrganizeReport, error) {
+	var report OrganizeReport
+	if opts.UIDRoot != "" {
+		if err := util.ValidateUIDRoot(opts.UIDRoot); err != nil {
+			return report, err
+		}
+	}
+	fanout := opts.Fanout
+	if fanout == 0 {
+		fanout = DefaultShardFanout
+	}
 	if len(files) == 0 {
-		return fmt.Errorf("no files to organize")
+		return report, fmt.Errorf("no files to organize")
 	}
 
-	if !quiet {
+	if !opts.Quiet {
 		fmt.Println("\nCreating DICOMDIR file...")
 	}
 
-	// Group files by patient -> study -> series
+	// Group files by patient -> study -> series, in generation order so that
+	// reruns of the same profile produce the same hierarchy
 	type SeriesGroup struct {
 		StudyUID   string
 		SeriesUID  string
@@ -48,16 +83,19 @@ func OrganizeFilesIntoDICOMDIR(outputDir string, files []GeneratedFile, quiet bo
 	}
 
 	type StudyGroup struct {
-		StudyUID string
-		Series   map[string]*SeriesGroup
+		StudyUID    string
+		Series      map[string]*SeriesGroup
+		SeriesOrder []string
 	}
 
 	type PatientGroup struct {
-		PatientID string
-		Studies   map[string]*StudyGroup
+		PatientID  string
+		Studies    map[string]*StudyGroup
+		StudyOrder []string
 	}
 
 	patients := make(map[string]*PatientGroup)
+	var patientOrder []string
 
 	// Group files
 	for _, file := range files {
@@ -67,6 +105,7 @@ func OrganizeFilesIntoDICOMDIR(outputDir string, files []GeneratedFile, quiet bo
 				PatientID: file.PatientID,
 				Studies:   make(map[string]*StudyGroup),
 			}
+			patientOrder = append(patientOrder, file.PatientID)
 		}
 		patient := patients[file.PatientID]
 
@@ -76,6 +115,7 @@ func OrganizeFilesIntoDICOMDIR(outputDir string, files []GeneratedFile, quiet bo
 				StudyUID: file.StudyUID,
 				Series:   make(map[string]*SeriesGroup),
 			}
+			patient.StudyOrder = append(patient.StudyOrder, file.StudyUID)
 		}
 		study := patient.Studies[file.StudyUID]
 
@@ -86,6 +126,7 @@ func OrganizeFilesIntoDICOMDIR(outputDir string, files []GeneratedFile, quiet bo
 				SeriesUID: file.SeriesUID,
 				Files:     []GeneratedFile{},
 			}
+			study.SeriesOrder = append(study.SeriesOrder, file.SeriesUID)
 		}
 		series := study.Series[file.SeriesUID]
 
@@ -94,81 +135,96 @@ func OrganizeFilesIntoDICOMDIR(outputDir string, files []GeneratedFile, quiet bo
 	}
 
 	// Create PT*/ST*/SE* hierarchy and move files
-	patientIdx := 0
 	totalMoved := 0
 
-	for _, patient := range patients {
+	for patientIdx, patientID := range patientOrder {
+		patient := patients[patientID]
 		patientDir := fmt.Sprintf("PT%06d", patientIdx)
-		patientPath := filepath.Join(outputDir, patientDir)
+		patientPath := shardedPath(outputDir, patientDir, patientIdx, len(patientOrder), fanout)
 		if err := os.MkdirAll(patientPath, 0755); err != nil {
-			return fmt.Errorf("create patient directory: %w", err)
+			return report, fmt.Errorf("create patient directory: %w", err)
 		}
 
-		studyIdx := 0
-		for _, study := range patient.Studies {
+		for studyIdx, studyUID := range patient.StudyOrder {
+			study := patient.Studies[studyUID]
 			studyDir := fmt.Sprintf("ST%06d", studyIdx)
-			studyPath := filepath.Join(patientPath, studyDir)
+			studyPath := shardedPath(patientPath, studyDir, studyIdx, len(patient.StudyOrder), fanout)
 			if err := os.MkdirAll(studyPath, 0755); err != nil {
-				return fmt.Errorf("create study directory: %w", err)
+				return report, fmt.Errorf("create study directory: %w", err)
 			}
 
-			seriesIdx := 0
-			for _, series := range study.Series {
+			for seriesIdx, seriesUID := range study.SeriesOrder {
+				series := study.Series[seriesUID]
 				seriesDir := fmt.Sprintf("SE%06d", seriesIdx)
-				seriesPath := filepath.Join(studyPath, seriesDir)
+				seriesPath := shardedPath(studyPath, seriesDir, seriesIdx, len(study.SeriesOrder), fanout)
 				if err := os.MkdirAll(seriesPath, 0755); err != nil {
-					return fmt.Errorf("create series directory: %w", err)
+					return report, fmt.Errorf("create series directory: %w", err)
 				}
 
 				// Sort files by instance number
-				sort.Slice(series.Files, func(i, j int) bool {
+				sort.SliceStable(series.Files, func(i, j int) bool {
 					return series.Files[i].InstanceNumber < series.Files[j].InstanceNumber
 				})
 
-				// Move files into series directory
+				// Move files into series directory, keeping identical existing files
 				for imageIdx, file := range series.Files {
 					imageFile := fmt.Sprintf("IM%06d", imageIdx+1)
-					destPath := filepath.Join(seriesPath, imageFile)
+					destPath := shardedPath(seriesPath, imageFile, imageIdx, len(series.Files), fanout)
+					if imageIdx%fanout == 0 {
+						if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+							return report, fmt.Errorf("create image shard directory: %w", err)
+						}
+					}
 
-					// Move file
-					if err := os.Rename(file.Path, destPath); err != nil {
-						return fmt.Errorf("move file %s to %s: %w", file.Path, destPath, err)
+					skipped, err := moveIfChanged(file.Path, destPath)
+					if err != nil {
+						return report, err
+					}
+					if skipped {
+						report.Skipped++
+					} else {
+						report.Written++
 					}
 
 					totalMoved++
 				}
-
-				seriesIdx++
 			}
-			studyIdx++
 		}
-		patientIdx++
 	}
 
-	if !quiet {
+	if !opts.Quiet {
 		fmt.Printf("✓ DICOMDIR created with standard hierarchy\n")
 		fmt.Printf("  Organized %d files into PT*/ST*/SE* structure\n", totalMoved)
+		if report.Skipped > 0 {
+			fmt.Printf("  %d files written, %d identical files skipped\n", report.Written, report.Skipped)
+		}
 	}
 
-	// Create DICOMDIR file with directory records
-	if err := createDICOMDIRFile(outputDir); err != nil {
-		return fmt.Errorf("create DICOMDIR file: %w", err)
+	// Create DICOMDIR file with directory records, kept untouched when unchanged
+	dicomdirPath := filepath.Join(outputDir, "DICOMDIR")
+	tmpPath := dicomdirPath + ".tmp"
+	if err := createDICOMDIRFile(outputDir, tmpPath, opts.UIDRoot); err != nil {
+		return report, fmt.Errorf("create DICOMDIR file: %w", err)
+	}
+	if _, err := moveIfChanged(tmpPath, dicomdirPath); err != nil {
+		return report, fmt.Errorf("create DICOMDIR file: %w", err)
 	}
 
 	// Clean up original IMG*.dcm files if they still exist
-	if !quiet {
+	if !opts.Quiet {
 		fmt.Println("\nCleaning up temporary files...")
 	}
 	removedCount := 0
-	pattern := filepath.Join(outputDir, "IMG*.dcm")
-	matches, _ := filepath.Glob(pattern)
-	for _, match := range matches {
+	matches, _ := filepath.Glob(filepath.Join(outputDir, "IMG*.dcm"))
+	staged, _ := filepath.Glob(filepath.Join(outputDir, stagingPrefix+"*", "IMG*.dcm"))
+	for _, match := range append(matches, staged...) {
 		if err := os.Remove(match); err == nil {
 			removedCount++
 		}
 	}
+	removeStagingDirs(outputDir)
 
-	if !quiet {
+	if !opts.Quiet {
 		if removedCount > 0 {
 			fmt.Printf("✓ %d temporary files removed\n", removedCount)
 		}
@@ -180,7 +236,7 @@ func OrganizeFilesIntoDICOMDIR(outputDir string, files []GeneratedFile, quiet bo
 		fmt.Println("  - PT*/ST*/SE*/ (patient/study/series hierarchy)")
 	}
 
-	return nil
+	return report, nil
 }
 
 // getStringValue safely extracts a string value from a dataset
@@ -235,69 +291,67 @@ func parseDICOMTolerant(filepath string) (dicom.Dataset, error) {
 	return ds, nil
 }
 
-// createDICOMDIRFile creates a complete DICOMDIR file with directory record sequence
-func createDICOMDIRFile(outputDir string) error {
-	dicomdirPath := filepath.Join(outputDir, "DICOMDIR")
+// Keys of the PATIENT, STUDY and SERIES directory records, in tag order
+var (
+	patientRecordKeys = []tag.Tag{tag.PatientName, tag.PatientID, tag.PatientBirthDate, tag.PatientSex}
+	studyRecordKeys   = []tag.Tag{tag.StudyDate, tag.StudyTime, tag.AccessionNumber, tag.StudyDescription, tag.StudyInstanceUID, tag.StudyID}
+	seriesRecordKeys  = []tag.Tag{tag.Modality, tag.SeriesInstanceUID, tag.SeriesNumber}
+)
+
+// directoryRecordKeys returns the keys of a directory record taken from ds,
+// empty when ds lacks them.
+func directoryRecordKeys(ds dicom.Dataset, keys []tag.Tag) []*dicom.Element {
+	elements := make([]*dicom.Element, len(keys))
+	for i, t := range keys {
+		elements[i] = mustNewElement(t, getStringValue(ds, t))
+	}
+	return elements
+}
+
+// createDICOMDIRFile creates a complete DICOMDIR file with directory record
+// sequence at dicomdirPath, indexing the hierarchy of outputDir, identified by
+// a UID under uidRoot (util.DefaultUIDRoot when empty)
+func createDICOMDIRFile(outputDir, dicomdirPath, uidRoot string) error {
 
 	// Collect all DICOM files organized by hierarchy
 	type ImageInfo struct {
-		RelPath        string
-		SOPClassUID    string
-		SOPInstanceUID string
+		RelPath           string
+		SOPClassUID       string
+		SOPInstanceUID    string
+		TransferSyntaxUID string
+		InstanceNumber    string
 	}
 
+	// Keys of the PATIENT, STUDY and SERIES records, read from the first
+	// readable file of their directory
 	type SeriesInfo struct {
-		SeriesUID    string
-		SeriesNumber string
-		Modality     string
-		Images       []ImageInfo
+		Keys   []*dicom.Element
+		Images []ImageInfo
 	}
 
 	type StudyInfo struct {
-		StudyUID  string
-		StudyID   string
-		StudyDate string
-		StudyTime string
-		Series    []SeriesInfo
+		Keys   []*dicom.Element
+		Series []SeriesInfo
 	}
 
 	type PatientInfo struct {
-		PatientID   string
-		PatientName string
-		Studies     []StudyInfo
+		Keys    []*dicom.Element
+		Studies []StudyInfo
 	}
 
 	var patients []PatientInfo
 
-	// Walk the PT*/ST*/SE* hierarchy
-	patientDirs, _ := filepath.Glob(filepath.Join(outputDir, "PT*"))
-	sort.Strings(patientDirs)
-
-	for _, patientDir := range patientDirs {
-		patient := PatientInfo{
-			Studies: []StudyInfo{},
-		}
-
-		studyDirs, _ := filepath.Glob(filepath.Join(patientDir, "ST*"))
-		sort.Strings(studyDirs)
+	// Walk the PT*/ST*/SE* hierarchy, through its shards
+	for _, patientDir := range hierarchyEntries(outputDir, "PT") {
+		var patient PatientInfo
 
-		for _, studyDir := range studyDirs {
-			study := StudyInfo{
-				Series: []SeriesInfo{},
-			}
+		for _, studyDir := range hierarchyEntries(patientDir, "ST") {
+			var study StudyInfo
 
-			seriesDirs, _ := filepath.Glob(filepath.Join(studyDir, "SE*"))
-			sort.Strings(seriesDirs)
-
-			for _, seriesDir := range seriesDirs {
-				series := SeriesInfo{
-					Images: []ImageInfo{},
-				}
+			for _, seriesDir := range hierarchyEntries(studyDir, "SE") {
+				var series SeriesInfo
 
-				imageFiles, _ := filepath.Glob(filepath.Join(seriesDir, "IM*"))
-				sort.Strings(imageFiles)
-
-				for _, imageFile := range imageFiles {
+				for _, imageFile := range hierarchyEntries(seriesDir, "IM") {
 					// Parse DICOM file with tolerance for malformed elements.
 					// Uses element-by-element parsing to handle files with intentionally
 					// corrupted tags (e.g., from --corrupt malformed-lengths).
@@ -309,36 +363,26 @@ func createDICOMDIRFile(outputDir string) error {
 					// Get relative path from outputDir
 					relPath, _ := filepath.Rel(outputDir, imageFile)
 
-					// Extract metadata
-					sopClass := getStringValue(ds, tag.SOPClassUID)
-					sopInstance := getStringValue(ds, tag.SOPInstanceUID)
-
 					image := ImageInfo{
-						RelPath:        filepath.ToSlash(relPath),
-						SOPClassUID:    sopClass[0],
-						SOPInstanceUID: sopInstance[0],
+						RelPath:           filepath.ToSlash(relPath),
+						SOPClassUID:       getStringValue(ds, tag.SOPClassUID)[0],
+						SOPInstanceUID:    getStringValue(ds, tag.SOPInstanceUID)[0],
+						TransferSyntaxUID: getStringValue(ds, tag.TransferSyntaxUID)[0],
+						InstanceNumber:    getStringValue(ds, tag.InstanceNumber)[0],
+					}
+					if image.TransferSyntaxUID == "" {
+						image.TransferSyntaxUID = TransferSyntaxExplicitVRLittleEndian
 					}
 					series.Images = append(series.Images, image)
 
-					// Get series info from first image
-					if len(series.Images) == 1 {
-						series.SeriesUID = getStringValue(ds, tag.SeriesInstanceUID)[0]
-						series.SeriesNumber = getStringValue(ds, tag.SeriesNumber)[0]
-						series.Modality = getStringValue(ds, tag.Modality)[0]
+					if series.Keys == nil {
+						series.Keys = directoryRecordKeys(ds, seriesRecordKeys)
 					}
-
-					// Get study info from first image
-					if len(study.Series) == 0 && len(series.Images) == 1 {
-						study.StudyUID = getStringValue(ds, tag.StudyInstanceUID)[0]
-						study.StudyID = getStringValue(ds, tag.StudyID)[0]
-						study.StudyDate = getStringValue(ds, tag.StudyDate)[0]
-						study.StudyTime = getStringValue(ds, tag.StudyTime)[0]
+					if study.Keys == nil {
+						study.Keys = directoryRecordKeys(ds, studyRecordKeys)
 					}
-
-					// Get patient info from first image of this patient
-					if patient.PatientID == "" && len(series.Images) == 1 {
-						patient.PatientID = getStringValue(ds, tag.PatientID)[0]
-						patient.PatientName = getStringValue(ds, tag.PatientName)[0]
+					if patient.Keys == nil {
+						patient.Keys = directoryRecordKeys(ds, patientRecordKeys)
 					}
 				}
 
@@ -368,10 +412,8 @@ func createDICOMDIRFile(outputDir string) error {
 			mustNewElement(tag.RecordInUseFlag, []int{0xFFFF}),           // 0xFFFF means record is in use
 			mustNewElement(tag.OffsetOfReferencedLowerLevelDirectoryEntity, []int{0}), // Points to first STUDY
 			mustNewElement(tag.DirectoryRecordType, []string{"PATIENT"}),
-			mustNewElement(tag.PatientID, []string{patient.PatientID}),
-			mustNewElement(tag.PatientName, []string{patient.PatientName}),
 		}
-		recordItems = append(recordItems, patientElements)
+		recordItems = append(recordItems, append(patientElements, patient.Keys...))
 
 		for _, study := range patient.Studies {
 			// STUDY record
@@ -380,12 +422,8 @@ func createDICOMDIRFile(outputDir string) error {
 				mustNewElement(tag.RecordInUseFlag, []int{0xFFFF}),           // 0xFFFF means record is in use
 				mustNewElement(tag.OffsetOfReferencedLowerLevelDirectoryEntity, []int{0}), // Points to first SERIES
 				mustNewElement(tag.DirectoryRecordType, []string{"STUDY"}),
-				mustNewElement(tag.StudyInstanceUID, []string{study.StudyUID}),
-				mustNewElement(tag.StudyID, []string{study.StudyID}),
-				mustNewElement(tag.StudyDate, []string{study.StudyDate}),
-				mustNewElement(tag.StudyTime, []string{study.StudyTime}),
 			}
-			recordItems = append(recordItems, studyElements)
+			recordItems = append(recordItems, append(studyElements, study.Keys...))
 
 			for _, series := range study.Series {
 				// SERIES record
@@ -394,11 +432,8 @@ func createDICOMDIRFile(outputDir string) error {
 					mustNewElement(tag.RecordInUseFlag, []int{0xFFFF}),           // 0xFFFF means record is in use
 					mustNewElement(tag.OffsetOfReferencedLowerLevelDirectoryEntity, []int{0}), // Points to first IMAGE
 					mustNewElement(tag.DirectoryRecordType, []string{"SERIES"}),
-					mustNewElement(tag.Modality, []string{series.Modality}),
-					mustNewElement(tag.SeriesInstanceUID, []string{series.SeriesUID}),
-					mustNewElement(tag.SeriesNumber, []string{series.SeriesNumber}),
 				}
-				recordItems = append(recordItems, seriesElements)
+				recordItems = append(recordItems, append(seriesElements, series.Keys...))
 
 				for _, image := range series.Images {
 					// IMAGE record
@@ -409,11 +444,12 @@ func createDICOMDIRFile(outputDir string) error {
 						mustNewElement(tag.OffsetOfTheNextDirectoryRecord, []int{0}), // Will be updated
 						mustNewElement(tag.RecordInUseFlag, []int{0xFFFF}),           // 0xFFFF means record is in use
 						mustNewElement(tag.OffsetOfReferencedLowerLevelDirectoryEntity, []int{0}), // No children for IMAGE
-						mustNewElement(tag.DirectoryRecordType, []string{"IMAGE"}),
+						mustNewElement(tag.DirectoryRecordType, []string{directoryRecordType(image.SOPClassUID)}),
 						mustNewElement(tag.ReferencedFileID, pathParts),
 						mustNewElement(tag.ReferencedSOPClassUIDInFile, []string{image.SOPClassUID}),
 						mustNewElement(tag.ReferencedSOPInstanceUIDInFile, []string{image.SOPInstanceUID}),
-						mustNewElement(tag.ReferencedTransferSyntaxUIDInFile, []string{"1.2.840.10008.1.2.1"}),
+						mustNewElement(tag.ReferencedTransferSyntaxUIDInFile, []string{image.TransferSyntaxUID}),
+						mustNewElement(tag.InstanceNumber, []string{image.InstanceNumber}),
 					}
 					recordItems = append(recordItems, imageElements)
 				}
@@ -426,23 +462,29 @@ func createDICOMDIRFile(outputDir string) error {
 		Elements: []*dicom.Element{},
 	}
 
-	// File Meta Information (must be first)
+	// FileSet ID, from the output directory name
+	filesetID := filepath.Base(outputDir)
+	if len(filesetID) > 16 {
+		filesetID = filesetID[:16]
+	}
+
+	// File Meta Information (must be first). ImplementationClassUID
+	// identifies dicomforge and keeps its root.
+	sopInstanceUID := util.DefaultUIDRoot + ".1"
+	if uidRoot != "" && uidRoot != util.DefaultUIDRoot {
+		sopInstanceUID = util.GenerateUIDUnderRoot(uidRoot, "dicomdir_"+filesetID)
+	}
 	ds.Elements = append(ds.Elements,
 		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}), // Explicit VR Little Endian
-		mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.1.3.10"}), // Media Storage Directory Storage
-		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.826.0.1.3680043.8.498.1"}),
-		mustNewElement(tag.ImplementationClassUID, []string{"1.2.826.0.1.3680043.8.498"}),
+		mustNewElement(tag.MediaStorageSOPClassUID, []string{mediaStorageDirectoryStorage}),
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.ImplementationClassUID, []string{util.DefaultUIDRoot}),
 	)
 
 	// FileSet Identification
-	filesetID := filepath.Base(outputDir)
-	if len(filesetID) > 16 {
-		filesetID = filesetID[:16]
-	}
 	ds.Elements = append(ds.Elements,
 		mustNewElement(tag.FileSetID, []string{filesetID}),
-		// Directory record offsets - these should be byte offsets but we set to 0
-		// A proper implementation would calculate these during write
+		// Directory record offsets, computed by writeDICOMDIR
 		mustNewElement(tag.OffsetOfTheFirstDirectoryRecordOfTheRootDirectoryEntity, []int{0}),
 		mustNewElement(tag.OffsetOfTheLastDirectoryRecordOfTheRootDirectoryEntity, []int{0}),
 		// FileSet Consistency Flag - 0 means no known inconsistencies
@@ -459,322 +501,187 @@ func createDICOMDIRFile(outputDir string) error {
 		ds.Elements = append(ds.Elements, seqElem)
 	}
 
-	// Write DICOMDIR (first pass with offsets at 0)
-	if err := writeDatasetToFile(dicomdirPath, *ds); err != nil {
+	if err := writeDICOMDIR(dicomdirPath, *ds); err != nil {
 		return fmt.Errorf("write DICOMDIR: %w", err)
 	}
-
-	// Second pass: update offsets with correct byte positions
-	if err := updateDICOMDIROffsets(dicomdirPath); err != nil {
-		return fmt.Errorf("update DICOMDIR offsets: %w", err)
-	}
-
 	return nil
 }
 
-// updateDICOMDIROffsets reads a DICOMDIR file and updates the offset tags with correct byte positions
-func updateDICOMDIROffsets(dicomdirPath string) error {
-	// Read the entire DICOMDIR file
-	data, err := os.ReadFile(dicomdirPath)
+// writeDICOMDIR writes a DICOMDIR data set to path with the byte offsets of
+// its directory records, from the first byte of the file: the first and last
+// PATIENT records, and in each record its next sibling and its first child
+// (0 for none). The records are in hierarchy order, each followed by its
+// children, their level given by their DirectoryRecordType.
+//
+// The data set is encoded twice: the offsets are UL values, so those of the
+// first encoding, all 0, take the same bytes as the final ones, and the
+// records are found at the length of the records before them. Its text is
+// encoded first (see util.EncodeText), for the lengths to be those written.
+func writeDICOMDIR(path string, ds dicom.Dataset) error {
+	ds.Elements = util.EncodeText(ds.Elements)
+	seqElem, err := ds.FindElementByTag(tag.DirectoryRecordSequence)
 	if err != nil {
-		return fmt.Errorf("read DICOMDIR: %w", err)
+		return writeEncodedDatasetToFile(path, ds)
 	}
-
-	// Find the Directory Record Sequence (0004,1220)
-	// We need to find where each Item (Directory Record) starts
-	recordPositions, err := findDirectoryRecordPositions(data)
-	if err != nil {
-		return fmt.Errorf("find record positions: %w", err)
+	if last := ds.Elements[len(ds.Elements)-1]; last != seqElem {
+		return fmt.Errorf("directory record sequence must be the last element, found %v after it", last.Tag)
 	}
-
-	if len(recordPositions) == 0 {
-		return fmt.Errorf("no directory records found")
+	var records [][]*dicom.Element
+	for _, item := range seqElem.Value.GetValue().([]*dicom.SequenceItemValue) {
+		records = append(records, item.GetValue().([]*dicom.Element))
 	}
 
-	// Now update the offset values in the file
-	// We need to update:
-	// 1. OffsetOfTheFirstDirectoryRecordOfTheRootDirectoryEntity (0004,1200)
-	// 2. OffsetOfTheLastDirectoryRecordOfTheRootDirectoryEntity (0004,1202)
-	// 3. OffsetOfTheNextDirectoryRecord (0004,1400) in each record
-	// 4. OffsetOfReferencedLowerLevelDirectoryEntity (0004,1420) in each record
-
-	// Update file with calculated offsets
-	if err := updateOffsetsInFile(dicomdirPath, data, recordPositions); err != nil {
-		return fmt.Errorf("update offsets in file: %w", err)
-	}
-
-	return nil
-}
-
-// findDirectoryRecordPositions scans the DICOMDIR binary data to find the byte position of each Directory Record
-func findDirectoryRecordPositions(data []byte) ([]int64, error) {
-	var positions []int64
-
-	// Look for Item tags (FFFE,E000) which indicate the start of each Directory Record
-	// In DICOM binary: Tag is little-endian, so (FFFE,E000) = 0xE0 0x00 0xFE 0xFF in bytes
-	itemTag := []byte{0xFE, 0xFF, 0x00, 0xE0}
-
-	// Start searching after the file meta information
-	// Skip preamble (128 bytes) + "DICM" (4 bytes) = 132 bytes minimum
-	searchStart := 132
-
-	for i := searchStart; i < len(data)-4; i++ {
-		if bytes.Equal(data[i:i+4], itemTag) {
-			// Found an item tag, this could be a Directory Record
-			// Verify it's within the Directory Record Sequence by checking context
-			positions = append(positions, int64(i))
+	setOffsets := func(offsets []uint32) error {
+		first, last := uint32(0), uint32(0)
+		links := linkDirectoryRecords(records)
+		for i, record := range records {
+			next, lower := uint32(0), uint32(0)
+			if links[i].next >= 0 {
+				next = offsets[links[i].next]
+			}
+			if links[i].lower >= 0 {
+				lower = offsets[links[i].lower]
+			}
+			if directoryRecordLevel(record) == 0 {
+				if first == 0 {
+					first = offsets[i]
+				}
+				last = offsets[i]
+			}
+			record = setElement(record, mustNewElement(tag.OffsetOfTheNextDirectoryRecord, []int{int(next)}))
+			records[i] = setElement(record, mustNewElement(tag.OffsetOfReferencedLowerLevelDirectoryEntity, []int{int(lower)}))
 		}
+		seq, err := dicom.NewElement(tag.DirectoryRecordSequence, records)
+		if err != nil {
+			return fmt.Errorf("create directory record sequence: %w", err)
+		}
+		ds.Elements = setElement(ds.Elements, mustNewElement(tag.OffsetOfTheFirstDirectoryRecordOfTheRootDirectoryEntity, []int{int(first)}))
+		ds.Elements = setElement(ds.Elements, mustNewElement(tag.OffsetOfTheLastDirectoryRecordOfTheRootDirectoryEntity, []int{int(last)}))
+		ds.Elements = setElement(ds.Elements, seq)
+		seqElem = seq
+		return nil
 	}
 
-	return positions, nil
-}
-
-// updateOffsetsInFile updates the offset values in the DICOMDIR file
-func updateOffsetsInFile(path string, data []byte, recordPositions []int64) error {
-	// Parse the DICOMDIR to understand the structure
-	ds, err := dicom.ParseFile(path, nil)
-	if err != nil {
-		return fmt.Errorf("parse DICOMDIR: %w", err)
+	// First pass: offsets at 0, to find where the records are
+	offsets := make([]uint32, len(records))
+	if err := setOffsets(offsets); err != nil {
+		return err
 	}
-
-	// Get the Directory Record Sequence
-	seqElem, err := ds.FindElementByTag(tag.DirectoryRecordSequence)
+	var file bytes.Buffer
+	if err := dicom.Write(&file, ds); err != nil {
+		return err
+	}
+	seqLength, err := encodedLength(seqElem)
 	if err != nil {
-		return fmt.Errorf("find directory record sequence: %w", err)
+		return err
 	}
-
-	seqItems := seqElem.Value.GetValue().([]*dicom.SequenceItemValue)
-
-	// We need to map which record position corresponds to which record in the hierarchy
-	// For simplicity, we'll build a mapping based on the order
-
-	// Count records by type to understand the hierarchy
-	var recordInfos []RecordInfo
-	for i, item := range seqItems {
-		if i >= len(recordPositions) {
-			break
-		}
-		elements := item.GetValue().([]*dicom.Element)
-		recordType := ""
-		for _, elem := range elements {
-			if elem.Tag == tag.DirectoryRecordType {
-				recordType = elem.Value.GetValue().([]string)[0]
-				break
-			}
+	// The sequence ends the file: its header (tag, VR, reserved bytes and
+	// undefined length), then each record as an item of undefined length
+	offset := file.Len() - seqLength + 12
+	for i, record := range records {
+		offsets[i] = uint32(offset)
+		length, err := encodedLength(record...)
+		if err != nil {
+			return err
 		}
-		recordInfos = append(recordInfos, RecordInfo{
-			Type:     recordType,
-			Index:    i,
-			Position: recordPositions[i],
-		})
+		offset += 8 + length + 8 // Item, its elements and item delimitation
 	}
-
-	// Now update the offsets
-	// Strategy: Open file for read/write and update specific offset fields
-	f, err := os.OpenFile(path, os.O_RDWR, 0644)
-	if err != nil {
-		return fmt.Errorf("open file for update: %w", err)
+	if offset+8 != file.Len() {
+		return fmt.Errorf("directory records end at %d, not before the sequence delimitation at %d", offset, file.Len()-8)
 	}
-	defer func() { _ = f.Close() }()
-
-	// Update FirstDirectoryRecordOffset and LastDirectoryRecordOffset in the header
-	if len(recordPositions) > 0 {
-		firstOffset := uint32(recordPositions[0])
-		lastOffset := uint32(recordPositions[len(recordPositions)-1])
 
-		// Find and update (0004,1200) - FirstDirectoryRecordOffset
-		if pos := findTagPosition(data, 0x0004, 0x1200); pos >= 0 {
-			if err := updateUInt32At(f, pos+8, firstOffset); err != nil {
-				return fmt.Errorf("update first offset: %w", err)
-			}
-		}
-
-		// Find and update (0004,1202) - LastDirectoryRecordOffset
-		if pos := findTagPosition(data, 0x0004, 0x1202); pos >= 0 {
-			if err := updateUInt32At(f, pos+8, lastOffset); err != nil {
-				return fmt.Errorf("update last offset: %w", err)
-			}
-		}
+	// Second pass: the same bytes, with the offsets
+	if err := setOffsets(offsets); err != nil {
+		return err
 	}
+	return writeEncodedDatasetToFile(path, ds)
+}
 
-	// Build parent-child relationships to calculate proper offsets
-	hierarchy := buildHierarchy(recordInfos)
-
-	// Update offsets within each Directory Record with proper hierarchy
-	for i, info := range recordInfos {
-		basePos := info.Position
-
-		// Calculate OffsetOfTheNextDirectoryRecord
-		// This should point to the next sibling (same parent, same level)
-		nextOffset := hierarchy[i].NextSibling
-
-		// Calculate OffsetOfReferencedLowerLevelDirectoryEntity
-		// This should point to the first CHILD record
-		lowerOffset := hierarchy[i].FirstChild
+// directoryRecordLink gives the indexes of the next sibling and first child
+// of a directory record, -1 for none.
+type directoryRecordLink struct {
+	next, lower int
+}
 
-		// Update OffsetOfTheNextDirectoryRecord (0004,1400) in this record
-		if pos := findTagPositionAfter(data, int(basePos), 0x0004, 0x1400); pos >= 0 {
-			if err := updateUInt32At(f, int64(pos+8), nextOffset); err != nil {
-				return fmt.Errorf("update next offset at record %d: %w", i, err)
-			}
+// linkDirectoryRecords links the records of a directory record sequence in
+// hierarchy order.
+func linkDirectoryRecords(records [][]*dicom.Element) []directoryRecordLink {
+	links := make([]directoryRecordLink, len(records))
+	for i := range records {
+		links[i] = directoryRecordLink{next: -1, lower: -1}
+		level := directoryRecordLevel(records[i])
+		if i+1 < len(records) && directoryRecordLevel(records[i+1]) > level {
+			links[i].lower = i + 1
 		}
-
-		// Update OffsetOfReferencedLowerLevelDirectoryEntity (0004,1420) in this record
-		if pos := findTagPositionAfter(data, int(basePos), 0x0004, 0x1420); pos >= 0 {
-			if err := updateUInt32At(f, int64(pos+8), lowerOffset); err != nil {
-				return fmt.Errorf("update lower offset at record %d: %w", i, err)
+		for j := i + 1; j < len(records); j++ {
+			if l := directoryRecordLevel(records[j]); l <= level {
+				if l == level {
+					links[i].next = j
+				}
+				break
 			}
 		}
 	}
-
-	return nil
+	return links
 }
 
-// RecordInfo holds information about a directory record
-type RecordInfo struct {
-	Type     string
-	Index    int
-	Position int64
-}
-
-// HierarchyInfo holds offset information for a record
-type HierarchyInfo struct {
-	NextSibling uint32
-	FirstChild  uint32
-}
-
-// buildHierarchy analyzes the record list and builds parent-child relationships
-func buildHierarchy(records []RecordInfo) map[int]HierarchyInfo {
-	result := make(map[int]HierarchyInfo)
-
-	// Track hierarchy levels: when we see a record, remember where we are
-	// We process records in order, maintaining a stack of "current" items at each level
-	type LevelState struct {
-		Type     string
-		Index    int
-		Children []int // indices of direct children
-	}
-
-	var stack []*LevelState       // stack of current items at each hierarchy level
-	var rootRecords []int         // indices of root-level records (PATIENT)
-
-	for i, record := range records {
-		// Pop stack until we find where this record belongs
-		level := getHierarchyLevel(record.Type)
-
-		// Pop items from stack that are at >= this level (we're back up the tree)
-		for len(stack) > level {
-			stack = stack[:len(stack)-1]
+// directoryRecordLevel returns the level of a directory record in the
+// hierarchy: 0 for PATIENT, 1 for STUDY, 2 for SERIES and 3 for the records
+// of instances.
+func directoryRecordLevel(record []*dicom.Element) int {
+	for _, elem := range record {
+		if elem.Tag != tag.DirectoryRecordType {
+			continue
 		}
-
-		// If stack is not empty, this record is a child of the top item
-		if len(stack) > 0 {
-			parent := stack[len(stack)-1]
-			parent.Children = append(parent.Children, i)
-		} else {
-			// This is a root-level record (PATIENT)
-			// Link to previous root record if exists
-			if len(rootRecords) > 0 {
-				prevRootIdx := rootRecords[len(rootRecords)-1]
-				info := result[prevRootIdx]
-				info.NextSibling = uint32(records[i].Position)
-				result[prevRootIdx] = info
-			}
-			rootRecords = append(rootRecords, i)
-		}
-
-		// Push this record onto the stack
-		stack = append(stack, &LevelState{
-			Type:     record.Type,
-			Index:    i,
-			Children: []int{},
-		})
-
-		// Now calculate offsets for all completed siblings
-		// When we add a new item at a level, we can finalize the previous item's NextSibling
-		if len(stack) >= 2 {
-			parentLevel := stack[len(stack)-2]
-			if len(parentLevel.Children) >= 2 {
-				// There are at least 2 children, so we can link them
-				prevChildIdx := parentLevel.Children[len(parentLevel.Children)-2]
-				currChildIdx := parentLevel.Children[len(parentLevel.Children)-1]
-
-				// Previous child's NextSibling points to current child
-				info := result[prevChildIdx]
-				info.NextSibling = uint32(records[currChildIdx].Position)
-				result[prevChildIdx] = info
-			}
-
-			// First child: parent's FirstChild points to it
-			if len(parentLevel.Children) == 1 {
-				childIdx := parentLevel.Children[0]
-				parentIdx := parentLevel.Index
-				info := result[parentIdx]
-				info.FirstChild = uint32(records[childIdx].Position)
-				result[parentIdx] = info
+		if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 {
+			switch strings.TrimSpace(values[0]) {
+			case "PATIENT":
+				return 0
+			case "STUDY":
+				return 1
+			case "SERIES":
+				return 2
 			}
 		}
 	}
-
-	// Final pass: ensure all indices have an entry (even if both offsets are 0)
-	for i := range records {
-		if _, exists := result[i]; !exists {
-			result[i] = HierarchyInfo{}
-		}
-	}
-
-	return result
+	return 3
 }
 
-// getHierarchyLevel returns the hierarchy level (0=PATIENT, 1=STUDY, 2=SERIES, 3=IMAGE)
-func getHierarchyLevel(recordType string) int {
-	switch recordType {
-	case "PATIENT":
-		return 0
-	case "STUDY":
-		return 1
-	case "SERIES":
-		return 2
-	case "IMAGE":
-		return 3
-	default:
-		return -1
+// encodedLength returns the length of elements encoded in Explicit VR Little
+// Endian.
+func encodedLength(elements ...*dicom.Element) (int, error) {
+	var buf bytes.Buffer
+	w, err := dicom.NewWriter(&buf, dicom.SkipVRVerification(), dicom.SkipValueTypeVerification())
+	if err != nil {
+		return 0, err
 	}
-}
-
-// findTagPosition finds the byte position of a DICOM tag in the data
-func findTagPosition(data []byte, group, element uint16) int64 {
-	// DICOM tags are stored as: group (2 bytes LE) + element (2 bytes LE)
-	tagBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint16(tagBytes[0:2], group)
-	binary.LittleEndian.PutUint16(tagBytes[2:4], element)
-
-	for i := 0; i < len(data)-4; i++ {
-		if bytes.Equal(data[i:i+4], tagBytes) {
-			return int64(i)
+	w.SetTransferSyntax(binary.LittleEndian, false)
+	for _, elem := range elements {
+		if err := w.WriteElement(elem); err != nil {
+			return 0, fmt.Errorf("encode %v: %w", elem.Tag, err)
 		}
 	}
-	return -1
+	return buf.Len(), nil
 }
 
-// findTagPositionAfter finds the byte position of a DICOM tag after a given position
-func findTagPositionAfter(data []byte, startPos int, group, element uint16) int64 {
-	tagBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint16(tagBytes[0:2], group)
-	binary.LittleEndian.PutUint16(tagBytes[2:4], element)
-
-	for i := startPos; i < len(data)-4 && i < startPos+500; i++ { // Search within 500 bytes
-		if bytes.Equal(data[i:i+4], tagBytes) {
-			return int64(i)
-		}
-	}
-	return -1
+// directoryRecordType returns the DICOMDIR record type of an instance-level
+// record for the given SOP Class UID
+func directoryRecordType(sopClassUID string) string {
+	if strings.HasPrefix(sopClassUID, "1.2.840.10008.5.1.4.1.1.88.") {
+		return "SR DOCUMENT"
+	}
+	switch sopClassUID {
+	case RTDoseStorage:
+		return "RT DOSE"
+	case RTStructureSetStorage:
+		return "RT STRUCTURE SET"
+	case RTPlanStorage:
+		return "RT PLAN"
+	case SegmentationStorage:
+		return "SEGMENTATION"
+	case GrayscaleSoftcopyPresentationStateStorage:
+		return "PRESENTATION"
+	}
+	return "IMAGE"
 }
 
-// updateUInt32At writes a uint32 value at the specified position in the file
-func updateUInt32At(f io.WriteSeeker, pos int64, value uint32) error {
-	if _, err := f.Seek(pos, io.SeekStart); err != nil {
-		return err
-	}
-	return binary.Write(f, binary.LittleEndian, value)
-}
@@ -0,0 +1,192 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// dicomdirRecord reads the directory record at offset of a DICOMDIR encoded in
+// Explicit VR Little Endian: its type and the offsets of its next sibling and
+// first child.
+func dicomdirRecord(t *testing.T, data []byte, offset uint32) (recordType string, next, lower uint32) {
+	t.Helper()
+	pos := int(offset)
+	if pos+8 > len(data) || binary.LittleEndian.Uint16(data[pos:]) != 0xFFFE || binary.LittleEndian.Uint16(data[pos+2:]) != 0xE000 {
+		t.Fatalf("no item at offset %d", offset)
+	}
+	pos += 8
+	for pos+8 <= len(data) {
+		group, element := binary.LittleEndian.Uint16(data[pos:]), binary.LittleEndian.Uint16(data[pos+2:])
+		if group == 0xFFFE {
+			break // Item delimitation
+		}
+		vr := string(data[pos+4 : pos+6])
+		length, header := int(binary.LittleEndian.Uint16(data[pos+6:])), 8
+		switch vr {
+		case "OB", "OW", "OF", "SQ", "UC", "UN", "UR", "UT":
+			length, header = int(binary.LittleEndian.Uint32(data[pos+8:])), 12
+		}
+		value := data[pos+header : pos+header+length]
+		switch {
+		case group == 0x0004 && element == 0x1400:
+			next = binary.LittleEndian.Uint32(value)
+		case group == 0x0004 && element == 0x1420:
+			lower = binary.LittleEndian.Uint32(value)
+		case group == 0x0004 && element == 0x1430:
+			recordType = strings.TrimSpace(string(value))
+		}
+		pos += header + length
+	}
+	return recordType, next, lower
+}
+
+func TestDICOMDIROffsets(t *testing.T) {
+	outputDir := t.TempDir()
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:      8,
+		TotalSize:      "1MB",
+		OutputDir:      outputDir,
+		Seed:           42,
+		NumStudies:     2,
+		NumPatients:    2,
+		SeriesPerStudy: util.SeriesRange{Min: 2, Max: 2},
+		Modality:       modalities.CT,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := OrganizeFilesIntoDICOMDIR(outputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, "DICOMDIR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Root offsets: (0004,1200) and (0004,1202) UL values after their 8 byte header
+	rootOffset := func(element uint16) uint32 {
+		for i := 132; i+12 <= len(data); i += 2 {
+			if binary.LittleEndian.Uint16(data[i:]) == 0x0004 && binary.LittleEndian.Uint16(data[i+2:]) == element && string(data[i+4:i+6]) == "UL" {
+				return binary.LittleEndian.Uint32(data[i+8:])
+			}
+		}
+		t.Fatalf("(0004,%04X) not found", element)
+		return 0
+	}
+
+	// Walk the hierarchy by offsets only
+	wantTypes := []string{"PATIENT", "STUDY", "SERIES", "IMAGE"}
+	counts := make(map[string]int)
+	var lastPatient uint32
+	var walk func(offset uint32, level int)
+	walk = func(offset uint32, level int) {
+		for offset != 0 {
+			recordType, next, lower := dicomdirRecord(t, data, offset)
+			if recordType != wantTypes[level] {
+				t.Fatalf("record at %d is %s, want %s", offset, recordType, wantTypes[level])
+			}
+			counts[recordType]++
+			if level == 0 {
+				lastPatient = offset
+			}
+			if (lower == 0) != (level == 3) {
+				t.Errorf("%s record at %d has lower level offset %d", recordType, offset, lower)
+			}
+			if lower != 0 {
+				walk(lower, level+1)
+			}
+			offset = next
+		}
+	}
+	walk(rootOffset(0x1200), 0)
+
+	if counts["PATIENT"] != 2 || counts["STUDY"] != 2 || counts["SERIES"] != 4 || counts["IMAGE"] != len(files) {
+		t.Errorf("reached %v, want 2 patients, 2 studies, 4 series and %d images", counts, len(files))
+	}
+	if got := rootOffset(0x1202); got != lastPatient {
+		t.Errorf("last root record offset = %d, want the last PATIENT record at %d", got, lastPatient)
+	}
+}
+
+func TestDICOMDIRRecordKeys(t *testing.T) {
+	outputDir := t.TempDir()
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:      12,
+		TotalSize:      "1MB",
+		OutputDir:      outputDir,
+		Seed:           42,
+		NumStudies:     4,
+		NumPatients:    3,
+		SeriesPerStudy: util.SeriesRange{Min: 1, Max: 2},
+		Modality:       modalities.CT,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := OrganizeFilesIntoDICOMDIR(outputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	dicomdir, err := dicom.ParseFile(filepath.Join(outputDir, "DICOMDIR"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequence, err := dicomdir.FindElementByTag(tag.DirectoryRecordSequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each IMAGE record must match the keys of the records above it
+	enclosing := make(map[string]dicom.Dataset)
+	patientIDs := make(map[string]bool)
+	for _, item := range sequence.Value.GetValue().([]*dicom.SequenceItemValue) {
+		record := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+		recordType := getStringValue(record, tag.DirectoryRecordType)[0]
+		if recordType == "PATIENT" {
+			patientIDs[getStringValue(record, tag.PatientID)[0]] = true
+		}
+		if recordType != "IMAGE" {
+			enclosing[recordType] = record
+			continue
+		}
+
+		fileID, err := record.FindElementByTag(tag.ReferencedFileID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(append([]string{outputDir}, fileID.Value.GetValue().([]string)...)...)
+		image, err := dicom.ParseFile(path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		checks := map[string][]tag.Tag{
+			"PATIENT": patientRecordKeys,
+			"STUDY":   studyRecordKeys,
+			"SERIES":  seriesRecordKeys,
+			"IMAGE":   {tag.InstanceNumber},
+		}
+		for level, keys := range checks {
+			parent := enclosing[level]
+			if level == "IMAGE" {
+				parent = record
+			}
+			for _, key := range keys {
+				if got, want := getStringValue(parent, key)[0], getStringValue(image, key)[0]; got != want {
+					t.Errorf("%s: %s record has %v %q, file has %q", path, level, key, got, want)
+				}
+			}
+		}
+	}
+	if len(patientIDs) != 3 {
+		t.Errorf("got PATIENT records for %v, want 3 patients", patientIDs)
+	}
+}
@@ -0,0 +1,189 @@
+// Package dicomjson encodes datasets in the DICOM JSON model (PS3.18 Annex F),
+// the format of QIDO-RS results and WADO-RS metadata.
+package dicomjson
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Attribute is a DICOM JSON attribute. Exactly one of Value, InlineBinary and
+// BulkDataURI is set, or none for an empty value.
+type Attribute struct {
+	VR           string `json:"vr"`
+	Value        []any  `json:"Value,omitempty"`
+	InlineBinary string `json:"InlineBinary,omitempty"`
+	BulkDataURI  string `json:"BulkDataURI,omitempty"`
+}
+
+// Object is a dataset (or sequence item) in the DICOM JSON model, keyed by
+// the uppercase hexadecimal tag ("00100010").
+type Object map[string]Attribute
+
+// Options controls the encoding of binary values.
+type Options struct {
+	// BulkDataURI returns the URI referencing the value of a binary element.
+	// When nil, binary values are inlined, except PixelData which is left out
+	// (as WADO-RS metadata without bulk data).
+	BulkDataURI func(t tag.Tag) string
+}
+
+// Encode converts the elements of ds to the DICOM JSON model. File Meta
+// Information (group 0002) is not part of the model and is left out.
+func Encode(ds dicom.Dataset, opts Options) (Object, error) {
+	return encodeElements(ds.Elements, opts)
+}
+
+// Marshal encodes ds as an indented DICOM JSON object.
+func Marshal(ds dicom.Dataset, opts Options) ([]byte, error) {
+	obj, err := Encode(ds, opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(obj, "", "  ")
+}
+
+// Key returns the DICOM JSON key of a tag ("GGGGEEEE").
+func Key(t tag.Tag) string {
+	return fmt.Sprintf("%04X%04X", t.Group, t.Element)
+}
+
+// encodeElements converts elements to an object.
+func encodeElements(elements []*dicom.Element, opts Options) (Object, error) {
+	obj := make(Object, len(elements))
+	for _, elem := range elements {
+		if elem.Tag.Group == 0x0002 {
+			continue
+		}
+		attr, ok, err := encodeElement(elem, opts)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", Key(elem.Tag), err)
+		}
+		if ok {
+			obj[Key(elem.Tag)] = attr
+		}
+	}
+	return obj, nil
+}
+
+// encodeElement converts an element to an attribute. It returns false for
+// elements left out of the model.
+func encodeElement(elem *dicom.Element, opts Options) (Attribute, bool, error) {
+	vr := elem.RawValueRepresentation
+	attr := Attribute{VR: vr}
+
+	switch value := elem.Value.GetValue().(type) {
+	case []string:
+		attr.Value = encodeStrings(vr, value)
+	case []int:
+		attr.Value = encodeInts(vr, value)
+	case []float64:
+		for _, f := range value {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				attr.Value = append(attr.Value, nil)
+				continue
+			}
+			attr.Value = append(attr.Value, f)
+		}
+	case []byte:
+		if opts.BulkDataURI != nil {
+			attr.BulkDataURI = opts.BulkDataURI(elem.Tag)
+		} else if len(value) > 0 {
+			attr.InlineBinary = base64.StdEncoding.EncodeToString(value)
+		}
+	case []*dicom.SequenceItemValue:
+		attr.VR = "SQ"
+		for _, item := range value {
+			obj, err := encodeElements(item.GetValue().([]*dicom.Element), opts)
+			if err != nil {
+				return attr, false, err
+			}
+			attr.Value = append(attr.Value, obj)
+		}
+	case dicom.PixelDataInfo:
+		if opts.BulkDataURI == nil {
+			return attr, false, nil
+		}
+		attr.BulkDataURI = opts.BulkDataURI(elem.Tag)
+	default:
+		return attr, false, fmt.Errorf("unsupported value type %T", value)
+	}
+	return attr, true, nil
+}
+
+// encodeStrings converts string values: person names to objects, DS and IS to
+// numbers, and empty values to null. Invalid DS and IS values (e.g., from
+// corrupted datasets) are kept as strings.
+func encodeStrings(vr string, values []string) []any {
+	// A single empty value is an empty attribute
+	if len(values) == 0 || (len(values) == 1 && strings.TrimSpace(values[0]) == "") {
+		return nil
+	}
+
+	encoded := make([]any, 0, len(values))
+	for _, v := range values {
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			encoded = append(encoded, nil)
+			continue
+		}
+		switch vr {
+		case "PN":
+			encoded = append(encoded, encodePersonName(v))
+		case "DS":
+			if f, err := strconv.ParseFloat(trimmed, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				encoded = append(encoded, f)
+			} else {
+				encoded = append(encoded, trimmed)
+			}
+		case "IS":
+			if i, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+				encoded = append(encoded, i)
+			} else {
+				encoded = append(encoded, trimmed)
+			}
+		case "LT", "ST", "UT":
+			// Leading spaces are significant in text values
+			encoded = append(encoded, strings.TrimRight(v, " "))
+		default:
+			encoded = append(encoded, trimmed)
+		}
+	}
+	return encoded
+}
+
+// encodePersonName splits a PN value in its alphabetic, ideographic and
+// phonetic component groups.
+func encodePersonName(v string) map[string]string {
+	groups := strings.SplitN(strings.TrimSpace(v), "=", 3)
+	name := make(map[string]string, len(groups))
+	for i, key := range []string{"Alphabetic", "Ideographic", "Phonetic"} {
+		if i < len(groups) && groups[i] != "" {
+			name[key] = groups[i]
+		}
+	}
+	return name
+}
+
+// encodeInts converts integer values; attribute tags are written as
+// "GGGGEEEE" strings.
+func encodeInts(vr string, values []int) []any {
+	encoded := make([]any, 0, len(values))
+	if vr == "AT" {
+		for i := 0; i+1 < len(values); i += 2 {
+			encoded = append(encoded, fmt.Sprintf("%04X%04X", values[i], values[i+1]))
+		}
+		return encoded
+	}
+	for _, v := range values {
+		encoded = append(encoded, v)
+	}
+	return encoded
+}
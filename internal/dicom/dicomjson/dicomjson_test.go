@@ -0,0 +1,146 @@
+package dicomjson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func mustElement(t *testing.T, tg tag.Tag, data any) *dicom.Element {
+	t.Helper()
+	elem, err := dicom.NewElement(tg, data)
+	if err != nil {
+		t.Fatalf("NewElement(%v): %v", tg, err)
+	}
+	return elem
+}
+
+func TestEncode_ValueRepresentations(t *testing.T) {
+	item := []*dicom.Element{mustElement(t, tag.CodeValue, []string{"T-A0100"})}
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustElement(t, tag.PatientName, []string{"Doe^John=ドウ^ジョン"}),
+		mustElement(t, tag.PatientID, []string{"PID001 "}),
+		mustElement(t, tag.StudyDescription, []string{""}),
+		mustElement(t, tag.PixelSpacing, []string{"0.5", "0.500000"}),
+		mustElement(t, tag.SeriesNumber, []string{" 3"}),
+		mustElement(t, tag.Rows, []int{256}),
+		mustElement(t, tag.ImageType, []string{"ORIGINAL", "", "AXIAL"}),
+		mustElement(t, tag.AnatomicRegionSequence, [][]*dicom.Element{item}),
+	}}
+
+	obj, err := Encode(ds, Options{})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if _, ok := obj["00020010"]; ok {
+		t.Error("File Meta Information should be left out")
+	}
+	name := obj["00100010"].Value[0].(map[string]string)
+	if name["Alphabetic"] != "Doe^John" || name["Ideographic"] != "ドウ^ジョン" || name["Phonetic"] != "" {
+		t.Errorf("PatientName = %v", name)
+	}
+	if got := obj["00100020"].Value[0]; got != "PID001" {
+		t.Errorf("PatientID = %q, want padding removed", got)
+	}
+	if attr := obj["00081030"]; attr.VR != "LO" || attr.Value != nil {
+		t.Errorf("empty StudyDescription = %+v, want no Value", attr)
+	}
+	if got := obj["00280030"].Value; len(got) != 2 || got[0] != 0.5 || got[1] != 0.5 {
+		t.Errorf("PixelSpacing (DS) = %v, want numbers", got)
+	}
+	if got := obj["00200011"].Value[0]; got != int64(3) {
+		t.Errorf("SeriesNumber (IS) = %v (%T), want 3", got, got)
+	}
+	if got := obj["00280010"].Value[0]; got != 256 {
+		t.Errorf("Rows = %v", got)
+	}
+	if got := obj["00080008"].Value; got[1] != nil {
+		t.Errorf("empty ImageType value = %v, want null", got[1])
+	}
+	seq := obj["00082218"]
+	if seq.VR != "SQ" || seq.Value[0].(Object)["00080100"].Value[0] != "T-A0100" {
+		t.Errorf("AnatomicRegionSequence = %+v", seq)
+	}
+}
+
+func TestEncode_Binary(t *testing.T) {
+	nativeFrame := frame.NewNativeFrame[uint16](16, 1, 1, 1, 1)
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		{Tag: tag.Tag{Group: 0x0029, Element: 0x1010}, RawValueRepresentation: "OB",
+			Value: mustValue(t, []byte{1, 2, 3})},
+		mustElement(t, tag.PixelData, dicom.PixelDataInfo{Frames: []*frame.Frame{{NativeData: nativeFrame}}}),
+	}}
+
+	obj, err := Encode(ds, Options{})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if got := obj["00291010"].InlineBinary; got != "AQID" {
+		t.Errorf("InlineBinary = %q, want AQID", got)
+	}
+	if _, ok := obj["7FE00010"]; ok {
+		t.Error("PixelData should be left out without bulk data URIs")
+	}
+
+	uri := func(tg tag.Tag) string { return "bulk/" + Key(tg) }
+	obj, err = Encode(ds, Options{BulkDataURI: uri})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if got := obj["7FE00010"]; got.VR != "OW" || got.BulkDataURI != "bulk/7FE00010" {
+		t.Errorf("PixelData = %+v", got)
+	}
+	if got := obj["00291010"]; got.InlineBinary != "" || got.BulkDataURI != "bulk/00291010" {
+		t.Errorf("private OB = %+v", got)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.SliceThickness, []string{"1.25"}),
+	}}
+	data, err := Marshal(ds, Options{})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded map[string]map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded["00080060"]["vr"] != "CS" || decoded["00080060"]["Value"].([]any)[0] != "CT" {
+		t.Errorf("Modality = %v", decoded["00080060"])
+	}
+	if !strings.Contains(string(data), `"Value": [
+      1.25
+    ]`) {
+		t.Errorf("SliceThickness not encoded as a number:\n%s", data)
+	}
+}
+
+func TestEncode_InvalidNumber(t *testing.T) {
+	ds := dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.SliceThickness, []string{"thin"})}}
+	obj, err := Encode(ds, Options{})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if got := obj["00180050"].Value[0]; got != "thin" {
+		t.Errorf("invalid DS = %v, want the original string", got)
+	}
+}
+
+func mustValue(t *testing.T, data any) dicom.Value {
+	t.Helper()
+	v, err := dicom.NewValue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,184 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	randv2 "math/rand/v2"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// CT dose model: CTDIvol (mGy) per mAs at 120 kV, in the 16 cm head and
+// 32 cm body phantoms. Tube output scales with kVp^2.5.
+const (
+	ctdiPerMAsHead = 0.1
+	ctdiPerMAsBody = 0.068
+)
+
+// Localizer (topogram) acquisition: tube current, table speed and CTDIvol
+// per mA relative to a spiral rotation
+const (
+	localizerTubeCurrent = 35  // mA
+	localizerTableSpeed  = 100 // mm/s
+	localizerDoseFactor  = 0.06
+)
+
+// Pitch factors and rotation times of head and body protocols
+var (
+	ctHeadPitches       = []float64{0.516, 0.55, 0.8}
+	ctBodyPitches       = []float64{0.8, 0.984, 1, 1.2, 1.375}
+	ctHeadRotationTimes = []float64{0.75, 1}
+	ctBodyRotationTimes = []float64{0.28, 0.33, 0.5}
+)
+
+// ctSingleCollimation returns the detector row width of a scanner (mm).
+func ctSingleCollimation(manufacturer string) float64 {
+	switch manufacturer {
+	case "SIEMENS":
+		return 0.6
+	case "CANON":
+		return 0.5
+	default:
+		return 0.625
+	}
+}
+
+// ctDoseEvents returns the irradiation events of a CT study: a localizer,
+// then one spiral acquisition per image series that is neither a localizer
+// nor reconstructed from another one. Each spiral uses the kVp and tube current of its series
+// and covers its slices; the localizer covers the longest of them. end is
+// the end of the last exposure.
+func ctDoseEvents(opts GeneratorOptions, study studyRecord, rng *randv2.Rand) (events []sr.CTIrradiationEvent, start, end time.Time) {
+	var acquisitions []seriesRecord
+	for _, series := range study.series {
+		if len(series.instanceUIDs) > 0 && !series.reconstruction && !series.localizer {
+			acquisitions = append(acquisitions, series)
+		}
+	}
+	if len(acquisitions) == 0 {
+		if series, ok := study.largestSeries(); ok {
+			acquisitions = append(acquisitions, series)
+		}
+	}
+	if len(acquisitions) == 0 {
+		return nil, start, end
+	}
+
+	head := study.bodyPart == "HEAD" || study.bodyPart == "BRAIN"
+	ctdiPerMAs, pitches, rotationTime := ctdiPerMAsBody, ctBodyPitches, ctBodyRotationTimes[rng.IntN(len(ctBodyRotationTimes))]
+	if head {
+		ctdiPerMAs, pitches, rotationTime = ctdiPerMAsHead, ctHeadPitches, ctHeadRotationTimes[rng.IntN(len(ctHeadRotationTimes))]
+	}
+	single := ctSingleCollimation(study.scanner.Manufacturer)
+	total := single * float64(min(max(study.scanner.DetectorRows, 1), 64))
+	region := sr.CTTargetRegion(study.bodyPart)
+	eventUID := func(i int) string {
+		return opts.uid(fmt.Sprintf("%s_study_%d_irradiation_%d", opts.OutputDir, study.studyNum, i))
+	}
+
+	var longest float64
+	for _, series := range acquisitions {
+		longest = math.Max(longest, float64(len(series.instanceUIDs))*series.sliceSpacing)
+	}
+
+	// Localizer planned over the longest acquisition, a minute before it
+	kvp := acquisitions[0].kvp
+	tubeOutput := ctdiPerMAs * math.Pow(kvp/120, 2.5)
+	scoutLength := math.Ceil((longest+100)/10) * 10
+	scoutExposure := scoutLength / localizerTableSpeed
+	scoutCTDI := tubeOutput * localizerTubeCurrent * localizerDoseFactor
+	events = append(events, sr.CTIrradiationEvent{
+		UID:                eventUID(0),
+		Protocol:           "Topogram",
+		TargetRegion:       region,
+		Localizer:          true,
+		ExposureTime:       scoutExposure,
+		ScanningLength:     scoutLength,
+		SingleCollimation:  single,
+		TotalCollimation:   single,
+		KVP:                kvp,
+		TubeCurrent:        localizerTubeCurrent,
+		MaximumTubeCurrent: localizerTubeCurrent,
+		CTDIvol:            scoutCTDI,
+		DLP:                scoutCTDI * scoutLength / 10,
+		HeadPhantom:        head,
+	})
+	start = acquisitions[0].start.Add(-time.Minute)
+	end = start.Add(time.Duration(scoutExposure * float64(time.Second)))
+
+	for i, series := range acquisitions {
+		pitch := pitches[rng.IntN(len(pitches))]
+		mA := float64(series.tubeCurrent)
+		// Over-ranging: half a turn of table travel at each end
+		length := float64(len(series.instanceUIDs))*series.sliceSpacing + pitch*total
+		exposure := length / (pitch * total) * rotationTime
+		ctdi := ctdiPerMAs * math.Pow(series.kvp/120, 2.5) * mA * rotationTime / pitch
+
+		events = append(events, sr.CTIrradiationEvent{
+			UID:                eventUID(i + 1),
+			Protocol:           series.description,
+			TargetRegion:       region,
+			ExposureTime:       exposure,
+			ScanningLength:     length,
+			SingleCollimation:  single,
+			TotalCollimation:   total,
+			Pitch:              pitch,
+			KVP:                series.kvp,
+			TubeCurrent:        mA,
+			MaximumTubeCurrent: math.Round(mA * (1.1 + 0.4*rng.Float64())),
+			RotationTime:       rotationTime,
+			CTDIvol:            ctdi,
+			DLP:                ctdi * length / 10,
+			HeadPhantom:        head,
+		})
+		if stop := series.start.Add(time.Duration(exposure * float64(time.Second))); stop.After(end) {
+			end = stop
+		}
+	}
+	return events, start, end
+}
+
+// writeCTDoseSR writes an X-Ray Radiation Dose SR (TID 10011) reporting the
+// localizer and spiral acquisitions of a CT study.
+func writeCTDoseSR(opts GeneratorOptions, study studyRecord, seriesNumber int, rng *randv2.Rand) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+
+	events, start, end := ctDoseEvents(opts, study, rng)
+	if len(events) == 0 {
+		return GeneratedFile{}, fmt.Errorf("no CT acquisition to report")
+	}
+	device := sr.CTDevice{
+		UID:          opts.uid(fmt.Sprintf("ct_device_%s_%s", study.scanner.Manufacturer, study.scanner.Model)),
+		Manufacturer: study.scanner.Manufacturer,
+		Model:        study.scanner.Model,
+	}
+
+	const dateTime = "20060102150405"
+	doc := sr.Document{
+		Root:        sr.CTDoseReport(device, study.studyUID, start.Format(dateTime), end.Format(dateTime), events),
+		TemplateID:  "10011",
+		ContentDate: util.FormatDA(end),
+		ContentTime: end.Format("150405"),
+	}
+
+	elements := study.headerElements("SR", seriesUID, seriesNumber, "Dose Report")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{sr.XRayRadiationDoseSRStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+	)
+	elements = append(elements, doc.Elements()...)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "SR", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
@@ -0,0 +1,92 @@
+package dicom
+
+import (
+	"math"
+	randv2 "math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+)
+
+func TestCTDoseEvents(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	series := func(n, count int, kvp float64, mA int, reconstruction bool) seriesRecord {
+		return seriesRecord{
+			seriesNumber:   n,
+			description:    "Series",
+			start:          start.Add(time.Duration(n) * 5 * time.Minute),
+			instanceUIDs:   make([]string, count),
+			sliceSpacing:   2,
+			kvp:            kvp,
+			tubeCurrent:    mA,
+			reconstruction: reconstruction,
+		}
+	}
+	study := studyRecord{
+		bodyPart: "ABDOMEN",
+		scanner:  modalities.Scanner{Manufacturer: "SIEMENS", Model: "SOMATOM Force", DetectorRows: 192},
+		series: []seriesRecord{
+			series(1, 150, 120, 200, false),
+			series(2, 150, 120, 200, true),
+			series(3, 100, 100, 300, false),
+		},
+	}
+
+	events, from, to := ctDoseEvents(GeneratorOptions{OutputDir: "out"}, study, randv2.New(randv2.NewPCG(1, 2)))
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want a localizer and 2 spirals", len(events))
+	}
+	if !events[0].Localizer || events[1].Localizer || events[2].Localizer {
+		t.Error("only the first event should be a localizer")
+	}
+	if events[0].ScanningLength < 300 {
+		t.Errorf("localizer length %v does not cover the 300 mm acquisition", events[0].ScanningLength)
+	}
+	if events[2].KVP != 100 || events[2].TubeCurrent != 300 {
+		t.Errorf("third event uses %v kV %v mA, want the series' 100 kV 300 mA", events[2].KVP, events[2].TubeCurrent)
+	}
+
+	uids := make(map[string]bool)
+	for i, e := range events {
+		uids[e.UID] = true
+		if e.HeadPhantom {
+			t.Errorf("event %d: head phantom for an abdomen", i)
+		}
+		if math.Abs(e.DLP-e.CTDIvol*e.ScanningLength/10) > 1e-9 {
+			t.Errorf("event %d: DLP %v != CTDIvol %v x length %v cm", i, e.DLP, e.CTDIvol, e.ScanningLength/10)
+		}
+		if e.Localizer {
+			if e.CTDIvol <= 0 || e.CTDIvol > 1 {
+				t.Errorf("localizer CTDIvol = %v mGy", e.CTDIvol)
+			}
+			continue
+		}
+		if e.CTDIvol < 1 || e.CTDIvol > 40 {
+			t.Errorf("event %d: CTDIvol = %v mGy, outside body CT range", i, e.CTDIvol)
+		}
+		if e.ScanningLength <= 200 || e.MaximumTubeCurrent < e.TubeCurrent {
+			t.Errorf("event %d: length %v, max current %v", i, e.ScanningLength, e.MaximumTubeCurrent)
+		}
+	}
+	if len(uids) != len(events) {
+		t.Error("irradiation event UIDs are not unique")
+	}
+	if !from.Before(study.series[0].start) || !to.After(study.series[2].start) {
+		t.Errorf("irradiation span %v - %v does not cover the acquisitions", from, to)
+	}
+
+	// Head scans are measured in the head phantom
+	study.bodyPart = "HEAD"
+	events, _, _ = ctDoseEvents(GeneratorOptions{OutputDir: "out"}, study, randv2.New(randv2.NewPCG(1, 2)))
+	if !events[1].HeadPhantom {
+		t.Error("head scan not measured in the head phantom")
+	}
+
+	// A study holding only reconstructions still reports one acquisition
+	study.series = study.series[1:2]
+	events, _, _ = ctDoseEvents(GeneratorOptions{OutputDir: "out"}, study, randv2.New(randv2.NewPCG(1, 2)))
+	if len(events) != 2 {
+		t.Errorf("got %d events for a reconstruction-only study, want 2", len(events))
+	}
+}
@@ -31,6 +31,8 @@ func (a *Applicator) ApplyToPatientName(sex, original string) string {
 		return GenerateSpecialCharName(sex, a.rng)
 	case LongNames:
 		return GenerateLongPatientName(sex, a.rng)
+	case Escaping:
+		return GenerateEscapingName(a.rng)
 	default:
 		return original
 	}
@@ -44,6 +46,8 @@ func (a *Applicator) ApplyToPatientID(original string) string {
 		return GenerateRandomVariedPatientID(a.rng)
 	case LongNames:
 		return GenerateLongPatientID(a.rng)
+	case Escaping:
+		return GenerateEscapingPatientID(a.rng)
 	default:
 		return original
 	}
@@ -0,0 +1,45 @@
+package edgecases
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Escaping values contain DICOM delimiters where they have no meaning, to
+// test the sanitization of downstream formats (HL7, CSV, JSON, SQL). They
+// are written as-is: a backslash in a single-valued element reads back as
+// two values, a caret or '=' in a name adds components or groups.
+
+// escapingNames are person names with carets and '=' inside components
+var escapingNames = []string{
+	"SMITH=JONES^JOHN",
+	"O^BRIEN^PATRICK",
+	"DOE^JANE^^^^EXTRA",
+	"MARTIN\\DUPONT^CLAIRE",
+	"LEE^ANNA=KIM",
+	"NGUYEN^^^^=^^^^",
+}
+
+// escapingIDFormats are patient ID patterns with delimiters and control
+// characters
+var escapingIDFormats = []string{
+	"PID\\%06d",     // Backslash: value delimiter
+	"PID^%06d",      // Caret: PN component delimiter
+	"PID=%06d",      // Equals: PN group delimiter
+	"PID%06d|^~\\&", // HL7 encoding characters
+	"PID\t%06d",     // Tab: control character
+	"PID%06d\x1b",   // ESC: starts ISO 2022 escape sequences
+}
+
+// GenerateEscapingName generates a patient name with delimiters inside its
+// components.
+func GenerateEscapingName(rng *rand.Rand) string {
+	return escapingNames[rng.IntN(len(escapingNames))]
+}
+
+// GenerateEscapingPatientID generates a patient ID containing delimiters or
+// control characters.
+func GenerateEscapingPatientID(rng *rand.Rand) string {
+	format := escapingIDFormats[rng.IntN(len(escapingIDFormats))]
+	return fmt.Sprintf(format, rng.IntN(1000000))
+}
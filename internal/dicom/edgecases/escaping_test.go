@@ -0,0 +1,44 @@
+package edgecases
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestGenerateEscapingName(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	for i := 0; i < 20; i++ {
+		name := GenerateEscapingName(rng)
+		// More than the two components of a plain name, a group separator
+		// or a backslash
+		if strings.Count(name, "^") < 2 && !strings.ContainsAny(name, "=\\") {
+			t.Errorf("Name should contain misplaced delimiters: %q", name)
+		}
+	}
+}
+
+func TestGenerateEscapingPatientID(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	for i := 0; i < 20; i++ {
+		id := GenerateEscapingPatientID(rng)
+		if !strings.ContainsAny(id, "\\^=|\t\x1b") {
+			t.Errorf("ID should contain a delimiter or control character: %q", id)
+		}
+		if len(id) > DICOMLOMaxLength {
+			t.Errorf("ID exceeds %d characters: %q", DICOMLOMaxLength, id)
+		}
+	}
+}
+
+func TestApplicator_Escaping(t *testing.T) {
+	config := Config{Percentage: 100, Types: []EdgeCaseType{Escaping}}
+	app := NewApplicator(config, rand.New(rand.NewPCG(42, 42)))
+
+	if name := app.ApplyToPatientName("F", "SMITH^JANE"); name == "SMITH^JANE" {
+		t.Error("Edge case should modify the name")
+	}
+	if id := app.ApplyToPatientID("PID123456"); id == "PID123456" {
+		t.Error("Edge case should modify the ID")
+	}
+}
@@ -14,11 +14,12 @@ const (
 	MissingTags  EdgeCaseType = "missing-tags"
 	OldDates     EdgeCaseType = "old-dates"
 	VariedIDs    EdgeCaseType = "varied-ids"
+	Escaping     EdgeCaseType = "escaping"
 )
 
 // AllEdgeCaseTypes returns all valid edge case types
 func AllEdgeCaseTypes() []EdgeCaseType {
-	return []EdgeCaseType{SpecialChars, LongNames, MissingTags, OldDates, VariedIDs}
+	return []EdgeCaseType{SpecialChars, LongNames, MissingTags, OldDates, VariedIDs, Escaping}
 }
 
 // Config holds edge case generation settings
@@ -34,6 +34,16 @@ func TestParseTypes_All(t *testing.T) {
 	}
 }
 
+func TestParseTypes_Escaping(t *testing.T) {
+	types, err := ParseTypes("escaping")
+	if err != nil {
+		t.Fatalf("ParseTypes failed: %v", err)
+	}
+	if len(types) != 1 || types[0] != Escaping {
+		t.Errorf("Expected [Escaping], got %v", types)
+	}
+}
+
 func TestConfig_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,327 @@
+package dicom
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Transfer syntaxes of generated images
+const (
+	TransferSyntaxImplicitVRLittleEndian = "1.2.840.10008.1.2"
+	TransferSyntaxExplicitVRLittleEndian = "1.2.840.10008.1.2.1"
+	TransferSyntaxJPEGBaseline           = "1.2.840.10008.1.2.4.50"
+)
+
+// DefaultJPEGQuality is the JPEG quality of lossy encoded images.
+const DefaultJPEGQuality = 90
+
+// transferSyntaxNames are the names of the transfer syntaxes accepted by
+// ParseTransferSyntax, besides their UIDs.
+var transferSyntaxNames = map[string]string{
+	"implicit-le":   TransferSyntaxImplicitVRLittleEndian,
+	"explicit-le":   TransferSyntaxExplicitVRLittleEndian,
+	"jpeg-baseline": TransferSyntaxJPEGBaseline,
+}
+
+// ParseTransferSyntax parses the transfer syntax of generated images, by name
+// (implicit-le, explicit-le, jpeg-baseline, case-insensitive) or UID. "" is Explicit VR
+// Little Endian.
+func ParseTransferSyntax(s string) (string, error) {
+	v := strings.TrimSpace(s)
+	if v == "" {
+		return TransferSyntaxExplicitVRLittleEndian, nil
+	}
+	if uid, ok := transferSyntaxNames[strings.ToLower(v)]; ok {
+		return uid, nil
+	}
+	for _, uid := range transferSyntaxNames {
+		if v == uid {
+			return uid, nil
+		}
+	}
+	return "", fmt.Errorf("invalid transfer syntax %q (expected implicit-le, explicit-le, jpeg-baseline or their UID)", s)
+}
+
+// TransferSyntaxShare is the weight of a transfer syntax in a mix of
+// instances encoded in different transfer syntaxes.
+type TransferSyntaxShare struct {
+	UID    string
+	Weight float64
+}
+
+// ParseTransferSyntaxMix parses weighted transfer syntaxes, by name or UID
+// (e.g., "jpeg-baseline:30,explicit-le:70"). Weights are relative.
+func ParseTransferSyntaxMix(s string) ([]TransferSyntaxShare, error) {
+	var mix []TransferSyntaxShare
+	total := 0.0
+	for _, part := range strings.Split(s, ",") {
+		name, weightStr, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid transfer syntax share %q (expected SYNTAX:WEIGHT)", part)
+		}
+		uid, err := ParseTransferSyntax(name)
+		if err != nil || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid transfer syntax in share %q (expected implicit-le, explicit-le, jpeg-baseline or their UID)", part)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight in transfer syntax share %q (expected a number >= 0)", part)
+		}
+		for _, share := range mix {
+			if share.UID == uid {
+				return nil, fmt.Errorf("transfer syntax %s appears twice in %q", uid, s)
+			}
+		}
+		mix = append(mix, TransferSyntaxShare{UID: uid, Weight: weight})
+		total += weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("transfer syntax mix %q has no positive weight", s)
+	}
+	return mix, nil
+}
+
+// drawTransferSyntax draws the transfer syntax of an instance from a mix.
+func drawTransferSyntax(mix []TransferSyntaxShare, rng *rand.Rand) string {
+	total := 0.0
+	for _, share := range mix {
+		total += share.Weight
+	}
+	r := rng.Float64() * total
+	for _, share := range mix {
+		if r < share.Weight {
+			return share.UID
+		}
+		r -= share.Weight
+	}
+	return mix[len(mix)-1].UID
+}
+
+// usedTransferSyntaxes returns the transfer syntaxes instances of a run may
+// be encoded in.
+func usedTransferSyntaxes(opts GeneratorOptions) []string {
+	if len(opts.TransferSyntaxMix) == 0 {
+		return []string{opts.TransferSyntax}
+	}
+	var uids []string
+	for _, share := range opts.TransferSyntaxMix {
+		if share.Weight > 0 {
+			uids = append(uids, share.UID)
+		}
+	}
+	return uids
+}
+
+// ParseJPEGQualitySweep parses the JPEG qualities of a sweep (e.g.,
+// "50,75,90"), each between 1 and 100 and appearing once.
+func ParseJPEGQualitySweep(s string) ([]int, error) {
+	var qualities []int
+	for _, part := range strings.Split(s, ",") {
+		quality, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || quality < 1 || quality > 100 {
+			return nil, fmt.Errorf("invalid JPEG quality %q (expected 1-100)", part)
+		}
+		if slices.Contains(qualities, quality) {
+			return nil, fmt.Errorf("JPEG quality %d appears twice in %q", quality, s)
+		}
+		qualities = append(qualities, quality)
+	}
+	if len(qualities) < 2 {
+		return nil, fmt.Errorf("a quality sweep needs at least 2 qualities, got %q", s)
+	}
+	return qualities, nil
+}
+
+// sweepJPEGQualities encodes the series of tasks once per quality of the
+// sweep: the tasks take the first quality, and a copy of each, generating the
+// same pixels, every other quality. The copies of a series form a new series,
+// numbered after the series of its study, with " Q<quality>" appended to its
+// description like the series of the first quality.
+func sweepJPEGQualities(opts GeneratorOptions, tasks []imageTask, numImagesForSize int) []imageTask {
+	numSeries := make(map[string]int)
+	for _, task := range tasks {
+		numSeries[task.studyUID] = max(numSeries[task.studyUID], task.seriesNumber)
+	}
+	originals := slices.Clone(tasks)
+	for k, quality := range opts.JPEGQualitySweep {
+		for i, original := range originals {
+			description := ""
+			for _, elem := range original.metadata {
+				if values, ok := elem.Value.GetValue().([]string); ok && elem.Tag == tag.SeriesDescription && len(values) > 0 {
+					description = values[0]
+				}
+			}
+			task := original
+			task.jpegQuality = quality
+			task.metadata = setElement(slices.Clone(original.metadata),
+				mustNewElement(tag.SeriesDescription, []string{fmt.Sprintf("%s Q%d", description, quality)}))
+			if k == 0 {
+				tasks[i] = task
+				continue
+			}
+			task.seriesNumber += k * numSeries[task.studyUID]
+			task.seriesUID = opts.uid(fmt.Sprintf("%s_quality_%d", original.seriesUID, quality))
+			task.sopInstanceUID = opts.uid(fmt.Sprintf("%s_quality_%d", original.sopInstanceUID, quality))
+			task.metadata = setElement(task.metadata, mustNewElement(tag.SeriesInstanceUID, []string{task.seriesUID}))
+			task.metadata = setElement(task.metadata, mustNewElement(tag.SOPInstanceUID, []string{task.sopInstanceUID}))
+			task.metadata = setElement(task.metadata, mustNewElement(tag.SeriesNumber, []string{util.FormatIS(task.seriesNumber)}))
+			task.globalIndex = len(tasks) + 1
+			task.filePath = stagingPath(opts.filesDir(), task.globalIndex, numImagesForSize, opts.ShardFanout)
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// pixelPhotometric returns the photometric interpretation of the generated
+// frames of color images in a transfer syntax: JPEG Baseline frames are
+// generated in RGB, the encoder subsampling the chroma itself.
+func pixelPhotometric(opts GeneratorOptions, transferSyntax string) string {
+	photometric := colorPhotometric(opts)
+	if photometric == PhotometricYBRFull422 && transferSyntax == TransferSyntaxJPEGBaseline {
+		return PhotometricRGB
+	}
+	return photometric
+}
+
+// encodeJPEGBaseline encapsulates the native pixel data of elements as JPEG
+// Baseline (Process 1), one fragment per frame with a Basic Offset Table.
+// JPEG Baseline only holds 8-bit samples: 16-bit frames are scaled down to
+// the 0-255 range over the maximum value of the instance, and RescaleSlope is
+// scaled up by as much so that modality values and windows still apply.
+// Color frames (RGB by pixel) are encoded as YBR_FULL_422. The lossy
+// compression attributes are set.
+func encodeJPEGBaseline(elements []*dicom.Element, quality int) ([]*dicom.Element, error) {
+	idx := -1
+	for i, elem := range elements {
+		if elem.Tag == tag.PixelData {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("no pixel data to encode")
+	}
+	info := dicom.MustGetPixelDataInfo(elements[idx].Value)
+
+	// Largest stored value of 16-bit frames, mapped to 255
+	maxValue := 0
+	for _, f := range info.Frames {
+		if native, ok := f.NativeData.(*frame.NativeFrame[uint16]); ok {
+			for _, v := range native.RawData {
+				maxValue = max(maxValue, int(v))
+			}
+		}
+	}
+	maxValue = max(maxValue, 1)
+
+	var fragments []*frame.Frame
+	var offsets []uint32
+	var offset, native, encoded int
+	color := false
+	for i, f := range info.Frames {
+		var img image.Image
+		switch data := f.NativeData.(type) {
+		case *frame.NativeFrame[uint8]:
+			rect := image.Rect(0, 0, data.Cols(), data.Rows())
+			switch data.SamplesPerPixel() {
+			case 1:
+				img = &image.Gray{Pix: data.RawData, Stride: data.Cols(), Rect: rect}
+			case 3:
+				rgba := image.NewRGBA(rect)
+				for p := 0; p < data.Rows()*data.Cols(); p++ {
+					copy(rgba.Pix[4*p:], data.RawData[3*p:3*p+3])
+					rgba.Pix[4*p+3] = 255
+				}
+				img, color = rgba, true
+			default:
+				return nil, fmt.Errorf("frame %d: %d samples per pixel cannot be encoded as JPEG Baseline", i+1, data.SamplesPerPixel())
+			}
+			native += len(data.RawData)
+		case *frame.NativeFrame[uint16]:
+			gray := image.NewGray(image.Rect(0, 0, data.Cols(), data.Rows()))
+			for p, v := range data.RawData {
+				gray.Pix[p] = uint8((int(v)*255 + maxValue/2) / maxValue)
+			}
+			img = gray
+			native += 2 * len(data.RawData)
+		default:
+			return nil, fmt.Errorf("frame %d: unsupported native data %T", i+1, f.NativeData)
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("encode frame %d: %w", i+1, err)
+		}
+		// Fragments have an even length: pad after the EOI marker
+		if buf.Len()%2 != 0 {
+			buf.WriteByte(0)
+		}
+		offsets = append(offsets, uint32(offset))
+		offset += 8 + buf.Len() // Item tag and length, then the fragment
+		encoded += buf.Len()
+		fragments = append(fragments, &frame.Frame{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: buf.Bytes()}})
+	}
+
+	pixelData := mustNewElement(tag.PixelData, dicom.PixelDataInfo{IsEncapsulated: true, Offsets: offsets, Frames: fragments})
+	pixelData.RawValueRepresentation = "OB"
+	pixelData.ValueLength = tag.VLUndefinedLength
+
+	result := make([]*dicom.Element, 0, len(elements)+8)
+	result = append(result, elements[:idx]...)
+	result = append(result, elements[idx+1:]...)
+	result = setElement(result, mustNewElement(tag.TransferSyntaxUID, []string{TransferSyntaxJPEGBaseline}))
+	if _, ok := info.Frames[0].NativeData.(*frame.NativeFrame[uint16]); ok {
+		slope, intercept := 1.0, 0.0
+		for _, elem := range result {
+			if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 {
+				v, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+				switch {
+				case err != nil:
+				case elem.Tag == tag.RescaleSlope:
+					slope = v
+				case elem.Tag == tag.RescaleIntercept:
+					intercept = v
+				}
+			}
+		}
+		result = setElement(result, mustNewElement(tag.BitsAllocated, []int{8}))
+		result = setElement(result, mustNewElement(tag.BitsStored, []int{8}))
+		result = setElement(result, mustNewElement(tag.HighBit, []int{7}))
+		result = setElement(result, mustNewElement(tag.PixelRepresentation, []int{0}))
+		result = setElement(result, mustNewElement(tag.RescaleIntercept, []string{util.FormatDS(intercept)}))
+		result = setElement(result, mustNewElement(tag.RescaleSlope, []string{util.FormatDS(slope * float64(maxValue) / 255)}))
+	}
+	if color {
+		result = setElement(result, mustNewElement(tag.PhotometricInterpretation, []string{PhotometricYBRFull422}))
+		result = setElement(result, mustNewElement(tag.PlanarConfiguration, []int{0}))
+	}
+	ratio := float64(native) / float64(max(encoded, 1))
+	result = setElement(result, mustNewElement(tag.LossyImageCompression, []string{"01"}))
+	result = setElement(result, mustNewElement(tag.LossyImageCompressionRatio, []string{util.FormatDS(math.Round(ratio*100) / 100)}))
+	result = setElement(result, mustNewElement(tag.LossyImageCompressionMethod, []string{"ISO_10918_1"}))
+	return append(result, pixelData), nil
+}
+
+// setElement replaces the element of elements with the tag of elem, or
+// appends elem when there is none.
+func setElement(elements []*dicom.Element, elem *dicom.Element) []*dicom.Element {
+	for i, e := range elements {
+		if e.Tag == elem.Tag {
+			elements[i] = elem
+			return elements
+		}
+	}
+	return append(elements, elem)
+}
@@ -0,0 +1,263 @@
+package dicom
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParseTransferSyntax(t *testing.T) {
+	for input, want := range map[string]string{
+		"":                       TransferSyntaxExplicitVRLittleEndian,
+		"explicit-le":            TransferSyntaxExplicitVRLittleEndian,
+		"Implicit-LE":            TransferSyntaxImplicitVRLittleEndian,
+		"1.2.840.10008.1.2":      TransferSyntaxImplicitVRLittleEndian,
+		"JPEG-Baseline":          TransferSyntaxJPEGBaseline,
+		"1.2.840.10008.1.2.4.50": TransferSyntaxJPEGBaseline,
+	} {
+		got, err := ParseTransferSyntax(input)
+		if err != nil || got != want {
+			t.Errorf("ParseTransferSyntax(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"jpeg2000", "1.2.840.10008.1.2.4.90"} {
+		if _, err := ParseTransferSyntax(input); err == nil {
+			t.Errorf("ParseTransferSyntax(%q) should fail", input)
+		}
+	}
+}
+
+func TestParseTransferSyntaxMix(t *testing.T) {
+	mix, err := ParseTransferSyntaxMix("jpeg-baseline:30, 1.2.840.10008.1.2.1:70")
+	if err != nil {
+		t.Fatalf("ParseTransferSyntaxMix failed: %v", err)
+	}
+	want := []TransferSyntaxShare{{TransferSyntaxJPEGBaseline, 30}, {TransferSyntaxExplicitVRLittleEndian, 70}}
+	if len(mix) != 2 || mix[0] != want[0] || mix[1] != want[1] {
+		t.Errorf("got %v, want %v", mix, want)
+	}
+	for _, input := range []string{"", "jpeg-baseline", ":10", "jpeg2000:30,explicit-le:70", "explicit-le:-1", "explicit-le:0", "explicit-le:1,1.2.840.10008.1.2.1:1"} {
+		if _, err := ParseTransferSyntaxMix(input); err == nil {
+			t.Errorf("ParseTransferSyntaxMix(%q) should fail", input)
+		}
+	}
+}
+
+func TestDrawTransferSyntax(t *testing.T) {
+	mix := []TransferSyntaxShare{{TransferSyntaxJPEGBaseline, 30}, {TransferSyntaxImplicitVRLittleEndian, 0}, {TransferSyntaxExplicitVRLittleEndian, 70}}
+	rng := rand.New(rand.NewPCG(1, 2))
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[drawTransferSyntax(mix, rng)]++
+	}
+	if counts[TransferSyntaxImplicitVRLittleEndian] != 0 {
+		t.Error("a transfer syntax of weight 0 was drawn")
+	}
+	if got := counts[TransferSyntaxJPEGBaseline]; got < 2800 || got > 3200 {
+		t.Errorf("drew JPEG Baseline %d times in 10000, want about 3000", got)
+	}
+}
+
+func TestParseJPEGQualitySweep(t *testing.T) {
+	qualities, err := ParseJPEGQualitySweep("50, 75,90")
+	if err != nil || len(qualities) != 3 || qualities[0] != 50 || qualities[2] != 90 {
+		t.Errorf("ParseJPEGQualitySweep = %v, %v, want [50 75 90]", qualities, err)
+	}
+	for _, input := range []string{"", "50", "0,50", "50,101", "50,high", "50,75,50"} {
+		if _, err := ParseJPEGQualitySweep(input); err == nil {
+			t.Errorf("ParseJPEGQualitySweep(%q) should fail", input)
+		}
+	}
+}
+
+func TestJPEGQualitySweep(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:        4,
+		TotalSize:        "1MB",
+		Modality:         modalities.CT,
+		SeriesPerStudy:   util.SeriesRange{Min: 2, Max: 2},
+		JPEGQualitySweep: []int{50, 75, 90},
+		OutputDir:        t.TempDir(),
+		Seed:             42,
+		NumStudies:       1,
+		NumPatients:      1,
+		Quiet:            true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if len(files) != 12 {
+		t.Fatalf("got %d files, want 4 images at 3 qualities", len(files))
+	}
+
+	// Files of the same instance at each quality, keyed by the series swept
+	// (numbered after the 2 series of the study) and instance number
+	type instance struct {
+		series, ratio, description string
+		pixels                     []byte
+	}
+	sweeps := make(map[string][]instance)
+	seriesUIDs := make(map[string]bool)
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := elementString(ds.Elements, tag.TransferSyntaxUID); got != TransferSyntaxJPEGBaseline {
+			t.Errorf("%s: TransferSyntaxUID = %q, want %s", f.Path, got, TransferSyntaxJPEGBaseline)
+		}
+		if got := elementString(ds.Elements, tag.LossyImageCompression); got != "01" {
+			t.Errorf("%s: LossyImageCompression = %q, want 01", f.Path, got)
+		}
+		if got := elementString(ds.Elements, tag.LossyImageCompressionMethod); got != "ISO_10918_1" {
+			t.Errorf("%s: LossyImageCompressionMethod = %q, want ISO_10918_1", f.Path, got)
+		}
+		elem, err := ds.FindElementByTag(tag.PixelData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(dicom.MustGetPixelDataInfo(elem.Value).Frames[0].EncapsulatedData.Data))
+		if err != nil {
+			t.Fatalf("%s: jpeg.Decode failed: %v", f.Path, err)
+		}
+		seriesUIDs[f.SeriesUID] = true
+		key := strconv.Itoa((f.SeriesNumber-1)%2+1) + "/" + strconv.Itoa(f.InstanceNumber)
+		sweeps[key] = append(sweeps[key], instance{
+			series:      f.SeriesUID,
+			ratio:       elementString(ds.Elements, tag.LossyImageCompressionRatio),
+			description: elementString(ds.Elements, tag.SeriesDescription),
+			pixels:      img.(*image.Gray).Pix,
+		})
+	}
+	if len(seriesUIDs) != 6 {
+		t.Errorf("%d series, want 2 series at 3 qualities", len(seriesUIDs))
+	}
+	for key, sweep := range sweeps {
+		if len(sweep) != 3 {
+			t.Fatalf("%s: %d qualities, want 3", key, len(sweep))
+		}
+		ratios := make(map[string]bool)
+		for i, inst := range sweep {
+			ratios[inst.ratio] = true
+			if want := []string{" Q50", " Q75", " Q90"}[i]; len(inst.description) < 4 || inst.description[len(inst.description)-4:] != want {
+				t.Errorf("%s: SeriesDescription %q, want the %s suffix", key, inst.description, want)
+			}
+			if i > 0 && inst.series == sweep[0].series {
+				t.Errorf("%s: qualities share the series %s", key, inst.series)
+			}
+			// Same pixels, encoded at another quality
+			diff := 0.0
+			for p := range inst.pixels {
+				diff += math.Abs(float64(inst.pixels[p]) - float64(sweep[2].pixels[p]))
+			}
+			if mean := diff / float64(len(inst.pixels)); mean > 8 {
+				t.Errorf("%s: %q pixels differ from Q90 by %.1f on average", key, inst.description, mean)
+			}
+		}
+		if len(ratios) != 3 {
+			t.Errorf("%s: compression ratios %v, want one per quality", key, ratios)
+		}
+	}
+
+	for _, sweep := range [][]int{{90}, {0, 90}, {50, 101}} {
+		opts := GeneratorOptions{NumImages: 1, TotalSize: "1MB", JPEGQualitySweep: sweep, OutputDir: t.TempDir(), NumStudies: 1, Quiet: true}
+		if _, err := GenerateDICOMSeries(opts); err == nil {
+			t.Errorf("GenerateDICOMSeries should reject the quality sweep %v", sweep)
+		}
+	}
+	opts := GeneratorOptions{NumImages: 1, TotalSize: "1MB", TransferSyntax: TransferSyntaxExplicitVRLittleEndian,
+		JPEGQualitySweep: []int{50, 90}, OutputDir: t.TempDir(), NumStudies: 1, Quiet: true}
+	if _, err := GenerateDICOMSeries(opts); err == nil {
+		t.Error("GenerateDICOMSeries should reject a quality sweep in Explicit VR Little Endian")
+	}
+}
+
+// elementString returns the first string value of an element of elements.
+func elementString(elements []*dicom.Element, tg tag.Tag) string {
+	for _, elem := range elements {
+		if elem.Tag == tg {
+			if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 {
+				return values[0]
+			}
+		}
+	}
+	return ""
+}
+
+func TestEncodeJPEGBaseline(t *testing.T) {
+	const width, height = 16, 8
+	var frames []*frame.Frame
+	for f := 0; f < 3; f++ {
+		native := frame.NewNativeFrame[uint16](16, height, width, width*height, 1)
+		for i := range native.RawData {
+			native.RawData[i] = uint16(i%width) * 200 * uint16(f+1)
+		}
+		frames = append(frames, &frame.Frame{NativeData: native})
+	}
+	elements := []*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{TransferSyntaxExplicitVRLittleEndian}),
+		mustNewElement(tag.BitsAllocated, []int{16}),
+		mustNewElement(tag.RescaleIntercept, []string{"-1024"}),
+		mustNewElement(tag.RescaleSlope, []string{"2"}),
+		mustNewElement(tag.PixelData, dicom.PixelDataInfo{Frames: frames}),
+	}
+
+	encoded, err := encodeJPEGBaseline(elements, 95)
+	if err != nil {
+		t.Fatalf("encodeJPEGBaseline failed: %v", err)
+	}
+	if got := elementString(encoded, tag.TransferSyntaxUID); got != TransferSyntaxJPEGBaseline {
+		t.Errorf("TransferSyntaxUID = %q", got)
+	}
+	// Largest value 15*200*3 = 9000 mapped to 255
+	slope, err := strconv.ParseFloat(elementString(encoded, tag.RescaleSlope), 64)
+	if err != nil || math.Abs(slope-2*9000.0/255) > 1e-3 {
+		t.Errorf("RescaleSlope = %v (%v), want 2*9000/255", slope, err)
+	}
+	if got := elementString(encoded, tag.RescaleIntercept); got != "-1024" {
+		t.Errorf("RescaleIntercept = %q, want -1024", got)
+	}
+	if got := elementString(encoded, tag.LossyImageCompression); got != "01" {
+		t.Errorf("LossyImageCompression = %q, want 01", got)
+	}
+
+	last := encoded[len(encoded)-1]
+	if last.Tag != tag.PixelData || last.ValueLength != tag.VLUndefinedLength {
+		t.Fatalf("last element %v with length %d, want encapsulated pixel data", last.Tag, last.ValueLength)
+	}
+	info := dicom.MustGetPixelDataInfo(last.Value)
+	if !info.IsEncapsulated || len(info.Frames) != 3 || len(info.Offsets) != 3 {
+		t.Fatalf("%d frames and %d offsets, want 3 encapsulated frames", len(info.Frames), len(info.Offsets))
+	}
+	offset := uint32(0)
+	for i, f := range info.Frames {
+		data := f.EncapsulatedData.Data
+		if info.Offsets[i] != offset || len(data)%2 != 0 {
+			t.Errorf("frame %d: offset %d, want %d, length %d", i+1, info.Offsets[i], offset, len(data))
+		}
+		offset += 8 + uint32(len(data))
+
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("frame %d: jpeg.Decode failed: %v", i+1, err)
+		}
+		gray, ok := img.(*image.Gray)
+		if !ok || gray.Bounds().Dx() != width || gray.Bounds().Dy() != height {
+			t.Fatalf("frame %d: decoded %T %v, want %dx%d grayscale", i+1, img, img.Bounds(), width, height)
+		}
+		want := int(15 * 200 * (i + 1) * 255 / 9000)
+		if got := int(gray.Pix[width-1]); got < want-8 || got > want+8 {
+			t.Errorf("frame %d: brightest pixel %d, want about %d", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Metadata bytes of an image file, estimated to size the images of an
+// exact total size; the padding makes up for the difference
+const exactSizeFileOverhead = 4 * 1024
+
+// Data Set Trailing Padding (FFFC,FFFC), the last element a data set can
+// hold, which readers ignore (PS3.10 7.2)
+const trailingPaddingGroup, trailingPaddingElement = 0xFFFC, 0xFFFC
+
+// calculateExactDimensions returns the largest square images, in multiples
+// of 16 pixels, of which numFiles files of samplesPerFile samples of
+// bytesPerSample bytes (frames, color planes) stay within totalBytes with
+// their metadata. Unlike CalculateDimensions, which rounds to multiples of
+// 256, it leaves little for the padding to fill.
+func calculateExactDimensions(totalBytes int64, numFiles, samplesPerFile, bytesPerSample int) (width, height int, err error) {
+	if totalBytes <= 0 {
+		return 0, 0, fmt.Errorf("total bytes must be > 0")
+	}
+	if numFiles <= 0 {
+		return 0, 0, fmt.Errorf("number of images must be > 0")
+	}
+
+	availableBytes := totalBytes/int64(numFiles) - exactSizeFileOverhead
+	// DICOM max size check (2^32 - 10MB ≈ 4.28GB), per file
+	maxDICOMSize := int64(math.Pow(2, 32)) - 10*1024*1024
+	if availableBytes > maxDICOMSize {
+		availableBytes = maxDICOMSize
+	}
+	pixelsPerSample := availableBytes / int64(samplesPerFile*bytesPerSample)
+	if pixelsPerSample < 0 {
+		pixelsPerSample = 0
+	}
+
+	dimension := int(math.Sqrt(float64(pixelsPerSample)))
+	width = max(dimension/16*16, 128) // Minimum, as CalculateDimensions
+	return width, width, nil
+}
+
+// padToTotalSize appends a Data Set Trailing Padding element to the image
+// files so that the generated files total totalBytes: the missing bytes are
+// shared between the images, in order, each padding being even and at
+// least an element header. It returns the total of the files once padded,
+// which exceeds totalBytes when they already did.
+func padToTotalSize(files []GeneratedFile, tasks []imageTask, totalBytes int64) (int64, error) {
+	var total int64
+	for _, f := range files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return 0, err
+		}
+		total += info.Size()
+	}
+
+	remaining := totalBytes - total
+	for i, task := range tasks {
+		if remaining <= 0 {
+			break
+		}
+		share := remaining / int64(len(tasks)-i) &^ 1
+		padded, err := padImage(task, share)
+		if err != nil {
+			return 0, err
+		}
+		if padded {
+			remaining -= share
+			total += share
+		}
+	}
+	return total, nil
+}
+
+// padToFileSize appends a Data Set Trailing Padding element to each image
+// file smaller than fileBytes so that it is fileBytes long, or one byte
+// less. It returns the number of images already larger.
+func padToFileSize(tasks []imageTask, fileBytes int64) (int, error) {
+	oversized := 0
+	for _, task := range tasks {
+		info, err := os.Stat(task.filePath)
+		if err != nil {
+			return 0, err
+		}
+		if info.Size() > fileBytes {
+			oversized++
+			continue
+		}
+		if _, err := padImage(task, (fileBytes-info.Size())&^1); err != nil {
+			return 0, err
+		}
+	}
+	return oversized, nil
+}
+
+// padImage appends a Data Set Trailing Padding element of size bytes, its
+// header included, to the file of task. It reports false, leaving the file
+// alone, when size cannot hold the header.
+func padImage(task imageTask, size int64) (bool, error) {
+	headerLen := int64(12) // Tag, VR, reserved, 32-bit length
+	if task.transferSyntax == TransferSyntaxImplicitVRLittleEndian {
+		headerLen = 8 // Tag, 32-bit length
+	}
+	if size < headerLen {
+		return false, nil
+	}
+	if err := appendTrailingPadding(task.filePath, size-headerLen, headerLen == 8); err != nil {
+		return false, fmt.Errorf("pad %s: %w", task.filePath, err)
+	}
+	return true, nil
+}
+
+// appendTrailingPadding appends a Data Set Trailing Padding element of
+// length zero bytes to the DICOM file at path.
+func appendTrailingPadding(path string, length int64, implicitVR bool) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	header := binary.LittleEndian.AppendUint16(nil, trailingPaddingGroup)
+	header = binary.LittleEndian.AppendUint16(header, trailingPaddingElement)
+	if !implicitVR {
+		header = append(header, 'O', 'B', 0, 0)
+	}
+	header = binary.LittleEndian.AppendUint32(header, uint32(length))
+	if _, err := f.Write(header); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := io.CopyN(f, zeroReader{}, length); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// zeroReader reads zero bytes, endlessly.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,132 @@
+package dicom
+
+import (
+	"os"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestExactSize(t *testing.T) {
+	for _, ts := range []string{TransferSyntaxExplicitVRLittleEndian, TransferSyntaxImplicitVRLittleEndian} {
+		outputDir := t.TempDir()
+		files, err := GenerateDICOMSeries(GeneratorOptions{
+			NumImages:      7,
+			TotalSize:      "3MB",
+			ExactSize:      true,
+			OutputDir:      outputDir,
+			Seed:           42,
+			NumStudies:     1,
+			TransferSyntax: ts,
+			TextSR:         true,
+			Quiet:          true,
+		})
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+
+		var total int64
+		for _, f := range files {
+			info, err := os.Stat(f.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += info.Size()
+		}
+		// Paddings are even: an odd missing byte count misses one
+		if want := int64(3 * 1024 * 1024); total != want && total != want-1 {
+			t.Errorf("%s: instances total %d bytes, want %d", ts, total, want)
+		}
+
+		// The padded images still parse, with their pixel data
+		for _, f := range files[:7] {
+			ds, err := dicom.ParseFile(f.Path, nil)
+			if err != nil {
+				t.Fatalf("%s: padded %s does not parse: %v", ts, f.Path, err)
+			}
+			if _, err := ds.FindElementByTag(tag.PixelData); err != nil {
+				t.Errorf("%s: no PixelData in %s", ts, f.Path)
+			}
+			padding, err := ds.FindElementByTag(tag.Tag{Group: trailingPaddingGroup, Element: trailingPaddingElement})
+			if err != nil {
+				t.Errorf("%s: no trailing padding in %s", ts, f.Path)
+			} else if len(padding.Value.GetValue().([]byte))%2 != 0 {
+				t.Errorf("%s: odd trailing padding in %s", ts, f.Path)
+			}
+		}
+	}
+}
+
+func TestCalculateExactDimensions(t *testing.T) {
+	tests := []struct {
+		name           string
+		totalBytes     int64
+		numFiles       int
+		samples        int
+		bytesPerSample int
+		want           int
+	}{
+		{"100MB 100 images", 100 * 1024 * 1024, 100, 1, 2, 720},
+		{"8-bit samples", 100 * 1024 * 1024, 100, 1, 1, 1008},
+		{"RGB cine", 100 * 1024 * 1024, 10, 30, 1, 576},
+		{"too small", 1024 * 1024, 100, 1, 2, 128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, err := calculateExactDimensions(tt.totalBytes, tt.numFiles, tt.samples, tt.bytesPerSample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w != tt.want || h != tt.want {
+				t.Errorf("got %dx%d, want %dx%d", w, h, tt.want, tt.want)
+			}
+		})
+	}
+	if _, _, err := calculateExactDimensions(1024, 0, 1, 2); err == nil {
+		t.Error("no error without images")
+	}
+}
+
+func TestFileSize(t *testing.T) {
+	const fileBytes = 256 * 1024
+	for _, exact := range []bool{false, true} {
+		files, err := GenerateDICOMSeries(GeneratorOptions{
+			NumImages:  4,
+			FileSize:   "256KB",
+			ExactSize:  exact,
+			OutputDir:  t.TempDir(),
+			Seed:       42,
+			NumStudies: 2,
+			Quiet:      true,
+		})
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		for _, f := range files {
+			info, err := os.Stat(f.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := info.Size()
+			if exact && size != fileBytes && size != fileBytes-1 {
+				t.Errorf("exact: %s is %d bytes, want %d", f.Path, size, fileBytes)
+			}
+			if !exact && (size > fileBytes || size < fileBytes*9/10) {
+				t.Errorf("%s is %d bytes, want at most %d, within 10%%", f.Path, size, fileBytes)
+			}
+		}
+	}
+
+	_, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:  1,
+		TotalSize:  "1MB",
+		FileSize:   "256KB",
+		OutputDir:  t.TempDir(),
+		NumStudies: 1,
+		Quiet:      true,
+	})
+	if err == nil {
+		t.Error("no error with both a total size and a file size")
+	}
+}
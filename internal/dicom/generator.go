@@ -1,6 +1,7 @@
 package dicom
 
 import (
+	"bufio"
 	"fmt"
 	"hash/fnv"
 	"image"
@@ -10,13 +11,16 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sync"
 
 	"sort"
 
 	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
 	"github.com/mrsinham/dicomforge/internal/dicom/edgecases"
+	"github.com/mrsinham/dicomforge/internal/dicom/variability"
 	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	dfimage "github.com/mrsinham/dicomforge/internal/image"
 	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/frame"
@@ -27,32 +31,64 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
-// writeDatasetToFile writes a DICOM dataset to a file
+// writeDatasetToFile writes a DICOM dataset to a file, its text in its
+// SpecificCharacterSet (see util.EncodeText)
 func writeDatasetToFile(filename string, ds dicom.Dataset, opts ...dicom.WriteOption) error {
+	ds.Elements = util.EncodeText(ds.Elements)
+	return writeEncodedDatasetToFile(filename, ds, opts...)
+}
+
+// writeEncodedDatasetToFile writes a DICOM dataset to a file, its text as it
+// is.
+func writeEncodedDatasetToFile(filename string, ds dicom.Dataset, opts ...dicom.WriteOption) error {
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = f.Close() }()
 
-	return dicom.Write(f, ds, opts...)
+	// Buffered: the encoder writes element by element
+	w := fileWriters.Get().(*bufio.Writer)
+	w.Reset(f)
+	defer func() {
+		w.Reset(nil)
+		fileWriters.Put(w)
+	}()
+	if err := dicom.Write(w, ds, opts...); err != nil {
+		return err
+	}
+	return w.Flush()
 }
 
-// drawTextOnFrame16 draws large text overlay on a uint16 frame
-func drawTextOnFrame16(nativeFrame *frame.NativeFrame[uint16], width, height int, text string) {
-	// Create an RGBA image for drawing (easier to draw text)
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// Copy pixel data to RGBA image (convert uint16 to uint8 for display)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			val := nativeFrame.RawData[y*width+x]
-			// Scale from uint16 (0-65535) to uint8 (0-255) for drawing
-			gray := uint8(val >> 8)
-			img.Set(x, y, color.RGBA{gray, gray, gray, 255})
-		}
+// reduceFrameTo8Bits reduces a uint16 frame to 8-bit gray levels, on which
+// the overlays of noise images are drawn.
+func reduceFrameTo8Bits(nativeFrame *frame.NativeFrame[uint16]) {
+	for i, val := range nativeFrame.RawData {
+		nativeFrame.RawData[i] = (val >> 8) * 0x101
 	}
+}
+
+// drawTextOnFrame16Levels draws large text overlay on a uint16 frame,
+// keeping its values: the overlay goes from black to white, stored values.
+func drawTextOnFrame16Levels(nativeFrame *frame.NativeFrame[uint16], width, height int, text string, black, white float64) {
+	levels, region := textOverlay(width, height, text)
+	forEachOverlayPixel(levels, region, width, height, func(offset int, gray uint8) {
+		nativeFrame.RawData[offset] = overlayValue(gray, black, white)
+	})
+}
+
+// overlayValue returns the stored value of an overlay gray level, from
+// black to white.
+func overlayValue(gray uint8, black, white float64) uint16 {
+	return uint16(math.Round(black + float64(gray)/255*(white-black)))
+}
 
+// textOverlay renders text as it is overlaid on width x height frames:
+// scaled to 30% of the frame width (twice its base size at least), centered,
+// white with a thick black outline. It returns the 8-bit gray levels of the
+// overlaid region of the frame, row by row, -1 where the frame shows
+// through.
+func textOverlay(width, height int, text string) (levels []int16, region image.Rectangle) {
 	// Step 1: Render text at base size
 	face := basicfont.Face7x13
 	baseTextWidth := font.MeasureString(face, text).Ceil()
@@ -88,31 +124,35 @@ func drawTextOnFrame16(nativeFrame *frame.NativeFrame[uint16], width, height int
 	// Scale up the text using bilinear interpolation
 	draw.BiLinear.Scale(scaledTextImg, scaledTextImg.Bounds(), textImg, textImg.Bounds(), draw.Over, nil)
 
-	// Step 4: Position the text - centered horizontally and vertically
-	x := (width - scaledWidth) / 2
-	y := (height - scaledHeight) / 2
-
-	// Step 5: Draw thick black outline for visibility
+	// Step 4: Position the text - centered horizontally and vertically,
+	// in a region with room for the outline around it
 	outlineThickness := max(3, scaledHeight/10) // Proportional outline
+	x := (width-scaledWidth)/2 - outlineThickness
+	y := (height-scaledHeight)/2 - outlineThickness
+	region = image.Rect(x, y, x+scaledWidth+2*outlineThickness, y+scaledHeight+2*outlineThickness)
+	stride := region.Dx()
+	levels = make([]int16, stride*region.Dy())
+	for i := range levels {
+		levels[i] = -1
+	}
 
-	for dx := -outlineThickness; dx <= outlineThickness; dx++ {
-		for dy := -outlineThickness; dy <= outlineThickness; dy++ {
-			if dx*dx+dy*dy <= outlineThickness*outlineThickness { // Circular outline
-				// Draw outline by copying with black color
-				for sy := 0; sy < scaledHeight; sy++ {
-					for sx := 0; sx < scaledWidth; sx++ {
-						r, g, b, a := scaledTextImg.At(sx, sy).RGBA()
-						if a > 0 { // If there's text here
-							destX := x + sx + dx
-							destY := y + sy + dy
-							if destX >= 0 && destX < width && destY >= 0 && destY < height {
-								// Draw black outline
-								img.Set(destX, destY, color.RGBA{0, 0, 0, 255})
-							}
-						}
-						_ = r
-						_ = g
-						_ = b
+	// Step 5: Draw thick black outline for visibility. The outline of a
+	// text pixel surrounded by text is covered by those of its neighbors.
+	hasText := func(sx, sy int) bool {
+		return sx >= 0 && sx < scaledWidth && sy >= 0 && sy < scaledHeight &&
+			scaledTextImg.Pix[sy*scaledTextImg.Stride+4*sx+3] > 0
+	}
+	for sy := 0; sy < scaledHeight; sy++ {
+		for sx := 0; sx < scaledWidth; sx++ {
+			if !hasText(sx, sy) ||
+				hasText(sx-1, sy) && hasText(sx+1, sy) && hasText(sx, sy-1) && hasText(sx, sy+1) {
+				continue
+			}
+			for dy := -outlineThickness; dy <= outlineThickness; dy++ {
+				row := (sy + outlineThickness + dy) * stride
+				for dx := -outlineThickness; dx <= outlineThickness; dx++ {
+					if dx*dx+dy*dy <= outlineThickness*outlineThickness { // Circular outline
+						levels[row+sx+outlineThickness+dx] = 0
 					}
 				}
 			}
@@ -122,31 +162,36 @@ func drawTextOnFrame16(nativeFrame *frame.NativeFrame[uint16], width, height int
 	// Step 6: Draw main text (white) on top
 	for sy := 0; sy < scaledHeight; sy++ {
 		for sx := 0; sx < scaledWidth; sx++ {
-			r, g, b, a := scaledTextImg.At(sx, sy).RGBA()
+			r, g, b, a := rgba16(scaledTextImg.RGBAAt(sx, sy))
 			if a > 0 { // If there's text here
-				destX := x + sx
-				destY := y + sy
-				if destX >= 0 && destX < width && destY >= 0 && destY < height {
-					// Blend white text on top
-					brightness := (r + g + b) / 3 / 256 // 0-255 range
-					img.Set(destX, destY, color.RGBA{uint8(brightness), uint8(brightness), uint8(brightness), 255})
-				}
+				brightness := (r + g + b) / 3 / 256 // 0-255 range
+				levels[(sy+outlineThickness)*stride+sx+outlineThickness] = int16(brightness)
 			}
 		}
 	}
+	return levels, region
+}
 
-	// Convert back to uint16 and update the frame
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, _ := img.At(x, y).RGBA()
-			// Average RGB to grayscale, scale back to uint16
-			gray := (r + g + b) / 3
-			// Scale from 16-bit color space (0-65535) to uint16
-			nativeFrame.RawData[y*width+x] = uint16(gray)
+// forEachOverlayPixel calls set with the offset and gray level of each pixel
+// of a width x height frame covered by the levels of textOverlay.
+func forEachOverlayPixel(levels []int16, region image.Rectangle, width, height int, set func(offset int, gray uint8)) {
+	stride := region.Dx()
+	visible := region.Intersect(image.Rect(0, 0, width, height))
+	for y := visible.Min.Y; y < visible.Max.Y; y++ {
+		for x := visible.Min.X; x < visible.Max.X; x++ {
+			if gray := levels[(y-region.Min.Y)*stride+x-region.Min.X]; gray >= 0 {
+				set(y*width+x, uint8(gray))
+			}
 		}
 	}
 }
 
+// rgba16 returns the 16-bit components of c, as c.RGBA() does without
+// boxing c in a color.Color.
+func rgba16(c color.RGBA) (r, g, b, a uint32) {
+	return uint32(c.R) * 0x101, uint32(c.G) * 0x101, uint32(c.B) * 0x101, uint32(c.A) * 0x101
+}
+
 // max returns the maximum of two integers
 func max(a, b int) int {
 	if a > b {
@@ -157,112 +202,20 @@ func max(a, b int) int {
 
 // drawTextOnFrame8 draws large text overlay on a uint8 frame
 func drawTextOnFrame8(nativeFrame *frame.NativeFrame[uint8], width, height int, text string) {
-	// Create an RGBA image for drawing (easier to draw text)
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// Copy pixel data to RGBA image
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			val := nativeFrame.RawData[y*width+x]
-			img.Set(x, y, color.RGBA{val, val, val, 255})
-		}
-	}
-
-	// Step 1: Render text at base size
-	face := basicfont.Face7x13
-	baseTextWidth := font.MeasureString(face, text).Ceil()
-	baseTextHeight := 13
-
-	// Create a small image for the base text
-	textImg := image.NewRGBA(image.Rect(0, 0, baseTextWidth, baseTextHeight))
-
-	// Draw text on the small image (white on transparent)
-	drawer := &font.Drawer{
-		Dst:  textImg,
-		Src:  image.NewUniform(color.RGBA{255, 255, 255, 255}),
-		Face: face,
-		Dot:  fixed.Point26_6{Y: fixed.I(13)}, // Baseline at height
-	}
-	drawer.DrawString(text)
-
-	// Step 2: Calculate scale factor to make text 30% of image width
-	targetWidth := int(float64(width) * 0.3)
-	scaleFactor := float64(targetWidth) / float64(baseTextWidth)
-
-	// Ensure minimum scale for readability
-	if scaleFactor < 2.0 {
-		scaleFactor = 2.0
-	}
-
-	scaledWidth := int(float64(baseTextWidth) * scaleFactor)
-	scaledHeight := int(float64(baseTextHeight) * scaleFactor)
-
-	// Step 3: Create scaled text image
-	scaledTextImg := image.NewRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
-
-	// Scale up the text using bilinear interpolation
-	draw.BiLinear.Scale(scaledTextImg, scaledTextImg.Bounds(), textImg, textImg.Bounds(), draw.Over, nil)
-
-	// Step 4: Position the text - centered horizontally and vertically
-	posX := (width - scaledWidth) / 2
-	posY := (height - scaledHeight) / 2
-
-	// Step 5: Draw thick black outline for visibility
-	outlineThickness := max(3, scaledHeight/10) // Proportional outline
-
-	for dx := -outlineThickness; dx <= outlineThickness; dx++ {
-		for dy := -outlineThickness; dy <= outlineThickness; dy++ {
-			if dx*dx+dy*dy <= outlineThickness*outlineThickness { // Circular outline
-				// Draw outline by copying with black color
-				for sy := 0; sy < scaledHeight; sy++ {
-					for sx := 0; sx < scaledWidth; sx++ {
-						_, _, _, a := scaledTextImg.At(sx, sy).RGBA()
-						if a > 0 { // If there's text here
-							destX := posX + sx + dx
-							destY := posY + sy + dy
-							if destX >= 0 && destX < width && destY >= 0 && destY < height {
-								// Draw black outline
-								img.Set(destX, destY, color.RGBA{0, 0, 0, 255})
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Step 6: Draw main text (white) on top
-	for sy := 0; sy < scaledHeight; sy++ {
-		for sx := 0; sx < scaledWidth; sx++ {
-			r, g, b, a := scaledTextImg.At(sx, sy).RGBA()
-			if a > 0 { // If there's text here
-				destX := posX + sx
-				destY := posY + sy
-				if destX >= 0 && destX < width && destY >= 0 && destY < height {
-					// Blend white text on top
-					brightness := (r + g + b) / 3 / 256 // 0-255 range
-					img.Set(destX, destY, color.RGBA{uint8(brightness), uint8(brightness), uint8(brightness), 255})
-				}
-			}
-		}
-	}
-
-	// Convert back to uint8 and update the frame
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, _ := img.At(x, y).RGBA()
-			// Average RGB to grayscale
-			gray := (r + g + b) / 3 / 256 // Scale to 0-255
-			nativeFrame.RawData[y*width+x] = uint8(gray)
-		}
-	}
+	levels, region := textOverlay(width, height, text)
+	forEachOverlayPixel(levels, region, width, height, func(offset int, gray uint8) {
+		nativeFrame.RawData[offset] = gray
+	})
 }
 
 // GeneratorOptions contains all parameters needed to generate a DICOM series
 type GeneratorOptions struct {
-	NumImages   int
+	NumImages   int // Total number of images (0 = typical count per series for the modality)
 	TotalSize   string
+	FileSize    string // Size of each image file, instead of TotalSize (e.g., "512KB")
+	ExactSize   bool   // Pad the instances to TotalSize (within 1%), or each image to FileSize
 	OutputDir   string
+	FilesDir    string // Directory the files are written to ("" = OutputDir, which still seeds the UIDs)
 	Seed        int64
 	NumStudies  int
 	NumPatients int // Number of patients (studies are distributed among patients)
@@ -271,9 +224,30 @@ type GeneratorOptions struct {
 	// Modality selection
 	Modality modalities.Modality // Imaging modality (MR, CT, etc.)
 
+	// Render a Shepp-Logan head phantom, sliced along each series, instead
+	// of noise around a bright center
+	Phantom bool
+
+	// Test pattern of the images instead of noise (PatternGradient,
+	// PatternCheckerboard, PatternBars, PatternSMPTE), windowed over the
+	// whole pixel range. "" for noise.
+	Pattern string
+
+	// Burn the series description and number, study date, instance number
+	// and slice location into the corners of the images
+	Annotations bool
+
+	// Burn a QR code of the SOPInstanceUID into the bottom right corner of
+	// the images, to check images against their metadata after transcoding
+	QRCode bool
+
 	// Multi-series support
 	SeriesPerStudy    util.SeriesRange // Number of series per study (default: 1)
+	StudiesRange      util.StudyRange  // Random total number of studies (overrides NumStudies)
+	StudiesPerPatient util.StudyRange  // Random number of studies per patient (overrides NumStudies)
+	ImagesPerSeries   util.ImageRange  // Random number of images per series (unset = split NumImages evenly)
 	StudyDescriptions []string         // Custom study descriptions (one per study, or empty for auto-generate)
+	Localizer         bool             // Start MR/CT studies with a localizer series the others reference
 
 	// Categorization options
 	Institution    string        // Fixed institution name (empty = random)
@@ -282,6 +256,15 @@ type GeneratorOptions struct {
 	Priority       util.Priority // Exam priority
 	VariedMetadata bool          // Generate varied institutions/physicians per study
 
+	// Patient distributions (sex ratio, age pyramid, name locales);
+	// nil keeps the default uniform generation
+	Demographics *util.Demographics
+
+	// Age group of the patients (neonate, pediatric, adult, geriatric): their
+	// ages at their studies, weight and size, protocols and CT technique;
+	// "" keeps them unconstrained
+	AgeProfile util.AgeProfile
+
 	// Custom tag overrides
 	CustomTags util.ParsedTags // User-defined tag overrides
 
@@ -291,13 +274,106 @@ type GeneratorOptions struct {
 	// Corruption generation (vendor-specific private tags and malformed elements)
 	CorruptionConfig corruption.Config
 
+	// Per-study realism variations (description casing, optional tags, DS precision)
+	VariabilityConfig variability.Config
+
+	// Fraction (0-0.5) by which the dose and exposure values of each instance
+	// vary around those of its series (0 = same values for the whole series)
+	NumericJitter float64
+
+	// Write times with microseconds (HHMMSS.FFFFFF)
+	FractionalSeconds bool
+
+	// SpecificCharacterSet of the files: util.CharacterSetLatin1 or
+	// util.CharacterSetUTF8 ("" declares ISO_IR 192 in files with non-ASCII
+	// text only); files with text ISO_IR 100 lacks are written in UTF-8
+	CharacterSet string
+
+	// Root of the generated UIDs, an organization's own (util.DefaultUIDRoot
+	// when empty)
+	UIDRoot string
+
+	// Frames per US instance: cine loops of temporally correlated frames
+	// (0 or 1: single frame images), with a FrameTimeVector of jittered
+	// intervals when FrameTimeVector is set
+	CineFrames      int
+	FrameTimeVector bool
+
+	// JPEG qualities at which every series is encoded as JPEG Baseline, once
+	// per quality with the same pixels, each in its own series
+	JPEGQualitySweep []int
+
+	// Color US images (RGB, YBR_FULL_422 or PALETTE COLOR, "" for monochrome)
+	// with a color Doppler box, pixel by pixel or plane by plane (ColorByPlane,
+	// RGB only)
+	USColor      string
+	ColorByPlane bool
+
+	// Photometric interpretation forced on monochrome images: MONOCHROME1,
+	// MONOCHROME2 or MIXED (alternating from series to series); "" keeps the
+	// modality's
+	Photometric string
+
+	// Transfer syntax UID of the images ("" for Explicit VR Little Endian):
+	// Implicit VR Little Endian, Explicit VR Little Endian or JPEG Baseline,
+	// encoded at JPEGQuality (0 for DefaultJPEGQuality)
+	TransferSyntax string
+	JPEGQuality    int
+
+	// Weighted transfer syntaxes drawn per instance instead of
+	// TransferSyntax, mixing them within series and studies
+	TransferSyntaxMix []TransferSyntaxShare
+
+	// Write a group length element (gggg,0000) before each group, as old
+	// toolkits did
+	GroupLengths bool
+
+	// Private tags added to every instance, images and derived objects, with
+	// the private creators reserving their blocks
+	PrivateTags []util.PrivateTag
+
+	// Write Siemens CSA Image and Series headers (0029,1010 and 0029,1020)
+	// on the MR images of Siemens scanners
+	SiemensCSA bool
+
+	// Vendor private tag flavors (VendorTagsGE, VendorTagsPhilips) written on
+	// the images of the scanners of their vendor. With them or SiemensCSA,
+	// the studies are acquired on the scanners of these vendors only
+	VendorTags []string
+
+	// Write the instances de-identified with the Basic Application Level
+	// Confidentiality Profile, pseudonyms and dummy dates in place of the
+	// identifiers, as research pipelines receive them
+	Deidentified bool
+
+	// Add RIS/EMR linkage IDs per study: AdmissionID (shared by the studies
+	// of a patient on the same day), placer and filler order numbers
+	LinkageIDs bool
+
+	// Derived objects
+	USMeasurementSR bool     // Add a measurement SR per US study referencing its images
+	AIResults       []string // AI result objects tied to inserted lesions (AIResultSR, AIResultSC)
+	TextSR          bool     // Add a Basic Text SR report per study referencing its images
+	RTDose          bool     // Add an RT Dose grid per CT study, aligned to its largest series
+	RTPlan          bool     // Add an RT Plan and its RT Structure Set per CT study
+	SegSegments     int      // Add a binary SEG with this many segments per CT/MR study (0: none)
+	GSPS            bool     // Add a grayscale presentation state per study on its largest series
+	DoseSR          bool     // Add an X-Ray Radiation Dose SR per CT study
+
 	// Output control
+	ShardFanout      int                     // Largest number of staged images per directory (0 for DefaultShardFanout)
 	Quiet            bool                    // Suppress progress output (for TUI integration)
 	ProgressCallback func(current, total int) // Optional callback for progress updates
 
 	// Pre-defined patient data (from config file)
 	// When set, overrides random generation for patient/study/series metadata
 	PredefinedPatients []PredefinedPatient
+
+	// When set, the identities of PredefinedPatients are replaced with
+	// generated ones, and the original->generated map is written to this
+	// path, encrypted with PseudonymKey
+	PseudonymMapPath string
+	PseudonymKey     []byte
 }
 
 // PredefinedPatient holds pre-configured patient data from config file.
@@ -319,6 +395,7 @@ type PredefinedStudy struct {
 	BodyPart           string
 	Priority           string
 	ReferringPhysician string
+	CustomTags         util.ParsedTags // Overrides of this study, over GeneratorOptions.CustomTags
 	Series             []PredefinedSeries
 }
 
@@ -327,7 +404,8 @@ type PredefinedSeries struct {
 	Description string
 	Protocol    string
 	Orientation string
-	ImageCount  int // 0 = auto-distribute
+	ImageCount  int             // 0 = auto-distribute
+	CustomTags  util.ParsedTags // Overrides of this series, over those of its study
 }
 
 // getTagValue returns the custom tag value if set, otherwise returns the generated value.
@@ -356,11 +434,25 @@ type imageTask struct {
 	height           int
 	filePath         string
 	textOverlay      string
+	annotations      dfimage.Annotations // Text and QR code burnt into the corners (zero: none)
 	pixelSeed          uint64 // Deterministic seed for this image's pixel generation
 	metadata           []*dicom.Element
 	pixelConfig        modalities.PixelConfig // Modality-specific pixel configuration
+	lesions            []lesion               // Synthetic lesions to insert (AI result simulation)
+	phantom            *phantomSlice          // Head phantom slice to render (nil: noise)
+	pattern            string                 // Test pattern to render ("": noise)
+	hounsfield         bool                   // CT noise in Hounsfield ranges instead of 12-bit noise
+	contrast           *mrContrast            // Look of the MR sequence of the noise images (nil: other modalities)
+	frames             int                    // Frames of a cine loop (0 or 1: single frame)
+	frameTime          float64                // ms between the frames of a cine loop
+	photometric        string                 // Color photometric interpretation of 8-bit images ("" for monochrome)
+	byPlane            bool                   // Color samples plane by plane
+	transferSyntax     string                 // Transfer syntax UID of the file
+	jpegQuality        int                    // Quality of JPEG Baseline images
 	writeOpts          []dicom.WriteOption    // Write options (e.g., SkipVRVerification for corruption)
 	hasMalformedLengths bool                  // Whether to apply malformed length post-processing
+	hasOddLengths       bool                  // Whether to remove padding bytes in post-processing
+	groupLengths        bool                  // Whether to write group length elements
 	// Result info
 	studyUID       string
 	seriesUID      string
@@ -369,6 +461,20 @@ type imageTask struct {
 	studyID        string
 }
 
+// filesDir returns the directory the files are written to.
+func (opts GeneratorOptions) filesDir() string {
+	if opts.FilesDir != "" {
+		return opts.FilesDir
+	}
+	return opts.OutputDir
+}
+
+// uid returns the deterministic UID of seed under the UID root of the
+// options.
+func (opts GeneratorOptions) uid(seed string) string {
+	return util.GenerateDeterministicUIDWithRoot(opts.UIDRoot, seed)
+}
+
 // GeneratedFile contains information about a generated DICOM file
 type GeneratedFile struct {
 	Path             string
@@ -385,7 +491,6 @@ type GeneratedFile struct {
 // generateImageFromTask generates a single DICOM image from a pre-computed task
 func generateImageFromTask(task imageTask) error {
 	width, height := task.width, task.height
-	pixelsPerFrame := width * height
 	cfg := task.pixelConfig
 
 	// Create deterministic RNG for this specific image
@@ -397,12 +502,21 @@ func generateImageFromTask(task imageTask) error {
 	centerX, centerY := float64(width)/2, float64(height)/2
 	maxDist := math.Sqrt(centerX*centerX + centerY*centerY)
 
+	annotations, err := dfimage.RenderAnnotations(width, height, task.annotations)
+	if err != nil {
+		return fmt.Errorf("render annotations: %w", err)
+	}
+
 	// Generate pixel data based on BitsAllocated
 	var pixelDataInfo dicom.PixelDataInfo
 
-	if cfg.BitsAllocated == 8 {
+	if cfg.BitsAllocated == 8 && task.frames > 1 {
+		// 8-bit cine loop (Ultrasound)
+		pixelDataInfo = dicom.PixelDataInfo{Frames: cineFrames8(task, annotations, rng)}
+	} else if cfg.BitsAllocated == 8 {
 		// 8-bit pixel data (e.g., Ultrasound)
-		nativeFrame := frame.NewNativeFrame[uint8](8, height, width, pixelsPerFrame, 1)
+		nativeFrame, put := pooledNativeFrame[uint8](&samples8, 8, height, width)
+		defer put()
 
 		for y := 0; y < height; y++ {
 			for x := 0; x < width; x++ {
@@ -419,6 +533,11 @@ func generateImageFromTask(task imageTask) error {
 
 				totalNoise := largeNoise + mediumNoise + fineNoise
 				intensity := baseIntensity + totalNoise
+				if task.phantom != nil {
+					intensity = task.phantom.value(x, y, width, height, rng)
+				} else if task.pattern != "" {
+					intensity = patternValue(task.pattern, cfg, x, y, width, height)
+				}
 
 				minVal := float64(0)
 				maxValInt := (1 << cfg.BitsStored) - 1
@@ -428,7 +547,9 @@ func generateImageFromTask(task imageTask) error {
 			}
 		}
 
+		drawLesions8(nativeFrame, width, height, task.instanceInSeries, task.lesions, valueRange)
 		drawTextOnFrame8(nativeFrame, width, height, task.textOverlay)
+		drawAnnotations8(nativeFrame, width, height, annotations)
 
 		pixelDataInfo = dicom.PixelDataInfo{
 			Frames: []*frame.Frame{
@@ -440,7 +561,8 @@ func generateImageFromTask(task imageTask) error {
 		}
 	} else {
 		// 16-bit pixel data (MR, CT, CR, DX, MG)
-		nativeFrame := frame.NewNativeFrame[uint16](16, height, width, pixelsPerFrame, 1)
+		nativeFrame, put := pooledNativeFrame[uint16](&samples16, 16, height, width)
+		defer put()
 
 		for y := 0; y < height; y++ {
 			for x := 0; x < width; x++ {
@@ -457,6 +579,15 @@ func generateImageFromTask(task imageTask) error {
 
 				totalNoise := largeNoise + mediumNoise + fineNoise
 				intensity := baseIntensity + totalNoise
+				if task.phantom != nil {
+					intensity = task.phantom.value(x, y, width, height, rng)
+				} else if task.pattern != "" {
+					intensity = patternValue(task.pattern, cfg, x, y, width, height)
+				} else if task.hounsfield {
+					intensity = ctValue(x, y, width, height, baseValue, rng)
+				} else if task.contrast != nil {
+					intensity = task.contrast.value(cfg, normalizedDist, totalNoise)
+				}
 
 				minVal := float64(0)
 				maxValInt := (1 << cfg.BitsStored) - 1
@@ -466,7 +597,23 @@ func generateImageFromTask(task imageTask) error {
 			}
 		}
 
-		drawTextOnFrame16(nativeFrame, width, height, task.textOverlay)
+		drawLesions16(nativeFrame, width, height, task.instanceInSeries, task.lesions, valueRange, float64(int(1)<<cfg.BitsStored-1))
+
+		// Overlays keep the values of phantoms, patterns and CT images, for
+		// their windows; noise images are reduced to 8-bit gray levels
+		black, white := 0.0, float64(0xFFFF)
+		switch {
+		case task.phantom != nil:
+			black, white = task.phantom.overlayLevels()
+		case task.pattern != "":
+			white = valueRange
+		case task.hounsfield:
+			black, white = huAir+baseValue, huMax+baseValue
+		default:
+			reduceFrameTo8Bits(nativeFrame)
+		}
+		drawTextOnFrame16Levels(nativeFrame, width, height, task.textOverlay, black, white)
+		drawAnnotations16(nativeFrame, width, height, annotations, black, white)
 
 		pixelDataInfo = dicom.PixelDataInfo{
 			Frames: []*frame.Frame{
@@ -478,13 +625,40 @@ func generateImageFromTask(task imageTask) error {
 		}
 	}
 
+	// Color Doppler over the B-mode frames, flow following the cardiac cycle
+	if task.photometric != "" && cfg.BitsAllocated == 8 {
+		beat := 60000.0 / cineHeartRate
+		for i, f := range pixelDataInfo.Frames {
+			phase := 2*math.Pi*float64(i)*task.frameTime/beat + float64(task.instanceInSeries)
+			f.NativeData = colorDopplerFrame(f.NativeData.(*frame.NativeFrame[uint8]), width, height, phase, task.photometric, task.byPlane)
+		}
+	}
+
 	// Build complete metadata with pixel data
 	elements := make([]*dicom.Element, len(task.metadata)+1)
 	copy(elements, task.metadata)
 	elements[len(task.metadata)] = mustNewElement(tag.PixelData, pixelDataInfo)
+	if task.transferSyntax == TransferSyntaxJPEGBaseline {
+		var err error
+		if elements, err = encodeJPEGBaseline(elements, task.jpegQuality); err != nil {
+			return err
+		}
+	} else {
+		packed := pooledSlice[byte](&pixelBytes, 0)
+		defer pixelBytes.Put(packed)
+		var packedInfo dicom.PixelDataInfo
+		packedInfo, *packed = packPixelData(pixelDataInfo, *packed)
+		elements[len(task.metadata)] = mustNewElement(tag.PixelData, packedInfo)
+	}
+	if task.groupLengths {
+		var err error
+		if elements, err = withGroupLengths(elements); err != nil {
+			return err
+		}
+	}
 
 	// Write DICOM file
-	if err := writeDatasetToFile(task.filePath, dicom.Dataset{Elements: elements}, task.writeOpts...); err != nil {
+	if err := writeEncodedDatasetToFile(task.filePath, dicom.Dataset{Elements: elements}, task.writeOpts...); err != nil {
 		return err
 	}
 
@@ -494,6 +668,11 @@ func generateImageFromTask(task imageTask) error {
 			return fmt.Errorf("patch malformed lengths: %w", err)
 		}
 	}
+	if task.hasOddLengths {
+		if err := corruption.PatchOddLengths(task.filePath); err != nil {
+			return fmt.Errorf("patch odd lengths: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -549,20 +728,160 @@ func CalculateDimensions(totalBytes int64, numImages int) (width, height int, er
 	return width, height, nil
 }
 
+// expectedImageCount returns the average number of images of a run whose
+// series get a random number of images within imagesPerSeries.
+func expectedImageCount(numStudies int, seriesPerStudy util.SeriesRange, imagesPerSeries util.ImageRange) int {
+	if seriesPerStudy.Max == 0 {
+		seriesPerStudy = util.SeriesRange{Min: 1, Max: 1}
+	}
+	meanSeries := float64(seriesPerStudy.Min+seriesPerStudy.Max) / 2
+	return max(int(math.Round(float64(numStudies)*meanSeries*imagesPerSeries.Mean())), 1)
+}
+
 // GenerateDICOMSeries generates a complete DICOM series with multiple studies
 func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 	// Validate options
-	if opts.NumImages <= 0 {
-		return nil, fmt.Errorf("number of images must be > 0, got %d", opts.NumImages)
+	if opts.NumImages < 0 {
+		return nil, fmt.Errorf("number of images must be >= 0, got %d", opts.NumImages)
+	}
+	if opts.NumImages > 0 && opts.ImagesPerSeries.IsSet() {
+		return nil, fmt.Errorf("number of images and images per series cannot be combined")
+	}
+	if len(opts.JPEGQualitySweep) > 0 {
+		if opts.TransferSyntax == "" {
+			opts.TransferSyntax = TransferSyntaxJPEGBaseline
+		}
+		if opts.TransferSyntax != TransferSyntaxJPEGBaseline || len(opts.TransferSyntaxMix) > 0 {
+			return nil, fmt.Errorf("a JPEG quality sweep requires the JPEG Baseline transfer syntax")
+		}
+		if opts.ExactSize {
+			return nil, fmt.Errorf("a JPEG quality sweep cannot be combined with exact sizes")
+		}
+	}
+	if len(opts.JPEGQualitySweep) == 1 {
+		return nil, fmt.Errorf("a JPEG quality sweep needs at least 2 qualities")
+	}
+	for _, quality := range opts.JPEGQualitySweep {
+		if quality < 1 || quality > 100 {
+			return nil, fmt.Errorf("JPEG quality %d out of range (1-100)", quality)
+		}
+	}
+	if opts.NumericJitter < 0 || opts.NumericJitter > 0.5 {
+		return nil, fmt.Errorf("numeric jitter must be 0-0.5, got %g", opts.NumericJitter)
+	}
+	if opts.UIDRoot != "" {
+		if err := util.ValidateUIDRoot(opts.UIDRoot); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range opts.PrivateTags {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("private tag %s: %w", p, err)
+		}
+	}
+	if opts.SiemensCSA {
+		if modalities.GetGenerator(opts.Modality).Modality() != modalities.MR {
+			return nil, fmt.Errorf("Siemens CSA headers require the MR modality")
+		}
+		if opts.CorruptionConfig.HasType(corruption.SiemensCSA) {
+			return nil, fmt.Errorf("Siemens CSA headers and the siemens-csa corruption cannot be combined")
+		}
+	}
+	if err := validateVendorTags(opts); err != nil {
+		return nil, err
+	}
+	if opts.Deidentified {
+		if opts.SiemensCSA || len(opts.VendorTags) > 0 {
+			return nil, fmt.Errorf("de-identified instances have no vendor private tags")
+		}
+		if opts.Annotations {
+			return nil, fmt.Errorf("annotations burn the study date into de-identified images")
+		}
+	}
+	if opts.CorruptionConfig.HasType(corruption.Truncated) && opts.ExactSize {
+		return nil, fmt.Errorf("the truncated corruption cannot be combined with exact sizes")
+	}
+	if opts.TransferSyntax == "" {
+		opts.TransferSyntax = TransferSyntaxExplicitVRLittleEndian
+	}
+	if opts.JPEGQuality == 0 {
+		opts.JPEGQuality = DefaultJPEGQuality
+	}
+	if opts.ShardFanout < 0 {
+		return nil, fmt.Errorf("shard fan-out must be >= 0, got %d", opts.ShardFanout)
+	}
+	if opts.ShardFanout == 0 {
+		opts.ShardFanout = DefaultShardFanout
+	}
+	if _, ok := patterns[opts.Pattern]; opts.Pattern != "" && !ok {
+		return nil, fmt.Errorf("invalid pattern %q", opts.Pattern)
+	}
+	if opts.Pattern != "" && opts.Phantom {
+		return nil, fmt.Errorf("pattern and phantom cannot be combined")
+	}
+	if len(opts.TransferSyntaxMix) > 0 && len(usedTransferSyntaxes(opts)) == 0 {
+		return nil, fmt.Errorf("transfer syntax mix has no positive weight")
+	}
+	for _, transferSyntax := range usedTransferSyntaxes(opts) {
+		if transferSyntax == TransferSyntaxJPEGBaseline && (colorPhotometric(opts) == PhotometricPaletteColor || opts.ColorByPlane) {
+			return nil, fmt.Errorf("JPEG Baseline cannot encode PALETTE COLOR or color by plane images")
+		}
+		// The malformed length patches rewrite explicit VR headers
+		if transferSyntax == TransferSyntaxImplicitVRLittleEndian && opts.CorruptionConfig.HasType(corruption.MalformedLengths) {
+			return nil, fmt.Errorf("malformed-lengths corruption requires an explicit VR transfer syntax")
+		}
+	}
+
+	// Set seed for reproducibility (derived from the output directory if not set)
+	seed := opts.Seed
+	if seed == 0 {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(opts.OutputDir)) // hash.Write never returns an error
+		seed = int64(h.Sum64())
+	}
+
+	// Pseudonymize predefined identities with a dedicated RNG, so that the
+	// rest of the generation is unchanged
+	if opts.PseudonymMapPath != "" {
+		if len(opts.PredefinedPatients) == 0 {
+			return nil, fmt.Errorf("a pseudonym map requires predefined patients")
+		}
+		pseudonymRNG := randv2.New(randv2.NewPCG(uint64(seed), 0x95e0))
+		var entries []PseudonymEntry
+		opts.PredefinedPatients, entries = pseudonymizePatients(opts.PredefinedPatients, pseudonymRNG)
+		if err := WritePseudonymMap(opts.PseudonymMapPath, entries, opts.PseudonymKey); err != nil {
+			return nil, err
+		}
+	}
+
+	// Age profiles draw the birth dates of generated patients from their
+	// pyramid, with the other distributions of the demographics
+	if opts.AgeProfile != "" {
+		if _, err := util.ParseAgeProfile(string(opts.AgeProfile)); err != nil {
+			return nil, err
+		}
+		demographics := util.DefaultDemographics()
+		if opts.Demographics != nil {
+			demographics = *opts.Demographics
+		}
+		demographics.AgePyramid = opts.AgeProfile.AgePyramid()
+		opts.Demographics = &demographics
 	}
 
 	// When using predefined patients, infer counts from the structure
+	var patientStudyCounts []int
 	if len(opts.PredefinedPatients) > 0 {
 		opts.NumPatients = len(opts.PredefinedPatients)
 		opts.NumStudies = 0
 		for _, p := range opts.PredefinedPatients {
 			opts.NumStudies += len(p.Studies)
 		}
+	} else {
+		var err error
+		opts.NumStudies, patientStudyCounts, err = planStudyCounts(opts, seed)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if opts.NumStudies <= 0 {
@@ -576,14 +895,60 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 		return nil, fmt.Errorf("number of patients (%d) cannot exceed number of studies (%d)", opts.NumPatients, opts.NumStudies)
 	}
 
-	// Parse total size
-	totalBytes, err := util.ParseSize(opts.TotalSize)
-	if err != nil {
+	// Parse total size, or the size of each image file
+	var totalBytes, fileBytes int64
+	var err error
+	if opts.FileSize != "" {
+		if opts.TotalSize != "" {
+			return nil, fmt.Errorf("total size and file size cannot be combined")
+		}
+		if fileBytes, err = util.ParseSize(opts.FileSize); err != nil {
+			return nil, fmt.Errorf("invalid file size: %w", err)
+		}
+	} else if totalBytes, err = util.ParseSize(opts.TotalSize); err != nil {
 		return nil, fmt.Errorf("invalid size: %w", err)
 	}
 
-	// Calculate dimensions
-	width, height, err := CalculateDimensions(totalBytes, opts.NumImages)
+	// Without a forced number of images, every series gets a random number
+	// of images within the requested range, or the typical range of the
+	// modality; images are sized from the expected total
+	autoImages := opts.NumImages == 0
+	imagesPerSeriesRange := opts.ImagesPerSeries
+	if !imagesPerSeriesRange.IsSet() {
+		imagesPerSeriesRange = modalities.GetGenerator(opts.Modality).ImagesPerSeries()
+	}
+	localizerTemplate, hasLocalizer := modalities.GetLocalizerTemplate(opts.Modality)
+	hasLocalizer = hasLocalizer && opts.Localizer
+	numImagesForSize := opts.NumImages
+	if autoImages {
+		numImagesForSize = expectedImageCount(opts.NumStudies, opts.SeriesPerStudy, imagesPerSeriesRange)
+		if hasLocalizer {
+			numImagesForSize += opts.NumStudies * len(localizerTemplate.LocalizerPlanes)
+		}
+	}
+
+	// Calculate dimensions, every frame of a cine loop and every color sample
+	// counting as an image
+	samplesPerImage := 1
+	if opts.Modality == modalities.US {
+		samplesPerImage = max(opts.CineFrames, 1)
+		switch opts.USColor {
+		case PhotometricRGB:
+			samplesPerImage *= 3
+		case PhotometricYBRFull422:
+			samplesPerImage *= 2
+		}
+	}
+	var width, height int
+	bytesPerSample := max(int(modalities.GetGenerator(opts.Modality).PixelConfig().BitsAllocated)/8, 1)
+	switch {
+	case fileBytes > 0:
+		width, height, err = calculateExactDimensions(fileBytes, 1, samplesPerImage, bytesPerSample)
+	case opts.ExactSize:
+		width, height, err = calculateExactDimensions(totalBytes, numImagesForSize, samplesPerImage, bytesPerSample)
+	default:
+		width, height, err = CalculateDimensions(totalBytes, numImagesForSize*samplesPerImage)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("calculate dimensions: %w", err)
 	}
@@ -593,23 +958,14 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 	}
 
 	// Create output directory
-	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+	if err := os.MkdirAll(opts.filesDir(), 0755); err != nil {
 		return nil, fmt.Errorf("create output directory: %w", err)
 	}
 
-	// Set seed for reproducibility
-	var seed int64
-	if opts.Seed != 0 {
-		seed = opts.Seed
-		if !opts.Quiet {
+	if !opts.Quiet {
+		if opts.Seed != 0 {
 			fmt.Printf("Using seed: %d\n", seed)
-		}
-	} else {
-		// Generate deterministic seed from output directory name
-		h := fnv.New64a()
-		_, _ = h.Write([]byte(opts.OutputDir)) // hash.Write never returns an error
-		seed = int64(h.Sum64())
-		if !opts.Quiet {
+		} else {
 			fmt.Printf("Auto-generated seed from '%s': %d\n", opts.OutputDir, seed)
 			fmt.Println("  (same directory = same patient/study IDs)")
 		}
@@ -636,6 +992,7 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 		numPatients = len(opts.PredefinedPatients)
 	}
 	patients := make([]patientInfo, numPatients)
+	var cohort []util.CohortPatient // Demographics of generated patients, for the summary
 
 	if len(opts.PredefinedPatients) > 0 {
 		// Use predefined patient data from config file
@@ -651,8 +1008,7 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 				patients[i].Sex = []string{"M", "F"}[rng.IntN(2)]
 			}
 			if patients[i].BirthDate == "" {
-				patients[i].BirthDate = fmt.Sprintf("%04d%02d%02d",
-					rng.IntN(51)+1950, rng.IntN(12)+1, rng.IntN(28)+1)
+				patients[i].BirthDate = util.GenerateDate(rng, 1950, 2000)
 			}
 			if patients[i].ID == "" {
 				patients[i].ID = fmt.Sprintf("PID%06d", rng.IntN(900000)+100000)
@@ -664,13 +1020,22 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 	} else {
 		// Generate random patients
 		for i := 0; i < numPatients; i++ {
-			generatedSex := []string{"M", "F"}[rng.IntN(2)]
-			generatedBirthDate := fmt.Sprintf("%04d%02d%02d",
-				rng.IntN(51)+1950, // 1950-2000
-				rng.IntN(12)+1,    // 1-12
-				rng.IntN(28)+1)    // 1-28
+			var generatedSex, generatedBirthDate, locale string
+			if opts.Demographics != nil {
+				generatedSex = opts.Demographics.SampleSex(rng)
+				generatedBirthDate = opts.Demographics.SampleBirthDate(rng)
+				locale = opts.Demographics.SampleLocale(rng)
+			} else {
+				generatedSex = []string{"M", "F"}[rng.IntN(2)]
+				generatedBirthDate = util.GenerateDate(rng, 1950, 2000)
+			}
 			generatedID := fmt.Sprintf("PID%06d", rng.IntN(900000)+100000)
-			generatedName := util.GeneratePatientName(generatedSex, rng)
+			var generatedName string
+			if locale != "" {
+				generatedName = util.GeneratePatientNameLocale(generatedSex, locale, rng)
+			} else {
+				generatedName = util.GeneratePatientName(generatedSex, rng)
+			}
 
 			// Apply edge cases if enabled and dice roll succeeds
 			if edgeCaseApplicator != nil && edgeCaseApplicator.ShouldApply() {
@@ -686,6 +1051,7 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 				BirthDate: getTagValue(opts.CustomTags, "PatientBirthDate", generatedBirthDate),
 				Name:      getTagValue(opts.CustomTags, "PatientName", generatedName),
 			}
+			cohort = append(cohort, util.CohortPatient{Sex: patients[i].Sex, BirthDate: patients[i].BirthDate, Locale: locale})
 		}
 	}
 
@@ -764,6 +1130,9 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 			if patientIdx < remainingStudies {
 				numStudiesForThisPatient++
 			}
+			if patientStudyCounts != nil {
+				numStudiesForThisPatient = patientStudyCounts[patientIdx]
+			}
 			for s := 0; s < numStudiesForThisPatient; s++ {
 				patientForStudy[studyIdx] = studyMapping{patientIdx: patientIdx, studyIdx: s}
 				studyIdx++
@@ -772,7 +1141,11 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 	}
 
 	if !opts.Quiet {
-		fmt.Printf("Generating %d DICOM files...\n", opts.NumImages)
+		if autoImages {
+			fmt.Printf("Generating DICOM files (%s images per series)...\n", imagesPerSeriesRange.String())
+		} else {
+			fmt.Printf("Generating %d DICOM files...\n", opts.NumImages)
+		}
 		fmt.Printf("Number of patients: %d\n", numPatients)
 		// Count studies per patient from the mapping
 		studyCountPerPatient := make(map[int]int)
@@ -784,6 +1157,9 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 				i+1, p.Name, p.ID, p.BirthDate, p.Sex, studyCountPerPatient[i])
 		}
 		fmt.Printf("Number of studies: %d\n", numStudies)
+		if opts.Demographics != nil && len(cohort) > 0 {
+			fmt.Print(util.SummarizeCohort(*opts.Demographics, cohort).String())
+		}
 	}
 
 	// Determine series per study range (default to 1 series if not specified)
@@ -805,8 +1181,27 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 
 	// Get available scanners for this modality
 	scanners := modalityGen.Scanners()
+	if manufacturers := opts.vendorScanners(); manufacturers != nil {
+		scanners = slices.DeleteFunc(scanners, func(s modalities.Scanner) bool { return !manufacturers[s.Manufacturer] })
+		if len(scanners) == 0 {
+			return nil, fmt.Errorf("no %s scanner for the vendor private tags", opts.Modality)
+		}
+	}
 	pixelConfig := modalityGen.PixelConfig()
 
+	// Study records for derived objects written after the images
+	studyRecords := make([]studyRecord, 0, opts.NumStudies)
+
+	var deidentifier *anonymizer
+	if opts.Deidentified {
+		deidentifier = newGeneratedAnonymizer()
+	}
+
+	var linkage *linkagePlanner
+	if opts.LinkageIDs {
+		linkage = newLinkagePlanner(seed)
+	}
+
 	// Phase 1: Build all tasks sequentially (maintains determinism)
 	for studyNum := 1; studyNum <= opts.NumStudies; studyNum++ {
 		// Get patient and study mapping for this study
@@ -818,11 +1213,23 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 		if len(opts.PredefinedPatients) > 0 {
 			predefinedStudy = &opts.PredefinedPatients[mapping.patientIdx].Studies[mapping.studyIdx]
 		}
+		studyTags := opts.CustomTags
+		if predefinedStudy != nil {
+			studyTags = opts.CustomTags.With(predefinedStudy.CustomTags)
+		}
 
 		// Generate deterministic UIDs for this study
-		studyUID := util.GenerateDeterministicUID(fmt.Sprintf("%s_study_%d", opts.OutputDir, studyNum))
+		studyUID := opts.uid(fmt.Sprintf("%s_study_%d", opts.OutputDir, studyNum))
 		// Frame of reference UID shared across all series in this study
-		frameOfReferenceUID := util.GenerateDeterministicUID(fmt.Sprintf("%s_study_%d_frame", opts.OutputDir, studyNum))
+		frameOfReferenceUID := opts.uid(fmt.Sprintf("%s_study_%d_frame", opts.OutputDir, studyNum))
+
+		// Pick the study style with a dedicated RNG so that the rest of the
+		// study is unchanged when variability is disabled
+		var style variability.Style
+		if opts.VariabilityConfig.IsEnabled() {
+			styleRNG := randv2.New(randv2.NewPCG(uint64(seed), uint64(studyNum)<<32|0x5e1))
+			style = variability.PickStyle(opts.VariabilityConfig, styleRNG)
+		}
 
 		// Generate study-specific info
 		studyID := fmt.Sprintf("STD%04d", rng.IntN(9000)+1000)
@@ -840,22 +1247,71 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 			} else {
 				studyDescription = baseDescription
 			}
+			studyDescription = style.ApplyCase(studyDescription)
 			// Allow custom tag override for auto-generated descriptions
-			studyDescription = getTagValue(opts.CustomTags, "StudyDescription", studyDescription)
+			studyDescription = getTagValue(studyTags, "StudyDescription", studyDescription)
 		}
 
 		// Generate study date and time
-		studyDate := fmt.Sprintf("%04d%02d%02d",
-			rng.IntN(5)+2020, // 2020-2024
-			rng.IntN(12)+1,   // 1-12
-			rng.IntN(28)+1)   // 1-28
+		studyDate := util.GenerateDate(rng, 2020, 2024)
+		if studyDate < patient.BirthDate {
+			// Young cohorts: a study never predates the patient's birth
+			studyDate = patient.BirthDate
+		}
 		if predefinedStudy != nil && predefinedStudy.Date != "" {
 			studyDate = predefinedStudy.Date
 		}
-		studyTime := fmt.Sprintf("%02d%02d%02d",
-			rng.IntN(24),  // 0-23 hours
-			rng.IntN(60),  // 0-59 minutes
-			rng.IntN(60))  // 0-59 seconds
+
+		// Age profiles image patients at the ages of the profile, unless the
+		// study date is predefined, and size them, with a dedicated RNG so
+		// that the rest of the study is unchanged
+		var patientWeight, patientSize float64
+		if opts.AgeProfile != "" {
+			ageProfileRNG := randv2.New(randv2.NewPCG(uint64(seed), uint64(studyNum)<<32|0xa9e))
+			if predefinedStudy == nil || predefinedStudy.Date == "" {
+				studyDate = opts.AgeProfile.StudyDate(patient.BirthDate, studyDate, ageProfileRNG)
+			}
+			if weight, size, ok := util.SampleBodySize(patient.BirthDate, studyDate, patient.Sex, ageProfileRNG); ok {
+				patientWeight, patientSize = weight, size
+			}
+		}
+		studyTime := util.GenerateTime(rng, opts.FractionalSeconds)
+
+		// Series and acquisition times follow the study start (the generated
+		// one when the study date or time is overridden with an empty value)
+		startDate, startTime := studyDate, studyTime
+		studyDate = getTagValue(studyTags, "StudyDate", studyDate)
+		studyTime = getTagValue(studyTags, "StudyTime", studyTime)
+		if studyDate != "" {
+			startDate = studyDate
+		}
+		if studyTime != "" {
+			startTime = studyTime
+		}
+		studyStart, err := util.CombineDATM(startDate, startTime)
+		if err != nil {
+			return nil, fmt.Errorf("study %d: %w", studyNum, err)
+		}
+		timeline := util.NewTimeline(studyStart)
+
+		// Patient position of cross-sectional studies, drawn with a dedicated
+		// RNG so that the rest of the study is unchanged
+		var placement tablePlacement
+		if opts.Modality == modalities.CT || opts.Modality == modalities.MR {
+			placementRNG := randv2.New(randv2.NewPCG(uint64(seed), uint64(studyNum)<<32|0x905))
+			placement = drawTablePlacement(placementRNG)
+		}
+
+		var studyLinkage linkageIDs
+		if linkage != nil {
+			studyLinkage = linkage.next(patient.ID, startDate)
+		}
+
+		// Age at the study, omitted when the birth date is partial or after the study
+		patientAge, ageErr := util.PatientAge(patient.BirthDate, studyDate)
+		if ageErr != nil {
+			patientAge = ""
+		}
 
 		// Select scanner for this study
 		scanner := scanners[rng.IntN(len(scanners))]
@@ -911,31 +1367,36 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 			stationName = defaultStationName
 			accessionNumber = defaultAccessionNumber
 		}
+		if predefinedStudy != nil && predefinedStudy.AccessionNumber != "" {
+			// Accession numbers from a config or CSV file apply on their own
+			accessionNumber = predefinedStudy.AccessionNumber
+		}
 
 		// Apply custom tag overrides for study-level tags
-		institutionName := getTagValue(opts.CustomTags, "InstitutionName", studyInstitution.Name)
-		institutionalDepartmentName := getTagValue(opts.CustomTags, "InstitutionalDepartmentName", studyInstitution.Department)
-		referringPhysician = getTagValue(opts.CustomTags, "ReferringPhysicianName", referringPhysician)
-		performingPhysician = getTagValue(opts.CustomTags, "PerformingPhysicianName", performingPhysician)
-		operatorName = getTagValue(opts.CustomTags, "OperatorsName", operatorName)
-		stationName = getTagValue(opts.CustomTags, "StationName", stationName)
-		accessionNumber = getTagValue(opts.CustomTags, "AccessionNumber", accessionNumber)
+		institutionName := getTagValue(studyTags, "InstitutionName", studyInstitution.Name)
+		institutionalDepartmentName := getTagValue(studyTags, "InstitutionalDepartmentName", studyInstitution.Department)
+		referringPhysician = getTagValue(studyTags, "ReferringPhysicianName", referringPhysician)
+		performingPhysician = getTagValue(studyTags, "PerformingPhysicianName", performingPhysician)
+		operatorName = getTagValue(studyTags, "OperatorsName", operatorName)
+		stationName = getTagValue(studyTags, "StationName", stationName)
+		accessionNumber = getTagValue(studyTags, "AccessionNumber", accessionNumber)
 
 		// Use predefined priority or default
 		studyPriority := opts.Priority.String()
 		if predefinedStudy != nil && predefinedStudy.Priority != "" {
 			studyPriority = predefinedStudy.Priority
 		}
-		requestedProcedurePriority := getTagValue(opts.CustomTags, "RequestedProcedurePriority", studyPriority)
+		requestedProcedurePriority := getTagValue(studyTags, "RequestedProcedurePriority", studyPriority)
 
 		// Generate series-level tags with custom overrides
 		protocolName := util.GenerateProtocolName(modalityStr, studyBodyPart, rng)
 		clinicalIndication := util.GenerateClinicalIndication(modalityStr, studyBodyPart, rng)
 
 		// Apply custom tag overrides for series-level tags
-		protocolName = getTagValue(opts.CustomTags, "ProtocolName", protocolName)
-		bodyPartExamined := getTagValue(opts.CustomTags, "BodyPartExamined", studyBodyPart)
-		requestedProcedureDescription := getTagValue(opts.CustomTags, "RequestedProcedureDescription", clinicalIndication)
+		protocolName = opts.AgeProfile.ProtocolName(protocolName)
+		protocolName = getTagValue(studyTags, "ProtocolName", protocolName)
+		bodyPartExamined := getTagValue(studyTags, "BodyPartExamined", studyBodyPart)
+		requestedProcedureDescription := getTagValue(studyTags, "RequestedProcedureDescription", clinicalIndication)
 
 		// Determine number of series for this study
 		var numSeriesThisStudy int
@@ -956,11 +1417,35 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 			numSeriesThisStudy = 1
 		}
 
+		// Draw the number of images of every series, using a dedicated RNG so
+		// that the rest of the study does not depend on the counts
+		var seriesImageCounts []int
+		if autoImages {
+			countRNG := randv2.New(randv2.NewPCG(uint64(seed), uint64(studyNum)<<32|0x1c0))
+			seriesImageCounts = make([]int, numSeriesThisStudy)
+			numImagesThisStudy = 0
+			for i := range seriesImageCounts {
+				seriesImageCounts[i] = imagesPerSeriesRange.Count(countRNG)
+				numImagesThisStudy += seriesImageCounts[i]
+			}
+		}
+
+		// The localizer series comes first, with an image per plane; they
+		// count toward a fixed number of images, when they leave some
+		localizerImages := 0
+		if hasLocalizer && (autoImages || numImagesThisStudy > len(localizerTemplate.LocalizerPlanes)) {
+			localizerImages = len(localizerTemplate.LocalizerPlanes)
+			if autoImages {
+				numImagesThisStudy += localizerImages
+			}
+		}
+
 		// Generate base modality-specific parameters for this study (shared across all series)
 		baseSeriesParams := modalityGen.GenerateSeriesParams(scanner, rng)
+		baseSeriesParams = baseSeriesParams.AdaptToPatientWeight(patientWeight)
 
 		if !opts.Quiet {
-			fmt.Printf("\nStudy %d/%d: %d images in %d series (Patient: %s)\n", studyNum, opts.NumStudies, numImagesThisStudy, numSeriesThisStudy, patient.Name)
+			fmt.Printf("\nStudy %d/%d: %d images in %d series (Patient: %s)\n", studyNum, opts.NumStudies, numImagesThisStudy, numSeriesThisStudy+min(localizerImages, 1), patient.Name)
 			fmt.Printf("  StudyID: %s, Description: %s\n", studyID, studyDescription)
 			fmt.Printf("  Modality: %s, Scanner: %s %s\n", modalityStr, scanner.Manufacturer, scanner.Model)
 			fmt.Printf("  Resolution: PixelSpacing=%.2fmm, SliceThickness=%.2fmm\n",
@@ -968,15 +1453,15 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 		}
 
 		// Distribute images across series
-		imagesPerSeries := numImagesThisStudy / numSeriesThisStudy
-		remainingSeriesImages := numImagesThisStudy % numSeriesThisStudy
-
-		instanceInStudy := 1
+		imagesPerSeries := (numImagesThisStudy - localizerImages) / numSeriesThisStudy
+		remainingSeriesImages := (numImagesThisStudy - localizerImages) % numSeriesThisStudy
 
-		// Generate images for each series
+		// Resolve template and image count of every series up front so that
+		// series can reference images of other series in the same study
+		// (e.g., localizer cross-reference lines).
+		seriesPlans := make([]seriesPlan, numSeriesThisStudy)
 		for seriesNum := 1; seriesNum <= numSeriesThisStudy; seriesNum++ {
-			// Generate deterministic series UID
-			seriesUID := util.GenerateDeterministicUID(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, studyNum, seriesNum))
+			plan := &seriesPlans[seriesNum-1]
 
 			// Get predefined series if available
 			var predefinedSeries *PredefinedSeries
@@ -985,33 +1470,110 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 			}
 
 			// Get series template (if available)
-			var seriesTemplate modalities.SeriesTemplate
-			var predefinedProtocol string
 			if predefinedSeries != nil {
 				// Build template from predefined data
-				seriesTemplate = modalities.SeriesTemplate{
+				plan.template = modalities.SeriesTemplate{
 					SeriesDescription: predefinedSeries.Description,
 				}
-				predefinedProtocol = predefinedSeries.Protocol
+				plan.predefinedProtocol = predefinedSeries.Protocol
+				plan.tags = predefinedSeries.CustomTags
 				// Parse orientation if provided
 				switch predefinedSeries.Orientation {
 				case "Sagittal", "sagittal", "SAG":
-					seriesTemplate.Orientation = modalities.OrientationSagittal
+					plan.template.Orientation = modalities.OrientationSagittal
 				case "Coronal", "coronal", "COR":
-					seriesTemplate.Orientation = modalities.OrientationCoronal
+					plan.template.Orientation = modalities.OrientationCoronal
 				default:
-					seriesTemplate.Orientation = modalities.OrientationAxial
+					plan.template.Orientation = modalities.OrientationAxial
 				}
 			} else if seriesNum <= len(seriesTemplates) {
-				seriesTemplate = seriesTemplates[seriesNum-1]
+				plan.template = seriesTemplates[seriesNum-1]
 			} else {
 				// Fallback template
-				seriesTemplate = modalities.SeriesTemplate{
+				plan.template = modalities.SeriesTemplate{
 					SeriesDescription: fmt.Sprintf("Series %d", seriesNum),
 					Orientation:       modalities.OrientationAxial,
 				}
 			}
 
+			// Calculate images for this series
+			if predefinedSeries != nil && predefinedSeries.ImageCount > 0 {
+				plan.numImages = predefinedSeries.ImageCount
+			} else if autoImages {
+				plan.numImages = seriesImageCounts[seriesNum-1]
+			} else {
+				plan.numImages = imagesPerSeries
+				if seriesNum <= remainingSeriesImages {
+					plan.numImages++
+				}
+			}
+		}
+
+		if localizerImages > 0 {
+			seriesPlans = append([]seriesPlan{{template: localizerTemplate, numImages: localizerImages}}, seriesPlans...)
+			numSeriesThisStudy++
+		}
+
+		// Without a localizer series, axial series reference the central
+		// image of the first orthogonal series, which viewers use as the
+		// scout for reference lines
+		var localizerSeriesNum, localizerInstance int
+		if localizerImages == 0 {
+			localizerSeriesNum, localizerInstance = findLocalizerReference(seriesPlans)
+		}
+		var localizerSOPInstanceUID string
+		var localizerSeries *seriesRecord
+		if localizerSeriesNum > 0 {
+			localizerSOPInstanceUID = opts.uid(
+				fmt.Sprintf("%s_study_%d_series_%d_instance_%d", opts.OutputDir, studyNum, localizerSeriesNum, localizerInstance))
+		}
+
+		instanceInStudy := 1
+
+		record := studyRecord{
+			studyNum:           studyNum,
+			patient:            patient,
+			patientAge:         patientAge,
+			studyUID:           studyUID,
+			studyID:            studyID,
+			studyDate:          studyDate,
+			studyTime:          studyTime,
+			studyDescription:   studyDescription,
+			accessionNumber:    accessionNumber,
+			referringPhysician: referringPhysician,
+			institutionName:    institutionName,
+			bodyPart:           bodyPartExamined,
+			pixelSpacing:       baseSeriesParams.PixelSpacing,
+			scanner:            scanner,
+			linkage:            studyLinkage,
+			characterSet:       opts.CharacterSet,
+			frameOfReference:   frameOfReferenceUID,
+			patientPosition:    placement.patientPosition,
+		}
+
+		// Insert lesions in the first non-empty series for AI result objects,
+		// using a dedicated RNG so that the rest of the study is unchanged
+		if len(opts.AIResults) > 0 {
+			lesionRNG := randv2.New(randv2.NewPCG(uint64(seed), uint64(studyNum)<<32|0x1e5))
+			for i, plan := range seriesPlans {
+				if plan.numImages > 0 && !plan.template.IsLocalizer() {
+					record.lesionSeries = i
+					record.lesions = planLesions(width, height, plan.numImages, lesionRNG)
+					break
+				}
+			}
+		}
+
+		// Generate images for each series
+		for seriesNum := 1; seriesNum <= numSeriesThisStudy; seriesNum++ {
+			// Generate deterministic series UID
+			seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, studyNum, seriesNum))
+
+			seriesTemplate := seriesPlans[seriesNum-1].template
+			predefinedProtocol := seriesPlans[seriesNum-1].predefinedProtocol
+			seriesTags := seriesPlans[seriesNum-1].tags
+			numImagesThisSeries := seriesPlans[seriesNum-1].numImages
+
 			// Copy base parameters and apply series-specific overrides
 			seriesParams := baseSeriesParams
 
@@ -1022,61 +1584,130 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 			if seriesTemplate.WindowWidth != 0 {
 				seriesParams.WindowWidth = seriesTemplate.WindowWidth
 			}
-
-			// Calculate images for this series
-			var numImagesThisSeries int
-			if predefinedSeries != nil && predefinedSeries.ImageCount > 0 {
-				numImagesThisSeries = predefinedSeries.ImageCount
-			} else {
-				numImagesThisSeries = imagesPerSeries
-				if seriesNum <= remainingSeriesImages {
-					numImagesThisSeries++
-				}
+			// MR images follow the contrast of their sequence, windowed on
+			// it
+			seriesSequence := seriesParams.SequenceName
+			if seriesTemplate.SequenceName != "" {
+				seriesSequence = seriesTemplate.SequenceName
+			}
+			var seriesContrast *mrContrast
+			if modalityGen.Modality() == modalities.MR {
+				contrast := mrContrastOf(seriesSequence)
+				seriesContrast = &contrast
+				seriesParams.WindowCenter, seriesParams.WindowWidth = contrast.window(pixelConfig)
+			}
+			if opts.Pattern != "" {
+				seriesParams.WindowCenter, seriesParams.WindowWidth = patternWindow(pixelConfig)
 			}
+			if opts.CineFrames > 1 && opts.Modality == modalities.US {
+				seriesParams.NumberOfFrames = opts.CineFrames
+				seriesParams.FrameTime, seriesParams.FrameTimeVector = cineFrameTimes(opts.CineFrames, opts.FrameTimeVector, rng)
+			}
+			sopClassUID := cineSOPClassUID(modalityGen, seriesParams.NumberOfFrames)
 
 			// Generate series description
 			generatedSeriesDescription := seriesTemplate.SeriesDescription
 			if generatedSeriesDescription == "" {
 				generatedSeriesDescription = fmt.Sprintf("Series %d - %s", seriesNum, modalityStr)
 			}
-			seriesDescription := getTagValue(opts.CustomTags, "SeriesDescription", generatedSeriesDescription)
+			if predefinedStudy == nil || len(predefinedStudy.Series) == 0 {
+				generatedSeriesDescription = style.ApplyCase(generatedSeriesDescription)
+			}
+			seriesDescription := getTagValue(studyTags, "SeriesDescription", generatedSeriesDescription)
+			seriesDescription = getTagValue(seriesTags, "SeriesDescription", seriesDescription)
 
 			// Use series-specific protocol if available
 			seriesProtocolName := protocolName
 			if predefinedProtocol != "" {
 				seriesProtocolName = predefinedProtocol
 			}
+			seriesProtocolName = getTagValue(seriesTags, "ProtocolName", seriesProtocolName)
+			seriesBodyPart := getTagValue(seriesTags, "BodyPartExamined", bodyPartExamined)
 
 			// Get image orientation from template
 			imageOrientationValues := seriesTemplate.ImageOrientationPatient()
-			imageOrientationPatient := make([]string, 6)
-			for i, v := range imageOrientationValues {
-				imageOrientationPatient[i] = fmt.Sprintf("%.6f", v)
-			}
+			imageOrientationPatient := formatOrientation(imageOrientationValues)
 
 			if !opts.Quiet {
 				fmt.Printf("  Series %d: %s (%d images, %s)\n", seriesNum, seriesDescription, numImagesThisSeries, seriesTemplate.Orientation)
 			}
 
+			seriesStart := timeline.Series(seriesNum - 1)
+
+			seriesRec := seriesRecord{
+				seriesUID:    seriesUID,
+				seriesNumber: seriesNum,
+				sopClassUID:  sopClassUID,
+				description:  seriesDescription,
+				start:        seriesStart,
+				orientation:  imageOrientationValues,
+				rows:         height,
+				columns:      width,
+				pixelSpacing: seriesParams.PixelSpacing,
+				sliceSpacing: seriesParams.SpacingBetweenSlices,
+
+				windowCenter:     seriesParams.WindowCenter,
+				windowWidth:      seriesParams.WindowWidth,
+				rescaleIntercept: seriesParams.RescaleIntercept,
+				rescaleSlope:     seriesParams.RescaleSlope,
+
+				kvp:            seriesParams.KVP,
+				tubeCurrent:    seriesParams.XRayTubeCurrent,
+				reconstruction: seriesTemplate.Reconstruction,
+				localizer:      seriesTemplate.IsLocalizer(),
+			}
+			var seriesLesions []lesion
+			if seriesNum-1 == record.lesionSeries {
+				seriesLesions = record.lesions
+			}
+
+			// Jitter the values of each instance with a dedicated RNG so that
+			// the rest of the series is unchanged when it is disabled
+			var jitterRNG *randv2.Rand
+			if opts.NumericJitter > 0 {
+				jitterRNG = randv2.New(randv2.NewPCG(uint64(seed), uint64(studyNum)<<32|uint64(seriesNum)<<16|0x717))
+			}
+
+			// Same for the transfer syntaxes of a mix
+			var transferSyntaxRNG *randv2.Rand
+			if len(opts.TransferSyntaxMix) > 0 {
+				transferSyntaxRNG = randv2.New(randv2.NewPCG(uint64(seed), uint64(studyNum)<<32|uint64(seriesNum)<<16|0x75))
+			}
+
 			// Build tasks for each image in this series
 			for instanceInSeries := 1; instanceInSeries <= numImagesThisSeries; instanceInSeries++ {
-				sopInstanceUID := util.GenerateDeterministicUID(
+				sopInstanceUID := opts.uid(
 					fmt.Sprintf("%s_study_%d_series_%d_instance_%d", opts.OutputDir, studyNum, seriesNum, instanceInSeries))
 
-				sliceIndex := float64(instanceInSeries - 1)
-				imagePositionX := -100.0
-				imagePositionY := -100.0
-				imagePositionZ := -100.0 + (sliceIndex * seriesParams.SpacingBetweenSlices)
+				// Slices are stacked along the plane normal, centered on the
+				// patient origin so that orthogonal series intersect; localizer
+				// images are the central slices of their planes
+				orientation, orientationDS := imageOrientationValues, imageOrientationPatient
+				sliceIndex, numSlices := instanceInSeries-1, numImagesThisSeries
+				if seriesTemplate.IsLocalizer() {
+					plane := modalities.SeriesTemplate{Orientation: seriesTemplate.LocalizerPlanes[instanceInSeries-1]}
+					orientation = plane.ImageOrientationPatient()
+					orientationDS = formatOrientation(orientation)
+					sliceIndex, numSlices = 0, 1
+				}
+				position := slicePosition(orientation, width, height,
+					seriesParams.PixelSpacing, seriesParams.SpacingBetweenSlices,
+					sliceIndex, numSlices)
 				imagePositionPatient := []string{
-					fmt.Sprintf("%.6f", imagePositionX),
-					fmt.Sprintf("%.6f", imagePositionY),
-					fmt.Sprintf("%.6f", imagePositionZ),
+					util.FormatDS(position[0]),
+					util.FormatDS(position[1]),
+					util.FormatDS(position[2]),
+				}
+				sliceLocation := sliceLocationOf(position, orientation)
+				acquired := timeline.Acquisition(seriesNum-1, instanceInSeries-1)
+				transferSyntax := opts.TransferSyntax
+				if transferSyntaxRNG != nil {
+					transferSyntax = drawTransferSyntax(opts.TransferSyntaxMix, transferSyntaxRNG)
 				}
-				sliceLocation := imagePositionZ
 
 				// Build metadata (without pixel data)
 				metadata := []*dicom.Element{
-					mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+					mustNewElement(tag.TransferSyntaxUID, []string{transferSyntax}),
 					mustNewElement(tag.PatientName, []string{patient.Name}),
 					mustNewElement(tag.PatientID, []string{patient.ID}),
 					mustNewElement(tag.PatientBirthDate, []string{patient.BirthDate}),
@@ -1085,27 +1716,33 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 					mustNewElement(tag.StudyID, []string{studyID}),
 					mustNewElement(tag.StudyDate, []string{studyDate}),
 					mustNewElement(tag.StudyTime, []string{studyTime}),
+					mustNewElement(tag.SeriesDate, []string{util.FormatDA(seriesStart)}),
+					mustNewElement(tag.SeriesTime, []string{util.FormatTM(seriesStart, opts.FractionalSeconds)}),
+					mustNewElement(tag.AcquisitionDate, []string{util.FormatDA(acquired)}),
+					mustNewElement(tag.AcquisitionTime, []string{util.FormatTM(acquired, opts.FractionalSeconds)}),
+					mustNewElement(tag.ContentDate, []string{util.FormatDA(acquired)}),
+					mustNewElement(tag.ContentTime, []string{util.FormatTM(acquired, opts.FractionalSeconds)}),
 					mustNewElement(tag.StudyDescription, []string{studyDescription}),
 					mustNewElement(tag.SeriesInstanceUID, []string{seriesUID}),
-					mustNewElement(tag.SeriesNumber, []string{fmt.Sprintf("%d", seriesNum)}),
+					mustNewElement(tag.SeriesNumber, []string{util.FormatIS(seriesNum)}),
 					mustNewElement(tag.SeriesDescription, []string{seriesDescription}),
 					mustNewElement(tag.Modality, []string{modalityStr}),
 					mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
-					mustNewElement(tag.SOPClassUID, []string{modalityGen.SOPClassUID()}),
-					mustNewElement(tag.InstanceNumber, []string{fmt.Sprintf("%d", instanceInSeries)}),
+					mustNewElement(tag.SOPClassUID, []string{sopClassUID}),
+					mustNewElement(tag.InstanceNumber, []string{util.FormatIS(instanceInSeries)}),
 					mustNewElement(tag.PixelSpacing, []string{
-						fmt.Sprintf("%.6f", seriesParams.PixelSpacing),
-						fmt.Sprintf("%.6f", seriesParams.PixelSpacing),
+						util.FormatDS(seriesParams.PixelSpacing),
+						util.FormatDS(seriesParams.PixelSpacing),
 					}),
-					mustNewElement(tag.SliceThickness, []string{fmt.Sprintf("%.6f", seriesParams.SliceThickness)}),
-					mustNewElement(tag.SpacingBetweenSlices, []string{fmt.Sprintf("%.6f", seriesParams.SpacingBetweenSlices)}),
+					mustNewElement(tag.SliceThickness, []string{util.FormatDS(seriesParams.SliceThickness)}),
+					mustNewElement(tag.SpacingBetweenSlices, []string{util.FormatDS(seriesParams.SpacingBetweenSlices)}),
 					mustNewElement(tag.Manufacturer, []string{scanner.Manufacturer}),
 					mustNewElement(tag.ManufacturerModelName, []string{scanner.Model}),
-					mustNewElement(tag.WindowCenter, []string{fmt.Sprintf("%.1f", seriesParams.WindowCenter)}),
-					mustNewElement(tag.WindowWidth, []string{fmt.Sprintf("%.1f", seriesParams.WindowWidth)}),
+					mustNewElement(tag.WindowCenter, []string{util.FormatDS(seriesParams.WindowCenter)}),
+					mustNewElement(tag.WindowWidth, []string{util.FormatDS(seriesParams.WindowWidth)}),
 					mustNewElement(tag.ImagePositionPatient, imagePositionPatient),
-					mustNewElement(tag.ImageOrientationPatient, imageOrientationPatient),
-					mustNewElement(tag.SliceLocation, []string{fmt.Sprintf("%.6f", sliceLocation)}),
+					mustNewElement(tag.ImageOrientationPatient, orientationDS),
+					mustNewElement(tag.SliceLocation, []string{util.FormatDS(sliceLocation)}),
 					mustNewElement(tag.FrameOfReferenceUID, []string{frameOfReferenceUID}),
 					mustNewElement(tag.Rows, []int{height}),
 					mustNewElement(tag.Columns, []int{width}),
@@ -1122,36 +1759,106 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 					mustNewElement(tag.ReferringPhysicianName, []string{referringPhysician}),
 					mustNewElement(tag.PerformingPhysicianName, []string{performingPhysician}),
 					mustNewElement(tag.OperatorsName, []string{operatorName}),
-					mustNewElement(tag.BodyPartExamined, []string{bodyPartExamined}),
+					mustNewElement(tag.BodyPartExamined, []string{seriesBodyPart}),
 					mustNewElement(tag.ProtocolName, []string{seriesProtocolName}),
 					mustNewElement(tag.RequestedProcedureDescription, []string{requestedProcedureDescription}),
 					mustNewElement(tag.RequestedProcedurePriority, []string{requestedProcedurePriority}),
 					mustNewElement(tag.AccessionNumber, []string{accessionNumber}),
 				}
+				if opts.CharacterSet != "" {
+					metadata = util.DeclareCharacterSet(metadata, opts.CharacterSet)
+				}
+
+				if patientAge != "" {
+					metadata = append(metadata, mustNewElement(tag.PatientAge, []string{patientAge}))
+				}
+				if patientWeight > 0 {
+					metadata = append(metadata,
+						mustNewElement(tag.PatientWeight, []string{util.FormatDS(patientWeight)}),
+						mustNewElement(tag.PatientSize, []string{util.FormatDS(patientSize)}),
+					)
+				}
 
 				// Add contrast agent info if this series uses contrast
 				if seriesTemplate.HasContrast && seriesTemplate.ContrastAgent != "" {
 					metadata = append(metadata, mustNewElement(tag.ContrastBolusAgent, []string{seriesTemplate.ContrastAgent}))
 				}
 
+				// Reference the localizer image for scout reference lines
+				if localizerSOPInstanceUID != "" && seriesTemplate.Orientation == modalities.OrientationAxial {
+					metadata = append(metadata, mustNewElement(tag.ReferencedImageSequence, [][]*dicom.Element{{
+						mustNewElement(tag.ReferencedSOPClassUID, []string{modalityGen.SOPClassUID()}),
+						mustNewElement(tag.ReferencedSOPInstanceUID, []string{localizerSOPInstanceUID}),
+					}}))
+				} else if localizerSeries != nil {
+					if refs := localizerReferences(*localizerSeries, localizerTemplate.LocalizerPlanes, seriesTemplate.Orientation); refs != nil {
+						metadata = append(metadata, mustNewElement(tag.ReferencedImageSequence, refs))
+					}
+				}
+				if seriesTemplate.IsLocalizer() {
+					metadata = append(metadata, mustNewElement(tag.ImageType, []string{"ORIGINAL", "PRIMARY", "LOCALIZER"}))
+				}
+
 				// Add sequence name for MR
 				if seriesTemplate.SequenceName != "" {
 					metadata = append(metadata, mustNewElement(tag.SequenceName, []string{seriesTemplate.SequenceName}))
 				}
 
+				if studyLinkage.admissionID != "" {
+					metadata = append(metadata, studyLinkage.elements()...)
+				}
+
+				if placement.patientPosition != "" {
+					metadata = append(metadata, placement.elements(opts.Modality, orientation, position)...)
+				}
+
 				// Add modality-specific elements
 				ds := &dicom.Dataset{Elements: metadata}
-				if err := modalityGen.AppendModalityElements(ds, seriesParams); err != nil {
+				instanceParams := seriesParams
+				if jitterRNG != nil {
+					instanceParams = seriesParams.Jitter(opts.NumericJitter, jitterRNG)
+				}
+				if err := modalityGen.AppendModalityElements(ds, instanceParams); err != nil {
 					return nil, fmt.Errorf("add modality elements for study %d, series %d, instance %d: %w", studyNum, seriesNum, instanceInSeries, err)
 				}
-				metadata = ds.Elements
+				metadata = monochromeElements(style.Apply(ds.Elements), seriesPhotometric(opts, seriesNum))
+				if photometric := colorPhotometric(opts); photometric != "" {
+					metadata = colorElements(metadata, photometric, opts.ColorByPlane)
+				}
+				if opts.Modality == modalities.US {
+					metadata = append(metadata, ultrasoundRegionsElement(width, height, seriesParams.PixelSpacing, colorPhotometric(opts) != ""))
+				}
+				if opts.SiemensCSA || len(opts.VendorTags) > 0 {
+					frequency, phase := acquisitionSteps(metadata)
+					img := vendorImage{
+						params:        instanceParams,
+						sequence:      seriesSequence,
+						protocol:      seriesProtocolName,
+						orientation:   orientation,
+						frequency:     frequency,
+						phase:         phase,
+						width:         width,
+						sliceIndex:    sliceIndex,
+						numSlices:     numSlices,
+						patientWeight: patientWeight,
+					}
+					if opts.SiemensCSA && scanner.Manufacturer == manufacturerSiemens {
+						metadata = append(metadata, siemensCSAElements(img)...)
+					}
+					metadata = append(metadata, vendorPrivateElements(opts.VendorTags, img)...)
+				}
+
+				if deidentifier != nil {
+					metadata = deidentifier.deidentifyInstance(metadata)
+				}
 
 				// Add corruption elements if enabled
 				var taskWriteOpts []dicom.WriteOption
-				var taskHasMalformedLengths bool
+				var taskHasMalformedLengths, taskHasOddLengths bool
 				if corruptionApplicator != nil {
 					corruptionElements := corruptionApplicator.GenerateCorruptionElements()
 					metadata = append(metadata, corruptionElements...)
+					metadata = corruptionApplicator.ApplyElementCorruption(metadata)
 
 					// Sort metadata by (Group, Element) so private tags (e.g., 0x0009)
 					// are placed before standard tags they might precede
@@ -1164,6 +1871,23 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 
 					taskWriteOpts = []dicom.WriteOption{dicom.SkipVRVerification(), dicom.SkipValueTypeVerification()}
 					taskHasMalformedLengths = corruptionApplicator.HasMalformedLengths()
+					taskHasOddLengths = corruptionApplicator.HasOddLengths()
+				}
+
+				// Private tags, in blocks next to those of the corruption
+				if len(opts.PrivateTags) > 0 {
+					if metadata, err = util.InsertPrivateTags(metadata, opts.PrivateTags); err != nil {
+						return nil, err
+					}
+					if taskWriteOpts == nil {
+						taskWriteOpts = []dicom.WriteOption{dicom.SkipVRVerification()}
+					}
+				}
+
+				// Text in the character set of the file, unless charset-mismatch
+				// corruption writes it in another one
+				if corruptionApplicator == nil || !corruptionApplicator.HasCharsetMismatch() {
+					metadata = util.EncodeText(metadata)
 				}
 
 				// Generate deterministic pixel seed for this specific image
@@ -1171,8 +1895,25 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 				_, _ = fmt.Fprintf(pixelSeedHash, "%d_pixel_%d", seed, globalImageIndex)
 				pixelSeed := pixelSeedHash.Sum64()
 
-				filename := fmt.Sprintf("IMG%04d.dcm", globalImageIndex)
-				filePath := filepath.Join(opts.OutputDir, filename)
+				// Staged flat, or in shards from the expected total when unknown yet
+				filePath := stagingPath(opts.filesDir(), globalImageIndex, numImagesForSize, opts.ShardFanout)
+				if dir := filepath.Dir(filePath); dir != opts.filesDir() && (globalImageIndex-1)%opts.ShardFanout == 0 {
+					if err := os.MkdirAll(dir, 0755); err != nil {
+						return nil, fmt.Errorf("create staging directory: %w", err)
+					}
+				}
+
+				var taskAnnotations dfimage.Annotations
+				if opts.Annotations {
+					taskAnnotations = imageAnnotations(seriesDescription, seriesNum, studyDate, instanceInSeries, numImagesThisSeries, sliceLocation)
+				}
+				if opts.QRCode {
+					taskAnnotations.QRCode = sopInstanceUID
+				}
+				var taskPhantom *phantomSlice
+				if opts.Phantom {
+					taskPhantom = newPhantomSlice(opts.Modality, seriesSequence, pixelConfig, orientation, sliceIndex+1, numSlices)
+				}
 
 				tasks = append(tasks, imageTask{
 					globalIndex:         globalImageIndex,
@@ -1183,11 +1924,25 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 					height:              height,
 					filePath:            filePath,
 					textOverlay:         fmt.Sprintf("File %d/%d", globalImageIndex, opts.NumImages),
+					annotations:         taskAnnotations,
 					pixelSeed:           pixelSeed,
 					metadata:            metadata,
 					pixelConfig:         pixelConfig,
+					frames:              seriesParams.NumberOfFrames,
+					frameTime:           seriesParams.FrameTime,
+					photometric:         pixelPhotometric(opts, transferSyntax),
+					byPlane:             opts.ColorByPlane,
+					transferSyntax:      transferSyntax,
+					jpegQuality:         opts.JPEGQuality,
+					lesions:             seriesLesions,
+					phantom:             taskPhantom,
+					pattern:             opts.Pattern,
+					hounsfield:          opts.Modality == modalities.CT,
+					contrast:            seriesContrast,
 					writeOpts:           taskWriteOpts,
 					hasMalformedLengths: taskHasMalformedLengths,
+					hasOddLengths:       taskHasOddLengths,
+					groupLengths:        opts.GroupLengths,
 					studyUID:            studyUID,
 					seriesUID:           seriesUID,
 					sopInstanceUID:      sopInstanceUID,
@@ -1195,9 +1950,42 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 					studyID:             studyID,
 				})
 
+				seriesRec.instanceUIDs = append(seriesRec.instanceUIDs, sopInstanceUID)
+				seriesRec.filePaths = append(seriesRec.filePaths, filePath)
+
 				globalImageIndex++
 				instanceInStudy++
 			}
+
+			record.series = append(record.series, seriesRec)
+			if seriesRec.localizer {
+				localizer := seriesRec
+				localizerSeries = &localizer
+			}
+		}
+
+		studyRecords = append(studyRecords, record)
+	}
+
+	// The total is only known once every series is planned
+	if autoImages {
+		opts.NumImages = len(tasks)
+		for i := range tasks {
+			tasks[i].textOverlay = fmt.Sprintf("File %d/%d", tasks[i].globalIndex, opts.NumImages)
+		}
+	}
+
+	// Every series again at the other qualities of the sweep
+	if len(opts.JPEGQualitySweep) > 0 {
+		base := len(tasks)
+		tasks = sweepJPEGQualities(opts, tasks, numImagesForSize)
+		for _, task := range tasks[base:] {
+			if err := os.MkdirAll(filepath.Dir(task.filePath), 0755); err != nil {
+				return nil, fmt.Errorf("create staging directory: %w", err)
+			}
+		}
+		if !opts.Quiet {
+			fmt.Printf("JPEG quality sweep: every series at qualities %v (%d images)\n", opts.JPEGQualitySweep, len(tasks))
 		}
 	}
 
@@ -1288,8 +2076,70 @@ func GenerateDICOMSeries(opts GeneratorOptions) ([]GeneratedFile, error) {
 		}
 	}
 
+	// Derived objects referencing the generated images
+	reportFiles, err := generateReports(opts, seed, studyRecords)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range reportFiles {
+		if deidentifier != nil {
+			if err := deidentifier.deidentifyFile(f.Path); err != nil {
+				return nil, err
+			}
+		}
+		if len(opts.PrivateTags) > 0 {
+			if err := addPrivateTagsToFile(f.Path, opts.PrivateTags); err != nil {
+				return nil, err
+			}
+		}
+		if opts.GroupLengths {
+			if err := addGroupLengthsToFile(f.Path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	generatedFiles = append(generatedFiles, reportFiles...)
+
+	// Cut the images last, once the derived objects have read them
+	if opts.CorruptionConfig.HasType(corruption.Truncated) {
+		for _, task := range tasks {
+			if err := corruption.TruncateFile(task.filePath, opts.CorruptionConfig.Truncation); err != nil {
+				return nil, fmt.Errorf("truncate %s: %w", task.filePath, err)
+			}
+		}
+	}
+
+	// Pad the images up to the requested file or total size
+	if opts.ExactSize && fileBytes > 0 {
+		oversized, err := padToFileSize(tasks, fileBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.Quiet {
+			fmt.Printf("Padded the images to %s each\n", util.FormatSize(fileBytes))
+			if oversized > 0 {
+				fmt.Printf("Warning: %d images exceed %s with the smallest dimensions\n", oversized, util.FormatSize(fileBytes))
+			}
+		}
+	} else if opts.ExactSize {
+		total, err := padToTotalSize(generatedFiles, tasks, totalBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.Quiet {
+			fmt.Printf("Padded the instances to %s (requested %s)\n", util.FormatSize(total), util.FormatSize(totalBytes))
+			if float64(total) > float64(totalBytes)*1.01 {
+				fmt.Printf("Warning: the instances exceed the requested size by %.1f%% (fewer images or a larger size would fit)\n",
+					(float64(total)/float64(totalBytes)-1)*100)
+			}
+		}
+	}
+
 	if !opts.Quiet {
-		fmt.Printf("\n✓ %d DICOM files created in: %s/\n", opts.NumImages, opts.OutputDir)
+		fmt.Printf("\n✓ %d DICOM files created in: %s/\n", len(tasks), opts.filesDir())
+		if len(reportFiles) > 0 {
+			fmt.Printf("✓ %d report objects created\n", len(reportFiles))
+		}
 	}
 
 	return generatedFiles, nil
@@ -0,0 +1,184 @@
+package dicom
+
+import (
+	"math"
+	randv2 "math/rand/v2"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// seriesPlan holds the resolved layout of a series before its images are built
+type seriesPlan struct {
+	template           modalities.SeriesTemplate
+	predefinedProtocol string
+	tags               util.ParsedTags // Tag overrides of the series
+	numImages          int
+}
+
+// findLocalizerReference returns the series number and instance number of the
+// image that axial series should reference as their localizer: the central
+// slice of the first sagittal or coronal series of the study.
+// Returns (0, 0) when the study has no axial series or no orthogonal series.
+func findLocalizerReference(plans []seriesPlan) (seriesNum, instanceNum int) {
+	hasAxial := false
+	for _, plan := range plans {
+		if plan.template.Orientation == modalities.OrientationAxial {
+			hasAxial = true
+			break
+		}
+	}
+	if !hasAxial {
+		return 0, 0
+	}
+
+	for i, plan := range plans {
+		if plan.numImages == 0 {
+			continue
+		}
+		switch plan.template.Orientation {
+		case modalities.OrientationSagittal, modalities.OrientationCoronal:
+			return i + 1, (plan.numImages + 1) / 2
+		}
+	}
+	return 0, 0
+}
+
+// localizerReferences returns the ReferencedImageSequence items of the images
+// of a series with the given orientation to the images of the localizer
+// series of its study, one per plane: those of the other planes, which its
+// slices cross as reference lines. nil when they are all parallel.
+func localizerReferences(localizer seriesRecord, planes []string, orientation string) [][]*dicom.Element {
+	var refs [][]*dicom.Element
+	for i, plane := range planes {
+		if plane == orientation || i >= len(localizer.instanceUIDs) {
+			continue
+		}
+		refs = append(refs, []*dicom.Element{
+			mustNewElement(tag.ReferencedSOPClassUID, []string{localizer.sopClassUID}),
+			mustNewElement(tag.ReferencedSOPInstanceUID, []string{localizer.instanceUIDs[i]}),
+		})
+	}
+	return refs
+}
+
+// formatOrientation returns the ImageOrientationPatient values as DS strings.
+func formatOrientation(orientation []float64) []string {
+	values := make([]string, len(orientation))
+	for i, v := range orientation {
+		values[i] = util.FormatDS(v)
+	}
+	return values
+}
+
+// sliceNormal returns the normal of the image plane described by an
+// ImageOrientationPatient (row cosines × column cosines).
+func sliceNormal(orientation []float64) [3]float64 {
+	row := orientation[0:3]
+	col := orientation[3:6]
+	return [3]float64{
+		row[1]*col[2] - row[2]*col[1],
+		row[2]*col[0] - row[0]*col[2],
+		row[0]*col[1] - row[1]*col[0],
+	}
+}
+
+// slicePosition computes the ImagePositionPatient (center of the top-left pixel)
+// of a slice. The series volume is centered on the patient origin: the image
+// plane is centered in-plane and slices are stacked symmetrically along the normal.
+func slicePosition(orientation []float64, width, height int, pixelSpacing, sliceSpacing float64, sliceIndex, numSlices int) [3]float64 {
+	row := orientation[0:3]
+	col := orientation[3:6]
+	normal := sliceNormal(orientation)
+
+	halfWidth := float64(width-1) / 2 * pixelSpacing
+	halfHeight := float64(height-1) / 2 * pixelSpacing
+	offset := (float64(sliceIndex) - float64(numSlices-1)/2) * sliceSpacing
+
+	var pos [3]float64
+	for i := 0; i < 3; i++ {
+		pos[i] = -halfWidth*row[i] - halfHeight*col[i] + offset*normal[i]
+	}
+	return pos
+}
+
+// sliceLocationOf returns the SliceLocation of a slice: the signed distance of
+// its position along the plane normal.
+func sliceLocationOf(position [3]float64, orientation []float64) float64 {
+	normal := sliceNormal(orientation)
+	return position[0]*normal[0] + position[1]*normal[1] + position[2]*normal[2]
+}
+
+// patientPositions are the patient positions of CT and MR studies and their
+// weights: mostly supine head first, feet first for lower limbs and some
+// cardiac or vascular protocols, prone for biopsies and breast imaging.
+var patientPositions = []struct {
+	position string
+	weight   float64
+}{
+	{"HFS", 80},
+	{"FFS", 12},
+	{"HFP", 5},
+	{"FFP", 3},
+}
+
+// tablePlacement is the patient position and table placement of a study,
+// shared by all its series
+type tablePlacement struct {
+	patientPosition string  // HFS, FFS, HFP, FFP
+	origin          float64 // TablePosition with the patient origin at the isocenter (mm)
+	height          float64 // TableHeight (mm)
+}
+
+// drawTablePlacement draws the patient position and table placement of a
+// study.
+func drawTablePlacement(rng *randv2.Rand) tablePlacement {
+	var total float64
+	for _, p := range patientPositions {
+		total += p.weight
+	}
+	placement := tablePlacement{
+		origin: math.Round(600 + rng.Float64()*800), // 600-1400 mm
+		height: math.Round(120 + rng.Float64()*80),  // 120-200 mm
+	}
+	draw := rng.Float64() * total
+	for _, p := range patientPositions {
+		placement.patientPosition = p.position
+		if draw -= p.weight; draw < 0 {
+			break
+		}
+	}
+	return placement
+}
+
+// tablePosition returns the longitudinal table position of a slice at a z
+// (superior) patient coordinate. The table moves further into the gantry to
+// reach the feet of a head first patient and the head of a feet first one.
+func (t tablePlacement) tablePosition(z float64) float64 {
+	if strings.HasPrefix(t.patientPosition, "FF") {
+		return t.origin + z
+	}
+	return t.origin - z
+}
+
+// elements returns the PatientPosition of an image and, for CT, its table
+// height and, for slices across the table axis, the table position of the
+// slice at position.
+func (t tablePlacement) elements(modality modalities.Modality, orientation []float64, position [3]float64) []*dicom.Element {
+	elements := []*dicom.Element{mustNewElement(tag.PatientPosition, []string{t.patientPosition})}
+	if modality != modalities.CT {
+		return elements
+	}
+	elements = append(elements, mustNewElement(tag.TableHeight, []string{util.FormatDS(t.height)}))
+	if normal := sliceNormal(orientation); math.Abs(normal[2]) == 1 {
+		table := math.Round(t.tablePosition(position[2])*100) / 100
+		elements = append(elements,
+			mustNewElement(tag.TableTraverse, []string{util.FormatDS(table)}),
+			mustNewElement(tag.TablePosition, []float64{table}),
+		)
+	}
+	return elements
+}
@@ -0,0 +1,231 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	randv2 "math/rand/v2"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestSliceNormal_Orientations(t *testing.T) {
+	tests := []struct {
+		orientation string
+		want        [3]float64
+	}{
+		{modalities.OrientationAxial, [3]float64{0, 0, 1}},
+		{modalities.OrientationSagittal, [3]float64{-1, 0, 0}},
+		{modalities.OrientationCoronal, [3]float64{0, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.orientation, func(t *testing.T) {
+			iop := modalities.SeriesTemplate{Orientation: tt.orientation}.ImageOrientationPatient()
+			got := sliceNormal(iop)
+			if got != tt.want {
+				t.Errorf("sliceNormal(%v) = %v, want %v", iop, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlicePosition_CenteredVolume(t *testing.T) {
+	iop := modalities.SeriesTemplate{Orientation: modalities.OrientationAxial}.ImageOrientationPatient()
+
+	first := slicePosition(iop, 256, 256, 1.0, 2.0, 0, 5)
+	middle := slicePosition(iop, 256, 256, 1.0, 2.0, 2, 5)
+	last := slicePosition(iop, 256, 256, 1.0, 2.0, 4, 5)
+
+	if middle[2] != 0 {
+		t.Errorf("middle slice should be at z=0, got %v", middle[2])
+	}
+	if first[2] != -4 || last[2] != 4 {
+		t.Errorf("slices should span z=-4..4, got %v..%v", first[2], last[2])
+	}
+	if first[0] != -127.5 || first[1] != -127.5 {
+		t.Errorf("top-left pixel should be at (-127.5, -127.5), got (%v, %v)", first[0], first[1])
+	}
+}
+
+func TestSliceLocationOf_FollowsNormal(t *testing.T) {
+	iop := modalities.SeriesTemplate{Orientation: modalities.OrientationSagittal}.ImageOrientationPatient()
+
+	prev := math.Inf(-1)
+	for i := 0; i < 4; i++ {
+		loc := sliceLocationOf(slicePosition(iop, 128, 128, 0.5, 3.0, i, 4), iop)
+		if loc <= prev {
+			t.Errorf("slice %d location %v should increase (previous %v)", i, loc, prev)
+		}
+		prev = loc
+	}
+}
+
+func TestFindLocalizerReference(t *testing.T) {
+	axial := modalities.SeriesTemplate{Orientation: modalities.OrientationAxial}
+	sagittal := modalities.SeriesTemplate{Orientation: modalities.OrientationSagittal}
+	coronal := modalities.SeriesTemplate{Orientation: modalities.OrientationCoronal}
+
+	tests := []struct {
+		name         string
+		plans        []seriesPlan
+		wantSeries   int
+		wantInstance int
+	}{
+		{"axial only", []seriesPlan{{template: axial, numImages: 10}}, 0, 0},
+		{"sagittal only", []seriesPlan{{template: sagittal, numImages: 10}}, 0, 0},
+		{"sagittal then axial", []seriesPlan{{template: sagittal, numImages: 9}, {template: axial, numImages: 10}}, 1, 5},
+		{"axial then coronal", []seriesPlan{{template: axial, numImages: 10}, {template: coronal, numImages: 4}}, 2, 2},
+		{"empty orthogonal skipped", []seriesPlan{{template: sagittal}, {template: axial, numImages: 3}, {template: coronal, numImages: 1}}, 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series, instance := findLocalizerReference(tt.plans)
+			if series != tt.wantSeries || instance != tt.wantInstance {
+				t.Errorf("findLocalizerReference() = (%d, %d), want (%d, %d)", series, instance, tt.wantSeries, tt.wantInstance)
+			}
+		})
+	}
+}
+
+func TestDrawTablePlacement_Positions(t *testing.T) {
+	rng := randv2.New(randv2.NewPCG(1, 2))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		p := drawTablePlacement(rng)
+		counts[p.patientPosition]++
+		if p.origin < 600 || p.origin > 1400 || p.height < 120 || p.height > 200 {
+			t.Fatalf("placement %+v out of range", p)
+		}
+	}
+	for _, p := range patientPositions {
+		if counts[p.position] == 0 {
+			t.Errorf("patient position %s never drawn: %v", p.position, counts)
+		}
+	}
+	if counts["HFS"] < 700 {
+		t.Errorf("HFS drawn %d times out of 1000, want most studies", counts["HFS"])
+	}
+}
+
+func TestTablePlacement_Elements(t *testing.T) {
+	axial := modalities.SeriesTemplate{Orientation: modalities.OrientationAxial}.ImageOrientationPatient()
+	sagittal := modalities.SeriesTemplate{Orientation: modalities.OrientationSagittal}.ImageOrientationPatient()
+
+	tableValue := func(elements []*dicom.Element) (float64, bool) {
+		for _, elem := range elements {
+			if elem.Tag == tag.TablePosition {
+				return elem.Value.GetValue().([]float64)[0], true
+			}
+		}
+		return 0, false
+	}
+
+	// Head first: the table goes further in to reach lower slices
+	hfs := tablePlacement{patientPosition: "HFS", origin: 1000, height: 150}
+	upper, _ := tableValue(hfs.elements(modalities.CT, axial, [3]float64{0, 0, 10}))
+	lower, _ := tableValue(hfs.elements(modalities.CT, axial, [3]float64{0, 0, -10}))
+	if upper != 990 || lower != 1010 {
+		t.Errorf("HFS table positions = %v, %v, want 990, 1010", upper, lower)
+	}
+	ffs := tablePlacement{patientPosition: "FFS", origin: 1000, height: 150}
+	if got, _ := tableValue(ffs.elements(modalities.CT, axial, [3]float64{0, 0, 10})); got != 1010 {
+		t.Errorf("FFS table position = %v, want 1010", got)
+	}
+
+	if _, ok := tableValue(hfs.elements(modalities.CT, sagittal, [3]float64{})); ok {
+		t.Error("sagittal slices should have no table position")
+	}
+	mr := hfs.elements(modalities.MR, axial, [3]float64{})
+	if len(mr) != 1 || mr[0].Tag != tag.PatientPosition {
+		t.Errorf("MR elements = %v, want PatientPosition only", mr)
+	}
+}
+
+func TestLocalizerSeries(t *testing.T) {
+	tests := []struct {
+		modality  modalities.Modality
+		numImages int
+		planes    int
+	}{
+		{modalities.MR, 12, 3},
+		{modalities.CT, 7, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.modality), func(t *testing.T) {
+			files, err := GenerateDICOMSeries(GeneratorOptions{
+				NumImages:      tt.numImages,
+				TotalSize:      "2MB",
+				Modality:       tt.modality,
+				SeriesPerStudy: util.SeriesRange{Min: 3, Max: 3},
+				Localizer:      true,
+				OutputDir:      t.TempDir(),
+				Seed:           42,
+				NumStudies:     1,
+				Quiet:          true,
+			})
+			if err != nil {
+				t.Fatalf("GenerateDICOMSeries failed: %v", err)
+			}
+			if len(files) != tt.numImages {
+				t.Fatalf("%d files, want %d: the localizer counts toward the images", len(files), tt.numImages)
+			}
+
+			// The localizer is series 1, an image per plane
+			localizers := map[string]string{} // SOPInstanceUID -> ImageOrientationPatient
+			var referencing int
+			for _, f := range files {
+				ds, err := dicom.ParseFile(f.Path, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				valueOf := func(tg tag.Tag) string {
+					e, err := ds.FindElementByTag(tg)
+					if err != nil {
+						return ""
+					}
+					return fmt.Sprint(e.Value.GetValue())
+				}
+				if f.SeriesNumber == 1 {
+					if valueOf(tag.ImageType) != "[ORIGINAL PRIMARY LOCALIZER]" {
+						t.Errorf("localizer ImageType %s", valueOf(tag.ImageType))
+					}
+					localizers[f.SOPInstanceUID] = valueOf(tag.ImageOrientationPatient)
+					continue
+				}
+
+				// The other series reference the localizer images they cross
+				refs, err := ds.FindElementByTag(tag.ReferencedImageSequence)
+				if err != nil {
+					continue
+				}
+				referencing++
+				for _, item := range refs.Value.GetValue().([]*dicom.SequenceItemValue) {
+					for _, e := range item.GetValue().([]*dicom.Element) {
+						if e.Tag != tag.ReferencedSOPInstanceUID {
+							continue
+						}
+						uid := e.Value.GetValue().([]string)[0]
+						orientation, ok := localizers[uid]
+						if !ok {
+							t.Errorf("series %d references %s, not a localizer image", f.SeriesNumber, uid)
+						} else if orientation == valueOf(tag.ImageOrientationPatient) {
+							t.Errorf("series %d references a parallel localizer image", f.SeriesNumber)
+						}
+					}
+				}
+			}
+			if len(localizers) != tt.planes {
+				t.Errorf("%d localizer images, want %d", len(localizers), tt.planes)
+			}
+			if referencing == 0 {
+				t.Error("no image references the localizer")
+			}
+		})
+	}
+}
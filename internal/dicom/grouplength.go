@@ -0,0 +1,98 @@
+package dicom
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+	"github.com/suyashkumar/dicom/pkg/uid"
+)
+
+// Group length elements (gggg,0000) give the encoded length of the rest of
+// their group. They were retired outside of the File Meta Information, but
+// old toolkits wrote one per group and archives are full of them.
+
+// withGroupLengths returns the elements sorted by tag, with a group length
+// element before each top-level group but the File Meta Information (which
+// always has one). Lengths are those of the elements encoded in the transfer
+// syntax of the elements; existing group lengths are replaced.
+func withGroupLengths(elements []*dicom.Element) ([]*dicom.Element, error) {
+	transferSyntaxUID := "1.2.840.10008.1.2.1"
+	sorted := make([]*dicom.Element, 0, len(elements))
+	for _, elem := range elements {
+		if elem.Tag == tag.TransferSyntaxUID {
+			if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 {
+				transferSyntaxUID = values[0]
+			}
+		}
+		if elem.Tag.Element != 0x0000 || elem.Tag.Group == 0x0002 {
+			sorted = append(sorted, elem)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Tag.Group != sorted[j].Tag.Group {
+			return sorted[i].Tag.Group < sorted[j].Tag.Group
+		}
+		return sorted[i].Tag.Element < sorted[j].Tag.Element
+	})
+
+	bo, implicit, err := uid.ParseTransferSyntaxUID(transferSyntaxUID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*dicom.Element, 0, len(sorted)+16)
+	for start := 0; start < len(sorted); {
+		group := sorted[start].Tag.Group
+		end := start
+		for end < len(sorted) && sorted[end].Tag.Group == group {
+			end++
+		}
+
+		if group != 0x0002 {
+			var buf bytes.Buffer
+			w, err := dicom.NewWriter(&buf, dicom.SkipVRVerification(), dicom.SkipValueTypeVerification())
+			if err != nil {
+				return nil, err
+			}
+			w.SetTransferSyntax(bo, implicit)
+			for _, elem := range sorted[start:end] {
+				if err := w.WriteElement(elem); err != nil {
+					return nil, fmt.Errorf("encode %v: %w", elem.Tag, err)
+				}
+			}
+			// Built by hand: private groups have no dictionary entry
+			length, err := dicom.NewValue([]int{buf.Len()})
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, &dicom.Element{
+				Tag:                    tag.Tag{Group: group, Element: 0x0000},
+				ValueRepresentation:    tag.VRUInt32List,
+				RawValueRepresentation: "UL",
+				Value:                  length,
+			})
+		}
+		result = append(result, sorted[start:end]...)
+		start = end
+	}
+	return result, nil
+}
+
+// addGroupLengthsToFile rewrites a written DICOM file with group length
+// elements (see withGroupLengths), of its text encoded in its
+// SpecificCharacterSet.
+func addGroupLengthsToFile(path string) error {
+	ds, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	elements, err := withGroupLengths(util.EncodeText(ds.Elements))
+	if err != nil {
+		return fmt.Errorf("group lengths of %s: %w", path, err)
+	}
+	return writeEncodedDatasetToFile(path, dicom.Dataset{Elements: elements}, dicom.SkipVRVerification())
+}
@@ -0,0 +1,75 @@
+package dicom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestWithGroupLengths(t *testing.T) {
+	elements := []*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustNewElement(tag.PatientID, []string{"PID001"}),
+		mustNewElement(tag.Modality, []string{"CT"}),
+		mustNewElement(tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.7"}),
+		mustNewElement(tag.Tag{Group: 0x0010, Element: 0x0000}, []int{999}),
+	}
+
+	result, err := withGroupLengths(elements)
+	if err != nil {
+		t.Fatalf("withGroupLengths failed: %v", err)
+	}
+
+	want := []tag.Tag{
+		tag.TransferSyntaxUID,
+		{Group: 0x0008, Element: 0x0000}, tag.SOPClassUID, tag.Modality,
+		{Group: 0x0010, Element: 0x0000}, tag.PatientID,
+	}
+	if len(result) != len(want) {
+		t.Fatalf("got %d elements, want %d", len(result), len(want))
+	}
+	for i, elem := range result {
+		if elem.Tag != want[i] {
+			t.Errorf("element %d = %v, want %v", i, elem.Tag, want[i])
+		}
+	}
+
+	// (0008,0016) UI 26 bytes + (0008,0060) CS 2 bytes, with 8-byte headers
+	if got := result[1].Value.GetValue().([]int)[0]; got != 44 {
+		t.Errorf("group 0008 length = %d, want 44", got)
+	}
+	// (0010,0020) LO 6 bytes; the previous group length is replaced
+	if got := result[4].Value.GetValue().([]int)[0]; got != 14 {
+		t.Errorf("group 0010 length = %d, want 14", got)
+	}
+}
+
+func TestWithGroupLengths_Written(t *testing.T) {
+	elements, err := withGroupLengths([]*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustNewElement(tag.StudyDescription, []string{"CT CHEST"}),
+		mustNewElement(tag.PatientName, []string{"DOE^JOHN"}),
+	})
+	if err != nil {
+		t.Fatalf("withGroupLengths failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := dicom.Write(&buf, dicom.Dataset{Elements: elements}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data := buf.Bytes()
+
+	// The group length of 0008 ends where group 0010 starts
+	start := bytes.Index(data, []byte{0x08, 0x00, 0x00, 0x00, 'U', 'L', 0x04, 0x00})
+	next := bytes.Index(data, []byte{0x10, 0x00, 0x00, 0x00, 'U', 'L'})
+	if start < 0 || next < 0 {
+		t.Fatal("group length elements not written")
+	}
+	length := int(data[start+8]) | int(data[start+9])<<8
+	if got := next - (start + 12); got != length {
+		t.Errorf("group 0008 length = %d, but the group has %d bytes", length, got)
+	}
+}
@@ -0,0 +1,193 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	randv2 "math/rand/v2"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// GrayscaleSoftcopyPresentationStateStorage is the SOP Class UID of GSPS
+// objects.
+const GrayscaleSoftcopyPresentationStateStorage = "1.2.840.10008.5.1.4.1.1.11.1"
+
+// Graphic layers of the presentation state
+const (
+	gspsMeasurementLayer = "MEASUREMENTS"
+	gspsLabelLayer       = "LABELS"
+)
+
+// gspsWindow is a VOI window applied by a presentation state
+type gspsWindow struct {
+	center, width float64
+	explanation   string
+}
+
+// ctWindows are the usual CT window presets (HU)
+var ctWindows = []gspsWindow{
+	{40, 400, "SOFT TISSUE"},
+	{-600, 1500, "LUNG"},
+	{400, 1800, "BONE"},
+	{40, 80, "BRAIN"},
+	{60, 160, "LIVER"},
+}
+
+// gspsWindowFor returns the window of a presentation state of a series: a CT
+// preset, or a narrower window than the images' own for other modalities.
+func gspsWindowFor(modality modalities.Modality, series seriesRecord, rng *randv2.Rand) gspsWindow {
+	if modality == modalities.CT {
+		return ctWindows[rng.IntN(len(ctWindows))]
+	}
+	return gspsWindow{
+		center:      math.Round(series.windowCenter * (0.8 + 0.4*rng.Float64())),
+		width:       math.Max(1, math.Round(series.windowWidth*(0.4+0.4*rng.Float64()))),
+		explanation: "CONTRAST",
+	}
+}
+
+// writeGSPS writes a Grayscale Softcopy Presentation State on the largest
+// image series of the study: a window, a zoom on part of the images, a label
+// on every image, and a circle with its measured diameter on the middle
+// image.
+func writeGSPS(opts GeneratorOptions, study studyRecord, seriesNumber int, rng *randv2.Rand) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+
+	source, ok := study.largestSeries()
+	if !ok {
+		return GeneratedFile{}, fmt.Errorf("no image series to present")
+	}
+	imageRef := func(uid string) []*dicom.Element {
+		return []*dicom.Element{
+			mustNewElement(tag.ReferencedSOPClassUID, []string{source.sopClassUID}),
+			mustNewElement(tag.ReferencedSOPInstanceUID, []string{uid}),
+		}
+	}
+	allImages := make([][]*dicom.Element, len(source.instanceUIDs))
+	for i, uid := range source.instanceUIDs {
+		allImages[i] = imageRef(uid)
+	}
+
+	window := gspsWindowFor(opts.Modality, source, rng)
+
+	// Displayed area: 50-80% of the image, somewhere around its center
+	// (1-based pixel coordinates, inclusive)
+	zoom := 0.5 + 0.3*rng.Float64()
+	areaColumns := max(1, int(float64(source.columns)*zoom))
+	areaRows := max(1, int(float64(source.rows)*zoom))
+	left := 1 + rng.IntN(source.columns-areaColumns+1)
+	top := 1 + rng.IntN(source.rows-areaRows+1)
+
+	// Circle and its measured diameter inside the displayed area (pixel
+	// coordinates)
+	cx := math.Round(float64(left) + float64(areaColumns)*(0.3+0.4*rng.Float64()))
+	cy := math.Round(float64(top) + float64(areaRows)*(0.3+0.4*rng.Float64()))
+	radius := math.Round(float64(min(areaColumns, areaRows)) * (0.05 + 0.1*rng.Float64()))
+	lineEnd := [2]float64{cx + radius, cy}
+	length := 2 * radius * source.pixelSpacing
+	middle := source.instanceUIDs[len(source.instanceUIDs)/2]
+
+	elements := study.headerElements("PR", seriesUID, seriesNumber, "Presentation State")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{GrayscaleSoftcopyPresentationStateStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+		mustNewElement(tag.ReferencedSeriesSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ReferencedImageSequence, allImages),
+			mustNewElement(tag.SeriesInstanceUID, []string{source.seriesUID}),
+		}}),
+		mustNewElement(tag.SoftcopyVOILUTSequence, [][]*dicom.Element{{
+			mustNewElement(tag.WindowCenter, []string{util.FormatDS(window.center)}),
+			mustNewElement(tag.WindowWidth, []string{util.FormatDS(window.width)}),
+			mustNewElement(tag.WindowCenterWidthExplanation, []string{window.explanation}),
+		}}),
+		mustNewElement(tag.PresentationLUTShape, []string{"IDENTITY"}),
+		mustNewElement(tag.GraphicAnnotationSequence, [][]*dicom.Element{
+			{
+				mustNewElement(tag.ReferencedImageSequence, allImages),
+				mustNewElement(tag.GraphicLayer, []string{gspsLabelLayer}),
+				mustNewElement(tag.TextObjectSequence, [][]*dicom.Element{{
+					mustNewElement(tag.BoundingBoxAnnotationUnits, []string{"DISPLAY"}),
+					mustNewElement(tag.UnformattedTextValue, []string{fmt.Sprintf("%s W:%s L:%s", window.explanation, util.FormatDS(window.width), util.FormatDS(window.center))}),
+					mustNewElement(tag.BoundingBoxTopLeftHandCorner, []float64{0.02, 0.02}),
+					mustNewElement(tag.BoundingBoxBottomRightHandCorner, []float64{0.4, 0.07}),
+					mustNewElement(tag.BoundingBoxTextHorizontalJustification, []string{"LEFT"}),
+				}}),
+			},
+			{
+				mustNewElement(tag.ReferencedImageSequence, [][]*dicom.Element{imageRef(middle)}),
+				mustNewElement(tag.GraphicLayer, []string{gspsMeasurementLayer}),
+				mustNewElement(tag.TextObjectSequence, [][]*dicom.Element{{
+					mustNewElement(tag.AnchorPointAnnotationUnits, []string{"PIXEL"}),
+					mustNewElement(tag.UnformattedTextValue, []string{fmt.Sprintf("%.1f mm", length)}),
+					mustNewElement(tag.AnchorPoint, []float64{lineEnd[0], lineEnd[1]}),
+					mustNewElement(tag.AnchorPointVisibility, []string{"Y"}),
+				}}),
+				mustNewElement(tag.GraphicObjectSequence, [][]*dicom.Element{
+					{
+						mustNewElement(tag.GraphicAnnotationUnits, []string{"PIXEL"}),
+						mustNewElement(tag.GraphicDimensions, []int{2}),
+						mustNewElement(tag.NumberOfGraphicPoints, []int{2}),
+						mustNewElement(tag.GraphicData, []float64{cx, cy, cx + radius, cy}),
+						mustNewElement(tag.GraphicType, []string{"CIRCLE"}),
+						mustNewElement(tag.GraphicFilled, []string{"N"}),
+					},
+					{
+						mustNewElement(tag.GraphicAnnotationUnits, []string{"PIXEL"}),
+						mustNewElement(tag.GraphicDimensions, []int{2}),
+						mustNewElement(tag.NumberOfGraphicPoints, []int{2}),
+						mustNewElement(tag.GraphicData, []float64{cx - radius, cy, lineEnd[0], lineEnd[1]}),
+						mustNewElement(tag.GraphicType, []string{"POLYLINE"}),
+						mustNewElement(tag.GraphicFilled, []string{"N"}),
+					},
+				}),
+			},
+		}),
+		mustNewElement(tag.DisplayedAreaSelectionSequence, [][]*dicom.Element{{
+			mustNewElement(tag.DisplayedAreaTopLeftHandCorner, []int{left, top}),
+			mustNewElement(tag.DisplayedAreaBottomRightHandCorner, []int{left + areaColumns - 1, top + areaRows - 1}),
+			mustNewElement(tag.PresentationSizeMode, []string{"SCALE TO FIT"}),
+			mustNewElement(tag.PresentationPixelSpacing, []string{util.FormatDS(source.pixelSpacing), util.FormatDS(source.pixelSpacing)}),
+		}}),
+		mustNewElement(tag.GraphicLayerSequence, [][]*dicom.Element{
+			{
+				mustNewElement(tag.GraphicLayer, []string{gspsMeasurementLayer}),
+				mustNewElement(tag.GraphicLayerOrder, []string{"1"}),
+				mustNewElement(tag.GraphicLayerRecommendedDisplayGrayscaleValue, []int{0xFFFF}),
+				mustNewElement(tag.GraphicLayerDescription, []string{"Measurements"}),
+			},
+			{
+				mustNewElement(tag.GraphicLayer, []string{gspsLabelLayer}),
+				mustNewElement(tag.GraphicLayerOrder, []string{"2"}),
+				mustNewElement(tag.GraphicLayerRecommendedDisplayGrayscaleValue, []int{0xFFFF}),
+				mustNewElement(tag.GraphicLayerDescription, []string{"Labels"}),
+			},
+		}),
+		mustNewElement(tag.ContentLabel, []string{"ANNOTATIONS"}),
+		mustNewElement(tag.ContentDescription, []string{"Window, zoom and measurement"}),
+		mustNewElement(tag.PresentationCreationDate, []string{study.studyDate}),
+		mustNewElement(tag.PresentationCreationTime, []string{study.studyTime}),
+		mustNewElement(tag.ContentCreatorName, []string{""}),
+	)
+	// Modality LUT of the images (CT only)
+	if source.rescaleSlope != 0 {
+		elements = append(elements,
+			mustNewElement(tag.RescaleIntercept, []string{util.FormatDS(source.rescaleIntercept)}),
+			mustNewElement(tag.RescaleSlope, []string{util.FormatDS(source.rescaleSlope)}),
+			mustNewElement(tag.RescaleType, []string{"HU"}),
+		)
+	}
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "PR", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
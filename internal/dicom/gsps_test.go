@@ -0,0 +1,29 @@
+package dicom
+
+import (
+	randv2 "math/rand/v2"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+)
+
+func TestGSPSWindowFor(t *testing.T) {
+	rng := randv2.New(randv2.NewPCG(1, 2))
+	series := seriesRecord{windowCenter: 1000, windowWidth: 2000}
+
+	for i := 0; i < 20; i++ {
+		ct := gspsWindowFor(modalities.CT, series, rng)
+		found := false
+		for _, preset := range ctWindows {
+			found = found || ct == preset
+		}
+		if !found {
+			t.Errorf("CT window %+v is not a preset", ct)
+		}
+
+		mr := gspsWindowFor(modalities.MR, series, rng)
+		if mr.center < 800 || mr.center > 1200 || mr.width < 800 || mr.width > 1600 {
+			t.Errorf("MR window %+v, want around the image window and narrower", mr)
+		}
+	}
+}
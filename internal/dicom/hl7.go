@@ -0,0 +1,324 @@
+package dicom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+)
+
+// HL7Dir is the directory of the HL7 v2 messages, inside the output
+// directory.
+const HL7Dir = "HL7"
+
+// hl7Application is the sending application of the HL7 messages.
+const hl7Application = "DICOMFORGE"
+
+// ExportHL7 writes the HL7 v2.3.1 messages of each study of the PT*/ST*/SE*
+// hierarchy of outputDir, as the RIS exchanges them around the images:
+// HL7/ORMnnnnnn.hl7, the ORM^O01 new order scheduled at the StudyDate and
+// StudyTime, and HL7/ORUnnnnnn.hl7, the ORU^R01 final report, with the text
+// of the study's Basic Text SR when it has one (--text-sr). Both carry the
+// patient demographics, the visit and order numbers, the accession number
+// (OBR-18), the requested procedure and scheduled procedure step IDs of the
+// --worklist item of the study (OBR-19, OBR-20) and its Study Instance UID
+// (ZDS-1, as IHE Scheduled Workflow does). Messages are UTF-8, segments end
+// with a carriage return. Unchanged files are kept (rerun of the same
+// profile). It returns the number of studies.
+func ExportHL7(outputDir string, quiet bool) (int, error) {
+	dir := filepath.Join(outputDir, HL7Dir)
+	n := 0
+	for _, patientDir := range hierarchyEntries(outputDir, "PT") {
+		for _, studyDir := range hierarchyEntries(patientDir, "ST") {
+			study, err := acquiredInstance(studyDir)
+			if err != nil {
+				return n, err
+			}
+			if study == nil {
+				continue
+			}
+			report, err := studyReport(studyDir)
+			if err != nil {
+				return n, err
+			}
+			n++
+			if n == 1 {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return 0, fmt.Errorf("create directory: %w", err)
+				}
+			}
+			messages := map[string]string{
+				fmt.Sprintf("ORM%06d.hl7", n): hl7Order(*study, n),
+				fmt.Sprintf("ORU%06d.hl7", n): hl7Result(*study, report, n),
+			}
+			for name, message := range messages {
+				dest := filepath.Join(dir, name)
+				tmpPath := dest + ".tmp"
+				if err := os.WriteFile(tmpPath, []byte(message), 0644); err != nil {
+					return n, fmt.Errorf("write %s: %w", tmpPath, err)
+				}
+				if _, err := moveIfChanged(tmpPath, dest); err != nil {
+					return n, err
+				}
+			}
+		}
+	}
+
+	if !quiet {
+		fmt.Printf("  Exported HL7 ORM/ORU messages of %d studies into %s/\n", n, HL7Dir)
+	}
+	return n, nil
+}
+
+// hl7Report is the report of a study: its lines, and when it was written.
+type hl7Report struct {
+	lines      []string
+	date, time string // Empty when the study has no report
+}
+
+// studyReport returns the report of the first Basic Text SR of a study
+// directory, as lines: a heading per section, then its text.
+func studyReport(studyDir string) (hl7Report, error) {
+	for _, seriesDir := range hierarchyEntries(studyDir, "SE") {
+		for _, path := range hierarchyEntries(seriesDir, "IM") {
+			ds, err := parseDICOMTolerant(path)
+			if err != nil {
+				return hl7Report{}, fmt.Errorf("parse %s: %w", path, err)
+			}
+			if getStringValue(ds, tag.SOPClassUID)[0] != sr.BasicTextSRStorage {
+				break // Series of another kind
+			}
+			var report hl7Report
+			if elem, err := ds.FindElementByTag(tag.ContentSequence); err == nil {
+				report.lines = reportLines(sequenceElements(elem))
+			}
+			report.date, report.time = getStringValue(ds, tag.ContentDate)[0], getStringValue(ds, tag.ContentTime)[0]
+			return report, nil
+		}
+	}
+	return hl7Report{}, nil
+}
+
+// reportLines returns the lines of SR content items: the concept name of
+// containers, upper case, and the value of TEXT items.
+func reportLines(items [][]*dicom.Element) []string {
+	var lines []string
+	for _, item := range items {
+		ds := dicom.Dataset{Elements: item}
+		switch getStringValue(ds, tag.ValueType)[0] {
+		case "CONTAINER":
+			if elem, err := ds.FindElementByTag(tag.ConceptNameCodeSequence); err == nil {
+				if concept := sequenceElements(elem); len(concept) > 0 {
+					lines = append(lines, strings.ToUpper(getStringValue(dicom.Dataset{Elements: concept[0]}, tag.CodeMeaning)[0])+":")
+				}
+			}
+		case "TEXT":
+			lines = append(lines, getStringValue(ds, tag.TextValue)[0])
+		}
+		if elem, err := ds.FindElementByTag(tag.ContentSequence); err == nil {
+			lines = append(lines, reportLines(sequenceElements(elem))...)
+		}
+	}
+	return lines
+}
+
+// sequenceElements returns the elements of each item of a sequence element.
+func sequenceElements(elem *dicom.Element) [][]*dicom.Element {
+	items, ok := elem.Value.GetValue().([]*dicom.SequenceItemValue)
+	if !ok {
+		return nil
+	}
+	var elements [][]*dicom.Element
+	for _, item := range items {
+		elements = append(elements, item.GetValue().([]*dicom.Element))
+	}
+	return elements
+}
+
+// hl7Order builds the ORM^O01 message of study n.
+func hl7Order(study dicom.Dataset, n int) string {
+	scheduled := hl7Timestamp(getStringValue(study, tag.StudyDate)[0], getStringValue(study, tag.StudyTime)[0])
+	segments := append(hl7Header(study, "RIS", "ORM^O01", fmt.Sprintf("ORM%06d", n), scheduled),
+		hl7Segment("ORC", map[int]string{
+			1:  "NW",
+			2:  hl7Value(study, tag.PlacerOrderNumberImagingServiceRequest),
+			3:  hl7Value(study, tag.FillerOrderNumberImagingServiceRequest),
+			5:  "SC",
+			7:  "^^^" + scheduled,
+			12: hl7Doctor(study),
+		}),
+		hl7Segment("OBR", hl7Request(study, n, map[int]string{
+			27: "^^^" + scheduled,
+		})),
+		hl7Segment("ZDS", map[int]string{
+			1: hl7Escape(getStringValue(study, tag.StudyInstanceUID)[0]) + "^" + hl7Application + "^Application^DICOM",
+		}),
+	)
+	return strings.Join(segments, "\r") + "\r"
+}
+
+// hl7Result builds the ORU^R01 message of study n, holding its report.
+func hl7Result(study dicom.Dataset, report hl7Report, n int) string {
+	observed := hl7Timestamp(getStringValue(study, tag.StudyDate)[0], getStringValue(study, tag.StudyTime)[0])
+	reported := observed
+	if report.date != "" {
+		reported = hl7Timestamp(report.date, report.time)
+	}
+	lines := report.lines
+	if len(lines) == 0 {
+		description := getStringValue(study, tag.StudyDescription)[0]
+		if description == "" {
+			description = "Imaging examination"
+		}
+		lines = []string{"EXAMINATION:", description + " performed.", "IMPRESSION:", "No report text available."}
+	}
+
+	segments := append(hl7Header(study, "EMR", "ORU^R01", fmt.Sprintf("ORU%06d", n), reported),
+		hl7Segment("ORC", map[int]string{
+			1:  "RE",
+			2:  hl7Value(study, tag.PlacerOrderNumberImagingServiceRequest),
+			3:  hl7Value(study, tag.FillerOrderNumberImagingServiceRequest),
+			5:  "CM",
+			12: hl7Doctor(study),
+		}),
+		hl7Segment("OBR", hl7Request(study, n, map[int]string{
+			7:  observed,
+			22: reported,
+			25: "F",
+		})),
+	)
+	for i, line := range lines {
+		segments = append(segments, hl7Segment("OBX", map[int]string{
+			1: strconv.Itoa(i + 1), 2: "TX", 3: "^Report Text", 5: hl7Escape(line), 11: "F",
+		}))
+	}
+	segments = append(segments, hl7Segment("OBX", map[int]string{
+		1: strconv.Itoa(len(lines) + 1), 2: "HD", 3: "^Study Instance UID", 5: hl7Value(study, tag.StudyInstanceUID), 11: "F",
+	}))
+	return strings.Join(segments, "\r") + "\r"
+}
+
+// hl7Header returns the MSH, PID and PV1 segments of a message about a
+// study, sent to receiver at timestamp.
+func hl7Header(study dicom.Dataset, receiver, messageType, controlID, timestamp string) []string {
+	facility := hl7Value(study, tag.InstitutionName)
+	patientID := hl7Value(study, tag.PatientID)
+	if issuer := hl7Value(study, tag.IssuerOfPatientID); issuer != "" {
+		patientID += "^^^" + issuer
+	}
+	visit := hl7Value(study, tag.AdmissionID)
+	if visit != "" {
+		visit += "^^^" + linkageIssuer
+	}
+	// MSH-1 is the field separator itself, so fields are shifted by one
+	return []string{
+		hl7Segment("MSH", map[int]string{
+			1: `^~\&`, 2: hl7Application, 3: facility, 4: receiver, 5: facility,
+			6: timestamp, 8: messageType, 9: controlID, 10: "P", 11: "2.3.1", 17: "UNICODE UTF-8",
+		}),
+		hl7Segment("PID", map[int]string{
+			1: "1",
+			3: patientID,
+			5: hl7Name(getStringValue(study, tag.PatientName)[0]),
+			7: hl7Value(study, tag.PatientBirthDate),
+			8: hl7Value(study, tag.PatientSex),
+		}),
+		hl7Segment("PV1", map[int]string{
+			1:  "1",
+			2:  "O",
+			8:  hl7Doctor(study),
+			19: visit,
+		}),
+	}
+}
+
+// hl7Request returns the OBR fields of the imaging service request of study
+// n, with extra fields.
+func hl7Request(study dicom.Dataset, n int, extra map[int]string) map[int]string {
+	description := hl7Value(study, tag.RequestedProcedureDescription)
+	if description == "" {
+		description = hl7Value(study, tag.StudyDescription)
+	}
+	fields := map[int]string{
+		1:  "1",
+		2:  hl7Value(study, tag.PlacerOrderNumberImagingServiceRequest),
+		3:  hl7Value(study, tag.FillerOrderNumberImagingServiceRequest),
+		4:  "^" + description,
+		16: hl7Doctor(study),
+		18: hl7Value(study, tag.AccessionNumber),
+		19: fmt.Sprintf("RP%06d", n),
+		20: fmt.Sprintf("SPS%06d", n),
+		24: hl7Value(study, tag.Modality),
+	}
+	for i, v := range extra {
+		fields[i] = v
+	}
+	return fields
+}
+
+// hl7Segment builds a segment from its fields by position, empty between
+// them. Trailing empty fields are left out.
+func hl7Segment(name string, fields map[int]string) string {
+	last := 0
+	for i, v := range fields {
+		if v != "" {
+			last = max(last, i)
+		}
+	}
+	values := make([]string, last+1)
+	values[0] = name
+	for i, v := range fields {
+		if i <= last {
+			values[i] = v
+		}
+	}
+	return strings.Join(values, "|")
+}
+
+// hl7Value returns the escaped value of an element, "" when absent.
+func hl7Value(ds dicom.Dataset, t tag.Tag) string {
+	return hl7Escape(getStringValue(ds, t)[0])
+}
+
+// hl7Escape escapes the HL7 delimiters of a value (HL7 v2 2.7.4).
+func hl7Escape(value string) string {
+	return strings.NewReplacer(`\`, `\E\`, "|", `\F\`, "^", `\S\`, "&", `\T\`, "~", `\R\`, "\r", `\X0D\`, "\n", `\X0A\`).Replace(value)
+}
+
+// hl7Name converts a DICOM person name, family^given^middle^prefix^suffix,
+// to an HL7 XPN, family^given^middle^suffix^prefix, escaped. Only the
+// alphabetic representation is kept.
+func hl7Name(name string) string {
+	name, _, _ = strings.Cut(name, "=")
+	components := strings.Split(name, "^")
+	for len(components) < 5 {
+		components = append(components, "")
+	}
+	components[3], components[4] = components[4], components[3]
+	for i, c := range components {
+		components[i] = hl7Escape(strings.TrimSpace(c))
+	}
+	return strings.TrimRight(strings.Join(components[:5], "^"), "^")
+}
+
+// hl7Doctor returns the referring physician of a study as an HL7 XCN,
+// without ID number.
+func hl7Doctor(study dicom.Dataset) string {
+	name := hl7Name(getStringValue(study, tag.ReferringPhysicianName)[0])
+	if name == "" {
+		return ""
+	}
+	return "^" + name
+}
+
+// hl7Timestamp returns a DICOM date and time as an HL7 TS, to the second.
+func hl7Timestamp(date, time string) string {
+	time, _, _ = strings.Cut(time, ".")
+	return date + time
+}
@@ -0,0 +1,125 @@
+package dicom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hl7Fields returns the fields of the first segment of a message with the
+// given name, or nil.
+func hl7Fields(message, name string) []string {
+	for _, segment := range strings.Split(message, "\r") {
+		if fields := strings.Split(segment, "|"); fields[0] == name {
+			return fields
+		}
+	}
+	return nil
+}
+
+// hl7Field returns field i of a segment, or "".
+func hl7Field(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+func TestExportHL7(t *testing.T) {
+	outputDir := t.TempDir()
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "1MB",
+		OutputDir:   outputDir,
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 2,
+		LinkageIDs:  true,
+		TextSR:      true,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := OrganizeFilesIntoDICOMDIR(outputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	n, err := ExportHL7(outputDir, true)
+	if err != nil {
+		t.Fatalf("ExportHL7 failed: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("exported %d studies, want 2", n)
+	}
+
+	studies := make(map[string]GeneratedFile)
+	for _, f := range files {
+		studies[f.StudyUID] = f
+	}
+	for i := 1; i <= n; i++ {
+		orm, err := os.ReadFile(filepath.Join(outputDir, HL7Dir, fmt.Sprintf("ORM%06d.hl7", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		oru, err := os.ReadFile(filepath.Join(outputDir, HL7Dir, fmt.Sprintf("ORU%06d.hl7", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := hl7Field(hl7Fields(string(orm), "MSH"), 8); got != "ORM^O01" {
+			t.Errorf("ORM message type %q", got)
+		}
+		if got := hl7Field(hl7Fields(string(oru), "MSH"), 8); got != "ORU^R01" {
+			t.Errorf("ORU message type %q", got)
+		}
+		studyUID, _, _ := strings.Cut(hl7Field(hl7Fields(string(orm), "ZDS"), 1), "^")
+		study, ok := studies[studyUID]
+		if !ok {
+			t.Fatalf("ORM %d orders unknown study %q", i, studyUID)
+		}
+
+		// Both messages carry the same patient and order as the images
+		for _, message := range []string{string(orm), string(oru)} {
+			if got, _, _ := strings.Cut(hl7Field(hl7Fields(message, "PID"), 3), "^"); got != study.PatientID {
+				t.Errorf("PID-3 %q, want %q", got, study.PatientID)
+			}
+			obr := hl7Fields(message, "OBR")
+			if hl7Field(obr, 18) == "" || hl7Field(obr, 2) == "" || hl7Field(obr, 3) == "" {
+				t.Errorf("OBR has no accession or order numbers: %v", obr)
+			}
+			if got, want := hl7Field(obr, 20), fmt.Sprintf("SPS%06d", i); got != want {
+				t.Errorf("OBR-20 %q, want %q", got, want)
+			}
+			if hl7Field(hl7Fields(message, "PV1"), 19) == "" {
+				t.Error("PV1 has no visit number")
+			}
+		}
+		if !strings.Contains(string(oru), "|TX|^Report Text||FINDINGS:|") || !strings.Contains(string(oru), "|HD|^Study Instance UID||"+studyUID+"|") {
+			t.Errorf("ORU has no report text or study UID:\n%s", strings.ReplaceAll(string(oru), "\r", "\n"))
+		}
+	}
+}
+
+func TestHL7Escaping(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"delimiters", hl7Escape(`A|B^C&D~E\F`), `A\F\B\S\C\T\D\R\E\E\F`},
+		{"name", hl7Name("Doe^John^Q^Dr^Jr"), "Doe^John^Q^Jr^Dr"},
+		{"short name", hl7Name("Doe^John"), "Doe^John"},
+		{"ideographic name", hl7Name("Yamada^Tarou=山田^太郎"), "Yamada^Tarou"},
+		{"escaped name", hl7Name("O&Brien^Anne"), `O\T\Brien^Anne`},
+		{"timestamp", hl7Timestamp("20240102", "093015.123456"), "20240102093015"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
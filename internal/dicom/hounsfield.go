@@ -0,0 +1,49 @@
+package dicom
+
+import (
+	"math"
+	randv2 "math/rand/v2"
+)
+
+// Hounsfield units of the synthetic CT images, stored with the -1024
+// RescaleIntercept of the modality
+const (
+	huAir         = -1000
+	huSoftTissue  = 40 // Center of the 0-80 HU soft tissue range
+	huBone        = 300
+	huBoneDensest = 1500
+	huMax         = 3071 // Largest value of 12 bits above the intercept
+)
+
+// Semi-axes of the body of the synthetic CT images, as fractions of their
+// width and height, and the radii (fractions of the body) of its bone ring
+const (
+	ctBodyA, ctBodyB         = 0.42, 0.36
+	ctBoneInner, ctBoneOuter = 0.84, 0.92
+)
+
+// ctHU returns the Hounsfield units of pixel (x, y) of a width x height CT
+// image: air around an elliptic body of soft tissue (0-80 HU, denser toward
+// the center) within a ring of bone (300 HU and more), with noise of a few
+// HU.
+func ctHU(x, y, width, height int, rng *randv2.Rand) float64 {
+	dx := (float64(x) + 0.5 - float64(width)/2) / (ctBodyA * float64(width))
+	dy := (float64(y) + 0.5 - float64(height)/2) / (ctBodyB * float64(height))
+	r := math.Sqrt(dx*dx + dy*dy)
+	switch {
+	case r > 1:
+		return huAir + rng.NormFloat64()*5
+	case r > ctBoneInner && r <= ctBoneOuter:
+		// Cortical bone at the middle of the ring, spongy at its edges
+		cortical := 1 - math.Abs(2*(r-ctBoneInner)/(ctBoneOuter-ctBoneInner)-1)
+		return huBone + cortical*(huBoneDensest-huBone) + rng.NormFloat64()*40
+	}
+	return huSoftTissue + (0.5-r)*40 + rng.NormFloat64()*8
+}
+
+// ctValue returns the stored value of pixel (x, y) of a width x height CT
+// image, ctHU shifted by the intercept (baseValue, the stored value of 0
+// HU).
+func ctValue(x, y, width, height int, baseValue float64, rng *randv2.Rand) float64 {
+	return math.Min(ctHU(x, y, width, height, rng), huMax) + baseValue
+}
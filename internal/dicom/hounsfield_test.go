@@ -0,0 +1,67 @@
+package dicom
+
+import (
+	randv2 "math/rand/v2"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestCTHU(t *testing.T) {
+	rng := randv2.New(randv2.NewPCG(1, 1))
+	const size = 200
+	for range 100 {
+		if hu := ctHU(0, 0, size, size, rng); hu < -1030 || hu > -970 {
+			t.Fatalf("air is %v HU, want about -1000", hu)
+		}
+		if hu := ctHU(size/2, size/2, size, size, rng); hu < 0 || hu > 80 {
+			t.Fatalf("soft tissue is %v HU, want 0-80", hu)
+		}
+		// Middle of the bone ring, at the side of the body (0.88 x 0.42 x 200)
+		if hu := ctHU(size/2+74, size/2, size, size, rng); hu < huBone {
+			t.Fatalf("bone is %v HU, want %d or more", hu, huBone)
+		}
+	}
+}
+
+func TestCTPixels(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:  1,
+		TotalSize:  "1MB",
+		Modality:   modalities.CT,
+		OutputDir:  t.TempDir(),
+		Seed:       42,
+		NumStudies: 1,
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ds, err := dicom.ParseFile(files[0].Path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := elementString(ds.Elements, tag.RescaleIntercept); got != "-1024" {
+		t.Fatalf("RescaleIntercept = %q, want -1024", got)
+	}
+	pixelData, err := ds.FindElementByTag(tag.PixelData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, err := dicom.MustGetPixelDataInfo(pixelData.Value).Frames[0].GetNativeFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pixels, cols := native.RawDataSlice().([]uint16), native.Cols()
+	hu := func(x, y int) int { return int(pixels[y*cols+x]) - 1024 }
+
+	// Values in HU once rescaled, not reduced to 8-bit levels by the overlay
+	if got := hu(0, 0); got < -1030 || got > -970 {
+		t.Errorf("corner is %d HU, want air", got)
+	}
+	if got := hu(cols/2, cols/4); got < 0 || got > 80 {
+		t.Errorf("body is %d HU, want soft tissue", got)
+	}
+}
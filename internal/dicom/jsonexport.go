@@ -0,0 +1,52 @@
+package dicom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/dicomjson"
+)
+
+// JSONDir is the directory of the DICOM JSON export, inside the output
+// directory. It does not match the PT* pattern of the DICOMDIR hierarchy.
+const JSONDir = "JSON"
+
+// ExportJSON writes each instance of the PT*/ST*/SE* hierarchy of outputDir
+// in the DICOM JSON model (PS3.18), as JSON/<same relative path>.json.
+// PixelData is left out, as in WADO-RS metadata. Unchanged files are kept
+// (rerun of the same profile). It returns the number of exported instances.
+func ExportJSON(outputDir string, quiet bool) (int, error) {
+	imageFiles := hierarchyImages(outputDir)
+	for _, path := range imageFiles {
+		ds, err := parseDICOMTolerant(path)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s: %w", path, err)
+		}
+		data, err := dicomjson.Marshal(ds, dicomjson.Options{})
+		if err != nil {
+			return 0, fmt.Errorf("encode %s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return 0, err
+		}
+		dest := filepath.Join(outputDir, JSONDir, rel+".json")
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return 0, fmt.Errorf("create directory: %w", err)
+		}
+		tmpPath := dest + ".tmp"
+		if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+			return 0, fmt.Errorf("write %s: %w", tmpPath, err)
+		}
+		if _, err := moveIfChanged(tmpPath, dest); err != nil {
+			return 0, err
+		}
+	}
+
+	if !quiet {
+		fmt.Printf("  Exported %d instances as DICOM JSON into %s/\n", len(imageFiles), JSONDir)
+	}
+	return len(imageFiles), nil
+}
@@ -0,0 +1,112 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	randv2 "math/rand/v2"
+
+	"github.com/suyashkumar/dicom/pkg/frame"
+)
+
+// lesion is a synthetic finding inserted into the pixel data of a series,
+// used as ground truth for simulated AI results.
+type lesion struct {
+	trackingID  string  // Human-readable identifier ("Lesion 1")
+	centerX     float64 // Center column (pixels)
+	centerY     float64 // Center row (pixels)
+	radius      float64 // Radius (pixels)
+	firstSlice  int     // First instance number containing the lesion
+	lastSlice   int     // Last instance number containing the lesion
+	centerSlice int     // Instance number of the largest cross-section
+	confidence  float64 // Simulated AI detection confidence (0-1)
+	uncertainty float64 // Simulated diameter uncertainty (mm, one standard deviation)
+}
+
+// contains reports whether the lesion appears on the given instance.
+func (l lesion) contains(instance int) bool {
+	return instance >= l.firstSlice && instance <= l.lastSlice
+}
+
+// radiusAt returns the lesion radius on an instance: full size on the
+// central slice, shrinking on neighboring slices.
+func (l lesion) radiusAt(instance int) float64 {
+	if !l.contains(instance) {
+		return 0
+	}
+	span := float64(max(l.centerSlice-l.firstSlice, l.lastSlice-l.centerSlice) + 1)
+	offset := math.Abs(float64(instance-l.centerSlice)) / span
+	return l.radius * math.Sqrt(1-offset*offset)
+}
+
+// planLesions places 1-3 lesions in a series of numImages images.
+func planLesions(width, height, numImages int, rng *randv2.Rand) []lesion {
+	if numImages < 1 {
+		return nil
+	}
+
+	count := rng.IntN(3) + 1
+	minDim := float64(min(width, height))
+	lesions := make([]lesion, count)
+	for i := range lesions {
+		center := rng.IntN(numImages) + 1
+		halfExtent := rng.IntN(3)
+		lesions[i] = lesion{
+			trackingID:  fmt.Sprintf("Lesion %d", i+1),
+			centerX:     float64(width) * (0.25 + rng.Float64()*0.5),
+			centerY:     float64(height) * (0.25 + rng.Float64()*0.5),
+			radius:      math.Max(2, minDim*(0.03+rng.Float64()*0.05)),
+			firstSlice:  max(1, center-halfExtent),
+			lastSlice:   min(numImages, center+halfExtent),
+			centerSlice: center,
+			confidence:  0.55 + rng.Float64()*0.44,
+			uncertainty: 0.5 + rng.Float64()*1.5,
+		}
+	}
+	return lesions
+}
+
+// lesionBoost returns the relative intensity increase (0-1) of the lesions at
+// a pixel of an instance, with a smooth falloff toward the lesion edge.
+func lesionBoost(lesions []lesion, instance int, x, y float64) float64 {
+	boost := 0.0
+	for _, l := range lesions {
+		r := l.radiusAt(instance)
+		if r <= 0 {
+			continue
+		}
+		dx, dy := x-l.centerX, y-l.centerY
+		d2 := (dx*dx + dy*dy) / (r * r)
+		if d2 < 1 {
+			boost = math.Max(boost, 1-d2)
+		}
+	}
+	return boost
+}
+
+// drawLesions8 brightens the lesion areas of an 8-bit frame.
+func drawLesions8(nativeFrame *frame.NativeFrame[uint8], width, height, instance int, lesions []lesion, valueRange float64) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			boost := lesionBoost(lesions, instance, float64(x), float64(y))
+			if boost == 0 {
+				continue
+			}
+			v := float64(nativeFrame.RawData[y*width+x]) + boost*valueRange*0.4
+			nativeFrame.RawData[y*width+x] = uint8(math.Min(v, math.MaxUint8))
+		}
+	}
+}
+
+// drawLesions16 brightens the lesion areas of a 16-bit frame, clamped to maxValue.
+func drawLesions16(nativeFrame *frame.NativeFrame[uint16], width, height, instance int, lesions []lesion, valueRange, maxValue float64) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			boost := lesionBoost(lesions, instance, float64(x), float64(y))
+			if boost == 0 {
+				continue
+			}
+			v := float64(nativeFrame.RawData[y*width+x]) + boost*valueRange*0.4
+			nativeFrame.RawData[y*width+x] = uint16(math.Min(v, maxValue))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package dicom
+
+import (
+	randv2 "math/rand/v2"
+	"testing"
+)
+
+func TestPlanLesions_Bounds(t *testing.T) {
+	for seed := uint64(0); seed < 50; seed++ {
+		rng := randv2.New(randv2.NewPCG(seed, 1))
+		lesions := planLesions(256, 128, 10, rng)
+		if len(lesions) < 1 || len(lesions) > 3 {
+			t.Fatalf("seed %d: got %d lesions, want 1-3", seed, len(lesions))
+		}
+		for _, l := range lesions {
+			if l.firstSlice < 1 || l.lastSlice > 10 || l.centerSlice < l.firstSlice || l.centerSlice > l.lastSlice {
+				t.Errorf("seed %d: %s slices %d-%d (center %d) out of range", seed, l.trackingID, l.firstSlice, l.lastSlice, l.centerSlice)
+			}
+			if l.centerX-l.radius < 0 || l.centerX+l.radius > 256 || l.centerY-l.radius < 0 || l.centerY+l.radius > 128 {
+				t.Errorf("seed %d: %s extends outside the image", seed, l.trackingID)
+			}
+			if l.confidence < 0.5 || l.confidence >= 1 {
+				t.Errorf("seed %d: confidence %v out of range", seed, l.confidence)
+			}
+		}
+	}
+
+	if lesions := planLesions(64, 64, 0, randv2.New(randv2.NewPCG(1, 1))); lesions != nil {
+		t.Errorf("empty series should have no lesions, got %d", len(lesions))
+	}
+}
+
+func TestLesion_RadiusAt(t *testing.T) {
+	l := lesion{radius: 10, firstSlice: 3, lastSlice: 7, centerSlice: 5}
+
+	if got := l.radiusAt(5); got != 10 {
+		t.Errorf("central slice radius = %v, want 10", got)
+	}
+	if got := l.radiusAt(4); got <= 0 || got >= 10 {
+		t.Errorf("neighbor slice radius = %v, want between 0 and 10", got)
+	}
+	if l.radiusAt(4) != l.radiusAt(6) {
+		t.Error("radius should be symmetric around the central slice")
+	}
+	if got := l.radiusAt(2); got != 0 {
+		t.Errorf("radius outside the lesion = %v, want 0", got)
+	}
+}
+
+func TestLesionBoost(t *testing.T) {
+	lesions := []lesion{{centerX: 20, centerY: 20, radius: 5, firstSlice: 1, lastSlice: 1, centerSlice: 1}}
+
+	if got := lesionBoost(lesions, 1, 20, 20); got != 1 {
+		t.Errorf("boost at center = %v, want 1", got)
+	}
+	if got := lesionBoost(lesions, 1, 30, 20); got != 0 {
+		t.Errorf("boost outside = %v, want 0", got)
+	}
+	if got := lesionBoost(lesions, 2, 20, 20); got != 0 {
+		t.Errorf("boost on another slice = %v, want 0", got)
+	}
+}
+
+func TestParseAIResultTypes(t *testing.T) {
+	types, err := ParseAIResultTypes("all")
+	if err != nil || len(types) != 2 {
+		t.Errorf("all: got %v, %v", types, err)
+	}
+	types, err = ParseAIResultTypes(" SC ")
+	if err != nil || len(types) != 1 || types[0] != AIResultSC {
+		t.Errorf("SC: got %v, %v", types, err)
+	}
+	if types, err := ParseAIResultTypes(""); err != nil || types != nil {
+		t.Errorf("empty: got %v, %v", types, err)
+	}
+	if _, err := ParseAIResultTypes("sr,seg"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestHeatmapPixel(t *testing.T) {
+	if r, g, b := heatmapPixel(100, 0); r != 100 || g != 100 || b != 100 {
+		t.Errorf("no heat: got (%d,%d,%d), want gray", r, g, b)
+	}
+	// High heat is rendered red
+	if r, g, b := heatmapPixel(0, 1); r <= g || r <= b {
+		t.Errorf("full heat: got (%d,%d,%d), want red dominant", r, g, b)
+	}
+}
@@ -0,0 +1,79 @@
+package dicom
+
+import (
+	"fmt"
+	randv2 "math/rand/v2"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// linkageIssuer is the issuer of the generated admission IDs (the EMR)
+const linkageIssuer = "EMR"
+
+// linkageIDs are the RIS/EMR identifiers of a study: the visit (AdmissionID,
+// the encounter number of the EMR, as HL7 PV1-19) and the imaging service
+// request, as ordered in the EMR (placer) and scheduled in the RIS (filler).
+type linkageIDs struct {
+	admissionID       string
+	placerOrderNumber string
+	fillerOrderNumber string
+}
+
+// linkagePlanner assigns linkage IDs to the studies of a run: studies of a
+// patient on the same day belong to the same visit, and every study has its
+// own order. IDs are unique within the run and drawn from a dedicated RNG so
+// that the rest of the run does not depend on them.
+type linkagePlanner struct {
+	rng    *randv2.Rand
+	visits map[string]string // patient ID and study date -> admission ID
+	used   map[string]bool
+}
+
+// newLinkagePlanner creates a planner for the run with the given seed.
+func newLinkagePlanner(seed int64) *linkagePlanner {
+	return &linkagePlanner{
+		rng:    randv2.New(randv2.NewPCG(uint64(seed), 0x115)),
+		visits: make(map[string]string),
+		used:   make(map[string]bool),
+	}
+}
+
+// next returns the linkage IDs of the next study of a patient.
+func (p *linkagePlanner) next(patientID, studyDate string) linkageIDs {
+	visit := patientID + "\x00" + studyDate
+	admissionID, ok := p.visits[visit]
+	if !ok {
+		admissionID = p.uniqueID("ADM")
+		p.visits[visit] = admissionID
+	}
+	return linkageIDs{
+		admissionID:       admissionID,
+		placerOrderNumber: p.uniqueID("ORD"),
+		fillerOrderNumber: p.uniqueID("RIS"),
+	}
+}
+
+// uniqueID draws an ID with the given prefix not used before in the run.
+func (p *linkagePlanner) uniqueID(prefix string) string {
+	for {
+		id := fmt.Sprintf("%s%08d", prefix, p.rng.IntN(90000000)+10000000)
+		if !p.used[id] {
+			p.used[id] = true
+			return id
+		}
+	}
+}
+
+// elements returns the Visit Identification and Imaging Service Request
+// elements holding the IDs.
+func (l linkageIDs) elements() []*dicom.Element {
+	return []*dicom.Element{
+		mustNewElement(tag.AdmissionID, []string{l.admissionID}),
+		mustNewElement(tag.IssuerOfAdmissionIDSequence, [][]*dicom.Element{{
+			mustNewElement(tag.LocalNamespaceEntityID, []string{linkageIssuer}),
+		}}),
+		mustNewElement(tag.PlacerOrderNumberImagingServiceRequest, []string{l.placerOrderNumber}),
+		mustNewElement(tag.FillerOrderNumberImagingServiceRequest, []string{l.fillerOrderNumber}),
+	}
+}
@@ -0,0 +1,58 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestLinkagePlanner(t *testing.T) {
+	p := newLinkagePlanner(42)
+
+	first := p.next("P1", "20240101")
+	sameDay := p.next("P1", "20240101")
+	nextDay := p.next("P1", "20240102")
+	otherPatient := p.next("P2", "20240101")
+
+	if sameDay.admissionID != first.admissionID {
+		t.Errorf("same-day studies have admissions %s and %s, want one visit", first.admissionID, sameDay.admissionID)
+	}
+	if nextDay.admissionID == first.admissionID || otherPatient.admissionID == first.admissionID {
+		t.Error("studies of another day or patient share the visit")
+	}
+
+	orders := make(map[string]bool)
+	for _, ids := range []linkageIDs{first, sameDay, nextDay, otherPatient} {
+		for _, order := range []string{ids.placerOrderNumber, ids.fillerOrderNumber} {
+			if orders[order] {
+				t.Errorf("order number %s is used twice", order)
+			}
+			orders[order] = true
+		}
+	}
+
+	// Same seed, same IDs
+	if again := newLinkagePlanner(42).next("P1", "20240101"); again != first {
+		t.Errorf("got %+v, want %+v for the same seed", again, first)
+	}
+}
+
+func TestLinkageIDs_Elements(t *testing.T) {
+	ids := linkageIDs{admissionID: "ADM10000001", placerOrderNumber: "ORD10000002", fillerOrderNumber: "RIS10000003"}
+
+	want := []tag.Tag{
+		tag.AdmissionID,
+		tag.IssuerOfAdmissionIDSequence,
+		tag.PlacerOrderNumberImagingServiceRequest,
+		tag.FillerOrderNumberImagingServiceRequest,
+	}
+	elements := ids.elements()
+	if len(elements) != len(want) {
+		t.Fatalf("got %d elements, want %d", len(elements), len(want))
+	}
+	for i, elem := range elements {
+		if elem.Tag != want[i] {
+			t.Errorf("element %d: tag %v, want %v", i, elem.Tag, want[i])
+		}
+	}
+}
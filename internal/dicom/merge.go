@@ -0,0 +1,136 @@
+package dicom
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// mergeCoercedTags are the patient and study attributes every file of a
+// merged study takes from the first study.
+var mergeCoercedTags = map[tag.Tag]bool{
+	tag.PatientName:            true,
+	tag.PatientID:              true,
+	tag.PatientBirthDate:       true,
+	tag.PatientSex:             true,
+	tag.PatientAge:             true,
+	tag.StudyDate:              true,
+	tag.StudyTime:              true,
+	tag.StudyID:                true,
+	tag.AccessionNumber:        true,
+	tag.StudyDescription:       true,
+	tag.ReferringPhysicianName: true,
+}
+
+// MergeOptions configures the merging of studies into one.
+type MergeOptions struct {
+	// Root of the new UIDs (util.DefaultUIDRoot when empty)
+	UIDRoot string
+}
+
+// MergeReport summarizes a merge.
+type MergeReport struct {
+	Studies int // Studies merged
+	Series  int // Series of the merged study
+	Files   int // DICOM files written
+	Ignored int // Files not readable as DICOM and DICOMDIRs
+}
+
+// MergeStudies writes all the studies of inputDirs as a single study: every
+// file gets the StudyInstanceUID of the merged study and the patient and
+// study attributes of the first study, and series are renumbered from 1 in
+// study then series number order. Series, SOP Instance and Frame of Reference
+// UIDs are replaced as well (every UID not defined by the standard), so the
+// merged study can be imported next to its sources, and references to the
+// source studies follow. outputDir receives a PT*/ST*/SE*/IM* hierarchy and a
+// DICOMDIR.
+func MergeStudies(inputDirs []string, outputDir string, opts MergeOptions) (MergeReport, error) {
+	var report MergeReport
+	if opts.UIDRoot != "" {
+		if err := util.ValidateUIDRoot(opts.UIDRoot); err != nil {
+			return report, err
+		}
+	}
+	var studyOrder []string
+	studies := make(map[string][]studyFile)
+	for _, inputDir := range inputDirs {
+		if err := checkOutputOutsideInput(inputDir, outputDir); err != nil {
+			return report, err
+		}
+		order, found, ignored, err := collectStudyFiles(inputDir)
+		if err != nil {
+			return report, err
+		}
+		report.Ignored += ignored
+		for _, studyUID := range order {
+			if _, ok := studies[studyUID]; !ok {
+				studyOrder = append(studyOrder, studyUID)
+			}
+			studies[studyUID] = append(studies[studyUID], found[studyUID]...)
+		}
+	}
+	if len(studyOrder) < 2 {
+		return report, fmt.Errorf("found %d studies, at least 2 are needed to merge", len(studyOrder))
+	}
+
+	first, err := dicom.ParseFile(studies[studyOrder[0]][0].path, nil, dicom.SkipPixelData())
+	if err != nil {
+		return report, fmt.Errorf("parse %s: %w", studies[studyOrder[0]][0].path, err)
+	}
+	coerced := make(map[tag.Tag]dicom.Value)
+	for _, elem := range first.Elements {
+		if mergeCoercedTags[elem.Tag] {
+			coerced[elem.Tag] = elem.Value
+		}
+	}
+
+	studyUID := util.GenerateDeterministicUIDWithRoot(opts.UIDRoot, "merge_"+strings.Join(studyOrder, "_"))
+	mapper := newUIDMapper(func(old string) string {
+		return util.GenerateDeterministicUIDWithRoot(opts.UIDRoot, studyUID+"_"+old)
+	})
+	studyIdx := make(map[string]int)
+	var files []studyFile
+	for i, uid := range studyOrder {
+		mapper.uids[uid] = studyUID
+		studyIdx[uid] = i
+		files = append(files, studies[uid]...)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].studyUID != files[j].studyUID {
+			return studyIdx[files[i].studyUID] < studyIdx[files[j].studyUID]
+		}
+		if files[i].seriesNumber != files[j].seriesNumber {
+			return files[i].seriesNumber < files[j].seriesNumber
+		}
+		return files[i].instance < files[j].instance
+	})
+
+	studyDir := filepath.Join(outputDir, "PT000000", "ST000000")
+	err = writeStudyFiles(studyDir, files, mapper, func(_ studyFile, seriesIdx int, ds *dicom.Dataset) {
+		seriesNumber, _ := dicom.NewValue([]string{strconv.Itoa(seriesIdx + 1)})
+		for _, elem := range ds.Elements {
+			if value, ok := coerced[elem.Tag]; ok {
+				elem.Value = value
+			} else if elem.Tag == tag.SeriesNumber {
+				elem.Value = seriesNumber
+			}
+		}
+		report.Series = seriesIdx + 1
+	})
+	if err != nil {
+		return report, err
+	}
+	report.Studies = len(studyOrder)
+	report.Files = len(files)
+
+	if err := createDICOMDIRFile(outputDir, filepath.Join(outputDir, "DICOMDIR"), opts.UIDRoot); err != nil {
+		return report, fmt.Errorf("create DICOMDIR: %w", err)
+	}
+	return report, nil
+}
@@ -1,8 +1,7 @@
 package dicom
 
 import (
-	"fmt"
-
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -41,11 +40,6 @@ type MetadataOptions struct {
 	FieldStrength        float64
 }
 
-// formatFloat converts a float64 to a string suitable for DICOM numeric tags
-func formatFloat(f float64) string {
-	return fmt.Sprintf("%f", f)
-}
-
 // mustNewElement creates a DICOM element or panics on error.
 // This simplifies element creation for test/development code.
 // Panics if the tag or data is invalid (should only happen with programming errors).
@@ -70,6 +64,9 @@ func GenerateMetadata(opts MetadataOptions) *dicom.Dataset {
 	ds.Elements = append(ds.Elements, mustNewElement(tag.PatientID, []string{opts.PatientID}))
 	ds.Elements = append(ds.Elements, mustNewElement(tag.PatientBirthDate, []string{opts.PatientBirthDate}))
 	ds.Elements = append(ds.Elements, mustNewElement(tag.PatientSex, []string{opts.PatientSex}))
+	if age, err := util.PatientAge(opts.PatientBirthDate, opts.StudyDate); err == nil {
+		ds.Elements = append(ds.Elements, mustNewElement(tag.PatientAge, []string{age}))
+	}
 
 	// Study Information Module
 	ds.Elements = append(ds.Elements, mustNewElement(tag.StudyInstanceUID, []string{opts.StudyUID}))
@@ -81,12 +78,12 @@ func GenerateMetadata(opts MetadataOptions) *dicom.Dataset {
 
 	// Series Information Module
 	ds.Elements = append(ds.Elements, mustNewElement(tag.SeriesInstanceUID, []string{opts.SeriesUID}))
-	ds.Elements = append(ds.Elements, mustNewElement(tag.SeriesNumber, []string{fmt.Sprintf("%d", opts.SeriesNumber)}))
+	ds.Elements = append(ds.Elements, mustNewElement(tag.SeriesNumber, []string{util.FormatIS(opts.SeriesNumber)}))
 	ds.Elements = append(ds.Elements, mustNewElement(tag.SeriesDescription, []string{"MRI Scan"}))
 	ds.Elements = append(ds.Elements, mustNewElement(tag.Modality, []string{"MR"}))
 
 	// Instance Information Module
-	ds.Elements = append(ds.Elements, mustNewElement(tag.InstanceNumber, []string{fmt.Sprintf("%d", opts.InstanceNumber)}))
+	ds.Elements = append(ds.Elements, mustNewElement(tag.InstanceNumber, []string{util.FormatIS(opts.InstanceNumber)}))
 	// SOP Class UID for MR Image Storage
 	ds.Elements = append(ds.Elements, mustNewElement(tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.4"}))
 
@@ -112,30 +109,30 @@ func GenerateMetadata(opts MetadataOptions) *dicom.Dataset {
 	if opts.PixelSpacing != 0 {
 		// PixelSpacing is stored as [row spacing, column spacing]
 		ds.Elements = append(ds.Elements, mustNewElement(tag.PixelSpacing, []string{
-			formatFloat(opts.PixelSpacing),
-			formatFloat(opts.PixelSpacing),
+			util.FormatDS(opts.PixelSpacing),
+			util.FormatDS(opts.PixelSpacing),
 		}))
 	}
 	if opts.SliceThickness != 0 {
-		ds.Elements = append(ds.Elements, mustNewElement(tag.SliceThickness, []string{formatFloat(opts.SliceThickness)}))
+		ds.Elements = append(ds.Elements, mustNewElement(tag.SliceThickness, []string{util.FormatDS(opts.SliceThickness)}))
 	}
 	if opts.SpacingBetweenSlices != 0 {
-		ds.Elements = append(ds.Elements, mustNewElement(tag.SpacingBetweenSlices, []string{formatFloat(opts.SpacingBetweenSlices)}))
+		ds.Elements = append(ds.Elements, mustNewElement(tag.SpacingBetweenSlices, []string{util.FormatDS(opts.SpacingBetweenSlices)}))
 	}
 	if opts.EchoTime != 0 {
-		ds.Elements = append(ds.Elements, mustNewElement(tag.EchoTime, []string{formatFloat(opts.EchoTime)}))
+		ds.Elements = append(ds.Elements, mustNewElement(tag.EchoTime, []string{util.FormatDS(opts.EchoTime)}))
 	}
 	if opts.RepetitionTime != 0 {
-		ds.Elements = append(ds.Elements, mustNewElement(tag.RepetitionTime, []string{formatFloat(opts.RepetitionTime)}))
+		ds.Elements = append(ds.Elements, mustNewElement(tag.RepetitionTime, []string{util.FormatDS(opts.RepetitionTime)}))
 	}
 	if opts.FlipAngle != 0 {
-		ds.Elements = append(ds.Elements, mustNewElement(tag.FlipAngle, []string{formatFloat(opts.FlipAngle)}))
+		ds.Elements = append(ds.Elements, mustNewElement(tag.FlipAngle, []string{util.FormatDS(opts.FlipAngle)}))
 	}
 	if opts.SequenceName != "" {
 		ds.Elements = append(ds.Elements, mustNewElement(tag.SequenceName, []string{opts.SequenceName}))
 	}
 	if opts.FieldStrength != 0 {
-		ds.Elements = append(ds.Elements, mustNewElement(tag.MagneticFieldStrength, []string{formatFloat(opts.FieldStrength)}))
+		ds.Elements = append(ds.Elements, mustNewElement(tag.MagneticFieldStrength, []string{util.FormatDS(opts.FieldStrength)}))
 	}
 
 	return ds
@@ -1,8 +1,17 @@
 package dicom
 
 import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
@@ -166,3 +175,266 @@ func TestGenerateMetadata_MRIParametersNotSet(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateMetadata_PatientAge(t *testing.T) {
+	opts := MetadataOptions{
+		PatientBirthDate: "19800615",
+		StudyDate:        "20260111",
+	}
+
+	ds := GenerateMetadata(opts)
+	age, err := ds.FindElementByTag(tag.PatientAge)
+	if err != nil {
+		t.Fatal("PatientAge tag not found")
+	}
+	if got := age.Value.GetValue().([]string)[0]; got != "045Y" {
+		t.Errorf("PatientAge = %q, want 045Y", got)
+	}
+
+	// A partial birth date does not allow deriving the age
+	opts.PatientBirthDate = "1980"
+	if _, err := GenerateMetadata(opts).FindElementByTag(tag.PatientAge); err == nil {
+		t.Error("PatientAge should not be present with a partial birth date")
+	}
+}
+
+func TestAgeProfile(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   6,
+		TotalSize:   "2MB",
+		Modality:    modalities.CT,
+		AgeProfile:  util.AgeProfileNeonate,
+		OutputDir:   t.TempDir(),
+		Seed:        42,
+		NumStudies:  3,
+		NumPatients: 3,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := func(tg tag.Tag) string {
+			elem, err := ds.FindElementByTag(tg)
+			if err != nil {
+				t.Fatalf("%s: %v", f.Path, err)
+			}
+			return elementString([]*dicom.Element{elem}, tg)
+		}
+		if age := value(tag.PatientAge); !strings.HasSuffix(age, "D") || age > "027D" {
+			t.Errorf("%s: PatientAge = %s, want a neonate", f.Path, age)
+		}
+		if weight, err := strconv.ParseFloat(value(tag.PatientWeight), 64); err != nil || weight < 2 || weight > 6 {
+			t.Errorf("%s: PatientWeight = %v, want a neonate weight", f.Path, weight)
+		}
+		if size, err := strconv.ParseFloat(value(tag.PatientSize), 64); err != nil || size < 0.4 || size > 0.65 {
+			t.Errorf("%s: PatientSize = %v, want a neonate size", f.Path, size)
+		}
+		if protocol := value(tag.ProtocolName); !strings.HasPrefix(protocol, "NEONATAL_") {
+			t.Errorf("%s: ProtocolName = %s, want a neonatal protocol", f.Path, protocol)
+		}
+		if kvp := value(tag.KVP); kvp != "80" {
+			t.Errorf("%s: KVP = %s, want 80", f.Path, kvp)
+		}
+	}
+}
+
+func TestCharacterSet(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:    4,
+		TotalSize:    "1MB",
+		Modality:     modalities.CT,
+		CharacterSet: util.CharacterSetLatin1,
+		Demographics: &util.Demographics{
+			MaleRatio:     0.5,
+			AgePyramid:    []util.AgeBand{{MinAge: 20, MaxAge: 80, Weight: 1}},
+			NameLocales:   []util.LocaleWeight{{Locale: util.LocaleFrench, Weight: 1}},
+			ReferenceDate: util.DefaultDemographics().ReferenceDate,
+		},
+		TextSR:      true,
+		OutputDir:   t.TempDir(),
+		Seed:        42,
+		NumStudies:  4,
+		NumPatients: 4,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	accented := 0
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := elementString(ds.Elements, tag.SpecificCharacterSet); got != util.CharacterSetLatin1 {
+			t.Errorf("%s: SpecificCharacterSet = %q, want %q", f.Path, got, util.CharacterSetLatin1)
+		}
+		// Names read back in UTF-8, written in ISO 8859-1
+		name := elementString(ds.Elements, tag.PatientName)
+		var latin1 []byte
+		for _, r := range name {
+			latin1 = append(latin1, byte(r))
+		}
+		if string(latin1) != name {
+			raw, err := os.ReadFile(f.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(raw, latin1) || bytes.Contains(raw, []byte(name)) {
+				t.Errorf("%s: PatientName %q not written in ISO 8859-1", f.Path, name)
+			}
+			accented++
+		}
+	}
+	if accented == 0 {
+		t.Error("no patient name with accents")
+	}
+}
+
+func TestUIDRoot(t *testing.T) {
+	const root = "1.2.3.4"
+	outputDir := t.TempDir()
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "1MB",
+		Modality:    modalities.CT,
+		UIDRoot:     root,
+		TextSR:      true,
+		OutputDir:   outputDir,
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 2,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if _, err := OrganizeFiles(outputDir, files, OrganizeOptions{UIDRoot: root, Quiet: true}); err != nil {
+		t.Fatalf("OrganizeFiles failed: %v", err)
+	}
+
+	checkUIDsUnderRoot(t, outputDir, root)
+
+	if _, err := GenerateDICOMSeries(GeneratorOptions{NumImages: 1, TotalSize: "1MB", UIDRoot: "1.02", OutputDir: t.TempDir(), Quiet: true}); err == nil {
+		t.Error("GenerateDICOMSeries should reject an invalid UID root")
+	}
+}
+
+// checkUIDsUnderRoot checks that every UID of the files of dir, but those of
+// the standard and the implementation, is under root.
+func checkUIDsUnderRoot(t *testing.T, dir, root string) {
+	t.Helper()
+	var check func(path string, elements []*dicom.Element)
+	check = func(path string, elements []*dicom.Element) {
+		for _, elem := range elements {
+			switch values := elem.Value.GetValue().(type) {
+			case []string:
+				if elem.RawValueRepresentation != "UI" || rerootKeptTags[elem.Tag] {
+					continue
+				}
+				for _, uid := range values {
+					if !strings.HasPrefix(uid, dicomStandardUIDRoot) && !strings.HasPrefix(uid, root+".") {
+						t.Errorf("%s: %v = %s, not under %s", path, elem.Tag, uid, root)
+					}
+				}
+			case []*dicom.SequenceItemValue:
+				for _, item := range values {
+					check(path, item.GetValue().([]*dicom.Element))
+				}
+			}
+		}
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ds, err := dicom.ParseFile(path, nil, dicom.SkipPixelData())
+		if err != nil {
+			return err
+		}
+		check(path, ds.Elements)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUIDRootRewrites(t *testing.T) {
+	const root = "1.2.3.4"
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join(tmpDir, DeidIdentifiedDir)
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:      8,
+		TotalSize:      "1MB",
+		Modality:       modalities.CT,
+		OutputDir:      inputDir,
+		Seed:           42,
+		NumStudies:     2,
+		NumPatients:    2,
+		SeriesPerStudy: util.SeriesRange{Min: 2, Max: 2},
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if _, err := OrganizeFiles(inputDir, files, OrganizeOptions{Quiet: true}); err != nil {
+		t.Fatalf("OrganizeFiles failed: %v", err)
+	}
+
+	// Split, merge and anonymize replace every UID, under the root
+	splitDir := filepath.Join(tmpDir, "split")
+	if _, err := SplitStudies(inputDir, splitDir, SplitOptions{UIDRoot: root}); err != nil {
+		t.Fatalf("SplitStudies failed: %v", err)
+	}
+	checkUIDsUnderRoot(t, splitDir, root)
+	mergeDir := filepath.Join(tmpDir, "merge")
+	if _, err := MergeStudies([]string{inputDir}, mergeDir, MergeOptions{UIDRoot: root}); err != nil {
+		t.Fatalf("MergeStudies failed: %v", err)
+	}
+	checkUIDsUnderRoot(t, mergeDir, root)
+	anonymizeDir := filepath.Join(tmpDir, "anonymize")
+	if _, err := AnonymizeDirectory(inputDir, anonymizeDir, AnonymizeOptions{UIDRoot: root}); err != nil {
+		t.Fatalf("AnonymizeDirectory failed: %v", err)
+	}
+	checkUIDsUnderRoot(t, anonymizeDir, root)
+
+	// The de-identification challenge replaces the instance hierarchy UIDs
+	answers, err := BuildDeidChallenge(tmpDir, DeidChallengeOptions{Seed: 42, UIDRoot: root})
+	if err != nil {
+		t.Fatalf("BuildDeidChallenge failed: %v", err)
+	}
+	for _, answer := range answers.Files {
+		path := filepath.Join(tmpDir, answer.Deidentified)
+		ds, err := dicom.ParseFile(path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		for _, uidTag := range []tag.Tag{tag.StudyInstanceUID, tag.SeriesInstanceUID, tag.SOPInstanceUID} {
+			if uid := getStringValue(ds, uidTag)[0]; !strings.HasPrefix(uid, root+".") {
+				t.Errorf("%s: %v = %s, not under %s", path, uidTag, uid, root)
+			}
+		}
+	}
+
+	invalid := filepath.Join(tmpDir, "invalid")
+	if _, err := SplitStudies(inputDir, invalid, SplitOptions{UIDRoot: "1.02"}); err == nil {
+		t.Error("SplitStudies should reject an invalid UID root")
+	}
+	if _, err := MergeStudies([]string{inputDir}, invalid, MergeOptions{UIDRoot: "1.02"}); err == nil {
+		t.Error("MergeStudies should reject an invalid UID root")
+	}
+	if _, err := AnonymizeDirectory(inputDir, invalid, AnonymizeOptions{UIDRoot: "1.02"}); err == nil {
+		t.Error("AnonymizeDirectory should reject an invalid UID root")
+	}
+	if _, err := BuildDeidChallenge(tmpDir, DeidChallengeOptions{UIDRoot: "1.02"}); err == nil {
+		t.Error("BuildDeidChallenge should reject an invalid UID root")
+	}
+}
@@ -3,6 +3,7 @@ package modalities
 import (
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -65,9 +66,21 @@ func (g *CRGenerator) GenerateSeriesParams(scanner Scanner, rng *rand.Rand) Seri
 		WindowWidth:              windowWidth,
 	}
 
+	// Tube voltage, dose indicators and geometry, drawn last so that the
+	// values above do not depend on them
+	params.KVP = float64(60 + rng.IntN(66)) // 60-125 kVp
+	drawRadiographyExposure(&params, rng)
+	drawRadiographyGeometry(&params, scanner, crGrids, rng)
+
 	return params
 }
 
+// ImagesPerSeries returns the typical number of CR images in a series.
+func (g *CRGenerator) ImagesPerSeries() util.ImageRange {
+	// A projection, sometimes with a second view
+	return util.ImageRange{Min: 1, Max: 2}
+}
+
 // PixelConfig returns CR pixel data configuration.
 func (g *CRGenerator) PixelConfig() PixelConfig {
 	return PixelConfig{
@@ -91,10 +104,13 @@ func (g *CRGenerator) AppendModalityElements(ds *dicom.Dataset, params SeriesPar
 		}),
 		mustNewElement(tag.DistanceSourceToDetector, []string{floatToDS(params.DistanceSourceToDetector)}),
 		mustNewElement(tag.DistanceSourceToPatient, []string{floatToDS(params.DistanceSourceToPatient)}),
+		mustNewElement(tag.KVP, []string{floatToDS(params.KVP)}),
 		mustNewElement(tag.Exposure, []string{intToIS(params.Exposure)}),
 		// Plate ID for CR
 		mustNewElement(tag.PlateID, []string{"PLATE001"}),
 	}
+	elements = append(elements, radiographyDoseElements(params)...)
+	elements = append(elements, radiographyGeometryElements(ds, params)...)
 
 	ds.Elements = append(ds.Elements, elements...)
 	return nil
@@ -3,6 +3,7 @@ package modalities
 import (
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -77,6 +78,12 @@ func (g *CTGenerator) GenerateSeriesParams(scanner Scanner, rng *rand.Rand) Seri
 	return params
 }
 
+// ImagesPerSeries returns the typical number of CT images in a series.
+func (g *CTGenerator) ImagesPerSeries() util.ImageRange {
+	// Helical acquisitions reconstructed as thin axial slices
+	return util.ImageRange{Min: 100, Max: 600}
+}
+
 // PixelConfig returns CT pixel data configuration.
 func (g *CTGenerator) PixelConfig() PixelConfig {
 	return PixelConfig{
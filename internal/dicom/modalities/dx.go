@@ -3,6 +3,7 @@ package modalities
 import (
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -70,9 +71,20 @@ func (g *DXGenerator) GenerateSeriesParams(scanner Scanner, rng *rand.Rand) Seri
 		WindowWidth:              windowWidth,
 	}
 
+	// Dose indicators and geometry, drawn last so that the values above do
+	// not depend on them
+	drawRadiographyExposure(&params, rng)
+	drawRadiographyGeometry(&params, scanner, dxGrids, rng)
+
 	return params
 }
 
+// ImagesPerSeries returns the typical number of DX images in a series.
+func (g *DXGenerator) ImagesPerSeries() util.ImageRange {
+	// A projection, sometimes with a second view
+	return util.ImageRange{Min: 1, Max: 2}
+}
+
 // PixelConfig returns DX pixel data configuration.
 func (g *DXGenerator) PixelConfig() PixelConfig {
 	return PixelConfig{
@@ -102,6 +114,8 @@ func (g *DXGenerator) AppendModalityElements(ds *dicom.Dataset, params SeriesPar
 		// Detector type for digital
 		mustNewElement(tag.DetectorType, []string{"SCINTILLATOR"}),
 	}
+	elements = append(elements, radiographyDoseElements(params)...)
+	elements = append(elements, radiographyGeometryElements(ds, params)...)
 
 	ds.Elements = append(ds.Elements, elements...)
 	return nil
@@ -0,0 +1,63 @@
+package modalities
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// targetExposureIndices are the exposure indices of correctly exposed
+// radiographs set by sites and vendors (IEC 62494-1)
+var targetExposureIndices = []float64{250, 300, 400, 500}
+
+// tubeOutput returns the air kerma at 1 m of an X-ray tube, in µGy per mAs,
+// at a tube voltage in kVp (about 50 µGy/mAs at 80 kVp).
+func tubeOutput(kvp float64) float64 {
+	return 0.0075 * kvp * kvp
+}
+
+// freeAirKerma returns the air kerma without patient at the detector of a
+// radiograph, in µGy.
+func freeAirKerma(p SeriesParams) float64 {
+	d := p.DistanceSourceToDetector / 1000 // m
+	return tubeOutput(p.KVP) * float64(p.Exposure) / (d * d)
+}
+
+// drawRadiographyExposure draws the collimated field, the target exposure
+// index and the attenuation by the patient of a radiography series. The
+// attenuation is the one automatic exposure control would have compensated
+// with the series' mAs, up to a deviation index of a few units, within the
+// 0.1-20% transmission of real patients.
+func drawRadiographyExposure(p *SeriesParams, rng *rand.Rand) {
+	width := 18 + rng.Float64()*17  // 18-35 cm
+	height := 24 + rng.Float64()*19 // 24-43 cm
+	p.FieldArea = width * height
+	p.TargetExposureIndex = targetExposureIndices[rng.IntN(len(targetExposureIndices))]
+
+	deviation := math.Max(-4, math.Min(4, rng.NormFloat64()*1.2))
+	detectorKerma := p.TargetExposureIndex / 100 * math.Pow(10, deviation/10)
+	p.PatientTransmission = math.Max(0.001, math.Min(0.2, detectorKerma/freeAirKerma(*p)))
+}
+
+// radiographyDoseElements returns the exposure index, its target and
+// deviation (IEC 62494-1: 100 per µGy at the detector) and the dose area
+// product (dGy*cm2) of a radiograph, from its kVp, mAs, distances, field and
+// patient attenuation.
+func radiographyDoseElements(p SeriesParams) []*dicom.Element {
+	freeKerma := freeAirKerma(p)
+	exposureIndex := 100 * freeKerma * p.PatientTransmission
+	deviationIndex := 10 * math.Log10(exposureIndex/p.TargetExposureIndex)
+
+	// The kerma-area product does not depend on the distance: field and
+	// kerma at the detector, µGy*cm2 to dGy*cm2
+	doseAreaProduct := freeKerma * p.FieldArea * 1e-5
+
+	return []*dicom.Element{
+		mustNewElement(tag.ExposureIndex, []string{floatToDS(math.Round(exposureIndex))}),
+		mustNewElement(tag.TargetExposureIndex, []string{floatToDS(p.TargetExposureIndex)}),
+		mustNewElement(tag.DeviationIndex, []string{floatToDS(math.Round(deviationIndex*100) / 100)}),
+		mustNewElement(tag.ImageAndFluoroscopyAreaDoseProduct, []string{floatToDS(math.Round(doseAreaProduct*1e4) / 1e4)}),
+	}
+}
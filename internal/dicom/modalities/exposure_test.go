@@ -0,0 +1,89 @@
+package modalities
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// doseValues returns the exposure index, target, deviation index and dose
+// area product of radiography dose elements.
+func doseValues(t *testing.T, elements []*dicom.Element) map[tag.Tag]float64 {
+	t.Helper()
+	values := make(map[tag.Tag]float64)
+	for _, elem := range elements {
+		v, err := strconv.ParseFloat(elem.Value.GetValue().([]string)[0], 64)
+		if err != nil {
+			t.Fatalf("%v: %v", elem.Tag, err)
+		}
+		values[elem.Tag] = v
+	}
+	return values
+}
+
+func TestRadiographyDoseElements(t *testing.T) {
+	p := SeriesParams{
+		KVP:                      80,
+		Exposure:                 10,
+		DistanceSourceToDetector: 1000,
+		FieldArea:                1000,
+		TargetExposureIndex:      400,
+		PatientTransmission:      0.01,
+	}
+	// 48 µGy/mAs at 80 kVp and 1 m: 480 µGy free in air, 4.8 µGy at the
+	// detector behind the patient
+	values := doseValues(t, radiographyDoseElements(p))
+	if got := values[tag.ExposureIndex]; got != 480 {
+		t.Errorf("ExposureIndex = %g, want 480", got)
+	}
+	if got := values[tag.TargetExposureIndex]; got != 400 {
+		t.Errorf("TargetExposureIndex = %g, want 400", got)
+	}
+	if got := values[tag.DeviationIndex]; got != 0.79 {
+		t.Errorf("DeviationIndex = %g, want 10*log10(480/400) = 0.79", got)
+	}
+	if got := values[tag.ImageAndFluoroscopyAreaDoseProduct]; got != 4.8 {
+		t.Errorf("DAP = %g dGy*cm2, want 480 µGy * 1000 cm2 = 4.8", got)
+	}
+
+	// Doubling the mAs doubles the exposure index and the DAP, +3 DI
+	p.Exposure = 20
+	doubled := doseValues(t, radiographyDoseElements(p))
+	if doubled[tag.ExposureIndex] != 960 || doubled[tag.ImageAndFluoroscopyAreaDoseProduct] != 9.6 || doubled[tag.DeviationIndex] != 3.8 {
+		t.Errorf("values at 20 mAs = %v", doubled)
+	}
+	// The DAP does not depend on the distance for the same field at the detector
+	p.DistanceSourceToDetector, p.Exposure = 2000, 10
+	far := doseValues(t, radiographyDoseElements(p))
+	if far[tag.ExposureIndex] != 120 || far[tag.ImageAndFluoroscopyAreaDoseProduct] != 1.2 {
+		t.Errorf("values at 2 m = %v", far)
+	}
+}
+
+func TestDrawRadiographyExposure(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	for _, gen := range []Generator{&CRGenerator{}, &DXGenerator{}} {
+		within := 0
+		for i := 0; i < 100; i++ {
+			p := gen.GenerateSeriesParams(gen.Scanners()[0], rng)
+			if p.KVP < 60 || p.KVP > 140 {
+				t.Fatalf("%s: KVP %g", p.Modality, p.KVP)
+			}
+			if p.FieldArea < 18*24 || p.FieldArea > 35*43 || p.PatientTransmission < 0.001 || p.PatientTransmission > 0.2 {
+				t.Fatalf("%s: field %g cm2, transmission %g", p.Modality, p.FieldArea, p.PatientTransmission)
+			}
+			di := doseValues(t, radiographyDoseElements(p))[tag.DeviationIndex]
+			if math.Abs(di) <= 3 {
+				within++
+			}
+		}
+		// Automatic exposure control keeps most radiographs within +/-3 DI
+		if within < 80 {
+			t.Errorf("%T: %d of 100 series within +/-3 DI", gen, within)
+		}
+	}
+}
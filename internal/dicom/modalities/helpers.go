@@ -3,6 +3,7 @@ package modalities
 import (
 	"fmt"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -18,10 +19,10 @@ func mustNewElement(t tag.Tag, value interface{}) *dicom.Element {
 
 // floatToDS converts a float64 to a DICOM Decimal String.
 func floatToDS(f float64) string {
-	return fmt.Sprintf("%.6g", f)
+	return util.FormatDS(f)
 }
 
 // intToIS converts an int to a DICOM Integer String.
 func intToIS(i int) string {
-	return fmt.Sprintf("%d", i)
+	return util.FormatIS(i)
 }
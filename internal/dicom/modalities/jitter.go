@@ -0,0 +1,54 @@
+package modalities
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Clinically plausible bounds of the values jittered per instance
+const (
+	minTubeCurrent, maxTubeCurrent           = 1, 800       // mA
+	minExposure, maxExposure                 = 1, 600       // mAs
+	minExposureTime, maxExposureTime         = 1, 5000      // ms
+	minOrganDose, maxOrganDose               = 0.1, 10.0    // mGy
+	minCompressionForce, maxCompressionForce = 30.0, 300.0  // N
+	minDoseAreaProduct, maxDoseAreaProduct   = 0.01, 500.0  // dGy*cm2
+	minEntranceDose, maxEntranceDose         = 0.01, 1000.0 // mGy
+)
+
+// Jitter returns the parameters of one instance of a series: its dose,
+// exposure and exposure time values (tube current, exposure, organ dose,
+// compression force, dose area product and the entrance dose with it) each
+// moved by a random fraction of up to amount (0.1 = +/-10%) of the series
+// value, as automatic exposure control does from image to image, within
+// clinically plausible bounds. Protocol settings (kVp, MR timings, geometry)
+// are kept, as are values the modality does not use (0).
+func (p SeriesParams) Jitter(amount float64, rng *rand.Rand) SeriesParams {
+	if amount <= 0 {
+		return p
+	}
+	jitter := func(v, lo, hi float64) float64 {
+		if v == 0 {
+			return 0
+		}
+		return math.Min(hi, math.Max(lo, v*(1+amount*(2*rng.Float64()-1))))
+	}
+	jitterInt := func(v, lo, hi int) int {
+		if v == 0 {
+			return 0
+		}
+		return int(math.Round(jitter(float64(v), float64(lo), float64(hi))))
+	}
+
+	p.XRayTubeCurrent = jitterInt(p.XRayTubeCurrent, minTubeCurrent, maxTubeCurrent)
+	p.Exposure = jitterInt(p.Exposure, minExposure, maxExposure)
+	p.ExposureTime = jitterInt(p.ExposureTime, minExposureTime, maxExposureTime)
+	p.OrganDose = jitter(p.OrganDose, minOrganDose, maxOrganDose)
+	p.CompressionForce = jitter(p.CompressionForce, minCompressionForce, maxCompressionForce)
+	// The entrance dose follows the dose area product it derives from
+	if dap := p.DoseAreaProduct; dap != 0 {
+		p.DoseAreaProduct = jitter(dap, minDoseAreaProduct, maxDoseAreaProduct)
+		p.EntranceDose = math.Min(maxEntranceDose, math.Max(minEntranceDose, p.EntranceDose*p.DoseAreaProduct/dap))
+	}
+	return p
+}
@@ -0,0 +1,53 @@
+package modalities
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSeriesParams_Jitter(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	gen := &RFGenerator{}
+	series := gen.GenerateSeriesParams(gen.Scanners()[0], rng)
+	series.OrganDose, series.CompressionForce = 2, 100
+
+	// No jitter keeps the series values
+	if got := series.Jitter(0, rng); got.DoseAreaProduct != series.DoseAreaProduct || got.XRayTubeCurrent != series.XRayTubeCurrent {
+		t.Error("Jitter(0) changed the series values")
+	}
+
+	varied := make(map[float64]bool)
+	for i := 0; i < 50; i++ {
+		p := series.Jitter(0.2, rng)
+		varied[p.DoseAreaProduct] = true
+		if p.DoseAreaProduct < 0.8*series.DoseAreaProduct || p.DoseAreaProduct > 1.2*series.DoseAreaProduct {
+			t.Errorf("DoseAreaProduct %g beyond +/-20%% of %g", p.DoseAreaProduct, series.DoseAreaProduct)
+		}
+		if p.OrganDose < 1.6 || p.OrganDose > 2.4 || p.CompressionForce < 80 || p.CompressionForce > 120 {
+			t.Errorf("OrganDose %g, CompressionForce %g beyond +/-20%%", p.OrganDose, p.CompressionForce)
+		}
+		if p.XRayTubeCurrent < 1 || p.ExposureTime < 1 {
+			t.Errorf("tube current %d, exposure time %d below 1", p.XRayTubeCurrent, p.ExposureTime)
+		}
+		// The entrance dose keeps its ratio to the dose area product
+		ratio, want := p.EntranceDose/p.DoseAreaProduct, series.EntranceDose/series.DoseAreaProduct
+		if ratio < want*0.999 || ratio > want*1.001 {
+			t.Errorf("entrance dose ratio %g, want %g", ratio, want)
+		}
+		if p.KVP != series.KVP || p.DistanceSourceToDetector != series.DistanceSourceToDetector || p.Exposure != 0 {
+			t.Error("protocol settings or unused values changed")
+		}
+	}
+	if len(varied) < 40 {
+		t.Errorf("only %d distinct dose area products out of 50", len(varied))
+	}
+
+	// Values stay within clinical bounds
+	series.XRayTubeCurrent, series.OrganDose = maxTubeCurrent, minOrganDose
+	for i := 0; i < 20; i++ {
+		p := series.Jitter(0.5, rng)
+		if p.XRayTubeCurrent > maxTubeCurrent || p.OrganDose < minOrganDose {
+			t.Errorf("tube current %d, organ dose %g beyond clinical bounds", p.XRayTubeCurrent, p.OrganDose)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package modalities
 import (
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -87,6 +88,12 @@ func (g *MGGenerator) GenerateSeriesParams(scanner Scanner, rng *rand.Rand) Seri
 	return params
 }
 
+// ImagesPerSeries returns the typical number of MG images in a series.
+func (g *MGGenerator) ImagesPerSeries() util.ImageRange {
+	// Screening views: CC and MLO of each breast
+	return util.ImageRange{Min: 4, Max: 4}
+}
+
 // PixelConfig returns MG pixel data configuration.
 func (g *MGGenerator) PixelConfig() PixelConfig {
 	return PixelConfig{
@@ -4,6 +4,7 @@ package modalities
 import (
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 )
 
@@ -17,11 +18,12 @@ const (
 	DX Modality = "DX" // Digital X-Ray
 	US Modality = "US" // Ultrasound
 	MG Modality = "MG" // Mammography
+	RF Modality = "RF" // Radiofluoroscopy
 )
 
 // AllModalities returns all supported modalities.
 func AllModalities() []Modality {
-	return []Modality{MR, CT, CR, DX, US, MG}
+	return []Modality{MR, CT, CR, DX, US, MG, RF}
 }
 
 // IsValid checks if a modality string is valid.
@@ -39,7 +41,9 @@ type Scanner struct {
 	Manufacturer string
 	Model        string
 	// MR-specific
-	FieldStrength float64 // Tesla (1.5, 3.0)
+	FieldStrength float64  // Tesla (1.5, 3.0)
+	ReceiveCoils  []string // Receive coils of the model
+	TransmitCoil  string   // Transmit (body) coil
 	// CT-specific
 	DetectorRows int // Number of detector rows (16, 64, 128, 256)
 }
@@ -53,12 +57,18 @@ type SeriesParams struct {
 	WindowWidth  float64
 
 	// MR-specific
-	EchoTime              float64
-	RepetitionTime        float64
-	FlipAngle             float64
-	SequenceName          string
-	MagneticFieldStrength float64
-	ImagingFrequency      float64
+	EchoTime               float64
+	RepetitionTime         float64
+	FlipAngle              float64
+	SequenceName           string
+	MagneticFieldStrength  float64
+	ImagingFrequency       float64
+	ReceiveCoilName        string
+	TransmitCoilName       string
+	ParallelFactor         float64 // In-plane parallel imaging acceleration, 1 without
+	FrequencyEncodingSteps int     // Acquisition matrix in the frequency direction
+	PhaseEncodingFraction  float64 // Phase encoding steps per frequency encoding step
+	PhaseEncodingDirection string  // ROW, COL
 
 	// CT-specific
 	KVP               float64 // Tube voltage (kV)
@@ -69,16 +79,25 @@ type SeriesParams struct {
 	GantryTilt        float64 // Gantry tilt angle
 
 	// CR/DX-specific (Radiography)
-	ViewPosition             string  // AP, PA, LAT, LL, RL
-	ImagerPixelSpacing       float64 // Detector pixel spacing
-	DistanceSourceToDetector float64 // SID (mm)
-	DistanceSourceToPatient  float64 // SOD (mm)
-	Exposure                 int     // Exposure (mAs)
-	ExposureTime             int     // Exposure time (ms)
+	ViewPosition             string     // AP, PA, LAT, LL, RL
+	ImagerPixelSpacing       float64    // Detector pixel spacing
+	DistanceSourceToDetector float64    // SID (mm)
+	DistanceSourceToPatient  float64    // SOD (mm)
+	Exposure                 int        // Exposure (mAs)
+	ExposureTime             int        // Exposure time (ms)
+	FieldArea                float64    // Collimated field at the detector (cm2)
+	TargetExposureIndex      float64    // Exposure index of a correct exposure
+	PatientTransmission      float64    // Fraction of the air kerma reaching the detector
+	Grid                     string     // NONE, FOCUSED, RECIPROCATING...
+	CollimatorMargins        [4]float64 // Left, right, upper, lower blade, fraction of the image
+	DetectorID               string     // Detector serial number
 
 	// US-specific (Ultrasound)
-	TransducerType      string  // LINEAR, CONVEX, PHASED
-	TransducerFrequency float64 // MHz
+	TransducerType      string    // LINEAR, CONVEX, PHASED
+	TransducerFrequency float64   // MHz
+	NumberOfFrames      int       // Frames per instance (cine loop); 0 or 1: single frame
+	FrameTime           float64   // ms between the frames of a cine loop
+	FrameTimeVector     []float64 // ms since the previous frame, 0 for the first (optional)
 
 	// MG-specific (Mammography)
 	ImageLaterality     string  // L, R
@@ -87,6 +106,12 @@ type SeriesParams struct {
 	CompressionForce    float64 // Newtons
 	OrganDose           float64 // mGy
 
+	// RF-specific (Radiofluoroscopy)
+	RadiationMode         string  // PULSED, CONTINUOUS
+	DoseAreaProduct       float64 // Image and fluoroscopy area dose product (dGy*cm2)
+	EntranceDose          float64 // mGy
+	ProcessingDescription string  // Acquisition device processing description
+
 	// Geometry (common)
 	PixelSpacing         float64
 	SliceThickness       float64
@@ -121,6 +146,10 @@ type Generator interface {
 	// PixelConfig returns pixel data configuration.
 	PixelConfig() PixelConfig
 
+	// ImagesPerSeries returns the typical number of images in a series,
+	// used when the number of images is not forced.
+	ImagesPerSeries() util.ImageRange
+
 	// AppendModalityElements appends modality-specific DICOM elements to a dataset.
 	AppendModalityElements(ds *dicom.Dataset, params SeriesParams) error
 
@@ -148,6 +177,8 @@ func GetGenerator(m Modality) Generator {
 		return &USGenerator{}
 	case MG:
 		return &MGGenerator{}
+	case RF:
+		return &RFGenerator{}
 	case MR:
 		fallthrough
 	default:
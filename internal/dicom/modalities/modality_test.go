@@ -3,6 +3,9 @@ package modalities
 import (
 	"math/rand/v2"
 	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
 func TestGetGenerator_MR(t *testing.T) {
@@ -43,6 +46,7 @@ func TestIsValid(t *testing.T) {
 		{"DX", true},
 		{"US", true},
 		{"MG", true},
+		{"RF", true},
 		{"mr", false}, // case sensitive
 		{"ct", false},
 		{"UNKNOWN", false},
@@ -61,12 +65,12 @@ func TestIsValid(t *testing.T) {
 
 func TestAllModalities(t *testing.T) {
 	mods := AllModalities()
-	if len(mods) != 6 {
-		t.Errorf("Expected 6 modalities, got %d", len(mods))
+	if len(mods) != 7 {
+		t.Errorf("Expected 7 modalities, got %d", len(mods))
 	}
 
 	// Verify all modalities are present
-	expected := map[Modality]bool{MR: false, CT: false, CR: false, DX: false, US: false, MG: false}
+	expected := map[Modality]bool{MR: false, CT: false, CR: false, DX: false, US: false, MG: false, RF: false}
 	for _, m := range mods {
 		if _, ok := expected[m]; ok {
 			expected[m] = true
@@ -99,6 +103,9 @@ func TestMRGenerator_Scanners(t *testing.T) {
 		if s.FieldStrength <= 0 {
 			t.Errorf("Scanner %d has invalid field strength: %f", i, s.FieldStrength)
 		}
+		if len(s.ReceiveCoils) == 0 || s.TransmitCoil == "" {
+			t.Errorf("Scanner %d has no receive or transmit coil", i)
+		}
 	}
 }
 
@@ -591,3 +598,167 @@ func TestMGGenerator_WindowPresets(t *testing.T) {
 		}
 	}
 }
+
+// RF Generator Tests
+func TestGetGenerator_RF(t *testing.T) {
+	gen := GetGenerator(RF)
+	if gen.Modality() != RF {
+		t.Errorf("Expected RF modality, got %v", gen.Modality())
+	}
+	if gen.SOPClassUID() != "1.2.840.10008.5.1.4.1.1.12.2" {
+		t.Errorf("Unexpected RF SOP Class UID: %s", gen.SOPClassUID())
+	}
+}
+
+func TestRFGenerator_GenerateSeriesParams(t *testing.T) {
+	gen := &RFGenerator{}
+	rng := rand.New(rand.NewPCG(42, 42))
+	scanner := gen.Scanners()[0]
+
+	params := gen.GenerateSeriesParams(scanner, rng)
+
+	if params.Modality != RF {
+		t.Errorf("Expected RF modality, got %v", params.Modality)
+	}
+	if params.RadiationMode != "PULSED" && params.RadiationMode != "CONTINUOUS" {
+		t.Errorf("Invalid RadiationMode: %s", params.RadiationMode)
+	}
+	if params.DoseAreaProduct < 0.5 || params.DoseAreaProduct > 20 {
+		t.Errorf("Invalid DoseAreaProduct: %f", params.DoseAreaProduct)
+	}
+	if params.EntranceDose <= params.DoseAreaProduct {
+		t.Errorf("EntranceDose %f should exceed the dose area product %f", params.EntranceDose, params.DoseAreaProduct)
+	}
+	if params.ProcessingDescription == "" {
+		t.Error("Empty ProcessingDescription")
+	}
+	if params.DistanceSourceToPatient >= params.DistanceSourceToDetector {
+		t.Errorf("Source to patient %f should be below source to detector %f", params.DistanceSourceToPatient, params.DistanceSourceToDetector)
+	}
+}
+
+func TestUSGenerator_AppendModalityElements_Cine(t *testing.T) {
+	gen := &USGenerator{}
+	params := gen.GenerateSeriesParams(gen.Scanners()[0], rand.New(rand.NewPCG(1, 1)))
+	params.NumberOfFrames = 3
+	params.FrameTime = 33.3
+	params.FrameTimeVector = []float64{0, 32.1, 34.5}
+
+	var ds dicom.Dataset
+	if err := gen.AppendModalityElements(&ds, params); err != nil {
+		t.Fatalf("AppendModalityElements failed: %v", err)
+	}
+	values := func(tg tag.Tag) []string {
+		elem, err := ds.FindElementByTag(tg)
+		if err != nil {
+			t.Fatalf("Missing %v", tg)
+		}
+		return elem.Value.GetValue().([]string)
+	}
+	if got := values(tag.NumberOfFrames); got[0] != "3" {
+		t.Errorf("NumberOfFrames = %v, want 3", got)
+	}
+	if got := values(tag.CineRate); got[0] != "30" {
+		t.Errorf("CineRate = %v, want 30", got)
+	}
+	if got := values(tag.FrameTimeVector); len(got) != 3 || got[1] != "32.1" {
+		t.Errorf("FrameTimeVector = %v, want 0, 32.1, 34.5", got)
+	}
+	pointer, err := ds.FindElementByTag(tag.FrameIncrementPointer)
+	if err != nil {
+		t.Fatal("Missing FrameIncrementPointer")
+	}
+	if got := pointer.Value.GetValue().([]int); len(got) != 2 || got[0] != 0x0018 || got[1] != 0x1065 {
+		t.Errorf("FrameIncrementPointer = %v, want FrameTimeVector", got)
+	}
+}
+
+func TestRFGenerator_AppendModalityElements(t *testing.T) {
+	gen := &RFGenerator{}
+	params := gen.GenerateSeriesParams(gen.Scanners()[0], rand.New(rand.NewPCG(1, 1)))
+
+	var ds dicom.Dataset
+	if err := gen.AppendModalityElements(&ds, params); err != nil {
+		t.Fatalf("AppendModalityElements failed: %v", err)
+	}
+	for _, tg := range []tag.Tag{
+		tag.ImageAndFluoroscopyAreaDoseProduct,
+		tag.EntranceDoseInmGy,
+		tag.AcquisitionDeviceProcessingDescription,
+		tag.RadiationSetting,
+		tag.PixelIntensityRelationship,
+	} {
+		if _, err := ds.FindElementByTag(tg); err != nil {
+			t.Errorf("Missing %v", tg)
+		}
+	}
+}
+
+func TestMRGenerator_AppendModalityElements_Technique(t *testing.T) {
+	gen := &MRGenerator{}
+	scanner := gen.Scanners()[1] // SIEMENS Skyra
+	params := gen.GenerateSeriesParams(scanner, rand.New(rand.NewPCG(1, 1)))
+	params.ParallelFactor = 2
+	params.FrequencyEncodingSteps = 512
+	params.PhaseEncodingFraction = 0.75
+	params.PhaseEncodingDirection = "COL"
+
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(tag.Rows, []int{384}),
+		mustNewElement(tag.Columns, []int{384}),
+	}}
+	if err := gen.AppendModalityElements(&ds, params); err != nil {
+		t.Fatalf("AppendModalityElements failed: %v", err)
+	}
+	values := func(tg tag.Tag) any {
+		elem, err := ds.FindElementByTag(tg)
+		if err != nil {
+			t.Fatalf("Missing %v", tg)
+		}
+		return elem.Value.GetValue()
+	}
+	coil := values(tag.ReceiveCoilName).([]string)[0]
+	found := false
+	for _, c := range scanner.ReceiveCoils {
+		found = found || c == coil
+	}
+	if !found {
+		t.Errorf("ReceiveCoilName = %s, not a coil of the %s", coil, scanner.Model)
+	}
+	if got := values(tag.ParallelAcquisitionTechnique).([]string); got[0] != "GRAPPA" {
+		t.Errorf("ParallelAcquisitionTechnique = %v, want GRAPPA", got)
+	}
+	if got := values(tag.ParallelReductionFactorInPlane).([]float64); got[0] != 2 {
+		t.Errorf("ParallelReductionFactorInPlane = %v, want 2", got)
+	}
+	// 512 frequency steps do not fit the 384 columns
+	matrix := values(tag.AcquisitionMatrix).([]int)
+	if len(matrix) != 4 || matrix[0] != 384 || matrix[1] != 0 || matrix[2] != 0 || matrix[3] != 288 {
+		t.Errorf("AcquisitionMatrix = %v, want 384\\0\\0\\288", matrix)
+	}
+	if got := values(tag.PercentSampling).([]string); got[0] != "75" {
+		t.Errorf("PercentSampling = %v, want 75", got)
+	}
+}
+
+func TestGenerators_ImagesPerSeries(t *testing.T) {
+	tests := []struct {
+		modality Modality
+		min, max int
+	}{
+		{CT, 100, 600},
+		{MR, 20, 40},
+		{CR, 1, 2},
+		{DX, 1, 2},
+		{MG, 4, 4},
+		{US, 1, 30},
+		{RF, 3, 15},
+	}
+
+	for _, tt := range tests {
+		r := GetGenerator(tt.modality).ImagesPerSeries()
+		if r.Min != tt.min || r.Max != tt.max {
+			t.Errorf("%s: ImagesPerSeries() = %s, want %d-%d", tt.modality, r, tt.min, tt.max)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package modalities
 
 import (
+	"math"
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -23,12 +25,18 @@ func (g *MRGenerator) SOPClassUID() string {
 // Scanners returns available MR scanner configurations.
 func (g *MRGenerator) Scanners() []Scanner {
 	return []Scanner{
-		{Manufacturer: "SIEMENS", Model: "Avanto", FieldStrength: 1.5},
-		{Manufacturer: "SIEMENS", Model: "Skyra", FieldStrength: 3.0},
-		{Manufacturer: "GE MEDICAL SYSTEMS", Model: "Signa HDxt", FieldStrength: 1.5},
-		{Manufacturer: "GE MEDICAL SYSTEMS", Model: "Discovery MR750", FieldStrength: 3.0},
-		{Manufacturer: "PHILIPS", Model: "Achieva", FieldStrength: 1.5},
-		{Manufacturer: "PHILIPS", Model: "Ingenia", FieldStrength: 3.0},
+		{Manufacturer: "SIEMENS", Model: "Avanto", FieldStrength: 1.5,
+			ReceiveCoils: []string{"HeadMatrix", "BodyMatrix", "SpineMatrix"}, TransmitCoil: "Body"},
+		{Manufacturer: "SIEMENS", Model: "Skyra", FieldStrength: 3.0,
+			ReceiveCoils: []string{"Head_32", "HeadNeck_20", "Body_18", "Spine_32"}, TransmitCoil: "Body"},
+		{Manufacturer: "GE MEDICAL SYSTEMS", Model: "Signa HDxt", FieldStrength: 1.5,
+			ReceiveCoils: []string{"8HRBRAIN", "HDNV Array", "8US TORSOPA"}, TransmitCoil: "BODY"},
+		{Manufacturer: "GE MEDICAL SYSTEMS", Model: "Discovery MR750", FieldStrength: 3.0,
+			ReceiveCoils: []string{"32Ch Head", "HNS Head", "GEM Body"}, TransmitCoil: "BODY"},
+		{Manufacturer: "PHILIPS", Model: "Achieva", FieldStrength: 1.5,
+			ReceiveCoils: []string{"SENSE-Head-8", "SENSE-Body", "SENSE-Spine"}, TransmitCoil: "B"},
+		{Manufacturer: "PHILIPS", Model: "Ingenia", FieldStrength: 3.0,
+			ReceiveCoils: []string{"dS Head 32ch", "dS HeadNeckSpine", "dS Torso"}, TransmitCoil: "B"},
 	}
 }
 
@@ -52,9 +60,79 @@ func (g *MRGenerator) GenerateSeriesParams(scanner Scanner, rng *rand.Rand) Seri
 	}
 	params.SpacingBetweenSlices = params.SliceThickness + rng.Float64()*0.5
 
+	// Coils and acquisition technique, drawn last so that the values above do
+	// not depend on them
+	if len(scanner.ReceiveCoils) > 0 {
+		params.ReceiveCoilName = scanner.ReceiveCoils[rng.IntN(len(scanner.ReceiveCoils))]
+	}
+	params.TransmitCoilName = scanner.TransmitCoil
+	params.ParallelFactor = parallelFactors[rng.IntN(len(parallelFactors))]
+	params.FrequencyEncodingSteps = frequencyEncodingSteps[rng.IntN(len(frequencyEncodingSteps))]
+	params.PhaseEncodingFraction = 0.6 + rng.Float64()*0.4 // 60-100% of the frequency steps
+	params.PhaseEncodingDirection = "ROW"
+	if rng.IntN(2) == 0 {
+		params.PhaseEncodingDirection = "COL"
+	}
+
 	return params
 }
 
+// Acquisition matrices and in-plane parallel imaging accelerations of MR
+// protocols (1: not accelerated)
+var (
+	frequencyEncodingSteps = []int{256, 320, 384, 448, 512}
+	parallelFactors        = []float64{1, 2, 2, 3}
+)
+
+// parallelTechniques are the parallel acquisition techniques of the
+// manufacturers, in DICOM defined terms: GE's ASSET is a SENSE technique.
+var parallelTechniques = map[string]string{
+	"SIEMENS":            "GRAPPA",
+	"GE MEDICAL SYSTEMS": "SENSE",
+	"PHILIPS":            "SENSE",
+}
+
+// acquisitionMatrixElements returns the acquisition matrix (frequency
+// rows\frequency columns\phase rows\phase columns, 0 in the direction not
+// encoded), phase encoding direction and sampling of an MR image. The
+// frequency steps are at most the size of the image in ds (Rows or Columns)
+// in their direction.
+func acquisitionMatrixElements(ds *dicom.Dataset, p SeriesParams) []*dicom.Element {
+	frequency := p.FrequencyEncodingSteps
+	var matrix []int
+	if p.PhaseEncodingDirection == "ROW" {
+		// Phase along the rows, frequency along the columns
+		if rows := intValue(ds, tag.Rows); rows > 0 {
+			frequency = min(frequency, rows)
+		}
+		matrix = []int{0, frequency, phaseSteps(frequency, p.PhaseEncodingFraction), 0}
+	} else {
+		if columns := intValue(ds, tag.Columns); columns > 0 {
+			frequency = min(frequency, columns)
+		}
+		matrix = []int{frequency, 0, 0, phaseSteps(frequency, p.PhaseEncodingFraction)}
+	}
+	phase := matrix[2] + matrix[3]
+	return []*dicom.Element{
+		mustNewElement(tag.AcquisitionMatrix, matrix),
+		mustNewElement(tag.InPlanePhaseEncodingDirection, []string{p.PhaseEncodingDirection}),
+		mustNewElement(tag.PercentSampling, []string{floatToDS(math.Round(1000*float64(phase)/float64(frequency)) / 10)}),
+		mustNewElement(tag.PercentPhaseFieldOfView, []string{floatToDS(100)}),
+	}
+}
+
+// phaseSteps returns the even number of phase encoding steps of a fraction
+// of the frequency steps.
+func phaseSteps(frequency int, fraction float64) int {
+	return max(2, int(float64(frequency)*fraction/2)*2)
+}
+
+// ImagesPerSeries returns the typical number of MR images in a series.
+func (g *MRGenerator) ImagesPerSeries() util.ImageRange {
+	// One slab of slices per sequence
+	return util.ImageRange{Min: 20, Max: 40}
+}
+
 // PixelConfig returns MR pixel data configuration.
 func (g *MRGenerator) PixelConfig() PixelConfig {
 	return PixelConfig{
@@ -87,6 +165,31 @@ func (g *MRGenerator) AppendModalityElements(ds *dicom.Dataset, params SeriesPar
 	if params.SequenceName != "" {
 		elements = append(elements, mustNewElement(tag.SequenceName, []string{params.SequenceName}))
 	}
+	if params.ReceiveCoilName != "" {
+		elements = append(elements, mustNewElement(tag.ReceiveCoilName, []string{params.ReceiveCoilName}))
+	}
+	if params.TransmitCoilName != "" {
+		elements = append(elements, mustNewElement(tag.TransmitCoilName, []string{params.TransmitCoilName}))
+	}
+	if params.FrequencyEncodingSteps != 0 {
+		elements = append(elements, acquisitionMatrixElements(ds, params)...)
+	}
+	if params.ParallelFactor > 1 {
+		technique, ok := parallelTechniques[params.Scanner.Manufacturer]
+		if !ok {
+			technique = "OTHER"
+		}
+		elements = append(elements,
+			mustNewElement(tag.ParallelAcquisition, []string{"YES"}),
+			mustNewElement(tag.ParallelAcquisitionTechnique, []string{technique}),
+			mustNewElement(tag.ParallelReductionFactorInPlane, []float64{params.ParallelFactor}),
+		)
+	} else if params.ParallelFactor == 1 {
+		elements = append(elements,
+			mustNewElement(tag.ParallelAcquisition, []string{"NO"}),
+			mustNewElement(tag.ParallelReductionFactorInPlane, []float64{1}),
+		)
+	}
 
 	ds.Elements = append(ds.Elements, elements...)
 	return nil
@@ -0,0 +1,31 @@
+package modalities
+
+import "math"
+
+// Reference weight of the adult CT protocols (kg), and the lowest tube
+// current of the protocols adapted to smaller patients (mA)
+const (
+	referenceWeight       = 70.0
+	minAdaptedTubeCurrent = 20
+)
+
+// AdaptToPatientWeight returns the parameters of a series acquired on a
+// patient of weightKg with a size-based CT protocol, as pediatric imaging
+// does: 80 kVp under 10 kg, at most 100 kVp under 50 kg, and the tube
+// current reduced in proportion of the weight below 70 kg. Patients of 70 kg
+// and more, unknown weights (0) and other modalities are unchanged.
+func (p SeriesParams) AdaptToPatientWeight(weightKg float64) SeriesParams {
+	if p.Modality != CT || weightKg <= 0 || weightKg >= referenceWeight {
+		return p
+	}
+	switch {
+	case weightKg < 10:
+		p.KVP = 80
+	case weightKg < 50:
+		p.KVP = math.Min(p.KVP, 100)
+	}
+	if p.XRayTubeCurrent > 0 {
+		p.XRayTubeCurrent = max(minAdaptedTubeCurrent, int(math.Round(float64(p.XRayTubeCurrent)*weightKg/referenceWeight)))
+	}
+	return p
+}
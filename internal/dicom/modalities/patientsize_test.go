@@ -0,0 +1,30 @@
+package modalities
+
+import "testing"
+
+func TestAdaptToPatientWeight(t *testing.T) {
+	ct := SeriesParams{Modality: CT, KVP: 120, XRayTubeCurrent: 300}
+
+	tests := []struct {
+		name     string
+		params   SeriesParams
+		weight   float64
+		wantKVP  float64
+		wantTube int
+	}{
+		{"adult", ct, 80, 120, 300},
+		{"unknown weight", ct, 0, 120, 300},
+		{"child", ct, 35, 100, 150},
+		{"neonate", ct, 3.5, 80, 20},
+		{"low kVp kept", SeriesParams{Modality: CT, KVP: 80, XRayTubeCurrent: 140}, 35, 80, 70},
+		{"not CT", SeriesParams{Modality: MR}, 3.5, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.params.AdaptToPatientWeight(tt.weight)
+			if got.KVP != tt.wantKVP || got.XRayTubeCurrent != tt.wantTube {
+				t.Errorf("AdaptToPatientWeight(%v) = %v kVp, %d mA, want %v kVp, %d mA", tt.weight, got.KVP, got.XRayTubeCurrent, tt.wantKVP, tt.wantTube)
+			}
+		})
+	}
+}
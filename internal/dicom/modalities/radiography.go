@@ -0,0 +1,84 @@
+package modalities
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Grids of radiography series: CR cassettes are often exposed without grid
+// at the bedside, DX detectors mostly have a stationary or moving one
+var (
+	crGrids = []string{"NONE", "FOCUSED", "PARALLEL"}
+	dxGrids = []string{"FOCUSED", "RECIPROCATING", "NONE"}
+)
+
+// drawRadiographyGeometry draws the grid, the collimation and the detector
+// of a radiography series. Collimator blades close by up to 15% of the image
+// on each side.
+func drawRadiographyGeometry(p *SeriesParams, scanner Scanner, grids []string, rng *rand.Rand) {
+	p.Grid = grids[rng.IntN(len(grids))]
+	for i := range p.CollimatorMargins {
+		p.CollimatorMargins[i] = rng.Float64() * 0.15
+	}
+
+	// Serial numbers prefixed with the manufacturer, e.g. "FUJ-004217"
+	prefix := strings.Map(func(r rune) rune {
+		if r < 'A' || r > 'Z' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(scanner.Manufacturer))
+	p.DetectorID = fmt.Sprintf("%.3s-%06d", prefix, rng.IntN(1000000))
+}
+
+// radiographyGeometryElements returns the grid, detector, field of view and
+// rectangular collimator elements of a radiograph. The field of view covers
+// the image at the imager pixel spacing, and the collimator edges are given
+// as columns and rows of the image, from 1, so they need its Rows and
+// Columns in ds.
+func radiographyGeometryElements(ds *dicom.Dataset, p SeriesParams) []*dicom.Element {
+	elements := []*dicom.Element{
+		mustNewElement(tag.Grid, []string{p.Grid}),
+		mustNewElement(tag.DetectorID, []string{p.DetectorID}),
+	}
+
+	rows, columns := intValue(ds, tag.Rows), intValue(ds, tag.Columns)
+	if rows == 0 || columns == 0 {
+		return elements
+	}
+	edge := func(margin float64, size int) int {
+		return int(math.Round(margin * float64(size)))
+	}
+	left, right := 1+edge(p.CollimatorMargins[0], columns), columns-edge(p.CollimatorMargins[1], columns)
+	upper, lower := 1+edge(p.CollimatorMargins[2], rows), rows-edge(p.CollimatorMargins[3], rows)
+
+	return append(elements,
+		mustNewElement(tag.FieldOfViewShape, []string{"RECTANGLE"}),
+		mustNewElement(tag.FieldOfViewDimensions, []string{
+			intToIS(int(math.Round(float64(rows) * p.ImagerPixelSpacing))),
+			intToIS(int(math.Round(float64(columns) * p.ImagerPixelSpacing))),
+		}),
+		mustNewElement(tag.CollimatorShape, []string{"RECTANGULAR"}),
+		mustNewElement(tag.CollimatorLeftVerticalEdge, []string{intToIS(left)}),
+		mustNewElement(tag.CollimatorRightVerticalEdge, []string{intToIS(right)}),
+		mustNewElement(tag.CollimatorUpperHorizontalEdge, []string{intToIS(upper)}),
+		mustNewElement(tag.CollimatorLowerHorizontalEdge, []string{intToIS(lower)}),
+	)
+}
+
+// intValue returns the first value of a US element of ds, 0 when absent.
+func intValue(ds *dicom.Dataset, t tag.Tag) int {
+	elem, err := ds.FindElementByTag(t)
+	if err != nil {
+		return 0
+	}
+	if values, ok := elem.Value.GetValue().([]int); ok && len(values) > 0 {
+		return values[0]
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package modalities
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestRadiographyGeometryElements(t *testing.T) {
+	p := SeriesParams{
+		ImagerPixelSpacing: 0.15,
+		Grid:               "FOCUSED",
+		CollimatorMargins:  [4]float64{0.1, 0, 0.05, 0.125},
+		DetectorID:         "FUJ-000042",
+	}
+	ds := &dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(tag.Rows, []int{2000}),
+		mustNewElement(tag.Columns, []int{1600}),
+	}}
+
+	values := make(map[tag.Tag][]string)
+	for _, elem := range radiographyGeometryElements(ds, p) {
+		values[elem.Tag] = elem.Value.GetValue().([]string)
+	}
+	want := map[tag.Tag][]string{
+		tag.Grid:                          {"FOCUSED"},
+		tag.DetectorID:                    {"FUJ-000042"},
+		tag.FieldOfViewShape:              {"RECTANGLE"},
+		tag.FieldOfViewDimensions:         {"300", "240"},
+		tag.CollimatorShape:               {"RECTANGULAR"},
+		tag.CollimatorLeftVerticalEdge:    {"161"},
+		tag.CollimatorRightVerticalEdge:   {"1600"},
+		tag.CollimatorUpperHorizontalEdge: {"101"},
+		tag.CollimatorLowerHorizontalEdge: {"1750"},
+	}
+	for tg, w := range want {
+		if got := values[tg]; len(got) != len(w) || (len(w) > 0 && got[0] != w[0]) || (len(w) > 1 && got[1] != w[1]) {
+			t.Errorf("%v = %v, want %v", tg, got, w)
+		}
+	}
+
+	// Without image size, only the grid and the detector
+	if got := radiographyGeometryElements(&dicom.Dataset{}, p); len(got) != 2 {
+		t.Errorf("got %d elements without Rows and Columns, want 2", len(got))
+	}
+}
+
+func TestDrawRadiographyGeometry(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	detectorID := regexp.MustCompile(`^[A-Z]{3}-\d{6}$`)
+	for _, gen := range []Generator{&CRGenerator{}, &DXGenerator{}} {
+		for _, scanner := range gen.Scanners() {
+			p := gen.GenerateSeriesParams(scanner, rng)
+			if p.Grid == "" || !detectorID.MatchString(p.DetectorID) {
+				t.Errorf("%s: grid %q, detector %q", scanner.Manufacturer, p.Grid, p.DetectorID)
+			}
+			for _, margin := range p.CollimatorMargins {
+				if margin < 0 || margin > 0.15 {
+					t.Errorf("%s: collimator margin %g", scanner.Manufacturer, margin)
+				}
+			}
+		}
+	}
+}
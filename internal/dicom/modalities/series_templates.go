@@ -7,13 +7,21 @@ import (
 
 // SeriesTemplate defines a template for a series within a study
 type SeriesTemplate struct {
-	SequenceName      string  // MR sequence name (e.g., "T1_SE", "T2_FSE")
-	SeriesDescription string  // Human-readable description
-	Orientation       string  // SAG, AX, COR
-	HasContrast       bool    // Whether this series uses contrast
-	ContrastAgent     string  // Contrast agent name if HasContrast
-	WindowCenter      float64 // Series-specific window center (0 = use default)
-	WindowWidth       float64 // Series-specific window width (0 = use default)
+	SequenceName      string   // MR sequence name (e.g., "T1_SE", "T2_FSE")
+	SeriesDescription string   // Human-readable description
+	Orientation       string   // SAG, AX, COR
+	HasContrast       bool     // Whether this series uses contrast
+	ContrastAgent     string   // Contrast agent name if HasContrast
+	WindowCenter      float64  // Series-specific window center (0 = use default)
+	WindowWidth       float64  // Series-specific window width (0 = use default)
+	Reconstruction    bool     // Reconstructed from another series' acquisition (no irradiation of its own)
+	LocalizerPlanes   []string // Orientations of the images of a localizer series, one image each (nil otherwise)
+}
+
+// IsLocalizer reports whether the series is a localizer (scout), on which
+// the other series of its study are planned.
+func (t SeriesTemplate) IsLocalizer() bool {
+	return len(t.LocalizerPlanes) > 0
 }
 
 // Orientation values
@@ -85,8 +93,8 @@ var ctWithContrastTemplates = []SeriesTemplate{
 // CT without contrast templates
 var ctWithoutContrastTemplates = []SeriesTemplate{
 	{SeriesDescription: "Acquisition standard", Orientation: OrientationAxial},
-	{SeriesDescription: "Reconstruction os", Orientation: OrientationAxial, WindowCenter: 400, WindowWidth: 2000},
-	{SeriesDescription: "Reconstruction poumon", Orientation: OrientationAxial, WindowCenter: -600, WindowWidth: 1500},
+	{SeriesDescription: "Reconstruction os", Orientation: OrientationAxial, WindowCenter: 400, WindowWidth: 2000, Reconstruction: true},
+	{SeriesDescription: "Reconstruction poumon", Orientation: OrientationAxial, WindowCenter: -600, WindowWidth: 1500, Reconstruction: true},
 }
 
 // CR/DX templates - typically single series, multiple views
@@ -111,6 +119,34 @@ var mgTemplates = []SeriesTemplate{
 	{SeriesDescription: "MLO Gauche", Orientation: OrientationAxial},
 }
 
+// RF templates - fluoroscopy-guided contrast examination
+var rfTemplates = []SeriesTemplate{
+	{SeriesDescription: "Scopie", Orientation: OrientationCoronal},
+	{SeriesDescription: "Cliches", Orientation: OrientationCoronal, HasContrast: true, ContrastAgent: "MICROPAQUE"},
+	{SeriesDescription: "Temps tardif", Orientation: OrientationCoronal, HasContrast: true, ContrastAgent: "MICROPAQUE"},
+}
+
+// Localizer templates: MR 3-plane localizer, CT topogram (frontal scout)
+var (
+	mrLocalizerTemplate = SeriesTemplate{SequenceName: "LOCALIZER", SeriesDescription: "3-PLANE LOC", Orientation: OrientationSagittal,
+		LocalizerPlanes: []string{OrientationSagittal, OrientationCoronal, OrientationAxial}}
+	ctLocalizerTemplate = SeriesTemplate{SeriesDescription: "Topogramme", Orientation: OrientationCoronal,
+		LocalizerPlanes: []string{OrientationCoronal}}
+)
+
+// GetLocalizerTemplate returns the template of the localizer series of the
+// given modality, and false for modalities without localizers (only MR and CT
+// plan their series on one).
+func GetLocalizerTemplate(modality Modality) (SeriesTemplate, bool) {
+	switch modality {
+	case MR:
+		return mrLocalizerTemplate, true
+	case CT:
+		return ctLocalizerTemplate, true
+	}
+	return SeriesTemplate{}, false
+}
+
 // GetSeriesTemplates returns series templates for the given modality and body part
 func GetSeriesTemplates(modality Modality, bodyPart string, count int, rng *rand.Rand) []SeriesTemplate {
 	var pool []SeriesTemplate
@@ -142,6 +178,8 @@ func GetSeriesTemplates(modality Modality, bodyPart string, count int, rng *rand
 		pool = usTemplates
 	case MG:
 		pool = mgTemplates
+	case RF:
+		pool = rfTemplates
 	default:
 		pool = mrBrainTemplates
 	}
@@ -174,6 +212,8 @@ func GetDefaultSeriesCount(modality Modality) int {
 		return 2
 	case MG:
 		return 4
+	case RF:
+		return 2
 	default:
 		return 1
 	}
@@ -158,3 +158,27 @@ func TestSeriesTemplatesHaveRequiredFields(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLocalizerTemplate(t *testing.T) {
+	tests := []struct {
+		modality Modality
+		planes   int
+	}{
+		{MR, 3}, // 3-plane localizer
+		{CT, 1}, // Topogram
+		{CR, 0},
+		{US, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.modality), func(t *testing.T) {
+			tmpl, ok := GetLocalizerTemplate(tt.modality)
+			if ok != (tt.planes > 0) || len(tmpl.LocalizerPlanes) != tt.planes {
+				t.Fatalf("GetLocalizerTemplate(%s) = %d planes, %v, want %d", tt.modality, len(tmpl.LocalizerPlanes), ok, tt.planes)
+			}
+			if ok && (!tmpl.IsLocalizer() || tmpl.Orientation != tmpl.LocalizerPlanes[0]) {
+				t.Errorf("localizer template %+v, want oriented as its first plane", tmpl)
+			}
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package modalities
 
 import (
+	"math"
 	"math/rand/v2"
 
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
+// USMultiFrameSOPClassUID is the Ultrasound Multi-frame Image Storage SOP
+// Class UID, for cine loops.
+const USMultiFrameSOPClassUID = "1.2.840.10008.5.1.4.1.1.3.1"
+
 // USGenerator generates US (Ultrasound) specific metadata.
 type USGenerator struct{}
 
@@ -70,6 +76,12 @@ func (g *USGenerator) GenerateSeriesParams(scanner Scanner, rng *rand.Rand) Seri
 	return params
 }
 
+// ImagesPerSeries returns the typical number of US images in a series.
+func (g *USGenerator) ImagesPerSeries() util.ImageRange {
+	// Captured frames, from a single snapshot to a full exam
+	return util.ImageRange{Min: 1, Max: 30}
+}
+
 // PixelConfig returns US pixel data configuration.
 func (g *USGenerator) PixelConfig() PixelConfig {
 	return PixelConfig{
@@ -88,11 +100,32 @@ func (g *USGenerator) AppendModalityElements(ds *dicom.Dataset, params SeriesPar
 	// Convert transducer frequency from MHz to Hz (UL tag expects integer Hz)
 	transducerFreqHz := int(params.TransducerFrequency * 1000000)
 
+	frames := max(params.NumberOfFrames, 1)
 	elements := []*dicom.Element{
 		mustNewElement(tag.TransducerType, []string{params.TransducerType}),
 		mustNewElement(tag.TransducerFrequency, []int{transducerFreqHz}),
-		// Number of frames (single frame for now)
-		mustNewElement(tag.NumberOfFrames, []string{"1"}),
+		mustNewElement(tag.NumberOfFrames, []string{intToIS(frames)}),
+	}
+
+	// Cine loop: frames follow each other at FrameTime, or at the intervals
+	// of FrameTimeVector when given
+	if frames > 1 {
+		frameRate := intToIS(int(math.Round(1000 / params.FrameTime)))
+		increment := tag.FrameTime
+		elements = append(elements,
+			mustNewElement(tag.FrameTime, []string{floatToDS(params.FrameTime)}),
+			mustNewElement(tag.CineRate, []string{frameRate}),
+			mustNewElement(tag.RecommendedDisplayFrameRate, []string{frameRate}),
+		)
+		if len(params.FrameTimeVector) > 0 {
+			vector := make([]string, len(params.FrameTimeVector))
+			for i, v := range params.FrameTimeVector {
+				vector[i] = floatToDS(v)
+			}
+			elements = append(elements, mustNewElement(tag.FrameTimeVector, vector))
+			increment = tag.FrameTimeVector
+		}
+		elements = append(elements, mustNewElement(tag.FrameIncrementPointer, []int{int(increment.Group), int(increment.Element)}))
 	}
 
 	ds.Elements = append(ds.Elements, elements...)
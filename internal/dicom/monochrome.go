@@ -0,0 +1,65 @@
+package dicom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Photometric interpretations of monochrome images, and the mode alternating
+// them from series to series
+const (
+	PhotometricMonochrome1 = "MONOCHROME1"
+	PhotometricMonochrome2 = "MONOCHROME2"
+	PhotometricMixed       = "MIXED"
+)
+
+// ParsePhotometric parses a forced monochrome photometric interpretation:
+// MONOCHROME1, MONOCHROME2 or MIXED, case-insensitive. "" keeps the
+// interpretation of the modality.
+func ParsePhotometric(s string) (string, error) {
+	switch v := strings.ToUpper(strings.TrimSpace(s)); v {
+	case "", PhotometricMonochrome1, PhotometricMonochrome2, PhotometricMixed:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid photometric interpretation %q (expected MONOCHROME1, MONOCHROME2 or MIXED)", s)
+	}
+}
+
+// seriesPhotometric returns the photometric interpretation forced on the
+// images of a series (numbered from 1), "" to keep the modality's. MIXED
+// alternates MONOCHROME1 on odd series and MONOCHROME2 on even ones.
+func seriesPhotometric(opts GeneratorOptions, seriesNum int) string {
+	if opts.Photometric != PhotometricMixed {
+		return opts.Photometric
+	}
+	if seriesNum%2 == 1 {
+		return PhotometricMonochrome1
+	}
+	return PhotometricMonochrome2
+}
+
+// monochromeElements leaves a single PhotometricInterpretation in elements:
+// photometric or, when "", the last one (a modality value such as MG's
+// MONOCHROME1 overriding the default MONOCHROME2). Pixel values are not
+// changed: a viewer honoring MONOCHROME1 displays the image inverted.
+func monochromeElements(elements []*dicom.Element, photometric string) []*dicom.Element {
+	idx := -1
+	result := elements[:0]
+	for _, elem := range elements {
+		if elem.Tag == tag.PhotometricInterpretation {
+			if idx >= 0 {
+				result[idx] = elem
+				continue
+			}
+			idx = len(result)
+		}
+		result = append(result, elem)
+	}
+	if photometric != "" && idx >= 0 {
+		result[idx] = mustNewElement(tag.PhotometricInterpretation, []string{photometric})
+	}
+	return result
+}
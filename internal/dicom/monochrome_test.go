@@ -0,0 +1,62 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParsePhotometric(t *testing.T) {
+	for input, want := range map[string]string{"": "", "monochrome1": PhotometricMonochrome1, "MONOCHROME2": PhotometricMonochrome2, "mixed": PhotometricMixed} {
+		got, err := ParsePhotometric(input)
+		if err != nil || got != want {
+			t.Errorf("ParsePhotometric(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParsePhotometric("RGB"); err == nil {
+		t.Error("ParsePhotometric should reject color interpretations")
+	}
+}
+
+func TestSeriesPhotometric(t *testing.T) {
+	opts := GeneratorOptions{Photometric: PhotometricMixed}
+	for seriesNum, want := range map[int]string{1: PhotometricMonochrome1, 2: PhotometricMonochrome2, 3: PhotometricMonochrome1} {
+		if got := seriesPhotometric(opts, seriesNum); got != want {
+			t.Errorf("series %d: %q, want %q", seriesNum, got, want)
+		}
+	}
+	if got := seriesPhotometric(GeneratorOptions{}, 1); got != "" {
+		t.Errorf("no forced interpretation: %q, want \"\"", got)
+	}
+}
+
+func TestMonochromeElements(t *testing.T) {
+	// MG appends MONOCHROME1 after the default MONOCHROME2
+	elements := func() []*dicom.Element {
+		return []*dicom.Element{
+			mustNewElement(tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+			mustNewElement(tag.Rows, []int{64}),
+			mustNewElement(tag.PhotometricInterpretation, []string{"MONOCHROME1"}),
+		}
+	}
+
+	tests := []struct {
+		photometric, want string
+	}{
+		{"", "MONOCHROME1"},
+		{PhotometricMonochrome2, "MONOCHROME2"},
+	}
+	for _, tt := range tests {
+		result := monochromeElements(elements(), tt.photometric)
+		var values []string
+		for _, elem := range result {
+			if elem.Tag == tag.PhotometricInterpretation {
+				values = append(values, elem.Value.GetValue().([]string)[0])
+			}
+		}
+		if len(result) != 2 || len(values) != 1 || values[0] != tt.want {
+			t.Errorf("monochromeElements(%q): %d elements, interpretations %v, want one %s", tt.photometric, len(result), values, tt.want)
+		}
+	}
+}
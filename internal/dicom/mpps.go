@@ -0,0 +1,207 @@
+package dicom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// MPPSDir is the directory of the Modality Performed Procedure Step messages,
+// inside the output directory.
+const MPPSDir = "MPPS"
+
+// ModalityPerformedProcedureStepSOPClass is the SOP class of the MPPS
+// N-CREATE and N-SET requests.
+const ModalityPerformedProcedureStepSOPClass = "1.2.840.10008.3.1.2.3.3"
+
+// ExportMPPS writes the Modality Performed Procedure Step messages (PS3.4
+// F.7) of each study of the PT*/ST*/SE* hierarchy of outputDir, as the
+// modality on stationAE would send them: MPPS/PPSnnnnnn.create.dcm, the
+// N-CREATE attributes of the step IN PROGRESS at the StudyDate and
+// StudyTime, and MPPS/PPSnnnnnn.set.dcm, the N-SET attributes COMPLETING it
+// with the acquired series and their images. The step refers to the
+// requested and scheduled procedure step of the --worklist item of the study,
+// and its SOP Instance UID, in the File Meta Information of both files,
+// derives from the Study Instance UID, under uidRoot (util.DefaultUIDRoot
+// when empty). Unchanged files are kept (rerun of
+// the same profile). It returns the number of performed procedure steps.
+func ExportMPPS(outputDir, stationAE, uidRoot string, quiet bool) (int, error) {
+	if stationAE == "" {
+		stationAE = DefaultWorklistStationAE
+	}
+	dir := filepath.Join(outputDir, MPPSDir)
+	n := 0
+	for _, patientDir := range hierarchyEntries(outputDir, "PT") {
+		for _, studyDir := range hierarchyEntries(patientDir, "ST") {
+			series, err := performedSeries(studyDir)
+			if err != nil {
+				return n, err
+			}
+			if len(series) == 0 {
+				continue
+			}
+			n++
+			if n == 1 {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return 0, fmt.Errorf("create directory: %w", err)
+				}
+			}
+			create, set := mppsMessages(series, stationAE, uidRoot, n)
+			for suffix, ds := range map[string]dicom.Dataset{"create": create, "set": set} {
+				dest := filepath.Join(dir, fmt.Sprintf("PPS%06d.%s.dcm", n, suffix))
+				tmpPath := dest + ".tmp"
+				if err := writeDatasetToFile(tmpPath, ds); err != nil {
+					return n, fmt.Errorf("write %s: %w", tmpPath, err)
+				}
+				if _, err := moveIfChanged(tmpPath, dest); err != nil {
+					return n, err
+				}
+			}
+		}
+	}
+
+	if !quiet {
+		fmt.Printf("  Exported %d Modality Performed Procedure Steps into %s/ (station AE %s)\n", n, MPPSDir, stationAE)
+	}
+	return n, nil
+}
+
+// performedSeries returns the headers of the instances of the acquired
+// series of a study directory, or of all its series when all are derived.
+func performedSeries(studyDir string) ([][]dicom.Dataset, error) {
+	var acquired, all [][]dicom.Dataset
+	for _, seriesDir := range hierarchyEntries(studyDir, "SE") {
+		var instances []dicom.Dataset
+		for _, image := range hierarchyEntries(seriesDir, "IM") {
+			ds, err := parseDICOMTolerant(image)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", image, err)
+			}
+			instances = append(instances, ds)
+		}
+		if len(instances) == 0 {
+			continue
+		}
+		all = append(all, instances)
+		if !derivedModalities[getStringValue(instances[0], tag.Modality)[0]] {
+			acquired = append(acquired, instances)
+		}
+	}
+	if len(acquired) == 0 {
+		return all, nil
+	}
+	return acquired, nil
+}
+
+// mppsMessages builds the N-CREATE and N-SET attributes of performed
+// procedure step n from the instances of its series, identified by a UID
+// under uidRoot.
+func mppsMessages(series [][]dicom.Dataset, stationAE, uidRoot string, n int) (create, set dicom.Dataset) {
+	study := series[0][0]
+	value := func(t tag.Tag) []string {
+		return getStringValue(study, t)
+	}
+	copied := func(tags ...tag.Tag) []*dicom.Element {
+		var elements []*dicom.Element
+		for _, t := range tags {
+			if elem, err := study.FindElementByTag(t); err == nil {
+				elements = append(elements, elem)
+			} else {
+				elements = append(elements, mustNewElement(t, []string{""}))
+			}
+		}
+		return elements
+	}
+	uid := util.GenerateDeterministicUIDWithRoot(uidRoot, value(tag.StudyInstanceUID)[0]+"_mpps")
+	meta := func() []*dicom.Element {
+		return []*dicom.Element{
+			mustNewElement(tag.MediaStorageSOPClassUID, []string{ModalityPerformedProcedureStepSOPClass}),
+			mustNewElement(tag.MediaStorageSOPInstanceUID, []string{uid}),
+			mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		}
+	}
+
+	// The scheduled step of the worklist item, as the modality selected it
+	scheduled := append(copied(tag.StudyInstanceUID, tag.AccessionNumber, tag.RequestedProcedureDescription),
+		mustNewElement(tag.ReferencedStudySequence, [][]*dicom.Element{}),
+		mustNewElement(tag.RequestedProcedureID, []string{fmt.Sprintf("RP%06d", n)}),
+		mustNewElement(tag.ScheduledProcedureStepDescription, value(tag.StudyDescription)),
+		mustNewElement(tag.ScheduledProtocolCodeSequence, [][]*dicom.Element{}),
+		mustNewElement(tag.ScheduledProcedureStepID, []string{fmt.Sprintf("SPS%06d", n)}),
+	)
+	sortElements(scheduled)
+
+	createElements := append(append(meta(), copied(tag.SpecificCharacterSet, tag.PatientName, tag.PatientID, tag.PatientBirthDate, tag.PatientSex, tag.Modality, tag.StudyID)...),
+		mustNewElement(tag.ReferencedPatientSequence, [][]*dicom.Element{}),
+		mustNewElement(tag.ScheduledStepAttributesSequence, [][]*dicom.Element{scheduled}),
+		mustNewElement(tag.ProcedureCodeSequence, [][]*dicom.Element{}),
+		mustNewElement(tag.PerformedStationAETitle, []string{stationAE}),
+		mustNewElement(tag.PerformedStationName, value(tag.StationName)),
+		mustNewElement(tag.PerformedLocation, value(tag.InstitutionName)),
+		mustNewElement(tag.PerformedProcedureStepStartDate, value(tag.StudyDate)),
+		mustNewElement(tag.PerformedProcedureStepStartTime, value(tag.StudyTime)),
+		mustNewElement(tag.PerformedProcedureStepEndDate, []string{""}),
+		mustNewElement(tag.PerformedProcedureStepEndTime, []string{""}),
+		mustNewElement(tag.PerformedProcedureStepStatus, []string{"IN PROGRESS"}),
+		mustNewElement(tag.PerformedProcedureStepID, []string{fmt.Sprintf("PPS%06d", n)}),
+		mustNewElement(tag.PerformedProcedureStepDescription, value(tag.StudyDescription)),
+		mustNewElement(tag.PerformedProcedureTypeDescription, []string{""}),
+		mustNewElement(tag.PerformedProtocolCodeSequence, [][]*dicom.Element{}),
+		mustNewElement(tag.PerformedSeriesSequence, [][]*dicom.Element{}),
+	)
+	sortElements(createElements)
+
+	// Completed at the last acquisition of its series
+	endDate, endTime := value(tag.StudyDate)[0], value(tag.StudyTime)[0]
+	var performed [][]*dicom.Element
+	for _, instances := range series {
+		first := instances[0]
+		var images [][]*dicom.Element
+		for _, inst := range instances {
+			images = append(images, []*dicom.Element{
+				mustNewElement(tag.ReferencedSOPClassUID, getStringValue(inst, tag.SOPClassUID)),
+				mustNewElement(tag.ReferencedSOPInstanceUID, getStringValue(inst, tag.SOPInstanceUID)),
+			})
+			date, time := getStringValue(inst, tag.AcquisitionDate)[0], getStringValue(inst, tag.AcquisitionTime)[0]
+			if date != "" && (date > endDate || (date == endDate && time > endTime)) {
+				endDate, endTime = date, time
+			}
+		}
+		item := []*dicom.Element{
+			mustNewElement(tag.PerformingPhysicianName, getStringValue(first, tag.PerformingPhysicianName)),
+			mustNewElement(tag.ProtocolName, getStringValue(first, tag.ProtocolName)),
+			mustNewElement(tag.OperatorsName, getStringValue(first, tag.OperatorsName)),
+			mustNewElement(tag.SeriesInstanceUID, getStringValue(first, tag.SeriesInstanceUID)),
+			mustNewElement(tag.SeriesDescription, getStringValue(first, tag.SeriesDescription)),
+			mustNewElement(tag.RetrieveAETitle, []string{""}),
+			mustNewElement(tag.ReferencedImageSequence, images),
+			mustNewElement(tag.ReferencedNonImageCompositeSOPInstanceSequence, [][]*dicom.Element{}),
+		}
+		sortElements(item)
+		performed = append(performed, item)
+	}
+	setElements := append(meta(),
+		mustNewElement(tag.PerformedProcedureStepEndDate, []string{endDate}),
+		mustNewElement(tag.PerformedProcedureStepEndTime, []string{endTime}),
+		mustNewElement(tag.PerformedProcedureStepStatus, []string{"COMPLETED"}),
+		mustNewElement(tag.PerformedSeriesSequence, performed),
+	)
+	sortElements(setElements)
+	return dicom.Dataset{Elements: createElements}, dicom.Dataset{Elements: setElements}
+}
+
+// sortElements sorts elements by tag, the order in which they are written.
+func sortElements(elements []*dicom.Element) {
+	sort.Slice(elements, func(a, b int) bool {
+		if elements[a].Tag.Group != elements[b].Tag.Group {
+			return elements[a].Tag.Group < elements[b].Tag.Group
+		}
+		return elements[a].Tag.Element < elements[b].Tag.Element
+	})
+}
@@ -0,0 +1,70 @@
+package dicom
+
+import (
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	dfimage "github.com/mrsinham/dicomforge/internal/image"
+)
+
+// mrContrast is the look of the images of an MR sequence, as fractions of
+// the pixel value range: the level of the noise images at their corners, the
+// brightness their center adds (negative when darker), the scale of their
+// noise and their window, and the tissue values of the head phantom.
+type mrContrast struct {
+	base, gain, noise         float64
+	windowCenter, windowWidth float64
+	tissues                   [dfimage.NumTissues]float64
+}
+
+// Contrasts of the MR sequences, by weighting: T1 (bright fat at the edges,
+// dark fluid), T2 (bright fluid in the center), FLAIR (flat, dark fluid and
+// bright lesions), DWI (low signal, grainy, bright restricted diffusion), PD
+// (bright, little contrast) and STIR (fat suppressed, bright fluid)
+var (
+	mrContrastT1    = mrContrast{base: 0.5, gain: -0.2, noise: 0.5, windowCenter: 0.4, windowWidth: 0.6, tissues: [dfimage.NumTissues]float64{dfimage.Air: 0.01, dfimage.Bone: 0.04, dfimage.Brain: 0.25, dfimage.CSF: 0.08, dfimage.Lesion: 0.4}}
+	mrContrastT2    = mrContrast{base: 0.1, gain: 0.6, noise: 0.6, windowCenter: 0.4, windowWidth: 0.8, tissues: [dfimage.NumTissues]float64{dfimage.Air: 0.01, dfimage.Bone: 0.03, dfimage.Brain: 0.2, dfimage.CSF: 0.7, dfimage.Lesion: 0.5}}
+	mrContrastFLAIR = mrContrast{base: 0.35, gain: 0.1, noise: 0.3, windowCenter: 0.4, windowWidth: 0.5, tissues: [dfimage.NumTissues]float64{dfimage.Air: 0.01, dfimage.Bone: 0.03, dfimage.Brain: 0.3, dfimage.CSF: 0.05, dfimage.Lesion: 0.65}}
+	mrContrastDWI   = mrContrast{base: 0.05, gain: 0.15, noise: 1.2, windowCenter: 0.3, windowWidth: 0.6, tissues: [dfimage.NumTissues]float64{dfimage.Air: 0.02, dfimage.Bone: 0.02, dfimage.Brain: 0.2, dfimage.CSF: 0.05, dfimage.Lesion: 0.8}}
+	mrContrastPD    = mrContrast{base: 0.5, gain: 0.1, noise: 0.4, windowCenter: 0.5, windowWidth: 0.6, tissues: [dfimage.NumTissues]float64{dfimage.Air: 0.01, dfimage.Bone: 0.05, dfimage.Brain: 0.45, dfimage.CSF: 0.55, dfimage.Lesion: 0.5}}
+	mrContrastSTIR  = mrContrast{base: 0.05, gain: 0.55, noise: 0.8, windowCenter: 0.35, windowWidth: 0.8, tissues: [dfimage.NumTissues]float64{dfimage.Air: 0.01, dfimage.Bone: 0.02, dfimage.Brain: 0.15, dfimage.CSF: 0.75, dfimage.Lesion: 0.6}}
+)
+
+// mrContrastOf returns the contrast of the images of an MR sequence, from
+// its SequenceName: T1 for unknown sequences.
+func mrContrastOf(sequence string) mrContrast {
+	s := strings.ToUpper(sequence)
+	switch {
+	case strings.Contains(s, "FLAIR"):
+		return mrContrastFLAIR
+	case isDiffusion(sequence):
+		return mrContrastDWI
+	case strings.HasPrefix(s, "STIR"):
+		return mrContrastSTIR
+	case strings.HasPrefix(s, "PD"):
+		return mrContrastPD
+	case strings.HasPrefix(s, "T2"):
+		return mrContrastT2
+	}
+	return mrContrastT1
+}
+
+// isDiffusion reports whether an MR sequence is a diffusion-weighted one.
+func isDiffusion(sequence string) bool {
+	s := strings.ToUpper(sequence)
+	return strings.HasPrefix(s, "DWI") || strings.Contains(s, "DIFF")
+}
+
+// value returns the stored value of a noise image pixel at normalizedDist
+// from the center (0 to 1, at the corners), with the noise drawn for it (in
+// stored values).
+func (c mrContrast) value(cfg modalities.PixelConfig, normalizedDist, noise float64) float64 {
+	valueRange := float64(cfg.MaxValue - cfg.MinValue)
+	return float64(cfg.MinValue) + valueRange*(c.base+(1-normalizedDist)*c.gain) + noise*c.noise
+}
+
+// window returns the window of the images of the contrast, in stored values.
+func (c mrContrast) window(cfg modalities.PixelConfig) (center, width float64) {
+	valueRange := float64(cfg.MaxValue - cfg.MinValue)
+	return float64(cfg.MinValue) + valueRange*c.windowCenter, valueRange * c.windowWidth
+}
@@ -0,0 +1,89 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestMRContrastOf(t *testing.T) {
+	tests := map[string]mrContrast{
+		"T1_SE":     mrContrastT1,
+		"T1_MPRAGE": mrContrastT1,
+		"T2_FSE":    mrContrastT2,
+		"T2_STAR":   mrContrastT2,
+		"T2_FLAIR":  mrContrastFLAIR,
+		"DWI":       mrContrastDWI,
+		"ep_diff":   mrContrastDWI,
+		"PD_FSE":    mrContrastPD,
+		"STIR":      mrContrastSTIR,
+		"":          mrContrastT1,
+	}
+	for sequence, want := range tests {
+		if got := mrContrastOf(sequence); got != want {
+			t.Errorf("mrContrastOf(%q) = %+v, want %+v", sequence, got, want)
+		}
+	}
+}
+
+func TestMRSequenceContrast(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:      5,
+		TotalSize:      "1MB",
+		Modality:       modalities.MR,
+		BodyPart:       "HEAD",
+		SeriesPerStudy: util.SeriesRange{Min: 5, Max: 5},
+		OutputDir:      t.TempDir(),
+		Seed:           42,
+		NumStudies:     1,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	// Center and corner brightness of the image of each sequence
+	type look struct{ center, corner float64 }
+	looks := map[mrContrast]look{}
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixelData, err := ds.FindElementByTag(tag.PixelData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		native, err := dicom.MustGetPixelDataInfo(pixelData.Value).Frames[0].GetNativeFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixels, cols := native.RawDataSlice().([]uint16), native.Cols()
+		mean := func(x0, y0 int) float64 {
+			sum := 0.0
+			for y := y0; y < y0+16; y++ {
+				for x := x0; x < x0+16; x++ {
+					sum += float64(pixels[y*cols+x])
+				}
+			}
+			return sum / 256
+		}
+		// Above the text overlay
+		looks[mrContrastOf(elementString(ds.Elements, tag.SequenceName))] = look{mean(cols/2-8, cols/4), mean(0, 0)}
+	}
+
+	t1, ok1 := looks[mrContrastT1]
+	t2, ok2 := looks[mrContrastT2]
+	if !ok1 || !ok2 {
+		t.Fatalf("no T1 or T2 series in %d series", len(looks))
+	}
+	if t1.center >= t1.corner {
+		t.Errorf("T1 center %.0f not darker than its corner %.0f", t1.center, t1.corner)
+	}
+	if t2.center <= t2.corner*2 {
+		t.Errorf("T2 center %.0f not much brighter than its corner %.0f", t2.center, t2.corner)
+	}
+}
@@ -0,0 +1,125 @@
+package dicom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+
+	"github.com/mrsinham/dicomforge/internal/objectstore"
+)
+
+// DefaultUploadConcurrency is the number of objects an ObjectWriter
+// uploads at a time.
+const DefaultUploadConcurrency = 8
+
+// ObjectKey returns the key of an instance in object storage:
+// <prefix>/<StudyInstanceUID>/<SeriesInstanceUID>/<SOPInstanceUID>.dcm, a
+// prefix per study, as cloud archives lay out their buckets.
+func ObjectKey(prefix string, f GeneratedFile) string {
+	return path.Join(prefix, f.StudyUID, f.SeriesUID, f.SOPInstanceUID+".dcm")
+}
+
+// ObjectWriter uploads the instances of a streamed generation to object
+// storage, under their ObjectKey, several at a time. The first failed
+// upload fails the next WriteInstance, and Close.
+type ObjectWriter struct {
+	store  objectstore.Store
+	prefix string
+	ctx    context.Context
+	jobs   chan objectUpload
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	err     error
+	objects int
+	bytes   int64
+}
+
+// objectUpload is an instance read for upload.
+type objectUpload struct {
+	key  string
+	data []byte
+}
+
+// NewObjectWriter returns an ObjectWriter uploading to store under prefix,
+// concurrency objects at a time (0 = DefaultUploadConcurrency). Close it to
+// wait for the last uploads.
+func NewObjectWriter(ctx context.Context, store objectstore.Store, prefix string, concurrency int) *ObjectWriter {
+	if concurrency <= 0 {
+		concurrency = DefaultUploadConcurrency
+	}
+	w := &ObjectWriter{
+		store:  store,
+		prefix: prefix,
+		ctx:    ctx,
+		jobs:   make(chan objectUpload),
+	}
+	for i := 0; i < concurrency; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for job := range w.jobs {
+				w.upload(job)
+			}
+		}()
+	}
+	return w
+}
+
+// upload puts an object and records its outcome.
+func (w *ObjectWriter) upload(job objectUpload) {
+	if w.failed() != nil {
+		return
+	}
+	err := w.store.Put(w.ctx, job.key, job.data, "application/dicom")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		return
+	}
+	w.objects++
+	w.bytes += int64(len(job.data))
+}
+
+// failed returns the first upload error.
+func (w *ObjectWriter) failed() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// WriteInstance reads an instance and queues its upload.
+func (w *ObjectWriter) WriteInstance(f GeneratedFile, r io.Reader) error {
+	if err := w.failed(); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read instance %s: %w", f.SOPInstanceUID, err)
+	}
+	select {
+	case w.jobs <- objectUpload{key: ObjectKey(w.prefix, f), data: data}:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
+
+// Close waits for the queued uploads and returns the first error.
+func (w *ObjectWriter) Close() error {
+	close(w.jobs)
+	w.wg.Wait()
+	return w.failed()
+}
+
+// Uploaded returns the number of objects uploaded and their bytes.
+func (w *ObjectWriter) Uploaded() (int, int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.objects, w.bytes
+}
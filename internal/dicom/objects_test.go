@@ -0,0 +1,93 @@
+package dicom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// memoryStore is an object store in memory.
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	err     error
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryStore) URL(key string) string {
+	return "mem://" + key
+}
+
+func TestObjectWriter(t *testing.T) {
+	store := &memoryStore{objects: make(map[string][]byte)}
+	w := NewObjectWriter(context.Background(), store, "load/run1", 4)
+	files, err := StreamDICOMSeries(GeneratorOptions{
+		NumImages:  6,
+		TotalSize:  "1MB",
+		OutputDir:  "run1",
+		Seed:       42,
+		NumStudies: 2,
+		Quiet:      true,
+	}, w)
+	if err != nil {
+		t.Fatalf("StreamDICOMSeries failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	objects, size := w.Uploaded()
+	if objects != len(files) || len(store.objects) != len(files) {
+		t.Fatalf("uploaded %d objects, stored %d, want %d", objects, len(store.objects), len(files))
+	}
+	var total int64
+	for _, f := range files {
+		key := "load/run1/" + f.StudyUID + "/" + f.SeriesUID + "/" + f.SOPInstanceUID + ".dcm"
+		if ObjectKey("load/run1", f) != key {
+			t.Errorf("ObjectKey = %q, want %q", ObjectKey("load/run1", f), key)
+		}
+		data, ok := store.objects[key]
+		if !ok {
+			t.Fatalf("no object %s", key)
+		}
+		if len(data) < 132 || string(data[128:132]) != "DICM" {
+			t.Errorf("object %s is not a DICOM file", key)
+		}
+		total += int64(len(data))
+	}
+	if size != total {
+		t.Errorf("uploaded %d bytes, stored %d", size, total)
+	}
+}
+
+func TestObjectWriter_Error(t *testing.T) {
+	failure := errors.New("bucket gone")
+	w := NewObjectWriter(context.Background(), &memoryStore{err: failure}, "", 2)
+	_, err := StreamDICOMSeries(GeneratorOptions{
+		NumImages:  3,
+		TotalSize:  "1MB",
+		OutputDir:  "run1",
+		Seed:       42,
+		NumStudies: 1,
+		Quiet:      true,
+	}, w)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if !errors.Is(err, failure) {
+		t.Errorf("got %v, want the upload error", err)
+	}
+	if objects, _ := w.Uploaded(); objects != 0 {
+		t.Errorf("uploaded %d objects", objects)
+	}
+}
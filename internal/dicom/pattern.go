@@ -0,0 +1,50 @@
+package dicom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	dfimage "github.com/mrsinham/dicomforge/internal/image"
+)
+
+// Test patterns of the pixel data, for display calibration and scaling
+const (
+	PatternGradient     = "gradient"
+	PatternCheckerboard = "checkerboard"
+	PatternBars         = "bars"
+	PatternSMPTE        = "smpte"
+)
+
+// patterns render the test patterns, by name
+var patterns = map[string]func(x, y, width, height int) float64{
+	PatternGradient:     dfimage.Gradient,
+	PatternCheckerboard: dfimage.Checkerboard,
+	PatternBars:         dfimage.ResolutionBars,
+	PatternSMPTE:        dfimage.SMPTE,
+}
+
+// ParsePattern parses a test pattern: gradient, checkerboard, bars or smpte,
+// case-insensitive. "" keeps the noise images.
+func ParsePattern(s string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	if _, ok := patterns[v]; ok || v == "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid pattern %q (expected gradient, checkerboard, bars or smpte)", s)
+}
+
+// patternWindow returns the window over the whole pixel range of cfg, in
+// which the levels of the test patterns show as their share of the display
+// range.
+func patternWindow(cfg modalities.PixelConfig) (center, width float64) {
+	width = float64(cfg.MaxValue-cfg.MinValue) + 1
+	return float64(cfg.MinValue) + width/2, width
+}
+
+// patternValue returns the stored value of pixel (x, y) of a width x height
+// image of the test pattern: its level over the pixel range of cfg, from
+// MinValue, stored as 0, to MaxValue.
+func patternValue(pattern string, cfg modalities.PixelConfig, x, y, width, height int) float64 {
+	return patterns[pattern](x, y, width, height) * float64(cfg.MaxValue-cfg.MinValue)
+}
@@ -0,0 +1,77 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParsePattern(t *testing.T) {
+	for in, want := range map[string]string{"": "", "SMPTE": PatternSMPTE, " bars ": PatternBars} {
+		if got, err := ParsePattern(in); err != nil || got != want {
+			t.Errorf("ParsePattern(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePattern("stripes"); err == nil {
+		t.Error("no error for an unknown pattern")
+	}
+}
+
+func TestPattern(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:  1,
+		TotalSize:  "1MB",
+		Modality:   modalities.CT,
+		Pattern:    PatternGradient,
+		OutputDir:  t.TempDir(),
+		Seed:       42,
+		NumStudies: 1,
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ds, err := dicom.ParseFile(files[0].Path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Window over the whole range, -1024 to 3071 HU
+	for tg, want := range map[tag.Tag]string{tag.WindowCenter: "1024", tag.WindowWidth: "4096"} {
+		if got := elementString(ds.Elements, tg); got != want {
+			t.Errorf("%v = %q, want %q", tg, got, want)
+		}
+	}
+
+	pixelData, err := ds.FindElementByTag(tag.PixelData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, err := dicom.MustGetPixelDataInfo(pixelData.Value).Frames[0].GetNativeFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pixels, cols, rows := native.RawDataSlice().([]uint16), native.Cols(), native.Rows()
+	// Steps of the bottom half, without noise: black to white
+	if got := pixels[(rows-1)*cols]; got != 0 {
+		t.Errorf("first step is %d, want 0", got)
+	}
+	if got := pixels[rows*cols-1]; got != 4095 {
+		t.Errorf("last step is %d, want 4095", got)
+	}
+
+	_, err = GenerateDICOMSeries(GeneratorOptions{
+		NumImages:  1,
+		TotalSize:  "1MB",
+		Pattern:    PatternSMPTE,
+		Phantom:    true,
+		OutputDir:  t.TempDir(),
+		NumStudies: 1,
+		Quiet:      true,
+	})
+	if err == nil {
+		t.Error("no error with both a pattern and the phantom")
+	}
+}
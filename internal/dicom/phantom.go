@@ -0,0 +1,89 @@
+package dicom
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	dfimage "github.com/mrsinham/dicomforge/internal/image"
+)
+
+// phantomSlice is the plane of an image through the head phantom, with the
+// stored pixel value of each tissue.
+type phantomSlice struct {
+	plane  dfimage.PhantomPlane
+	levels [dfimage.NumTissues]float64
+	noise  float64 // Standard deviation of the noise, in stored values
+}
+
+// Tissue values of CT images, in Hounsfield units: a brain window (40/80)
+// separates brain, CSF and nodules, a bone window the skull
+var phantomHU = [dfimage.NumTissues]float64{
+	dfimage.Air:    -1000,
+	dfimage.Bone:   1000,
+	dfimage.Brain:  35,
+	dfimage.CSF:    8,
+	dfimage.Lesion: 70,
+}
+
+// Tissue values of the other modalities, as fractions of their pixel value
+// range: X-ray attenuation (bright bone) and ultrasound echogenicity
+// (anechoic CSF); MR ones follow the contrast of the sequence
+var (
+	phantomXRay = [dfimage.NumTissues]float64{dfimage.Air: 0.05, dfimage.Bone: 0.8, dfimage.Brain: 0.35, dfimage.CSF: 0.3, dfimage.Lesion: 0.45}
+	phantomUS   = [dfimage.NumTissues]float64{dfimage.Air: 0, dfimage.Bone: 0.9, dfimage.Brain: 0.35, dfimage.CSF: 0.05, dfimage.Lesion: 0.6}
+)
+
+// newPhantomSlice returns the slice of the head phantom shown by the image
+// instance (1-based) of a series of n images of the given orientation: the
+// slices of the series span most of the head, the image of a single-image
+// series goes through its center. sequence is the SequenceName of MR images.
+func newPhantomSlice(modality modalities.Modality, sequence string, cfg modalities.PixelConfig, orientation []float64, instance, n int) *phantomSlice {
+	offset := 0.0
+	if n > 1 {
+		offset = -0.7 + 1.4*float64(instance-1)/float64(n-1)
+	}
+	s := &phantomSlice{
+		plane: dfimage.NewPhantomPlane(
+			[3]float64{orientation[0], orientation[1], orientation[2]},
+			[3]float64{orientation[3], orientation[4], orientation[5]},
+			offset),
+	}
+
+	valueRange := float64(cfg.MaxValue - cfg.MinValue)
+	if modality == modalities.CT {
+		for tissue, hu := range phantomHU {
+			s.levels[tissue] = hu + float64(cfg.BaseValue) // Stored 0 HU
+		}
+		s.noise = 6
+		return s
+	}
+	fractions, noise := mrContrastOf(sequence).tissues, 0.015
+	switch modality {
+	case modalities.CR, modalities.DX, modalities.MG, modalities.RF:
+		fractions = phantomXRay
+	case modalities.US:
+		fractions, noise = phantomUS, 0.06 // Speckle
+	}
+	for tissue, f := range fractions {
+		s.levels[tissue] = float64(cfg.MinValue) + f*valueRange
+	}
+	s.noise = noise * valueRange
+	return s
+}
+
+// value returns the stored value of pixel (x, y) of a width x height image
+// of the slice, with noise, before clamping.
+func (s *phantomSlice) value(x, y, width, height int, rng *rand.Rand) float64 {
+	return s.levels[s.plane.Tissue(x, y, width, height)] + rng.NormFloat64()*s.noise
+}
+
+// overlayLevels returns the stored values of the black and the white of the
+// overlays of the slice: air and its brightest tissue.
+func (s *phantomSlice) overlayLevels() (black, white float64) {
+	black, white = s.levels[dfimage.Air], s.levels[dfimage.Air]
+	for _, level := range s.levels {
+		white = math.Max(white, level)
+	}
+	return black, white
+}
@@ -0,0 +1,66 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestPhantom(t *testing.T) {
+	generate := func() (pixels []uint16, cols int) {
+		files, err := GenerateDICOMSeries(GeneratorOptions{
+			NumImages:  3,
+			TotalSize:  "1MB",
+			Modality:   modalities.CT,
+			Phantom:    true,
+			OutputDir:  t.TempDir(),
+			Seed:       42,
+			NumStudies: 1,
+			Quiet:      true,
+		})
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		// The middle slice goes through the center of the head
+		ds, err := dicom.ParseFile(files[1].Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixelData, err := ds.FindElementByTag(tag.PixelData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		native, err := dicom.MustGetPixelDataInfo(pixelData.Value).Frames[0].GetNativeFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return native.RawDataSlice().([]uint16), native.Cols()
+	}
+
+	pixels, size := generate()
+	// Stored values: HU + 1024, noise of 6
+	hu := func(x, y int) int { return int(pixels[y*size+x]) - 1024 }
+	if got := hu(0, 0); got > -970 {
+		t.Errorf("corner is %d HU, want air", got)
+	}
+	// Below the text overlay, in the center
+	if got := hu(size/2, size*7/10); got < 10 || got > 60 {
+		t.Errorf("posterior brain is %d HU, want 35", got)
+	}
+	if got := hu(size/2, size*3/10); got < 45 || got > 95 {
+		t.Errorf("anterior nodule is %d HU, want 70", got)
+	}
+	if got := hu(size/2, size*5/100); got < 900 {
+		t.Errorf("top of the head is %d HU, want the skull", got)
+	}
+
+	// Same seed, same images
+	again, _ := generate()
+	for i := range pixels {
+		if pixels[i] != again[i] {
+			t.Fatalf("pixel %d differs between runs: %d and %d", i, pixels[i], again[i])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package dicom
+
+import (
+	"fmt"
+	"hash/fnv"
+	randv2 "math/rand/v2"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// PriorsOptions configures a hanging "priors" package: one current study plus
+// older studies of the same patient, for comparison workflow testing.
+type PriorsOptions struct {
+	NumPriors      int    // Number of prior studies (K)
+	IntervalMonths int    // Months between consecutive studies (default: 12)
+	IntervalWeeks  int    // Weeks between consecutive studies, instead of IntervalMonths
+	CurrentDate    string // Date of the current study, YYYYMMDD (empty = random)
+}
+
+// BuildPriorsPatient builds a single predefined patient holding the current
+// study followed by its priors, each one IntervalMonths (or IntervalWeeks)
+// older than the previous, with accession numbers incrementing from the
+// oldest study. All studies share the same body part and description so that
+// hanging protocols match them, but each study gets its own protocol name and
+// series selection to mimic protocol changes over time.
+// The result is meant to be passed as GeneratorOptions.PredefinedPatients.
+func BuildPriorsPatient(opts GeneratorOptions, priors PriorsOptions) (PredefinedPatient, error) {
+	if priors.NumPriors <= 0 {
+		return PredefinedPatient{}, fmt.Errorf("number of priors must be > 0, got %d", priors.NumPriors)
+	}
+	interval := priors.IntervalMonths
+	if interval == 0 {
+		interval = 12
+	}
+	if interval < 0 {
+		return PredefinedPatient{}, fmt.Errorf("interval must be > 0 months, got %d", interval)
+	}
+	if priors.IntervalWeeks < 0 {
+		return PredefinedPatient{}, fmt.Errorf("interval must be > 0 weeks, got %d", priors.IntervalWeeks)
+	}
+
+	// Use a dedicated RNG so the package layout is reproducible for a given seed
+	// (or output directory, matching GenerateDICOMSeries when no seed is set)
+	seed := uint64(opts.Seed)
+	if seed == 0 {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(opts.OutputDir)) // hash.Write never returns an error
+		seed = h.Sum64()
+	}
+	rng := randv2.New(randv2.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	var current time.Time
+	if priors.CurrentDate != "" {
+		parsed, err := time.Parse("20060102", priors.CurrentDate)
+		if err != nil {
+			return PredefinedPatient{}, fmt.Errorf("invalid current date %q (expected YYYYMMDD): %w", priors.CurrentDate, err)
+		}
+		current = parsed
+	} else {
+		current = time.Date(rng.IntN(5)+2020, time.Month(rng.IntN(12)+1), rng.IntN(28)+1, 0, 0, 0, 0, time.UTC)
+	}
+
+	modalityGen := modalities.GetGenerator(opts.Modality)
+	modalityStr := string(modalityGen.Modality())
+
+	bodyPart := opts.BodyPart
+	if bodyPart == "" {
+		bodyPart = util.GenerateBodyPart(modalityStr, rng)
+	}
+	description := fmt.Sprintf("%s %s", bodyPart, modalityStr)
+
+	seriesPerStudy := opts.SeriesPerStudy
+	if seriesPerStudy.Max == 0 {
+		seriesPerStudy = util.SeriesRange{Min: 1, Max: 1}
+	}
+
+	patient := PredefinedPatient{}
+	for i := 0; i <= priors.NumPriors; i++ {
+		date := current.AddDate(0, -interval*i, 0)
+		if priors.IntervalWeeks > 0 {
+			date = current.AddDate(0, 0, -7*priors.IntervalWeeks*i)
+		}
+		study := PredefinedStudy{
+			Description: description,
+			Date:        date.Format("20060102"),
+			BodyPart:    bodyPart,
+		}
+
+		protocolName := util.GenerateProtocolName(modalityStr, bodyPart, rng)
+		templates := modalities.GetSeriesTemplates(opts.Modality, bodyPart, seriesPerStudy.GetSeriesCount(rng), rng)
+		for _, tmpl := range templates {
+			study.Series = append(study.Series, PredefinedSeries{
+				Description: tmpl.SeriesDescription,
+				Protocol:    protocolName,
+				Orientation: tmpl.Orientation,
+			})
+		}
+
+		patient.Studies = append(patient.Studies, study)
+	}
+
+	// Accession numbers are assigned as the exams are ordered: the oldest
+	// prior first, the current study last
+	first := rng.IntN(90000000-priors.NumPriors) + 10000000
+	for i := range patient.Studies {
+		patient.Studies[i].AccessionNumber = fmt.Sprintf("ACC%08d", first+priors.NumPriors-i)
+	}
+
+	return patient, nil
+}
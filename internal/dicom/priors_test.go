@@ -0,0 +1,138 @@
+package dicom
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+func TestBuildPriorsPatient_Dates(t *testing.T) {
+	opts := GeneratorOptions{Seed: 42, Modality: modalities.MR, BodyPart: "HEAD"}
+
+	patient, err := BuildPriorsPatient(opts, PriorsOptions{NumPriors: 3, IntervalMonths: 6, CurrentDate: "20240315"})
+	if err != nil {
+		t.Fatalf("BuildPriorsPatient failed: %v", err)
+	}
+
+	want := []string{"20240315", "20230915", "20230315", "20220915"}
+	if len(patient.Studies) != len(want) {
+		t.Fatalf("got %d studies, want %d", len(patient.Studies), len(want))
+	}
+	for i, study := range patient.Studies {
+		if study.Date != want[i] {
+			t.Errorf("study %d: date = %s, want %s", i, study.Date, want[i])
+		}
+		if study.BodyPart != "HEAD" {
+			t.Errorf("study %d: body part = %s, want HEAD", i, study.BodyPart)
+		}
+		if study.Description != patient.Studies[0].Description {
+			t.Errorf("study %d: description %q differs from current %q", i, study.Description, patient.Studies[0].Description)
+		}
+	}
+}
+
+func TestBuildPriorsPatient_Weeks(t *testing.T) {
+	opts := GeneratorOptions{Seed: 42, Modality: modalities.CT}
+
+	patient, err := BuildPriorsPatient(opts, PriorsOptions{NumPriors: 2, IntervalMonths: 12, IntervalWeeks: 6, CurrentDate: "20240315"})
+	if err != nil {
+		t.Fatalf("BuildPriorsPatient failed: %v", err)
+	}
+
+	want := []string{"20240315", "20240202", "20231222"}
+	for i, study := range patient.Studies {
+		if study.Date != want[i] {
+			t.Errorf("study %d: date = %s, want %s", i, study.Date, want[i])
+		}
+	}
+}
+
+func TestBuildPriorsPatient_AccessionNumbers(t *testing.T) {
+	opts := GeneratorOptions{Seed: 42, Modality: modalities.MR}
+
+	patient, err := BuildPriorsPatient(opts, PriorsOptions{NumPriors: 3})
+	if err != nil {
+		t.Fatalf("BuildPriorsPatient failed: %v", err)
+	}
+
+	// Incrementing from the oldest prior to the current study
+	for i := 1; i < len(patient.Studies); i++ {
+		newer, older := patient.Studies[i-1].AccessionNumber, patient.Studies[i].AccessionNumber
+		var n, m int
+		if _, err := fmt.Sscanf(newer, "ACC%08d", &n); err != nil {
+			t.Fatalf("accession number %q: %v", newer, err)
+		}
+		if _, err := fmt.Sscanf(older, "ACC%08d", &m); err != nil {
+			t.Fatalf("accession number %q: %v", older, err)
+		}
+		if n != m+1 {
+			t.Errorf("study %d: accession number %s, want the one after %s", i-1, newer, older)
+		}
+	}
+}
+
+func TestBuildPriorsPatient_Series(t *testing.T) {
+	opts := GeneratorOptions{
+		Seed:           7,
+		Modality:       modalities.MR,
+		BodyPart:       "HEAD",
+		SeriesPerStudy: util.SeriesRange{Min: 2, Max: 4},
+	}
+
+	patient, err := BuildPriorsPatient(opts, PriorsOptions{NumPriors: 2})
+	if err != nil {
+		t.Fatalf("BuildPriorsPatient failed: %v", err)
+	}
+
+	for i, study := range patient.Studies {
+		if len(study.Series) < 2 || len(study.Series) > 4 {
+			t.Errorf("study %d: got %d series, want 2-4", i, len(study.Series))
+		}
+		for _, s := range study.Series {
+			if s.Description == "" || s.Protocol == "" {
+				t.Errorf("study %d: series missing description or protocol: %+v", i, s)
+			}
+		}
+	}
+}
+
+func TestBuildPriorsPatient_Deterministic(t *testing.T) {
+	opts := GeneratorOptions{Seed: 99, Modality: modalities.CT}
+
+	a, err := BuildPriorsPatient(opts, PriorsOptions{NumPriors: 2})
+	if err != nil {
+		t.Fatalf("BuildPriorsPatient failed: %v", err)
+	}
+	b, err := BuildPriorsPatient(opts, PriorsOptions{NumPriors: 2})
+	if err != nil {
+		t.Fatalf("BuildPriorsPatient failed: %v", err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("same seed should produce the same priors package")
+	}
+}
+
+func TestBuildPriorsPatient_Invalid(t *testing.T) {
+	opts := GeneratorOptions{Seed: 1, Modality: modalities.MR}
+
+	tests := []struct {
+		name   string
+		priors PriorsOptions
+	}{
+		{"no priors", PriorsOptions{NumPriors: 0}},
+		{"negative interval", PriorsOptions{NumPriors: 1, IntervalMonths: -1}},
+		{"negative weeks", PriorsOptions{NumPriors: 1, IntervalWeeks: -2}},
+		{"bad date", PriorsOptions{NumPriors: 1, CurrentDate: "2024-03-15"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildPriorsPatient(opts, tt.priors); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package dicom
+
+import (
+	"fmt"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// mustNewPrivateElement creates an element of a private tag with an explicit
+// VR or panics on error (see util.NewPrivateElement).
+func mustNewPrivateElement(t tag.Tag, rawVR string, data any) *dicom.Element {
+	elem, err := util.NewPrivateElement(t, rawVR, data)
+	if err != nil {
+		panic(err)
+	}
+	return elem
+}
+
+// addPrivateTagsToFile rewrites a written DICOM file with the private tags
+// (see util.InsertPrivateTags), of its text encoded in its
+// SpecificCharacterSet.
+func addPrivateTagsToFile(path string, tags []util.PrivateTag) error {
+	ds, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	elements, err := util.InsertPrivateTags(ds.Elements, tags)
+	if err != nil {
+		return fmt.Errorf("private tags of %s: %w", path, err)
+	}
+	return writeDatasetToFile(path, dicom.Dataset{Elements: elements}, dicom.SkipVRVerification())
+}
@@ -0,0 +1,73 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestPrivateTags(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages: 3,
+		TotalSize: "1MB",
+		Modality:  modalities.MR,
+		PrivateTags: []util.PrivateTag{
+			{Creator: "ACME 1.0", Group: 0x0009, Element: 0x01, VR: "LO", Value: "Fast protocol"},
+			{Creator: "ACME 1.0", Group: 0x0009, Element: 0x02, VR: "FD", Value: `1.5\2.5`},
+		},
+		// GE private tags reserve block 0x10 of group 0009
+		CorruptionConfig: corruption.Config{Types: []corruption.CorruptionType{corruption.GEPrivate}},
+		TextSR:           true,
+		OutputDir:        t.TempDir(),
+		Seed:             42,
+		NumStudies:       1,
+		NumPatients:      1,
+		Quiet:            true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		isImage := elementString(ds.Elements, tag.Modality) == "MR"
+		block := uint16(0x10)
+		if isImage {
+			block = 0x11
+			if got := elementString(ds.Elements, tag.Tag{Group: 0x0009, Element: 0x0010}); got != "GEMS_IDEN_01" {
+				t.Errorf("%s: (0009,0010) = %q, want GE creator kept", f.Path, got)
+			}
+		}
+		if got := elementString(ds.Elements, tag.Tag{Group: 0x0009, Element: block}); got != "ACME 1.0" {
+			t.Errorf("%s: creator (0009,00%02X) = %q", f.Path, block, got)
+		}
+		if got := elementString(ds.Elements, tag.Tag{Group: 0x0009, Element: block<<8 | 0x01}); got != "Fast protocol" {
+			t.Errorf("%s: (0009,%02X01) = %q", f.Path, block, got)
+		}
+		elem, err := ds.FindElementByTag(tag.Tag{Group: 0x0009, Element: block<<8 | 0x02})
+		if err != nil {
+			t.Errorf("%s: (0009,%02X02) missing", f.Path, block)
+		} else if values, ok := elem.Value.GetValue().([]float64); !ok || len(values) != 2 || values[1] != 2.5 {
+			t.Errorf("%s: (0009,%02X02) = %v", f.Path, block, elem.Value)
+		}
+	}
+
+	if _, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   1,
+		TotalSize:   "1MB",
+		PrivateTags: []util.PrivateTag{{Creator: "ACME", Group: 0x0010, Element: 0x01, VR: "LO", Value: "x"}},
+		OutputDir:   t.TempDir(),
+		NumStudies:  1,
+		NumPatients: 1,
+		Quiet:       true,
+	}); err == nil {
+		t.Error("GenerateDICOMSeries should reject a private tag in a standard group")
+	}
+}
@@ -0,0 +1,186 @@
+package dicom
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	randv2 "math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// PseudonymEntry maps the identity of a predefined study (a CSV row) to the
+// synthetic identity written in the generated files.
+type PseudonymEntry struct {
+	PatientID, PseudoPatientID               string
+	PatientName, PseudoPatientName           string
+	PatientBirthDate, PseudoPatientBirthDate string
+	AccessionNumber, PseudoAccessionNumber   string
+}
+
+// pseudonymMapHeader is the header row of the decrypted map
+var pseudonymMapHeader = []string{
+	"PatientID", "PatientName", "PatientBirthDate", "AccessionNumber",
+	"PseudoPatientID", "PseudoPatientName", "PseudoPatientBirthDate", "PseudoAccessionNumber",
+}
+
+// Encrypted map file layout: magic, PBKDF2 salt, AES-GCM nonce, ciphertext
+const (
+	pseudonymMapMagic = "DFPMAP1\n"
+	pseudonymSaltSize = 16
+	pseudonymKDFIter  = 600000
+)
+
+// pseudonymizePatients replaces the identifying values of predefined patients
+// (PatientID, PatientName, PatientBirthDate and AccessionNumber) with
+// generated ones and returns the original→generated map, one entry per
+// study. Empty values stay empty, to be generated as usual. Birth dates keep
+// their year so that ages stay plausible.
+func pseudonymizePatients(patients []PredefinedPatient, rng *randv2.Rand) ([]PredefinedPatient, []PseudonymEntry) {
+	usedIDs := make(map[string]bool)
+	usedAccessions := make(map[string]bool)
+	unique := func(used map[string]bool, generate func() string) string {
+		for {
+			if v := generate(); !used[v] {
+				used[v] = true
+				return v
+			}
+		}
+	}
+
+	result := make([]PredefinedPatient, len(patients))
+	var entries []PseudonymEntry
+	for i, p := range patients {
+		pseudo := p
+		pseudo.Studies = append([]PredefinedStudy(nil), p.Studies...)
+		if p.ID != "" {
+			pseudo.ID = unique(usedIDs, func() string { return fmt.Sprintf("PID%06d", rng.IntN(900000)+100000) })
+		}
+		if p.Name != "" {
+			pseudo.Name = util.GeneratePatientName(p.Sex, rng)
+		}
+		if p.BirthDate != "" {
+			pseudo.BirthDate = pseudonymBirthDate(p.BirthDate, rng)
+		}
+
+		for j, s := range p.Studies {
+			if s.AccessionNumber != "" {
+				pseudo.Studies[j].AccessionNumber = unique(usedAccessions, func() string {
+					return fmt.Sprintf("ACC%08d", rng.IntN(90000000)+10000000)
+				})
+			}
+			entries = append(entries, PseudonymEntry{
+				PatientID: p.ID, PseudoPatientID: pseudo.ID,
+				PatientName: p.Name, PseudoPatientName: pseudo.Name,
+				PatientBirthDate: p.BirthDate, PseudoPatientBirthDate: pseudo.BirthDate,
+				AccessionNumber: s.AccessionNumber, PseudoAccessionNumber: pseudo.Studies[j].AccessionNumber,
+			})
+		}
+		result[i] = pseudo
+	}
+	return result, entries
+}
+
+// pseudonymBirthDate returns a random date in the year of a YYYYMMDD date.
+func pseudonymBirthDate(date string, rng *randv2.Rand) string {
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return date
+	}
+	start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	days := start.AddDate(1, 0, 0).Sub(start).Hours() / 24
+	return start.AddDate(0, 0, rng.IntN(int(days))).Format("20060102")
+}
+
+// WritePseudonymMap writes the entries as CSV, encrypted with AES-256-GCM
+// under a key derived from passphrase (PBKDF2-SHA256).
+func WritePseudonymMap(path string, entries []PseudonymEntry, passphrase []byte) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(pseudonymMapHeader)
+	for _, e := range entries {
+		_ = w.Write([]string{
+			e.PatientID, e.PatientName, e.PatientBirthDate, e.AccessionNumber,
+			e.PseudoPatientID, e.PseudoPatientName, e.PseudoPatientBirthDate, e.PseudoAccessionNumber,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("encode pseudonym map: %w", err)
+	}
+
+	salt := make([]byte, pseudonymSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("generate salt: %w", err)
+	}
+	gcm, err := pseudonymCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+
+	data := append([]byte(pseudonymMapMagic), salt...)
+	data = append(data, nonce...)
+	data = gcm.Seal(data, nonce, buf.Bytes(), []byte(pseudonymMapMagic))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("write pseudonym map: %w", err)
+	}
+	return nil
+}
+
+// ReadPseudonymMap decrypts a map written by WritePseudonymMap and returns
+// its CSV content.
+func ReadPseudonymMap(path string, passphrase []byte) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pseudonym map: %w", err)
+	}
+	if !bytes.HasPrefix(data, []byte(pseudonymMapMagic)) {
+		return nil, fmt.Errorf("%s is not a pseudonym map", path)
+	}
+	data = data[len(pseudonymMapMagic):]
+	if len(data) < pseudonymSaltSize {
+		return nil, fmt.Errorf("truncated pseudonym map")
+	}
+
+	gcm, err := pseudonymCipher(passphrase, data[:pseudonymSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[pseudonymSaltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("truncated pseudonym map")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(pseudonymMapMagic))
+	if err != nil {
+		return nil, errors.New("cannot decrypt pseudonym map: wrong key or corrupted file")
+	}
+	return plain, nil
+}
+
+// pseudonymCipher returns the AES-256-GCM cipher of a passphrase and salt.
+func pseudonymCipher(passphrase, salt []byte) (cipher.AEAD, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("empty pseudonym map key")
+	}
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, pseudonymKDFIter, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,95 @@
+package dicom
+
+import (
+	"encoding/csv"
+	randv2 "math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testPseudonymPatients() []PredefinedPatient {
+	return []PredefinedPatient{
+		{ID: "MRN001", Name: "DOE^JOHN", BirthDate: "19650412", Sex: "M", Studies: []PredefinedStudy{
+			{Description: "CT CHEST", Date: "20240115", AccessionNumber: "A1001"},
+			{Description: "CT CHEST FOLLOW-UP", Date: "20240602", AccessionNumber: "A1002"},
+		}},
+		{ID: "MRN002", Name: "MARTIN^CLAIRE", Sex: "F", Studies: []PredefinedStudy{
+			{Date: "20240320"},
+		}},
+	}
+}
+
+func TestPseudonymizePatients(t *testing.T) {
+	original := testPseudonymPatients()
+	pseudo, entries := pseudonymizePatients(original, randv2.New(randv2.NewPCG(1, 2)))
+
+	if original[0].ID != "MRN001" || original[0].Studies[0].AccessionNumber != "A1001" {
+		t.Fatal("pseudonymizePatients modified its input")
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want one per study (3)", len(entries))
+	}
+
+	p := pseudo[0]
+	if p.ID == "MRN001" || p.Name == "DOE^JOHN" || p.BirthDate == "19650412" {
+		t.Errorf("identity not replaced: %+v", p)
+	}
+	if !strings.HasPrefix(p.BirthDate, "1965") {
+		t.Errorf("birth date %s should stay in 1965", p.BirthDate)
+	}
+	if p.Sex != "M" || p.Studies[0].Date != "20240115" || p.Studies[1].Description != "CT CHEST FOLLOW-UP" {
+		t.Errorf("non-identifying values changed: %+v", p)
+	}
+	if p.Studies[0].AccessionNumber == "A1001" || p.Studies[0].AccessionNumber == p.Studies[1].AccessionNumber {
+		t.Errorf("accession numbers = %s, %s, want new distinct values", p.Studies[0].AccessionNumber, p.Studies[1].AccessionNumber)
+	}
+
+	// Empty values stay empty, to be generated as usual
+	if pseudo[1].BirthDate != "" || pseudo[1].Studies[0].AccessionNumber != "" {
+		t.Errorf("empty values should stay empty: %+v", pseudo[1])
+	}
+
+	want := PseudonymEntry{
+		PatientID: "MRN001", PseudoPatientID: p.ID,
+		PatientName: "DOE^JOHN", PseudoPatientName: p.Name,
+		PatientBirthDate: "19650412", PseudoPatientBirthDate: p.BirthDate,
+		AccessionNumber: "A1002", PseudoAccessionNumber: p.Studies[1].AccessionNumber,
+	}
+	if entries[1] != want {
+		t.Errorf("entry = %+v, want %+v", entries[1], want)
+	}
+}
+
+func TestPseudonymMap_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map.enc")
+	_, entries := pseudonymizePatients(testPseudonymPatients(), randv2.New(randv2.NewPCG(1, 2)))
+
+	if err := WritePseudonymMap(path, entries, []byte("secret")); err != nil {
+		t.Fatalf("WritePseudonymMap failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "MRN001") {
+		t.Error("map is not encrypted")
+	}
+
+	plain, err := ReadPseudonymMap(path, []byte("secret"))
+	if err != nil {
+		t.Fatalf("ReadPseudonymMap failed: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(plain))).ReadAll()
+	if err != nil {
+		t.Fatalf("decrypted map is not CSV: %v", err)
+	}
+	if len(records) != 4 || records[0][0] != "PatientID" || records[1][0] != "MRN001" || records[1][4] != entries[0].PseudoPatientID {
+		t.Errorf("decrypted map = %v", records)
+	}
+
+	if _, err := ReadPseudonymMap(path, []byte("wrong")); err == nil {
+		t.Error("ReadPseudonymMap should fail with a wrong key")
+	}
+	if err := WritePseudonymMap(path, entries, nil); err == nil {
+		t.Error("WritePseudonymMap should fail without a key")
+	}
+}
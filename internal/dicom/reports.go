@@ -0,0 +1,352 @@
+package dicom
+
+import (
+	"fmt"
+	randv2 "math/rand/v2"
+	"path/filepath"
+	"time"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// studyRecord captures the study-level attributes and image UIDs of a generated
+// study, so that derived objects (reports, ...) can be attached to it once
+// its images are written.
+type studyRecord struct {
+	studyNum           int
+	patient            patientInfo
+	patientAge         string // Empty when it cannot be derived
+	studyUID           string
+	studyID            string
+	studyDate          string
+	studyTime          string
+	studyDescription   string
+	accessionNumber    string
+	referringPhysician string
+	institutionName    string
+	bodyPart           string
+	pixelSpacing       float64
+	scanner            modalities.Scanner
+	linkage            linkageIDs // Empty when linkage IDs are disabled
+	characterSet       string     // SpecificCharacterSet of the files; empty for the default
+	frameOfReference   string     // FrameOfReferenceUID shared by the image series
+	patientPosition    string     // HFS, FFS...; empty for projection modalities
+	series             []seriesRecord
+
+	// Lesions inserted in series[lesionSeries] (AI result simulation)
+	lesions      []lesion
+	lesionSeries int
+}
+
+// seriesRecord lists the instances of a generated image series, with the
+// geometry of its slices (see slicePosition).
+type seriesRecord struct {
+	seriesUID    string
+	seriesNumber int
+	sopClassUID  string
+	description  string
+	start        time.Time // SeriesDate and SeriesTime
+	instanceUIDs []string
+	filePaths    []string
+
+	orientation  []float64
+	rows         int
+	columns      int
+	pixelSpacing float64
+	sliceSpacing float64
+
+	windowCenter     float64
+	windowWidth      float64
+	rescaleIntercept float64
+	rescaleSlope     float64 // 0 when the images have no modality LUT
+
+	kvp            float64 // 0 when the modality uses no X-rays
+	tubeCurrent    int     // mA
+	reconstruction bool    // Reconstructed from the acquisition of another series
+	localizer      bool    // Scout images the other series are planned on
+}
+
+// evidence returns references to all instances of the series.
+func (s seriesRecord) evidence(studyUID string) sr.Evidence {
+	ev := sr.Evidence{StudyUID: studyUID, SeriesUID: s.seriesUID}
+	for _, uid := range s.instanceUIDs {
+		ev.Instances = append(ev.Instances, sr.ImageRef{SOPClassUID: s.sopClassUID, SOPInstanceUID: uid})
+	}
+	return ev
+}
+
+// nextSeriesNumber returns the series number following the last image series.
+func (s studyRecord) nextSeriesNumber() int {
+	next := 1
+	for _, series := range s.series {
+		if series.seriesNumber >= next {
+			next = series.seriesNumber + 1
+		}
+	}
+	return next
+}
+
+// largestSeries returns the image series of the study with the most
+// instances, the volume derived objects are aligned with. ok is false when
+// the study has no images.
+func (s studyRecord) largestSeries() (series seriesRecord, ok bool) {
+	for _, candidate := range s.series {
+		if len(candidate.instanceUIDs) > len(series.instanceUIDs) {
+			series = candidate
+		}
+	}
+	return series, len(series.instanceUIDs) > 0
+}
+
+// imageRefs returns references to all images of the study.
+func (s studyRecord) imageRefs() []sr.ImageRef {
+	var refs []sr.ImageRef
+	for _, series := range s.series {
+		for _, uid := range series.instanceUIDs {
+			refs = append(refs, sr.ImageRef{SOPClassUID: series.sopClassUID, SOPInstanceUID: uid})
+		}
+	}
+	return refs
+}
+
+// evidence returns the study's images grouped by series.
+func (s studyRecord) evidence() []sr.Evidence {
+	evidence := make([]sr.Evidence, 0, len(s.series))
+	for _, series := range s.series {
+		evidence = append(evidence, series.evidence(s.studyUID))
+	}
+	return evidence
+}
+
+// derivedFile returns the GeneratedFile entry of a derived object of the study.
+func (s studyRecord) derivedFile(path, seriesUID, sopInstanceUID string, seriesNumber, instanceNumber int) GeneratedFile {
+	return GeneratedFile{
+		Path:           path,
+		StudyUID:       s.studyUID,
+		SeriesUID:      seriesUID,
+		SOPInstanceUID: sopInstanceUID,
+		PatientID:      s.patient.ID,
+		StudyID:        s.studyID,
+		SeriesNumber:   seriesNumber,
+		InstanceNumber: instanceNumber,
+	}
+}
+
+// derivedFilePath returns the temporary path of a derived object, named after
+// its kind (SR, SC, ...), study, series and instance.
+func derivedFilePath(opts GeneratorOptions, prefix string, studyNum, seriesNumber, instanceNumber int) string {
+	return filepath.Join(opts.filesDir(), fmt.Sprintf("%s%04d_%02d_%04d.dcm", prefix, studyNum, seriesNumber, instanceNumber))
+}
+
+// headerElements returns the patient, study and general equipment elements
+// shared by every object of the study, plus the series-level elements of a
+// new derived series.
+func (s studyRecord) headerElements(modality, seriesUID string, seriesNumber int, seriesDescription string) []*dicom.Element {
+	elements := []*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustNewElement(tag.PatientName, []string{s.patient.Name}),
+		mustNewElement(tag.PatientID, []string{s.patient.ID}),
+		mustNewElement(tag.PatientBirthDate, []string{s.patient.BirthDate}),
+		mustNewElement(tag.PatientSex, []string{s.patient.Sex}),
+		mustNewElement(tag.StudyInstanceUID, []string{s.studyUID}),
+		mustNewElement(tag.StudyID, []string{s.studyID}),
+		mustNewElement(tag.StudyDate, []string{s.studyDate}),
+		mustNewElement(tag.StudyTime, []string{s.studyTime}),
+		mustNewElement(tag.StudyDescription, []string{s.studyDescription}),
+		mustNewElement(tag.AccessionNumber, []string{s.accessionNumber}),
+		mustNewElement(tag.ReferringPhysicianName, []string{s.referringPhysician}),
+		mustNewElement(tag.InstitutionName, []string{s.institutionName}),
+		mustNewElement(tag.Manufacturer, []string{s.scanner.Manufacturer}),
+		mustNewElement(tag.ManufacturerModelName, []string{s.scanner.Model}),
+		mustNewElement(tag.SeriesInstanceUID, []string{seriesUID}),
+		mustNewElement(tag.SeriesNumber, []string{util.FormatIS(seriesNumber)}),
+		mustNewElement(tag.SeriesDescription, []string{seriesDescription}),
+		mustNewElement(tag.Modality, []string{modality}),
+	}
+	if s.patientAge != "" {
+		elements = append(elements, mustNewElement(tag.PatientAge, []string{s.patientAge}))
+	}
+	if s.linkage.admissionID != "" {
+		elements = append(elements, s.linkage.elements()...)
+	}
+	if s.characterSet != "" {
+		elements = util.DeclareCharacterSet(elements, s.characterSet)
+	}
+	return elements
+}
+
+// generateReports writes the derived report objects enabled in opts for every
+// generated study. Each study uses its own RNG so that enabling reports does
+// not change the generated images.
+func generateReports(opts GeneratorOptions, seed int64, studies []studyRecord) ([]GeneratedFile, error) {
+	var files []GeneratedFile
+
+	for _, study := range studies {
+		rng := randv2.New(randv2.NewPCG(uint64(seed), uint64(study.studyNum)))
+		seriesNumber := study.nextSeriesNumber()
+
+		if opts.USMeasurementSR && opts.Modality == modalities.US {
+			file, err := writeUSMeasurementSR(opts, study, seriesNumber, rng)
+			if err != nil {
+				return nil, fmt.Errorf("write US measurement SR for study %d: %w", study.studyNum, err)
+			}
+			files = append(files, file)
+			seriesNumber++
+		}
+
+		if len(study.lesions) > 0 && hasAIResult(opts.AIResults, AIResultSR) {
+			file, err := writeAIResultSR(opts, study, seriesNumber)
+			if err != nil {
+				return nil, fmt.Errorf("write AI result SR for study %d: %w", study.studyNum, err)
+			}
+			files = append(files, file)
+			seriesNumber++
+		}
+
+		if len(study.lesions) > 0 && hasAIResult(opts.AIResults, AIResultSC) {
+			scFiles, err := writeAIHeatmapSCs(opts, study, seriesNumber)
+			if err != nil {
+				return nil, fmt.Errorf("write AI heatmaps for study %d: %w", study.studyNum, err)
+			}
+			files = append(files, scFiles...)
+			seriesNumber++
+		}
+
+		if opts.TextSR {
+			file, err := writeTextSR(opts, study, seriesNumber, rng)
+			if err != nil {
+				return nil, fmt.Errorf("write text SR for study %d: %w", study.studyNum, err)
+			}
+			files = append(files, file)
+			seriesNumber++
+		}
+
+		if (opts.RTPlan || opts.RTDose) && opts.Modality == modalities.CT {
+			target, err := newRTTarget(study, rng)
+			if err != nil {
+				return nil, fmt.Errorf("place RT target for study %d: %w", study.studyNum, err)
+			}
+
+			if opts.RTPlan {
+				structureSet, err := writeRTStructureSet(opts, study, target, seriesNumber)
+				if err != nil {
+					return nil, fmt.Errorf("write RT structure set for study %d: %w", study.studyNum, err)
+				}
+				files = append(files, structureSet)
+				seriesNumber++
+
+				file, err := writeRTPlan(opts, study, target, structureSet.SOPInstanceUID, seriesNumber, rng)
+				if err != nil {
+					return nil, fmt.Errorf("write RT plan for study %d: %w", study.studyNum, err)
+				}
+				files = append(files, file)
+				seriesNumber++
+			}
+
+			if opts.RTDose {
+				file, err := writeRTDose(opts, study, target, seriesNumber)
+				if err != nil {
+					return nil, fmt.Errorf("write RT dose for study %d: %w", study.studyNum, err)
+				}
+				files = append(files, file)
+				seriesNumber++
+			}
+		}
+
+		if opts.GSPS {
+			file, err := writeGSPS(opts, study, seriesNumber, rng)
+			if err != nil {
+				return nil, fmt.Errorf("write presentation state for study %d: %w", study.studyNum, err)
+			}
+			files = append(files, file)
+			seriesNumber++
+		}
+
+		if opts.SegSegments > 0 && (opts.Modality == modalities.CT || opts.Modality == modalities.MR) {
+			file, err := writeSegmentation(opts, study, opts.SegSegments, seriesNumber, rng)
+			if err != nil {
+				return nil, fmt.Errorf("write segmentation for study %d: %w", study.studyNum, err)
+			}
+			files = append(files, file)
+			seriesNumber++
+		}
+
+		if opts.DoseSR && opts.Modality == modalities.CT {
+			file, err := writeCTDoseSR(opts, study, seriesNumber, rng)
+			if err != nil {
+				return nil, fmt.Errorf("write dose SR for study %d: %w", study.studyNum, err)
+			}
+			files = append(files, file)
+			seriesNumber++
+		}
+	}
+
+	return files, nil
+}
+
+// writeUSMeasurementSR writes a Comprehensive SR holding US measurements
+// (fetal biometry or organ lengths) that reference the study's images.
+func writeUSMeasurementSR(opts GeneratorOptions, study studyRecord, seriesNumber int, rng *randv2.Rand) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+
+	root, templateID := sr.USMeasurementReport(study.bodyPart, study.imageRefs(), rng)
+	doc := sr.Document{
+		Root:        root,
+		TemplateID:  templateID,
+		ContentDate: study.studyDate,
+		ContentTime: study.studyTime,
+		Evidence:    study.evidence(),
+	}
+
+	elements := study.headerElements("SR", seriesUID, seriesNumber, "US Measurements")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{sr.ComprehensiveSRStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+	)
+	elements = append(elements, doc.Elements()...)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "SR", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
+
+// writeTextSR writes a Basic Text SR holding a free-text report whose
+// findings reference the study's images.
+func writeTextSR(opts GeneratorOptions, study studyRecord, seriesNumber int, rng *randv2.Rand) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+
+	doc := sr.Document{
+		Root:        sr.TextReport(study.bodyPart, study.imageRefs(), rng),
+		TemplateID:  "2000",
+		ContentDate: study.studyDate,
+		ContentTime: study.studyTime,
+		Evidence:    study.evidence(),
+	}
+
+	elements := study.headerElements("SR", seriesUID, seriesNumber, "Report")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{sr.BasicTextSRStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+	)
+	elements = append(elements, doc.Elements()...)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "SR", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
@@ -0,0 +1,227 @@
+package dicom
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// mediaStorageDirectoryStorage is the SOP Class UID of DICOMDIR files
+const mediaStorageDirectoryStorage = "1.2.840.10008.1.3.10"
+
+// dicomStandardUIDRoot prefixes the UIDs defined by the standard (SOP
+// classes, transfer syntaxes, ...), which re-rooting keeps.
+const dicomStandardUIDRoot = "1.2.840.10008."
+
+// rerootKeptTags hold UIDs that identify software or code systems rather than
+// instances, and are kept whatever their root.
+var rerootKeptTags = map[tag.Tag]bool{
+	tag.ImplementationClassUID: true,
+	tag.CodingSchemeUID:        true,
+}
+
+// RerootOptions configures the re-rooting of an existing dataset.
+type RerootOptions struct {
+	Root    string // UID root of the new UIDs
+	MapFile string // CSV file receiving the old->new UID mapping (optional)
+}
+
+// RerootReport summarizes a re-rooting.
+type RerootReport struct {
+	Rewritten int // DICOM files written with new UIDs
+	Copied    int // Files that are not readable DICOM, copied unchanged
+	UIDs      int // Distinct UIDs replaced
+}
+
+// uidMapper replaces UIDs by the UIDs generate derives from them. The new UID
+// depends on the old one alone, so references within and across files, and
+// across runs, resolve to the same new UID.
+type uidMapper struct {
+	generate func(old string) string
+	uids     map[string]string
+}
+
+// newUIDMapper returns a uidMapper replacing UIDs by generate(old).
+func newUIDMapper(generate func(old string) string) *uidMapper {
+	return &uidMapper{generate: generate, uids: make(map[string]string)}
+}
+
+// RerootDirectory copies the files of inputDir to outputDir, with the same
+// relative paths, replacing every UID (VR UI, in sequences as well) by a UID
+// under opts.Root. UIDs defined by the standard, ImplementationClassUID and
+// CodingSchemeUID are kept. DICOMDIR files get their record offsets
+// recomputed; files that cannot be parsed as DICOM are copied unchanged.
+func RerootDirectory(inputDir, outputDir string, opts RerootOptions) (RerootReport, error) {
+	if err := util.ValidateUIDRoot(opts.Root); err != nil {
+		return RerootReport{}, err
+	}
+	if err := checkOutputOutsideInput(inputDir, outputDir); err != nil {
+		return RerootReport{}, err
+	}
+
+	mapper := newUIDMapper(func(old string) string {
+		return util.GenerateUIDUnderRoot(opts.Root, old)
+	})
+	var report RerootReport
+	err := filepath.WalkDir(inputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(inputDir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(outputDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+
+		ds, err := dicom.ParseFile(path, nil, dicom.SkipProcessingPixelDataValue())
+		if err != nil {
+			if err := copyFile(path, dest); err != nil {
+				return err
+			}
+			report.Copied++
+			return nil
+		}
+
+		mapper.rewrite(ds.Elements)
+		if sopClass, err := ds.FindElementByTag(tag.MediaStorageSOPClassUID); err == nil && deidString(sopClass) == mediaStorageDirectoryStorage {
+			err = writeDICOMDIR(dest, ds)
+		} else {
+			err = writeDatasetToFile(dest, ds)
+		}
+		if err != nil {
+			return fmt.Errorf("write %s: %w", dest, err)
+		}
+		report.Rewritten++
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	report.UIDs = len(mapper.uids)
+
+	if opts.MapFile != "" {
+		if err := mapper.writeMap(opts.MapFile); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// checkOutputOutsideInput rejects an output directory inside the input one,
+// whose files would be read back as input.
+func checkOutputOutsideInput(inputDir, outputDir string) error {
+	absInput, err := filepath.Abs(inputDir)
+	if err != nil {
+		return err
+	}
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(absInput, absOutput); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("output directory %s must not be inside input directory %s", outputDir, inputDir)
+	}
+	return nil
+}
+
+// rewrite replaces the UIDs of elements and of their sequence items, in
+// place.
+func (m *uidMapper) rewrite(elements []*dicom.Element) {
+	for _, elem := range elements {
+		if elem.Value == nil {
+			continue
+		}
+		if elem.Value.ValueType() == dicom.Sequences {
+			for _, item := range elem.Value.GetValue().([]*dicom.SequenceItemValue) {
+				m.rewrite(item.GetValue().([]*dicom.Element))
+			}
+			continue
+		}
+		if elem.RawValueRepresentation != "UI" || rerootKeptTags[elem.Tag] {
+			continue
+		}
+		values, ok := elem.Value.GetValue().([]string)
+		if !ok {
+			continue
+		}
+		replaced := make([]string, len(values))
+		for i, v := range values {
+			replaced[i] = m.uid(strings.TrimRight(v, " \x00"))
+		}
+		value, err := dicom.NewValue(replaced)
+		if err != nil {
+			continue
+		}
+		elem.Value = value
+	}
+}
+
+// uid returns the replacement of a UID. Empty and standard UIDs are kept.
+func (m *uidMapper) uid(old string) string {
+	if old == "" || strings.HasPrefix(old, dicomStandardUIDRoot) {
+		return old
+	}
+	if uid, ok := m.uids[old]; ok {
+		return uid
+	}
+	uid := m.generate(old)
+	m.uids[old] = uid
+	return uid
+}
+
+// writeMap writes the UID mapping as CSV, sorted by old UID.
+func (m *uidMapper) writeMap(path string) error {
+	olds := make([]string, 0, len(m.uids))
+	for old := range m.uids {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create UID map: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"OldUID", "NewUID"})
+	for _, old := range olds {
+		_ = w.Write([]string{old, m.uids[old]})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("write UID map: %w", err)
+	}
+	return f.Close()
+}
+
+// copyFile copies src to dest.
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,50 @@
+package dicom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestUIDMapperRewrite(t *testing.T) {
+	elements := []*dicom.Element{
+		mustNewElement(tag.ImplementationClassUID, []string{"1.2.3.99"}),
+		mustNewElement(tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+		mustNewElement(tag.StudyInstanceUID, []string{"1.2.3.1"}),
+		mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.2"}),
+		mustNewElement(tag.ReferencedImageSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ReferencedSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+			mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3.2"}),
+		}}),
+		mustNewElement(tag.PatientID, []string{"1.2.3.1"}),
+	}
+
+	m := newUIDMapper(func(old string) string { return util.GenerateUIDUnderRoot("9.8", old) })
+	m.rewrite(elements)
+
+	value := func(elem *dicom.Element) string { return elem.Value.GetValue().([]string)[0] }
+	if got := value(elements[0]); got != "1.2.3.99" {
+		t.Errorf("ImplementationClassUID = %s, want it kept", got)
+	}
+	if got := value(elements[1]); got != "1.2.840.10008.5.1.4.1.1.2" {
+		t.Errorf("SOPClassUID = %s, want it kept", got)
+	}
+	sop := value(elements[3])
+	if !strings.HasPrefix(value(elements[2]), "9.8.") || !strings.HasPrefix(sop, "9.8.") {
+		t.Errorf("instance UIDs not under the new root: %s, %s", value(elements[2]), sop)
+	}
+	item := elements[4].Value.GetValue().([]*dicom.SequenceItemValue)[0].GetValue().([]*dicom.Element)
+	if got := value(item[1]); got != sop {
+		t.Errorf("ReferencedSOPInstanceUID = %s, want %s", got, sop)
+	}
+	if got := value(elements[5]); got != "1.2.3.1" {
+		t.Errorf("PatientID = %s, want it kept", got)
+	}
+	if len(m.uids) != 2 {
+		t.Errorf("mapped %d UIDs, want 2", len(m.uids))
+	}
+}
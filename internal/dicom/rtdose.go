@@ -0,0 +1,228 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	randv2 "math/rand/v2"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// RT SOP Class UIDs
+const (
+	RTDoseStorage         = "1.2.840.10008.5.1.4.1.1.481.2"
+	RTStructureSetStorage = "1.2.840.10008.5.1.4.1.1.481.3"
+	RTPlanStorage         = "1.2.840.10008.5.1.4.1.1.481.5"
+)
+
+// rtDoseResolution is the target spacing (mm) of the dose grid, in-plane and
+// between frames. Dose is calculated on a grid coarser than the CT.
+const rtDoseResolution = 3.0
+
+// rtDoseMaxValue is the largest stored dose value: values are scaled by
+// DoseGridScaling so that the maximum dose fits 16 bits with some headroom.
+const rtDoseMaxValue = 65000
+
+// rtDoseGrid is a dose grid aligned with a CT series: its points are a
+// subsample of the CT voxel centers, so that dose overlays line up exactly.
+type rtDoseGrid struct {
+	origin       [3]float64 // ImagePositionPatient of the first frame
+	orientation  []float64
+	rows         int
+	columns      int
+	frames       int
+	pixelSpacing float64 // mm
+	frameSpacing float64 // mm, along the slice normal
+}
+
+// newRTDoseGrid returns the dose grid of a CT series: every step-th voxel
+// in-plane and every step-th slice, the steps bringing the spacing close to
+// rtDoseResolution.
+func newRTDoseGrid(series seriesRecord) rtDoseGrid {
+	step := func(spacing float64) int {
+		if spacing <= 0 {
+			return 1
+		}
+		return max(1, int(math.Round(rtDoseResolution/spacing)))
+	}
+	inPlane := step(series.pixelSpacing)
+	between := step(series.sliceSpacing)
+	numSlices := len(series.instanceUIDs)
+
+	return rtDoseGrid{
+		origin: slicePosition(series.orientation, series.columns, series.rows,
+			series.pixelSpacing, series.sliceSpacing, 0, numSlices),
+		orientation:  series.orientation,
+		rows:         (series.rows-1)/inPlane + 1,
+		columns:      (series.columns-1)/inPlane + 1,
+		frames:       (numSlices-1)/between + 1,
+		pixelSpacing: series.pixelSpacing * float64(inPlane),
+		frameSpacing: series.sliceSpacing * float64(between),
+	}
+}
+
+// patientPosition returns the patient coordinates (mm) of a point given in
+// grid coordinates: along the rows, the columns and the frames.
+func (g rtDoseGrid) patientPosition(x, y, z float64) [3]float64 {
+	row, col, normal := g.orientation[0:3], g.orientation[3:6], sliceNormal(g.orientation)
+	var pos [3]float64
+	for i := range pos {
+		pos[i] = g.origin[i] + x*row[i] + y*col[i] + z*normal[i]
+	}
+	return pos
+}
+
+// frameOffsets returns the GridFrameOffsetVector: the offsets (mm) of the
+// frames along the normal, relative to the first one.
+func (g rtDoseGrid) frameOffsets() []string {
+	offsets := make([]string, g.frames)
+	for i := range offsets {
+		offsets[i] = util.FormatDS(float64(i) * g.frameSpacing)
+	}
+	return offsets
+}
+
+// rtDoseDistribution is a target dose (Gy) delivered to a spherical volume
+// with a steep fall-off outside of it.
+type rtDoseDistribution struct {
+	center     [3]float64 // Grid coordinates (mm) of the target center
+	radius     float64    // mm
+	prescribed float64    // Gy
+}
+
+// newRTDoseDistribution places a target of random size and dose around the
+// center of the grid.
+func newRTDoseDistribution(g rtDoseGrid, rng *randv2.Rand) rtDoseDistribution {
+	extent := [3]float64{
+		float64(g.columns-1) * g.pixelSpacing,
+		float64(g.rows-1) * g.pixelSpacing,
+		float64(g.frames-1) * g.frameSpacing,
+	}
+	smallest := math.Min(extent[0], extent[1])
+	if extent[2] > 0 {
+		smallest = math.Min(smallest, extent[2])
+	}
+
+	var center [3]float64
+	for i := range center {
+		center[i] = extent[i] * (0.35 + 0.3*rng.Float64())
+	}
+	return rtDoseDistribution{
+		center:     center,
+		radius:     math.Max(smallest*(0.15+0.15*rng.Float64()), g.pixelSpacing),
+		prescribed: float64(50 + 2*rng.IntN(11)), // 50-70 Gy
+	}
+}
+
+// at returns the dose (Gy) at grid coordinates (mm): the prescribed dose in
+// the target, falling to a low dose bath away from it.
+func (d rtDoseDistribution) at(x, y, z float64) float64 {
+	dx, dy, dz := x-d.center[0], y-d.center[1], z-d.center[2]
+	r := math.Sqrt(dx*dx+dy*dy+dz*dz) / d.radius
+	return d.prescribed * (0.05 + 0.95/(1+math.Pow(r, 8)))
+}
+
+// rtTarget is the treatment target of a CT study, shared by its RT objects:
+// the dose grid aligned with the largest image series and the dose delivered
+// to a sphere in it.
+type rtTarget struct {
+	volume seriesRecord
+	grid   rtDoseGrid
+	dose   rtDoseDistribution
+}
+
+// newRTTarget places a treatment target in the largest image series of the
+// study.
+func newRTTarget(study studyRecord, rng *randv2.Rand) (rtTarget, error) {
+	volume, ok := study.largestSeries()
+	if !ok {
+		return rtTarget{}, fmt.Errorf("no image series to align the dose grid with")
+	}
+
+	grid := newRTDoseGrid(volume)
+	return rtTarget{volume: volume, grid: grid, dose: newRTDoseDistribution(grid, rng)}, nil
+}
+
+// rtPlanUID returns the SOP Instance UID of the RT Plan of a study, which RT
+// Dose objects reference.
+func rtPlanUID(opts GeneratorOptions, studyNum int) string {
+	return opts.uid(fmt.Sprintf("%s_study_%d_rtplan", opts.OutputDir, studyNum))
+}
+
+// writeRTDose writes a multi-frame RT Dose of the target, whose grid is
+// aligned with the image series and shares its frame of reference.
+func writeRTDose(opts GeneratorOptions, study studyRecord, target rtTarget, seriesNumber int) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+
+	grid, dose := target.grid, target.dose
+	scaling := dose.prescribed / rtDoseMaxValue
+
+	frames := make([]*frame.Frame, grid.frames)
+	for f := range frames {
+		nativeFrame := frame.NewNativeFrame[uint16](16, grid.rows, grid.columns, grid.rows*grid.columns, 1)
+		z := float64(f) * grid.frameSpacing
+		for row := 0; row < grid.rows; row++ {
+			for col := 0; col < grid.columns; col++ {
+				value := dose.at(float64(col)*grid.pixelSpacing, float64(row)*grid.pixelSpacing, z) / scaling
+				nativeFrame.RawData[row*grid.columns+col] = uint16(math.Min(math.Round(value), math.MaxUint16))
+			}
+		}
+		frames[f] = &frame.Frame{Encapsulated: false, NativeData: nativeFrame}
+	}
+
+	orientation := make([]string, len(grid.orientation))
+	for i, v := range grid.orientation {
+		orientation[i] = util.FormatDS(v)
+	}
+
+	elements := study.headerElements("RTDOSE", seriesUID, seriesNumber, "RT Dose")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{RTDoseStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+		mustNewElement(tag.ContentDate, []string{study.studyDate}),
+		mustNewElement(tag.ContentTime, []string{study.studyTime}),
+		mustNewElement(tag.FrameOfReferenceUID, []string{study.frameOfReference}),
+		mustNewElement(tag.PositionReferenceIndicator, []string{""}),
+		mustNewElement(tag.ImagePositionPatient, []string{
+			util.FormatDS(grid.origin[0]), util.FormatDS(grid.origin[1]), util.FormatDS(grid.origin[2]),
+		}),
+		mustNewElement(tag.ImageOrientationPatient, orientation),
+		mustNewElement(tag.PixelSpacing, []string{util.FormatDS(grid.pixelSpacing), util.FormatDS(grid.pixelSpacing)}),
+		mustNewElement(tag.SliceThickness, []string{util.FormatDS(grid.frameSpacing)}),
+		mustNewElement(tag.Rows, []int{grid.rows}),
+		mustNewElement(tag.Columns, []int{grid.columns}),
+		mustNewElement(tag.NumberOfFrames, []string{util.FormatIS(grid.frames)}),
+		mustNewElement(tag.FrameIncrementPointer, []int{int(tag.GridFrameOffsetVector.Group), int(tag.GridFrameOffsetVector.Element)}),
+		mustNewElement(tag.SamplesPerPixel, []int{1}),
+		mustNewElement(tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+		mustNewElement(tag.BitsAllocated, []int{16}),
+		mustNewElement(tag.BitsStored, []int{16}),
+		mustNewElement(tag.HighBit, []int{15}),
+		mustNewElement(tag.PixelRepresentation, []int{0}),
+		mustNewElement(tag.DoseUnits, []string{"GY"}),
+		mustNewElement(tag.DoseType, []string{"PHYSICAL"}),
+		mustNewElement(tag.DoseSummationType, []string{"PLAN"}),
+		mustNewElement(tag.GridFrameOffsetVector, grid.frameOffsets()),
+		mustNewElement(tag.DoseGridScaling, []string{util.FormatDS(scaling)}),
+		mustNewElement(tag.TissueHeterogeneityCorrection, []string{"IMAGE"}),
+		mustNewElement(tag.ReferencedRTPlanSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ReferencedSOPClassUID, []string{RTPlanStorage}),
+			mustNewElement(tag.ReferencedSOPInstanceUID, []string{rtPlanUID(opts, study.studyNum)}),
+		}}),
+		mustNewElement(tag.PixelData, dicom.PixelDataInfo{Frames: frames}),
+	)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "RD", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
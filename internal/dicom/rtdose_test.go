@@ -0,0 +1,83 @@
+package dicom
+
+import (
+	"math"
+	randv2 "math/rand/v2"
+	"testing"
+)
+
+func testCTSeries(numSlices int) seriesRecord {
+	return seriesRecord{
+		instanceUIDs: make([]string, numSlices),
+		orientation:  []float64{1, 0, 0, 0, 1, 0},
+		rows:         256,
+		columns:      256,
+		pixelSpacing: 0.75,
+		sliceSpacing: 1.25,
+	}
+}
+
+func TestNewRTDoseGrid(t *testing.T) {
+	series := testCTSeries(40)
+	grid := newRTDoseGrid(series)
+
+	// 3 mm / 0.75 mm: every 4th voxel; 3 mm / 1.25 mm: every 2nd slice
+	if grid.columns != 64 || grid.rows != 64 || grid.frames != 20 {
+		t.Errorf("grid = %dx%dx%d, want 64x64x20", grid.columns, grid.rows, grid.frames)
+	}
+	if grid.pixelSpacing != 3 || grid.frameSpacing != 2.5 {
+		t.Errorf("spacing = %v mm, %v mm, want 3 mm, 2.5 mm", grid.pixelSpacing, grid.frameSpacing)
+	}
+
+	first := slicePosition(series.orientation, series.columns, series.rows, series.pixelSpacing, series.sliceSpacing, 0, 40)
+	if grid.origin != first {
+		t.Errorf("origin = %v, want first CT slice position %v", grid.origin, first)
+	}
+
+	// The last frame lies on a CT slice
+	last := slicePosition(series.orientation, series.columns, series.rows, series.pixelSpacing, series.sliceSpacing, 38, 40)
+	if z := grid.origin[2] + float64(grid.frames-1)*grid.frameSpacing; math.Abs(z-last[2]) > 1e-9 {
+		t.Errorf("last frame at z = %v, want CT slice at %v", z, last[2])
+	}
+
+	offsets := grid.frameOffsets()
+	if len(offsets) != 20 || offsets[0] != "0" || offsets[1] != "2.5" {
+		t.Errorf("GridFrameOffsetVector = %v", offsets)
+	}
+}
+
+func TestNewRTDoseGrid_FineSpacing(t *testing.T) {
+	series := testCTSeries(1)
+	series.pixelSpacing = 4
+	series.sliceSpacing = 5
+
+	grid := newRTDoseGrid(series)
+	if grid.columns != 256 || grid.frames != 1 || grid.pixelSpacing != 4 {
+		t.Errorf("grid = %dx%dx%d at %v mm, want the CT grid", grid.columns, grid.rows, grid.frames, grid.pixelSpacing)
+	}
+}
+
+func TestRTDoseDistribution(t *testing.T) {
+	grid := newRTDoseGrid(testCTSeries(40))
+	rng := randv2.New(randv2.NewPCG(1, 2))
+
+	for i := 0; i < 20; i++ {
+		dose := newRTDoseDistribution(grid, rng)
+		if dose.prescribed < 50 || dose.prescribed > 70 {
+			t.Errorf("prescribed dose = %v Gy, want 50-70", dose.prescribed)
+		}
+
+		center := dose.at(dose.center[0], dose.center[1], dose.center[2])
+		if math.Abs(center-dose.prescribed) > 1e-9 {
+			t.Errorf("dose at target center = %v, want %v", center, dose.prescribed)
+		}
+		edge := dose.at(dose.center[0]+dose.radius, dose.center[1], dose.center[2])
+		if edge >= center || edge <= center/3 {
+			t.Errorf("dose at target edge = %v, want about half of %v", edge, center)
+		}
+		far := dose.at(dose.center[0]+4*dose.radius, dose.center[1], dose.center[2])
+		if far > 0.1*dose.prescribed {
+			t.Errorf("dose far from target = %v, want a low dose bath", far)
+		}
+	}
+}
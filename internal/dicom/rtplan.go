@@ -0,0 +1,278 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	randv2 "math/rand/v2"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// rtContourPoints is the number of points of each planar target contour
+const rtContourPoints = 36
+
+// rtFieldMargin is the margin (mm) between the target and the jaws
+const rtFieldMargin = 7.0
+
+// rtDosePerFraction is the dose (Gy) delivered by each fraction
+const rtDosePerFraction = 2.0
+
+// Detached Study Management SOP Class, which RT objects use to reference
+// the study of their images
+const detachedStudyManagement = "1.2.840.10008.3.1.2.3.1"
+
+// targetContours returns the planar contours of the target sphere on the
+// slices of the image series that cross it, with the index of each slice.
+// Points are patient coordinates (mm), flattened as ContourData.
+func (t rtTarget) targetContours() ([]int, [][]string) {
+	var slices []int
+	var contours [][]string
+	for i := range t.volume.instanceUIDs {
+		dz := float64(i)*t.volume.sliceSpacing - t.dose.center[2]
+		if math.Abs(dz) >= t.dose.radius {
+			continue
+		}
+		r := math.Sqrt(t.dose.radius*t.dose.radius - dz*dz)
+
+		data := make([]string, 0, 3*rtContourPoints)
+		for p := 0; p < rtContourPoints; p++ {
+			angle := 2 * math.Pi * float64(p) / rtContourPoints
+			pos := t.grid.patientPosition(t.dose.center[0]+r*math.Cos(angle), t.dose.center[1]+r*math.Sin(angle), float64(i)*t.volume.sliceSpacing)
+			data = append(data, util.FormatDS(pos[0]), util.FormatDS(pos[1]), util.FormatDS(pos[2]))
+		}
+		slices = append(slices, i)
+		contours = append(contours, data)
+	}
+	return slices, contours
+}
+
+// imageRef returns a ContourImageSequence item referencing a slice of the
+// image series.
+func (t rtTarget) imageRef(slice int) []*dicom.Element {
+	return []*dicom.Element{
+		mustNewElement(tag.ReferencedSOPClassUID, []string{t.volume.sopClassUID}),
+		mustNewElement(tag.ReferencedSOPInstanceUID, []string{t.volume.instanceUIDs[slice]}),
+	}
+}
+
+// writeRTStructureSet writes an RT Structure Set holding the target as a
+// PTV contoured on the images it crosses.
+func writeRTStructureSet(opts GeneratorOptions, study studyRecord, target rtTarget, seriesNumber int) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+
+	allImages := make([][]*dicom.Element, len(target.volume.instanceUIDs))
+	for i := range allImages {
+		allImages[i] = target.imageRef(i)
+	}
+
+	slices, data := target.targetContours()
+	contours := make([][]*dicom.Element, len(slices))
+	for i, slice := range slices {
+		contours[i] = []*dicom.Element{
+			mustNewElement(tag.ContourImageSequence, [][]*dicom.Element{target.imageRef(slice)}),
+			mustNewElement(tag.ContourGeometricType, []string{"CLOSED_PLANAR"}),
+			mustNewElement(tag.NumberOfContourPoints, []string{util.FormatIS(rtContourPoints)}),
+			mustNewElement(tag.ContourNumber, []string{util.FormatIS(i + 1)}),
+			mustNewElement(tag.ContourData, data[i]),
+		}
+	}
+
+	elements := study.headerElements("RTSTRUCT", seriesUID, seriesNumber, "RT Structure Set")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{RTStructureSetStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+		mustNewElement(tag.FrameOfReferenceUID, []string{study.frameOfReference}),
+		mustNewElement(tag.PositionReferenceIndicator, []string{""}),
+		mustNewElement(tag.StructureSetLabel, []string{"Target"}),
+		mustNewElement(tag.StructureSetDate, []string{study.studyDate}),
+		mustNewElement(tag.StructureSetTime, []string{study.studyTime}),
+		mustNewElement(tag.ReferencedFrameOfReferenceSequence, [][]*dicom.Element{{
+			mustNewElement(tag.FrameOfReferenceUID, []string{study.frameOfReference}),
+			mustNewElement(tag.RTReferencedStudySequence, [][]*dicom.Element{{
+				mustNewElement(tag.ReferencedSOPClassUID, []string{detachedStudyManagement}),
+				mustNewElement(tag.ReferencedSOPInstanceUID, []string{study.studyUID}),
+				mustNewElement(tag.RTReferencedSeriesSequence, [][]*dicom.Element{{
+					mustNewElement(tag.SeriesInstanceUID, []string{target.volume.seriesUID}),
+					mustNewElement(tag.ContourImageSequence, allImages),
+				}}),
+			}}),
+		}}),
+		mustNewElement(tag.StructureSetROISequence, [][]*dicom.Element{{
+			mustNewElement(tag.ROINumber, []string{"1"}),
+			mustNewElement(tag.ReferencedFrameOfReferenceUID, []string{study.frameOfReference}),
+			mustNewElement(tag.ROIName, []string{"PTV"}),
+			mustNewElement(tag.ROIGenerationAlgorithm, []string{"MANUAL"}),
+		}}),
+		mustNewElement(tag.ROIContourSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ROIDisplayColor, []string{"255", "0", "0"}),
+			mustNewElement(tag.ContourSequence, contours),
+			mustNewElement(tag.ReferencedROINumber, []string{"1"}),
+		}}),
+		mustNewElement(tag.RTROIObservationsSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ObservationNumber, []string{"1"}),
+			mustNewElement(tag.ReferencedROINumber, []string{"1"}),
+			mustNewElement(tag.RTROIInterpretedType, []string{"PTV"}),
+			mustNewElement(tag.ROIInterpreter, []string{""}),
+		}}),
+	)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "RS", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
+
+// rtBeam is a static photon beam of the plan, aimed at the isocenter
+type rtBeam struct {
+	gantryAngle int     // degrees
+	meterset    float64 // MU per fraction
+}
+
+// writeRTPlan writes an RT Plan treating the target with coplanar static
+// photon beams: the prescription, one fraction group and the beams with
+// their two control points. It references the structure set holding the
+// target and has the UID referenced by the RT Dose.
+func writeRTPlan(opts GeneratorOptions, study studyRecord, target rtTarget, structureSetUID string, seriesNumber int, rng *randv2.Rand) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := rtPlanUID(opts, study.studyNum)
+
+	prescribed := target.dose.prescribed
+	fractions := int(math.Round(prescribed / rtDosePerFraction))
+	energy := []string{"6", "10", "15"}[rng.IntN(3)]
+	numBeams := []int{3, 4, 5, 7}[rng.IntN(4)]
+	beams := make([]rtBeam, numBeams)
+	for i := range beams {
+		beams[i] = rtBeam{
+			gantryAngle: (i * 360 / numBeams) % 360,
+			// About 1 MU per cGy, plus attenuation
+			meterset: math.Round(rtDosePerFraction*100/float64(numBeams)*(1.1+0.3*rng.Float64())*10) / 10,
+		}
+	}
+
+	iso := target.grid.patientPosition(target.dose.center[0], target.dose.center[1], target.dose.center[2])
+	isocenter := []string{util.FormatDS(iso[0]), util.FormatDS(iso[1]), util.FormatDS(iso[2])}
+	jaw := util.FormatDS(math.Round(target.dose.radius + rtFieldMargin))
+	jawPositions := func(device string) []*dicom.Element {
+		return []*dicom.Element{
+			mustNewElement(tag.RTBeamLimitingDeviceType, []string{device}),
+			mustNewElement(tag.LeafJawPositions, []string{"-" + jaw, jaw}),
+		}
+	}
+
+	beamItems := make([][]*dicom.Element, numBeams)
+	referencedBeams := make([][]*dicom.Element, numBeams)
+	for i, beam := range beams {
+		number := util.FormatIS(i + 1)
+		beamItems[i] = []*dicom.Element{
+			mustNewElement(tag.TreatmentMachineName, []string{"LINAC1"}),
+			mustNewElement(tag.PrimaryDosimeterUnit, []string{"MU"}),
+			mustNewElement(tag.SourceAxisDistance, []string{"1000"}),
+			mustNewElement(tag.BeamLimitingDeviceSequence, [][]*dicom.Element{
+				{
+					mustNewElement(tag.RTBeamLimitingDeviceType, []string{"ASYMX"}),
+					mustNewElement(tag.NumberOfLeafJawPairs, []string{"1"}),
+				},
+				{
+					mustNewElement(tag.RTBeamLimitingDeviceType, []string{"ASYMY"}),
+					mustNewElement(tag.NumberOfLeafJawPairs, []string{"1"}),
+				},
+			}),
+			mustNewElement(tag.BeamNumber, []string{number}),
+			mustNewElement(tag.BeamName, []string{fmt.Sprintf("G%03d", beam.gantryAngle)}),
+			mustNewElement(tag.BeamType, []string{"STATIC"}),
+			mustNewElement(tag.RadiationType, []string{"PHOTON"}),
+			mustNewElement(tag.TreatmentDeliveryType, []string{"TREATMENT"}),
+			mustNewElement(tag.NumberOfWedges, []string{"0"}),
+			mustNewElement(tag.NumberOfCompensators, []string{"0"}),
+			mustNewElement(tag.NumberOfBoli, []string{"0"}),
+			mustNewElement(tag.NumberOfBlocks, []string{"0"}),
+			mustNewElement(tag.FinalCumulativeMetersetWeight, []string{"1"}),
+			mustNewElement(tag.NumberOfControlPoints, []string{"2"}),
+			mustNewElement(tag.ControlPointSequence, [][]*dicom.Element{
+				{
+					mustNewElement(tag.ControlPointIndex, []string{"0"}),
+					mustNewElement(tag.NominalBeamEnergy, []string{energy}),
+					mustNewElement(tag.DoseRateSet, []string{"600"}),
+					mustNewElement(tag.BeamLimitingDevicePositionSequence, [][]*dicom.Element{
+						jawPositions("ASYMX"),
+						jawPositions("ASYMY"),
+					}),
+					mustNewElement(tag.GantryAngle, []string{util.FormatIS(beam.gantryAngle)}),
+					mustNewElement(tag.GantryRotationDirection, []string{"NONE"}),
+					mustNewElement(tag.BeamLimitingDeviceAngle, []string{"0"}),
+					mustNewElement(tag.BeamLimitingDeviceRotationDirection, []string{"NONE"}),
+					mustNewElement(tag.PatientSupportAngle, []string{"0"}),
+					mustNewElement(tag.PatientSupportRotationDirection, []string{"NONE"}),
+					mustNewElement(tag.TableTopEccentricAngle, []string{"0"}),
+					mustNewElement(tag.TableTopEccentricRotationDirection, []string{"NONE"}),
+					mustNewElement(tag.IsocenterPosition, isocenter),
+					mustNewElement(tag.CumulativeMetersetWeight, []string{"0"}),
+				},
+				{
+					mustNewElement(tag.ControlPointIndex, []string{"1"}),
+					mustNewElement(tag.CumulativeMetersetWeight, []string{"1"}),
+				},
+			}),
+			mustNewElement(tag.ReferencedPatientSetupNumber, []string{"1"}),
+		}
+		referencedBeams[i] = []*dicom.Element{
+			mustNewElement(tag.BeamDose, []string{util.FormatDS(rtDosePerFraction / float64(numBeams))}),
+			mustNewElement(tag.BeamMeterset, []string{util.FormatDS(beam.meterset)}),
+			mustNewElement(tag.ReferencedBeamNumber, []string{number}),
+		}
+	}
+
+	elements := study.headerElements("RTPLAN", seriesUID, seriesNumber, "RT Plan")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{RTPlanStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+		mustNewElement(tag.FrameOfReferenceUID, []string{study.frameOfReference}),
+		mustNewElement(tag.PositionReferenceIndicator, []string{""}),
+		mustNewElement(tag.RTPlanLabel, []string{fmt.Sprintf("%g Gy/%d fx", prescribed, fractions)}),
+		mustNewElement(tag.RTPlanDate, []string{study.studyDate}),
+		mustNewElement(tag.RTPlanTime, []string{study.studyTime}),
+		mustNewElement(tag.RTPlanGeometry, []string{"PATIENT"}),
+		mustNewElement(tag.ReferencedStructureSetSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ReferencedSOPClassUID, []string{RTStructureSetStorage}),
+			mustNewElement(tag.ReferencedSOPInstanceUID, []string{structureSetUID}),
+		}}),
+		mustNewElement(tag.DoseReferenceSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ReferencedROINumber, []string{"1"}),
+			mustNewElement(tag.DoseReferenceNumber, []string{"1"}),
+			mustNewElement(tag.DoseReferenceStructureType, []string{"VOLUME"}),
+			mustNewElement(tag.DoseReferenceDescription, []string{"PTV"}),
+			mustNewElement(tag.DoseReferenceType, []string{"TARGET"}),
+			mustNewElement(tag.TargetPrescriptionDose, []string{util.FormatDS(prescribed)}),
+		}}),
+		mustNewElement(tag.FractionGroupSequence, [][]*dicom.Element{{
+			mustNewElement(tag.FractionGroupNumber, []string{"1"}),
+			mustNewElement(tag.NumberOfFractionsPlanned, []string{util.FormatIS(fractions)}),
+			mustNewElement(tag.NumberOfBeams, []string{util.FormatIS(numBeams)}),
+			mustNewElement(tag.NumberOfBrachyApplicationSetups, []string{"0"}),
+			mustNewElement(tag.ReferencedBeamSequence, referencedBeams),
+		}}),
+		mustNewElement(tag.BeamSequence, beamItems),
+		mustNewElement(tag.PatientSetupSequence, [][]*dicom.Element{{
+			mustNewElement(tag.PatientPosition, []string{study.patientPosition}),
+			mustNewElement(tag.PatientSetupNumber, []string{"1"}),
+			mustNewElement(tag.SetupTechnique, []string{"ISOCENTRIC"}),
+		}}),
+	)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "RP", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
@@ -0,0 +1,53 @@
+package dicom
+
+import (
+	"math"
+	randv2 "math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+func TestRTTargetContours(t *testing.T) {
+	series := testCTSeries(40)
+	series.orientation = []float64{1, 0, 0, 0, 0, -1} // Coronal
+	study := studyRecord{series: []seriesRecord{series}}
+
+	target, err := newRTTarget(study, randv2.New(randv2.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatalf("newRTTarget: %v", err)
+	}
+	center := target.grid.patientPosition(target.dose.center[0], target.dose.center[1], target.dose.center[2])
+
+	slices, contours := target.targetContours()
+	if len(slices) == 0 || len(slices) != len(contours) {
+		t.Fatalf("%d slices, %d contours", len(slices), len(contours))
+	}
+	for i, slice := range slices {
+		slicePos := slicePosition(series.orientation, series.columns, series.rows, series.pixelSpacing, series.sliceSpacing, slice, 40)
+		location := sliceLocationOf(slicePos, series.orientation)
+
+		if len(contours[i]) != 3*rtContourPoints {
+			t.Fatalf("contour %d has %d values", i, len(contours[i]))
+		}
+		for p := 0; p < len(contours[i]); p += 3 {
+			var point [3]float64
+			for j := range point {
+				point[j], _ = strconv.ParseFloat(contours[i][p+j], 64)
+			}
+			// On the slice plane, and on the target sphere
+			if got := sliceLocationOf(point, series.orientation); math.Abs(got-location) > 0.01 {
+				t.Fatalf("contour %d point at slice location %v, want %v", i, got, location)
+			}
+			dx, dy, dz := point[0]-center[0], point[1]-center[1], point[2]-center[2]
+			if r := math.Sqrt(dx*dx + dy*dy + dz*dz); math.Abs(r-target.dose.radius) > 0.01 {
+				t.Fatalf("contour %d point at %v mm from the center, want %v", i, r, target.dose.radius)
+			}
+		}
+	}
+}
+
+func TestNewRTTarget_NoImages(t *testing.T) {
+	if _, err := newRTTarget(studyRecord{}, randv2.New(randv2.NewPCG(1, 2))); err == nil {
+		t.Error("expected an error without image series")
+	}
+}
@@ -0,0 +1,292 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	randv2 "math/rand/v2"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// SegmentationStorage is the SOP Class UID of DICOM Segmentation objects.
+const SegmentationStorage = "1.2.840.10008.5.1.4.1.1.66.4"
+
+// segAlgorithmName identifies the simulated segmentation algorithm
+const segAlgorithmName = "DICOMFORGE SEGMENT"
+
+// Segmented property categories
+var (
+	segAnatomicalStructure = sr.Code{Value: "123037004", Scheme: "SCT", Meaning: "Anatomical Structure"}
+	segAlteredStructure    = sr.Code{Value: "49755003", Scheme: "SCT", Meaning: "Morphologically Altered Structure"}
+)
+
+// segmentType is the segmented property of a segment, with its display color.
+type segmentType struct {
+	category sr.Code
+	property sr.Code
+	color    [3]uint8 // sRGB
+}
+
+// segmentTypes are assigned to the segments in order, starting over when
+// there are more segments than types.
+var segmentTypes = []segmentType{
+	{segAnatomicalStructure, sr.Code{Value: "10200004", Scheme: "SCT", Meaning: "Liver"}, [3]uint8{221, 130, 101}},
+	{segAnatomicalStructure, sr.Code{Value: "78961009", Scheme: "SCT", Meaning: "Spleen"}, [3]uint8{157, 108, 162}},
+	{segAnatomicalStructure, sr.Code{Value: "64033007", Scheme: "SCT", Meaning: "Kidney"}, [3]uint8{185, 102, 83}},
+	{segAnatomicalStructure, sr.Code{Value: "80891009", Scheme: "SCT", Meaning: "Heart"}, [3]uint8{206, 110, 84}},
+	{segAnatomicalStructure, sr.Code{Value: "39607008", Scheme: "SCT", Meaning: "Lung"}, [3]uint8{197, 165, 145}},
+	{segAnatomicalStructure, sr.Code{Value: "89837001", Scheme: "SCT", Meaning: "Urinary bladder"}, [3]uint8{222, 198, 113}},
+	{segAnatomicalStructure, sr.Code{Value: "272673000", Scheme: "SCT", Meaning: "Bone"}, [3]uint8{241, 214, 145}},
+	{segAlteredStructure, sr.Code{Value: "4147007", Scheme: "SCT", Meaning: "Mass"}, [3]uint8{255, 0, 0}},
+}
+
+// segment is an ellipsoid segmented in an image series, in voxel units:
+// columns, rows and slices.
+type segment struct {
+	number int
+	label  string
+	typ    segmentType
+	center [3]float64
+	radii  [3]float64
+}
+
+// contains reports whether the segment covers the voxel at (column, row,
+// slice).
+func (s segment) contains(col, row, slice int) bool {
+	d := 0.0
+	for i, v := range [3]float64{float64(col), float64(row), float64(slice)} {
+		x := (v - s.center[i]) / s.radii[i]
+		d += x * x
+	}
+	return d <= 1
+}
+
+// planSegments places count ellipsoid segments in a series, each about 5 to
+// 20% of the image size and round in patient space.
+func planSegments(count int, series seriesRecord, rng *randv2.Rand) []segment {
+	numSlices := len(series.instanceUIDs)
+	segments := make([]segment, count)
+	for i := range segments {
+		typ := segmentTypes[i%len(segmentTypes)]
+		label := typ.property.Meaning
+		if i >= len(segmentTypes) {
+			label = fmt.Sprintf("%s %d", label, i/len(segmentTypes)+1)
+		}
+
+		radius := float64(min(series.columns, series.rows)) * (0.05 + 0.15*rng.Float64())
+		sliceRadius := 0.5
+		if series.sliceSpacing > 0 {
+			sliceRadius = math.Max(sliceRadius, radius*series.pixelSpacing/series.sliceSpacing)
+		}
+		segments[i] = segment{
+			number: i + 1,
+			label:  label,
+			typ:    typ,
+			center: [3]float64{
+				float64(series.columns-1) * (0.3 + 0.4*rng.Float64()),
+				float64(series.rows-1) * (0.3 + 0.4*rng.Float64()),
+				float64(numSlices-1) * (0.3 + 0.4*rng.Float64()),
+			},
+			radii: [3]float64{radius * (0.8 + 0.4*rng.Float64()), radius * (0.8 + 0.4*rng.Float64()), sliceRadius},
+		}
+	}
+	return segments
+}
+
+// cieLab returns the DICOM encoding of an sRGB color in CIELab (D65): L*
+// scaled from 0-100 and a*, b* from -128-127 to 0-65535.
+func cieLab(rgb [3]uint8) []int {
+	linear := func(c uint8) float64 {
+		v := float64(c) / 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	r, g, b := linear(rgb[0]), linear(rgb[1]), linear(rgb[2])
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	l := 116*f(y) - 16
+	a := 500 * (f(x) - f(y))
+	bb := 200 * (f(y) - f(z))
+
+	scale := func(v, lo, hi float64) int {
+		return int(math.Round((math.Min(math.Max(v, lo), hi) - lo) / (hi - lo) * 65535))
+	}
+	return []int{scale(l, 0, 100), scale(a, -128, 127), scale(bb, -128, 127)}
+}
+
+// writeSegmentation writes a binary Segmentation of the largest image series
+// of the study with numSegments segments. Each frame holds one segment on one
+// slice; slices a segment does not cover have no frame.
+func writeSegmentation(opts GeneratorOptions, study studyRecord, numSegments, seriesNumber int, rng *randv2.Rand) (GeneratedFile, error) {
+	seriesUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d", opts.OutputDir, study.studyNum, seriesNumber))
+	sopInstanceUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_instance_1", opts.OutputDir, study.studyNum, seriesNumber))
+	dimensionsUID := opts.uid(fmt.Sprintf("%s_study_%d_series_%d_dimensions", opts.OutputDir, study.studyNum, seriesNumber))
+
+	source, ok := study.largestSeries()
+	if !ok {
+		return GeneratedFile{}, fmt.Errorf("no image series to segment")
+	}
+	numSlices := len(source.instanceUIDs)
+	segments := planSegments(numSegments, source, rng)
+
+	segmentItems := make([][]*dicom.Element, len(segments))
+	for i, s := range segments {
+		segmentItems[i] = []*dicom.Element{
+			sr.CodeSequence(tag.SegmentedPropertyCategoryCodeSequence, s.typ.category),
+			mustNewElement(tag.SegmentNumber, []int{s.number}),
+			mustNewElement(tag.SegmentLabel, []string{s.label}),
+			mustNewElement(tag.SegmentAlgorithmType, []string{"AUTOMATIC"}),
+			mustNewElement(tag.SegmentAlgorithmName, []string{segAlgorithmName}),
+			mustNewElement(tag.RecommendedDisplayCIELabValue, cieLab(s.typ.color)),
+			sr.CodeSequence(tag.SegmentedPropertyTypeCodeSequence, s.typ.property),
+		}
+	}
+
+	// Frames are ordered by segment, then slice. Bits are packed across
+	// frames, first pixel in the least significant bit.
+	pixelsPerFrame := source.rows * source.columns
+	var frames [][]*dicom.Element
+	var bits []byte
+	numBits := 0
+	for _, s := range segments {
+		for slice := 0; slice < numSlices; slice++ {
+			var covered []int
+			for row := 0; row < source.rows; row++ {
+				for col := 0; col < source.columns; col++ {
+					if s.contains(col, row, slice) {
+						covered = append(covered, row*source.columns+col)
+					}
+				}
+			}
+			if len(covered) == 0 {
+				continue
+			}
+
+			bits = append(bits, make([]byte, (numBits+pixelsPerFrame+7)/8-len(bits))...)
+			for _, pixel := range covered {
+				bit := numBits + pixel
+				bits[bit/8] |= 1 << (bit % 8)
+			}
+			numBits += pixelsPerFrame
+
+			position := slicePosition(source.orientation, source.columns, source.rows,
+				source.pixelSpacing, source.sliceSpacing, slice, numSlices)
+			frames = append(frames, []*dicom.Element{
+				mustNewElement(tag.DerivationImageSequence, [][]*dicom.Element{{
+					mustNewElement(tag.SourceImageSequence, [][]*dicom.Element{{
+						mustNewElement(tag.ReferencedSOPClassUID, []string{source.sopClassUID}),
+						mustNewElement(tag.ReferencedSOPInstanceUID, []string{source.instanceUIDs[slice]}),
+						sr.CodeSequence(tag.PurposeOfReferenceCodeSequence, sr.Code{Value: "121322", Scheme: "DCM", Meaning: "Source image for image processing operation"}),
+					}}),
+					sr.CodeSequence(tag.DerivationCodeSequence, sr.Code{Value: "113076", Scheme: "DCM", Meaning: "Segmentation"}),
+				}}),
+				mustNewElement(tag.FrameContentSequence, [][]*dicom.Element{{
+					mustNewElement(tag.DimensionIndexValues, []int{s.number, slice + 1}),
+				}}),
+				mustNewElement(tag.PlanePositionSequence, [][]*dicom.Element{{
+					mustNewElement(tag.ImagePositionPatient, []string{
+						util.FormatDS(position[0]), util.FormatDS(position[1]), util.FormatDS(position[2]),
+					}),
+				}}),
+				mustNewElement(tag.SegmentIdentificationSequence, [][]*dicom.Element{{
+					mustNewElement(tag.ReferencedSegmentNumber, []int{s.number}),
+				}}),
+			})
+		}
+	}
+	if len(bits)%2 != 0 {
+		bits = append(bits, 0)
+	}
+
+	orientation := make([]string, len(source.orientation))
+	for i, v := range source.orientation {
+		orientation[i] = util.FormatDS(v)
+	}
+	sourceImages := make([][]*dicom.Element, numSlices)
+	for i, uid := range source.instanceUIDs {
+		sourceImages[i] = []*dicom.Element{
+			mustNewElement(tag.ReferencedSOPClassUID, []string{source.sopClassUID}),
+			mustNewElement(tag.ReferencedSOPInstanceUID, []string{uid}),
+		}
+	}
+
+	elements := study.headerElements("SEG", seriesUID, seriesNumber, "Segmentation")
+	elements = append(elements,
+		mustNewElement(tag.SOPClassUID, []string{SegmentationStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{sopInstanceUID}),
+		mustNewElement(tag.ImageType, []string{"DERIVED", "PRIMARY"}),
+		mustNewElement(tag.InstanceNumber, []string{"1"}),
+		mustNewElement(tag.ContentDate, []string{study.studyDate}),
+		mustNewElement(tag.ContentTime, []string{study.studyTime}),
+		mustNewElement(tag.FrameOfReferenceUID, []string{study.frameOfReference}),
+		mustNewElement(tag.PositionReferenceIndicator, []string{""}),
+		mustNewElement(tag.ReferencedSeriesSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ReferencedInstanceSequence, sourceImages),
+			mustNewElement(tag.SeriesInstanceUID, []string{source.seriesUID}),
+		}}),
+		mustNewElement(tag.DimensionOrganizationSequence, [][]*dicom.Element{{
+			mustNewElement(tag.DimensionOrganizationUID, []string{dimensionsUID}),
+		}}),
+		mustNewElement(tag.DimensionIndexSequence, [][]*dicom.Element{
+			{
+				mustNewElement(tag.DimensionOrganizationUID, []string{dimensionsUID}),
+				mustNewElement(tag.DimensionIndexPointer, []int{int(tag.ReferencedSegmentNumber.Group), int(tag.ReferencedSegmentNumber.Element)}),
+				mustNewElement(tag.FunctionalGroupPointer, []int{int(tag.SegmentIdentificationSequence.Group), int(tag.SegmentIdentificationSequence.Element)}),
+				mustNewElement(tag.DimensionDescriptionLabel, []string{"ReferencedSegmentNumber"}),
+			},
+			{
+				mustNewElement(tag.DimensionOrganizationUID, []string{dimensionsUID}),
+				mustNewElement(tag.DimensionIndexPointer, []int{int(tag.ImagePositionPatient.Group), int(tag.ImagePositionPatient.Element)}),
+				mustNewElement(tag.FunctionalGroupPointer, []int{int(tag.PlanePositionSequence.Group), int(tag.PlanePositionSequence.Element)}),
+				mustNewElement(tag.DimensionDescriptionLabel, []string{"ImagePositionPatient"}),
+			},
+		}),
+		mustNewElement(tag.SamplesPerPixel, []int{1}),
+		mustNewElement(tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+		mustNewElement(tag.NumberOfFrames, []string{util.FormatIS(len(frames))}),
+		mustNewElement(tag.Rows, []int{source.rows}),
+		mustNewElement(tag.Columns, []int{source.columns}),
+		mustNewElement(tag.BitsAllocated, []int{1}),
+		mustNewElement(tag.BitsStored, []int{1}),
+		mustNewElement(tag.HighBit, []int{0}),
+		mustNewElement(tag.PixelRepresentation, []int{0}),
+		mustNewElement(tag.LossyImageCompression, []string{"00"}),
+		mustNewElement(tag.SegmentationType, []string{"BINARY"}),
+		mustNewElement(tag.SegmentSequence, segmentItems),
+		mustNewElement(tag.ContentLabel, []string{"SEGMENTATION"}),
+		mustNewElement(tag.ContentDescription, []string{"Synthetic segmentation"}),
+		mustNewElement(tag.ContentCreatorName, []string{""}),
+		mustNewElement(tag.SharedFunctionalGroupsSequence, [][]*dicom.Element{{
+			mustNewElement(tag.PlaneOrientationSequence, [][]*dicom.Element{{
+				mustNewElement(tag.ImageOrientationPatient, orientation),
+			}}),
+			mustNewElement(tag.PixelMeasuresSequence, [][]*dicom.Element{{
+				mustNewElement(tag.SliceThickness, []string{util.FormatDS(source.sliceSpacing)}),
+				mustNewElement(tag.PixelSpacing, []string{util.FormatDS(source.pixelSpacing), util.FormatDS(source.pixelSpacing)}),
+			}}),
+		}}),
+		mustNewElement(tag.PerFrameFunctionalGroupsSequence, frames),
+		mustNewElement(tag.PixelData, dicom.PixelDataInfo{IntentionallyUnprocessed: true, UnprocessedValueData: bits}),
+	)
+	sr.SortElements(elements)
+
+	path := derivedFilePath(opts, "SG", study.studyNum, seriesNumber, 1)
+	if err := writeDatasetToFile(path, dicom.Dataset{Elements: elements}); err != nil {
+		return GeneratedFile{}, err
+	}
+
+	return study.derivedFile(path, seriesUID, sopInstanceUID, seriesNumber, 1), nil
+}
@@ -0,0 +1,54 @@
+package dicom
+
+import (
+	randv2 "math/rand/v2"
+	"testing"
+)
+
+func TestPlanSegments(t *testing.T) {
+	series := testCTSeries(40)
+	segments := planSegments(10, series, randv2.New(randv2.NewPCG(1, 2)))
+
+	if len(segments) != 10 {
+		t.Fatalf("got %d segments, want 10", len(segments))
+	}
+	if segments[0].label != "Liver" || segments[8].label != "Liver 2" || segments[9].number != 10 {
+		t.Errorf("labels = %q, %q, number %d", segments[0].label, segments[8].label, segments[9].number)
+	}
+	for _, s := range segments {
+		col, row, slice := int(s.center[0]+0.5), int(s.center[1]+0.5), int(s.center[2]+0.5)
+		if !s.contains(col, row, slice) {
+			t.Errorf("%s does not contain its center", s.label)
+		}
+		if s.contains(int(s.center[0]+s.radii[0])+1, row, slice) {
+			t.Errorf("%s extends past its radius", s.label)
+		}
+		// Round in patient space: 0.75 mm pixels, 1.25 mm slices
+		if ratio := s.radii[2] / s.radii[0]; ratio < 0.4 || ratio > 0.8 {
+			t.Errorf("%s slice radius / column radius = %v, want about 0.6", s.label, ratio)
+		}
+	}
+}
+
+func TestCIELab(t *testing.T) {
+	tests := []struct {
+		rgb  [3]uint8
+		want []int
+	}{
+		{[3]uint8{0, 0, 0}, []int{0, 32896, 32896}},
+		{[3]uint8{255, 255, 255}, []int{65535, 32896, 32896}},
+	}
+	for _, tt := range tests {
+		got := cieLab(tt.rgb)
+		for i := range got {
+			if d := got[i] - tt.want[i]; d < -5 || d > 5 {
+				t.Errorf("cieLab(%v) = %v, want %v", tt.rgb, got, tt.want)
+				break
+			}
+		}
+	}
+	// Red: positive a*
+	if red := cieLab([3]uint8{255, 0, 0}); red[1] <= 32896 {
+		t.Errorf("cieLab(red) = %v, want a* > 0", red)
+	}
+}
@@ -0,0 +1,78 @@
+package dicom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultShardFanout is the largest number of entries of an output directory
+// level before they are sharded, well above typical runs and below the sizes
+// at which huge flat directories slow filesystems and file browsers down.
+const DefaultShardFanout = 10000
+
+// shardPrefix names the directories that group the entries of a hierarchy
+// level having more than the fan-out. It matches none of the PT, ST, SE and
+// IM prefixes, and a single shard level per directory level keeps File IDs
+// within the 8 components of PS3.10.
+const shardPrefix = "SH"
+
+// stagingPrefix names the directories that group the files of a generation
+// of more images than the fan-out, until they are organized.
+const stagingPrefix = "TMP"
+
+// shardedPath returns the path of entry idx of the n entries of dir: dir/name
+// when n fits in fanout, dir/SHnnnnnn/name grouping fanout entries per shard
+// otherwise. With more than fanout² entries, there are more shards than
+// fanout.
+func shardedPath(dir, name string, idx, n, fanout int) string {
+	if fanout <= 0 || n <= fanout {
+		return filepath.Join(dir, name)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s%06d", shardPrefix, idx/fanout), name)
+}
+
+// stagingPath returns the path of the image globalIndex (from 1) of a
+// generation of numImages images before they are organized: flat in
+// outputDir, or in TMPnnnn shards when numImages exceeds fanout.
+func stagingPath(outputDir string, globalIndex, numImages, fanout int) string {
+	name := fmt.Sprintf("IMG%04d.dcm", globalIndex)
+	if fanout <= 0 || numImages <= fanout {
+		return filepath.Join(outputDir, name)
+	}
+	return filepath.Join(outputDir, fmt.Sprintf("%s%04d", stagingPrefix, (globalIndex-1)/fanout), name)
+}
+
+// hierarchyEntries returns the entries of dir whose name starts with prefix,
+// directly in dir or in its shards, sorted.
+func hierarchyEntries(dir, prefix string) []string {
+	entries, _ := filepath.Glob(filepath.Join(dir, prefix+"*"))
+	sharded, _ := filepath.Glob(filepath.Join(dir, shardPrefix+"*", prefix+"*"))
+	entries = append(entries, sharded...)
+	sort.Strings(entries)
+	return entries
+}
+
+// hierarchyImages returns the image files of the PT*/ST*/SE* hierarchy of
+// dir, sharded or not, in hierarchy order.
+func hierarchyImages(dir string) []string {
+	var images []string
+	for _, patientDir := range hierarchyEntries(dir, "PT") {
+		for _, studyDir := range hierarchyEntries(patientDir, "ST") {
+			for _, seriesDir := range hierarchyEntries(studyDir, "SE") {
+				images = append(images, hierarchyEntries(seriesDir, "IM")...)
+			}
+		}
+	}
+	return images
+}
+
+// removeStagingDirs removes the TMP* staging shards of outputDir left empty
+// once their files are organized.
+func removeStagingDirs(outputDir string) {
+	dirs, _ := filepath.Glob(filepath.Join(outputDir, stagingPrefix+"*"))
+	for _, dir := range dirs {
+		_ = os.Remove(dir) // Fails on directories still holding files, kept
+	}
+}
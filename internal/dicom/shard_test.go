@@ -0,0 +1,129 @@
+package dicom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestShardedPath(t *testing.T) {
+	tests := []struct {
+		idx, n, fanout int
+		want           string
+	}{
+		{idx: 4, n: 10, fanout: 10, want: "out/IM000005"},
+		{idx: 4, n: 11, fanout: 10, want: "out/SH000000/IM000005"},
+		{idx: 10, n: 11, fanout: 10, want: "out/SH000001/IM000005"},
+		{idx: 250, n: 300, fanout: 10, want: "out/SH000025/IM000005"},
+	}
+	for _, tt := range tests {
+		if got := filepath.ToSlash(shardedPath("out", "IM000005", tt.idx, tt.n, tt.fanout)); got != tt.want {
+			t.Errorf("shardedPath(%d of %d, fan-out %d) = %s, want %s", tt.idx, tt.n, tt.fanout, got, tt.want)
+		}
+	}
+	if got := filepath.ToSlash(stagingPath("out", 21, 30, 10)); got != "out/TMP0002/IMG0021.dcm" {
+		t.Errorf("stagingPath = %s, want out/TMP0002/IMG0021.dcm", got)
+	}
+	if got := filepath.ToSlash(stagingPath("out", 21, 30, 30)); got != "out/IMG0021.dcm" {
+		t.Errorf("stagingPath = %s, want out/IMG0021.dcm", got)
+	}
+}
+
+func TestOrganizeFilesShardsFanout(t *testing.T) {
+	const fanout = 4
+	outputDir := t.TempDir()
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:      30,
+		TotalSize:      "1MB",
+		OutputDir:      outputDir,
+		Seed:           42,
+		NumStudies:     6,
+		NumPatients:    6,
+		SeriesPerStudy: util.SeriesRange{Min: 1, Max: 1},
+		Modality:       modalities.CT,
+		ShardFanout:    fanout,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if dir := filepath.Dir(files[len(files)-1].Path); dir == outputDir {
+		t.Errorf("last staged file %s is not in a staging shard", files[len(files)-1].Path)
+	}
+	if _, err := OrganizeFiles(outputDir, files, OrganizeOptions{Fanout: fanout, Quiet: true}); err != nil {
+		t.Fatalf("OrganizeFiles failed: %v", err)
+	}
+
+	// No directory holds more than the fan-out, staging shards are gone
+	err = filepath.WalkDir(outputDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), stagingPrefix) {
+			t.Errorf("staging directory %s left", path)
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		if path != outputDir && len(entries) > fanout {
+			t.Errorf("%s has %d entries, want at most %d", path, len(entries), fanout)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The DICOMDIR references every file, through the shards
+	dicomdir, err := dicom.ParseFile(filepath.Join(outputDir, "DICOMDIR"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequence, err := dicomdir.FindElementByTag(tag.DirectoryRecordSequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patients, images, sharded := 0, 0, 0
+	for _, item := range sequence.Value.GetValue().([]*dicom.SequenceItemValue) {
+		record := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+		switch getStringValue(record, tag.DirectoryRecordType)[0] {
+		case "PATIENT":
+			patients++
+		case "IMAGE":
+			images++
+			fileID, err := record.FindElementByTag(tag.ReferencedFileID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			components := fileID.Value.GetValue().([]string)
+			if components[0] != "SH000000" && components[0] != "SH000001" {
+				t.Errorf("File ID %v does not start with a patient shard", components)
+			}
+			if len(components) > 8 {
+				t.Errorf("File ID %v has more than 8 components", components)
+			}
+			if len(components) > 5 {
+				sharded++
+			}
+			if _, err := os.Stat(filepath.Join(append([]string{outputDir}, components...)...)); err != nil {
+				t.Errorf("File ID %v: %v", components, err)
+			}
+		}
+	}
+	if patients != 6 || images != len(files) {
+		t.Errorf("DICOMDIR has %d patients and %d images, want 6 and %d", patients, images, len(files))
+	}
+	if sharded == 0 {
+		t.Error("no series sharded its images")
+	}
+	if got := len(hierarchyImages(outputDir)); got != len(files) {
+		t.Errorf("hierarchyImages found %d files, want %d", got, len(files))
+	}
+}
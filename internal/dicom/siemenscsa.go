@@ -0,0 +1,235 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/csa"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// syngo MR software of the Siemens scanners: the baseline of the protocols
+// and the version of the CSA headers
+var csaSoftware = map[string]struct{ baseline, version string }{
+	"Avanto": {"N4_VB17A_LATEST_20090307", "20090307"},
+	"Skyra":  {"N4_VD13A_LATEST_20120616", "20120616"},
+}
+
+// syngo data types of the VRs of CSA elements
+var syngoDataTypes = map[string]int32{"DS": 3, "FD": 4, "IS": 6, "SL": 7, "UL": 9, "US": 10, "CS": 16, "LO": 19, "SH": 22, "UN": 0}
+
+// csaItems is the number of items syngo MR writes for the elements with
+// values, the values then empty items.
+const csaItems = 6
+
+// Readout bandwidths (Hz/pixel) of the echo-planar diffusion sequences and
+// of the others, and the readout oversampling
+const (
+	csaEPIBandwidth    = 1500
+	csaBandwidth       = 200
+	csaOversampling    = 2
+	csaDiffusionBValue = 1000
+)
+
+// siemensCSAElements returns the Siemens private elements of an MR image in
+// block 0x10 of group 0029: the private creator, the CSA Image Header
+// (0029,1010) and the CSA Series Header (0029,1020) in the SV10 format, with
+// their types and versions. The headers hold the parameters of the image as
+// syngo MR writes them, and the ASCCONV protocol of the series in
+// MrPhoenixProtocol.
+func siemensCSAElements(img vendorImage) []*dicom.Element {
+	software, ok := csaSoftware[img.params.Scanner.Model]
+	if !ok {
+		software = csaSoftware["Avanto"]
+	}
+	if img.frequency == 0 || img.phase == 0 {
+		// AcquisitionMatrix omitted by the style: the image matrix
+		img.frequency, img.phase = img.width, img.width
+	}
+	block := func(element uint16, rawVR string, data any) *dicom.Element {
+		return mustNewPrivateElement(tag.Tag{Group: 0x0029, Element: element}, rawVR, data)
+	}
+	return []*dicom.Element{
+		block(0x0010, "LO", []string{"SIEMENS CSA HEADER"}),
+		block(0x1008, "CS", []string{"IMAGE NUM 4"}),
+		block(0x1009, "LO", []string{software.version}),
+		block(0x1010, "OB", csa.Encode(csaImageHeader(img))),
+		block(0x1018, "CS", []string{"MR"}),
+		block(0x1019, "LO", []string{software.version}),
+		block(0x1020, "OB", csa.Encode(csaSeriesHeader(img, software.baseline))),
+	}
+}
+
+// csaImageHeader returns the elements of the CSA Image Header of img.
+func csaImageHeader(img vendorImage) []csa.Element {
+	p := img.params
+	normal := sliceNormal(img.orientation)
+	diffusion := isDiffusion(img.sequence)
+
+	var bValue, directionality, phaseBandwidth []string
+	if diffusion {
+		// Trace-weighted images, without gradient direction
+		bValue = []string{util.FormatIS(csaDiffusionBValue)}
+		directionality = []string{"ISOTROPIC"}
+		// Inverse of the echo spacing (1/bandwidth) times the lines acquired
+		phaseBandwidth = []string{csaFloat(csaEPIBandwidth * math.Max(p.ParallelFactor, 1) / float64(img.phase))}
+	}
+	return []csa.Element{
+		csaElement("EchoLinePosition", "IS", 1, util.FormatIS(img.phase/2)),
+		csaElement("EchoColumnPosition", "IS", 1, util.FormatIS(img.frequency/2)),
+		csaElement("EchoPartitionPosition", "IS", 1, "0"),
+		csaElement("B_value", "IS", 1, bValue...),
+		csaElement("ProtocolSliceNumber", "IS", 1, util.FormatIS(img.sliceIndex)),
+		csaElement("RealDwellTime", "IS", 1, util.FormatIS(csaDwellTime(img))),
+		csaElement("SliceMeasurementDuration", "DS", 1, csaFloat(p.RepetitionTime*float64(img.phase)/math.Max(p.ParallelFactor, 1))),
+		csaElement("AcquisitionMatrixText", "SH", 1, fmt.Sprintf("%dp*%d", img.phase, img.frequency)),
+		csaElement("PhaseEncodingDirectionPositive", "IS", 1, "1"),
+		csaElement("NumberOfImagesInMosaic", "US", 1),
+		csaElement("DiffusionGradientDirection", "FD", 3),
+		csaElement("SliceNormalVector", "FD", 3, csaFloat(normal[0]), csaFloat(normal[1]), csaFloat(normal[2])),
+		csaElement("DiffusionDirectionality", "CS", 1, directionality...),
+		// Slices interleaved over the repetition time
+		csaElement("TimeAfterStart", "DS", 1, csaFloat(float64(img.sliceIndex)*p.RepetitionTime/float64(max(img.numSlices, 1))/1000)),
+		csaElement("ImaRelTablePosition", "IS", 3, "0", "0", "0"),
+		csaElement("ImaCoilString", "LO", 1, p.ReceiveCoilName),
+		csaElement("ImaPATModeText", "LO", 1, csaPATMode(p)...),
+		csaElement("BandwidthPerPixelPhaseEncode", "FD", 1, phaseBandwidth...),
+		csaElement("MosaicRefAcqTimes", "FD", 0),
+		csaElement("RFSWDDataType", "SH", 1, "predicted"),
+		csaElement("GSWDDataType", "SH", 1, "predicted"),
+		csaElement("MultistepIndex", "IS", 1, "0"),
+	}
+}
+
+// csaSeriesHeader returns the elements of the CSA Series Header of img, of a
+// protocol of the baseline.
+func csaSeriesHeader(img vendorImage, baseline string) []csa.Element {
+	var weight []string
+	if img.patientWeight > 0 {
+		weight = []string{util.FormatIS(int(math.Round(img.patientWeight)))}
+	}
+	return []csa.Element{
+		csaElement("UsedPatientWeight", "IS", 1, weight...),
+		csaElement("NumberOfPrescans", "IS", 1, "0"),
+		csaElement("PhaseGradientAmplitude", "DS", 1, csaFloat(0)),
+		csaElement("ReadoutGradientAmplitude", "DS", 1, csaFloat(0)),
+		csaElement("SelectionGradientAmplitude", "DS", 1, csaFloat(0)),
+		csaElement("RfWatchdogMask", "IS", 1, "0"),
+		csaElement("SafetyStandard", "LO", 1, "IEC"),
+		csaElement("SliceArrayConcatenations", "IS", 1, "1"),
+		csaElement("SliceResolution", "DS", 1, csaFloat(1)),
+		csaElement("CoilString", "LO", 1, img.params.ReceiveCoilName),
+		csaElement("PATModeText", "LO", 1, csaPATMode(img.params)...),
+		csaElement("PositivePCSDirections", "SH", 1, "+LPH"),
+		csaElement("ProtocolChangeHistory", "US", 1, "0"),
+		csaElement("Isocentered", "US", 1, "1"),
+		csaElement("MrPhoenixProtocol", "UN", 1, csaProtocol(img, baseline)),
+	}
+}
+
+// csaProtocol returns the ASCCONV block of the protocol of img, in which
+// syngo MR lists the parameters of the sequence: times in microseconds,
+// strings in doubled quotes.
+func csaProtocol(img vendorImage, baseline string) string {
+	p := img.params
+	fov := float64(img.width) * p.PixelSpacing
+	patMode := 1 // None
+	if p.ParallelFactor > 1 {
+		patMode = 2 // GRAPPA
+	}
+
+	var b strings.Builder
+	line := func(name string, value any) {
+		switch v := value.(type) {
+		case string:
+			value = `""` + v + `""`
+		case float64:
+			value = util.FormatDS(v)
+		}
+		fmt.Fprintf(&b, "%-40s = %v\n", name, value)
+	}
+	b.WriteString("### ASCCONV BEGIN ###\n")
+	line("tSequenceFileName", `%SiemensSeq%\`+vendorSequenceOf(img.sequence).siemens)
+	line("tProtocolName", img.protocol)
+	line("sProtConsistencyInfo.tBaselineString", baseline)
+	line("sProtConsistencyInfo.flNominalB0", p.MagneticFieldStrength)
+	line("sRXSPEC.alDwellTime[0]", csaDwellTime(img))
+	line("sTXSPEC.asNucleusInfo[0].tNucleus", "1H")
+	line("sTXSPEC.asNucleusInfo[0].lFrequency", int(math.Round(p.ImagingFrequency*1e6)))
+	line("sKSpace.lBaseResolution", img.frequency)
+	line("sKSpace.lPhaseEncodingLines", img.phase)
+	line("sKSpace.dPhaseResolution", float64(img.phase)/float64(img.frequency))
+	line("sSliceArray.asSlice[0].dThickness", p.SliceThickness)
+	line("sSliceArray.asSlice[0].dPhaseFOV", fov)
+	line("sSliceArray.asSlice[0].dReadoutFOV", fov)
+	line("sSliceArray.lSize", img.numSlices)
+	line("sPat.lAccelFactPE", max(int(p.ParallelFactor), 1))
+	fmt.Fprintf(&b, "%-40s = 0x%x\n", "sPat.ucPATMode", patMode)
+	line("alTR[0]", int(math.Round(p.RepetitionTime*1000)))
+	line("alTE[0]", int(math.Round(p.EchoTime*1000)))
+	line("adFlipAngleDegree[0]", p.FlipAngle)
+	if p.ReceiveCoilName != "" {
+		line("sCoilSelectMeas.aRxCoilSelectData[0].asList[0].sCoilElementID.tCoilID", p.ReceiveCoilName)
+	}
+	if isDiffusion(img.sequence) {
+		line("sDiffusion.lDiffWeightings", 2)
+		line("sDiffusion.alBValue[1]", csaDiffusionBValue)
+	}
+	b.WriteString("### ASCCONV END ###")
+	return b.String()
+}
+
+// csaElement returns a CSA element of a VR with its values, each item
+// terminated by a NUL as syngo MR writes them, or without items when values
+// is empty.
+func csaElement(name, vr string, vm int32, values ...string) csa.Element {
+	elem := csa.Element{Name: name, VM: vm, VR: vr, SyngoDT: syngoDataTypes[vr]}
+	if len(values) > 0 {
+		elem.NumItems = int32(max(csaItems, len(values)))
+		for _, v := range values {
+			elem.Values = append(elem.Values, v+"\x00")
+		}
+	}
+	return elem
+}
+
+// csaPATMode returns the parallel imaging mode of an acquisition, "p" and
+// its acceleration, or no value without.
+func csaPATMode(p modalities.SeriesParams) []string {
+	if p.ParallelFactor <= 1 {
+		return nil
+	}
+	return []string{fmt.Sprintf("p%d", int(p.ParallelFactor))}
+}
+
+// csaFloat formats a decimal CSA value as syngo MR does, with 8 decimals.
+func csaFloat(v float64) string {
+	return fmt.Sprintf("%.8f", v)
+}
+
+// csaDwellTime returns the dwell time (ns) of the oversampled readout of img.
+func csaDwellTime(img vendorImage) int {
+	bandwidth := float64(csaBandwidth)
+	if isDiffusion(img.sequence) {
+		bandwidth = csaEPIBandwidth
+	}
+	return int(math.Round(1e9 / (bandwidth * float64(img.frequency*csaOversampling))))
+}
+
+// acquisitionSteps returns the frequency and phase encoding steps of the
+// AcquisitionMatrix of an MR image, 0 without.
+func acquisitionSteps(elements []*dicom.Element) (frequency, phase int) {
+	for _, elem := range elements {
+		if elem.Tag != tag.AcquisitionMatrix {
+			continue
+		}
+		if m, ok := elem.Value.GetValue().([]int); ok && len(m) == 4 {
+			return m[0] + m[1], m[2] + m[3]
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,118 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
+	"github.com/mrsinham/dicomforge/internal/dicom/csa"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestSiemensCSA(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "1MB",
+		Modality:    modalities.MR,
+		SiemensCSA:  true,
+		OutputDir:   t.TempDir(),
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 1,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := elementString(ds.Elements, tag.Manufacturer); got != "SIEMENS" {
+			t.Errorf("%s: Manufacturer = %q, want SIEMENS", f.Path, got)
+		}
+		if got := elementString(ds.Elements, tag.Tag{Group: 0x0029, Element: 0x0010}); got != "SIEMENS CSA HEADER" {
+			t.Errorf("%s: (0029,0010) = %q", f.Path, got)
+		}
+		image := csaHeader(t, ds, 0x1010)
+		series := csaHeader(t, ds, 0x1020)
+
+		// Slice normal of the orientation, slice number of the instance
+		orientation := make([]float64, 6)
+		if elem, err := ds.FindElementByTag(tag.ImageOrientationPatient); err == nil {
+			for i, v := range elem.Value.GetValue().([]string) {
+				orientation[i], _ = strconv.ParseFloat(v, 64)
+			}
+		}
+		normal := sliceNormal(orientation)
+		var want []string
+		for _, v := range normal {
+			want = append(want, csaFloat(v))
+		}
+		if got := image["SliceNormalVector"]; len(got) < 3 || fmt.Sprint(got[:3]) != fmt.Sprint(want) {
+			t.Errorf("%s: SliceNormalVector = %q, want %q", f.Path, got, want)
+		}
+		instance, _ := strconv.Atoi(elementString(ds.Elements, tag.InstanceNumber))
+		if got := image["ProtocolSliceNumber"]; len(got) == 0 || got[0] != strconv.Itoa(instance-1) {
+			t.Errorf("%s: ProtocolSliceNumber = %q, want %d", f.Path, got, instance-1)
+		}
+		if got := image["ImaCoilString"]; len(got) == 0 || got[0] != elementString(ds.Elements, tag.ReceiveCoilName) {
+			t.Errorf("%s: ImaCoilString = %q", f.Path, got)
+		}
+
+		// Protocol of the series
+		protocol := strings.Join(series["MrPhoenixProtocol"], "")
+		tr, _ := strconv.ParseFloat(elementString(ds.Elements, tag.RepetitionTime), 64)
+		for _, line := range []string{
+			"### ASCCONV BEGIN ###",
+			fmt.Sprintf(`%-40s = ""%s""`, "tProtocolName", elementString(ds.Elements, tag.ProtocolName)),
+			fmt.Sprintf("%-40s = %d", "alTR[0]", int(math.Round(tr*1000))),
+			"### ASCCONV END ###",
+		} {
+			if !strings.Contains(protocol, line) {
+				t.Errorf("%s: MrPhoenixProtocol missing %q:\n%s", f.Path, line, protocol)
+			}
+		}
+	}
+
+	for _, opts := range []GeneratorOptions{
+		{Modality: modalities.CT},
+		{CorruptionConfig: corruption.Config{Types: []corruption.CorruptionType{corruption.SiemensCSA}}},
+	} {
+		opts.NumImages, opts.TotalSize, opts.SiemensCSA = 1, "1MB", true
+		opts.OutputDir, opts.NumStudies, opts.NumPatients, opts.Quiet = t.TempDir(), 1, 1, true
+		if _, err := GenerateDICOMSeries(opts); err == nil {
+			t.Errorf("GenerateDICOMSeries(%s, %v) should return error", opts.Modality, opts.CorruptionConfig.Types)
+		}
+	}
+}
+
+// csaHeader returns the values of the CSA header in element (0029,element)
+// of ds, by name.
+func csaHeader(t *testing.T, ds dicom.Dataset, element uint16) map[string][]string {
+	t.Helper()
+	elem, err := ds.FindElementByTag(tag.Tag{Group: 0x0029, Element: element})
+	if err != nil {
+		t.Fatalf("(0029,%04X) missing", element)
+	}
+	data, ok := elem.Value.GetValue().([]byte)
+	if !ok {
+		t.Fatalf("(0029,%04X) is not binary: %v", element, elem.Value)
+	}
+	elements, err := csa.Decode(data)
+	if err != nil {
+		t.Fatalf("(0029,%04X): %v", element, err)
+	}
+	values := make(map[string][]string, len(elements))
+	for _, e := range elements {
+		values[e.Name] = e.Values
+	}
+	return values
+}
@@ -0,0 +1,221 @@
+package dicom
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// SplitOptions configures the splitting of studies into new studies.
+type SplitOptions struct {
+	// Groups lists the series numbers of each new study. Empty: one new study
+	// per series.
+	Groups [][]int
+
+	// Root of the new UIDs (util.DefaultUIDRoot when empty)
+	UIDRoot string
+}
+
+// SplitReport summarizes a split.
+type SplitReport struct {
+	Studies int // New studies written
+	Files   int // DICOM files written
+	Ignored int // Files not readable as DICOM, DICOMDIRs and files of series in no group
+}
+
+// studyFile is an input file of a split or a merge, with the attributes that
+// place it.
+type studyFile struct {
+	path         string
+	patientID    string
+	studyUID     string
+	seriesNumber int
+	instance     int
+}
+
+// SplitStudies writes, for every study of inputDir and every group of series
+// numbers, a new study holding the files of these series. The new studies get
+// new Study, Series, SOP Instance and Frame of Reference UIDs (every UID not
+// defined by the standard), derived from the originals and the group, so they
+// can be imported next to the original study. References to series outside
+// the group are left dangling. Groups matching no series of a study are
+// skipped. outputDir receives a PT*/ST*/SE*/IM* hierarchy and a DICOMDIR.
+func SplitStudies(inputDir, outputDir string, opts SplitOptions) (SplitReport, error) {
+	if opts.UIDRoot != "" {
+		if err := util.ValidateUIDRoot(opts.UIDRoot); err != nil {
+			return SplitReport{}, err
+		}
+	}
+	if err := checkOutputOutsideInput(inputDir, outputDir); err != nil {
+		return SplitReport{}, err
+	}
+
+	studyOrder, studies, ignored, err := collectStudyFiles(inputDir)
+	if err != nil {
+		return SplitReport{}, err
+	}
+	report := SplitReport{Ignored: ignored}
+
+	patientIdx := make(map[string]int)
+	patientStudies := make(map[string]int)
+	for _, studyUID := range studyOrder {
+		files := studies[studyUID]
+		groups := opts.Groups
+		if len(groups) == 0 {
+			groups = seriesNumbers(files)
+		}
+
+		grouped := 0
+		for _, group := range groups {
+			var selected []studyFile
+			for _, file := range files {
+				for _, n := range group {
+					if file.seriesNumber == n {
+						selected = append(selected, file)
+					}
+				}
+			}
+			if len(selected) == 0 {
+				continue
+			}
+			grouped += len(selected)
+
+			patientID := selected[0].patientID
+			if _, ok := patientIdx[patientID]; !ok {
+				patientIdx[patientID] = len(patientIdx)
+			}
+			studyDir := filepath.Join(outputDir, fmt.Sprintf("PT%06d", patientIdx[patientID]), fmt.Sprintf("ST%06d", patientStudies[patientID]))
+			patientStudies[patientID]++
+
+			if err := writeSplitStudy(studyDir, studyUID, group, selected, opts.UIDRoot); err != nil {
+				return report, err
+			}
+			report.Studies++
+			report.Files += len(selected)
+		}
+		report.Ignored += len(files) - grouped
+	}
+
+	if report.Studies == 0 {
+		return report, fmt.Errorf("no series of %s matches the requested groups", inputDir)
+	}
+	if err := createDICOMDIRFile(outputDir, filepath.Join(outputDir, "DICOMDIR"), opts.UIDRoot); err != nil {
+		return report, fmt.Errorf("create DICOMDIR: %w", err)
+	}
+	return report, nil
+}
+
+// collectStudyFiles groups the DICOM files of inputDir by study, studies in
+// order of first file. ignored counts the files not readable as DICOM and the
+// DICOMDIRs.
+func collectStudyFiles(inputDir string) (order []string, studies map[string][]studyFile, ignored int, err error) {
+	studies = make(map[string][]studyFile)
+	err = filepath.WalkDir(inputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		ds, err := dicom.ParseFile(path, nil, dicom.SkipPixelData())
+		if err != nil {
+			ignored++
+			return nil
+		}
+		if strings.TrimRight(getStringValue(ds, tag.MediaStorageSOPClassUID)[0], "\x00") == mediaStorageDirectoryStorage {
+			ignored++
+			return nil
+		}
+		file := studyFile{
+			path:      path,
+			patientID: getStringValue(ds, tag.PatientID)[0],
+			studyUID:  getStringValue(ds, tag.StudyInstanceUID)[0],
+		}
+		file.seriesNumber, _ = strconv.Atoi(getStringValue(ds, tag.SeriesNumber)[0])
+		file.instance, _ = strconv.Atoi(getStringValue(ds, tag.InstanceNumber)[0])
+		if _, ok := studies[file.studyUID]; !ok {
+			order = append(order, file.studyUID)
+		}
+		studies[file.studyUID] = append(studies[file.studyUID], file)
+		return nil
+	})
+	return order, studies, ignored, err
+}
+
+// seriesNumbers returns one group per series number of files, in ascending
+// order.
+func seriesNumbers(files []studyFile) [][]int {
+	seen := make(map[int]bool)
+	var numbers []int
+	for _, file := range files {
+		if !seen[file.seriesNumber] {
+			seen[file.seriesNumber] = true
+			numbers = append(numbers, file.seriesNumber)
+		}
+	}
+	sort.Ints(numbers)
+
+	groups := make([][]int, len(numbers))
+	for i, n := range numbers {
+		groups[i] = []int{n}
+	}
+	return groups
+}
+
+// writeSplitStudy writes files into studyDir, one SE* directory per series
+// in ascending series number, with the UIDs of the new study, under uidRoot.
+func writeSplitStudy(studyDir, studyUID string, group []int, files []studyFile, uidRoot string) error {
+	numbers := make([]string, len(group))
+	for i, n := range group {
+		numbers[i] = strconv.Itoa(n)
+	}
+	seed := fmt.Sprintf("split_%s_%s", studyUID, strings.Join(numbers, ","))
+	mapper := newUIDMapper(func(old string) string {
+		return util.GenerateDeterministicUIDWithRoot(uidRoot, seed+"_"+old)
+	})
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].seriesNumber != files[j].seriesNumber {
+			return files[i].seriesNumber < files[j].seriesNumber
+		}
+		return files[i].instance < files[j].instance
+	})
+	return writeStudyFiles(studyDir, files, mapper, nil)
+}
+
+// writeStudyFiles writes files, sorted by series, into studyDir: a new SE*
+// directory starts whenever the study or the series number changes. The UIDs
+// of each file are replaced by mapper, then edit, if any, gets the file and
+// the index of its series.
+func writeStudyFiles(studyDir string, files []studyFile, mapper *uidMapper, edit func(file studyFile, seriesIdx int, ds *dicom.Dataset)) error {
+	seriesIdx, imageIdx := -1, 0
+	for i, file := range files {
+		if i == 0 || file.seriesNumber != files[i-1].seriesNumber || file.studyUID != files[i-1].studyUID {
+			seriesIdx++
+			imageIdx = 0
+		}
+		imageIdx++
+		dest := filepath.Join(studyDir, fmt.Sprintf("SE%06d", seriesIdx), fmt.Sprintf("IM%06d", imageIdx))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+
+		ds, err := dicom.ParseFile(file.path, nil, dicom.SkipProcessingPixelDataValue())
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", file.path, err)
+		}
+		mapper.rewrite(ds.Elements)
+		if edit != nil {
+			edit(file, seriesIdx, &ds)
+		}
+		if err := writeDatasetToFile(dest, ds); err != nil {
+			return fmt.Errorf("write %s: %w", dest, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package dicom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeriesNumbers(t *testing.T) {
+	files := []studyFile{{seriesNumber: 3}, {seriesNumber: 1}, {seriesNumber: 3}, {seriesNumber: 2}}
+	want := [][]int{{1}, {2}, {3}}
+	if got := seriesNumbers(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("seriesNumbers = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package sr
+
+// AI result concepts
+var (
+	CodeObserverType      = Code{"121005", "DCM", "Observer Type"}
+	CodeDevice            = Code{"121007", "DCM", "Device"}
+	CodeDeviceObserverNm  = Code{"121013", "DCM", "Device Observer Name"}
+	CodeImagingProcedure  = Code{"363679005", "SCT", "Imaging"}
+	CodeFinding           = Code{"121071", "DCM", "Finding"}
+	CodeMass              = Code{"4147007", "SCT", "Mass"}
+	CodeAlgorithmName     = Code{"111001", "DCM", "Algorithm Name"}
+	CodeAlgorithmVersion  = Code{"111003", "DCM", "Algorithm Version"}
+	CodeImageRegion       = Code{"111030", "DCM", "Image Region"}
+	CodeLongAxis          = Code{"103339001", "SCT", "Long Axis"}
+	CodeCertaintyFinding  = Code{"111012", "DCM", "Certainty of Finding"}
+	CodeStandardDeviation = Code{"386136009", "SCT", "Standard Deviation"}
+	UnitPercent           = Code{"%", "UCUM", "%"}
+)
+
+// Finding is a lesion reported by a simulated AI algorithm.
+type Finding struct {
+	TrackingID  string   // Human-readable identifier (e.g., "Lesion 1")
+	TrackingUID string   // Unique identifier of the lesion across reports
+	Image       ImageRef // Image holding the largest cross-section
+	CenterX     float64  // Center column (pixels)
+	CenterY     float64  // Center row (pixels)
+	Radius      float64  // Radius (pixels)
+	DiameterMM  float64  // Long axis diameter (mm)
+	Confidence  float64  // Detection confidence (0-1)
+	Uncertainty float64  // Diameter standard deviation (mm)
+}
+
+// AIFindingsReport builds an Imaging Measurement Report (TID 1500) as produced
+// by an AI algorithm: a device observer, then one measurement group per
+// finding with its outline (SCOORD circle), long axis diameter with its
+// standard deviation, and the detection confidence as Certainty of Finding.
+func AIFindingsReport(algorithm, version string, findings []Finding) Item {
+	measurements := Container(Contains, CodeImagingMeasurements)
+	for _, f := range findings {
+		diameter := Num(Contains, CodeLongAxis, roundTenth(f.DiameterMM), UnitMillimeter,
+			Num(HasProperties, CodeStandardDeviation, roundTenth(f.Uncertainty), UnitMillimeter),
+			Image(InferredFrom, f.Image),
+		)
+		outline := SCoord(Contains, CodeImageRegion, "CIRCLE",
+			[]float64{f.CenterX, f.CenterY, f.CenterX + f.Radius, f.CenterY},
+			Image(SelectedFrom, f.Image),
+		)
+
+		measurements.Children = append(measurements.Children, Container(Contains, CodeMeasurementGroup,
+			Text(HasObsContext, CodeTrackingIdentifier, f.TrackingID),
+			UIDRef(HasObsContext, CodeTrackingUID, f.TrackingUID),
+			CodeItem(Contains, CodeFinding, CodeMass),
+			Text(HasConceptMod, CodeAlgorithmName, algorithm),
+			Text(HasConceptMod, CodeAlgorithmVersion, version),
+			outline,
+			diameter,
+			Num(Contains, CodeCertaintyFinding, roundTenth(f.Confidence*100), UnitPercent),
+		))
+	}
+
+	return Container("", CodeImagingMeasReport,
+		CodeItem(HasConceptMod, CodeLanguage, CodeEnglish),
+		CodeItem(HasObsContext, CodeObserverType, CodeDevice),
+		Text(HasObsContext, CodeDeviceObserverNm, algorithm),
+		CodeItem(HasConceptMod, CodeProcedureReported, CodeImagingProcedure),
+		measurements,
+	)
+}
@@ -0,0 +1,61 @@
+package sr
+
+import "testing"
+
+func TestAIFindingsReport(t *testing.T) {
+	image := ImageRef{"1.2", "1.2.3"}
+	findings := []Finding{
+		{TrackingID: "Lesion 1", TrackingUID: "1.2.9.1", Image: image, CenterX: 40, CenterY: 50, Radius: 6, DiameterMM: 8.4, Confidence: 0.873, Uncertainty: 1.26},
+		{TrackingID: "Lesion 2", TrackingUID: "1.2.9.2", Image: image, CenterX: 10, CenterY: 20, Radius: 3, DiameterMM: 4.2, Confidence: 0.6, Uncertainty: 0.5},
+	}
+
+	root := AIFindingsReport("ALGO", "2.0", findings)
+	if root.Concept != CodeImagingMeasReport {
+		t.Errorf("root concept = %v, want %v", root.Concept, CodeImagingMeasReport)
+	}
+	observer, ok := root.Find(CodeObserverType.Value)
+	if !ok || observer.Coded != CodeDevice {
+		t.Errorf("observer type = %v, want %v", observer.Coded, CodeDevice)
+	}
+
+	group, ok := root.Find(CodeImagingMeasurements.Value)
+	if !ok {
+		t.Fatal("missing Imaging Measurements container")
+	}
+	if len(group.Children) != len(findings) {
+		t.Fatalf("got %d measurement groups, want %d", len(group.Children), len(findings))
+	}
+
+	first := group.Children[0]
+	if id, _ := first.Find(CodeTrackingIdentifier.Value); id.Text != "Lesion 1" {
+		t.Errorf("tracking identifier = %q, want Lesion 1", id.Text)
+	}
+	certainty, ok := first.Find(CodeCertaintyFinding.Value)
+	if !ok || certainty.Numeric != 87.3 || certainty.Units != UnitPercent {
+		t.Errorf("certainty = %v %v, want 87.3 %%", certainty.Numeric, certainty.Units)
+	}
+
+	region, ok := first.Find(CodeImageRegion.Value)
+	if !ok {
+		t.Fatal("missing image region")
+	}
+	if region.GraphicType != "CIRCLE" || len(region.GraphicData) != 4 || region.GraphicData[2] != 46 {
+		t.Errorf("region = %s %v, want CIRCLE centered at (40,50) with radius 6", region.GraphicType, region.GraphicData)
+	}
+	if len(region.Children) != 1 || region.Children[0].Relationship != SelectedFrom || region.Children[0].Image != image {
+		t.Error("image region should be selected from the lesion image")
+	}
+
+	axis, ok := first.Find(CodeLongAxis.Value)
+	if !ok || axis.Numeric != 8.4 {
+		t.Fatalf("long axis = %v, want 8.4", axis.Numeric)
+	}
+	if sd, ok := axis.Find(CodeStandardDeviation.Value); !ok || sd.Numeric != 1.3 || sd.Relationship != HasProperties {
+		t.Errorf("standard deviation = %v (%s), want 1.3 HAS PROPERTIES", sd.Numeric, sd.Relationship)
+	}
+
+	// The tree must convert to elements without panicking
+	if len(root.Elements()) == 0 {
+		t.Error("no elements generated")
+	}
+}
@@ -0,0 +1,21 @@
+package sr
+
+// Units (UCUM)
+var (
+	UnitMillimeter = Code{"mm", "UCUM", "mm"}
+	UnitWeeks      = Code{"wk", "UCUM", "weeks"}
+)
+
+// Common concepts
+var (
+	CodeLanguage            = Code{"121049", "DCM", "Language of Content Item and Descendants"}
+	CodeEnglish             = Code{"en-US", "RFC5646", "English (United States)"}
+	CodeProcedureReported   = Code{"121058", "DCM", "Procedure reported"}
+	CodeFindingSite         = Code{"363698007", "SCT", "Finding Site"}
+	CodeLength              = Code{"410668003", "SCT", "Length"}
+	CodeImagingMeasurements = Code{"126010", "DCM", "Imaging Measurements"}
+	CodeMeasurementGroup    = Code{"125007", "DCM", "Measurement Group"}
+	CodeTrackingIdentifier  = Code{"112039", "DCM", "Tracking Identifier"}
+	CodeTrackingUID         = Code{"112040", "DCM", "Tracking Unique Identifier"}
+	CodeImagingMeasReport   = Code{"126000", "DCM", "Imaging Measurement Report"}
+)
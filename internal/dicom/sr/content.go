@@ -0,0 +1,213 @@
+// Package sr builds DICOM Structured Report (SR) content trees.
+//
+// A report is a tree of content items rooted at a CONTAINER. The tree is
+// converted to the elements of the SR Document Content module with Elements;
+// patient, study and series modules are added by the caller.
+package sr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// SR Storage SOP Class UIDs
+const (
+	BasicTextSRStorage         = "1.2.840.10008.5.1.4.1.1.88.11"
+	EnhancedSRStorage          = "1.2.840.10008.5.1.4.1.1.88.22"
+	ComprehensiveSRStorage     = "1.2.840.10008.5.1.4.1.1.88.33"
+	XRayRadiationDoseSRStorage = "1.2.840.10008.5.1.4.1.1.88.67"
+)
+
+// Relationship types between a content item and its parent
+const (
+	Contains      = "CONTAINS"
+	HasObsContext = "HAS OBS CONTEXT"
+	HasProperties = "HAS PROPERTIES"
+	HasConceptMod = "HAS CONCEPT MOD"
+	InferredFrom  = "INFERRED FROM"
+	SelectedFrom  = "SELECTED FROM"
+)
+
+// Value types of content items
+const (
+	ValueContainer = "CONTAINER"
+	ValueNum       = "NUM"
+	ValueCode      = "CODE"
+	ValueText      = "TEXT"
+	ValueImage     = "IMAGE"
+	ValueUIDRef    = "UIDREF"
+	ValueSCoord    = "SCOORD"
+	ValueDateTime  = "DATETIME"
+)
+
+// Code is a coded concept (Code Sequence Macro).
+type Code struct {
+	Value   string // CodeValue
+	Scheme  string // CodingSchemeDesignator (e.g., "DCM", "LN", "SCT", "UCUM")
+	Meaning string // CodeMeaning
+}
+
+// ImageRef references a composite instance by SOP Class and SOP Instance UID.
+type ImageRef struct {
+	SOPClassUID    string
+	SOPInstanceUID string
+}
+
+// Item is a node of an SR content tree.
+type Item struct {
+	Relationship string // Relationship with the parent (empty for the root)
+	ValueType    string
+	Concept      Code
+
+	Numeric float64 // NUM
+	Units   Code    // NUM
+	Coded   Code    // CODE
+	Text    string  // TEXT, UIDREF and DATETIME
+	Image   ImageRef
+
+	GraphicType string    // SCOORD (e.g., "POINT", "CIRCLE", "POLYLINE")
+	GraphicData []float64 // SCOORD column/row pairs
+
+	Children []Item
+}
+
+// Container creates a CONTAINER item.
+func Container(rel string, concept Code, children ...Item) Item {
+	return Item{Relationship: rel, ValueType: ValueContainer, Concept: concept, Children: children}
+}
+
+// Num creates a NUM item holding a measurement.
+func Num(rel string, concept Code, value float64, units Code, children ...Item) Item {
+	return Item{Relationship: rel, ValueType: ValueNum, Concept: concept, Numeric: value, Units: units, Children: children}
+}
+
+// CodeItem creates a CODE item.
+func CodeItem(rel string, concept, value Code) Item {
+	return Item{Relationship: rel, ValueType: ValueCode, Concept: concept, Coded: value}
+}
+
+// Text creates a TEXT item.
+func Text(rel string, concept Code, text string) Item {
+	return Item{Relationship: rel, ValueType: ValueText, Concept: concept, Text: text}
+}
+
+// UIDRef creates a UIDREF item.
+func UIDRef(rel string, concept Code, uid string) Item {
+	return Item{Relationship: rel, ValueType: ValueUIDRef, Concept: concept, Text: uid}
+}
+
+// DateTime creates a DATETIME item; value is YYYYMMDDHHMMSS.
+func DateTime(rel string, concept Code, value string) Item {
+	return Item{Relationship: rel, ValueType: ValueDateTime, Concept: concept, Text: value}
+}
+
+// SCoord creates a SCOORD item (spatial coordinates in image pixels). The image
+// the coordinates apply to is given as a SELECTED FROM child.
+func SCoord(rel string, concept Code, graphicType string, data []float64, children ...Item) Item {
+	return Item{Relationship: rel, ValueType: ValueSCoord, Concept: concept, GraphicType: graphicType, GraphicData: data, Children: children}
+}
+
+// Image creates an IMAGE item referencing an instance.
+func Image(rel string, ref ImageRef) Item {
+	return Item{Relationship: rel, ValueType: ValueImage, Image: ref}
+}
+
+// Elements returns the DICOM elements of the item and its subtree, sorted by tag.
+// For the root container these are the SR Document Content module attributes.
+func (it Item) Elements() []*dicom.Element {
+	var elems []*dicom.Element
+
+	if it.Relationship != "" {
+		elems = append(elems, mustNewElement(tag.RelationshipType, []string{it.Relationship}))
+	}
+	elems = append(elems, mustNewElement(tag.ValueType, []string{it.ValueType}))
+	if it.Concept.Value != "" {
+		elems = append(elems, CodeSequence(tag.ConceptNameCodeSequence, it.Concept))
+	}
+
+	switch it.ValueType {
+	case ValueContainer:
+		elems = append(elems, mustNewElement(tag.ContinuityOfContent, []string{"SEPARATE"}))
+	case ValueNum:
+		elems = append(elems, mustNewElement(tag.MeasuredValueSequence, [][]*dicom.Element{{
+			CodeSequence(tag.MeasurementUnitsCodeSequence, it.Units),
+			mustNewElement(tag.NumericValue, []string{util.FormatDS(it.Numeric)}),
+		}}))
+	case ValueCode:
+		elems = append(elems, CodeSequence(tag.ConceptCodeSequence, it.Coded))
+	case ValueText:
+		elems = append(elems, mustNewElement(tag.TextValue, []string{it.Text}))
+	case ValueUIDRef:
+		elems = append(elems, mustNewElement(tag.UID, []string{it.Text}))
+	case ValueDateTime:
+		elems = append(elems, mustNewElement(tag.DateTime, []string{it.Text}))
+	case ValueSCoord:
+		elems = append(elems,
+			mustNewElement(tag.GraphicData, it.GraphicData),
+			mustNewElement(tag.GraphicType, []string{it.GraphicType}),
+		)
+	case ValueImage:
+		elems = append(elems, mustNewElement(tag.ReferencedSOPSequence, [][]*dicom.Element{{
+			mustNewElement(tag.ReferencedSOPClassUID, []string{it.Image.SOPClassUID}),
+			mustNewElement(tag.ReferencedSOPInstanceUID, []string{it.Image.SOPInstanceUID}),
+		}}))
+	}
+
+	if len(it.Children) > 0 {
+		items := make([][]*dicom.Element, len(it.Children))
+		for i, child := range it.Children {
+			items[i] = child.Elements()
+		}
+		elems = append(elems, mustNewElement(tag.ContentSequence, items))
+	}
+
+	SortElements(elems)
+	return elems
+}
+
+// Find returns the first item of the subtree (depth-first, including the item
+// itself) whose concept has the given code value.
+func (it Item) Find(codeValue string) (Item, bool) {
+	if it.Concept.Value == codeValue {
+		return it, true
+	}
+	for _, child := range it.Children {
+		if found, ok := child.Find(codeValue); ok {
+			return found, true
+		}
+	}
+	return Item{}, false
+}
+
+// SortElements sorts elements in ascending tag order, as required within a
+// dataset or sequence item.
+func SortElements(elems []*dicom.Element) {
+	sort.SliceStable(elems, func(i, j int) bool {
+		if elems[i].Tag.Group != elems[j].Tag.Group {
+			return elems[i].Tag.Group < elems[j].Tag.Group
+		}
+		return elems[i].Tag.Element < elems[j].Tag.Element
+	})
+}
+
+// CodeSequence creates a single-item code sequence element.
+func CodeSequence(t tag.Tag, c Code) *dicom.Element {
+	return mustNewElement(t, [][]*dicom.Element{{
+		mustNewElement(tag.CodeValue, []string{c.Value}),
+		mustNewElement(tag.CodingSchemeDesignator, []string{c.Scheme}),
+		mustNewElement(tag.CodeMeaning, []string{c.Meaning}),
+	}})
+}
+
+// mustNewElement creates a new DICOM element, panicking on error.
+func mustNewElement(t tag.Tag, value any) *dicom.Element {
+	elem, err := dicom.NewElement(t, value)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create element %v: %v", t, err))
+	}
+	return elem
+}
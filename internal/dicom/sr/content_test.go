@@ -0,0 +1,109 @@
+package sr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestItemElements_Sorted(t *testing.T) {
+	item := Num(Contains, CodeLength, 42.5, UnitMillimeter,
+		Image(InferredFrom, ImageRef{SOPClassUID: "1.2.3", SOPInstanceUID: "1.2.3.4"}))
+
+	elems := item.Elements()
+	for i := 1; i < len(elems); i++ {
+		prev, cur := elems[i-1].Tag, elems[i].Tag
+		if prev.Group > cur.Group || (prev.Group == cur.Group && prev.Element >= cur.Element) {
+			t.Errorf("elements not in ascending order: %v before %v", prev, cur)
+		}
+	}
+}
+
+func TestItemElements_ValueTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want tag.Tag
+	}{
+		{"container", Container("", CodeImagingMeasReport), tag.ContinuityOfContent},
+		{"num", Num(Contains, CodeLength, 1, UnitMillimeter), tag.MeasuredValueSequence},
+		{"code", CodeItem(HasConceptMod, CodeFindingSite, CodeLiver), tag.ConceptCodeSequence},
+		{"text", Text(HasObsContext, CodeTrackingIdentifier, "lesion 1"), tag.TextValue},
+		{"uidref", UIDRef(HasObsContext, CodeTrackingUID, "1.2.3"), tag.UID},
+		{"datetime", DateTime(Contains, CodeStartOfIrradiation, "20240102103000"), tag.DateTime},
+		{"image", Image(InferredFrom, ImageRef{"1.2", "1.2.3"}), tag.ReferencedSOPSequence},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := dicom.Dataset{Elements: tt.item.Elements()}
+			if _, err := ds.FindElementByTag(tt.want); err != nil {
+				t.Errorf("missing %v for %s item", tt.want, tt.item.ValueType)
+			}
+			_, err := ds.FindElementByTag(tag.RelationshipType)
+			if hasRel := err == nil; hasRel != (tt.item.Relationship != "") {
+				t.Errorf("RelationshipType present = %v, want %v", hasRel, tt.item.Relationship != "")
+			}
+		})
+	}
+}
+
+func TestItemFind(t *testing.T) {
+	root := Container("", CodeImagingMeasReport,
+		Container(Contains, CodeImagingMeasurements,
+			Num(Contains, CodeLength, 12.3, UnitMillimeter)))
+
+	found, ok := root.Find(CodeLength.Value)
+	if !ok {
+		t.Fatal("expected to find Length item")
+	}
+	if found.Numeric != 12.3 {
+		t.Errorf("found value = %v, want 12.3", found.Numeric)
+	}
+	if _, ok := root.Find("does-not-exist"); ok {
+		t.Error("unexpected match for unknown code")
+	}
+}
+
+func TestDocumentElements_RoundTrip(t *testing.T) {
+	doc := Document{
+		Root:        Container("", CodeImagingMeasReport, Num(Contains, CodeLength, 10, UnitMillimeter)),
+		TemplateID:  "1500",
+		ContentDate: "20240101",
+		ContentTime: "120000",
+		Evidence: []Evidence{
+			{StudyUID: "1.2.1", SeriesUID: "1.2.1.1", Instances: []ImageRef{{"1.2", "1.2.1.1.1"}}},
+			{StudyUID: "1.2.1", SeriesUID: "1.2.1.2", Instances: []ImageRef{{"1.2", "1.2.1.2.1"}}},
+		},
+	}
+
+	elements := append([]*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustNewElement(tag.SOPClassUID, []string{ComprehensiveSRStorage}),
+		mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.4.5"}),
+	}, doc.Elements()...)
+	SortElements(elements)
+
+	var buf bytes.Buffer
+	if err := dicom.Write(&buf, dicom.Dataset{Elements: elements}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	parsed, err := dicom.Parse(&buf, int64(buf.Len()), nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	evidence, err := parsed.FindElementByTag(tag.CurrentRequestedProcedureEvidenceSequence)
+	if err != nil {
+		t.Fatalf("missing evidence sequence: %v", err)
+	}
+	studies := evidence.Value.GetValue().([]*dicom.SequenceItemValue)
+	if len(studies) != 1 {
+		t.Errorf("evidence should group series under 1 study, got %d", len(studies))
+	}
+	if _, err := parsed.FindElementByTag(tag.ContentTemplateSequence); err != nil {
+		t.Error("missing ContentTemplateSequence")
+	}
+}
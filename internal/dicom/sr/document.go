@@ -0,0 +1,82 @@
+package sr
+
+import (
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Evidence lists the instances of one series a report was derived from.
+type Evidence struct {
+	StudyUID  string
+	SeriesUID string
+	Instances []ImageRef
+}
+
+// Document holds the document-level attributes of an SR instance.
+type Document struct {
+	Root        Item       // Root CONTAINER of the content tree
+	TemplateID  string     // DCMR template identifier of the root (e.g., "5000"), empty if none
+	ContentDate string     // YYYYMMDD
+	ContentTime string     // HHMMSS
+	Evidence    []Evidence // Current Requested Procedure Evidence
+}
+
+// Elements returns the SR Document General and SR Document Content module
+// elements, sorted by tag.
+func (d Document) Elements() []*dicom.Element {
+	elems := []*dicom.Element{
+		mustNewElement(tag.ContentDate, []string{d.ContentDate}),
+		mustNewElement(tag.ContentTime, []string{d.ContentTime}),
+		mustNewElement(tag.CompletionFlag, []string{"COMPLETE"}),
+		mustNewElement(tag.VerificationFlag, []string{"UNVERIFIED"}),
+	}
+
+	if d.TemplateID != "" {
+		elems = append(elems, mustNewElement(tag.ContentTemplateSequence, [][]*dicom.Element{{
+			mustNewElement(tag.MappingResource, []string{"DCMR"}),
+			mustNewElement(tag.TemplateIdentifier, []string{d.TemplateID}),
+		}}))
+	}
+
+	if len(d.Evidence) > 0 {
+		elems = append(elems, evidenceSequence(tag.CurrentRequestedProcedureEvidenceSequence, d.Evidence))
+	}
+
+	elems = append(elems, d.Root.Elements()...)
+	SortElements(elems)
+	return elems
+}
+
+// evidenceSequence builds a Hierarchical SOP Instance Reference sequence,
+// grouping series under their study.
+func evidenceSequence(t tag.Tag, evidence []Evidence) *dicom.Element {
+	var studyOrder []string
+	seriesByStudy := make(map[string][][]*dicom.Element)
+
+	for _, ev := range evidence {
+		if _, ok := seriesByStudy[ev.StudyUID]; !ok {
+			studyOrder = append(studyOrder, ev.StudyUID)
+		}
+
+		sops := make([][]*dicom.Element, len(ev.Instances))
+		for i, ref := range ev.Instances {
+			sops[i] = []*dicom.Element{
+				mustNewElement(tag.ReferencedSOPClassUID, []string{ref.SOPClassUID}),
+				mustNewElement(tag.ReferencedSOPInstanceUID, []string{ref.SOPInstanceUID}),
+			}
+		}
+		seriesByStudy[ev.StudyUID] = append(seriesByStudy[ev.StudyUID], []*dicom.Element{
+			mustNewElement(tag.ReferencedSOPSequence, sops),
+			mustNewElement(tag.SeriesInstanceUID, []string{ev.SeriesUID}),
+		})
+	}
+
+	items := make([][]*dicom.Element, len(studyOrder))
+	for i, studyUID := range studyOrder {
+		items[i] = []*dicom.Element{
+			mustNewElement(tag.ReferencedSeriesSequence, seriesByStudy[studyUID]),
+			mustNewElement(tag.StudyInstanceUID, []string{studyUID}),
+		}
+	}
+	return mustNewElement(t, items)
+}
@@ -0,0 +1,200 @@
+package sr
+
+import "math"
+
+// CT radiation dose concepts (TID 10011 and its sub-templates)
+var (
+	CodeXRayDoseReport          = Code{"113701", "DCM", "X-Ray Radiation Dose Report"}
+	CodeComputedTomography      = Code{"77477000", "SCT", "Computed Tomography"}
+	CodeHasIntent               = Code{"363703001", "SCT", "Has Intent"}
+	CodeDiagnosticIntent        = Code{"261004008", "SCT", "Diagnostic Intent"}
+	CodeDeviceObserverUID       = Code{"121012", "DCM", "Device Observer UID"}
+	CodeDeviceManufacturer      = Code{"121014", "DCM", "Device Observer Manufacturer"}
+	CodeDeviceModelName         = Code{"121015", "DCM", "Device Observer Model Name"}
+	CodeStartOfIrradiation      = Code{"113809", "DCM", "Start of X-Ray Irradiation"}
+	CodeEndOfIrradiation        = Code{"113810", "DCM", "End of X-Ray Irradiation"}
+	CodeScopeOfAccumulation     = Code{"113705", "DCM", "Scope of Accumulation"}
+	CodeStudy                   = Code{"113014", "DCM", "Study"}
+	CodeStudyInstanceUID        = Code{"110180", "DCM", "Study Instance UID"}
+	CodeCTAccumulatedDose       = Code{"113811", "DCM", "CT Accumulated Dose Data"}
+	CodeTotalEvents             = Code{"113812", "DCM", "Total Number of Irradiation Events"}
+	CodeDLPTotal                = Code{"113813", "DCM", "CT Dose Length Product Total"}
+	CodeCTAcquisition           = Code{"113819", "DCM", "CT Acquisition"}
+	CodeAcquisitionProtocol     = Code{"125203", "DCM", "Acquisition Protocol"}
+	CodeTargetRegion            = Code{"123014", "DCM", "Target Region"}
+	CodeCTAcquisitionType       = Code{"113820", "DCM", "CT Acquisition Type"}
+	CodeSpiralAcquisition       = Code{"116152004", "SCT", "Spiral Acquisition"}
+	CodeConstantAngle           = Code{"113805", "DCM", "Constant Angle Acquisition"}
+	CodeIrradiationEventUID     = Code{"113769", "DCM", "Irradiation Event UID"}
+	CodeCTAcquisitionParams     = Code{"113822", "DCM", "CT Acquisition Parameters"}
+	CodeExposureTime            = Code{"113824", "DCM", "Exposure Time"}
+	CodeScanningLength          = Code{"113825", "DCM", "Scanning Length"}
+	CodeSingleCollimation       = Code{"113826", "DCM", "Nominal Single Collimation Width"}
+	CodeTotalCollimation        = Code{"113827", "DCM", "Nominal Total Collimation Width"}
+	CodePitchFactor             = Code{"113828", "DCM", "Pitch Factor"}
+	CodeNumberOfSources         = Code{"113823", "DCM", "Number of X-Ray Sources"}
+	CodeXRaySourceParams        = Code{"113831", "DCM", "CT X-Ray Source Parameters"}
+	CodeSourceIdentification    = Code{"113832", "DCM", "Identification of the X-Ray Source"}
+	CodeKVP                     = Code{"113733", "DCM", "KVP"}
+	CodeMaximumTubeCurrent      = Code{"113833", "DCM", "Maximum X-Ray Tube Current"}
+	CodeTubeCurrent             = Code{"113734", "DCM", "X-Ray Tube Current"}
+	CodeExposureTimePerRot      = Code{"113834", "DCM", "Exposure Time per Rotation"}
+	CodeCTDose                  = Code{"113829", "DCM", "CT Dose"}
+	CodeMeanCTDIvol             = Code{"113830", "DCM", "Mean CTDIvol"}
+	CodeCTDIwPhantomType        = Code{"113835", "DCM", "CTDIw Phantom Type"}
+	CodeHeadPhantom             = Code{"113690", "DCM", "IEC Head Dosimetry Phantom"}
+	CodeBodyPhantom             = Code{"113691", "DCM", "IEC Body Dosimetry Phantom"}
+	CodeDLP                     = Code{"113838", "DCM", "DLP"}
+	CodeSourceOfDoseInfo        = Code{"113854", "DCM", "Source of Dose Information"}
+	CodeAutomatedDataCollection = Code{"113856", "DCM", "Automated Data Collection"}
+
+	UnitSecond  = Code{"s", "UCUM", "s"}
+	UnitKV      = Code{"kV", "UCUM", "kV"}
+	UnitMA      = Code{"mA", "UCUM", "mA"}
+	UnitMGy     = Code{"mGy", "UCUM", "mGy"}
+	UnitMGyCm   = Code{"mGy.cm", "UCUM", "mGy.cm"}
+	UnitRatio   = Code{"{ratio}", "UCUM", "ratio"}
+	UnitEvents  = Code{"{events}", "UCUM", "events"}
+	UnitSources = Code{"{X-Ray sources}", "UCUM", "X-Ray sources"}
+)
+
+// Target regions of CT acquisitions, by body part
+var ctTargetRegions = map[string]Code{
+	"HEAD":      {"69536005", "SCT", "Head"},
+	"CHEST":     {"43799004", "SCT", "Chest"},
+	"ABDOMEN":   {"818981001", "SCT", "Abdomen"},
+	"PELVIS":    {"816092008", "SCT", "Pelvis"},
+	"CSPINE":    {"122494005", "SCT", "Cervical spine"},
+	"TSPINE":    {"122495006", "SCT", "Thoracic spine"},
+	"LSPINE":    {"122496007", "SCT", "Lumbar spine"},
+	"EXTREMITY": {"66019005", "SCT", "Extremity"},
+}
+
+// CTTargetRegion returns the Target Region code of a body part, Chest when
+// the body part is unknown.
+func CTTargetRegion(bodyPart string) Code {
+	if code, ok := ctTargetRegions[bodyPart]; ok {
+		return code
+	}
+	return ctTargetRegions["CHEST"]
+}
+
+// CTIrradiationEvent is one CT acquisition of a dose report.
+type CTIrradiationEvent struct {
+	UID          string
+	Protocol     string
+	TargetRegion Code
+	Localizer    bool // Constant angle acquisition (scout); spiral otherwise
+
+	ExposureTime      float64 // s
+	ScanningLength    float64 // mm
+	SingleCollimation float64 // mm
+	TotalCollimation  float64 // mm
+	Pitch             float64 // Spiral acquisitions only
+
+	KVP                float64 // kV
+	TubeCurrent        float64 // mA
+	MaximumTubeCurrent float64 // mA
+	RotationTime       float64 // s, spiral acquisitions only
+
+	CTDIvol     float64 // mGy
+	DLP         float64 // mGy.cm
+	HeadPhantom bool    // CTDIvol measured in the 16 cm head phantom
+}
+
+// CTDevice identifies the CT scanner reporting the dose.
+type CTDevice struct {
+	UID          string
+	Manufacturer string
+	Model        string
+}
+
+// CTDoseReport builds an X-Ray Radiation Dose Report for CT (TID 10011): the
+// device observer, the irradiation time span, the study scope, the
+// accumulated DLP, then one CT Acquisition container per irradiation event.
+// start and end are YYYYMMDDHHMMSS.
+func CTDoseReport(device CTDevice, studyUID, start, end string, events []CTIrradiationEvent) Item {
+	var dlpTotal float64
+	for _, e := range events {
+		dlpTotal += e.DLP
+	}
+
+	root := Container("", CodeXRayDoseReport,
+		CodeItem(HasConceptMod, CodeLanguage, CodeEnglish),
+		CodeItem(HasConceptMod, CodeProcedureReported, CodeComputedTomography),
+		CodeItem(HasConceptMod, CodeHasIntent, CodeDiagnosticIntent),
+		CodeItem(HasObsContext, CodeObserverType, CodeDevice),
+		UIDRef(HasObsContext, CodeDeviceObserverUID, device.UID),
+		Text(HasObsContext, CodeDeviceManufacturer, device.Manufacturer),
+		Text(HasObsContext, CodeDeviceModelName, device.Model),
+		DateTime(HasObsContext, CodeStartOfIrradiation, start),
+		DateTime(HasObsContext, CodeEndOfIrradiation, end),
+		Item{
+			Relationship: HasObsContext, ValueType: ValueCode, Concept: CodeScopeOfAccumulation, Coded: CodeStudy,
+			Children: []Item{UIDRef(HasProperties, CodeStudyInstanceUID, studyUID)},
+		},
+		Container(Contains, CodeCTAccumulatedDose,
+			Num(Contains, CodeTotalEvents, float64(len(events)), UnitEvents),
+			Num(Contains, CodeDLPTotal, roundHundredth(dlpTotal), UnitMGyCm),
+		),
+	)
+	for _, e := range events {
+		root.Children = append(root.Children, ctAcquisition(e))
+	}
+	root.Children = append(root.Children, CodeItem(Contains, CodeSourceOfDoseInfo, CodeAutomatedDataCollection))
+	return root
+}
+
+// ctAcquisition builds the CT Acquisition container of an irradiation event
+// (TID 10013).
+func ctAcquisition(e CTIrradiationEvent) Item {
+	acquisitionType := CodeSpiralAcquisition
+	if e.Localizer {
+		acquisitionType = CodeConstantAngle
+	}
+	phantom := CodeBodyPhantom
+	if e.HeadPhantom {
+		phantom = CodeHeadPhantom
+	}
+
+	params := Container(Contains, CodeCTAcquisitionParams,
+		Num(Contains, CodeExposureTime, roundHundredth(e.ExposureTime), UnitSecond),
+		Num(Contains, CodeScanningLength, roundTenth(e.ScanningLength), UnitMillimeter),
+		Num(Contains, CodeSingleCollimation, e.SingleCollimation, UnitMillimeter),
+		Num(Contains, CodeTotalCollimation, e.TotalCollimation, UnitMillimeter),
+	)
+	if !e.Localizer {
+		params.Children = append(params.Children, Num(Contains, CodePitchFactor, e.Pitch, UnitRatio))
+	}
+	source := Container(Contains, CodeXRaySourceParams,
+		Text(Contains, CodeSourceIdentification, "A"),
+		Num(Contains, CodeKVP, e.KVP, UnitKV),
+		Num(Contains, CodeMaximumTubeCurrent, e.MaximumTubeCurrent, UnitMA),
+		Num(Contains, CodeTubeCurrent, e.TubeCurrent, UnitMA),
+	)
+	if !e.Localizer {
+		source.Children = append(source.Children, Num(Contains, CodeExposureTimePerRot, e.RotationTime, UnitSecond))
+	}
+	params.Children = append(params.Children,
+		Num(Contains, CodeNumberOfSources, 1, UnitSources),
+		source,
+	)
+
+	return Container(Contains, CodeCTAcquisition,
+		Text(Contains, CodeAcquisitionProtocol, e.Protocol),
+		CodeItem(Contains, CodeTargetRegion, e.TargetRegion),
+		CodeItem(Contains, CodeCTAcquisitionType, acquisitionType),
+		UIDRef(Contains, CodeIrradiationEventUID, e.UID),
+		params,
+		Container(Contains, CodeCTDose,
+			Num(Contains, CodeMeanCTDIvol, roundHundredth(e.CTDIvol), UnitMGy),
+			CodeItem(Contains, CodeCTDIwPhantomType, phantom),
+			Num(Contains, CodeDLP, roundHundredth(e.DLP), UnitMGyCm),
+		),
+	)
+}
+
+// roundHundredth rounds to two decimals, the precision of dose values.
+func roundHundredth(v float64) float64 {
+	return math.Round(v*100) / 100
+}
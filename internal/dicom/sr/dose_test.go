@@ -0,0 +1,75 @@
+package sr
+
+import "testing"
+
+func TestCTDoseReport(t *testing.T) {
+	events := []CTIrradiationEvent{
+		{UID: "1.2.1", Protocol: "Topogram", TargetRegion: CTTargetRegion("CHEST"), Localizer: true,
+			ExposureTime: 3.4, ScanningLength: 400, SingleCollimation: 0.6, TotalCollimation: 0.6,
+			KVP: 120, TubeCurrent: 35, MaximumTubeCurrent: 35, CTDIvol: 0.12, DLP: 4.8},
+		{UID: "1.2.2", Protocol: "Thorax", TargetRegion: CTTargetRegion("CHEST"),
+			ExposureTime: 4.567, ScanningLength: 300, SingleCollimation: 0.6, TotalCollimation: 38.4, Pitch: 1.2,
+			KVP: 120, TubeCurrent: 200, MaximumTubeCurrent: 260, RotationTime: 0.5, CTDIvol: 8.123, DLP: 243.69},
+	}
+
+	root := CTDoseReport(CTDevice{UID: "1.2.9", Manufacturer: "SIEMENS", Model: "SOMATOM Force"}, "1.2.3", "20240102103000", "20240102103130", events)
+	if root.Concept != CodeXRayDoseReport {
+		t.Errorf("root concept = %v, want %v", root.Concept, CodeXRayDoseReport)
+	}
+	if total, _ := root.Find(CodeTotalEvents.Value); total.Numeric != 2 {
+		t.Errorf("total events = %v, want 2", total.Numeric)
+	}
+	if total, _ := root.Find(CodeDLPTotal.Value); total.Numeric != 248.49 || total.Units != UnitMGyCm {
+		t.Errorf("DLP total = %v %v, want 248.49 mGy.cm", total.Numeric, total.Units)
+	}
+	scope, ok := root.Find(CodeScopeOfAccumulation.Value)
+	if !ok || scope.Coded != CodeStudy || len(scope.Children) != 1 || scope.Children[0].Text != "1.2.3" {
+		t.Errorf("scope of accumulation = %+v, want the study", scope)
+	}
+
+	var acquisitions []Item
+	for _, child := range root.Children {
+		if child.Concept == CodeCTAcquisition {
+			acquisitions = append(acquisitions, child)
+		}
+	}
+	if len(acquisitions) != len(events) {
+		t.Fatalf("got %d CT acquisitions, want %d", len(acquisitions), len(events))
+	}
+
+	scout := acquisitions[0]
+	if kind, _ := scout.Find(CodeCTAcquisitionType.Value); kind.Coded != CodeConstantAngle {
+		t.Errorf("localizer acquisition type = %v", kind.Coded)
+	}
+	if _, ok := scout.Find(CodePitchFactor.Value); ok {
+		t.Error("localizer should have no pitch factor")
+	}
+
+	spiral := acquisitions[1]
+	if kind, _ := spiral.Find(CodeCTAcquisitionType.Value); kind.Coded != CodeSpiralAcquisition {
+		t.Errorf("spiral acquisition type = %v", kind.Coded)
+	}
+	if ctdi, _ := spiral.Find(CodeMeanCTDIvol.Value); ctdi.Numeric != 8.12 || ctdi.Units != UnitMGy {
+		t.Errorf("CTDIvol = %v %v, want 8.12 mGy", ctdi.Numeric, ctdi.Units)
+	}
+	if phantom, _ := spiral.Find(CodeCTDIwPhantomType.Value); phantom.Coded != CodeBodyPhantom {
+		t.Errorf("phantom = %v, want body phantom", phantom.Coded)
+	}
+	if uid, _ := spiral.Find(CodeIrradiationEventUID.Value); uid.Text != "1.2.2" {
+		t.Errorf("irradiation event UID = %q, want 1.2.2", uid.Text)
+	}
+
+	last := root.Children[len(root.Children)-1]
+	if last.Concept != CodeSourceOfDoseInfo || last.Coded != CodeAutomatedDataCollection {
+		t.Errorf("last item = %v, want source of dose information", last.Concept)
+	}
+}
+
+func TestCTTargetRegion(t *testing.T) {
+	if got := CTTargetRegion("HEAD"); got.Meaning != "Head" {
+		t.Errorf("CTTargetRegion(HEAD) = %v", got)
+	}
+	if got := CTTargetRegion("UNKNOWN"); got != CTTargetRegion("CHEST") {
+		t.Errorf("CTTargetRegion(UNKNOWN) = %v, want Chest", got)
+	}
+}
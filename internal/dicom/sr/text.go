@@ -0,0 +1,61 @@
+package sr
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// Basic diagnostic imaging report concepts (TID 2000)
+var (
+	CodeDiagnosticImagingReport = Code{"18748-4", "LN", "Diagnostic Imaging Report"}
+	CodeFindings                = Code{"121070", "DCM", "Findings"}
+	CodeImpressions             = Code{"121072", "DCM", "Impressions"}
+	CodeImpression              = Code{"121073", "DCM", "Impression"}
+)
+
+// Report sentences, with %s replaced by the examined body part
+var (
+	textFindings = []string{
+		"No acute abnormality of the %s.",
+		"The %s appears within normal limits.",
+		"No focal lesion is identified in the %s.",
+		"Mild degenerative changes of the %s, without acute findings.",
+		"Small nonspecific focus in the %s, likely benign.",
+		"Postoperative changes of the %s, stable compared with prior.",
+	}
+	textImpressions = []string{
+		"No acute findings.",
+		"Normal examination.",
+		"No significant change compared with prior examination.",
+		"Findings likely benign. Follow-up as clinically indicated.",
+		"Correlation with clinical history is recommended.",
+	}
+)
+
+// TextReport builds a free-text diagnostic imaging report (TID 2000) that can
+// be stored as a Basic Text SR: a Findings section of 1-3 sentences, each
+// INFERRED FROM one of the referenced images, and an Impressions section.
+func TextReport(bodyPart string, refs []ImageRef, rng *rand.Rand) Item {
+	site := strings.ToLower(bodyPart)
+	if site == "" {
+		site = "examined region"
+	}
+
+	count := 1 + rng.IntN(3)
+	findings := Container(Contains, CodeFindings)
+	for i, n := range rng.Perm(len(textFindings))[:count] {
+		sentence := strings.Replace(textFindings[n], "%s", site, 1)
+		finding := Text(Contains, CodeFinding, sentence)
+		finding.Children = imageEvidence(refs, i, count)
+		findings.Children = append(findings.Children, finding)
+	}
+
+	impressions := Container(Contains, CodeImpressions,
+		Text(Contains, CodeImpression, textImpressions[rng.IntN(len(textImpressions))]))
+
+	return Container("", CodeDiagnosticImagingReport,
+		CodeItem(HasConceptMod, CodeLanguage, CodeEnglish),
+		findings,
+		impressions,
+	)
+}
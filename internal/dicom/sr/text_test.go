@@ -0,0 +1,65 @@
+package sr
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// basicTextValueTypes are the value types allowed in a Basic Text SR
+var basicTextValueTypes = map[string]bool{
+	ValueContainer: true, ValueText: true, ValueCode: true, ValueUIDRef: true, ValueImage: true,
+}
+
+func TestTextReport(t *testing.T) {
+	refs := []ImageRef{{"1.2", "1.2.1"}, {"1.2", "1.2.2"}, {"1.2", "1.2.3"}}
+	root := TextReport("CHEST", refs, rand.New(rand.NewPCG(1, 2)))
+
+	if root.Concept != CodeDiagnosticImagingReport {
+		t.Errorf("root concept = %v, want %v", root.Concept, CodeDiagnosticImagingReport)
+	}
+
+	findings, ok := root.Find(CodeFindings.Value)
+	if !ok {
+		t.Fatal("missing Findings section")
+	}
+	if n := len(findings.Children); n < 1 || n > 3 {
+		t.Fatalf("got %d findings, want 1-3", n)
+	}
+	for _, finding := range findings.Children {
+		if finding.ValueType != ValueText || !strings.Contains(finding.Text, "chest") {
+			t.Errorf("finding = %s %q, want TEXT about the chest", finding.ValueType, finding.Text)
+		}
+		if len(finding.Children) != 1 || finding.Children[0].Relationship != InferredFrom || finding.Children[0].ValueType != ValueImage {
+			t.Error("finding should be inferred from an image")
+		}
+	}
+
+	if impression, ok := root.Find(CodeImpression.Value); !ok || impression.Text == "" {
+		t.Error("missing impression")
+	}
+
+	var check func(it Item)
+	check = func(it Item) {
+		if !basicTextValueTypes[it.ValueType] {
+			t.Errorf("value type %s is not allowed in a Basic Text SR", it.ValueType)
+		}
+		for _, child := range it.Children {
+			check(child)
+		}
+	}
+	check(root)
+}
+
+func TestTextReport_NoImages(t *testing.T) {
+	root := TextReport("", nil, rand.New(rand.NewPCG(1, 2)))
+	findings, _ := root.Find(CodeFindings.Value)
+	for _, finding := range findings.Children {
+		if len(finding.Children) != 0 {
+			t.Error("finding without images should have no evidence")
+		}
+		if !strings.Contains(finding.Text, "examined region") {
+			t.Errorf("finding = %q, want the generic region", finding.Text)
+		}
+	}
+}
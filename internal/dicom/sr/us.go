@@ -0,0 +1,130 @@
+package sr
+
+import (
+	"math/rand/v2"
+)
+
+// OB-GYN concepts (TID 5000)
+var (
+	CodeOBGYNReport       = Code{"125000", "DCM", "OB-GYN Ultrasound Procedure Report"}
+	CodeFetalBiometry     = Code{"125002", "DCM", "Fetal Biometry"}
+	CodeBiparietal        = Code{"11820-8", "LN", "Biparietal Diameter"}
+	CodeHeadCircumference = Code{"11984-2", "LN", "Head Circumference"}
+	CodeAbdominalCircum   = Code{"11979-2", "LN", "Abdominal Circumference"}
+	CodeFemurLength       = Code{"11963-6", "LN", "Femur Length"}
+	CodeGestationalAge    = Code{"18185-9", "LN", "Gestational Age"}
+	CodeOBUltrasound      = Code{"268445003", "SCT", "Obstetric ultrasonography"}
+	CodeAbdominalUS       = Code{"45036003", "SCT", "Ultrasonography of abdomen"}
+)
+
+// Anatomy concepts used as finding sites
+var (
+	CodeLiver   = Code{"10200004", "SCT", "Liver"}
+	CodeSpleen  = Code{"78961009", "SCT", "Spleen"}
+	CodeKidney  = Code{"64033007", "SCT", "Kidney"}
+	CodeThyroid = Code{"69748006", "SCT", "Thyroid"}
+)
+
+// organMeasurement describes a plausible organ length range in millimeters.
+type organMeasurement struct {
+	site     Code
+	min, max float64
+}
+
+var organMeasurements = map[string][]organMeasurement{
+	"ABDOMEN": {{CodeLiver, 110, 165}, {CodeSpleen, 80, 120}, {CodeKidney, 95, 120}},
+	"LIVER":   {{CodeLiver, 110, 165}},
+	"KIDNEY":  {{CodeKidney, 95, 120}},
+	"THYROID": {{CodeThyroid, 40, 60}},
+}
+
+// IsOBBodyPart reports whether US studies of the body part get an obstetric report.
+func IsOBBodyPart(bodyPart string) bool {
+	return bodyPart == "PELVIS" || bodyPart == "UTERUS"
+}
+
+// USMeasurementReport builds a US measurement report for the body part and
+// returns its root container with the DCMR template it follows.
+// PELVIS and UTERUS get an OB-GYN fetal biometry report (TID 5000: BPD, HC, AC,
+// FL and the gestational age they were derived from); other body parts get
+// organ lengths (e.g., liver span) in an Imaging Measurement Report (TID 1500).
+// Every measurement is INFERRED FROM one of the referenced images.
+func USMeasurementReport(bodyPart string, refs []ImageRef, rng *rand.Rand) (Item, string) {
+	if IsOBBodyPart(bodyPart) {
+		return obReport(refs, rng), "5000"
+	}
+	return organReport(bodyPart, refs, rng), "1500"
+}
+
+// obReport builds fetal biometry consistent with a random gestational age.
+func obReport(refs []ImageRef, rng *rand.Rand) Item {
+	ga := 16 + rng.Float64()*22 // 16-38 weeks
+
+	// Linear approximations of the Hadlock growth curves, with +/-3% scatter
+	jitter := func(v float64) float64 {
+		return roundTenth(v * (0.97 + rng.Float64()*0.06))
+	}
+	measurements := []struct {
+		concept Code
+		value   float64
+	}{
+		{CodeBiparietal, jitter(2.6*ga - 3)},
+		{CodeHeadCircumference, jitter(9.2*ga - 8)},
+		{CodeAbdominalCircum, jitter(10*ga - 45)},
+		{CodeFemurLength, jitter(2.4*ga - 15)},
+	}
+
+	biometry := Container(Contains, CodeFetalBiometry)
+	for i, m := range measurements {
+		biometry.Children = append(biometry.Children,
+			Num(Contains, m.concept, m.value, UnitMillimeter, imageEvidence(refs, i, len(measurements))...))
+	}
+	biometry.Children = append(biometry.Children,
+		Num(Contains, CodeGestationalAge, roundTenth(ga), UnitWeeks))
+
+	return Container("", CodeOBGYNReport,
+		CodeItem(HasConceptMod, CodeLanguage, CodeEnglish),
+		CodeItem(HasConceptMod, CodeProcedureReported, CodeOBUltrasound),
+		biometry,
+	)
+}
+
+// organReport builds one measurement group per organ length.
+func organReport(bodyPart string, refs []ImageRef, rng *rand.Rand) Item {
+	organs, ok := organMeasurements[bodyPart]
+	if !ok {
+		organs = organMeasurements["ABDOMEN"]
+	}
+
+	measurements := Container(Contains, CodeImagingMeasurements)
+	for i, organ := range organs {
+		value := roundTenth(organ.min + rng.Float64()*(organ.max-organ.min))
+		length := Num(Contains, CodeLength, value, UnitMillimeter, imageEvidence(refs, i, len(organs))...)
+		length.Children = append([]Item{CodeItem(HasConceptMod, CodeFindingSite, organ.site)}, length.Children...)
+
+		measurements.Children = append(measurements.Children, Container(Contains, CodeMeasurementGroup,
+			Text(HasObsContext, CodeTrackingIdentifier, organ.site.Meaning),
+			length,
+		))
+	}
+
+	return Container("", CodeImagingMeasReport,
+		CodeItem(HasConceptMod, CodeLanguage, CodeEnglish),
+		CodeItem(HasConceptMod, CodeProcedureReported, CodeAbdominalUS),
+		measurements,
+	)
+}
+
+// imageEvidence returns the INFERRED FROM image item for measurement i of n,
+// spreading measurements over the referenced images.
+func imageEvidence(refs []ImageRef, i, n int) []Item {
+	if len(refs) == 0 {
+		return nil
+	}
+	return []Item{Image(InferredFrom, refs[i*len(refs)/n])}
+}
+
+// roundTenth rounds to one decimal, the precision of caliper measurements.
+func roundTenth(v float64) float64 {
+	return float64(int(v*10+0.5)) / 10
+}
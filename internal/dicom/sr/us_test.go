@@ -0,0 +1,70 @@
+package sr
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestUSMeasurementReport_OB(t *testing.T) {
+	refs := []ImageRef{{"1.2", "1.2.1"}, {"1.2", "1.2.2"}}
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	root, templateID := USMeasurementReport("PELVIS", refs, rng)
+	if templateID != "5000" {
+		t.Errorf("templateID = %s, want 5000", templateID)
+	}
+	if root.Concept != CodeOBGYNReport {
+		t.Errorf("root concept = %v, want %v", root.Concept, CodeOBGYNReport)
+	}
+
+	bpd, ok := root.Find(CodeBiparietal.Value)
+	if !ok {
+		t.Fatal("missing BPD measurement")
+	}
+	fl, ok := root.Find(CodeFemurLength.Value)
+	if !ok {
+		t.Fatal("missing femur length measurement")
+	}
+	// Between 16 and 38 weeks, BPD is always larger than femur length
+	if bpd.Numeric <= fl.Numeric {
+		t.Errorf("BPD %v should exceed FL %v", bpd.Numeric, fl.Numeric)
+	}
+	if len(bpd.Children) != 1 || bpd.Children[0].ValueType != ValueImage {
+		t.Error("BPD should be inferred from an image")
+	}
+}
+
+func TestUSMeasurementReport_Organs(t *testing.T) {
+	refs := []ImageRef{{"1.2", "1.2.1"}}
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	root, templateID := USMeasurementReport("LIVER", refs, rng)
+	if templateID != "1500" {
+		t.Errorf("templateID = %s, want 1500", templateID)
+	}
+
+	length, ok := root.Find(CodeLength.Value)
+	if !ok {
+		t.Fatal("missing length measurement")
+	}
+	if length.Numeric < 110 || length.Numeric > 165 {
+		t.Errorf("liver span %v out of range 110-165", length.Numeric)
+	}
+	site, ok := length.Find(CodeFindingSite.Value)
+	if !ok || site.Coded != CodeLiver {
+		t.Errorf("finding site = %v, want %v", site.Coded, CodeLiver)
+	}
+}
+
+func TestUSMeasurementReport_UnknownBodyPartUsesAbdomen(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+
+	root, _ := USMeasurementReport("BREAST", nil, rng)
+	group, ok := root.Find(CodeImagingMeasurements.Value)
+	if !ok {
+		t.Fatal("missing Imaging Measurements container")
+	}
+	if got, want := len(group.Children), len(organMeasurements["ABDOMEN"]); got != want {
+		t.Errorf("got %d measurement groups, want %d", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package dicom
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+)
+
+// InstanceWriter receives the instances of a streamed generation, one at a
+// time: the generated file and its content, to read before returning.
+type InstanceWriter interface {
+	WriteInstance(f GeneratedFile, r io.Reader) error
+}
+
+// InstanceWriterFunc adapts a function to an InstanceWriter.
+type InstanceWriterFunc func(f GeneratedFile, r io.Reader) error
+
+// WriteInstance calls fn(f, r).
+func (fn InstanceWriterFunc) WriteInstance(f GeneratedFile, r io.Reader) error {
+	return fn(f, r)
+}
+
+// MultipartWriter writes instances as the application/dicom parts of a
+// multipart/related stream, the body of a STOW-RS request (PS3.18 10.5) or
+// of a WADO-RS response.
+type MultipartWriter struct {
+	mw *multipart.Writer
+}
+
+// NewMultipartWriter returns a MultipartWriter writing to w, with a random
+// boundary.
+func NewMultipartWriter(w io.Writer) *MultipartWriter {
+	return &MultipartWriter{mw: multipart.NewWriter(w)}
+}
+
+// SetBoundary replaces the random boundary, so that an uploader can be
+// given the Content-Type before the stream starts. It must be called before
+// the first instance is written.
+func (m *MultipartWriter) SetBoundary(boundary string) error {
+	return m.mw.SetBoundary(boundary)
+}
+
+// ContentType returns the media type of the stream, with its boundary, as
+// an uploader sends it in its Content-Type header.
+func (m *MultipartWriter) ContentType() string {
+	return mime.FormatMediaType("multipart/related", map[string]string{
+		"type":     "application/dicom",
+		"boundary": m.mw.Boundary(),
+	})
+}
+
+// WriteInstance writes an instance as an application/dicom part.
+func (m *MultipartWriter) WriteInstance(f GeneratedFile, r io.Reader) error {
+	part, err := m.mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/dicom"}})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return fmt.Errorf("write instance %s: %w", f.SOPInstanceUID, err)
+	}
+	return nil
+}
+
+// Close writes the closing boundary of the stream. It does not close the
+// underlying writer.
+func (m *MultipartWriter) Close() error {
+	return m.mw.Close()
+}
+
+// StreamDICOMSeries generates the instances of opts as GenerateDICOMSeries
+// does, then writes each one to w in generation order: images, then derived
+// objects. The files are staged in a temporary directory, removed once
+// written, so nothing is left on disk. opts.OutputDir only names the run: it
+// seeds the UIDs (and the seed when unset) as it would on disk, so that a
+// stream and a directory of the same name hold the same instances. It
+// returns the instances written, whose Path is their staged file name.
+func StreamDICOMSeries(opts GeneratorOptions, w InstanceWriter) ([]GeneratedFile, error) {
+	stagingDir, err := os.MkdirTemp("", "dicomforge-stream-")
+	if err != nil {
+		return nil, fmt.Errorf("create staging directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	opts.FilesDir = stagingDir
+	files, err := GenerateDICOMSeries(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		if err := streamFile(w, files[i]); err != nil {
+			return nil, err
+		}
+		files[i].Path = filepath.Base(files[i].Path)
+	}
+	return files, nil
+}
+
+// streamFile writes a staged file to w and removes it.
+func streamFile(w InstanceWriter, f GeneratedFile) error {
+	in, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	err = w.WriteInstance(f, in)
+	_ = in.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(f.Path)
+}
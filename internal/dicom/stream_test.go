@@ -0,0 +1,98 @@
+package dicom
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamDICOMSeries(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "run")
+	opts := GeneratorOptions{
+		NumImages:  4,
+		TotalSize:  "1MB",
+		OutputDir:  outputDir,
+		Seed:       42,
+		NumStudies: 2,
+		TextSR:     true,
+		Quiet:      true,
+	}
+
+	var body bytes.Buffer
+	mw := NewMultipartWriter(&body)
+	streamed, err := StreamDICOMSeries(opts, mw)
+	if err != nil {
+		t.Fatalf("StreamDICOMSeries failed: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outputDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("streaming wrote into the output directory: %v", err)
+	}
+
+	// The same run on disk holds the same instances
+	files, err := GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if len(streamed) != len(files) {
+		t.Fatalf("streamed %d instances, generated %d", len(streamed), len(files))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(mw.ContentType())
+	if err != nil || mediaType != "multipart/related" || params["type"] != "application/dicom" {
+		t.Fatalf("content type %q", mw.ContentType())
+	}
+	reader := multipart.NewReader(&body, params["boundary"])
+	for i, f := range files {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != "application/dicom" {
+			t.Errorf("part %d has content type %q", i, got)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("part %d differs from %s", i, f.Path)
+		}
+		if streamed[i].SOPInstanceUID != f.SOPInstanceUID || streamed[i].Path != filepath.Base(f.Path) {
+			t.Errorf("streamed %+v, want %+v", streamed[i], f)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("got %v after the last instance, want EOF", err)
+	}
+}
+
+func TestStreamDICOMSeries_WriterError(t *testing.T) {
+	failure := errors.New("uploader gone")
+	calls := 0
+	_, err := StreamDICOMSeries(GeneratorOptions{
+		NumImages:  3,
+		TotalSize:  "1MB",
+		OutputDir:  "run",
+		Seed:       42,
+		NumStudies: 1,
+		Quiet:      true,
+	}, InstanceWriterFunc(func(f GeneratedFile, r io.Reader) error {
+		calls++
+		return failure
+	}))
+	if !errors.Is(err, failure) || calls != 1 {
+		t.Errorf("got %v after %d calls, want the writer error after the first instance", err, calls)
+	}
+}
@@ -0,0 +1,30 @@
+package dicom
+
+import (
+	"fmt"
+	randv2 "math/rand/v2"
+)
+
+// planStudyCounts resolves the number of studies of a run. With a studies
+// range, the total is drawn from it; with studies per patient, every patient
+// gets its own count, returned in patient order. The draws use a dedicated
+// RNG so that the rest of the run does not depend on them.
+func planStudyCounts(opts GeneratorOptions, seed int64) (numStudies int, perPatient []int, err error) {
+	if opts.StudiesRange.IsSet() && opts.StudiesPerPatient.IsSet() {
+		return 0, nil, fmt.Errorf("studies range and studies per patient cannot be combined")
+	}
+
+	rng := randv2.New(randv2.NewPCG(uint64(seed), 0x57d))
+	switch {
+	case opts.StudiesRange.IsSet():
+		return opts.StudiesRange.Count(rng), nil, nil
+	case opts.StudiesPerPatient.IsSet():
+		perPatient = make([]int, max(opts.NumPatients, 1))
+		for i := range perPatient {
+			perPatient[i] = opts.StudiesPerPatient.Count(rng)
+			numStudies += perPatient[i]
+		}
+		return numStudies, perPatient, nil
+	}
+	return opts.NumStudies, nil, nil
+}
@@ -0,0 +1,81 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+func TestPlanStudyCounts_Fixed(t *testing.T) {
+	numStudies, perPatient, err := planStudyCounts(GeneratorOptions{NumStudies: 7, NumPatients: 3}, 42)
+	if err != nil {
+		t.Fatalf("planStudyCounts failed: %v", err)
+	}
+	if numStudies != 7 || perPatient != nil {
+		t.Errorf("got %d studies, per patient %v; want 7 studies split evenly", numStudies, perPatient)
+	}
+}
+
+func TestPlanStudyCounts_StudiesRange(t *testing.T) {
+	seen := make(map[int]bool)
+	for seed := int64(1); seed <= 100; seed++ {
+		numStudies, perPatient, err := planStudyCounts(GeneratorOptions{
+			NumStudies:   1,
+			StudiesRange: util.StudyRange{Min: 10, Max: 20},
+		}, seed)
+		if err != nil {
+			t.Fatalf("planStudyCounts failed: %v", err)
+		}
+		if numStudies < 10 || numStudies > 20 || perPatient != nil {
+			t.Fatalf("seed %d: got %d studies, per patient %v; want 10-20 split evenly", seed, numStudies, perPatient)
+		}
+		seen[numStudies] = true
+	}
+	if len(seen) < 5 {
+		t.Errorf("only %d distinct study counts over 100 seeds", len(seen))
+	}
+}
+
+func TestPlanStudyCounts_PerPatient(t *testing.T) {
+	opts := GeneratorOptions{NumPatients: 50, StudiesPerPatient: util.StudyRange{Min: 1, Max: 4}}
+	numStudies, perPatient, err := planStudyCounts(opts, 42)
+	if err != nil {
+		t.Fatalf("planStudyCounts failed: %v", err)
+	}
+	if len(perPatient) != 50 {
+		t.Fatalf("got %d patient counts, want 50", len(perPatient))
+	}
+	total := 0
+	distinct := make(map[int]bool)
+	for _, n := range perPatient {
+		if n < 1 || n > 4 {
+			t.Errorf("patient study count %d outside 1-4", n)
+		}
+		total += n
+		distinct[n] = true
+	}
+	if total != numStudies {
+		t.Errorf("total %d does not match the sum of patient counts %d", numStudies, total)
+	}
+	if len(distinct) < 2 {
+		t.Error("every patient has the same number of studies")
+	}
+
+	// Same seed, same counts
+	_, again, _ := planStudyCounts(opts, 42)
+	for i := range perPatient {
+		if again[i] != perPatient[i] {
+			t.Fatalf("counts are not deterministic at patient %d", i)
+		}
+	}
+}
+
+func TestPlanStudyCounts_Conflict(t *testing.T) {
+	_, _, err := planStudyCounts(GeneratorOptions{
+		StudiesRange:      util.StudyRange{Min: 1, Max: 2},
+		StudiesPerPatient: util.StudyRange{Min: 1, Max: 2},
+	}, 42)
+	if err == nil {
+		t.Error("expected an error when combining studies range and studies per patient")
+	}
+}
@@ -0,0 +1,100 @@
+package dicom
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// TarPath returns the path of the tar archive of outputDir: a .tar file of
+// the same name next to it, .tar.gz when compressed.
+func TarPath(outputDir string, compress bool) string {
+	if compress {
+		return filepath.Clean(outputDir) + ".tar.gz"
+	}
+	return filepath.Clean(outputDir) + ".tar"
+}
+
+// ExportTar writes the DICOMDIR and the PT*/ST*/SE* hierarchy of outputDir,
+// sharded or not, into the single tar archive tarPath, gzip-compressed when
+// compress is set, as pipelines keep their artifacts: the entries are those
+// of ExportZIP, in the same order, as ustar regular files owned by root. An
+// unchanged archive is kept (rerun of the same profile). It returns the
+// number of archived instances.
+func ExportTar(outputDir, tarPath string, compress, quiet bool) (int, error) {
+	tmpPath := tarPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", tmpPath, err)
+	}
+
+	var w io.Writer = out
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(out) // No name nor time in the header, reproducible
+		w = zw
+	}
+	paths, imageFiles := archivedFiles(outputDir)
+	tw := tar.NewWriter(w)
+	for _, path := range paths {
+		if err := addTarEntry(tw, outputDir, path); err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmpPath)
+			return 0, err
+		}
+	}
+	err = tw.Close()
+	if zw != nil && err == nil {
+		err = zw.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("write %s: %w", tmpPath, err)
+	}
+	if _, err := moveIfChanged(tmpPath, tarPath); err != nil {
+		return 0, err
+	}
+
+	if !quiet {
+		fmt.Printf("  Archived %d instances into %s\n", imageFiles, tarPath)
+	}
+	return imageFiles, nil
+}
+
+// addTarEntry copies the file at path into tw, named by its path relative
+// to outputDir.
+func addTarEntry(tw *tar.Writer, outputDir, path string) error {
+	rel, err := filepath.Rel(outputDir, path)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(rel),
+		Size:     info.Size(),
+		Mode:     0644,
+		ModTime:  archiveModTime,
+		Format:   tar.FormatUSTAR,
+	}); err != nil {
+		return fmt.Errorf("archive %s: %w", rel, err)
+	}
+	if _, err := io.Copy(tw, in); err != nil {
+		return fmt.Errorf("archive %s: %w", rel, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package dicom
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportTar(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "series")
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:  3,
+		TotalSize:  "1MB",
+		OutputDir:  outputDir,
+		Seed:       42,
+		NumStudies: 1,
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := OrganizeFilesIntoDICOMDIR(outputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+
+	for _, compress := range []bool{false, true} {
+		tarPath := TarPath(outputDir, compress)
+		n, err := ExportTar(outputDir, tarPath, compress, true)
+		if err != nil {
+			t.Fatalf("ExportTar(compress=%v) failed: %v", compress, err)
+		}
+		if n != len(files) {
+			t.Fatalf("archived %d instances, want %d", n, len(files))
+		}
+
+		first, err := os.ReadFile(tarPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = bytes.NewReader(first)
+		if compress {
+			if r, err = gzip.NewReader(r); err != nil {
+				t.Fatalf("%s is not gzip: %v", tarPath, err)
+			}
+		}
+		tr := tar.NewReader(r)
+		var names []string
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("invalid tar %s: %v", tarPath, err)
+			}
+			names = append(names, hdr.Name)
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(hdr.Name)))
+			if err != nil {
+				t.Fatalf("entry %s is not a file of the output: %v", hdr.Name, err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Errorf("entry %s differs from its file", hdr.Name)
+			}
+		}
+		if len(names) != n+1 || names[0] != "DICOMDIR" {
+			t.Fatalf("%s holds %v, want the DICOMDIR and %d instances", tarPath, names, n)
+		}
+
+		// A rerun writes the same archive
+		if _, err := ExportTar(outputDir, tarPath, compress, true); err != nil {
+			t.Fatalf("second ExportTar failed: %v", err)
+		}
+		second, err := os.ReadFile(tarPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Errorf("rerun changed %s", tarPath)
+		}
+	}
+}
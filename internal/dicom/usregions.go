@@ -0,0 +1,59 @@
+package dicom
+
+import (
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Ultrasound regions map the pixels of areas of an image to physical values.
+// Measurement tools use them rather than PixelSpacing to turn calipers into
+// distances, and refuse to measure without them.
+
+// Values of the ultrasound region attributes (PS3.3 C.8.5.5.1)
+const (
+	usRegionSpatial2D      = 1 // RegionSpatialFormat: 2D image
+	usRegionTissue         = 1 // RegionDataType
+	usRegionColorFlow      = 2
+	usRegionLowPriority    = 1 // RegionFlags bit 0: another region is drawn over it
+	usRegionUnitCentimeter = 3 // PhysicalUnitsXDirection and Y
+)
+
+// ultrasoundRegionsElement returns the SequenceOfUltrasoundRegions of a
+// width x height image at a pixel spacing in mm: a 2D tissue region over the
+// whole image and, for color images, the color flow region of the Doppler
+// box, drawn over it. Distances are in cm from the probe, at the middle of
+// the top row.
+func ultrasoundRegionsElement(width, height int, pixelSpacing float64, color bool) *dicom.Element {
+	delta := pixelSpacing / 10 // cm
+	region := func(dataType, flags, left, top, right, bottom int) []*dicom.Element {
+		return []*dicom.Element{
+			mustNewElement(tag.RegionSpatialFormat, []int{usRegionSpatial2D}),
+			mustNewElement(tag.RegionDataType, []int{dataType}),
+			mustNewElement(tag.RegionFlags, []int{flags}),
+			mustNewElement(tag.RegionLocationMinX0, []int{left}),
+			mustNewElement(tag.RegionLocationMinY0, []int{top}),
+			mustNewElement(tag.RegionLocationMaxX1, []int{right}),
+			mustNewElement(tag.RegionLocationMaxY1, []int{bottom}),
+			// The reference pixel is given from the top left of the region
+			mustNewElement(tag.ReferencePixelX0, []int{width/2 - left}),
+			mustNewElement(tag.ReferencePixelY0, []int{-top}),
+			mustNewElement(tag.PhysicalUnitsXDirection, []int{usRegionUnitCentimeter}),
+			mustNewElement(tag.PhysicalUnitsYDirection, []int{usRegionUnitCentimeter}),
+			mustNewElement(tag.ReferencePixelPhysicalValueX, []float64{0}),
+			mustNewElement(tag.ReferencePixelPhysicalValueY, []float64{0}),
+			mustNewElement(tag.PhysicalDeltaX, []float64{delta}),
+			mustNewElement(tag.PhysicalDeltaY, []float64{delta}),
+		}
+	}
+
+	if !color {
+		return mustNewElement(tag.SequenceOfUltrasoundRegions, [][]*dicom.Element{
+			region(usRegionTissue, 0, 0, 0, width-1, height-1),
+		})
+	}
+	left, top, right, bottom := dopplerBox(width, height)
+	return mustNewElement(tag.SequenceOfUltrasoundRegions, [][]*dicom.Element{
+		region(usRegionTissue, usRegionLowPriority, 0, 0, width-1, height-1),
+		region(usRegionColorFlow, 0, left, top, right, bottom),
+	})
+}
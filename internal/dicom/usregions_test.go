@@ -0,0 +1,60 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestUltrasoundRegionsElement(t *testing.T) {
+	const width, height = 640, 480
+	items := ultrasoundRegionsElement(width, height, 0.3, false).Value.GetValue().([]*dicom.SequenceItemValue)
+	if len(items) != 1 {
+		t.Fatalf("got %d regions, want 1", len(items))
+	}
+	tissue := dicom.Dataset{Elements: items[0].GetValue().([]*dicom.Element)}
+	for tg, want := range map[tag.Tag]int{
+		tag.RegionDataType:      usRegionTissue,
+		tag.RegionLocationMinX0: 0,
+		tag.RegionLocationMaxX1: width - 1,
+		tag.RegionLocationMaxY1: height - 1,
+		tag.ReferencePixelX0:    width / 2,
+	} {
+		if got := elementInts(tissue, tg); got != want {
+			t.Errorf("%v = %d, want %d", tg, got, want)
+		}
+	}
+	elem, err := tissue.FindElementByTag(tag.PhysicalDeltaX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := elem.Value.GetValue().([]float64)[0]; got != 0.03 {
+		t.Errorf("PhysicalDeltaX = %g, want 0.03 cm", got)
+	}
+
+	// The color flow region is the Doppler box, over the tissue region
+	items = ultrasoundRegionsElement(width, height, 0.3, true).Value.GetValue().([]*dicom.SequenceItemValue)
+	if len(items) != 2 {
+		t.Fatalf("got %d regions, want 2", len(items))
+	}
+	tissue = dicom.Dataset{Elements: items[0].GetValue().([]*dicom.Element)}
+	flow := dicom.Dataset{Elements: items[1].GetValue().([]*dicom.Element)}
+	if got := elementInts(tissue, tag.RegionFlags); got != usRegionLowPriority {
+		t.Errorf("tissue RegionFlags = %d, want low priority", got)
+	}
+	left, top, right, bottom := dopplerBox(width, height)
+	for tg, want := range map[tag.Tag]int{
+		tag.RegionDataType:      usRegionColorFlow,
+		tag.RegionLocationMinX0: left,
+		tag.RegionLocationMinY0: top,
+		tag.RegionLocationMaxX1: right,
+		tag.RegionLocationMaxY1: bottom,
+		tag.ReferencePixelX0:    width/2 - left,
+		tag.ReferencePixelY0:    -top,
+	} {
+		if got := elementInts(flow, tg); got != want {
+			t.Errorf("color flow %v = %d, want %d", tg, got, want)
+		}
+	}
+}
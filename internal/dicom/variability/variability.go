@@ -0,0 +1,174 @@
+// Package variability applies per-study "styles" to generated datasets so that
+// large corpora are not suspiciously uniform: real archives mix sites and
+// scanners that case descriptions differently, leave out optional attributes
+// and write decimal strings with varying precision.
+package variability
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Config holds variability settings
+type Config struct {
+	Percentage int // 0-100, percentage of studies with a randomized style
+}
+
+// Validate checks if config is valid
+func (c *Config) Validate() error {
+	if c.Percentage < 0 || c.Percentage > 100 {
+		return fmt.Errorf("variability percentage must be 0-100, got %d", c.Percentage)
+	}
+	return nil
+}
+
+// IsEnabled returns true if variability is enabled
+func (c *Config) IsEnabled() bool {
+	return c.Percentage > 0
+}
+
+// Casing is the letter case applied to descriptions
+type Casing int
+
+const (
+	CaseAsIs  Casing = iota // Keep generated casing
+	CaseUpper               // "BRAIN MR"
+	CaseLower               // "brain mr"
+	CaseTitle               // "Brain Mr"
+)
+
+// OptionalTags lists Type 3 (or empty-able Type 2) attributes that a style
+// may leave out without making the dataset invalid.
+var OptionalTags = []tag.Tag{
+	tag.InstitutionalDepartmentName,
+	tag.StationName,
+	tag.PerformingPhysicianName,
+	tag.OperatorsName,
+	tag.ProtocolName,
+	tag.RequestedProcedureDescription,
+	tag.SpacingBetweenSlices,
+	tag.SliceLocation,
+	tag.SequenceName,
+}
+
+// dsPrecisions lists the decimal places a style may use for DS values;
+// -1 writes the shortest representation (trailing zeros trimmed).
+var dsPrecisions = []int{-1, 3, 4, 5, 6}
+
+// Style is the set of realism choices applied to every object of a study.
+// The zero value leaves datasets unchanged.
+type Style struct {
+	Casing      Casing
+	OmitTags    []tag.Tag
+	DSPrecision int // Decimal places of DS values (0 = unchanged, -1 = shortest)
+}
+
+// PickStyle returns a random style, or the zero style if the study is not
+// selected by the configured percentage.
+func PickStyle(c Config, rng *rand.Rand) Style {
+	if rng.IntN(100) >= c.Percentage {
+		return Style{}
+	}
+
+	style := Style{
+		Casing:      Casing(rng.IntN(4)),
+		DSPrecision: dsPrecisions[rng.IntN(len(dsPrecisions))],
+	}
+	for _, i := range rng.Perm(len(OptionalTags))[:rng.IntN(4)] {
+		style.OmitTags = append(style.OmitTags, OptionalTags[i])
+	}
+	return style
+}
+
+// ApplyCase returns text in the style's casing.
+func (s Style) ApplyCase(text string) string {
+	switch s.Casing {
+	case CaseUpper:
+		return strings.ToUpper(text)
+	case CaseLower:
+		return strings.ToLower(text)
+	case CaseTitle:
+		words := strings.Fields(strings.ToLower(text))
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+		return strings.Join(words, " ")
+	default:
+		return text
+	}
+}
+
+// Apply removes the omitted tags from elements and reformats top-level DS
+// values with the style's precision.
+func (s Style) Apply(elements []*dicom.Element) []*dicom.Element {
+	if len(s.OmitTags) == 0 && s.DSPrecision == 0 {
+		return elements
+	}
+
+	omit := make(map[tag.Tag]bool, len(s.OmitTags))
+	for _, t := range s.OmitTags {
+		omit[t] = true
+	}
+
+	result := elements[:0]
+	for _, elem := range elements {
+		if omit[elem.Tag] {
+			continue
+		}
+		if s.DSPrecision != 0 && elem.RawValueRepresentation == "DS" {
+			elem = s.reformatDS(elem)
+		}
+		result = append(result, elem)
+	}
+	return result
+}
+
+// reformatDS rewrites the values of a DS element with the style's precision.
+// Values that cannot be parsed are kept as they are.
+func (s Style) reformatDS(elem *dicom.Element) *dicom.Element {
+	values, ok := elem.Value.GetValue().([]string)
+	if !ok {
+		return elem
+	}
+
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return elem
+		}
+		formatted[i] = formatDS(f, s.DSPrecision)
+	}
+
+	newElem, err := dicom.NewElement(elem.Tag, formatted)
+	if err != nil {
+		return elem
+	}
+	return newElem
+}
+
+// formatDS formats a decimal string with the given number of decimal places
+// (-1 = shortest form of 6 decimals), within the 16 bytes allowed for DS.
+func formatDS(v float64, precision int) string {
+	var s string
+	if precision < 0 {
+		s = strconv.FormatFloat(v, 'f', 6, 64)
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	} else {
+		s = strconv.FormatFloat(v, 'f', precision, 64)
+	}
+	if strings.Trim(s, "-0.") == "" {
+		s = strings.TrimPrefix(s, "-") // No negative zero
+	}
+	if len(s) > util.MaxDSLength {
+		s = util.FormatDS(v)
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package variability
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	for _, pct := range []int{0, 50, 100} {
+		c := Config{Percentage: pct}
+		if err := c.Validate(); err != nil {
+			t.Errorf("Percentage %d: unexpected error %v", pct, err)
+		}
+	}
+	for _, pct := range []int{-1, 101} {
+		c := Config{Percentage: pct}
+		if err := c.Validate(); err == nil {
+			t.Errorf("Percentage %d: expected error", pct)
+		}
+	}
+}
+
+func TestPickStyle_Percentage(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 100; i++ {
+		if style := PickStyle(Config{Percentage: 0}, rng); style.Casing != CaseAsIs || style.DSPrecision != 0 || len(style.OmitTags) != 0 {
+			t.Fatalf("0%% variability should give the zero style, got %+v", style)
+		}
+	}
+
+	styled := 0
+	for i := 0; i < 100; i++ {
+		style := PickStyle(Config{Percentage: 100}, rng)
+		if style.DSPrecision == 0 {
+			t.Fatal("100% variability should always pick a DS precision")
+		}
+		if len(style.OmitTags) > 3 {
+			t.Errorf("omitted %d tags, want at most 3", len(style.OmitTags))
+		}
+		if style.Casing != CaseAsIs {
+			styled++
+		}
+	}
+	if styled == 0 {
+		t.Error("expected some studies with a non-default casing")
+	}
+}
+
+func TestStyle_ApplyCase(t *testing.T) {
+	tests := []struct {
+		casing Casing
+		want   string
+	}{
+		{CaseAsIs, "BRAIN MR - Study 2"},
+		{CaseUpper, "BRAIN MR - STUDY 2"},
+		{CaseLower, "brain mr - study 2"},
+		{CaseTitle, "Brain Mr - Study 2"},
+	}
+	for _, tt := range tests {
+		if got := (Style{Casing: tt.casing}).ApplyCase("BRAIN MR - Study 2"); got != tt.want {
+			t.Errorf("casing %d: got %q, want %q", tt.casing, got, tt.want)
+		}
+	}
+}
+
+func TestStyle_Apply(t *testing.T) {
+	newElem := func(tg tag.Tag, value any) *dicom.Element {
+		elem, err := dicom.NewElement(tg, value)
+		if err != nil {
+			t.Fatalf("NewElement: %v", err)
+		}
+		return elem
+	}
+	elements := []*dicom.Element{
+		newElem(tag.StationName, []string{"CT01"}),
+		newElem(tag.SliceThickness, []string{"2.500000"}),
+		newElem(tag.PixelSpacing, []string{"0.781250", "0.781250"}),
+		newElem(tag.Rows, []int{512}),
+	}
+
+	result := Style{OmitTags: []tag.Tag{tag.StationName}, DSPrecision: -1}.Apply(elements)
+	if len(result) != 3 {
+		t.Fatalf("got %d elements, want 3", len(result))
+	}
+	if result[0].Tag != tag.SliceThickness {
+		t.Fatalf("StationName should be omitted, first element is %v", result[0].Tag)
+	}
+	if got := result[0].Value.GetValue().([]string)[0]; got != "2.5" {
+		t.Errorf("SliceThickness = %q, want 2.5", got)
+	}
+	if got := result[1].Value.GetValue().([]string)[1]; got != "0.78125" {
+		t.Errorf("PixelSpacing = %q, want 0.78125", got)
+	}
+}
+
+func TestFormatDS(t *testing.T) {
+	tests := []struct {
+		v         float64
+		precision int
+		want      string
+	}{
+		{2.5, -1, "2.5"},
+		{2.5, 3, "2.500"},
+		{-0.0000001, -1, "0"},
+		{-0.0001, 3, "0.000"},
+		{1, -1, "1"},
+		{1234567890.123456, 6, "1234567890.12346"},
+	}
+	for _, tt := range tests {
+		if got := formatDS(tt.v, tt.precision); got != tt.want {
+			t.Errorf("formatDS(%v, %d) = %q, want %q", tt.v, tt.precision, got, tt.want)
+		}
+		if len(formatDS(tt.v, tt.precision)) > 16 {
+			t.Errorf("formatDS(%v, %d) exceeds 16 characters", tt.v, tt.precision)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Vendor private tag flavors
+const (
+	VendorTagsGE      = "ge"      // GEMS groups 0009, 0019 and 0043 (CT and MR)
+	VendorTagsPhilips = "philips" // Philips groups 2001 and 2005 (MR)
+)
+
+// Manufacturers of the scanners of the vendors
+const (
+	manufacturerGE      = "GE MEDICAL SYSTEMS"
+	manufacturerPhilips = "PHILIPS"
+	manufacturerSiemens = "SIEMENS"
+)
+
+// vendorManufacturers maps the vendor private tag flavors to the
+// Manufacturer of their scanners.
+var vendorManufacturers = map[string]string{
+	VendorTagsGE:      manufacturerGE,
+	VendorTagsPhilips: manufacturerPhilips,
+}
+
+// ParseVendorTags parses a comma-separated list of vendor private tag
+// flavors ("ge", "philips" or "all").
+func ParseVendorTags(s string) ([]string, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return nil, nil
+	}
+	if s == "all" {
+		return []string{VendorTagsGE, VendorTagsPhilips}, nil
+	}
+
+	var flavors []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if _, ok := vendorManufacturers[part]; !ok {
+			return nil, fmt.Errorf("unknown vendor tags %q (valid: ge, philips, all)", part)
+		}
+		flavors = append(flavors, part)
+	}
+	return flavors, nil
+}
+
+// validateVendorTags checks that the vendor private tags of opts can be
+// written on its modality and do not collide with the private tags of its
+// corruptions.
+func validateVendorTags(opts GeneratorOptions) error {
+	modality := modalities.GetGenerator(opts.Modality).Modality()
+	for _, flavor := range opts.VendorTags {
+		switch flavor {
+		case VendorTagsGE:
+			if modality != modalities.CT && modality != modalities.MR {
+				return fmt.Errorf("GE vendor tags require the CT or MR modality")
+			}
+			if opts.CorruptionConfig.HasType(corruption.GEPrivate) {
+				return fmt.Errorf("GE vendor tags and the ge-private corruption cannot be combined")
+			}
+		case VendorTagsPhilips:
+			if modality != modalities.MR {
+				return fmt.Errorf("Philips vendor tags require the MR modality")
+			}
+			if opts.CorruptionConfig.HasType(corruption.PhilipsPrivate) {
+				return fmt.Errorf("Philips vendor tags and the philips-private corruption cannot be combined")
+			}
+		default:
+			return fmt.Errorf("unknown vendor tags %q", flavor)
+		}
+	}
+	return nil
+}
+
+// vendorScanners returns the manufacturers of the scanners the studies of
+// opts are acquired on, for the vendor private tags they get; nil for all.
+func (opts GeneratorOptions) vendorScanners() map[string]bool {
+	if len(opts.VendorTags) == 0 && !opts.SiemensCSA {
+		return nil
+	}
+	manufacturers := make(map[string]bool)
+	for _, flavor := range opts.VendorTags {
+		manufacturers[vendorManufacturers[flavor]] = true
+	}
+	if opts.SiemensCSA {
+		manufacturers[manufacturerSiemens] = true
+	}
+	return manufacturers
+}
+
+// vendorImage is the image the vendor private tags describe.
+type vendorImage struct {
+	params                modalities.SeriesParams // Acquisition of the image, on its scanner
+	sequence              string                  // Sequence of the series, e.g. "T2_FSE"
+	protocol              string                  // ProtocolName
+	orientation           []float64               // ImageOrientationPatient
+	frequency, phase      int                     // Acquisition matrix
+	width                 int                     // Columns
+	sliceIndex, numSlices int                     // Slice in the stack of the series
+	patientWeight         float64                 // kg, 0 when unknown
+}
+
+// vendorPrivateElements returns the private elements the scanner of img
+// writes, when its vendor is among flavors: none for the other vendors.
+func vendorPrivateElements(flavors []string, img vendorImage) []*dicom.Element {
+	for _, flavor := range flavors {
+		if vendorManufacturers[flavor] != img.params.Scanner.Manufacturer {
+			continue
+		}
+		switch flavor {
+		case VendorTagsGE:
+			return gePrivateElements(img)
+		case VendorTagsPhilips:
+			return philipsPrivateElements(img)
+		}
+	}
+	return nil
+}
+
+// GE CT helical acquisitions: rotation time (s), pitch and detector row width (mm)
+const (
+	geRotationTime = 0.5
+	gePitch        = 0.984375
+	geRowWidth     = 0.625
+)
+
+// gePrivateElements returns the GEMS private elements of a GE CT or MR
+// image: the identification block of group 0009, the acquisition block of
+// group 0019 (pulse sequence, diffusion direction; table speed, gantry
+// period) and the parameter block of group 0043 (image type, b-value in the
+// slop integers, ASSET factors; pitch).
+func gePrivateElements(img vendorImage) []*dicom.Element {
+	p := img.params
+	product, _, _ := strings.Cut(strings.ToUpper(p.Scanner.Model), " ")
+	elements := []*dicom.Element{
+		mustNewPrivateElement(tag.Tag{Group: 0x0009, Element: 0x0010}, "LO", []string{"GEMS_IDEN_01"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0009, Element: 0x1001}, "LO", []string{"GE_GENESIS_FF"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0009, Element: 0x1004}, "SH", []string{product}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x0010}, "LO", []string{"GEMS_ACQU_01"}),
+	}
+
+	if p.Modality == modalities.CT {
+		collimation := float64(p.Scanner.DetectorRows) * geRowWidth
+		return append(elements,
+			mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x1023}, "DS", []string{util.FormatDS(gePitch * collimation)}),
+			mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x1027}, "DS", []string{util.FormatDS(geRotationTime)}),
+			mustNewPrivateElement(tag.Tag{Group: 0x0043, Element: 0x0010}, "LO", []string{"GEMS_PARM_01"}),
+			mustNewPrivateElement(tag.Tag{Group: 0x0043, Element: 0x1027}, "SH", []string{fmt.Sprintf("%g:1", gePitch)}),
+		)
+	}
+
+	sequence := vendorSequenceOf(img.sequence)
+	bValue := 0
+	elements = append(elements,
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x107E}, "SS", []int{1}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x109C}, "LO", []string{sequence.ge}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x109E}, "LO", []string{sequence.geInternal}),
+	)
+	if isDiffusion(img.sequence) {
+		// Trace-weighted images: no gradient direction
+		bValue = csaDiffusionBValue
+		for _, element := range []uint16{0x10BB, 0x10BC, 0x10BD} {
+			elements = append(elements, mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: element}, "DS", []string{"0"}))
+		}
+	}
+	elements = append(elements,
+		mustNewPrivateElement(tag.Tag{Group: 0x0043, Element: 0x0010}, "LO", []string{"GEMS_PARM_01"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0043, Element: 0x102F}, "SS", []int{0}), // Magnitude
+		mustNewPrivateElement(tag.Tag{Group: 0x0043, Element: 0x1039}, "IS", []string{util.FormatIS(bValue), "0", "0", "0"}),
+	)
+	if p.ParallelFactor > 1 {
+		elements = append(elements, mustNewPrivateElement(tag.Tag{Group: 0x0043, Element: 0x1083}, "DS",
+			[]string{util.FormatDS(1 / p.ParallelFactor), "1"}))
+	}
+	return elements
+}
+
+// philipsWaterFatShift is the water-fat shift (pixels) of Philips protocols.
+const philipsWaterFatShift = 1.2
+
+// philipsPrivateElements returns the Philips private elements of a Philips
+// MR image: the imaging block of group 2001 (b-factor, slice and phase
+// numbers, image plane, scanning technique, exam card) and the MR imaging
+// block of group 2005 (scale slope and intercept, image type, repetition
+// time).
+func philipsPrivateElements(img vendorImage) []*dicom.Element {
+	p := img.params
+	var bFactor float64
+	if isDiffusion(img.sequence) {
+		bFactor = csaDiffusionBValue
+	}
+	elements := []*dicom.Element{
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x0010}, "LO", []string{"Philips Imaging DD 001"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x1003}, "FL", []float64{bFactor}),
+	}
+	if bFactor > 0 {
+		elements = append(elements, mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x1004}, "CS", []string{"I"})) // Isotropic
+	}
+	elements = append(elements,
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x1008}, "IS", []string{"1"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x100A}, "IS", []string{util.FormatIS(img.sliceIndex + 1)}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x100B}, "CS", []string{philipsImagePlane(img.orientation)}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x1017}, "SL", []int{1}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x1018}, "SL", []int{img.numSlices}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x1020}, "LO", []string{vendorSequenceOf(img.sequence).philips}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x1022}, "FL", []float64{philipsWaterFatShift}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2001, Element: 0x10C8}, "LO", []string{img.protocol}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2005, Element: 0x0010}, "LO", []string{"Philips MR Imaging DD 001"}),
+		// Floating point values of the pixels: stored / scale slope, with a
+		// slope spreading the window over the stored range
+		mustNewPrivateElement(tag.Tag{Group: 0x2005, Element: 0x100D}, "FL", []float64{0}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2005, Element: 0x100E}, "FL", []float64{math.Round(1e6*1000/math.Max(p.WindowWidth, 1)) / 1e6}),
+		mustNewPrivateElement(tag.Tag{Group: 0x2005, Element: 0x1011}, "CS", []string{"M"}), // Magnitude
+		mustNewPrivateElement(tag.Tag{Group: 0x2005, Element: 0x1030}, "FL", []float64{p.RepetitionTime}),
+	)
+	return elements
+}
+
+// philipsImagePlane returns the Philips image plane of an orientation, from
+// the axis its normal is closest to.
+func philipsImagePlane(orientation []float64) string {
+	normal := sliceNormal(orientation)
+	switch x, y, z := math.Abs(normal[0]), math.Abs(normal[1]), math.Abs(normal[2]); {
+	case x >= y && x >= z:
+		return "SAGITTAL"
+	case y >= z:
+		return "CORONAL"
+	}
+	return "TRANSVERSAL"
+}
+
+// vendorSequence is an MR sequence as the vendors name it.
+type vendorSequence struct {
+	siemens        string // syngo MR sequence file
+	ge, geInternal string // GE pulse sequence and internal pulse sequence names
+	philips        string // Philips scanning technique
+}
+
+// vendorSequenceOf returns the vendor names of an MR sequence: turbo (fast)
+// spin echo unless it is a diffusion, gradient echo, single-shot or spin
+// echo one.
+func vendorSequenceOf(sequence string) vendorSequence {
+	s := strings.ToUpper(sequence)
+	switch {
+	case isDiffusion(sequence):
+		return vendorSequence{siemens: "ep2d_diff", ge: "epi2", geInternal: "EPI", philips: "DwiSE"}
+	case strings.Contains(s, "MPRAGE"):
+		return vendorSequence{siemens: "tfl", ge: "efgre3d", geInternal: "EFGRE3D", philips: "T1TFE"}
+	case strings.Contains(s, "VIBE"):
+		return vendorSequence{siemens: "fl3d_vibe", ge: "efgre3d", geInternal: "EFGRE3D", philips: "T1FFE"}
+	case strings.Contains(s, "STAR") || strings.Contains(s, "GRE") || s == "LOCALIZER":
+		return vendorSequence{siemens: "gre", ge: "fgre", geInternal: "FGRE", philips: "FFE"}
+	case strings.Contains(s, "SSFSE"):
+		return vendorSequence{siemens: "haste", ge: "ssfse", geInternal: "SSFSE", philips: "TSE"}
+	case strings.Contains(s, "FLAIR") || strings.HasPrefix(s, "STIR"):
+		return vendorSequence{siemens: "tir", ge: "fse-xl", geInternal: "FSE", philips: "TIR"}
+	case strings.HasSuffix(s, "_SE"):
+		return vendorSequence{siemens: "se", ge: "memp", geInternal: "SE", philips: "SE"}
+	}
+	return vendorSequence{siemens: "tse", ge: "fse-xl", geInternal: "FSE", philips: "TSE"}
+}
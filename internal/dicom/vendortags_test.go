@@ -0,0 +1,139 @@
+package dicom
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParseVendorTags(t *testing.T) {
+	for input, want := range map[string]string{
+		"":              "[]",
+		"ge, Philips":   "[ge philips]",
+		"all":           "[ge philips]",
+		"philips":       "[philips]",
+		"ALL ":          "[ge philips]",
+		"ge,ge":         "[ge ge]",
+		"philips , ge ": "[philips ge]",
+	} {
+		got, err := ParseVendorTags(input)
+		if err != nil || fmt.Sprint(got) != want {
+			t.Errorf("ParseVendorTags(%q) = %v, %v, want %s", input, got, err, want)
+		}
+	}
+	if _, err := ParseVendorTags("ge,canon"); err == nil {
+		t.Error("ParseVendorTags should reject an unknown vendor")
+	}
+}
+
+func TestVendorTags(t *testing.T) {
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   12,
+		TotalSize:   "2MB",
+		Modality:    modalities.MR,
+		VendorTags:  []string{VendorTagsGE, VendorTagsPhilips},
+		SiemensCSA:  true,
+		OutputDir:   t.TempDir(),
+		Seed:        1,
+		NumStudies:  6,
+		NumPatients: 2,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	creators := map[string]tag.Tag{
+		manufacturerGE:      {Group: 0x0043, Element: 0x0010},
+		manufacturerPhilips: {Group: 0x2001, Element: 0x0010},
+		manufacturerSiemens: {Group: 0x0029, Element: 0x0010},
+	}
+	seen := make(map[string]bool)
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		manufacturer := elementString(ds.Elements, tag.Manufacturer)
+		seen[manufacturer] = true
+		for vendor, creator := range creators {
+			if _, err := ds.FindElementByTag(creator); (err == nil) != (vendor == manufacturer) {
+				t.Errorf("%s: %s image, private creator %v of %s present: %v", f.Path, manufacturer, creator, vendor, err == nil)
+			}
+		}
+
+		switch manufacturer {
+		case manufacturerGE:
+			if got := elementString(ds.Elements, tag.Tag{Group: 0x0019, Element: 0x109C}); got == "" {
+				t.Errorf("%s: GE pulse sequence name (0019,109C) missing", f.Path)
+			}
+			elem, err := ds.FindElementByTag(tag.Tag{Group: 0x0043, Element: 0x1039})
+			if err != nil || len(elem.Value.GetValue().([]string)) != 4 {
+				t.Errorf("%s: GE slop integers (0043,1039) = %v", f.Path, elem)
+			}
+		case manufacturerPhilips:
+			if got, want := elementString(ds.Elements, tag.Tag{Group: 0x2001, Element: 0x100A}), elementString(ds.Elements, tag.InstanceNumber); got != want {
+				t.Errorf("%s: Philips slice number (2001,100A) = %q, want %q", f.Path, got, want)
+			}
+			if got, want := elementString(ds.Elements, tag.Tag{Group: 0x2001, Element: 0x10C8}), elementString(ds.Elements, tag.ProtocolName); got != want {
+				t.Errorf("%s: Philips exam card (2001,10C8) = %q, want %q", f.Path, got, want)
+			}
+			tr, _ := strconv.ParseFloat(elementString(ds.Elements, tag.RepetitionTime), 64)
+			elem, err := ds.FindElementByTag(tag.Tag{Group: 0x2005, Element: 0x1030})
+			if err != nil {
+				t.Errorf("%s: Philips repetition time (2005,1030) missing", f.Path)
+			} else if values, ok := elem.Value.GetValue().([]float64); !ok || len(values) != 1 || values[0]-tr > 0.01 || tr-values[0] > 0.01 {
+				t.Errorf("%s: Philips repetition time (2005,1030) = %v, want %g", f.Path, elem.Value, tr)
+			}
+		case manufacturerSiemens:
+		default:
+			t.Errorf("%s: Manufacturer %q outside the vendors", f.Path, manufacturer)
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("manufacturers = %v, want the 3 vendors", seen)
+	}
+
+	// GE CT helical acquisition
+	files, err = GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   1,
+		TotalSize:   "1MB",
+		Modality:    modalities.CT,
+		VendorTags:  []string{VendorTagsGE},
+		OutputDir:   t.TempDir(),
+		NumStudies:  1,
+		NumPatients: 1,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ds, err := dicom.ParseFile(files[0].Path, nil, dicom.SkipPixelData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := elementString(ds.Elements, tag.Tag{Group: 0x0019, Element: 0x1027}); got != "0.5" {
+		t.Errorf("GE gantry period (0019,1027) = %q, want 0.5", got)
+	}
+	if got := elementString(ds.Elements, tag.Tag{Group: 0x0043, Element: 0x1027}); got != "0.984375:1" {
+		t.Errorf("GE pitch ratio (0043,1027) = %q", got)
+	}
+
+	for _, opts := range []GeneratorOptions{
+		{Modality: modalities.CT, VendorTags: []string{VendorTagsPhilips}},
+		{Modality: modalities.US, VendorTags: []string{VendorTagsGE}},
+		{VendorTags: []string{"canon"}},
+		{VendorTags: []string{VendorTagsGE}, CorruptionConfig: corruption.Config{Types: []corruption.CorruptionType{corruption.GEPrivate}}},
+	} {
+		opts.NumImages, opts.TotalSize = 1, "1MB"
+		opts.OutputDir, opts.NumStudies, opts.NumPatients, opts.Quiet = t.TempDir(), 1, 1, true
+		if _, err := GenerateDICOMSeries(opts); err == nil {
+			t.Errorf("GenerateDICOMSeries(%s, %v, %v) should return error", opts.Modality, opts.VendorTags, opts.CorruptionConfig.Types)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package dicom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// WorklistDir is the directory of the Modality Worklist items, inside the
+// output directory. It does not match the PT* pattern of the DICOMDIR
+// hierarchy.
+const WorklistDir = "WORKLIST"
+
+// DefaultWorklistStationAE is the Scheduled Station AE Title of the
+// worklist items when none is given.
+const DefaultWorklistStationAE = "MODALITY"
+
+// worklistAttributes are the Patient, Visit, Imaging Service Request and
+// Requested Procedure attributes of a worklist item, copied from the study.
+var worklistAttributes = []tag.Tag{
+	tag.SpecificCharacterSet,
+	tag.AccessionNumber,
+	tag.ReferringPhysicianName,
+	tag.PatientName,
+	tag.PatientID,
+	tag.PatientBirthDate,
+	tag.PatientSex,
+	tag.StudyInstanceUID,
+	tag.RequestedProcedureDescription,
+	tag.RequestedProcedurePriority,
+	tag.AdmissionID,
+	tag.IssuerOfAdmissionIDSequence,
+	tag.PlacerOrderNumberImagingServiceRequest,
+	tag.FillerOrderNumberImagingServiceRequest,
+}
+
+// derivedModalities are the modalities of derived series, not acquired on
+// the scheduled station.
+var derivedModalities = map[string]bool{
+	"SR": true, "PR": true, "KO": true, "SEG": true, "OT": true,
+	"RTSTRUCT": true, "RTPLAN": true, "RTDOSE": true,
+}
+
+// ExportWorklist writes a Modality Worklist item (PS3.4 K.6) per study of
+// the PT*/ST*/SE* hierarchy of outputDir, as WORKLIST/WLnnnnnn.wl: the
+// scheduled procedure step the study fulfils, on stationAE at the StudyDate
+// and StudyTime, with the patient, the order and the Study Instance UID the
+// modality is to use. Items are DICOM files, as read by worklist SCPs
+// (dicomforge serve --worklist, dcmtk's wlmscpfs). Unchanged files are kept
+// (rerun of the same profile). It returns the number of items.
+func ExportWorklist(outputDir, stationAE string, quiet bool) (int, error) {
+	if stationAE == "" {
+		stationAE = DefaultWorklistStationAE
+	}
+	dir := filepath.Join(outputDir, WorklistDir)
+	n := 0
+	for _, patientDir := range hierarchyEntries(outputDir, "PT") {
+		for _, studyDir := range hierarchyEntries(patientDir, "ST") {
+			study, err := acquiredInstance(studyDir)
+			if err != nil {
+				return n, err
+			}
+			if study == nil {
+				continue
+			}
+			n++
+			if n == 1 {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return 0, fmt.Errorf("create directory: %w", err)
+				}
+			}
+			dest := filepath.Join(dir, fmt.Sprintf("WL%06d.wl", n))
+			tmpPath := dest + ".tmp"
+			if err := writeDatasetToFile(tmpPath, worklistItem(*study, stationAE, n)); err != nil {
+				return n, fmt.Errorf("write %s: %w", tmpPath, err)
+			}
+			if _, err := moveIfChanged(tmpPath, dest); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	if !quiet {
+		fmt.Printf("  Exported %d Modality Worklist items into %s/ (station AE %s)\n", n, WorklistDir, stationAE)
+	}
+	return n, nil
+}
+
+// acquiredInstance returns the header of the first instance of the first
+// acquired series of a study directory, or of its first series when all are
+// derived, or nil for an empty study.
+func acquiredInstance(studyDir string) (*dicom.Dataset, error) {
+	var first *dicom.Dataset
+	for _, seriesDir := range hierarchyEntries(studyDir, "SE") {
+		images := hierarchyEntries(seriesDir, "IM")
+		if len(images) == 0 {
+			continue
+		}
+		ds, err := parseDICOMTolerant(images[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", images[0], err)
+		}
+		if !derivedModalities[getStringValue(ds, tag.Modality)[0]] {
+			return &ds, nil
+		}
+		if first == nil {
+			first = &ds
+		}
+	}
+	return first, nil
+}
+
+// worklistItem builds worklist item n of a study from one of its instances.
+func worklistItem(study dicom.Dataset, stationAE string, n int) dicom.Dataset {
+	elements := []*dicom.Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+	}
+	for _, t := range worklistAttributes {
+		if elem, err := study.FindElementByTag(t); err == nil {
+			elements = append(elements, elem)
+		}
+	}
+	value := func(t tag.Tag) []string {
+		return getStringValue(study, t)
+	}
+	elements = append(elements,
+		mustNewElement(tag.RequestedProcedureID, []string{fmt.Sprintf("RP%06d", n)}),
+		mustNewElement(tag.ScheduledProcedureStepSequence, [][]*dicom.Element{{
+			mustNewElement(tag.Modality, value(tag.Modality)),
+			mustNewElement(tag.ScheduledStationAETitle, []string{stationAE}),
+			mustNewElement(tag.ScheduledProcedureStepStartDate, value(tag.StudyDate)),
+			mustNewElement(tag.ScheduledProcedureStepStartTime, value(tag.StudyTime)),
+			mustNewElement(tag.ScheduledPerformingPhysicianName, value(tag.PerformingPhysicianName)),
+			mustNewElement(tag.ScheduledProcedureStepDescription, value(tag.StudyDescription)),
+			mustNewElement(tag.ScheduledStationName, value(tag.StationName)),
+			mustNewElement(tag.ScheduledProcedureStepID, []string{fmt.Sprintf("SPS%06d", n)}),
+			mustNewElement(tag.ScheduledProcedureStepStatus, []string{"SCHEDULED"}),
+		}}),
+	)
+	sortElements(elements)
+	return dicom.Dataset{Elements: elements}
+}
@@ -0,0 +1,98 @@
+package dicom
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Modification time of the archive entries: fixed, so that the archive of
+// a rerun is identical (the oldest time the ZIP format holds)
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ZIPPath returns the path of the ZIP archive of outputDir: a .zip file of
+// the same name, next to it.
+func ZIPPath(outputDir string) string {
+	return filepath.Clean(outputDir) + ".zip"
+}
+
+// ExportZIP writes the DICOMDIR and the PT*/ST*/SE* hierarchy of outputDir,
+// sharded or not, into the single ZIP archive zipPath, as upload portals
+// accept studies: the DICOMDIR at the root, then the files in hierarchy
+// order under their path relative to outputDir, deflated. An unchanged
+// archive is kept (rerun of the same profile). It returns the number of
+// archived instances.
+func ExportZIP(outputDir, zipPath string, quiet bool) (int, error) {
+	tmpPath := zipPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", tmpPath, err)
+	}
+
+	paths, imageFiles := archivedFiles(outputDir)
+	zw := zip.NewWriter(out)
+	for _, path := range paths {
+		if err := addZIPEntry(zw, outputDir, path); err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmpPath)
+			return 0, err
+		}
+	}
+	err = zw.Close()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("write %s: %w", tmpPath, err)
+	}
+	if _, err := moveIfChanged(tmpPath, zipPath); err != nil {
+		return 0, err
+	}
+
+	if !quiet {
+		fmt.Printf("  Archived %d instances into %s\n", imageFiles, zipPath)
+	}
+	return imageFiles, nil
+}
+
+// archivedFiles returns the files of outputDir that go into its archive:
+// the DICOMDIR, if any, then the images of the hierarchy in hierarchy
+// order, and the number of images.
+func archivedFiles(outputDir string) ([]string, int) {
+	imageFiles := hierarchyImages(outputDir)
+	dicomdirPath := filepath.Join(outputDir, "DICOMDIR")
+	if _, err := os.Stat(dicomdirPath); err != nil {
+		return imageFiles, len(imageFiles)
+	}
+	return append([]string{dicomdirPath}, imageFiles...), len(imageFiles)
+}
+
+// addZIPEntry copies the file at path into zw, named by its path relative
+// to outputDir.
+func addZIPEntry(zw *zip.Writer, outputDir, path string) error {
+	rel, err := filepath.Rel(outputDir, path)
+	if err != nil {
+		return err
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     filepath.ToSlash(rel),
+		Method:   zip.Deflate,
+		Modified: archiveModTime,
+	})
+	if err != nil {
+		return fmt.Errorf("archive %s: %w", rel, err)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("archive %s: %w", rel, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package dicom
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportZIP(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "series")
+	files, err := GenerateDICOMSeries(GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "1MB",
+		OutputDir:   outputDir,
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 2,
+		Quiet:       true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := OrganizeFilesIntoDICOMDIR(outputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	zipPath := ZIPPath(outputDir)
+	if zipPath != outputDir+".zip" {
+		t.Fatalf("ZIPPath(%q) = %q", outputDir, zipPath)
+	}
+	n, err := ExportZIP(outputDir, zipPath, true)
+	if err != nil {
+		t.Fatalf("ExportZIP failed: %v", err)
+	}
+	if n != len(files) {
+		t.Fatalf("archived %d instances, want %d", n, len(files))
+	}
+
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("invalid ZIP: %v", err)
+	}
+	defer func() { _ = zr.Close() }()
+	if len(zr.File) != n+1 || zr.File[0].Name != "DICOMDIR" {
+		t.Fatalf("got %d entries starting with %q, want the DICOMDIR and %d instances", len(zr.File), zr.File[0].Name, n)
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("entry %s: %v", f.Name, err)
+		}
+		want, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(f.Name)))
+		if err != nil {
+			t.Fatalf("entry %s is not a file of the output: %v", f.Name, err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("entry %s differs from its file", f.Name)
+		}
+	}
+
+	// A rerun writes the same archive
+	first, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportZIP(outputDir, zipPath, true); err != nil {
+		t.Fatalf("second ExportZIP failed: %v", err)
+	}
+	second, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("rerun changed the archive")
+	}
+}
@@ -0,0 +1,156 @@
+package dicomweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/dicomjson"
+	"github.com/mrsinham/dicomforge/internal/dimse"
+)
+
+// mediaDICOMJSON is the media type of WADO-RS metadata.
+const mediaDICOMJSON = "application/dicom+json"
+
+// retrieveMetadata answers a WADO-RS metadata retrieval of a study, a series
+// or an instance: the DICOM JSON model of its instances, as an array. With
+// BulkData, binary values and the pixel data are referenced by a BulkDataURI
+// of the bulkdata endpoint; otherwise binary values are inlined and the pixel
+// data left out.
+func (s *Server) retrieveMetadata(w http.ResponseWriter, r *http.Request) {
+	study, series, instance := r.PathValue("study"), r.PathValue("series"), r.PathValue("instance")
+	var instances []*dimse.Instance
+	if instance != "" {
+		if inst, ok := s.Index.Lookup(study, series, instance); ok {
+			instances = append(instances, inst)
+		}
+	} else {
+		instances = s.Index.Instances(study, series)
+	}
+	if len(instances) == 0 {
+		http.Error(w, "no instance found", http.StatusNotFound)
+		return
+	}
+	if !acceptableJSON(r.Header.Values("Accept")) {
+		http.Error(w, "metadata is only available as "+mediaDICOMJSON, http.StatusNotAcceptable)
+		return
+	}
+
+	objects := make([]dicomjson.Object, 0, len(instances))
+	for _, inst := range instances {
+		ds, err := inst.Dataset()
+		if err != nil {
+			s.logf("WADO-RS metadata of %s failed: %v", inst.SOPInstanceUID, err)
+			http.Error(w, "read instance: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var opts dicomjson.Options
+		if s.BulkData {
+			opts.BulkDataURI = func(t tag.Tag) string {
+				return bulkDataURI(r, inst, t)
+			}
+		}
+		obj, err := dicomjson.Encode(ds, opts)
+		if err != nil {
+			s.logf("WADO-RS metadata of %s failed: %v", inst.SOPInstanceUID, err)
+			http.Error(w, "encode metadata: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		objects = append(objects, obj)
+	}
+	data, err := json.Marshal(objects)
+	if err != nil {
+		http.Error(w, "encode metadata: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mediaDICOMJSON)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+	s.logf("WADO-RS metadata of %d instance(s): %s", len(instances), r.URL.Path)
+}
+
+// bulkDataURI returns the absolute URI of the bulkdata endpoint serving the
+// value of an element of an instance.
+func bulkDataURI(r *http.Request, inst *dimse.Instance, t tag.Tag) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/studies/%s/series/%s/instances/%s/bulkdata/%s",
+		scheme, r.Host, inst.StudyInstanceUID, inst.SeriesInstanceUID, inst.SOPInstanceUID, dicomjson.Key(t))
+}
+
+// retrieveBulkData answers a bulk data retrieval of an element of an instance,
+// as a multipart/related response: the frames of encapsulated pixel data,
+// one part per frame, or the whole value of native pixel data and other
+// binary elements in a single application/octet-stream part.
+func (s *Server) retrieveBulkData(w http.ResponseWriter, r *http.Request) {
+	inst, ok := s.Index.Lookup(r.PathValue("study"), r.PathValue("series"), r.PathValue("instance"))
+	if !ok {
+		http.Error(w, "instance not found", http.StatusNotFound)
+		return
+	}
+	t, err := parseTag(r.PathValue("tag"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	parts, encapsulated, err := inst.BulkData(t)
+	if errors.Is(err, dimse.ErrNoBulkData) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logf("WADO-RS bulk data of %s failed: %v", inst.SOPInstanceUID, err)
+		http.Error(w, "read bulk data: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	mediaType, transferSyntax := partMediaType(inst, encapsulated)
+	if !acceptable(r.Header.Values("Accept"), mediaType, transferSyntax) {
+		http.Error(w, fmt.Sprintf("bulk data is only available as %s with transfer syntax %s", mediaType, transferSyntax), http.StatusNotAcceptable)
+		return
+	}
+	if err := writeParts(w, mediaType, transferSyntax, parts); err != nil {
+		s.logf("WADO-RS bulk data of %s interrupted: %v", inst.SOPInstanceUID, err)
+		return
+	}
+	s.logf("WADO-RS bulk data of %s: %s", inst.SOPInstanceUID, dicomjson.Key(t))
+}
+
+// parseTag parses a tag in the "GGGGEEEE" form of DICOM JSON keys.
+func parseTag(s string) (tag.Tag, error) {
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 8 {
+		return tag.Tag{}, fmt.Errorf("invalid tag %q (expected GGGGEEEE)", s)
+	}
+	return tag.Tag{Group: uint16(n >> 16), Element: uint16(n)}, nil
+}
+
+// acceptableJSON reports whether the Accept header values allow a DICOM JSON
+// response. No Accept header, */* and application/* accept it, as does
+// application/json.
+func acceptableJSON(accept []string) bool {
+	if len(accept) == 0 {
+		return true
+	}
+	for _, value := range accept {
+		for _, field := range strings.Split(value, ",") {
+			t, _, err := mime.ParseMediaType(strings.TrimSpace(field))
+			if err != nil {
+				continue
+			}
+			switch t {
+			case mediaDICOMJSON, "application/json", "application/*", "*/*":
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package dicomweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/dicom/dicomjson"
+)
+
+// metadataURL returns the metadata endpoint of the series of a generated
+// file, or of the file when instance is set.
+func metadataURL(srv *httptest.Server, file internaldicom.GeneratedFile, instance bool) string {
+	url := srv.URL + "/studies/" + file.StudyUID + "/series/" + file.SeriesUID
+	if instance {
+		url += "/instances/" + file.SOPInstanceUID
+	}
+	return url + "/metadata"
+}
+
+// getMetadata retrieves and decodes a metadata response.
+func getMetadata(t *testing.T, url string) []dicomjson.Object {
+	t.Helper()
+	resp := get(t, url, "application/dicom+json")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/dicom+json" {
+		t.Fatalf("status = %d, Content-Type = %q, want 200 application/dicom+json", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var objects []dicomjson.Object
+	if err := json.NewDecoder(resp.Body).Decode(&objects); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	return objects
+}
+
+func TestRetrieveMetadata(t *testing.T) {
+	ix, files := generateIndex(t, internaldicom.GeneratorOptions{USColor: internaldicom.PhotometricPaletteColor})
+	inline := httptest.NewServer(&Server{Index: ix, Quiet: true})
+	t.Cleanup(inline.Close)
+	bulk := httptest.NewServer(&Server{Index: ix, Quiet: true, BulkData: true})
+	t.Cleanup(bulk.Close)
+
+	if objects := getMetadata(t, metadataURL(inline, files[0], false)); len(objects) != 2 {
+		t.Errorf("series metadata has %d instances, want 2", len(objects))
+	}
+	if objects := getMetadata(t, inline.URL+"/studies/"+files[0].StudyUID+"/metadata"); len(objects) != len(files) {
+		t.Errorf("study metadata has %d instances, want %d", len(objects), len(files))
+	}
+
+	// Inline: binary values in InlineBinary, no pixel data
+	obj := getMetadata(t, metadataURL(inline, files[0], true))[0]
+	if _, ok := obj["7FE00010"]; ok {
+		t.Error("inline metadata should leave the pixel data out")
+	}
+	red := obj["00281201"]
+	lut, err := base64.StdEncoding.DecodeString(red.InlineBinary)
+	if err != nil || len(lut) == 0 || red.BulkDataURI != "" {
+		t.Fatalf("inline RedPaletteColorLookupTableData = %+v, want InlineBinary", red)
+	}
+
+	// Bulk data: BulkDataURI references served by the bulkdata endpoint
+	obj = getMetadata(t, metadataURL(bulk, files[0], true))[0]
+	if obj["00281201"].InlineBinary != "" || obj["7FE00010"].BulkDataURI == "" {
+		t.Fatalf("bulk data metadata: RedPaletteColorLookupTableData = %+v, PixelData = %+v", obj["00281201"], obj["7FE00010"])
+	}
+	for key, want := range map[string][]byte{
+		"00281201": lut,
+		"7FE00010": bytes.Join(nativeFrames(t, files[0].Path), nil),
+	} {
+		uri := obj[key].BulkDataURI
+		if !strings.HasPrefix(uri, bulk.URL+"/studies/") {
+			t.Errorf("%s BulkDataURI = %q, want an absolute URI of the server", key, uri)
+			continue
+		}
+		types, parts := readParts(t, get(t, uri, ""))
+		if len(parts) != 1 || !strings.HasPrefix(types[0], "application/octet-stream") {
+			t.Fatalf("%s bulk data: %d parts of %v, want 1 application/octet-stream", key, len(parts), types)
+		}
+		if !bytes.Equal(parts[0], want) {
+			t.Errorf("%s bulk data of %d bytes, want %d bytes of the value", key, len(parts[0]), len(want))
+		}
+	}
+}
+
+func TestRetrieveMetadata_Errors(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	unknown := files[0]
+	unknown.StudyUID = "1.2.3"
+	bulkData := srv.URL + "/studies/" + files[0].StudyUID + "/series/" + files[0].SeriesUID + "/instances/" + files[0].SOPInstanceUID + "/bulkdata/"
+
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   int
+	}{
+		{"unknown study", metadataURL(srv, unknown, false), "", http.StatusNotFound},
+		{"unknown instance", metadataURL(srv, unknown, true), "", http.StatusNotFound},
+		{"unavailable media type", metadataURL(srv, files[0], true), "application/dicom+xml", http.StatusNotAcceptable},
+		{"application/json", metadataURL(srv, files[0], true), "application/json", http.StatusOK},
+		{"invalid tag", bulkData + "7FE0", "", http.StatusBadRequest},
+		{"absent element", bulkData + "00281201", "", http.StatusNotFound},
+		{"not binary", bulkData + "00100010", "", http.StatusNotFound},
+		{"pixel data", bulkData + "7FE00010", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(t, tt.url, tt.accept); resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
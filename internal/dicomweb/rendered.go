@@ -0,0 +1,166 @@
+package dicomweb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dimse"
+)
+
+// Media types of rendered images
+const (
+	mediaJPEG = "image/jpeg"
+	mediaPNG  = "image/png"
+)
+
+// windowFunctions are the VOI LUT functions of the window query parameter
+// (PS3.18 8.3.5.1.4).
+var windowFunctions = map[string]string{
+	"linear":       dimse.VOILinear,
+	"linear-exact": dimse.VOILinearExact,
+	"sigmoid":      dimse.VOISigmoid,
+}
+
+// retrieveRendered answers a WADO-RS rendered retrieval: a frame of the
+// instance, the first one at the instance level, rendered as image/jpeg or
+// image/png. The window query parameter ("center,width[,function]")
+// overrides the window of the instance, quality (1-100) sets the JPEG
+// quality.
+func (s *Server) retrieveRendered(w http.ResponseWriter, r *http.Request) {
+	inst, ok := s.Index.Lookup(r.PathValue("study"), r.PathValue("series"), r.PathValue("instance"))
+	if !ok {
+		http.Error(w, "instance not found", http.StatusNotFound)
+		return
+	}
+	n := 1
+	if list := r.PathValue("frames"); list != "" {
+		numbers, err := parseFrameList(list)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(numbers) != 1 {
+			http.Error(w, "only a single frame can be rendered", http.StatusBadRequest)
+			return
+		}
+		n = numbers[0]
+	}
+	window, quality, err := parseRenderParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mediaType, ok := renderedMediaType(r.Header.Values("Accept"))
+	if !ok {
+		http.Error(w, "rendered images are only available as image/jpeg or image/png", http.StatusNotAcceptable)
+		return
+	}
+
+	img, err := inst.Render(n, window)
+	switch {
+	case errors.Is(err, dimse.ErrNoSuchFrame):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, dimse.ErrNotRenderable):
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		return
+	case err != nil:
+		s.logf("WADO-RS rendering of %s failed: %v", inst.SOPInstanceUID, err)
+		http.Error(w, "render: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeImage(w, img, mediaType, quality); err != nil {
+		http.Error(w, "encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.logf("WADO-RS rendered frame %d of %s as %s", n, inst.SOPInstanceUID, mediaType)
+}
+
+// writeImage encodes img as image/jpeg, with quality, or image/png, and
+// writes it as the response. Nothing is written when encoding fails.
+func writeImage(w http.ResponseWriter, img image.Image, mediaType string, quality int) error {
+	var buf bytes.Buffer
+	var err error
+	if mediaType == mediaPNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	_, _ = w.Write(buf.Bytes())
+	return nil
+}
+
+// parseRenderParams parses the window and quality query parameters of a
+// rendered retrieval. The window is nil when absent, the quality defaults
+// to 90.
+func parseRenderParams(query url.Values) (*dimse.Window, int, error) {
+	quality := 90
+	if q := query.Get("quality"); q != "" {
+		n, err := strconv.Atoi(q)
+		if err != nil || n < 1 || n > 100 {
+			return nil, 0, fmt.Errorf("invalid quality %q (expected 1 to 100)", q)
+		}
+		quality = n
+	}
+	param := query.Get("window")
+	if param == "" {
+		return nil, quality, nil
+	}
+	fields := strings.Split(param, ",")
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, 0, fmt.Errorf("invalid window %q (expected center,width[,function])", param)
+	}
+	center, err1 := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+	width, err2 := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+	if err1 != nil || err2 != nil || width <= 0 {
+		return nil, 0, fmt.Errorf("invalid window %q (expected a number center and a positive width)", param)
+	}
+	window := &dimse.Window{Center: center, Width: width, Function: dimse.VOILinear}
+	if len(fields) == 3 {
+		function, ok := windowFunctions[strings.ToLower(strings.TrimSpace(fields[2]))]
+		if !ok {
+			return nil, 0, fmt.Errorf("invalid window function %q (expected linear, linear-exact or sigmoid)", fields[2])
+		}
+		window.Function = function
+	}
+	return window, quality, nil
+}
+
+// renderedMediaType returns the media type of a rendered image accepted by
+// the Accept header values, in their order: image/jpeg when absent or for
+// */* and image/*, image/png when asked for.
+func renderedMediaType(accept []string) (string, bool) {
+	if len(accept) == 0 {
+		return mediaJPEG, true
+	}
+	for _, value := range accept {
+		for _, field := range strings.Split(value, ",") {
+			t, _, err := mime.ParseMediaType(strings.TrimSpace(field))
+			if err != nil {
+				continue
+			}
+			switch t {
+			case mediaJPEG, "*/*", "image/*":
+				return mediaJPEG, true
+			case mediaPNG:
+				return mediaPNG, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,125 @@
+package dicomweb
+
+import (
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+)
+
+// renderedURL returns the rendered endpoint of a generated file, or of one
+// of its frames.
+func renderedURL(srv *httptest.Server, file internaldicom.GeneratedFile, frames string) string {
+	if frames == "" {
+		return srv.URL + "/studies/" + file.StudyUID + "/series/" + file.SeriesUID + "/instances/" + file.SOPInstanceUID + "/rendered"
+	}
+	return framesURL(srv, file, frames) + "/rendered"
+}
+
+// decodePNG decodes a rendered PNG response.
+func decodePNG(t *testing.T, resp *http.Response) image.Image {
+	t.Helper()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("status = %d, Content-Type = %q, want 200 image/png", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("png.Decode failed: %v", err)
+	}
+	return img
+}
+
+func TestRetrieveRendered(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	frames := nativeFrames(t, files[0].Path)
+
+	resp := get(t, renderedURL(srv, files[0], ""), "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("status = %d, Content-Type = %q, want 200 image/jpeg", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	img, err := jpeg.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("jpeg.Decode failed: %v", err)
+	}
+	size := img.Bounds().Dx() * img.Bounds().Dy()
+	if size != len(frames[0]) {
+		t.Fatalf("rendered %v, want %d pixels", img.Bounds(), len(frames[0]))
+	}
+
+	// A linear window of center 128 and width 256 keeps 8-bit values
+	gray, ok := decodePNG(t, get(t, renderedURL(srv, files[0], "3")+"?window=128,256,linear", "image/png")).(*image.Gray)
+	if !ok {
+		t.Fatal("rendered PNG is not grayscale")
+	}
+	if string(gray.Pix) != string(frames[2]) {
+		t.Error("rendered frame 3 differs from its pixel data")
+	}
+}
+
+func TestRetrieveRendered_Monochrome1(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{Photometric: internaldicom.PhotometricMonochrome1})
+	frame := nativeFrames(t, files[0].Path)[0]
+
+	gray := decodePNG(t, get(t, renderedURL(srv, files[0], "1")+"?window=128,256", "image/png")).(*image.Gray)
+	for i, v := range gray.Pix {
+		if v != 255-frame[i] {
+			t.Fatalf("pixel %d = %d, want %d inverted", i, v, frame[i])
+		}
+	}
+}
+
+func TestRetrieveRendered_Color(t *testing.T) {
+	for _, photometric := range []string{internaldicom.PhotometricRGB, internaldicom.PhotometricYBRFull422, internaldicom.PhotometricPaletteColor} {
+		t.Run(photometric, func(t *testing.T) {
+			srv, files := startServer(t, internaldicom.GeneratorOptions{USColor: photometric})
+			img := decodePNG(t, get(t, renderedURL(srv, files[0], "2"), "image/png"))
+			colored := false
+			bounds := img.Bounds()
+			for y := bounds.Min.Y; y < bounds.Max.Y && !colored; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					r, g, b, _ := img.At(x, y).RGBA()
+					if r>>8 > g>>8+64 || b>>8 > g>>8+64 {
+						colored = true
+						break
+					}
+				}
+			}
+			if !colored {
+				t.Error("no color Doppler pixel in the rendered image")
+			}
+		})
+	}
+}
+
+func TestRetrieveRendered_Errors(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	unknown := files[0]
+	unknown.SOPInstanceUID = "1.2.3"
+
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   int
+	}{
+		{"unknown instance", renderedURL(srv, unknown, ""), "", http.StatusNotFound},
+		{"frame out of range", renderedURL(srv, files[0], "5"), "", http.StatusNotFound},
+		{"frame list", renderedURL(srv, files[0], "1,2"), "", http.StatusBadRequest},
+		{"invalid window", renderedURL(srv, files[0], "1") + "?window=128", "", http.StatusBadRequest},
+		{"invalid window function", renderedURL(srv, files[0], "1") + "?window=128,256,log", "", http.StatusBadRequest},
+		{"invalid quality", renderedURL(srv, files[0], "") + "?quality=0", "", http.StatusBadRequest},
+		{"unavailable media type", renderedURL(srv, files[0], ""), "image/gif", http.StatusNotAcceptable},
+		{"any image", renderedURL(srv, files[0], "") + "?quality=50", "image/gif, image/*", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(t, tt.url, tt.accept); resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package dicomweb
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+
+	"github.com/mrsinham/dicomforge/internal/dimse"
+)
+
+// mediaDICOM is the media type of DICOM files (PS3.10) in WADO-RS.
+const mediaDICOM = "application/dicom"
+
+// retrieveInstances answers a WADO-RS retrieval of a study, a series or an
+// instance: its files, as stored, in a multipart/related response with one
+// application/dicom part per instance. Instances are not transcoded: an Accept
+// header asking for a transfer syntax that is not the one of every instance
+// is answered with 406.
+func (s *Server) retrieveInstances(w http.ResponseWriter, r *http.Request) {
+	study, series, instance := r.PathValue("study"), r.PathValue("series"), r.PathValue("instance")
+	var instances []*dimse.Instance
+	if instance != "" {
+		if inst, ok := s.Index.Lookup(study, series, instance); ok {
+			instances = append(instances, inst)
+		}
+	} else {
+		instances = s.Index.Instances(study, series)
+	}
+	if len(instances) == 0 {
+		http.Error(w, "no instance found", http.StatusNotFound)
+		return
+	}
+
+	// The transfer syntax of the response, when shared by every instance
+	transferSyntax := instances[0].TransferSyntaxUID
+	for _, inst := range instances {
+		if !acceptable(r.Header.Values("Accept"), mediaDICOM, inst.TransferSyntaxUID) {
+			http.Error(w, fmt.Sprintf("instance %s is only available as %s with transfer syntax %s", inst.SOPInstanceUID, mediaDICOM, inst.TransferSyntaxUID), http.StatusNotAcceptable)
+			return
+		}
+		if inst.TransferSyntaxUID != transferSyntax {
+			transferSyntax = ""
+		}
+	}
+
+	if err := writeFiles(w, instances, transferSyntax); err != nil {
+		s.logf("WADO-RS retrieval of %s interrupted: %v", r.URL.Path, err)
+		return
+	}
+	s.logf("WADO-RS retrieval of %d instance(s): %s", len(instances), r.URL.Path)
+}
+
+// writeFiles writes a multipart/related response with the file of each
+// instance as an application/dicom part, streamed from disk. transferSyntax,
+// when not "", is announced for the whole response.
+func writeFiles(w http.ResponseWriter, instances []*dimse.Instance, transferSyntax string) error {
+	mw := multipart.NewWriter(w)
+	params := map[string]string{"type": mediaDICOM, "boundary": mw.Boundary()}
+	if transferSyntax != "" {
+		params["transfer-syntax"] = transferSyntax
+	}
+	w.Header().Set("Content-Type", mime.FormatMediaType("multipart/related", params))
+	for _, inst := range instances {
+		if err := writeFile(mw, inst); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeFile writes the file of an instance as a part.
+func writeFile(mw *multipart.Writer, inst *dimse.Instance) error {
+	f, err := os.Open(inst.Path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":   {mime.FormatMediaType(mediaDICOM, map[string]string{"transfer-syntax": inst.TransferSyntaxUID})},
+		"Content-Length": {strconv.FormatInt(info.Size(), 10)},
+		"Content-Location": {fmt.Sprintf("/studies/%s/series/%s/instances/%s",
+			inst.StudyInstanceUID, inst.SeriesInstanceUID, inst.SOPInstanceUID)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
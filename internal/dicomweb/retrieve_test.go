@@ -0,0 +1,135 @@
+package dicomweb
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+)
+
+// instancesURL returns the retrieval endpoint of the study of a generated
+// file, of its series with series, or of the file with instance.
+func instancesURL(srv *httptest.Server, file internaldicom.GeneratedFile, series, instance bool) string {
+	url := srv.URL + "/studies/" + file.StudyUID
+	if series || instance {
+		url += "/series/" + file.SeriesUID
+	}
+	if instance {
+		url += "/instances/" + file.SOPInstanceUID
+	}
+	return url
+}
+
+func TestRetrieveInstances(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	generated := make(map[string][]byte)
+	inSeries := 0
+	for _, f := range files {
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated[f.SOPInstanceUID] = data
+		if f.SeriesUID == files[0].SeriesUID {
+			inSeries++
+		}
+	}
+
+	tests := []struct {
+		name             string
+		series, instance bool
+		want             int
+	}{
+		{"study", false, false, len(files)},
+		{"series", true, false, inSeries},
+		{"instance", true, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, instancesURL(srv, files[0], tt.series, tt.instance), `multipart/related; type="application/dicom"`)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+			_, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+			if params["type"] != "application/dicom" || params["transfer-syntax"] != "1.2.840.10008.1.2.1" {
+				t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+			}
+			types, parts := readParts(t, resp)
+			if len(parts) != tt.want {
+				t.Fatalf("got %d parts, want %d", len(parts), tt.want)
+			}
+			for i, part := range parts {
+				if mediaType, _, _ := mime.ParseMediaType(types[i]); mediaType != "application/dicom" {
+					t.Errorf("part %d Content-Type = %q", i, types[i])
+				}
+				ds, err := dicom.Parse(bytes.NewReader(part), int64(len(part)), nil, dicom.SkipPixelData())
+				if err != nil {
+					t.Fatalf("parse part %d: %v", i, err)
+				}
+				uid := firstString(ds, tag.SOPInstanceUID)
+				if !bytes.Equal(part, generated[uid]) {
+					t.Errorf("part %d (%s) differs from the generated file", i, uid)
+				}
+			}
+		})
+	}
+}
+
+func TestRetrieveInstances_Errors(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	unknown := files[0]
+	unknown.SOPInstanceUID = "1.2.3"
+
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   int
+	}{
+		{"unknown study", srv.URL + "/studies/1.2.3", "", http.StatusNotFound},
+		{"unknown instance", instancesURL(srv, unknown, true, true), "", http.StatusNotFound},
+		{"unavailable media type", instancesURL(srv, files[0], false, false), `multipart/related; type="application/octet-stream"`, http.StatusNotAcceptable},
+		{"unavailable transfer syntax", instancesURL(srv, files[0], false, false), `multipart/related; type="application/dicom"; transfer-syntax=1.2.840.10008.1.2.4.50`, http.StatusNotAcceptable},
+		{"stored transfer syntax", instancesURL(srv, files[0], false, false), `multipart/related; type="application/dicom"; transfer-syntax=1.2.840.10008.1.2.1`, http.StatusOK},
+		{"any transfer syntax", instancesURL(srv, files[0], false, false), `multipart/related; type="application/dicom"; transfer-syntax=*`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(t, tt.url, tt.accept); resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_CORS(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+
+	req, err := http.NewRequest(http.MethodOptions, instancesURL(srv, files[0], false, false), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "http://viewer.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "accept")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "*" ||
+		resp.Header.Get("Access-Control-Allow-Headers") != "accept" {
+		t.Errorf("preflight: status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+
+	if resp := get(t, instancesURL(srv, files[0], true, true)+"/metadata", ""); resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("metadata response has no Access-Control-Allow-Origin")
+	}
+}
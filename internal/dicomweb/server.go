@@ -0,0 +1,233 @@
+// Package dicomweb implements a mock DICOMweb origin server (PS3.18) over the
+// instances of a dimse.Index, and a STOW-RS client uploading them to a real
+// one.
+package dicomweb
+
+import (
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mrsinham/dicomforge/internal/dimse"
+)
+
+// Transfer syntaxes of native pixel data
+const (
+	explicitVRLittleEndian = "1.2.840.10008.1.2.1"
+	explicitVRBigEndian    = "1.2.840.10008.1.2.2"
+)
+
+// frameMediaTypes are the media types of the frames of encapsulated
+// transfer syntaxes (PS3.18 Table 8.7.3-2).
+var frameMediaTypes = map[string]string{
+	"1.2.840.10008.1.2.4.50":  "image/jpeg", // JPEG Baseline
+	"1.2.840.10008.1.2.4.51":  "image/jpeg", // JPEG Extended
+	"1.2.840.10008.1.2.4.57":  "image/jpeg", // JPEG Lossless
+	"1.2.840.10008.1.2.4.70":  "image/jpeg", // JPEG Lossless SV1
+	"1.2.840.10008.1.2.4.80":  "image/jls",  // JPEG-LS Lossless
+	"1.2.840.10008.1.2.4.81":  "image/jls",  // JPEG-LS Near-Lossless
+	"1.2.840.10008.1.2.4.90":  "image/jp2",  // JPEG 2000 Lossless
+	"1.2.840.10008.1.2.4.91":  "image/jp2",  // JPEG 2000
+	"1.2.840.10008.1.2.4.92":  "image/jpx",  // JPEG 2000 Part 2 Lossless
+	"1.2.840.10008.1.2.4.93":  "image/jpx",  // JPEG 2000 Part 2
+	"1.2.840.10008.1.2.5":     "image/x-dicom-rle",
+	"1.2.840.10008.1.2.4.201": "image/jphc", // HTJ2K Lossless
+	"1.2.840.10008.1.2.4.202": "image/jphc", // HTJ2K Lossless RPCL
+	"1.2.840.10008.1.2.4.203": "image/jphc", // HTJ2K
+}
+
+// Server is a DICOMweb origin server. It answers WADO-RS retrieval requests
+// of studies, series and instances, at /studies/{study}[/series/{series}
+// [/instances/{instance}]], frame retrieval requests, at
+// .../instances/{instance}/frames/{frames}, rendered retrieval requests of
+// instances and frames, at .../rendered, metadata requests of studies, series
+// and instances, at .../metadata, bulk data requests, at
+// .../instances/{instance}/bulkdata/{tag}, and legacy WADO-URI requests, at
+// /wado?requestType=WADO&studyUID=...&seriesUID=...&objectUID=... Any origin
+// may send requests (CORS), so that browser viewers served from elsewhere
+// can use it.
+type Server struct {
+	Index    *dimse.Index // Instances served
+	Quiet    bool         // Do not log requests
+	BulkData bool         // Reference binary values in metadata with a BulkDataURI instead of inlining them
+
+	once sync.Once
+	mux  *http.ServeMux
+}
+
+// ServeHTTP dispatches a request to its endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.once.Do(func() {
+		s.mux = http.NewServeMux()
+		s.mux.HandleFunc("GET /studies/{study}", s.retrieveInstances)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}", s.retrieveInstances)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}/instances/{instance}", s.retrieveInstances)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}/instances/{instance}/frames/{frames}", s.retrieveFrames)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}/instances/{instance}/rendered", s.retrieveRendered)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}/instances/{instance}/frames/{frames}/rendered", s.retrieveRendered)
+		s.mux.HandleFunc("GET /studies/{study}/metadata", s.retrieveMetadata)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}/metadata", s.retrieveMetadata)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}/instances/{instance}/metadata", s.retrieveMetadata)
+		s.mux.HandleFunc("GET /studies/{study}/series/{series}/instances/{instance}/bulkdata/{tag}", s.retrieveBulkData)
+		s.mux.HandleFunc("GET /wado", s.retrieveWADO)
+	})
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		// CORS preflight of a request with an Accept header
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.Header().Set("Access-Control-Max-Age", "86400")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// logf logs a server event unless the server is quiet.
+func (s *Server) logf(format string, args ...any) {
+	if !s.Quiet {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// retrieveFrames answers a WADO-RS frame retrieval: the requested frames, in
+// request order, as a multipart/related response with one part per frame.
+// Native frames are sent as application/octet-stream in Explicit VR Little
+// Endian (Big Endian when stored so), encapsulated frames in the media type
+// of their transfer syntax.
+func (s *Server) retrieveFrames(w http.ResponseWriter, r *http.Request) {
+	inst, ok := s.Index.Lookup(r.PathValue("study"), r.PathValue("series"), r.PathValue("instance"))
+	if !ok {
+		http.Error(w, "instance not found", http.StatusNotFound)
+		return
+	}
+	numbers, err := parseFrameList(r.PathValue("frames"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	frames, encapsulated, err := inst.Frames()
+	if err != nil {
+		s.logf("WADO-RS frames of %s failed: %v", inst.SOPInstanceUID, err)
+		http.Error(w, "read pixel data: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, n := range numbers {
+		if n > len(frames) {
+			http.Error(w, fmt.Sprintf("frame %d out of range, the instance has %d frame(s)", n, len(frames)), http.StatusNotFound)
+			return
+		}
+	}
+
+	mediaType, transferSyntax := partMediaType(inst, encapsulated)
+	if !acceptable(r.Header.Values("Accept"), mediaType, transferSyntax) {
+		http.Error(w, fmt.Sprintf("frames are only available as %s with transfer syntax %s", mediaType, transferSyntax), http.StatusNotAcceptable)
+		return
+	}
+	parts := make([][]byte, len(numbers))
+	for i, n := range numbers {
+		parts[i] = frames[n-1]
+	}
+	if err := writeParts(w, mediaType, transferSyntax, parts); err != nil {
+		s.logf("WADO-RS frames of %s interrupted: %v", inst.SOPInstanceUID, err)
+		return
+	}
+	s.logf("WADO-RS frames of %s: %s", inst.SOPInstanceUID, r.PathValue("frames"))
+}
+
+// partMediaType returns the media type and transfer syntax of the binary
+// parts of an instance: application/octet-stream in Explicit VR Little
+// Endian (Big Endian when stored so), or the media type of the transfer
+// syntax of encapsulated frames.
+func partMediaType(inst *dimse.Instance, encapsulated bool) (mediaType, transferSyntax string) {
+	mediaType, transferSyntax = "application/octet-stream", explicitVRLittleEndian
+	if inst.TransferSyntaxUID == explicitVRBigEndian {
+		transferSyntax = explicitVRBigEndian
+	}
+	if encapsulated {
+		transferSyntax = inst.TransferSyntaxUID
+		if t, ok := frameMediaTypes[transferSyntax]; ok {
+			mediaType = t
+		}
+	}
+	return mediaType, transferSyntax
+}
+
+// writeParts writes a multipart/related response with one part per value.
+func writeParts(w http.ResponseWriter, mediaType, transferSyntax string, parts [][]byte) error {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", mime.FormatMediaType("multipart/related", map[string]string{
+		"type":            mediaType,
+		"transfer-syntax": transferSyntax,
+		"boundary":        mw.Boundary(),
+	}))
+	partType := mime.FormatMediaType(mediaType, map[string]string{"transfer-syntax": transferSyntax})
+	for _, data := range parts {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":   {partType},
+			"Content-Length": {strconv.Itoa(len(data))},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(data); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// parseFrameList parses a comma separated list of frame numbers, from 1.
+func parseFrameList(s string) ([]int, error) {
+	var numbers []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid frame number %q in frame list %q", field, s)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// acceptable reports whether the Accept header values allow a multipart
+// response of mediaType parts in transferSyntax. No Accept header, */* and
+// multipart/related without type accept any; a transfer-syntax of "*"
+// accepts any transfer syntax.
+func acceptable(accept []string, mediaType, transferSyntax string) bool {
+	if len(accept) == 0 {
+		return true
+	}
+	for _, value := range accept {
+		for _, field := range strings.Split(value, ",") {
+			t, params, err := mime.ParseMediaType(strings.TrimSpace(field))
+			if err != nil {
+				continue
+			}
+			switch t {
+			case "*/*", "multipart/*":
+				return true
+			case "multipart/related":
+				partType, ok := params["type"]
+				if !ok {
+					return true
+				}
+				ts, ok := params["transfer-syntax"]
+				if (partType == mediaType || partType == "*/*") && (!ok || ts == "*" || ts == transferSyntax) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
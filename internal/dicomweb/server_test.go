@@ -0,0 +1,265 @@
+package dicomweb
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/dimse"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// startServer generates US cine loops of 4 frames with opts and serves them.
+func startServer(t *testing.T, opts internaldicom.GeneratorOptions) (*httptest.Server, []internaldicom.GeneratedFile) {
+	t.Helper()
+	ix, files := generateIndex(t, opts)
+	srv := httptest.NewServer(&Server{Index: ix, Quiet: true})
+	t.Cleanup(srv.Close)
+	return srv, files
+}
+
+// generateIndex generates US cine loops of 4 frames with opts, two per
+// series, and indexes them.
+func generateIndex(t *testing.T, opts internaldicom.GeneratorOptions) (*dimse.Index, []internaldicom.GeneratedFile) {
+	t.Helper()
+	dir := t.TempDir()
+	opts.TotalSize, opts.OutputDir, opts.Seed, opts.Quiet = "1MB", dir, 42, true
+	opts.Modality, opts.CineFrames, opts.NumStudies = modalities.US, 4, 1
+	opts.ImagesPerSeries = util.ImageRange{Min: 2, Max: 2}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ix, err := dimse.NewIndex(dir)
+	if err != nil {
+		t.Fatalf("NewIndex failed: %v", err)
+	}
+	return ix, files
+}
+
+// framesURL returns the frames endpoint of a generated file.
+func framesURL(srv *httptest.Server, file internaldicom.GeneratedFile, frames string) string {
+	return srv.URL + "/studies/" + file.StudyUID + "/series/" + file.SeriesUID + "/instances/" + file.SOPInstanceUID + "/frames/" + frames
+}
+
+// get sends a GET request with an optional Accept header.
+func get(t *testing.T, url, accept string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// readParts reads a multipart/related response and returns its parts.
+func readParts(t *testing.T, resp *http.Response) ([]string, [][]byte) {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" {
+		t.Fatalf("Content-Type = %q, want multipart/related", resp.Header.Get("Content-Type"))
+	}
+	var types []string
+	var parts [][]byte
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return types, parts
+		}
+		if err != nil {
+			t.Fatalf("NextPart failed: %v", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		parts = append(parts, data)
+	}
+}
+
+// nativeFrames returns the frames of a generated file parsed by the library.
+func nativeFrames(t *testing.T, path string) [][]byte {
+	t.Helper()
+	ds, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	elem, err := ds.FindElementByTag(tag.PixelData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frames [][]byte
+	for _, f := range dicom.MustGetPixelDataInfo(elem.Value).Frames {
+		native, err := f.GetNativeFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, native.RawDataSlice().([]uint8))
+	}
+	return frames
+}
+
+func TestRetrieveFrames(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	want := nativeFrames(t, files[0].Path)
+	if len(want) != 4 {
+		t.Fatalf("generated %d frames, want 4", len(want))
+	}
+
+	resp := get(t, framesURL(srv, files[0], "3,1"), "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	_, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if params["type"] != "application/octet-stream" || params["transfer-syntax"] != "1.2.840.10008.1.2.1" {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+	types, parts := readParts(t, resp)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+	for i, n := range []int{3, 1} {
+		if !strings.HasPrefix(types[i], "application/octet-stream") {
+			t.Errorf("part %d Content-Type = %q", i, types[i])
+		}
+		if string(parts[i]) != string(want[n-1]) {
+			t.Errorf("part %d (%d bytes) differs from frame %d (%d bytes)", i, len(parts[i]), n, len(want[n-1]))
+		}
+	}
+}
+
+func TestRetrieveFrames_YBRFull422(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{USColor: internaldicom.PhotometricYBRFull422})
+	ds, err := dicom.ParseFile(files[0].Path, nil, dicom.SkipPixelData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := ds.FindElementByTag(tag.Rows)
+	columns, _ := ds.FindElementByTag(tag.Columns)
+	size := rows.Value.GetValue().([]int)[0] * columns.Value.GetValue().([]int)[0] * 2
+
+	_, parts := readParts(t, get(t, framesURL(srv, files[0], "1,2,3,4"), ""))
+	if len(parts) != 4 {
+		t.Fatalf("got %d parts, want 4", len(parts))
+	}
+	for i, part := range parts {
+		if len(part) != size {
+			t.Errorf("frame %d has %d bytes, want %d", i+1, len(part), size)
+		}
+	}
+}
+
+func TestRetrieveFrames_Errors(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	unknown := files[0]
+	unknown.SOPInstanceUID = "1.2.3"
+
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   int
+	}{
+		{"unknown instance", framesURL(srv, unknown, "1"), "", http.StatusNotFound},
+		{"frame out of range", framesURL(srv, files[0], "1,5"), "", http.StatusNotFound},
+		{"frame zero", framesURL(srv, files[0], "0"), "", http.StatusBadRequest},
+		{"invalid frame list", framesURL(srv, files[0], "1,a"), "", http.StatusBadRequest},
+		{"unavailable media type", framesURL(srv, files[0], "1"), `multipart/related; type="image/jpeg"`, http.StatusNotAcceptable},
+		{"unavailable transfer syntax", framesURL(srv, files[0], "1"), `multipart/related; type="application/octet-stream"; transfer-syntax=1.2.840.10008.1.2.4.50`, http.StatusNotAcceptable},
+		{"any transfer syntax", framesURL(srv, files[0], "1"), `multipart/related; type="application/octet-stream"; transfer-syntax=*`, http.StatusOK},
+		{"any type", framesURL(srv, files[0], "1"), `multipart/related; type="image/jpeg", */*`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(t, tt.url, tt.accept); resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrieveFrames_Encapsulated(t *testing.T) {
+	dir := t.TempDir()
+	jpegs := [][]byte{{0xFF, 0xD8, 0x01, 0xFF, 0xD9, 0x00}, {0xFF, 0xD8, 0x02, 0x03, 0xFF, 0xD9}}
+	pixelData := mustElement(t, tag.PixelData, dicom.PixelDataInfo{
+		IsEncapsulated: true,
+		Frames: []*frame.Frame{
+			{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: jpegs[0]}},
+			{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: jpegs[1]}},
+		},
+	})
+	pixelData.ValueLength = tag.VLUndefinedLength
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.7"}),
+		mustElement(t, tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4"}),
+		mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.4.50"}),
+		mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.7"}),
+		mustElement(t, tag.SOPInstanceUID, []string{"1.2.3.4"}),
+		mustElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+		mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3.1"}),
+		mustElement(t, tag.Rows, []int{8}),
+		mustElement(t, tag.Columns, []int{8}),
+		mustElement(t, tag.NumberOfFrames, []string{"2"}),
+		pixelData,
+	}}
+	f, err := os.Create(filepath.Join(dir, "IM000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dicom.Write(f, ds, dicom.SkipVRVerification()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	_ = f.Close()
+
+	ix, err := dimse.NewIndex(dir)
+	if err != nil {
+		t.Fatalf("NewIndex failed: %v", err)
+	}
+	srv := httptest.NewServer(&Server{Index: ix, Quiet: true})
+	t.Cleanup(srv.Close)
+
+	file := internaldicom.GeneratedFile{StudyUID: "1.2.3", SeriesUID: "1.2.3.1", SOPInstanceUID: "1.2.3.4"}
+	resp := get(t, framesURL(srv, file, "2,1"), `multipart/related; type="image/jpeg"`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	types, parts := readParts(t, resp)
+	if len(parts) != 2 || string(parts[0]) != string(jpegs[1]) || string(parts[1]) != string(jpegs[0]) {
+		t.Fatalf("parts = %v, want frames 2 and 1", parts)
+	}
+	if want := `image/jpeg; transfer-syntax=1.2.840.10008.1.2.4.50`; types[0] != want {
+		t.Errorf("part Content-Type = %q, want %q", types[0], want)
+	}
+}
+
+// mustElement creates an element or fails the test.
+func mustElement(t *testing.T, tg tag.Tag, data any) *dicom.Element {
+	t.Helper()
+	elem, err := dicom.NewElement(tg, data)
+	if err != nil {
+		t.Fatalf("NewElement %v: %v", tg, err)
+	}
+	return elem
+}
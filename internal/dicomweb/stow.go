@@ -0,0 +1,258 @@
+package dicomweb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/mrsinham/dicomforge/internal/dicom/dicomjson"
+	"github.com/mrsinham/dicomforge/internal/dimse"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// DefaultStowBatchSize is the largest number of instances of a STOW-RS request.
+const DefaultStowBatchSize = 50
+
+// StowClient uploads DICOM files to a DICOMweb origin server with STOW-RS
+// (PS3.18 10.5): POST requests to {URL}/studies with a multipart/related body
+// of one application/dicom part per file. Instances are sent study by study,
+// at most BatchSize per request, so that a rejected request concerns a single
+// study.
+type StowClient struct {
+	URL        string       // Service root, e.g. https://archive.example.org/dicom-web
+	Header     http.Header  // Added to every request (e.g. Authorization)
+	BatchSize  int          // Instances per request (0 = DefaultStowBatchSize)
+	HTTPClient *http.Client // nil = http.DefaultClient
+	Quiet      bool         // Do not log requests
+}
+
+// StowResult is the outcome of an upload.
+type StowResult struct {
+	Requests int           // STOW-RS requests sent
+	Stored   int           // Instances stored by the server
+	Warnings int           // Stored instances with a WarningReason (e.g. coerced attributes)
+	Bytes    int64         // Bytes of DICOM files sent
+	Failed   []StowFailure // Instances the server did not store
+}
+
+// StowFailure is an instance the server did not store.
+type StowFailure struct {
+	Path           string
+	SOPInstanceUID string
+	Reason         uint16 // FailureReason of the response, 0 when not given
+}
+
+// stowItem is a response, or an item of its sequences, in the DICOM JSON
+// model. Values are decoded when read.
+type stowItem map[string]struct {
+	Value []json.RawMessage `json:"Value"`
+}
+
+// items returns the items of a sequence.
+func (it stowItem) items(t tag.Tag) []stowItem {
+	var items []stowItem
+	for _, raw := range it[dicomjson.Key(t)].Value {
+		var item stowItem
+		if json.Unmarshal(raw, &item) == nil {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// str returns the first value of a string attribute, or "".
+func (it stowItem) str(t tag.Tag) string {
+	var s string
+	if values := it[dicomjson.Key(t)].Value; len(values) > 0 {
+		_ = json.Unmarshal(values[0], &s)
+	}
+	return s
+}
+
+// number returns the first value of a US attribute, if present.
+func (it stowItem) number(t tag.Tag) (uint16, bool) {
+	var n uint16
+	values := it[dicomjson.Key(t)].Value
+	return n, len(values) > 0 && json.Unmarshal(values[0], &n) == nil
+}
+
+// Store uploads the files of instances. It returns an error when a request
+// fails as a whole (unreachable server, authentication, unsupported media
+// type...); instances refused by the server are reported in the result.
+func (c *StowClient) Store(ctx context.Context, instances []*dimse.Instance) (*StowResult, error) {
+	size := c.BatchSize
+	if size < 0 {
+		return nil, fmt.Errorf("invalid batch size %d", size)
+	}
+	if size == 0 {
+		size = DefaultStowBatchSize
+	}
+
+	result := &StowResult{}
+	batches := stowBatches(instances, size)
+	for i, batch := range batches {
+		failed := len(result.Failed)
+		if err := c.storeBatch(ctx, batch, result); err != nil {
+			return result, fmt.Errorf("STOW-RS request %d of %d: %w", i+1, len(batches), err)
+		}
+		c.logf("STOW-RS %d/%d: study %s, %d instances, %d failed",
+			i+1, len(batches), batch[0].StudyInstanceUID, len(batch), len(result.Failed)-failed)
+	}
+	return result, nil
+}
+
+// stowBatches groups instances by study, in order of first appearance, and
+// splits each study into batches of at most size instances.
+func stowBatches(instances []*dimse.Instance, size int) [][]*dimse.Instance {
+	var studies []string
+	byStudy := make(map[string][]*dimse.Instance)
+	for _, inst := range instances {
+		if _, ok := byStudy[inst.StudyInstanceUID]; !ok {
+			studies = append(studies, inst.StudyInstanceUID)
+		}
+		byStudy[inst.StudyInstanceUID] = append(byStudy[inst.StudyInstanceUID], inst)
+	}
+	var batches [][]*dimse.Instance
+	for _, study := range studies {
+		for rest := byStudy[study]; len(rest) > 0; {
+			n := min(size, len(rest))
+			batches = append(batches, rest[:n])
+			rest = rest[n:]
+		}
+	}
+	return batches
+}
+
+// storeBatch sends one STOW-RS request, streaming the files of batch, and
+// adds its outcome to result.
+func (c *StowClient) storeBatch(ctx context.Context, batch []*dimse.Instance, result *StowResult) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	written := make(chan int64, 1)
+	go func() {
+		n, err := writeStowParts(mw, batch)
+		written <- n
+		_ = pw.CloseWithError(err)
+	}()
+	// Unblock the writer if the server answers before reading the whole body
+	defer func() { _ = pr.Close() }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.URL, "/")+"/studies", pr)
+	if err != nil {
+		return err
+	}
+	for key, values := range c.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	req.Header.Set("Content-Type", mime.FormatMediaType("multipart/related", map[string]string{
+		"type":     "application/dicom",
+		"boundary": mw.Boundary(),
+	}))
+	req.Header.Set("Accept", "application/dicom+json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	result.Requests++
+	_ = pr.Close()
+	result.Bytes += <-written
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusConflict:
+	default:
+		return fmt.Errorf("%s: %s", resp.Status, snippet(body))
+	}
+	var response stowItem
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &response); err != nil {
+			return fmt.Errorf("%s: invalid response: %w", resp.Status, err)
+		}
+	}
+
+	// Instances are stored when referenced by the response, or all of them on
+	// 200 OK without references; 409 Conflict stores none.
+	reasons := make(map[string]uint16)
+	for _, item := range response.items(tag.FailedSOPSequence) {
+		reason, _ := item.number(tag.FailureReason)
+		reasons[item.str(tag.ReferencedSOPInstanceUID)] = reason
+	}
+	stored := make(map[string]bool)
+	for _, item := range response.items(tag.ReferencedSOPSequence) {
+		uid := item.str(tag.ReferencedSOPInstanceUID)
+		stored[uid] = true
+		if _, warned := item.number(tag.WarningReason); warned {
+			result.Warnings++
+		}
+	}
+	storedAll := resp.StatusCode == http.StatusOK && len(stored) == 0
+	for _, inst := range batch {
+		reason, failed := reasons[inst.SOPInstanceUID]
+		if !failed && (stored[inst.SOPInstanceUID] || storedAll) {
+			result.Stored++
+			continue
+		}
+		result.Failed = append(result.Failed, StowFailure{Path: inst.Path, SOPInstanceUID: inst.SOPInstanceUID, Reason: reason})
+	}
+	return nil
+}
+
+// writeStowParts writes the files of batch as application/dicom parts and
+// closes the multipart body. It returns the number of file bytes written.
+func writeStowParts(mw *multipart.Writer, batch []*dimse.Instance) (int64, error) {
+	var n int64
+	for _, inst := range batch {
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/dicom"}})
+		if err != nil {
+			return n, err
+		}
+		f, err := os.Open(inst.Path)
+		if err != nil {
+			return n, err
+		}
+		copied, err := io.Copy(part, f)
+		_ = f.Close()
+		n += copied
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, mw.Close()
+}
+
+// snippet returns the beginning of an error response body, on one line.
+func snippet(body []byte) string {
+	s := strings.Join(strings.Fields(string(body)), " ")
+	if len(s) > 200 {
+		s = s[:200] + "..."
+	}
+	if s == "" {
+		return "empty response"
+	}
+	return s
+}
+
+// logf logs an upload event unless the client is quiet.
+func (c *StowClient) logf(format string, args ...any) {
+	if !c.Quiet {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
@@ -0,0 +1,244 @@
+package dicomweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/dicom/dicomjson"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/dimse"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// stowServer is a STOW-RS endpoint recording the instances it receives, and
+// refusing those of reject.
+type stowServer struct {
+	t      *testing.T
+	reject map[string]bool
+
+	mu       sync.Mutex
+	requests int
+	stored   map[string]string // SOP Instance UID -> Study Instance UID
+}
+
+func (s *stowServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/dicom-web/studies" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" || params["type"] != "application/dicom" {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var referenced, failed []any
+	studies := make(map[string]bool)
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.t.Errorf("NextPart failed: %v", err)
+			return
+		}
+		data, err := io.ReadAll(part)
+		if err != nil || part.Header.Get("Content-Type") != "application/dicom" {
+			s.t.Errorf("part %q: %v", part.Header.Get("Content-Type"), err)
+			return
+		}
+		ds, err := dicom.Parse(bytes.NewReader(data), int64(len(data)), nil, dicom.SkipPixelData())
+		if err != nil {
+			s.t.Errorf("parse part: %v", err)
+			return
+		}
+		uid, study := firstString(ds, tag.SOPInstanceUID), firstString(ds, tag.StudyInstanceUID)
+		studies[study] = true
+		item := dicomjson.Object{dicomjson.Key(tag.ReferencedSOPInstanceUID): {VR: "UI", Value: []any{uid}}}
+		if s.reject[uid] {
+			item[dicomjson.Key(tag.FailureReason)] = dicomjson.Attribute{VR: "US", Value: []any{0xC000}}
+			failed = append(failed, item)
+			continue
+		}
+		referenced = append(referenced, item)
+		s.mu.Lock()
+		s.stored[uid] = study
+		s.mu.Unlock()
+	}
+	s.mu.Lock()
+	s.requests++
+	s.mu.Unlock()
+	if len(studies) != 1 {
+		s.t.Errorf("request holds %d studies, want 1", len(studies))
+	}
+
+	response := dicomjson.Object{}
+	status := http.StatusOK
+	if len(referenced) > 0 {
+		response[dicomjson.Key(tag.ReferencedSOPSequence)] = dicomjson.Attribute{VR: "SQ", Value: referenced}
+	}
+	if len(failed) > 0 {
+		response[dicomjson.Key(tag.FailedSOPSequence)] = dicomjson.Attribute{VR: "SQ", Value: failed}
+		status = http.StatusAccepted
+		if len(referenced) == 0 {
+			status = http.StatusConflict
+		}
+	}
+	w.Header().Set("Content-Type", "application/dicom+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// generateStudies generates 3 CT studies and indexes them.
+func generateStudies(t *testing.T) (*dimse.Index, []internaldicom.GeneratedFile) {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		NumImages:  10,
+		TotalSize:  "1MB",
+		OutputDir:  dir,
+		Seed:       42,
+		NumStudies: 3,
+		Modality:   modalities.CT,
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ix, err := dimse.NewIndex(dir)
+	if err != nil {
+		t.Fatalf("NewIndex failed: %v", err)
+	}
+	return ix, files
+}
+
+func TestStowClient_Store(t *testing.T) {
+	ix, files := generateStudies(t)
+	rejected := files[len(files)-1].SOPInstanceUID
+	server := &stowServer{t: t, reject: map[string]bool{rejected: true}, stored: make(map[string]string)}
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	c := &StowClient{
+		URL:       srv.URL + "/dicom-web/",
+		Header:    http.Header{"Authorization": {"Bearer token"}},
+		BatchSize: 3,
+		Quiet:     true,
+	}
+	result, err := c.Store(context.Background(), ix.All())
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	if result.Stored != len(files)-1 || len(result.Failed) != 1 {
+		t.Fatalf("stored %d and failed %d instances, want %d and 1", result.Stored, len(result.Failed), len(files)-1)
+	}
+	if f := result.Failed[0]; f.SOPInstanceUID != rejected || f.Reason != 0xC000 || f.Path == "" {
+		t.Errorf("failure = %+v, want %s with reason 0xC000", f, rejected)
+	}
+	if result.Requests != server.requests || result.Bytes == 0 {
+		t.Errorf("result has %d requests and %d bytes, server received %d requests", result.Requests, result.Bytes, server.requests)
+	}
+
+	// At least one request per study, none beyond the batch size
+	studies := make(map[string]int)
+	for _, f := range files {
+		studies[f.StudyUID]++
+	}
+	minRequests := 0
+	for _, n := range studies {
+		minRequests += (n + 2) / 3
+	}
+	if result.Requests != minRequests {
+		t.Errorf("sent %d requests, want %d", result.Requests, minRequests)
+	}
+	for _, f := range files {
+		if f.SOPInstanceUID != rejected && server.stored[f.SOPInstanceUID] != f.StudyUID {
+			t.Errorf("%s not stored in study %s", f.SOPInstanceUID, f.StudyUID)
+		}
+	}
+}
+
+func TestStowClient_Store_Errors(t *testing.T) {
+	ix, files := generateStudies(t)
+	srv := httptest.NewServer(&stowServer{t: t, stored: make(map[string]string)})
+	t.Cleanup(srv.Close)
+
+	// Requests refused as a whole are errors
+	for name, c := range map[string]*StowClient{
+		"unauthorized": {URL: srv.URL + "/dicom-web"},
+		"not found":    {URL: srv.URL + "/other", Header: http.Header{"Authorization": {"Bearer token"}}},
+		"batch size":   {URL: srv.URL + "/dicom-web", BatchSize: -1},
+	} {
+		c.Quiet = true
+		if _, err := c.Store(context.Background(), ix.All()); err == nil {
+			t.Errorf("%s: Store succeeded, want an error", name)
+		}
+	}
+
+	// A study refused entirely (409) fails its instances only
+	reject := make(map[string]bool)
+	for _, f := range files {
+		if f.StudyUID == files[0].StudyUID {
+			reject[f.SOPInstanceUID] = true
+		}
+	}
+	srv = httptest.NewServer(&stowServer{t: t, reject: reject, stored: make(map[string]string)})
+	t.Cleanup(srv.Close)
+	c := &StowClient{URL: srv.URL + "/dicom-web", Header: http.Header{"Authorization": {"Bearer token"}}, Quiet: true}
+	result, err := c.Store(context.Background(), ix.All())
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if len(result.Failed) != len(reject) || result.Stored != len(files)-len(reject) {
+		t.Errorf("stored %d and failed %d instances, want %d and %d", result.Stored, len(result.Failed), len(files)-len(reject), len(reject))
+	}
+}
+
+func TestStowBatches(t *testing.T) {
+	var instances []*dimse.Instance
+	for _, study := range []string{"1", "2", "1", "1", "2", "1"} {
+		instances = append(instances, &dimse.Instance{StudyInstanceUID: study})
+	}
+	var sizes []int
+	for _, batch := range stowBatches(instances, 3) {
+		for _, inst := range batch {
+			if inst.StudyInstanceUID != batch[0].StudyInstanceUID {
+				t.Errorf("batch mixes studies %s and %s", batch[0].StudyInstanceUID, inst.StudyInstanceUID)
+			}
+		}
+		sizes = append(sizes, len(batch))
+	}
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 1 || sizes[2] != 2 {
+		t.Errorf("batch sizes = %v, want [3 1 2]", sizes)
+	}
+}
+
+// firstString returns the first value of a string element, or "".
+func firstString(ds dicom.Dataset, tg tag.Tag) string {
+	elem, err := ds.FindElementByTag(tg)
+	if err != nil {
+		return ""
+	}
+	values, _ := elem.Value.GetValue().([]string)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
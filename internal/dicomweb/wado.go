@@ -0,0 +1,208 @@
+package dicomweb
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+
+	"github.com/mrsinham/dicomforge/internal/dimse"
+)
+
+// wadoParams are the parameters of a WADO-URI request.
+type wadoParams struct {
+	studyUID, seriesUID, objectUID string
+	mediaType                      string // application/dicom, image/jpeg or image/png
+	transferSyntax                 string // Requested with application/dicom, "" for any
+	frame                          int
+	window                         *dimse.Window
+	quality                        int
+	rows, columns                  int // Largest size of the image, 0 when not constrained
+}
+
+// retrieveWADO answers a legacy WADO-URI request (PS3.18 Chapter 9):
+// GET /wado?requestType=WADO&studyUID=...&seriesUID=...&objectUID=...,
+// returning the file of the instance, as stored, with
+// contentType=application/dicom, or a frame (frameNumber, the first by
+// default) rendered as image/jpeg, the default, or image/png.
+// windowCenter/windowWidth override the window of the instance,
+// imageQuality sets the JPEG quality, and rows/columns scale the image to fit
+// within that size.
+func (s *Server) retrieveWADO(w http.ResponseWriter, r *http.Request) {
+	p, err := parseWADOParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inst, ok := s.Index.Lookup(p.studyUID, p.seriesUID, p.objectUID)
+	if !ok {
+		http.Error(w, "instance not found", http.StatusNotFound)
+		return
+	}
+	if p.mediaType == "" {
+		http.Error(w, "contentType must allow application/dicom, image/jpeg or image/png", http.StatusNotAcceptable)
+		return
+	}
+
+	if p.mediaType == mediaDICOM {
+		if p.transferSyntax != "" && p.transferSyntax != inst.TransferSyntaxUID {
+			http.Error(w, "the instance is only available with transfer syntax "+inst.TransferSyntaxUID, http.StatusNotAcceptable)
+			return
+		}
+		if err := writeInstance(w, inst); err != nil {
+			s.logf("WADO-URI retrieval of %s failed: %v", inst.SOPInstanceUID, err)
+			return
+		}
+		s.logf("WADO-URI retrieval of %s as %s", inst.SOPInstanceUID, mediaDICOM)
+		return
+	}
+
+	img, err := inst.Render(p.frame, p.window)
+	switch {
+	case errors.Is(err, dimse.ErrNoSuchFrame):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, dimse.ErrNotRenderable):
+		http.Error(w, err.Error()+" (use contentType=application/dicom)", http.StatusNotAcceptable)
+		return
+	case err != nil:
+		s.logf("WADO-URI rendering of %s failed: %v", inst.SOPInstanceUID, err)
+		http.Error(w, "render: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := writeImage(w, fitImage(img, p.rows, p.columns), p.mediaType, p.quality); err != nil {
+		http.Error(w, "encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.logf("WADO-URI rendered frame %d of %s as %s", p.frame, inst.SOPInstanceUID, p.mediaType)
+}
+
+// parseWADOParams parses and checks the query parameters of a WADO-URI
+// request. The media type is "" when contentType allows none of those served.
+func parseWADOParams(query url.Values) (wadoParams, error) {
+	p := wadoParams{
+		studyUID:       query.Get("studyUID"),
+		seriesUID:      query.Get("seriesUID"),
+		objectUID:      query.Get("objectUID"),
+		transferSyntax: query.Get("transferSyntax"),
+		frame:          1,
+		quality:        90,
+	}
+	if requestType := query.Get("requestType"); requestType != "WADO" {
+		return p, fmt.Errorf("invalid requestType %q (expected WADO)", requestType)
+	}
+	if p.studyUID == "" || p.seriesUID == "" || p.objectUID == "" {
+		return p, fmt.Errorf("studyUID, seriesUID and objectUID are required")
+	}
+	p.mediaType = wadoMediaType(query.Get("contentType"))
+
+	ints := []struct {
+		name string
+		dst  *int
+		min  int
+		max  int
+	}{
+		{"frameNumber", &p.frame, 1, math.MaxInt32},
+		{"imageQuality", &p.quality, 1, 100},
+		{"rows", &p.rows, 1, math.MaxInt16},
+		{"columns", &p.columns, 1, math.MaxInt16},
+	}
+	for _, param := range ints {
+		v := query.Get(param.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < param.min || n > param.max {
+			return p, fmt.Errorf("invalid %s %q (expected %d to %d)", param.name, v, param.min, param.max)
+		}
+		*param.dst = n
+	}
+
+	center, width := query.Get("windowCenter"), query.Get("windowWidth")
+	if (center == "") != (width == "") {
+		return p, fmt.Errorf("windowCenter and windowWidth must be given together")
+	}
+	if center != "" {
+		c, err1 := strconv.ParseFloat(center, 64)
+		w, err2 := strconv.ParseFloat(width, 64)
+		if err1 != nil || err2 != nil || w <= 0 {
+			return p, fmt.Errorf("invalid window %s/%s (expected a number center and a positive width)", center, width)
+		}
+		p.window = &dimse.Window{Center: c, Width: w, Function: dimse.VOILinear}
+	}
+	return p, nil
+}
+
+// wadoMediaType returns the first media type of a contentType list that is
+// served: image/jpeg when absent or for */* and image/*.
+func wadoMediaType(contentType string) string {
+	if strings.TrimSpace(contentType) == "" {
+		return mediaJPEG
+	}
+	for _, field := range strings.Split(contentType, ",") {
+		t, _, err := mime.ParseMediaType(strings.TrimSpace(field))
+		if err != nil {
+			continue
+		}
+		switch t {
+		case mediaDICOM, mediaJPEG, mediaPNG:
+			return t
+		case "image/*", "*/*":
+			return mediaJPEG
+		}
+	}
+	return ""
+}
+
+// writeInstance writes the file of an instance as an application/dicom
+// response.
+func writeInstance(w http.ResponseWriter, inst *dimse.Instance) error {
+	f, err := os.Open(inst.Path)
+	if err != nil {
+		http.Error(w, "read instance: "+err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "read instance: "+err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", mediaDICOM)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// fitImage scales img, keeping its aspect ratio, to fit within rows and
+// columns; 0 leaves a dimension unconstrained.
+func fitImage(img image.Image, rows, columns int) image.Image {
+	b := img.Bounds()
+	if (rows == 0 && columns == 0) || b.Empty() {
+		return img
+	}
+	scale := math.Inf(1)
+	if rows > 0 {
+		scale = float64(rows) / float64(b.Dy())
+	}
+	if columns > 0 {
+		scale = min(scale, float64(columns)/float64(b.Dx()))
+	}
+	r := image.Rect(0, 0, max(1, int(math.Round(float64(b.Dx())*scale))), max(1, int(math.Round(float64(b.Dy())*scale))))
+	var dst draw.Image = image.NewRGBA(r)
+	if _, gray := img.(*image.Gray); gray {
+		dst = image.NewGray(r)
+	}
+	draw.BiLinear.Scale(dst, r, img, b, draw.Src, nil)
+	return dst
+}
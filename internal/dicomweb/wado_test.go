@@ -0,0 +1,119 @@
+package dicomweb
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+)
+
+// wadoURL returns the WADO-URI URL of a generated file, with extra query
+// parameters.
+func wadoURL(srv *httptest.Server, file internaldicom.GeneratedFile, extra url.Values) string {
+	query := url.Values{
+		"requestType": {"WADO"},
+		"studyUID":    {file.StudyUID},
+		"seriesUID":   {file.SeriesUID},
+		"objectUID":   {file.SOPInstanceUID},
+	}
+	for k, v := range extra {
+		query[k] = v
+	}
+	return srv.URL + "/wado?" + query.Encode()
+}
+
+func TestRetrieveWADO(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	frames := nativeFrames(t, files[0].Path)
+
+	// The file, as stored
+	resp := get(t, wadoURL(srv, files[0], url.Values{"contentType": {"application/dicom"}}), "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/dicom" {
+		t.Fatalf("status = %d, Content-Type = %q, want 200 application/dicom", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("retrieved %d bytes, differing from the %d bytes of the file", len(got), len(want))
+	}
+
+	// The first frame as a JPEG by default
+	resp = get(t, wadoURL(srv, files[0], nil), "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("status = %d, Content-Type = %q, want 200 image/jpeg", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	img, err := jpeg.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("jpeg.Decode failed: %v", err)
+	}
+	if size := img.Bounds().Dx() * img.Bounds().Dy(); size != len(frames[0]) {
+		t.Errorf("rendered %v, want %d pixels", img.Bounds(), len(frames[0]))
+	}
+
+	// Frame 3 as a PNG through a window keeping 8-bit values
+	gray, ok := decodePNG(t, get(t, wadoURL(srv, files[0], url.Values{
+		"contentType":  {"image/png"},
+		"frameNumber":  {"3"},
+		"windowCenter": {"128"},
+		"windowWidth":  {"256"},
+	}), "")).(*image.Gray)
+	if !ok {
+		t.Fatal("rendered PNG is not grayscale")
+	}
+	if !bytes.Equal(gray.Pix, frames[2]) {
+		t.Error("rendered frame 3 differs from its pixel data")
+	}
+
+	// A thumbnail keeps the aspect ratio
+	thumb := decodePNG(t, get(t, wadoURL(srv, files[0], url.Values{"contentType": {"image/png"}, "rows": {"64"}, "columns": {"64"}}), ""))
+	b, full := thumb.Bounds(), img.Bounds()
+	if max(b.Dx(), b.Dy()) != 64 || b.Dx() > 64 || b.Dy() > 64 {
+		t.Errorf("thumbnail is %v, want to fit 64x64", b)
+	}
+	if ratio, want := float64(b.Dx())/float64(b.Dy()), float64(full.Dx())/float64(full.Dy()); ratio < want*0.9 || ratio > want*1.1 {
+		t.Errorf("thumbnail ratio %.2f, want %.2f", ratio, want)
+	}
+}
+
+func TestRetrieveWADO_Errors(t *testing.T) {
+	srv, files := startServer(t, internaldicom.GeneratorOptions{})
+	unknown := files[0]
+	unknown.SOPInstanceUID = "1.2.3"
+
+	tests := []struct {
+		name string
+		url  string
+		want int
+	}{
+		{"missing requestType", wadoURL(srv, files[0], url.Values{"requestType": {""}}), http.StatusBadRequest},
+		{"missing objectUID", wadoURL(srv, files[0], url.Values{"objectUID": {""}}), http.StatusBadRequest},
+		{"unknown instance", wadoURL(srv, unknown, nil), http.StatusNotFound},
+		{"frame out of range", wadoURL(srv, files[0], url.Values{"frameNumber": {"5"}}), http.StatusNotFound},
+		{"invalid frame", wadoURL(srv, files[0], url.Values{"frameNumber": {"0"}}), http.StatusBadRequest},
+		{"window center alone", wadoURL(srv, files[0], url.Values{"windowCenter": {"40"}}), http.StatusBadRequest},
+		{"invalid quality", wadoURL(srv, files[0], url.Values{"imageQuality": {"101"}}), http.StatusBadRequest},
+		{"unavailable content type", wadoURL(srv, files[0], url.Values{"contentType": {"image/gif"}}), http.StatusNotAcceptable},
+		{"unavailable transfer syntax", wadoURL(srv, files[0], url.Values{"contentType": {"application/dicom"}, "transferSyntax": {"1.2.840.10008.1.2.4.50"}}), http.StatusNotAcceptable},
+		{"first served content type", wadoURL(srv, files[0], url.Values{"contentType": {"image/gif,image/png"}}), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(t, tt.url, ""); resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package dimse
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxPDULength is the maximum P-DATA-TF length announced to peers
+const DefaultMaxPDULength = 16384
+
+// Errors ending the message loop of an association
+var (
+	ErrReleased = errors.New("association released by peer")
+	ErrAborted  = errors.New("association aborted by peer")
+)
+
+// dialTimeout bounds the connection to a remote application entity
+const dialTimeout = 10 * time.Second
+
+// Association is an established association, on the requestor or the
+// acceptor side. Messages are exchanged with ReadMessage and WriteMessage.
+type Association struct {
+	conn       net.Conn
+	callingAE  string
+	calledAE   string
+	contexts   map[byte]PresentationContext // Accepted presentation contexts
+	peerMaxPDU uint32                       // Maximum P-DATA-TF length of the peer (0 = unlimited)
+	maxPDU     uint32                       // Maximum P-DATA-TF length announced to the peer (0 = unlimited)
+
+	writeMu   sync.Mutex
+	messageID uint16 // Last message ID of the requests sent
+}
+
+// Message is a DIMSE message: a command, and a data set encoded in the
+// transfer syntax of its presentation context when Command.HasDataSet is set.
+type Message struct {
+	ContextID byte
+	Command   Command
+	Data      []byte
+}
+
+// CallingAE returns the AE title of the association requestor.
+func (a *Association) CallingAE() string { return a.callingAE }
+
+// CalledAE returns the AE title of the association acceptor.
+func (a *Association) CalledAE() string { return a.calledAE }
+
+// Contexts returns the accepted presentation contexts, ordered by ID.
+func (a *Association) Contexts() []PresentationContext {
+	contexts := make([]PresentationContext, 0, len(a.contexts))
+	for _, pc := range a.contexts {
+		contexts = append(contexts, pc)
+	}
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].ID < contexts[j].ID })
+	return contexts
+}
+
+// Context returns the accepted presentation context with the given ID.
+func (a *Association) Context(id byte) (PresentationContext, bool) {
+	pc, ok := a.contexts[id]
+	return pc, ok
+}
+
+// FindContext returns the first accepted presentation context of the
+// abstract syntax, preferring the given transfer syntax when not empty.
+func (a *Association) FindContext(abstractSyntax, transferSyntax string) (PresentationContext, bool) {
+	var found PresentationContext
+	ok := false
+	for _, pc := range a.Contexts() {
+		if pc.AbstractSyntax != abstractSyntax {
+			continue
+		}
+		if transferSyntax == "" || pc.TransferSyntax == transferSyntax {
+			return pc, true
+		}
+		if !ok {
+			found, ok = pc, true
+		}
+	}
+	if transferSyntax != "" {
+		// The data set cannot be sent in another transfer syntax
+		return PresentationContext{}, false
+	}
+	return found, ok
+}
+
+// nextMessageID returns the ID of a new request.
+func (a *Association) nextMessageID() uint16 {
+	a.messageID++
+	return a.messageID
+}
+
+// ReadMessage reads the next DIMSE message. It returns ErrReleased when the
+// peer releases the association (the release is acknowledged), and
+// ErrAborted when the peer aborts it. A P-DATA-TF PDU longer than the
+// announced maximum length is an error.
+func (a *Association) ReadMessage() (*Message, error) {
+	var msg *Message
+	var command, data []byte
+	commandDone := false
+	for {
+		pduType, body, err := readPDU(a.conn)
+		if err != nil {
+			return nil, err
+		}
+		switch pduType {
+		case pduDataTF:
+			if a.maxPDU > 0 && len(body) > int(a.maxPDU) {
+				return nil, fmt.Errorf("P-DATA-TF of %d bytes exceeds the maximum length %d", len(body), a.maxPDU)
+			}
+		case pduReleaseRQ:
+			a.writeMu.Lock()
+			err := writePDU(a.conn, pduReleaseRP, make([]byte, 4))
+			a.writeMu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrReleased
+		case pduAbort:
+			return nil, ErrAborted
+		default:
+			return nil, fmt.Errorf("unexpected PDU type 0x%02X during association", pduType)
+		}
+
+		pdvs, err := decodePDVs(body)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pdvs {
+			if _, ok := a.contexts[p.contextID]; !ok {
+				return nil, fmt.Errorf("PDV on presentation context %d which was not accepted", p.contextID)
+			}
+			if p.command {
+				if commandDone {
+					return nil, fmt.Errorf("command fragment after the last one")
+				}
+				command = append(command, p.data...)
+				if p.last {
+					cmd, err := decodeCommand(command)
+					if err != nil {
+						return nil, err
+					}
+					msg = &Message{ContextID: p.contextID, Command: cmd}
+					commandDone = true
+					if !cmd.HasDataSet {
+						return msg, nil
+					}
+				}
+				continue
+			}
+			if !commandDone {
+				return nil, fmt.Errorf("data set fragment before the command")
+			}
+			data = append(data, p.data...)
+			if p.last {
+				msg.Data = data
+				return msg, nil
+			}
+		}
+	}
+}
+
+// WriteMessage sends a DIMSE message, fragmented to the peer's maximum PDU
+// length.
+func (a *Association) WriteMessage(msg *Message) error {
+	if _, ok := a.contexts[msg.ContextID]; !ok {
+		return fmt.Errorf("presentation context %d was not accepted", msg.ContextID)
+	}
+	cmd := msg.Command
+	cmd.HasDataSet = msg.Data != nil
+
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	if err := a.writeFragments(msg.ContextID, true, encodeCommand(cmd)); err != nil {
+		return err
+	}
+	if msg.Data != nil {
+		return a.writeFragments(msg.ContextID, false, msg.Data)
+	}
+	return nil
+}
+
+// writeFragments sends a command or data set as P-DATA-TF PDUs.
+func (a *Association) writeFragments(contextID byte, command bool, data []byte) error {
+	// A PDV item adds 6 bytes (length, context ID and header) to its fragment
+	maxFragment := len(data)
+	if a.peerMaxPDU > 6 && int(a.peerMaxPDU)-6 < maxFragment {
+		maxFragment = int(a.peerMaxPDU) - 6
+	}
+	for {
+		n := min(len(data), maxFragment)
+		last := n == len(data)
+		body := encodePDVs(pdv{contextID: contextID, command: command, last: last, data: data[:n]})
+		if err := writePDU(a.conn, pduDataTF, body); err != nil {
+			return fmt.Errorf("send P-DATA-TF: %w", err)
+		}
+		data = data[n:]
+		if last {
+			return nil
+		}
+	}
+}
+
+// Release releases the association and closes its connection.
+func (a *Association) Release() error {
+	defer func() { _ = a.conn.Close() }()
+
+	a.writeMu.Lock()
+	err := writePDU(a.conn, pduReleaseRQ, make([]byte, 4))
+	a.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("send A-RELEASE-RQ: %w", err)
+	}
+	for {
+		pduType, _, err := readPDU(a.conn)
+		if err != nil {
+			return fmt.Errorf("wait for A-RELEASE-RP: %w", err)
+		}
+		switch pduType {
+		case pduReleaseRP:
+			return nil
+		case pduAbort:
+			return ErrAborted
+		}
+		// Late P-DATA-TF PDUs are discarded
+	}
+}
+
+// Abort aborts the association and closes its connection.
+func (a *Association) Abort() error {
+	a.writeMu.Lock()
+	err := writePDU(a.conn, pduAbort, make([]byte, 4))
+	a.writeMu.Unlock()
+	_ = a.conn.Close()
+	return err
+}
+
+// Dial requests an association with the application entity at addr
+// (host:port), proposing the abstract and transfer syntaxes of contexts.
+// Context IDs are assigned by Dial. The SCP role is proposed for the
+// abstract syntaxes of contexts with SCPRole set; it is cleared in the
+// accepted contexts when the acceptor does not agree.
+func Dial(addr, callingAE, calledAE string, contexts []PresentationContext) (*Association, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", addr, err)
+	}
+
+	rq := associate{
+		calledAE:               calledAE,
+		callingAE:              callingAE,
+		maxPDULength:           DefaultMaxPDULength,
+		implementationClassUID: ImplementationClassUID,
+		implementationVersion:  ImplementationVersionName,
+	}
+	proposed := make(map[byte]PresentationContext, len(contexts))
+	scpRoles := make(map[string]bool)
+	for i, pc := range contexts {
+		pc.ID = byte(2*i + 1) // Context IDs are odd
+		rq.contexts = append(rq.contexts, pc)
+		proposed[pc.ID] = pc
+		if pc.SCPRole && !scpRoles[pc.AbstractSyntax] {
+			scpRoles[pc.AbstractSyntax] = true
+			rq.roles = append(rq.roles, roleSelection{sopClassUID: pc.AbstractSyntax, scp: true})
+		}
+	}
+	if err := writePDU(conn, pduAssociateRQ, encodeAssociate(rq, true)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("send A-ASSOCIATE-RQ: %w", err)
+	}
+
+	pduType, body, err := readPDU(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("read association response: %w", err)
+	}
+	switch pduType {
+	case pduAssociateAC:
+	case pduAssociateRJ:
+		_ = conn.Close()
+		return nil, decodeRejection(body)
+	case pduAbort:
+		_ = conn.Close()
+		return nil, ErrAborted
+	default:
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected PDU type 0x%02X in association response", pduType)
+	}
+
+	ac, err := decodeAssociate(body)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	a := &Association{
+		conn:       conn,
+		callingAE:  callingAE,
+		calledAE:   calledAE,
+		contexts:   make(map[byte]PresentationContext),
+		peerMaxPDU: ac.maxPDULength,
+		maxPDU:     rq.maxPDULength,
+	}
+	acceptedRoles := make(map[string]bool)
+	for _, role := range ac.roles {
+		acceptedRoles[role.sopClassUID] = role.scp
+	}
+	for _, result := range ac.contexts {
+		pc, ok := proposed[result.ID]
+		if !ok || result.Result != ContextAccepted {
+			continue
+		}
+		pc.Result = ContextAccepted
+		pc.TransferSyntax = result.TransferSyntax
+		pc.SCPRole = pc.SCPRole && acceptedRoles[pc.AbstractSyntax]
+		a.contexts[pc.ID] = pc
+	}
+	if len(a.contexts) == 0 {
+		_ = a.Abort()
+		return nil, fmt.Errorf("no presentation context accepted by %s", calledAE)
+	}
+	return a, nil
+}
+
+// acceptor negotiates associations on the acceptor side.
+type acceptor struct {
+	aeTitle      string
+	maxPDULength uint32 // Announced maximum P-DATA-TF length (0 = DefaultMaxPDULength)
+	// negotiate returns the transfer syntax accepted for a proposed context, or
+	// the reason of its rejection. SCPRole is set on contexts for which the
+	// requestor proposed the SCP role; it is granted when the context is
+	// accepted.
+	negotiate func(pc PresentationContext) (string, byte)
+}
+
+// accept reads an A-ASSOCIATE-RQ from conn and answers it. The connection
+// is closed when the association is not established.
+func (acc acceptor) accept(conn net.Conn) (*Association, error) {
+	pduType, body, err := readPDU(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("read A-ASSOCIATE-RQ: %w", err)
+	}
+	if pduType != pduAssociateRQ {
+		_ = writePDU(conn, pduAbort, make([]byte, 4))
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected PDU type 0x%02X instead of A-ASSOCIATE-RQ", pduType)
+	}
+	rq, err := decodeAssociate(body)
+	if err != nil {
+		_ = writePDU(conn, pduAbort, make([]byte, 4))
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if acc.aeTitle != "" && rq.calledAE != acc.aeTitle {
+		// Permanent rejection by the service user: called AE title not recognized
+		reject := rejection{result: 1, source: 1, reason: 7}
+		_ = writePDU(conn, pduAssociateRJ, encodeRejection(reject))
+		_ = conn.Close()
+		return nil, fmt.Errorf("called AE title %q is not %q", rq.calledAE, acc.aeTitle)
+	}
+
+	a := &Association{
+		conn:       conn,
+		callingAE:  rq.callingAE,
+		calledAE:   rq.calledAE,
+		contexts:   make(map[byte]PresentationContext),
+		peerMaxPDU: rq.maxPDULength,
+		maxPDU:     acc.maxPDULength,
+	}
+	if a.maxPDU == 0 {
+		a.maxPDU = DefaultMaxPDULength
+	}
+	ac := associate{
+		calledAE:               rq.calledAE,
+		callingAE:              rq.callingAE,
+		maxPDULength:           a.maxPDU,
+		implementationClassUID: ImplementationClassUID,
+		implementationVersion:  ImplementationVersionName,
+	}
+	roles := make(map[string]roleSelection)
+	for _, role := range rq.roles {
+		roles[role.sopClassUID] = role
+	}
+	granted := make(map[string]bool)
+	for _, pc := range rq.contexts {
+		pc.SCPRole = roles[pc.AbstractSyntax].scp
+		pc.TransferSyntax, pc.Result = acc.negotiate(pc)
+		ac.contexts = append(ac.contexts, pc)
+		if pc.Result != ContextAccepted {
+			continue
+		}
+		a.contexts[pc.ID] = pc
+		if pc.SCPRole && !granted[pc.AbstractSyntax] {
+			granted[pc.AbstractSyntax] = true
+			// The proposed roles are accepted as is
+			ac.roles = append(ac.roles, roles[pc.AbstractSyntax])
+		}
+	}
+	if err := writePDU(conn, pduAssociateAC, encodeAssociate(ac, false)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("send A-ASSOCIATE-AC: %w", err)
+	}
+	return a, nil
+}
@@ -0,0 +1,43 @@
+package dimse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// ErrNoBulkData is returned when an instance has no binary value with the
+// requested tag.
+var ErrNoBulkData = errors.New("no bulk data")
+
+// Dataset reads the data set of the instance, File Meta Information
+// included, with the PixelData element but not its value.
+func (inst *Instance) Dataset() (dicom.Dataset, error) {
+	return dicom.ParseFile(inst.Path, nil, dicom.SkipPixelData(), dicom.AllowUnknownSpecificCharacterSet())
+}
+
+// BulkData returns the binary value of a top-level element of the instance
+// in parts: one per frame of encapsulated pixel data, a single one for
+// native pixel data and the value of an OB, OW, OD, OF, OL, OV or UN
+// element.
+func (inst *Instance) BulkData(t tag.Tag) (parts [][]byte, encapsulated bool, err error) {
+	if t == tag.PixelData {
+		frames, encapsulated, err := inst.Frames()
+		if err != nil || encapsulated {
+			return frames, encapsulated, err
+		}
+		return [][]byte{bytes.Join(frames, nil)}, false, nil
+	}
+	elem, ok := inst.elements[t]
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %s has no element %v", ErrNoBulkData, inst.SOPInstanceUID, t)
+	}
+	value, ok := elem.Value.GetValue().([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: element %v of %s is not binary", ErrNoBulkData, t, inst.SOPInstanceUID)
+	}
+	return [][]byte{value}, false, nil
+}
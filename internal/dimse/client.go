@@ -0,0 +1,211 @@
+package dimse
+
+import (
+	"fmt"
+
+	"github.com/suyashkumar/dicom"
+)
+
+// StatusError is a failure or warning status of a DIMSE response.
+type StatusError struct {
+	Command Command
+}
+
+// Error returns a description of the status.
+func (e StatusError) Error() string {
+	if e.Command.ErrorComment != "" {
+		return fmt.Sprintf("status 0x%04X: %s", e.Command.Status, e.Command.ErrorComment)
+	}
+	return fmt.Sprintf("status 0x%04X", e.Command.Status)
+}
+
+// request sends a request on the first accepted context of its SOP class,
+// the affected one or, for N-SET, the requested one. When the data set is
+// given, it is encoded in the context's transfer syntax.
+func (a *Association) request(cmd Command, identifier *dicom.Dataset) (PresentationContext, error) {
+	sopClassUID := cmd.AffectedSOPClassUID
+	if sopClassUID == "" {
+		sopClassUID = cmd.RequestedSOPClassUID
+	}
+	pc, ok := a.FindContext(sopClassUID, "")
+	if !ok {
+		return pc, fmt.Errorf("no accepted presentation context for %s", sopClassUID)
+	}
+	cmd.MessageID = a.nextMessageID()
+	msg := &Message{ContextID: pc.ID, Command: cmd}
+	if identifier != nil {
+		data, err := EncodeDataset(*identifier, pc.TransferSyntax)
+		if err != nil {
+			return pc, err
+		}
+		msg.Data = data
+	}
+	return pc, a.WriteMessage(msg)
+}
+
+// Echo sends a C-ECHO request.
+func (a *Association) Echo() error {
+	if _, err := a.request(Command{CommandField: CEchoRQ, AffectedSOPClassUID: VerificationSOPClass}, nil); err != nil {
+		return err
+	}
+	rsp, err := a.ReadMessage()
+	if err != nil {
+		return err
+	}
+	if rsp.Command.Status != StatusSuccess {
+		return StatusError{rsp.Command}
+	}
+	return nil
+}
+
+// Store sends a C-STORE request of a data set encoded in transferSyntaxUID,
+// and returns the response. The move originator is set for the
+// sub-operations of a C-MOVE.
+func (a *Association) Store(sopClassUID, sopInstanceUID, transferSyntaxUID string, data []byte, moveOriginatorAE string, moveOriginatorID uint16) (Command, error) {
+	pc, ok := a.FindContext(sopClassUID, transferSyntaxUID)
+	if !ok {
+		return Command{}, fmt.Errorf("no accepted presentation context for %s in %s", sopClassUID, transferSyntaxUID)
+	}
+	msg := &Message{
+		ContextID: pc.ID,
+		Command: Command{
+			CommandField:            CStoreRQ,
+			MessageID:               a.nextMessageID(),
+			AffectedSOPClassUID:     sopClassUID,
+			AffectedSOPInstanceUID:  sopInstanceUID,
+			Priority:                PriorityMedium,
+			MoveOriginatorAETitle:   moveOriginatorAE,
+			MoveOriginatorMessageID: moveOriginatorID,
+		},
+		Data: data,
+	}
+	if err := a.WriteMessage(msg); err != nil {
+		return Command{}, err
+	}
+	rsp, err := a.ReadMessage()
+	if err != nil {
+		return Command{}, err
+	}
+	return rsp.Command, nil
+}
+
+// Find sends a C-FIND request and returns the matches.
+func (a *Association) Find(sopClassUID string, identifier dicom.Dataset) ([]dicom.Dataset, error) {
+	pc, err := a.request(Command{CommandField: CFindRQ, AffectedSOPClassUID: sopClassUID, Priority: PriorityMedium}, &identifier)
+	if err != nil {
+		return nil, err
+	}
+	var matches []dicom.Dataset
+	for {
+		rsp, err := a.ReadMessage()
+		if err != nil {
+			return matches, err
+		}
+		switch rsp.Command.Status {
+		case StatusPending, 0xFF01:
+			if rsp.Data == nil {
+				continue
+			}
+			ds, err := DecodeDataset(rsp.Data, pc.TransferSyntax)
+			if err != nil {
+				return matches, err
+			}
+			matches = append(matches, ds)
+		case StatusSuccess:
+			return matches, nil
+		default:
+			return matches, StatusError{rsp.Command}
+		}
+	}
+}
+
+// Move sends a C-MOVE request to the destination AE title and returns the
+// final response, holding the sub-operation counts.
+func (a *Association) Move(sopClassUID, destination string, identifier dicom.Dataset) (Command, error) {
+	cmd := Command{CommandField: CMoveRQ, AffectedSOPClassUID: sopClassUID, Priority: PriorityMedium, MoveDestination: destination}
+	if _, err := a.request(cmd, &identifier); err != nil {
+		return Command{}, err
+	}
+	for {
+		rsp, err := a.ReadMessage()
+		if err != nil {
+			return Command{}, err
+		}
+		if rsp.Command.Status == StatusPending {
+			continue
+		}
+		if rsp.Command.Status != StatusSuccess && rsp.Command.Status != StatusSubOperationsWarning {
+			return rsp.Command, StatusError{rsp.Command}
+		}
+		return rsp.Command, nil
+	}
+}
+
+// Get sends a C-GET request and returns the final response, holding the
+// sub-operation counts. The instances are received on the association as
+// C-STORE requests, on contexts proposed with SCPRole: store is called with
+// each of them and returns the status of its C-STORE response.
+func (a *Association) Get(sopClassUID string, identifier dicom.Dataset, store func(msg *Message) uint16) (Command, error) {
+	cmd := Command{CommandField: CGetRQ, AffectedSOPClassUID: sopClassUID, Priority: PriorityMedium}
+	if _, err := a.request(cmd, &identifier); err != nil {
+		return Command{}, err
+	}
+	for {
+		msg, err := a.ReadMessage()
+		if err != nil {
+			return Command{}, err
+		}
+		if msg.Command.CommandField == CStoreRQ {
+			rsp := Command{
+				CommandField:              CStoreRSP,
+				MessageIDBeingRespondedTo: msg.Command.MessageID,
+				AffectedSOPClassUID:       msg.Command.AffectedSOPClassUID,
+				AffectedSOPInstanceUID:    msg.Command.AffectedSOPInstanceUID,
+				Status:                    store(msg),
+			}
+			if err := a.WriteMessage(&Message{ContextID: msg.ContextID, Command: rsp}); err != nil {
+				return Command{}, err
+			}
+			continue
+		}
+		if msg.Command.Status == StatusPending {
+			continue
+		}
+		if msg.Command.Status != StatusSuccess && msg.Command.Status != StatusSubOperationsWarning {
+			return msg.Command, StatusError{msg.Command}
+		}
+		return msg.Command, nil
+	}
+}
+
+// Create sends an N-CREATE request of a SOP instance with its attributes,
+// and returns the response. Warning statuses (attribute list error,
+// attribute value out of range) are not errors.
+func (a *Association) Create(sopClassUID, sopInstanceUID string, attributes dicom.Dataset) (Command, error) {
+	cmd := Command{CommandField: NCreateRQ, AffectedSOPClassUID: sopClassUID, AffectedSOPInstanceUID: sopInstanceUID}
+	return a.normalized(cmd, attributes)
+}
+
+// Set sends an N-SET request of the modifications of a SOP instance, and
+// returns the response. Warning statuses are not errors.
+func (a *Association) Set(sopClassUID, sopInstanceUID string, modifications dicom.Dataset) (Command, error) {
+	cmd := Command{CommandField: NSetRQ, RequestedSOPClassUID: sopClassUID, RequestedSOPInstanceUID: sopInstanceUID}
+	return a.normalized(cmd, modifications)
+}
+
+// normalized sends a request of a DIMSE-N service with its data set and
+// returns the response.
+func (a *Association) normalized(cmd Command, data dicom.Dataset) (Command, error) {
+	if _, err := a.request(cmd, &data); err != nil {
+		return Command{}, err
+	}
+	rsp, err := a.ReadMessage()
+	if err != nil {
+		return Command{}, err
+	}
+	switch rsp.Command.Status {
+	case StatusSuccess, StatusAttributeListError, StatusAttributeOutOfRange:
+		return rsp.Command, nil
+	}
+	return rsp.Command, StatusError{rsp.Command}
+}
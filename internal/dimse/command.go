@@ -0,0 +1,266 @@
+package dimse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Command fields (PS3.7 section E.1)
+const (
+	CStoreRQ   uint16 = 0x0001
+	CStoreRSP  uint16 = 0x8001
+	CGetRQ     uint16 = 0x0010
+	CGetRSP    uint16 = 0x8010
+	CFindRQ    uint16 = 0x0020
+	CFindRSP   uint16 = 0x8020
+	CMoveRQ    uint16 = 0x0021
+	CMoveRSP   uint16 = 0x8021
+	CEchoRQ    uint16 = 0x0030
+	CEchoRSP   uint16 = 0x8030
+	CCancelRQ  uint16 = 0x0FFF
+	NSetRQ     uint16 = 0x0120
+	NSetRSP    uint16 = 0x8120
+	NCreateRQ  uint16 = 0x0140
+	NCreateRSP uint16 = 0x8140
+)
+
+// Statuses (PS3.7 annex C)
+const (
+	StatusSuccess                uint16 = 0x0000
+	StatusPending                uint16 = 0xFF00
+	StatusCancel                 uint16 = 0xFE00
+	StatusSOPClassNotSupported   uint16 = 0x0122
+	StatusUnrecognizedOperation  uint16 = 0x0211
+	StatusAttributeListError     uint16 = 0x0107
+	StatusAttributeOutOfRange    uint16 = 0x0116
+	StatusNoSuchSOPInstance      uint16 = 0x0112
+	StatusDuplicateSOPInstance   uint16 = 0x0111
+	StatusMoveDestinationUnknown uint16 = 0xA801
+	StatusOutOfResources         uint16 = 0xA700
+	StatusSubOperationsFailed    uint16 = 0xA702
+	StatusIdentifierMismatch     uint16 = 0xA900
+	StatusSubOperationsWarning   uint16 = 0xB000
+	StatusUnableToProcess        uint16 = 0xC000
+)
+
+// Priorities of requests
+const (
+	PriorityMedium uint16 = 0x0000
+	PriorityHigh   uint16 = 0x0001
+	PriorityLow    uint16 = 0x0002
+)
+
+// noDataSet is the CommandDataSetType of a message without data set
+const noDataSet uint16 = 0x0101
+
+// Command is a DIMSE command set (group 0000). Only the fields relevant to
+// CommandField are encoded.
+type Command struct {
+	CommandField              uint16
+	MessageID                 uint16
+	MessageIDBeingRespondedTo uint16
+	AffectedSOPClassUID       string
+	AffectedSOPInstanceUID    string
+	RequestedSOPClassUID      string // N-SET requests
+	RequestedSOPInstanceUID   string // N-SET requests
+	Priority                  uint16
+	HasDataSet                bool
+	Status                    uint16
+	ErrorComment              string
+	MoveDestination           string
+
+	// Sub-operation counts of C-MOVE and C-GET responses
+	RemainingSuboperations uint16
+	CompletedSuboperations uint16
+	FailedSuboperations    uint16
+	WarningSuboperations   uint16
+
+	// C-STORE sub-operations of a C-MOVE or C-GET
+	MoveOriginatorAETitle   string
+	MoveOriginatorMessageID uint16
+}
+
+// Elements of the command set (group 0000)
+const (
+	elemCommandGroupLength        uint16 = 0x0000
+	elemAffectedSOPClassUID       uint16 = 0x0002
+	elemRequestedSOPClassUID      uint16 = 0x0003
+	elemCommandField              uint16 = 0x0100
+	elemMessageID                 uint16 = 0x0110
+	elemMessageIDBeingRespondedTo uint16 = 0x0120
+	elemMoveDestination           uint16 = 0x0600
+	elemPriority                  uint16 = 0x0700
+	elemCommandDataSetType        uint16 = 0x0800
+	elemStatus                    uint16 = 0x0900
+	elemErrorComment              uint16 = 0x0902
+	elemAffectedSOPInstanceUID    uint16 = 0x1000
+	elemRequestedSOPInstanceUID   uint16 = 0x1001
+	elemRemainingSuboperations    uint16 = 0x1020
+	elemCompletedSuboperations    uint16 = 0x1021
+	elemFailedSuboperations       uint16 = 0x1022
+	elemWarningSuboperations      uint16 = 0x1023
+	elemMoveOriginatorAETitle     uint16 = 0x1030
+	elemMoveOriginatorMessageID   uint16 = 0x1031
+)
+
+// IsResponse reports whether the command is a response.
+func (c Command) IsResponse() bool {
+	return c.CommandField&0x8000 != 0
+}
+
+// encodeCommand encodes a command set in Implicit VR Little Endian, the
+// transfer syntax of every command.
+func encodeCommand(c Command) []byte {
+	var body bytes.Buffer
+	putString := func(element uint16, value string, pad byte) {
+		if len(value)%2 != 0 {
+			value += string(pad)
+		}
+		putElement(&body, element, []byte(value))
+	}
+	putUS := func(element uint16, value uint16) {
+		b := make([]byte, 2)
+		binary.LittleEndian.PutUint16(b, value)
+		putElement(&body, element, b)
+	}
+
+	if c.AffectedSOPClassUID != "" {
+		putString(elemAffectedSOPClassUID, c.AffectedSOPClassUID, 0)
+	}
+	if c.RequestedSOPClassUID != "" {
+		putString(elemRequestedSOPClassUID, c.RequestedSOPClassUID, 0)
+	}
+	putUS(elemCommandField, c.CommandField)
+	if c.IsResponse() {
+		putUS(elemMessageIDBeingRespondedTo, c.MessageIDBeingRespondedTo)
+	} else {
+		putUS(elemMessageID, c.MessageID)
+	}
+	if c.CommandField == CMoveRQ {
+		putString(elemMoveDestination, c.MoveDestination, ' ')
+	}
+	switch c.CommandField {
+	case CStoreRQ, CFindRQ, CMoveRQ, CGetRQ:
+		putUS(elemPriority, c.Priority)
+	}
+	dataSetType := noDataSet
+	if c.HasDataSet {
+		dataSetType = 0x0000
+	}
+	putUS(elemCommandDataSetType, dataSetType)
+	if c.IsResponse() {
+		putUS(elemStatus, c.Status)
+		if c.ErrorComment != "" {
+			putString(elemErrorComment, c.ErrorComment, ' ')
+		}
+	}
+	if c.AffectedSOPInstanceUID != "" {
+		putString(elemAffectedSOPInstanceUID, c.AffectedSOPInstanceUID, 0)
+	}
+	if c.RequestedSOPInstanceUID != "" {
+		putString(elemRequestedSOPInstanceUID, c.RequestedSOPInstanceUID, 0)
+	}
+	if c.CommandField == CMoveRSP || c.CommandField == CGetRSP {
+		if c.Status == StatusPending {
+			putUS(elemRemainingSuboperations, c.RemainingSuboperations)
+		}
+		putUS(elemCompletedSuboperations, c.CompletedSuboperations)
+		putUS(elemFailedSuboperations, c.FailedSuboperations)
+		putUS(elemWarningSuboperations, c.WarningSuboperations)
+	}
+	if c.MoveOriginatorAETitle != "" {
+		putString(elemMoveOriginatorAETitle, c.MoveOriginatorAETitle, ' ')
+		putUS(elemMoveOriginatorMessageID, c.MoveOriginatorMessageID)
+	}
+
+	var buf bytes.Buffer
+	groupLength := make([]byte, 4)
+	binary.LittleEndian.PutUint32(groupLength, uint32(body.Len()))
+	putElement(&buf, elemCommandGroupLength, groupLength)
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// decodeCommand decodes an Implicit VR Little Endian command set. Unknown
+// elements are ignored.
+func decodeCommand(data []byte) (Command, error) {
+	var c Command
+	dataSetType := noDataSet
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return c, fmt.Errorf("truncated command element")
+		}
+		group := binary.LittleEndian.Uint16(data)
+		element := binary.LittleEndian.Uint16(data[2:])
+		length := binary.LittleEndian.Uint32(data[4:])
+		if uint64(length) > uint64(len(data)-8) {
+			return c, fmt.Errorf("command element (%04X,%04X) length %d exceeds command", group, element, length)
+		}
+		value := data[8 : 8+length]
+		data = data[8+length:]
+		if group != 0x0000 {
+			return c, fmt.Errorf("unexpected element (%04X,%04X) in command", group, element)
+		}
+
+		us := func() uint16 {
+			if len(value) < 2 {
+				return 0
+			}
+			return binary.LittleEndian.Uint16(value)
+		}
+		str := func() string {
+			return strings.TrimRight(string(value), "\x00 ")
+		}
+		switch element {
+		case elemAffectedSOPClassUID:
+			c.AffectedSOPClassUID = str()
+		case elemRequestedSOPClassUID:
+			c.RequestedSOPClassUID = str()
+		case elemCommandField:
+			c.CommandField = us()
+		case elemMessageID:
+			c.MessageID = us()
+		case elemMessageIDBeingRespondedTo:
+			c.MessageIDBeingRespondedTo = us()
+		case elemMoveDestination:
+			c.MoveDestination = strings.TrimSpace(str())
+		case elemPriority:
+			c.Priority = us()
+		case elemCommandDataSetType:
+			dataSetType = us()
+		case elemStatus:
+			c.Status = us()
+		case elemErrorComment:
+			c.ErrorComment = str()
+		case elemAffectedSOPInstanceUID:
+			c.AffectedSOPInstanceUID = str()
+		case elemRequestedSOPInstanceUID:
+			c.RequestedSOPInstanceUID = str()
+		case elemRemainingSuboperations:
+			c.RemainingSuboperations = us()
+		case elemCompletedSuboperations:
+			c.CompletedSuboperations = us()
+		case elemFailedSuboperations:
+			c.FailedSuboperations = us()
+		case elemWarningSuboperations:
+			c.WarningSuboperations = us()
+		case elemMoveOriginatorAETitle:
+			c.MoveOriginatorAETitle = strings.TrimSpace(str())
+		case elemMoveOriginatorMessageID:
+			c.MoveOriginatorMessageID = us()
+		}
+	}
+	c.HasDataSet = dataSetType != noDataSet
+	return c, nil
+}
+
+// putElement appends an Implicit VR Little Endian element of group 0000.
+func putElement(buf *bytes.Buffer, element uint16, value []byte) {
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint16(header[2:], element)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(value)))
+	buf.Write(header)
+	buf.Write(value)
+}
@@ -0,0 +1,161 @@
+package dimse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+	"github.com/suyashkumar/dicom/pkg/uid"
+)
+
+// EncodeDataset encodes the elements of ds, sorted by tag, in the transfer
+// syntax, their text in their SpecificCharacterSet (see util.EncodeText).
+// File Meta Information elements (group 0002) are left out.
+func EncodeDataset(ds dicom.Dataset, transferSyntaxUID string) ([]byte, error) {
+	bo, implicit, err := uid.ParseTransferSyntaxUID(transferSyntaxUID)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]*dicom.Element, 0, len(ds.Elements))
+	for _, elem := range util.EncodeText(ds.Elements) {
+		if elem.Tag.Group != 0x0002 {
+			elements = append(elements, elem)
+		}
+	}
+	sort.SliceStable(elements, func(i, j int) bool {
+		return tagLess(elements[i].Tag, elements[j].Tag)
+	})
+
+	var buf bytes.Buffer
+	w, err := dicom.NewWriter(&buf, dicom.SkipVRVerification(), dicom.SkipValueTypeVerification())
+	if err != nil {
+		return nil, err
+	}
+	w.SetTransferSyntax(bo, implicit)
+	for _, elem := range elements {
+		if err := w.WriteElement(elem); err != nil {
+			return nil, fmt.Errorf("encode %v: %w", elem.Tag, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeDataset decodes a data set encoded in the transfer syntax.
+func DecodeDataset(data []byte, transferSyntaxUID string) (dicom.Dataset, error) {
+	if _, _, err := uid.ParseTransferSyntaxUID(transferSyntaxUID); err != nil {
+		return dicom.Dataset{}, err
+	}
+
+	// The parser reads the transfer syntax from the File Meta Information,
+	// so the data set is given a minimal one
+	file := part10Header(fileMeta{transferSyntaxUID: transferSyntaxUID})
+	file = append(file, data...)
+	ds, err := dicom.Parse(bytes.NewReader(file), int64(len(file)), nil, dicom.AllowUnknownSpecificCharacterSet())
+	if err != nil {
+		return dicom.Dataset{}, fmt.Errorf("decode data set: %w", err)
+	}
+
+	elements := ds.Elements[:0]
+	for _, elem := range ds.Elements {
+		if elem.Tag.Group != 0x0002 {
+			elements = append(elements, elem)
+		}
+	}
+	return dicom.Dataset{Elements: elements}, nil
+}
+
+// fileMeta holds the File Meta Information of a DICOM file.
+type fileMeta struct {
+	sopClassUID       string
+	sopInstanceUID    string
+	transferSyntaxUID string
+}
+
+// splitPart10 splits a DICOM file into its File Meta Information and the
+// encoded data set that follows it.
+func splitPart10(data []byte) (fileMeta, []byte, error) {
+	parsed, pos, err := util.ParseFileMeta(data)
+	if err != nil {
+		return fileMeta{}, nil, err
+	}
+	meta := fileMeta{
+		sopClassUID:       parsed.SOPClassUID,
+		sopInstanceUID:    parsed.SOPInstanceUID,
+		transferSyntaxUID: parsed.TransferSyntaxUID,
+	}
+	if meta.transferSyntaxUID == "" {
+		return meta, nil, fmt.Errorf("missing TransferSyntaxUID in File Meta Information")
+	}
+	return meta, data[pos:], nil
+}
+
+// part10Header returns the preamble, prefix and File Meta Information of a
+// DICOM file. Empty meta values are left out.
+func part10Header(meta fileMeta) []byte {
+	var group bytes.Buffer
+	putMetaElement(&group, 0x0001, "OB", []byte{0x00, 0x01})
+	for _, e := range []struct {
+		element uint16
+		value   string
+	}{
+		{0x0002, meta.sopClassUID},
+		{0x0003, meta.sopInstanceUID},
+		{0x0010, meta.transferSyntaxUID},
+		{0x0012, ImplementationClassUID},
+	} {
+		if e.value != "" {
+			putMetaElement(&group, e.element, "UI", padValue(e.value, 0))
+		}
+	}
+	putMetaElement(&group, 0x0013, "SH", padValue(ImplementationVersionName, ' '))
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 128))
+	buf.WriteString("DICM")
+	groupLength := make([]byte, 4)
+	binary.LittleEndian.PutUint32(groupLength, uint32(group.Len()))
+	putMetaElement(&buf, 0x0000, "UL", groupLength)
+	buf.Write(group.Bytes())
+	return buf.Bytes()
+}
+
+// putMetaElement appends an Explicit VR Little Endian element of group 0002.
+func putMetaElement(buf *bytes.Buffer, element uint16, vr string, value []byte) {
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint16(header, 0x0002)
+	binary.LittleEndian.PutUint16(header[2:], element)
+	buf.Write(header)
+	buf.WriteString(vr)
+	if util.HasLongLength(vr) {
+		length := make([]byte, 6)
+		binary.LittleEndian.PutUint32(length[2:], uint32(len(value)))
+		buf.Write(length)
+	} else {
+		length := make([]byte, 2)
+		binary.LittleEndian.PutUint16(length, uint16(len(value)))
+		buf.Write(length)
+	}
+	buf.Write(value)
+}
+
+// padValue pads a value to an even length.
+func padValue(value string, pad byte) []byte {
+	b := []byte(value)
+	if len(b)%2 != 0 {
+		b = append(b, pad)
+	}
+	return b
+}
+
+// tagLess orders tags by group, then element.
+func tagLess(a, b tag.Tag) bool {
+	if a.Group != b.Group {
+		return a.Group < b.Group
+	}
+	return a.Element < b.Element
+}
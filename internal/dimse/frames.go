@@ -0,0 +1,89 @@
+package dimse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Lookup returns the instance of a study and series with a SOP Instance UID.
+func (ix *Index) Lookup(studyUID, seriesUID, sopInstanceUID string) (*Instance, bool) {
+	for _, inst := range ix.instances {
+		if inst.SOPInstanceUID == sopInstanceUID && inst.StudyInstanceUID == studyUID && inst.SeriesInstanceUID == seriesUID {
+			return inst, true
+		}
+	}
+	return nil, false
+}
+
+// Instances returns the instances of a study, or of one of its series when
+// seriesUID is not "".
+func (ix *Index) Instances(studyUID, seriesUID string) []*Instance {
+	var instances []*Instance
+	for _, inst := range ix.instances {
+		if inst.StudyInstanceUID == studyUID && (seriesUID == "" || inst.SeriesInstanceUID == seriesUID) {
+			instances = append(instances, inst)
+		}
+	}
+	return instances
+}
+
+// Frames reads the pixel data of the instance and returns its frames: the
+// items of encapsulated pixel data, one frame per item, or native pixel data
+// cut in NumberOfFrames frames of Rows x Columns pixels.
+func (inst *Instance) Frames() (frames [][]byte, encapsulated bool, err error) {
+	ds, err := dicom.ParseFile(inst.Path, nil, dicom.SkipProcessingPixelDataValue(), dicom.AllowUnknownSpecificCharacterSet())
+	if err != nil {
+		return nil, false, err
+	}
+	elem, err := ds.FindElementByTag(tag.PixelData)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: no pixel data", inst.Path)
+	}
+	info := dicom.MustGetPixelDataInfo(elem.Value)
+	if info.IsEncapsulated {
+		for _, f := range info.Frames {
+			frames = append(frames, f.EncapsulatedData.Data)
+		}
+		return frames, true, nil
+	}
+
+	size, err := inst.frameSize()
+	if err != nil {
+		return nil, false, err
+	}
+	count := 1
+	if n, err := strconv.Atoi(strings.TrimSpace(inst.key(tag.NumberOfFrames))); err == nil && n > 0 {
+		count = n
+	}
+	data := info.UnprocessedValueData
+	if len(data) < count*size {
+		return nil, false, fmt.Errorf("%s: pixel data of %d bytes, %d frames of %d bytes expected", inst.Path, len(data), count, size)
+	}
+	for i := 0; i < count; i++ {
+		frames = append(frames, data[i*size:(i+1)*size])
+	}
+	return frames, false, nil
+}
+
+// frameSize returns the length in bytes of a native frame of the instance.
+// YBR_FULL_422 shares the chroma of each pair of pixels: two samples per
+// pixel are stored instead of three.
+func (inst *Instance) frameSize() (int, error) {
+	var dims [4]int
+	for i, t := range []tag.Tag{tag.Rows, tag.Columns, tag.SamplesPerPixel, tag.BitsAllocated} {
+		n, err := strconv.Atoi(inst.key(t))
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%s: invalid image pixel attribute %v %q", inst.Path, t, inst.key(t))
+		}
+		dims[i] = n
+	}
+	rows, columns, samples, bits := dims[0], dims[1], dims[2], dims[3]
+	if strings.TrimSpace(inst.key(tag.PhotometricInterpretation)) == "YBR_FULL_422" {
+		samples = 2
+	}
+	return (rows*columns*samples*bits + 7) / 8, nil
+}
@@ -0,0 +1,470 @@
+package dimse
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Query/retrieve levels
+const (
+	LevelPatient = "PATIENT"
+	LevelStudy   = "STUDY"
+	LevelSeries  = "SERIES"
+	LevelImage   = "IMAGE"
+)
+
+// levelKeys are the unique keys of the query/retrieve levels
+var levelKeys = map[string]tag.Tag{
+	LevelPatient: tag.PatientID,
+	LevelStudy:   tag.StudyInstanceUID,
+	LevelSeries:  tag.SeriesInstanceUID,
+	LevelImage:   tag.SOPInstanceUID,
+}
+
+// Instance is a DICOM file of an Index.
+type Instance struct {
+	Path              string
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	SOPClassUID       string
+	SOPInstanceUID    string
+	TransferSyntaxUID string
+
+	elements map[tag.Tag]*dicom.Element // Data set without pixel data
+}
+
+// value returns the string values of an attribute of the instance.
+func (inst *Instance) value(t tag.Tag) ([]string, bool) {
+	elem, ok := inst.elements[t]
+	if !ok {
+		return nil, false
+	}
+	return elementStrings(elem), true
+}
+
+// key returns the first value of an attribute, or "" when absent.
+func (inst *Instance) key(t tag.Tag) string {
+	values, _ := inst.value(t)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Index is the Query/Retrieve information model of a set of DICOM files
+// (Patient Root and Study Root). It is read-only once built.
+type Index struct {
+	instances []*Instance
+}
+
+// NewIndex indexes the DICOM files of a directory tree. DICOMDIR and files
+// that are not DICOM are skipped.
+func NewIndex(dir string) (*Index, error) {
+	ix := &Index{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() == "DICOMDIR" {
+			return nil
+		}
+		inst, err := readInstance(path)
+		if err != nil {
+			return nil // Not a DICOM file
+		}
+		ix.instances = append(ix.instances, inst)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("index %s: %w", dir, err)
+	}
+	return ix, nil
+}
+
+// readInstance parses the header of a DICOM file.
+func readInstance(path string) (*Instance, error) {
+	ds, err := dicom.ParseFile(path, nil, dicom.SkipPixelData(), dicom.AllowUnknownSpecificCharacterSet())
+	if err != nil {
+		return nil, err
+	}
+	inst := &Instance{Path: path, elements: make(map[tag.Tag]*dicom.Element, len(ds.Elements))}
+	for _, elem := range ds.Elements {
+		if elem.Tag == tag.PixelData {
+			continue
+		}
+		inst.elements[elem.Tag] = elem
+	}
+	inst.StudyInstanceUID = inst.key(tag.StudyInstanceUID)
+	inst.SeriesInstanceUID = inst.key(tag.SeriesInstanceUID)
+	inst.SOPClassUID = inst.key(tag.SOPClassUID)
+	inst.SOPInstanceUID = inst.key(tag.SOPInstanceUID)
+	inst.TransferSyntaxUID = inst.key(tag.TransferSyntaxUID)
+	if inst.SOPInstanceUID == "" || inst.TransferSyntaxUID == "" {
+		return nil, fmt.Errorf("%s: missing SOPInstanceUID or TransferSyntaxUID", path)
+	}
+	return inst, nil
+}
+
+// Len returns the number of indexed instances.
+func (ix *Index) Len() int {
+	return len(ix.instances)
+}
+
+// All returns every indexed instance, in directory order.
+func (ix *Index) All() []*Instance {
+	return ix.instances
+}
+
+// entity is a patient, study, series or instance at a query level, with all
+// of its instances.
+type entity struct {
+	instances []*Instance
+	match     *Instance // First instance matching the keys
+}
+
+// query groups the instances at the level of the identifier, and keeps the
+// entities with an instance matching its keys.
+func (ix *Index) query(identifier dicom.Dataset) (string, []*entity, error) {
+	levelElem, err := identifier.FindElementByTag(tag.QueryRetrieveLevel)
+	if err != nil {
+		return "", nil, fmt.Errorf("missing QueryRetrieveLevel")
+	}
+	level := ""
+	if values := elementStrings(levelElem); len(values) > 0 {
+		level = strings.ToUpper(values[0])
+	}
+	levelKey, ok := levelKeys[level]
+	if !ok {
+		return "", nil, fmt.Errorf("invalid QueryRetrieveLevel %q", level)
+	}
+
+	var entities []*entity
+	byKey := make(map[string]*entity)
+	for _, inst := range ix.instances {
+		k := inst.key(levelKey)
+		e, ok := byKey[k]
+		if !ok {
+			e = &entity{}
+			byKey[k] = e
+			entities = append(entities, e)
+		}
+		e.instances = append(e.instances, inst)
+		if e.match == nil && matchInstance(identifier, inst) {
+			e.match = inst
+		}
+	}
+
+	matched := entities[:0]
+	for _, e := range entities {
+		if e.match != nil && matchComputed(identifier, e) {
+			matched = append(matched, e)
+		}
+	}
+	return level, matched, nil
+}
+
+// Find returns the C-FIND responses to an identifier: one data set per
+// matching entity, holding the requested attributes.
+func (ix *Index) Find(identifier dicom.Dataset) ([]dicom.Dataset, error) {
+	level, entities, err := ix.query(identifier)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]dicom.Dataset, 0, len(entities))
+	for _, e := range entities {
+		results = append(results, response(identifier, level, e))
+	}
+	return results, nil
+}
+
+// Match returns the instances of the entities matching an identifier, as
+// retrieved by C-MOVE.
+func (ix *Index) Match(identifier dicom.Dataset) ([]*Instance, error) {
+	_, entities, err := ix.query(identifier)
+	if err != nil {
+		return nil, err
+	}
+	var instances []*Instance
+	for _, e := range entities {
+		instances = append(instances, e.instances...)
+	}
+	return instances, nil
+}
+
+// computedKeys are attributes computed over the instances of an entity
+var computedKeys = map[tag.Tag]bool{
+	tag.ModalitiesInStudy:               true,
+	tag.NumberOfPatientRelatedStudies:   true,
+	tag.NumberOfPatientRelatedSeries:    true,
+	tag.NumberOfPatientRelatedInstances: true,
+	tag.NumberOfStudyRelatedSeries:      true,
+	tag.NumberOfStudyRelatedInstances:   true,
+	tag.NumberOfSeriesRelatedInstances:  true,
+	tag.QueryRetrieveLevel:              true,
+	tag.SpecificCharacterSet:            true,
+	tag.RetrieveAETitle:                 true,
+}
+
+// matchInstance reports whether an instance matches the keys of an
+// identifier that are attributes of the instance.
+func matchInstance(identifier dicom.Dataset, inst *Instance) bool {
+	for _, key := range identifier.Elements {
+		if computedKeys[key.Tag] || key.Tag.Group == 0x0002 || key.Value == nil {
+			continue
+		}
+		if key.Value.ValueType() == dicom.Sequences {
+			continue // Sequence matching is not supported: keys are returned only
+		}
+		keyValues := elementStrings(key)
+		if isUniversal(keyValues) {
+			continue
+		}
+		values, ok := inst.value(key.Tag)
+		if !ok || !matchValues(key.RawValueRepresentation, keyValues, values) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchComputed reports whether an entity matches the computed keys of an
+// identifier (ModalitiesInStudy).
+func matchComputed(identifier dicom.Dataset, e *entity) bool {
+	key, err := identifier.FindElementByTag(tag.ModalitiesInStudy)
+	if err != nil {
+		return true
+	}
+	keyValues := elementStrings(key)
+	if isUniversal(keyValues) {
+		return true
+	}
+	for _, modality := range modalitiesOf(e) {
+		for _, v := range keyValues {
+			if matchSingle("CS", v, modality) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// response builds the C-FIND response of an entity: the requested
+// attributes with the values of its matching instance.
+func response(identifier dicom.Dataset, level string, e *entity) dicom.Dataset {
+	var elements []*dicom.Element
+	if cs, ok := e.match.elements[tag.SpecificCharacterSet]; ok {
+		elements = append(elements, cs)
+	}
+	for _, key := range identifier.Elements {
+		switch key.Tag {
+		case tag.SpecificCharacterSet:
+			continue
+		case tag.QueryRetrieveLevel:
+			elements = append(elements, mustNewElement(tag.QueryRetrieveLevel, []string{level}))
+			continue
+		}
+		if elem := computedElement(key.Tag, e); elem != nil {
+			elements = append(elements, elem)
+		} else if elem, ok := e.match.elements[key.Tag]; ok {
+			elements = append(elements, elem)
+		} else {
+			elements = append(elements, key) // Unknown attribute, returned empty
+		}
+	}
+	return dicom.Dataset{Elements: elements}
+}
+
+// computedElement returns the value of a computed attribute for an entity,
+// or nil when t is not computed.
+func computedElement(t tag.Tag, e *entity) *dicom.Element {
+	count := func(key tag.Tag) *dicom.Element {
+		distinct := make(map[string]bool)
+		for _, inst := range e.instances {
+			distinct[inst.key(key)] = true
+		}
+		return mustNewElement(t, []string{strconv.Itoa(len(distinct))})
+	}
+	switch t {
+	case tag.ModalitiesInStudy:
+		return mustNewElement(t, modalitiesOf(e))
+	case tag.NumberOfPatientRelatedStudies:
+		return count(tag.StudyInstanceUID)
+	case tag.NumberOfPatientRelatedSeries, tag.NumberOfStudyRelatedSeries:
+		return count(tag.SeriesInstanceUID)
+	case tag.NumberOfPatientRelatedInstances, tag.NumberOfStudyRelatedInstances, tag.NumberOfSeriesRelatedInstances:
+		return count(tag.SOPInstanceUID)
+	}
+	return nil
+}
+
+// modalitiesOf returns the distinct modalities of an entity, sorted.
+func modalitiesOf(e *entity) []string {
+	distinct := make(map[string]bool)
+	for _, inst := range e.instances {
+		if m := inst.key(tag.Modality); m != "" {
+			distinct[m] = true
+		}
+	}
+	modalities := make([]string, 0, len(distinct))
+	for m := range distinct {
+		modalities = append(modalities, m)
+	}
+	sort.Strings(modalities)
+	return modalities
+}
+
+// isUniversal reports whether key values match everything (empty key).
+func isUniversal(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// matchValues applies the C-FIND matching rules (PS3.4 C.2.2.2): UID list,
+// range, wildcard and single value matching. A key with several values
+// matches when any of them matches any attribute value.
+func matchValues(vr string, keyValues, values []string) bool {
+	for _, k := range keyValues {
+		for _, v := range values {
+			if matchSingle(vr, k, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSingle matches an attribute value against a single key value.
+func matchSingle(vr, key, value string) bool {
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	switch vr {
+	case "DA", "TM", "DT":
+		if lower, upper, ok := strings.Cut(key, "-"); ok {
+			return matchRange(vr, strings.TrimSpace(lower), strings.TrimSpace(upper), value)
+		}
+		return key == value
+	case "UI":
+		return key == value
+	case "PN":
+		// Person names match case-insensitively, and a key of a single
+		// component group any group of the name (its alphabetic, ideographic
+		// or phonetic spelling)
+		key, value = strings.ToUpper(key), strings.ToUpper(value)
+		if !strings.Contains(key, "=") && strings.Contains(value, "=") {
+			for _, group := range strings.Split(value, "=") {
+				if group != "" && matchSingle(vr, key, group) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if strings.ContainsAny(key, "*?") {
+		return matchWildcard(key, value)
+	}
+	return key == value
+}
+
+// matchRange matches a DA, TM or DT value against an inclusive range with
+// an optional lower or upper bound.
+func matchRange(vr, lower, upper, value string) bool {
+	if vr == "TM" {
+		// Times compare on their significant digits (HHMMSS.FFFFFF)
+		value = strings.ReplaceAll(value, ":", "")
+	}
+	if lower != "" && value < lower {
+		return false
+	}
+	if upper != "" {
+		// An upper bound covers all values starting with it (20240131 covers
+		// any time that day in a DT range)
+		if value > upper && !strings.HasPrefix(value, upper) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWildcard matches a value against a key with * (any sequence) and ?
+// (any single character).
+func matchWildcard(pattern, value string) bool {
+	p, v := []rune(pattern), []rune(value)
+	var star, match = -1, 0
+	i, j := 0, 0
+	for j < len(v) {
+		switch {
+		case i < len(p) && (p[i] == '?' || p[i] == v[j]):
+			i++
+			j++
+		case i < len(p) && p[i] == '*':
+			star, match = i, j
+			i++
+		case star >= 0:
+			i = star + 1
+			match++
+			j = match
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
+
+// elementStrings returns the values of a string or numeric element as
+// strings; other values have none.
+func elementStrings(elem *dicom.Element) []string {
+	if elem == nil || elem.Value == nil {
+		return nil
+	}
+	switch v := elem.Value.GetValue().(type) {
+	case []string:
+		return v
+	case []int:
+		values := make([]string, len(v))
+		for i, n := range v {
+			values[i] = strconv.Itoa(n)
+		}
+		return values
+	case []float64:
+		values := make([]string, len(v))
+		for i, f := range v {
+			values[i] = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return values
+	}
+	return nil
+}
+
+// mustNewElement creates an element or panics (values are built internally).
+func mustNewElement(t tag.Tag, data any) *dicom.Element {
+	elem, err := dicom.NewElement(t, data)
+	if err != nil {
+		panic(fmt.Sprintf("dimse: create element %v: %v", t, err))
+	}
+	return elem
+}
+
+// fileData reads an instance file and returns its File Meta Information and
+// encoded data set.
+func (inst *Instance) fileData() (fileMeta, []byte, error) {
+	data, err := os.ReadFile(inst.Path)
+	if err != nil {
+		return fileMeta{}, nil, err
+	}
+	return splitPart10(data)
+}
@@ -0,0 +1,200 @@
+package dimse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestMatchSingle(t *testing.T) {
+	tests := []struct {
+		vr, key, value string
+		want           bool
+	}{
+		{"LO", "PAT001", "PAT001", true},
+		{"LO", "PAT001", "PAT002", false},
+		{"LO", "PAT*", "PAT002", true},
+		{"LO", "P?T00?", "PAT002", true},
+		{"LO", "P?T", "PAT002", false},
+		{"PN", "doe^*", "DOE^JOHN", true},
+		{"PN", "山田^*", "Yamada^Taro=山田^太郎=やまだ^たろう", true},
+		{"PN", "yamada^taro", "Yamada^Taro=山田^太郎=やまだ^たろう", true},
+		{"PN", "Yamada^Taro=山田^太郎=やまだ^たろう", "Yamada^Taro=山田^太郎=やまだ^たろう", true},
+		{"PN", "佐藤^*", "Yamada^Taro=山田^太郎=やまだ^たろう", false},
+		{"CS", "mr", "MR", false},
+		{"DA", "20240101-20240131", "20240115", true},
+		{"DA", "20240101-20240131", "20240201", false},
+		{"DA", "20240101-", "20991231", true},
+		{"DA", "-20240101", "20240101", true},
+		{"DA", "-20240101", "20240102", false},
+		{"TM", "080000-120000", "093000.123", true},
+		{"DT", "20240101-20240101", "20240101101500", true},
+		{"UI", "1.2.3", "1.2.3", true},
+		{"UI", "1.2.*", "1.2.3", false},
+	}
+	for _, tt := range tests {
+		if got := matchSingle(tt.vr, tt.key, tt.value); got != tt.want {
+			t.Errorf("matchSingle(%s, %q, %q) = %v, want %v", tt.vr, tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMatchValues_UIDList(t *testing.T) {
+	if !matchValues("UI", []string{"1.2.3", "1.2.4"}, []string{"1.2.4"}) {
+		t.Error("UID list does not match one of its UIDs")
+	}
+	if matchValues("UI", []string{"1.2.3", "1.2.4"}, []string{"1.2.5"}) {
+		t.Error("UID list matches another UID")
+	}
+}
+
+// testIndex builds an index of two patients: P1 with a CT and an MR study,
+// P2 with an MR study.
+func testIndex() *Index {
+	instance := func(patient, name, study, date, series, modality, sop string) *Instance {
+		inst := &Instance{SOPInstanceUID: sop, elements: make(map[tag.Tag]*dicom.Element)}
+		for t, v := range map[tag.Tag]string{
+			tag.PatientID:         patient,
+			tag.PatientName:       name,
+			tag.StudyInstanceUID:  study,
+			tag.StudyDate:         date,
+			tag.SeriesInstanceUID: series,
+			tag.Modality:          modality,
+			tag.SOPInstanceUID:    sop,
+		} {
+			inst.elements[t] = mustNewElement(t, []string{v})
+		}
+		return inst
+	}
+	return &Index{instances: []*Instance{
+		instance("P1", "DOE^JOHN", "1.1", "20240101", "1.1.1", "CT", "1.1.1.1"),
+		instance("P1", "DOE^JOHN", "1.1", "20240101", "1.1.1", "CT", "1.1.1.2"),
+		instance("P1", "DOE^JOHN", "1.2", "20240301", "1.2.1", "MR", "1.2.1.1"),
+		instance("P2", "ROE^JANE", "2.1", "20240105", "2.1.1", "MR", "2.1.1.1"),
+	}}
+}
+
+// identifier builds a query identifier from tag/value pairs.
+func identifier(level string, keys map[tag.Tag][]string) dicom.Dataset {
+	elements := []*dicom.Element{mustNewElement(tag.QueryRetrieveLevel, []string{level})}
+	for t, v := range keys {
+		elements = append(elements, mustNewElement(t, v))
+	}
+	return dicom.Dataset{Elements: elements}
+}
+
+func TestIndex_Find(t *testing.T) {
+	ix := testIndex()
+
+	tests := []struct {
+		name  string
+		query dicom.Dataset
+		want  []string // StudyInstanceUID of the matches
+	}{
+		{"all studies", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}}), []string{"1.1", "1.2", "2.1"}},
+		{"by patient", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}, tag.PatientID: {"P1"}}), []string{"1.1", "1.2"}},
+		{"by date range", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}, tag.StudyDate: {"20240101-20240110"}}), []string{"1.1", "2.1"}},
+		{"by name wildcard", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}, tag.PatientName: {"roe*"}}), []string{"2.1"}},
+		{"by modality", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}, tag.ModalitiesInStudy: {"MR"}}), []string{"1.2", "2.1"}},
+		{"by series modality", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}, tag.Modality: {"CT"}}), []string{"1.1"}},
+		{"no match", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}, tag.PatientID: {"P3"}}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ix.Find(tt.query)
+			if err != nil {
+				t.Fatalf("Find failed: %v", err)
+			}
+			var got []string
+			for _, ds := range results {
+				elem, err := ds.FindElementByTag(tag.StudyInstanceUID)
+				if err != nil {
+					t.Fatalf("response without StudyInstanceUID")
+				}
+				got = append(got, elementStrings(elem)[0])
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got studies %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got studies %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestIndex_Find_ComputedAttributes(t *testing.T) {
+	results, err := testIndex().Find(identifier(LevelPatient, map[tag.Tag][]string{
+		tag.PatientID:                       {"P1"},
+		tag.NumberOfPatientRelatedStudies:   {},
+		tag.NumberOfPatientRelatedInstances: {},
+		tag.PatientSex:                      {""},
+	}))
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	for tg, want := range map[tag.Tag]string{
+		tag.NumberOfPatientRelatedStudies:   "2",
+		tag.NumberOfPatientRelatedInstances: "3",
+		tag.QueryRetrieveLevel:              LevelPatient,
+	} {
+		elem, err := results[0].FindElementByTag(tg)
+		if err != nil {
+			t.Fatalf("missing %v", tg)
+		}
+		if got := elementStrings(elem); len(got) != 1 || got[0] != want {
+			t.Errorf("%v = %v, want %s", tg, got, want)
+		}
+	}
+	// Unknown attributes are returned empty
+	if _, err := results[0].FindElementByTag(tag.PatientSex); err != nil {
+		t.Error("missing requested PatientSex")
+	}
+}
+
+func TestIndex_Match(t *testing.T) {
+	instances, err := testIndex().Match(identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {"1.1", "2.1"}}))
+	if err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	if len(instances) != 3 {
+		t.Errorf("got %d instances, want 3", len(instances))
+	}
+
+	if _, err := testIndex().Match(identifier("VOLUME", nil)); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
+
+func TestEncodeDataset_CharacterSet(t *testing.T) {
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(tag.SpecificCharacterSet, []string{"ISO_IR 100"}),
+		mustNewElement(tag.PatientName, []string{"Gérard^Hélène"}),
+	}}
+	data, err := EncodeDataset(ds, "1.2.840.10008.1.2.1")
+	if err != nil {
+		t.Fatalf("EncodeDataset failed: %v", err)
+	}
+	if !strings.Contains(string(data), "G\xe9rard^H\xe9l\xe8ne") {
+		t.Errorf("PatientName not encoded in ISO 8859-1: %q", data)
+	}
+
+	decoded, err := DecodeDataset(data, "1.2.840.10008.1.2.1")
+	if err != nil {
+		t.Fatalf("DecodeDataset failed: %v", err)
+	}
+	name, err := decoded.FindElementByTag(tag.PatientName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := name.Value.GetValue().([]string)[0]; got != "Gérard^Hélène" {
+		t.Errorf("decoded PatientName = %q, want Gérard^Hélène", got)
+	}
+}
@@ -0,0 +1,96 @@
+package dimse
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// PerformedProcedureStep is a Modality Performed Procedure Step as a
+// modality reports it: the attributes of its N-CREATE, IN PROGRESS, and the
+// modifications of its N-SET, COMPLETED or DISCONTINUED.
+type PerformedProcedureStep struct {
+	SOPInstanceUID string
+	Create         dicom.Dataset
+	Set            dicom.Dataset // No elements when the step is left in progress
+}
+
+// LoadPerformedProcedureSteps reads the MPPS messages of a directory tree:
+// files whose File Meta Information has the MPPS SOP class, the N-CREATE
+// attributes when their PerformedProcedureStepStatus is IN PROGRESS, the
+// N-SET modifications otherwise. Other files are skipped. Steps are in the
+// order of their first file.
+func LoadPerformedProcedureSteps(dir string) ([]PerformedProcedureStep, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MPPS %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+
+	var steps []PerformedProcedureStep
+	byUID := make(map[string]int) // Index in steps by SOP Instance UID
+	for _, path := range paths {
+		ds, err := dicom.ParseFile(path, nil, dicom.AllowUnknownSpecificCharacterSet())
+		if err != nil {
+			continue // Not a DICOM file
+		}
+		if firstString(ds, tag.MediaStorageSOPClassUID) != ModalityPerformedProcedureStepSOPClass {
+			continue
+		}
+		uid := firstString(ds, tag.MediaStorageSOPInstanceUID)
+		if uid == "" {
+			return nil, fmt.Errorf("%s: missing MediaStorageSOPInstanceUID", path)
+		}
+		i, ok := byUID[uid]
+		if !ok {
+			i = len(steps)
+			byUID[uid] = i
+			steps = append(steps, PerformedProcedureStep{SOPInstanceUID: uid})
+		}
+		var elements []*dicom.Element
+		for _, elem := range ds.Elements {
+			if elem.Tag.Group != 0x0002 {
+				elements = append(elements, elem)
+			}
+		}
+		if firstString(ds, tag.PerformedProcedureStepStatus) == "IN PROGRESS" {
+			steps[i].Create = dicom.Dataset{Elements: elements}
+		} else {
+			steps[i].Set = dicom.Dataset{Elements: elements}
+		}
+	}
+	for _, step := range steps {
+		if len(step.Create.Elements) == 0 {
+			return nil, fmt.Errorf("MPPS %s: no IN PROGRESS attributes to create it with", step.SOPInstanceUID)
+		}
+	}
+	return steps, nil
+}
+
+// ReportProcedureStep sends the N-CREATE of a performed procedure step, then
+// its N-SET unless inProgress is set or it has no modifications.
+func (a *Association) ReportProcedureStep(step PerformedProcedureStep, inProgress bool) error {
+	if _, err := a.Create(ModalityPerformedProcedureStepSOPClass, step.SOPInstanceUID, step.Create); err != nil {
+		return fmt.Errorf("N-CREATE %s: %w", step.SOPInstanceUID, err)
+	}
+	if inProgress || len(step.Set.Elements) == 0 {
+		return nil
+	}
+	if _, err := a.Set(ModalityPerformedProcedureStepSOPClass, step.SOPInstanceUID, step.Set); err != nil {
+		return fmt.Errorf("N-SET %s: %w", step.SOPInstanceUID, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package dimse
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// mppsSCP is an MPPS SCP recording the steps it is sent.
+type mppsSCP struct {
+	mu     sync.Mutex
+	status map[string]string   // PerformedProcedureStepStatus by SOP Instance UID
+	steps  map[string][]string // Referenced SOP Instance UIDs of the N-SET by SOP Instance UID
+	study  map[string]string   // Scheduled Study Instance UID by SOP Instance UID
+}
+
+// startMPPSSCP serves an MPPS SCP answering N-CREATE and N-SET as an
+// information system would: a step is created once, and set while in
+// progress.
+func startMPPSSCP(t *testing.T) (string, *mppsSCP) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	scp := &mppsSCP{status: make(map[string]string), steps: make(map[string][]string), study: make(map[string]string)}
+	acc := acceptor{aeTitle: "RIS", negotiate: func(pc PresentationContext) (string, byte) {
+		return pc.TransferSyntaxes[0], ContextAccepted
+	}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				a, err := acc.accept(conn)
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+				for {
+					msg, err := a.ReadMessage()
+					if err != nil {
+						return
+					}
+					rsp := scp.handle(t, a, msg)
+					if err := a.WriteMessage(&Message{ContextID: msg.ContextID, Command: rsp}); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { _ = l.Close() })
+	return l.Addr().String(), scp
+}
+
+// handle answers an N-CREATE or N-SET request.
+func (scp *mppsSCP) handle(t *testing.T, a *Association, msg *Message) Command {
+	pc, _ := a.Context(msg.ContextID)
+	ds, err := DecodeDataset(msg.Data, pc.TransferSyntax)
+	if err != nil {
+		t.Errorf("DecodeDataset failed: %v", err)
+		return Command{CommandField: msg.Command.CommandField | 0x8000, MessageIDBeingRespondedTo: msg.Command.MessageID, Status: StatusUnableToProcess}
+	}
+	scp.mu.Lock()
+	defer scp.mu.Unlock()
+	rsp := Command{CommandField: msg.Command.CommandField | 0x8000, MessageIDBeingRespondedTo: msg.Command.MessageID, Status: StatusSuccess}
+	switch msg.Command.CommandField {
+	case NCreateRQ:
+		uid := msg.Command.AffectedSOPInstanceUID
+		rsp.AffectedSOPClassUID, rsp.AffectedSOPInstanceUID = msg.Command.AffectedSOPClassUID, uid
+		if _, ok := scp.status[uid]; ok {
+			rsp.Status = StatusDuplicateSOPInstance
+			break
+		}
+		scp.status[uid] = firstString(ds, tag.PerformedProcedureStepStatus)
+		if elem, err := ds.FindElementByTag(tag.ScheduledStepAttributesSequence); err == nil {
+			if items := sequenceItems(elem); len(items) == 1 {
+				if study := findElement(items[0], tag.StudyInstanceUID); study != nil {
+					scp.study[uid] = elementStrings(study)[0]
+				}
+			}
+		}
+	case NSetRQ:
+		uid := msg.Command.RequestedSOPInstanceUID
+		rsp.AffectedSOPClassUID, rsp.AffectedSOPInstanceUID = msg.Command.RequestedSOPClassUID, uid
+		if scp.status[uid] != "IN PROGRESS" {
+			rsp.Status = StatusNoSuchSOPInstance
+			break
+		}
+		scp.status[uid] = firstString(ds, tag.PerformedProcedureStepStatus)
+		elem, _ := ds.FindElementByTag(tag.PerformedSeriesSequence)
+		for _, series := range sequenceItems(elem) {
+			for _, image := range sequenceItems(findElement(series, tag.ReferencedImageSequence)) {
+				scp.steps[uid] = append(scp.steps[uid], elementStrings(findElement(image, tag.ReferencedSOPInstanceUID))[0])
+			}
+		}
+	default:
+		rsp.Status = StatusUnrecognizedOperation
+	}
+	return rsp
+}
+
+func TestReportProcedureStep(t *testing.T) {
+	dir := t.TempDir()
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		TotalSize:       "1MB",
+		OutputDir:       dir,
+		Seed:            7,
+		NumStudies:      2,
+		ImagesPerSeries: util.ImageRange{Min: 2, Max: 2},
+		Quiet:           true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(dir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	if _, err := internaldicom.ExportMPPS(dir, "SCANNER1", "", true); err != nil {
+		t.Fatalf("ExportMPPS failed: %v", err)
+	}
+	steps, err := LoadPerformedProcedureSteps(dir)
+	if err != nil {
+		t.Fatalf("LoadPerformedProcedureSteps failed: %v", err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("loaded %d steps, want 2", len(steps))
+	}
+
+	addr, scp := startMPPSSCP(t)
+	contexts := []PresentationContext{{AbstractSyntax: ModalityPerformedProcedureStepSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}}}
+	a, err := Dial(addr, "SCANNER1", "RIS", contexts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+
+	for _, step := range steps {
+		if err := a.ReportProcedureStep(step, false); err != nil {
+			t.Fatalf("ReportProcedureStep failed: %v", err)
+		}
+	}
+	// A step is created once
+	var status StatusError
+	if err := a.ReportProcedureStep(steps[0], false); !errors.As(err, &status) || status.Command.Status != StatusDuplicateSOPInstance {
+		t.Errorf("got %v, want a duplicate SOP instance status", err)
+	}
+
+	studies := make(map[string]bool)
+	images := make(map[string]bool)
+	for _, f := range files {
+		studies[f.StudyUID] = true
+		images[f.SOPInstanceUID] = true
+	}
+	scp.mu.Lock()
+	defer scp.mu.Unlock()
+	referenced := 0
+	for _, step := range steps {
+		if scp.status[step.SOPInstanceUID] != "COMPLETED" {
+			t.Errorf("step %s is %q, want COMPLETED", step.SOPInstanceUID, scp.status[step.SOPInstanceUID])
+		}
+		if !studies[scp.study[step.SOPInstanceUID]] {
+			t.Errorf("step %s schedules unknown study %q", step.SOPInstanceUID, scp.study[step.SOPInstanceUID])
+		}
+		for _, uid := range scp.steps[step.SOPInstanceUID] {
+			if !images[uid] {
+				t.Errorf("step %s references unknown image %s", step.SOPInstanceUID, uid)
+			}
+			referenced++
+		}
+	}
+	if referenced != len(files) {
+		t.Errorf("steps reference %d images, want %d", referenced, len(files))
+	}
+}
+
+func TestReportProcedureStep_InProgress(t *testing.T) {
+	addr, scp := startMPPSSCP(t)
+	contexts := []PresentationContext{{AbstractSyntax: ModalityPerformedProcedureStepSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}}}
+	a, err := Dial(addr, "SCANNER1", "RIS", contexts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+
+	step := PerformedProcedureStep{
+		SOPInstanceUID: "1.2.3.4",
+		Create:         dicom.Dataset{Elements: []*dicom.Element{mustNewElement(tag.PerformedProcedureStepStatus, []string{"IN PROGRESS"})}},
+		Set:            dicom.Dataset{Elements: []*dicom.Element{mustNewElement(tag.PerformedProcedureStepStatus, []string{"DISCONTINUED"})}},
+	}
+	if err := a.ReportProcedureStep(step, true); err != nil {
+		t.Fatalf("ReportProcedureStep failed: %v", err)
+	}
+	scp.mu.Lock()
+	defer scp.mu.Unlock()
+	if scp.status[step.SOPInstanceUID] != "IN PROGRESS" {
+		t.Errorf("step is %q, want IN PROGRESS", scp.status[step.SOPInstanceUID])
+	}
+}
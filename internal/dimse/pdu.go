@@ -0,0 +1,358 @@
+// Package dimse implements the DICOM upper layer protocol (PS3.8) and the
+// DIMSE services (PS3.7) needed to simulate a PACS over generated data:
+// association negotiation, C-ECHO, C-FIND, C-MOVE and C-GET.
+package dimse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PDU types (PS3.8 section 9.3)
+const (
+	pduAssociateRQ byte = 0x01
+	pduAssociateAC byte = 0x02
+	pduAssociateRJ byte = 0x03
+	pduDataTF      byte = 0x04
+	pduReleaseRQ   byte = 0x05
+	pduReleaseRP   byte = 0x06
+	pduAbort       byte = 0x07
+)
+
+// Item types of the A-ASSOCIATE PDUs
+const (
+	itemApplicationContext     byte = 0x10
+	itemPresentationContextRQ  byte = 0x20
+	itemPresentationContextAC  byte = 0x21
+	itemAbstractSyntax         byte = 0x30
+	itemTransferSyntax         byte = 0x40
+	itemUserInformation        byte = 0x50
+	itemMaxLength              byte = 0x51
+	itemImplementationClassUID byte = 0x52
+	itemRoleSelection          byte = 0x54
+	itemImplementationVersion  byte = 0x55
+)
+
+// Results of a presentation context negotiation
+const (
+	ContextAccepted                  byte = 0
+	ContextUserRejection             byte = 1
+	ContextNoReason                  byte = 2
+	ContextAbstractSyntaxUnsupported byte = 3
+	ContextTransferSyntaxUnsupported byte = 4
+)
+
+// maxPDUBodyLength bounds the PDUs read from a peer, whatever it announces
+const maxPDUBodyLength = 64 << 20
+
+// PresentationContext is a presentation context of an association: the
+// proposed transfer syntaxes of an abstract syntax and, once negotiated,
+// the result and the accepted transfer syntax.
+type PresentationContext struct {
+	ID               byte
+	AbstractSyntax   string
+	TransferSyntaxes []string // Proposed transfer syntaxes
+	Result           byte
+	TransferSyntax   string // Accepted transfer syntax
+
+	// SCPRole is set when the requestor acts as SCP of the abstract syntax,
+	// as needed to receive the C-STORE sub-operations of a C-GET (SCP/SCU
+	// role selection, PS3.7 D.3.3.4)
+	SCPRole bool
+}
+
+// associate is the content of an A-ASSOCIATE-RQ or A-ASSOCIATE-AC PDU.
+type associate struct {
+	calledAE               string
+	callingAE              string
+	contexts               []PresentationContext
+	maxPDULength           uint32 // Maximum P-DATA-TF length accepted by the sender (0 = unlimited)
+	implementationClassUID string
+	implementationVersion  string
+	roles                  []roleSelection
+}
+
+// roleSelection is an SCP/SCU role selection sub-item: the roles of the
+// association requestor for a SOP class.
+type roleSelection struct {
+	sopClassUID string
+	scu, scp    bool
+}
+
+// rejection is the content of an A-ASSOCIATE-RJ PDU.
+type rejection struct {
+	result byte // 1 = permanent, 2 = transient
+	source byte // 1 = service user, 2 = ACSE provider, 3 = presentation provider
+	reason byte
+}
+
+// Error returns a description of the rejection.
+func (r rejection) Error() string {
+	return fmt.Sprintf("association rejected (result %d, source %d, reason %d)", r.result, r.source, r.reason)
+}
+
+// pdv is a presentation data value item of a P-DATA-TF PDU.
+type pdv struct {
+	contextID byte
+	command   bool // Command (true) or data set (false) fragment
+	last      bool // Last fragment of the command or data set
+	data      []byte
+}
+
+// readPDU reads a PDU and returns its type and body.
+func readPDU(r io.Reader) (byte, []byte, error) {
+	var header [6]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[2:])
+	if length > maxPDUBodyLength {
+		return 0, nil, fmt.Errorf("PDU length %d exceeds %d", length, maxPDUBodyLength)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, fmt.Errorf("read PDU body: %w", err)
+	}
+	return header[0], body, nil
+}
+
+// writePDU writes a PDU of the given type and body.
+func writePDU(w io.Writer, pduType byte, body []byte) error {
+	buf := make([]byte, 6, 6+len(body))
+	buf[0] = pduType
+	binary.BigEndian.PutUint32(buf[2:], uint32(len(body)))
+	_, err := w.Write(append(buf, body...))
+	return err
+}
+
+// encodeAssociate encodes an A-ASSOCIATE-RQ (request) or A-ASSOCIATE-AC body.
+func encodeAssociate(a associate, request bool) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, uint16(1)) // Protocol version
+	buf.Write([]byte{0, 0})
+	buf.WriteString(padAE(a.calledAE))
+	buf.WriteString(padAE(a.callingAE))
+	buf.Write(make([]byte, 32))
+
+	writeItem(&buf, itemApplicationContext, []byte(ApplicationContextName))
+	for _, pc := range a.contexts {
+		var item bytes.Buffer
+		if request {
+			item.Write([]byte{pc.ID, 0, 0, 0})
+			writeItem(&item, itemAbstractSyntax, []byte(pc.AbstractSyntax))
+			for _, ts := range pc.TransferSyntaxes {
+				writeItem(&item, itemTransferSyntax, []byte(ts))
+			}
+			writeItem(&buf, itemPresentationContextRQ, item.Bytes())
+		} else {
+			item.Write([]byte{pc.ID, 0, pc.Result, 0})
+			// The transfer syntax is not significant when the context is rejected
+			ts := pc.TransferSyntax
+			if pc.Result != ContextAccepted {
+				ts = ""
+			}
+			writeItem(&item, itemTransferSyntax, []byte(ts))
+			writeItem(&buf, itemPresentationContextAC, item.Bytes())
+		}
+	}
+
+	var user bytes.Buffer
+	maxLength := make([]byte, 4)
+	binary.BigEndian.PutUint32(maxLength, a.maxPDULength)
+	writeItem(&user, itemMaxLength, maxLength)
+	writeItem(&user, itemImplementationClassUID, []byte(a.implementationClassUID))
+	for _, role := range a.roles {
+		var sub bytes.Buffer
+		_ = binary.Write(&sub, binary.BigEndian, uint16(len(role.sopClassUID)))
+		sub.WriteString(role.sopClassUID)
+		sub.Write([]byte{boolByte(role.scu), boolByte(role.scp)})
+		writeItem(&user, itemRoleSelection, sub.Bytes())
+	}
+	if a.implementationVersion != "" {
+		writeItem(&user, itemImplementationVersion, []byte(a.implementationVersion))
+	}
+	writeItem(&buf, itemUserInformation, user.Bytes())
+
+	return buf.Bytes()
+}
+
+// decodeAssociate decodes an A-ASSOCIATE-RQ or A-ASSOCIATE-AC body.
+func decodeAssociate(body []byte) (associate, error) {
+	var a associate
+	if len(body) < 68 {
+		return a, fmt.Errorf("A-ASSOCIATE PDU too short (%d bytes)", len(body))
+	}
+	a.calledAE = strings.TrimSpace(string(body[4:20]))
+	a.callingAE = strings.TrimSpace(string(body[20:36]))
+
+	items, err := readItems(body[68:])
+	if err != nil {
+		return a, err
+	}
+	for _, it := range items {
+		switch it.itemType {
+		case itemPresentationContextRQ, itemPresentationContextAC:
+			if len(it.data) < 4 {
+				return a, fmt.Errorf("presentation context item too short")
+			}
+			pc := PresentationContext{ID: it.data[0], Result: it.data[2]}
+			subItems, err := readItems(it.data[4:])
+			if err != nil {
+				return a, err
+			}
+			for _, sub := range subItems {
+				value := trimUID(sub.data)
+				switch sub.itemType {
+				case itemAbstractSyntax:
+					pc.AbstractSyntax = value
+				case itemTransferSyntax:
+					if it.itemType == itemPresentationContextAC {
+						pc.TransferSyntax = value
+					} else {
+						pc.TransferSyntaxes = append(pc.TransferSyntaxes, value)
+					}
+				}
+			}
+			a.contexts = append(a.contexts, pc)
+		case itemUserInformation:
+			subItems, err := readItems(it.data)
+			if err != nil {
+				return a, err
+			}
+			for _, sub := range subItems {
+				switch sub.itemType {
+				case itemMaxLength:
+					if len(sub.data) == 4 {
+						a.maxPDULength = binary.BigEndian.Uint32(sub.data)
+					}
+				case itemImplementationClassUID:
+					a.implementationClassUID = trimUID(sub.data)
+				case itemImplementationVersion:
+					a.implementationVersion = strings.TrimSpace(string(sub.data))
+				case itemRoleSelection:
+					if len(sub.data) < 2 {
+						return a, fmt.Errorf("role selection item too short")
+					}
+					n := int(binary.BigEndian.Uint16(sub.data))
+					if 2+n+2 > len(sub.data) {
+						return a, fmt.Errorf("role selection item length %d exceeds item", n)
+					}
+					a.roles = append(a.roles, roleSelection{
+						sopClassUID: trimUID(sub.data[2 : 2+n]),
+						scu:         sub.data[2+n] == 1,
+						scp:         sub.data[3+n] == 1,
+					})
+				}
+			}
+		}
+	}
+	return a, nil
+}
+
+// encodeRejection encodes an A-ASSOCIATE-RJ body.
+func encodeRejection(r rejection) []byte {
+	return []byte{0, r.result, r.source, r.reason}
+}
+
+// decodeRejection decodes an A-ASSOCIATE-RJ body.
+func decodeRejection(body []byte) rejection {
+	if len(body) < 4 {
+		return rejection{}
+	}
+	return rejection{result: body[1], source: body[2], reason: body[3]}
+}
+
+// encodePDVs encodes the items of a P-DATA-TF body.
+func encodePDVs(pdvs ...pdv) []byte {
+	var buf bytes.Buffer
+	for _, p := range pdvs {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(p.data)+2))
+		header := byte(0)
+		if p.command {
+			header |= 0x01
+		}
+		if p.last {
+			header |= 0x02
+		}
+		buf.Write([]byte{p.contextID, header})
+		buf.Write(p.data)
+	}
+	return buf.Bytes()
+}
+
+// decodePDVs decodes the items of a P-DATA-TF body.
+func decodePDVs(body []byte) ([]pdv, error) {
+	var pdvs []pdv
+	for len(body) > 0 {
+		if len(body) < 6 {
+			return nil, fmt.Errorf("truncated PDV item")
+		}
+		length := binary.BigEndian.Uint32(body)
+		if length < 2 || uint64(length) > uint64(len(body)-4) {
+			return nil, fmt.Errorf("invalid PDV item length %d", length)
+		}
+		header := body[5]
+		pdvs = append(pdvs, pdv{
+			contextID: body[4],
+			command:   header&0x01 != 0,
+			last:      header&0x02 != 0,
+			data:      body[6 : 4+length],
+		})
+		body = body[4+length:]
+	}
+	return pdvs, nil
+}
+
+// item is a variable item of an A-ASSOCIATE PDU.
+type item struct {
+	itemType byte
+	data     []byte
+}
+
+// writeItem appends an item with a 16-bit length.
+func writeItem(buf *bytes.Buffer, itemType byte, data []byte) {
+	buf.Write([]byte{itemType, 0})
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(data)))
+	buf.Write(data)
+}
+
+// readItems splits data into items with a 16-bit length.
+func readItems(data []byte) ([]item, error) {
+	var items []item
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("truncated item")
+		}
+		length := int(binary.BigEndian.Uint16(data[2:]))
+		if 4+length > len(data) {
+			return nil, fmt.Errorf("item 0x%02X length %d exceeds PDU", data[0], length)
+		}
+		items = append(items, item{itemType: data[0], data: data[4 : 4+length]})
+		data = data[4+length:]
+	}
+	return items, nil
+}
+
+// padAE pads an AE title to its 16-byte field.
+func padAE(ae string) string {
+	if len(ae) > 16 {
+		ae = ae[:16]
+	}
+	return ae + strings.Repeat(" ", 16-len(ae))
+}
+
+// trimUID removes the padding of a UID value.
+func trimUID(b []byte) string {
+	return strings.TrimRight(string(b), "\x00 ")
+}
+
+// boolByte encodes a flag of an item.
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package dimse
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestAssociate_RoundTrip(t *testing.T) {
+	rq := associate{
+		calledAE:               "DICOMFORGE",
+		callingAE:              "VIEWER",
+		maxPDULength:           32768,
+		implementationClassUID: ImplementationClassUID,
+		implementationVersion:  ImplementationVersionName,
+		contexts: []PresentationContext{
+			{ID: 1, AbstractSyntax: VerificationSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}},
+			{ID: 3, AbstractSyntax: StudyRootFindSOPClass, TransferSyntaxes: []string{ExplicitVRLittleEndian, ImplicitVRLittleEndian}},
+		},
+		roles: []roleSelection{{sopClassUID: "1.2.840.10008.5.1.4.1.1.4", scp: true}},
+	}
+	got, err := decodeAssociate(encodeAssociate(rq, true))
+	if err != nil {
+		t.Fatalf("decodeAssociate failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, rq) {
+		t.Errorf("got %+v, want %+v", got, rq)
+	}
+
+	ac := rq
+	ac.contexts = []PresentationContext{
+		{ID: 1, Result: ContextAccepted, TransferSyntax: ImplicitVRLittleEndian},
+		{ID: 3, Result: ContextAbstractSyntaxUnsupported},
+	}
+	got, err = decodeAssociate(encodeAssociate(ac, false))
+	if err != nil {
+		t.Fatalf("decodeAssociate failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.contexts, ac.contexts) {
+		t.Errorf("got contexts %+v, want %+v", got.contexts, ac.contexts)
+	}
+}
+
+func TestPDVs_RoundTrip(t *testing.T) {
+	pdvs := []pdv{
+		{contextID: 1, command: true, last: true, data: []byte{1, 2, 3, 4}},
+		{contextID: 1, command: false, last: false, data: []byte{5, 6}},
+	}
+	got, err := decodePDVs(encodePDVs(pdvs...))
+	if err != nil {
+		t.Fatalf("decodePDVs failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, pdvs) {
+		t.Errorf("got %+v, want %+v", got, pdvs)
+	}
+}
+
+func TestPDU_ReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writePDU(&buf, pduDataTF, []byte("body")); err != nil {
+		t.Fatalf("writePDU failed: %v", err)
+	}
+	pduType, body, err := readPDU(&buf)
+	if err != nil {
+		t.Fatalf("readPDU failed: %v", err)
+	}
+	if pduType != pduDataTF || string(body) != "body" {
+		t.Errorf("got type 0x%02X body %q", pduType, body)
+	}
+}
+
+func TestCommand_RoundTrip(t *testing.T) {
+	tests := []Command{
+		{CommandField: CEchoRQ, MessageID: 1, AffectedSOPClassUID: VerificationSOPClass},
+		{CommandField: CFindRQ, MessageID: 2, AffectedSOPClassUID: StudyRootFindSOPClass, HasDataSet: true},
+		{CommandField: CMoveRQ, MessageID: 3, AffectedSOPClassUID: StudyRootMoveSOPClass, MoveDestination: "STORE", Priority: PriorityHigh, HasDataSet: true},
+		{CommandField: CMoveRSP, MessageIDBeingRespondedTo: 3, AffectedSOPClassUID: StudyRootMoveSOPClass, Status: StatusPending,
+			RemainingSuboperations: 4, CompletedSuboperations: 2, FailedSuboperations: 1},
+		{CommandField: CStoreRQ, MessageID: 4, AffectedSOPClassUID: "1.2.840.10008.5.1.4.1.1.4", AffectedSOPInstanceUID: "1.2.3",
+			HasDataSet: true, MoveOriginatorAETitle: "VIEWER", MoveOriginatorMessageID: 3},
+		{CommandField: CFindRSP, MessageIDBeingRespondedTo: 2, Status: StatusIdentifierMismatch, ErrorComment: "bad level"},
+		{CommandField: CGetRQ, MessageID: 5, AffectedSOPClassUID: StudyRootGetSOPClass, HasDataSet: true},
+		{CommandField: CGetRSP, MessageIDBeingRespondedTo: 5, AffectedSOPClassUID: StudyRootGetSOPClass, Status: StatusSuccess,
+			CompletedSuboperations: 3, WarningSuboperations: 1},
+	}
+	for _, want := range tests {
+		got, err := decodeCommand(encodeCommand(want))
+		if err != nil {
+			t.Fatalf("decodeCommand failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
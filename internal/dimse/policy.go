@@ -0,0 +1,52 @@
+package dimse
+
+import "slices"
+
+// Policy restricts the negotiation of the associations accepted by a
+// Server, so that the fallback logic of clients can be tested
+// deterministically. The zero Policy accepts every supported context.
+type Policy struct {
+	// RejectSOPClasses are abstract syntaxes whose contexts are rejected
+	// by the user (result 1)
+	RejectSOPClasses []string
+	// RejectTransferSyntaxes are never accepted; a context left without
+	// transfer syntax is rejected (result 4)
+	RejectTransferSyntaxes []string
+	// MaxPDULength is the maximum P-DATA-TF length announced to the
+	// requestor and enforced on the PDUs it sends (0 = DefaultMaxPDULength)
+	MaxPDULength uint32
+	// MaxContexts is the number of contexts accepted per association;
+	// further contexts are rejected without reason (result 2). 0 = no limit
+	MaxContexts int
+}
+
+// negotiator returns the negotiation function of an association, applying
+// the policy to the choices of accept.
+func (p Policy) negotiator(accept func(pc PresentationContext) (string, byte)) func(pc PresentationContext) (string, byte) {
+	accepted := 0
+	return func(pc PresentationContext) (string, byte) {
+		if slices.Contains(p.RejectSOPClasses, pc.AbstractSyntax) {
+			return "", ContextUserRejection
+		}
+		var syntaxes []string
+		for _, ts := range pc.TransferSyntaxes {
+			if !slices.Contains(p.RejectTransferSyntaxes, ts) {
+				syntaxes = append(syntaxes, ts)
+			}
+		}
+		if len(syntaxes) == 0 {
+			return "", ContextTransferSyntaxUnsupported
+		}
+		pc.TransferSyntaxes = syntaxes
+
+		ts, result := accept(pc)
+		if result != ContextAccepted {
+			return ts, result
+		}
+		if p.MaxContexts > 0 && accepted >= p.MaxContexts {
+			return "", ContextNoReason
+		}
+		accepted++
+		return ts, result
+	}
+}
@@ -0,0 +1,30 @@
+package dimse
+
+import "testing"
+
+func TestPolicy_Negotiator(t *testing.T) {
+	negotiate := Policy{
+		RejectSOPClasses:       []string{StudyRootMoveSOPClass},
+		RejectTransferSyntaxes: []string{ExplicitVRLittleEndian},
+		MaxContexts:            2,
+	}.negotiator(acceptContext)
+
+	tests := []struct {
+		pc         PresentationContext
+		wantTS     string
+		wantResult byte
+	}{
+		{PresentationContext{AbstractSyntax: StudyRootMoveSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}}, "", ContextUserRejection},
+		{PresentationContext{AbstractSyntax: StudyRootFindSOPClass, TransferSyntaxes: []string{ExplicitVRLittleEndian}}, "", ContextTransferSyntaxUnsupported},
+		{PresentationContext{AbstractSyntax: StudyRootFindSOPClass, TransferSyntaxes: []string{ExplicitVRLittleEndian, ImplicitVRLittleEndian}}, ImplicitVRLittleEndian, ContextAccepted},
+		{PresentationContext{AbstractSyntax: "1.2.3", TransferSyntaxes: []string{ImplicitVRLittleEndian}}, "", ContextAbstractSyntaxUnsupported},
+		{PresentationContext{AbstractSyntax: VerificationSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}}, ImplicitVRLittleEndian, ContextAccepted},
+		{PresentationContext{AbstractSyntax: PatientRootFindSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}}, "", ContextNoReason},
+	}
+	for i, tt := range tests {
+		ts, result := negotiate(tt.pc)
+		if ts != tt.wantTS || result != tt.wantResult {
+			t.Errorf("context %d: got (%q, %d), want (%q, %d)", i, ts, result, tt.wantTS, tt.wantResult)
+		}
+	}
+}
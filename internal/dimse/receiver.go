@@ -0,0 +1,345 @@
+package dimse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Receiver is a Storage SCP writing the instances it receives with C-STORE
+// into a directory, and answering C-ECHO. It is a disposable destination to
+// test modality simulators and routers against.
+type Receiver struct {
+	AETitle     string // Called AE title accepted ("" accepts any)
+	Dir         string // Directory of the received files, as <study>/<series>/<instance>.dcm
+	SummaryPath string // JSON summary, rewritten after each association ("" = none)
+	Policy      Policy // Restrictions of the association negotiation
+	Quiet       bool   // Do not log associations and requests
+
+	summaryMu    sync.Mutex // Serializes the writes of the summary
+	mu           sync.Mutex
+	associations int
+	failed       int
+	duplicates   int
+	instances    []ReceivedInstance
+	byUID        map[string]int // Index in instances by SOP Instance UID
+}
+
+// ReceivedInstance is an instance stored by a Receiver.
+type ReceivedInstance struct {
+	CallingAETitle    string `json:"callingAETitle"`
+	SOPClassUID       string `json:"sopClassUID"`
+	SOPInstanceUID    string `json:"sopInstanceUID"`
+	TransferSyntaxUID string `json:"transferSyntaxUID"`
+	PatientID         string `json:"patientID"`
+	StudyInstanceUID  string `json:"studyInstanceUID"`
+	SeriesInstanceUID string `json:"seriesInstanceUID"`
+	Modality          string `json:"modality"`
+	Path              string `json:"path"` // Relative to the receiver directory
+	Bytes             int    `json:"bytes"`
+}
+
+// ReceiveSummary sums up what a Receiver received: the instances, with the
+// last copy of the ones received several times, grouped by study and series.
+type ReceiveSummary struct {
+	Associations int                `json:"associations"`
+	Instances    int                `json:"instances"`
+	Failed       int                `json:"failed"`     // C-STORE requests answered with a failure
+	Duplicates   int                `json:"duplicates"` // Instances received again, overwritten
+	Bytes        int64              `json:"bytes"`
+	Studies      []ReceivedStudy    `json:"studies"`
+	Received     []ReceivedInstance `json:"received"`
+}
+
+// ReceivedStudy counts the instances received for a study.
+type ReceivedStudy struct {
+	StudyInstanceUID string           `json:"studyInstanceUID"`
+	PatientID        string           `json:"patientID"`
+	Series           []ReceivedSeries `json:"series"`
+}
+
+// ReceivedSeries counts the instances received for a series.
+type ReceivedSeries struct {
+	SeriesInstanceUID string `json:"seriesInstanceUID"`
+	Modality          string `json:"modality"`
+	Instances         int    `json:"instances"`
+}
+
+// ListenAndServe listens on the TCP address addr and serves associations.
+func (r *Receiver) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return r.Serve(l)
+}
+
+// Serve accepts connections on l and serves each association in its own
+// goroutine. When l is closed, the associations in progress are closed, the
+// summary is written and Serve returns nil.
+func (r *Receiver) Serve(l net.Listener) error {
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	err := serveConns(l, r.serveConn)
+	if summaryErr := r.writeSummary(); err == nil {
+		err = summaryErr
+	}
+	return err
+}
+
+// Summary returns the summary of the instances received so far.
+func (r *Receiver) Summary() ReceiveSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := ReceiveSummary{
+		Associations: r.associations,
+		Instances:    len(r.instances),
+		Failed:       r.failed,
+		Duplicates:   r.duplicates,
+		Studies:      []ReceivedStudy{},
+		Received:     append([]ReceivedInstance{}, r.instances...),
+	}
+	studies := make(map[string]*ReceivedStudy)
+	series := make(map[string]int) // Index in the Series of its study
+	var studyOrder []string
+	for _, inst := range r.instances {
+		summary.Bytes += int64(inst.Bytes)
+		study, ok := studies[inst.StudyInstanceUID]
+		if !ok {
+			study = &ReceivedStudy{StudyInstanceUID: inst.StudyInstanceUID, PatientID: inst.PatientID}
+			studies[inst.StudyInstanceUID] = study
+			studyOrder = append(studyOrder, inst.StudyInstanceUID)
+		}
+		key := inst.StudyInstanceUID + "/" + inst.SeriesInstanceUID
+		i, ok := series[key]
+		if !ok {
+			i = len(study.Series)
+			series[key] = i
+			study.Series = append(study.Series, ReceivedSeries{SeriesInstanceUID: inst.SeriesInstanceUID, Modality: inst.Modality})
+		}
+		study.Series[i].Instances++
+	}
+	sort.Strings(studyOrder)
+	for _, uid := range studyOrder {
+		study := studies[uid]
+		sort.Slice(study.Series, func(i, j int) bool {
+			return study.Series[i].SeriesInstanceUID < study.Series[j].SeriesInstanceUID
+		})
+		summary.Studies = append(summary.Studies, *study)
+	}
+	return summary
+}
+
+// writeSummary writes the summary to SummaryPath, when set.
+func (r *Receiver) writeSummary() error {
+	if r.SummaryPath == "" {
+		return nil
+	}
+	r.summaryMu.Lock()
+	defer r.summaryMu.Unlock()
+	data, err := json.MarshalIndent(r.Summary(), "", "  ")
+	if err != nil {
+		return err
+	}
+	// Replaced at once, so that readers never see a partial summary
+	tmp, err := os.CreateTemp(filepath.Dir(r.SummaryPath), ".summary-*")
+	if err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), r.SummaryPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write summary: %w", err)
+	}
+	return nil
+}
+
+// logf logs a receiver event unless the receiver is quiet.
+func (r *Receiver) logf(format string, args ...any) {
+	if !r.Quiet {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// acceptStorageContext accepts any abstract syntax, as a storage SOP class
+// or Verification, preferring Explicit VR Little Endian. Other transfer
+// syntaxes (compressed ones) are stored as they are: when neither little
+// endian syntax is proposed, the first one is accepted.
+func acceptStorageContext(pc PresentationContext) (string, byte) {
+	for _, ts := range []string{ExplicitVRLittleEndian, ImplicitVRLittleEndian} {
+		for _, proposed := range pc.TransferSyntaxes {
+			if proposed == ts {
+				return ts, ContextAccepted
+			}
+		}
+	}
+	if len(pc.TransferSyntaxes) > 0 {
+		return pc.TransferSyntaxes[0], ContextAccepted
+	}
+	return "", ContextTransferSyntaxUnsupported
+}
+
+// serveConn negotiates an association and stores its instances until it is
+// released or aborted, then rewrites the summary.
+func (r *Receiver) serveConn(conn net.Conn) {
+	acc := acceptor{
+		aeTitle:      r.AETitle,
+		maxPDULength: r.Policy.MaxPDULength,
+		negotiate:    r.Policy.negotiator(acceptStorageContext),
+	}
+	a, err := acc.accept(conn)
+	if err != nil {
+		r.logf("Association from %s refused: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	r.mu.Lock()
+	r.associations++
+	r.mu.Unlock()
+	r.logf("Association from %s (%s)", a.CallingAE(), conn.RemoteAddr())
+	defer func() {
+		if err := r.writeSummary(); err != nil {
+			r.logf("%v", err)
+		}
+	}()
+
+	for {
+		msg, err := a.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, ErrReleased) {
+				r.logf("Association from %s ended: %v", a.CallingAE(), err)
+			}
+			return
+		}
+		rsp := Command{
+			CommandField:              msg.Command.CommandField | 0x8000,
+			MessageIDBeingRespondedTo: msg.Command.MessageID,
+			AffectedSOPClassUID:       msg.Command.AffectedSOPClassUID,
+			Status:                    StatusUnrecognizedOperation,
+		}
+		switch msg.Command.CommandField {
+		case CEchoRQ:
+			rsp.Status = StatusSuccess
+		case CStoreRQ:
+			rsp.AffectedSOPInstanceUID = msg.Command.AffectedSOPInstanceUID
+			rsp.Status, rsp.ErrorComment = r.store(a, msg)
+		}
+		if err := a.WriteMessage(&Message{ContextID: msg.ContextID, Command: rsp}); err != nil {
+			r.logf("Association from %s aborted: %v", a.CallingAE(), err)
+			_ = a.Abort()
+			return
+		}
+	}
+}
+
+// store writes the data set of a C-STORE request as a DICOM file and
+// returns the status of the response.
+func (r *Receiver) store(a *Association, msg *Message) (uint16, string) {
+	pc, _ := a.Context(msg.ContextID)
+	inst := ReceivedInstance{
+		CallingAETitle:    a.CallingAE(),
+		SOPClassUID:       msg.Command.AffectedSOPClassUID,
+		SOPInstanceUID:    msg.Command.AffectedSOPInstanceUID,
+		TransferSyntaxUID: pc.TransferSyntax,
+	}
+	fail := func(status uint16, err error) (uint16, string) {
+		r.logf("C-STORE of %s from %s failed: %v", inst.SOPInstanceUID, inst.CallingAETitle, err)
+		r.mu.Lock()
+		r.failed++
+		r.mu.Unlock()
+		return status, err.Error()
+	}
+	if msg.Data == nil {
+		return fail(StatusUnableToProcess, fmt.Errorf("missing data set"))
+	}
+
+	// The file is stored even when the data set cannot be parsed: its keys
+	// are then unknown
+	if ds, err := decodeStoreKeys(msg.Data, pc.TransferSyntax); err == nil {
+		inst.PatientID = firstString(ds, tag.PatientID)
+		inst.StudyInstanceUID = firstString(ds, tag.StudyInstanceUID)
+		inst.SeriesInstanceUID = firstString(ds, tag.SeriesInstanceUID)
+		inst.Modality = firstString(ds, tag.Modality)
+	} else {
+		r.logf("C-STORE of %s from %s: %v", inst.SOPInstanceUID, inst.CallingAETitle, err)
+	}
+
+	inst.Path = filepath.ToSlash(filepath.Join(pathComponent(inst.StudyInstanceUID), pathComponent(inst.SeriesInstanceUID), pathComponent(inst.SOPInstanceUID)+".dcm"))
+	file := part10Header(fileMeta{sopClassUID: inst.SOPClassUID, sopInstanceUID: inst.SOPInstanceUID, transferSyntaxUID: inst.TransferSyntaxUID})
+	file = append(file, msg.Data...)
+	inst.Bytes = len(file)
+	path := filepath.Join(r.Dir, filepath.FromSlash(inst.Path))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fail(StatusOutOfResources, err)
+	}
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		return fail(StatusOutOfResources, err)
+	}
+
+	r.mu.Lock()
+	if r.byUID == nil {
+		r.byUID = make(map[string]int)
+	}
+	if i, ok := r.byUID[inst.SOPInstanceUID]; ok {
+		r.duplicates++
+		r.instances[i] = inst
+	} else {
+		r.byUID[inst.SOPInstanceUID] = len(r.instances)
+		r.instances = append(r.instances, inst)
+	}
+	r.mu.Unlock()
+	r.logf("C-STORE from %s: %s", inst.CallingAETitle, inst.Path)
+	return StatusSuccess, ""
+}
+
+// decodeStoreKeys decodes a received data set up to its pixel data, for the
+// keys of the summary.
+func decodeStoreKeys(data []byte, transferSyntaxUID string) (dicom.Dataset, error) {
+	file := part10Header(fileMeta{transferSyntaxUID: transferSyntaxUID})
+	file = append(file, data...)
+	return dicom.Parse(bytes.NewReader(file), int64(len(file)), nil, dicom.SkipPixelData(), dicom.AllowUnknownSpecificCharacterSet())
+}
+
+// firstString returns the first string value of an element, or "".
+func firstString(ds dicom.Dataset, t tag.Tag) string {
+	elem, err := ds.FindElementByTag(t)
+	if err != nil {
+		return ""
+	}
+	if values := elementStrings(elem); len(values) > 0 {
+		return strings.TrimRight(values[0], "\x00 ")
+	}
+	return ""
+}
+
+// pathComponent returns a UID as a file name: characters other than digits
+// and dots are replaced, and a missing UID is "unknown".
+func pathComponent(uid string) string {
+	if strings.Trim(uid, ".") == "" {
+		return "unknown"
+	}
+	return strings.Map(func(c rune) rune {
+		if (c >= '0' && c <= '9') || c == '.' {
+			return c
+		}
+		return '_'
+	}, uid)
+}
@@ -0,0 +1,153 @@
+package dimse
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// startStoreSCP serves r, as STORESCP, on a local port, until stop is
+// called or the test ends.
+func startStoreSCP(t *testing.T, r *Receiver) (addr string, stop func()) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	r.AETitle, r.Quiet = "STORESCP", true
+	done := make(chan error, 1)
+	go func() { done <- r.Serve(l) }()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			_ = l.Close()
+			if err := <-done; err != nil {
+				t.Errorf("Serve failed: %v", err)
+			}
+		})
+	}
+	t.Cleanup(stop)
+	return l.Addr().String(), stop
+}
+
+func TestReceiver_MoveAndStore(t *testing.T) {
+	dir := t.TempDir()
+	r := &Receiver{Dir: filepath.Join(dir, "received"), SummaryPath: filepath.Join(dir, "summary.json")}
+	storeAddr, stop := startStoreSCP(t, r)
+	addr, files := startServer(t, &Server{Destinations: map[string]string{"STORESCP": storeAddr}})
+
+	// Every instance, moved by the Q/R server
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", queryContexts())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	rsp, err := a.Move(StudyRootMoveSOPClass, "STORESCP", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}}))
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	_ = a.Release()
+	if int(rsp.CompletedSuboperations) != len(files) {
+		t.Fatalf("moved %d instances, want %d", rsp.CompletedSuboperations, len(files))
+	}
+
+	// One of them again, directly, after a C-ECHO
+	data, err := os.ReadFile(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, dataset, err := splitPart10(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := Dial(storeAddr, "MODALITY", "STORESCP", []PresentationContext{
+		{AbstractSyntax: VerificationSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}},
+		{AbstractSyntax: meta.sopClassUID, TransferSyntaxes: []string{meta.transferSyntaxUID}},
+	})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if err := store.Echo(); err != nil {
+		t.Errorf("Echo failed: %v", err)
+	}
+	stored, err := store.Store(meta.sopClassUID, files[0].SOPInstanceUID, meta.transferSyntaxUID, dataset, "", 0)
+	if err != nil || stored.Status != StatusSuccess {
+		t.Errorf("Store returned status 0x%04X: %v", stored.Status, err)
+	}
+	if err := store.Release(); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+
+	summary := r.Summary()
+	if summary.Instances != len(files) || summary.Duplicates != 1 || summary.Failed != 0 || summary.Associations != 2 {
+		t.Errorf("summary has %d instances, %d duplicates, %d failures and %d associations, want %d, 1, 0 and 2",
+			summary.Instances, summary.Duplicates, summary.Failed, summary.Associations, len(files))
+	}
+	studies := make(map[string]bool)
+	for _, f := range files {
+		studies[f.StudyUID] = true
+	}
+	if len(summary.Studies) != len(studies) {
+		t.Errorf("summary has %d studies, want %d", len(summary.Studies), len(studies))
+	}
+
+	// Received files are complete DICOM files with the UIDs of the originals
+	generated := make(map[string]string)
+	for _, f := range files {
+		generated[f.SOPInstanceUID] = f.StudyUID
+	}
+	for _, inst := range summary.Received {
+		ds, err := dicom.ParseFile(filepath.Join(r.Dir, inst.Path), nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", inst.Path, err)
+		}
+		uid := dicom.MustGetStrings(mustFind(t, ds, tag.SOPInstanceUID).Value)[0]
+		if uid != inst.SOPInstanceUID || generated[uid] != inst.StudyInstanceUID {
+			t.Errorf("%s holds %s, summary says %s of study %s", inst.Path, uid, inst.SOPInstanceUID, inst.StudyInstanceUID)
+		}
+		if got := dicom.MustGetStrings(mustFind(t, ds, tag.MediaStorageSOPInstanceUID).Value)[0]; got != uid {
+			t.Errorf("%s: MediaStorageSOPInstanceUID %s, want %s", inst.Path, got, uid)
+		}
+	}
+
+	// The summary file is complete once the receiver stops
+	stop()
+	raw, err := os.ReadFile(r.SummaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written ReceiveSummary
+	if err := json.Unmarshal(raw, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Instances != summary.Instances || written.Duplicates != 1 {
+		t.Errorf("summary file has %d instances and %d duplicates, want %d and 1", written.Instances, written.Duplicates, summary.Instances)
+	}
+}
+
+func TestPathComponent(t *testing.T) {
+	for uid, want := range map[string]string{
+		"1.2.840.10008": "1.2.840.10008",
+		"":              "unknown",
+		"..":            "unknown",
+		"1.2/../3":      "1.2_.._3",
+	} {
+		if got := pathComponent(uid); got != want {
+			t.Errorf("pathComponent(%q) = %q, want %q", uid, got, want)
+		}
+	}
+}
+
+func mustFind(t *testing.T, ds dicom.Dataset, tg tag.Tag) *dicom.Element {
+	t.Helper()
+	elem, err := ds.FindElementByTag(tg)
+	if err != nil {
+		t.Fatalf("%v: %v", tg, err)
+	}
+	return elem
+}
@@ -0,0 +1,272 @@
+package dimse
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// ErrNotRenderable is returned when the pixel data of an instance cannot be
+// rendered: encapsulated frames, or an unsupported image pixel module.
+var ErrNotRenderable = errors.New("pixel data cannot be rendered")
+
+// ErrNoSuchFrame is returned when rendering a frame the instance does not
+// have.
+var ErrNoSuchFrame = errors.New("frame out of range")
+
+// VOI LUT functions (PS3.3 C.11.2.1.3)
+const (
+	VOILinear      = "LINEAR"
+	VOILinearExact = "LINEAR_EXACT"
+	VOISigmoid     = "SIGMOID"
+)
+
+// Window is a VOI window applied to the modality values of monochrome
+// images.
+type Window struct {
+	Center, Width float64
+	Function      string // VOILinear, VOILinearExact or VOISigmoid; "" is VOILinear
+}
+
+// apply maps a modality value to an 8-bit display value (PS3.3 C.11.2.1.2).
+func (w Window) apply(x float64) uint8 {
+	var y float64
+	switch w.Function {
+	case VOILinearExact:
+		y = (x-w.Center)/w.Width + 0.5
+	case VOISigmoid:
+		y = 1 / (1 + math.Exp(-4*(x-w.Center)/w.Width))
+	default:
+		if w.Width <= 1 {
+			y = 0
+			if x > w.Center-0.5 {
+				y = 1
+			}
+		} else {
+			y = (x-(w.Center-0.5))/(w.Width-1) + 0.5
+		}
+	}
+	return uint8(math.Round(255 * math.Min(1, math.Max(0, y))))
+}
+
+// Render decodes frame n (from 1) of the instance into an image for display.
+// Monochrome frames are mapped through the Modality LUT (RescaleSlope and
+// RescaleIntercept) and window, or the first WindowCenter/WindowWidth and
+// VOILUTFunction of the instance when window is nil, or the range of the
+// frame when it has none; MONOCHROME1 is inverted. RGB, YBR_FULL,
+// YBR_FULL_422 and PALETTE COLOR frames are converted to RGB.
+func (inst *Instance) Render(n int, window *Window) (image.Image, error) {
+	frames, encapsulated, err := inst.Frames()
+	if err != nil {
+		return nil, err
+	}
+	if encapsulated {
+		return nil, fmt.Errorf("%w: encapsulated transfer syntax %s", ErrNotRenderable, inst.TransferSyntaxUID)
+	}
+	if n < 1 || n > len(frames) {
+		return nil, fmt.Errorf("%w: frame %d, the instance has %d frame(s)", ErrNoSuchFrame, n, len(frames))
+	}
+	data := frames[n-1]
+	rows, _ := strconv.Atoi(inst.key(tag.Rows))
+	columns, _ := strconv.Atoi(inst.key(tag.Columns))
+	bits, _ := strconv.Atoi(inst.key(tag.BitsAllocated))
+	samples, _ := strconv.Atoi(inst.key(tag.SamplesPerPixel))
+	pixels := rows * columns
+
+	photometric := strings.TrimSpace(inst.key(tag.PhotometricInterpretation))
+	if want := renderSamples[photometric]; want != 0 && samples != want {
+		return nil, fmt.Errorf("%w: %s with SamplesPerPixel %d", ErrNotRenderable, photometric, samples)
+	}
+	switch photometric {
+	case "MONOCHROME1", "MONOCHROME2":
+		if bits != 8 && bits != 16 {
+			return nil, fmt.Errorf("%w: BitsAllocated %d", ErrNotRenderable, bits)
+		}
+		values := inst.modalityValues(data, pixels, bits)
+		if window == nil {
+			window = inst.window(values)
+		}
+		img := image.NewGray(image.Rect(0, 0, columns, rows))
+		for i, x := range values {
+			v := window.apply(x)
+			if photometric == "MONOCHROME1" {
+				v = 255 - v
+			}
+			img.Pix[i] = v
+		}
+		return img, nil
+	case "RGB", "YBR_FULL", "YBR_FULL_422":
+		if bits != 8 || (photometric == "YBR_FULL_422" && pixels%2 != 0) {
+			return nil, fmt.Errorf("%w: %s with BitsAllocated %d and %d pixels", ErrNotRenderable, photometric, bits, pixels)
+		}
+		img := image.NewRGBA(image.Rect(0, 0, columns, rows))
+		for i := 0; i < pixels; i++ {
+			var c color.RGBA
+			switch {
+			case photometric == "YBR_FULL_422":
+				// Y1 Y2 Cb Cr per pair of pixels along a row
+				pair := data[i/2*4:]
+				r, g, b := color.YCbCrToRGB(pair[i%2], pair[2], pair[3])
+				c = color.RGBA{r, g, b, 255}
+			case inst.key(tag.PlanarConfiguration) == "1":
+				c = color.RGBA{data[i], data[pixels+i], data[2*pixels+i], 255}
+			default:
+				c = color.RGBA{data[3*i], data[3*i+1], data[3*i+2], 255}
+			}
+			if photometric == "YBR_FULL" {
+				c.R, c.G, c.B = color.YCbCrToRGB(c.R, c.G, c.B)
+			}
+			img.SetRGBA(i%columns, i/columns, c)
+		}
+		return img, nil
+	case "PALETTE COLOR":
+		if bits != 8 {
+			return nil, fmt.Errorf("%w: PALETTE COLOR with BitsAllocated %d", ErrNotRenderable, bits)
+		}
+		lut, err := inst.palette()
+		if err != nil {
+			return nil, err
+		}
+		img := image.NewRGBA(image.Rect(0, 0, columns, rows))
+		for i, index := range data[:pixels] {
+			img.SetRGBA(i%columns, i/columns, lut.color(int(index)))
+		}
+		return img, nil
+	default:
+		return nil, fmt.Errorf("%w: photometric interpretation %q", ErrNotRenderable, photometric)
+	}
+}
+
+// renderSamples is the SamplesPerPixel of the renderable photometric
+// interpretations.
+var renderSamples = map[string]int{
+	"MONOCHROME1":   1,
+	"MONOCHROME2":   1,
+	"RGB":           3,
+	"YBR_FULL":      3,
+	"YBR_FULL_422":  3,
+	"PALETTE COLOR": 1,
+}
+
+// byteOrder returns the byte order of the 16-bit words of the instance.
+func (inst *Instance) byteOrder() binary.ByteOrder {
+	if inst.TransferSyntaxUID == "1.2.840.10008.1.2.2" {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// modalityValues returns the modality values of the first count stored
+// values of a monochrome frame: masked to BitsStored, signed when
+// PixelRepresentation is 1, and rescaled.
+func (inst *Instance) modalityValues(data []byte, count, bits int) []float64 {
+	stored, err := strconv.Atoi(inst.key(tag.BitsStored))
+	if err != nil || stored <= 0 || stored > bits {
+		stored = bits
+	}
+	signed := inst.key(tag.PixelRepresentation) == "1"
+	slope, err := strconv.ParseFloat(strings.TrimSpace(inst.key(tag.RescaleSlope)), 64)
+	if err != nil || slope == 0 {
+		slope = 1
+	}
+	intercept, _ := strconv.ParseFloat(strings.TrimSpace(inst.key(tag.RescaleIntercept)), 64)
+
+	order := inst.byteOrder()
+	mask := uint32(1)<<stored - 1
+	values := make([]float64, count)
+	for i := range values {
+		var raw uint32
+		if bits == 8 {
+			raw = uint32(data[i])
+		} else {
+			raw = uint32(order.Uint16(data[2*i:]))
+		}
+		raw &= mask
+		v := int64(raw)
+		if signed && raw&(1<<(stored-1)) != 0 {
+			v -= int64(1) << stored
+		}
+		values[i] = float64(v)*slope + intercept
+	}
+	return values
+}
+
+// window returns the first VOI window of the instance, or a linear window
+// over the range of values when it has none.
+func (inst *Instance) window(values []float64) *Window {
+	center, err1 := strconv.ParseFloat(strings.TrimSpace(inst.key(tag.WindowCenter)), 64)
+	width, err2 := strconv.ParseFloat(strings.TrimSpace(inst.key(tag.WindowWidth)), 64)
+	if err1 == nil && err2 == nil && width > 0 {
+		function := strings.ToUpper(strings.TrimSpace(inst.key(tag.VOILUTFunction)))
+		return &Window{Center: center, Width: width, Function: function}
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	if len(values) == 0 || high <= low {
+		return &Window{Center: low, Width: 1}
+	}
+	return &Window{Center: (low+high)/2 + 0.5, Width: high - low + 1}
+}
+
+// paletteLUT is the Red, Green and Blue Palette Color Lookup Tables of a
+// PALETTE COLOR instance.
+type paletteLUT struct {
+	first int         // Pixel value mapped to the first entry
+	data  [3][]uint16 // Entries of each table
+	shift int         // Right shift to 8 bits
+}
+
+// color returns the color of a pixel value; values outside the tables take
+// the first or last entry.
+func (lut *paletteLUT) color(v int) color.RGBA {
+	var c [3]uint8
+	for i, entries := range lut.data {
+		k := min(max(v-lut.first, 0), len(entries)-1)
+		c[i] = uint8(entries[k] >> lut.shift)
+	}
+	return color.RGBA{c[0], c[1], c[2], 255}
+}
+
+// palette reads the palette color lookup tables of the instance.
+func (inst *Instance) palette() (*paletteLUT, error) {
+	lut := &paletteLUT{}
+	tables := [3][2]tag.Tag{
+		{tag.RedPaletteColorLookupTableDescriptor, tag.RedPaletteColorLookupTableData},
+		{tag.GreenPaletteColorLookupTableDescriptor, tag.GreenPaletteColorLookupTableData},
+		{tag.BluePaletteColorLookupTableDescriptor, tag.BluePaletteColorLookupTableData},
+	}
+	for i, table := range tables {
+		descriptor, _ := inst.value(table[0])
+		var data []byte
+		if elem, ok := inst.elements[table[1]]; ok {
+			data, _ = elem.Value.GetValue().([]byte)
+		}
+		if len(descriptor) != 3 || len(data) == 0 {
+			return nil, fmt.Errorf("%w: missing or invalid palette color lookup table", ErrNotRenderable)
+		}
+		entries, _ := strconv.Atoi(descriptor[0])
+		if entries == 0 {
+			entries = 65536
+		}
+		lut.first, _ = strconv.Atoi(descriptor[1])
+		bits, _ := strconv.Atoi(descriptor[2])
+		lut.shift = max(bits-8, 0)
+		if len(data) < 2*entries {
+			return nil, fmt.Errorf("%w: palette color lookup table of %d bytes, %d entries expected", ErrNotRenderable, len(data), entries)
+		}
+		lut.data[i] = make([]uint16, entries)
+		for k := range lut.data[i] {
+			lut.data[i][k] = inst.byteOrder().Uint16(data[2*k:])
+		}
+	}
+	return lut, nil
+}
@@ -0,0 +1,403 @@
+package dimse
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Server is a Query/Retrieve SCP answering C-ECHO, C-FIND, C-MOVE and C-GET
+// requests over an Index, and optionally a Modality Worklist SCP answering
+// C-FIND requests over a Worklist.
+type Server struct {
+	AETitle      string            // Called AE title accepted ("" accepts any)
+	Index        *Index            // Instances served
+	Worklist     *Worklist         // Worklist items served (nil = no Modality Worklist service)
+	Destinations map[string]string // C-MOVE destinations: AE title -> host:port
+	Policy       Policy            // Restrictions of the association negotiation
+	Quiet        bool              // Do not log associations and requests
+}
+
+// supportedSOPClasses are the abstract syntaxes accepted by the server
+var supportedSOPClasses = map[string]bool{
+	VerificationSOPClass:    true,
+	PatientRootFindSOPClass: true,
+	PatientRootMoveSOPClass: true,
+	StudyRootFindSOPClass:   true,
+	StudyRootMoveSOPClass:   true,
+	PatientRootGetSOPClass:  true,
+	StudyRootGetSOPClass:    true,
+
+	ModalityWorklistFindSOPClass: true,
+}
+
+// ListenAndServe listens on the TCP address addr and serves associations.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and serves each association in its own
+// goroutine. When l is closed, the associations in progress are closed and
+// Serve returns nil.
+func (s *Server) Serve(l net.Listener) error {
+	return serveConns(l, s.serveConn)
+}
+
+// serveConns accepts connections on l and serves each with serve in its own
+// goroutine. When l is closed, the connections in progress are closed and
+// serveConns returns nil once their goroutines are done.
+func serveConns(l net.Listener, serve func(conn net.Conn)) error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		conns = make(map[net.Conn]bool) // Connections being served
+	)
+	defer func() {
+		mu.Lock()
+		for conn := range conns {
+			_ = conn.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		mu.Lock()
+		conns[conn] = true
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(conn)
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+}
+
+// logf logs a server event unless the server is quiet.
+func (s *Server) logf(format string, args ...any) {
+	if !s.Quiet {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// acceptContext selects the transfer syntax of a proposed context, preferring
+// Explicit VR Little Endian. Any abstract syntax for which the requestor
+// takes the SCP role is accepted, as a storage SOP class of C-GET: when
+// neither little endian syntax is proposed, the first one is accepted.
+func acceptContext(pc PresentationContext) (string, byte) {
+	if !supportedSOPClasses[pc.AbstractSyntax] && !pc.SCPRole {
+		return "", ContextAbstractSyntaxUnsupported
+	}
+	for _, ts := range []string{ExplicitVRLittleEndian, ImplicitVRLittleEndian} {
+		for _, proposed := range pc.TransferSyntaxes {
+			if proposed == ts {
+				return ts, ContextAccepted
+			}
+		}
+	}
+	if pc.SCPRole && len(pc.TransferSyntaxes) > 0 {
+		return pc.TransferSyntaxes[0], ContextAccepted
+	}
+	return "", ContextTransferSyntaxUnsupported
+}
+
+// serveConn negotiates an association and answers its requests until it is
+// released or aborted.
+func (s *Server) serveConn(conn net.Conn) {
+	acc := acceptor{
+		aeTitle:      s.AETitle,
+		maxPDULength: s.Policy.MaxPDULength,
+		negotiate: s.Policy.negotiator(func(pc PresentationContext) (string, byte) {
+			if pc.AbstractSyntax == ModalityWorklistFindSOPClass && s.Worklist == nil {
+				return "", ContextAbstractSyntaxUnsupported
+			}
+			return acceptContext(pc)
+		}),
+	}
+	a, err := acc.accept(conn)
+	if err != nil {
+		s.logf("Association from %s refused: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	s.logf("Association from %s (%s)", a.CallingAE(), conn.RemoteAddr())
+
+	for {
+		msg, err := a.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, ErrReleased) {
+				s.logf("Association from %s ended: %v", a.CallingAE(), err)
+			}
+			return
+		}
+		if err := s.handle(a, msg); err != nil {
+			s.logf("Association from %s aborted: %v", a.CallingAE(), err)
+			_ = a.Abort()
+			return
+		}
+	}
+}
+
+// handle answers a request. Errors are failures of the association; DIMSE
+// failures are reported in response statuses.
+func (s *Server) handle(a *Association, msg *Message) error {
+	cmd := msg.Command
+	switch cmd.CommandField {
+	case CEchoRQ:
+		return s.respond(a, msg, Command{CommandField: CEchoRSP, Status: StatusSuccess}, nil)
+	case CFindRQ:
+		return s.find(a, msg)
+	case CMoveRQ:
+		return s.move(a, msg)
+	case CGetRQ:
+		return s.get(a, msg)
+	case CCancelRQ:
+		// Responses are sent as soon as the request is read: nothing to cancel
+		return nil
+	}
+	return s.respond(a, msg, Command{CommandField: cmd.CommandField | 0x8000, Status: StatusUnrecognizedOperation}, nil)
+}
+
+// respond sends the response to a request message.
+func (s *Server) respond(a *Association, msg *Message, rsp Command, data []byte) error {
+	rsp.MessageIDBeingRespondedTo = msg.Command.MessageID
+	rsp.AffectedSOPClassUID = msg.Command.AffectedSOPClassUID
+	return a.WriteMessage(&Message{ContextID: msg.ContextID, Command: rsp, Data: data})
+}
+
+// identifier decodes the identifier of a C-FIND or C-MOVE request, checking
+// that its level is valid for the information model.
+func (s *Server) identifier(a *Association, msg *Message) (dicom.Dataset, error) {
+	pc, _ := a.Context(msg.ContextID)
+	if msg.Data == nil {
+		return dicom.Dataset{}, fmt.Errorf("missing identifier")
+	}
+	identifier, err := DecodeDataset(msg.Data, pc.TransferSyntax)
+	if err != nil {
+		return identifier, err
+	}
+	switch pc.AbstractSyntax {
+	case StudyRootFindSOPClass, StudyRootMoveSOPClass, StudyRootGetSOPClass:
+		if elem, err := identifier.FindElementByTag(tag.QueryRetrieveLevel); err == nil {
+			if values := elementStrings(elem); len(values) > 0 && values[0] == LevelPatient {
+				return identifier, fmt.Errorf("PATIENT level is not part of the Study Root model")
+			}
+		}
+	}
+	return identifier, nil
+}
+
+// find answers a C-FIND request with a pending response per match.
+func (s *Server) find(a *Association, msg *Message) error {
+	failed := Command{CommandField: CFindRSP}
+	identifier, err := s.identifier(a, msg)
+	if err != nil {
+		failed.Status, failed.ErrorComment = StatusIdentifierMismatch, err.Error()
+		return s.respond(a, msg, failed, nil)
+	}
+	pc, _ := a.Context(msg.ContextID)
+	var matches []dicom.Dataset
+	if pc.AbstractSyntax == ModalityWorklistFindSOPClass {
+		matches, err = s.Worklist.Find(identifier)
+	} else {
+		matches, err = s.Index.Find(identifier)
+	}
+	if err != nil {
+		failed.Status, failed.ErrorComment = StatusIdentifierMismatch, err.Error()
+		return s.respond(a, msg, failed, nil)
+	}
+	s.logf("C-FIND from %s: %d match(es)", a.CallingAE(), len(matches))
+
+	for _, match := range matches {
+		data, err := EncodeDataset(match, pc.TransferSyntax)
+		if err != nil {
+			failed.Status, failed.ErrorComment = StatusUnableToProcess, err.Error()
+			return s.respond(a, msg, failed, nil)
+		}
+		if err := s.respond(a, msg, Command{CommandField: CFindRSP, Status: StatusPending}, data); err != nil {
+			return err
+		}
+	}
+	return s.respond(a, msg, Command{CommandField: CFindRSP, Status: StatusSuccess}, nil)
+}
+
+// move answers a C-MOVE request: the matching instances are sent with
+// C-STORE sub-operations on a new association to the destination AE.
+func (s *Server) move(a *Association, msg *Message) error {
+	failed := Command{CommandField: CMoveRSP}
+	addr, ok := s.Destinations[msg.Command.MoveDestination]
+	if !ok {
+		failed.Status, failed.ErrorComment = StatusMoveDestinationUnknown, fmt.Sprintf("unknown destination %q", msg.Command.MoveDestination)
+		return s.respond(a, msg, failed, nil)
+	}
+	instances, err := s.match(a, msg)
+	if err != nil {
+		failed.Status, failed.ErrorComment = StatusIdentifierMismatch, err.Error()
+		return s.respond(a, msg, failed, nil)
+	}
+	s.logf("C-MOVE from %s to %s: %d instance(s)", a.CallingAE(), msg.Command.MoveDestination, len(instances))
+	if len(instances) == 0 {
+		return s.respond(a, msg, Command{CommandField: CMoveRSP, Status: StatusSuccess}, nil)
+	}
+
+	dest, err := Dial(addr, s.AETitle, msg.Command.MoveDestination, storageContexts(instances))
+	if err != nil {
+		failed.Status, failed.ErrorComment = StatusUnableToProcess, err.Error()
+		failed.FailedSuboperations = uint16(min(len(instances), 0xFFFF))
+		return s.respond(a, msg, failed, nil)
+	}
+	defer func() { _ = dest.Release() }()
+
+	return s.retrieve(a, msg, instances, func(inst *Instance) (uint16, error) {
+		return storeInstance(dest, inst, a.CallingAE(), msg.Command.MessageID)
+	})
+}
+
+// get answers a C-GET request: the matching instances are sent with C-STORE
+// sub-operations on the same association, on the contexts for which the
+// requestor took the SCP role.
+func (s *Server) get(a *Association, msg *Message) error {
+	instances, err := s.match(a, msg)
+	if err != nil {
+		failed := Command{CommandField: CGetRSP, Status: StatusIdentifierMismatch, ErrorComment: err.Error()}
+		return s.respond(a, msg, failed, nil)
+	}
+	s.logf("C-GET from %s: %d instance(s)", a.CallingAE(), len(instances))
+	if len(instances) == 0 {
+		return s.respond(a, msg, Command{CommandField: CGetRSP, Status: StatusSuccess}, nil)
+	}
+	return s.retrieve(a, msg, instances, func(inst *Instance) (uint16, error) {
+		if pc, ok := a.FindContext(inst.SOPClassUID, inst.TransferSyntaxUID); !ok || !pc.SCPRole {
+			return 0, fmt.Errorf("no presentation context with the SCP role for %s in %s", inst.SOPClassUID, inst.TransferSyntaxUID)
+		}
+		return storeInstance(a, inst, "", 0)
+	})
+}
+
+// match decodes the identifier of a C-MOVE or C-GET request and returns the
+// instances to retrieve.
+func (s *Server) match(a *Association, msg *Message) ([]*Instance, error) {
+	identifier, err := s.identifier(a, msg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Index.Match(identifier)
+}
+
+// retrieve performs the C-STORE sub-operations of a C-MOVE or C-GET with
+// store, sending a pending response after each of them and the final
+// response with the sub-operation counts.
+func (s *Server) retrieve(a *Association, msg *Message, instances []*Instance, store func(inst *Instance) (uint16, error)) error {
+	field := msg.Command.CommandField | 0x8000
+	var completed, failed, warning uint16
+	var failedUIDs []string
+	for i, inst := range instances {
+		status, err := store(inst)
+		switch {
+		case err != nil:
+			s.logf("C-STORE of %s failed: %v", inst.SOPInstanceUID, err)
+			failed++
+			failedUIDs = append(failedUIDs, inst.SOPInstanceUID)
+		case status == StatusSuccess:
+			completed++
+		case status&0xF000 == 0xB000:
+			warning++
+		default:
+			failed++
+			failedUIDs = append(failedUIDs, inst.SOPInstanceUID)
+		}
+		if i < len(instances)-1 {
+			pending := Command{
+				CommandField:           field,
+				Status:                 StatusPending,
+				RemainingSuboperations: uint16(min(len(instances)-i-1, 0xFFFF)),
+				CompletedSuboperations: completed,
+				FailedSuboperations:    failed,
+				WarningSuboperations:   warning,
+			}
+			if err := s.respond(a, msg, pending, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	final := Command{
+		CommandField:           field,
+		Status:                 StatusSuccess,
+		CompletedSuboperations: completed,
+		FailedSuboperations:    failed,
+		WarningSuboperations:   warning,
+	}
+	if failed > 0 || warning > 0 {
+		final.Status = StatusSubOperationsWarning
+		if completed == 0 && warning == 0 {
+			final.Status = StatusSubOperationsFailed
+		}
+	}
+	var data []byte
+	if len(failedUIDs) > 0 {
+		pc, _ := a.Context(msg.ContextID)
+		failedList := dicom.Dataset{Elements: []*dicom.Element{mustNewElement(tag.FailedSOPInstanceUIDList, failedUIDs)}}
+		var err error
+		if data, err = EncodeDataset(failedList, pc.TransferSyntax); err != nil {
+			return err
+		}
+	}
+	return s.respond(a, msg, final, data)
+}
+
+// storageContexts proposes a presentation context per SOP class and
+// transfer syntax of the instances.
+func storageContexts(instances []*Instance) []PresentationContext {
+	type key struct{ sopClass, ts string }
+	seen := make(map[key]bool)
+	var contexts []PresentationContext
+	for _, inst := range instances {
+		k := key{inst.SOPClassUID, inst.TransferSyntaxUID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		contexts = append(contexts, PresentationContext{AbstractSyntax: k.sopClass, TransferSyntaxes: []string{k.ts}})
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		if contexts[i].AbstractSyntax != contexts[j].AbstractSyntax {
+			return contexts[i].AbstractSyntax < contexts[j].AbstractSyntax
+		}
+		return contexts[i].TransferSyntaxes[0] < contexts[j].TransferSyntaxes[0]
+	})
+	return contexts
+}
+
+// storeInstance sends an instance file with a C-STORE sub-operation and
+// returns the response status.
+func storeInstance(dest *Association, inst *Instance, originatorAE string, originatorID uint16) (uint16, error) {
+	meta, data, err := inst.fileData()
+	if err != nil {
+		return 0, err
+	}
+	rsp, err := dest.Store(inst.SOPClassUID, inst.SOPInstanceUID, meta.transferSyntaxUID, data, originatorAE, originatorID)
+	if err != nil {
+		return 0, err
+	}
+	return rsp.Status, nil
+}
@@ -0,0 +1,367 @@
+package dimse
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// startServer generates a small data set and serves it with srv, as
+// DICOMFORGE, on a local port.
+func startServer(t *testing.T, srv *Server) (string, []internaldicom.GeneratedFile) {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		TotalSize:       "1MB",
+		OutputDir:       dir,
+		Seed:            42,
+		NumStudies:      2,
+		ImagesPerSeries: util.ImageRange{Min: 2, Max: 2},
+		Quiet:           true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ix, err := NewIndex(dir)
+	if err != nil {
+		t.Fatalf("NewIndex failed: %v", err)
+	}
+	if ix.Len() != len(files) {
+		t.Fatalf("indexed %d instances, want %d", ix.Len(), len(files))
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	srv.AETitle, srv.Index, srv.Quiet = "DICOMFORGE", ix, true
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+	t.Cleanup(func() {
+		_ = l.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+	return l.Addr().String(), files
+}
+
+// receiver is a storage SCP recording the SOP instances it receives.
+type receiver struct {
+	mu         sync.Mutex
+	received   map[string]bool
+	originator string
+}
+
+// startReceiver serves a storage SCP accepting any SOP class.
+func startReceiver(t *testing.T) (string, *receiver) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	r := &receiver{received: make(map[string]bool)}
+	acc := acceptor{aeTitle: "STORE", negotiate: func(pc PresentationContext) (string, byte) {
+		return pc.TransferSyntaxes[0], ContextAccepted
+	}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				a, err := acc.accept(conn)
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+				for {
+					msg, err := a.ReadMessage()
+					if err != nil {
+						return
+					}
+					r.mu.Lock()
+					r.received[msg.Command.AffectedSOPInstanceUID] = true
+					r.originator = msg.Command.MoveOriginatorAETitle
+					r.mu.Unlock()
+					rsp := Command{
+						CommandField:              CStoreRSP,
+						MessageIDBeingRespondedTo: msg.Command.MessageID,
+						AffectedSOPClassUID:       msg.Command.AffectedSOPClassUID,
+						AffectedSOPInstanceUID:    msg.Command.AffectedSOPInstanceUID,
+						Status:                    StatusSuccess,
+					}
+					if err := a.WriteMessage(&Message{ContextID: msg.ContextID, Command: rsp}); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { _ = l.Close() })
+	return l.Addr().String(), r
+}
+
+// queryContexts proposes the Verification and Study Root contexts.
+func queryContexts() []PresentationContext {
+	var contexts []PresentationContext
+	for _, sopClass := range []string{VerificationSOPClass, StudyRootFindSOPClass, StudyRootMoveSOPClass, PatientRootFindSOPClass} {
+		contexts = append(contexts, PresentationContext{AbstractSyntax: sopClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}})
+	}
+	return contexts
+}
+
+func TestServer_Echo(t *testing.T) {
+	addr, _ := startServer(t, &Server{})
+
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", queryContexts())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if err := a.Echo(); err != nil {
+		t.Errorf("Echo failed: %v", err)
+	}
+	if err := a.Release(); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+
+	// Unknown called AE title
+	var rj rejection
+	if _, err := Dial(addr, "VIEWER", "OTHER", queryContexts()); !errors.As(err, &rj) || rj.reason != 7 {
+		t.Errorf("got %v, want a rejection with reason 7", err)
+	}
+}
+
+func TestServer_Find(t *testing.T) {
+	addr, files := startServer(t, &Server{})
+
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", queryContexts())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+
+	studies := make(map[string]bool)
+	for _, f := range files {
+		studies[f.StudyUID] = true
+	}
+	matches, err := a.Find(StudyRootFindSOPClass, identifier(LevelStudy, map[tag.Tag][]string{
+		tag.StudyInstanceUID:              {""},
+		tag.PatientName:                   {""},
+		tag.NumberOfStudyRelatedInstances: {},
+	}))
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(matches) != len(studies) {
+		t.Fatalf("got %d studies, want %d", len(matches), len(studies))
+	}
+	for _, m := range matches {
+		elem, err := m.FindElementByTag(tag.StudyInstanceUID)
+		if err != nil || !studies[dicom.MustGetStrings(elem.Value)[0]] {
+			t.Errorf("unexpected study in %v", m)
+		}
+	}
+
+	// PATIENT level is not part of the Study Root model
+	_, err = a.Find(StudyRootFindSOPClass, identifier(LevelPatient, map[tag.Tag][]string{tag.PatientID: {""}}))
+	var statusErr StatusError
+	if !errors.As(err, &statusErr) || statusErr.Command.Status != StatusIdentifierMismatch {
+		t.Errorf("got %v, want status 0xA900", err)
+	}
+
+	// Patient Root supports it
+	patients, err := a.Find(PatientRootFindSOPClass, identifier(LevelPatient, map[tag.Tag][]string{tag.PatientID: {""}}))
+	if err != nil || len(patients) == 0 {
+		t.Errorf("patient level query returned %d matches: %v", len(patients), err)
+	}
+}
+
+func TestServer_Move(t *testing.T) {
+	storeAddr, r := startReceiver(t)
+	addr, files := startServer(t, &Server{Destinations: map[string]string{"STORE": storeAddr}})
+
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", queryContexts())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+
+	study := files[0].StudyUID
+	want := make(map[string]bool)
+	for _, f := range files {
+		if f.StudyUID == study {
+			want[f.SOPInstanceUID] = true
+		}
+	}
+
+	rsp, err := a.Move(StudyRootMoveSOPClass, "STORE", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {study}}))
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if int(rsp.CompletedSuboperations) != len(want) || rsp.FailedSuboperations != 0 {
+		t.Errorf("got %d completed and %d failed sub-operations, want %d completed", rsp.CompletedSuboperations, rsp.FailedSuboperations, len(want))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.received) != len(want) {
+		t.Errorf("received %d instances, want %d", len(r.received), len(want))
+	}
+	for uid := range want {
+		if !r.received[uid] {
+			t.Errorf("instance %s not received", uid)
+		}
+	}
+	if r.originator != "VIEWER" {
+		t.Errorf("move originator %q, want VIEWER", r.originator)
+	}
+
+	// Unknown destination
+	_, err = a.Move(StudyRootMoveSOPClass, "NOWHERE", identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {study}}))
+	var statusErr StatusError
+	if !errors.As(err, &statusErr) || statusErr.Command.Status != StatusMoveDestinationUnknown {
+		t.Errorf("got %v, want status 0xA801", err)
+	}
+}
+
+func TestServer_Get(t *testing.T) {
+	addr, files := startServer(t, &Server{})
+
+	study := files[0].StudyUID
+	want := make(map[string]bool)
+	for _, f := range files {
+		if f.StudyUID == study {
+			want[f.SOPInstanceUID] = true
+		}
+	}
+
+	// The storage SOP class of the generated images, with the SCP role
+	ix, err := NewIndex(filepath.Dir(files[0].Path))
+	if err != nil {
+		t.Fatalf("NewIndex failed: %v", err)
+	}
+	contexts := append(queryContexts(),
+		PresentationContext{AbstractSyntax: StudyRootGetSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}},
+		PresentationContext{AbstractSyntax: ix.instances[0].SOPClassUID, TransferSyntaxes: []string{ExplicitVRLittleEndian}, SCPRole: true},
+	)
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", contexts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+	if pc, ok := a.FindContext(ix.instances[0].SOPClassUID, ""); !ok || !pc.SCPRole {
+		t.Fatalf("storage context not accepted with the SCP role: %+v", pc)
+	}
+
+	received := make(map[string]bool)
+	rsp, err := a.Get(StudyRootGetSOPClass, identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {study}}), func(msg *Message) uint16 {
+		pc, _ := a.Context(msg.ContextID)
+		ds, err := DecodeDataset(msg.Data, pc.TransferSyntax)
+		if err != nil {
+			t.Errorf("DecodeDataset failed: %v", err)
+			return StatusUnableToProcess
+		}
+		elem, err := ds.FindElementByTag(tag.SOPInstanceUID)
+		if err != nil || dicom.MustGetStrings(elem.Value)[0] != msg.Command.AffectedSOPInstanceUID {
+			t.Errorf("data set does not match instance %s", msg.Command.AffectedSOPInstanceUID)
+		}
+		received[msg.Command.AffectedSOPInstanceUID] = true
+		return StatusSuccess
+	})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if int(rsp.CompletedSuboperations) != len(want) || rsp.FailedSuboperations != 0 {
+		t.Errorf("got %d completed and %d failed sub-operations, want %d completed", rsp.CompletedSuboperations, rsp.FailedSuboperations, len(want))
+	}
+	if len(received) != len(want) {
+		t.Errorf("received %d instances, want %d", len(received), len(want))
+	}
+	for uid := range want {
+		if !received[uid] {
+			t.Errorf("instance %s not received", uid)
+		}
+	}
+}
+
+func TestServer_Get_WithoutSCPRole(t *testing.T) {
+	addr, files := startServer(t, &Server{})
+
+	contexts := append(queryContexts(), PresentationContext{AbstractSyntax: StudyRootGetSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}})
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", contexts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+
+	rsp, err := a.Get(StudyRootGetSOPClass, identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {files[0].StudyUID}}), func(*Message) uint16 {
+		t.Error("unexpected C-STORE request")
+		return StatusSuccess
+	})
+	var statusErr StatusError
+	if !errors.As(err, &statusErr) || rsp.Status != StatusSubOperationsFailed || rsp.FailedSuboperations == 0 {
+		t.Errorf("got %+v (%v), want status 0xA702 with failed sub-operations", rsp, err)
+	}
+}
+
+func TestServer_Policy(t *testing.T) {
+	addr, _ := startServer(t, &Server{Policy: Policy{
+		RejectSOPClasses:       []string{PatientRootFindSOPClass},
+		RejectTransferSyntaxes: []string{ExplicitVRLittleEndian},
+		MaxPDULength:           4096,
+	}})
+
+	contexts := []PresentationContext{
+		{AbstractSyntax: PatientRootFindSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}},
+		{AbstractSyntax: StudyRootFindSOPClass, TransferSyntaxes: []string{ExplicitVRLittleEndian, ImplicitVRLittleEndian}},
+		{AbstractSyntax: VerificationSOPClass, TransferSyntaxes: []string{ExplicitVRLittleEndian}},
+	}
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", contexts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Abort() }()
+
+	if _, ok := a.FindContext(PatientRootFindSOPClass, ""); ok {
+		t.Error("rejected SOP class was accepted")
+	}
+	if _, ok := a.FindContext(VerificationSOPClass, ""); ok {
+		t.Error("context with only a refused transfer syntax was accepted")
+	}
+	if pc, ok := a.FindContext(StudyRootFindSOPClass, ""); !ok || pc.TransferSyntax != ImplicitVRLittleEndian {
+		t.Errorf("got context %+v, want Study Root FIND in Implicit VR Little Endian", pc)
+	}
+	if a.peerMaxPDU != 4096 {
+		t.Errorf("announced maximum PDU length %d, want 4096", a.peerMaxPDU)
+	}
+
+	// A PDU over the announced length ends the association
+	a.peerMaxPDU = 0
+	long := identifier(LevelStudy, map[tag.Tag][]string{tag.StudyInstanceUID: {""}, tag.StudyDescription: {strings.Repeat("X", 6000)}})
+	if _, err := a.Find(StudyRootFindSOPClass, long); err == nil {
+		t.Error("expected the server to abort the association")
+	}
+}
+
+func TestServer_Policy_MaxContexts(t *testing.T) {
+	addr, _ := startServer(t, &Server{Policy: Policy{MaxContexts: 2}})
+
+	a, err := Dial(addr, "VIEWER", "DICOMFORGE", queryContexts())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+	if got := len(a.Contexts()); got != 2 {
+		t.Errorf("got %d accepted contexts, want 2", got)
+	}
+}
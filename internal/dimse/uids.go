@@ -0,0 +1,31 @@
+package dimse
+
+// Application context of every DICOM association
+const ApplicationContextName = "1.2.840.10008.3.1.1.1"
+
+// Implementation identification sent during association negotiation
+const (
+	ImplementationClassUID    = "1.2.826.0.1.3680043.8.498.1"
+	ImplementationVersionName = "DICOMFORGE"
+)
+
+// SOP classes of the supported services
+const (
+	VerificationSOPClass    = "1.2.840.10008.1.1"
+	PatientRootFindSOPClass = "1.2.840.10008.5.1.4.1.2.1.1"
+	PatientRootMoveSOPClass = "1.2.840.10008.5.1.4.1.2.1.2"
+	PatientRootGetSOPClass  = "1.2.840.10008.5.1.4.1.2.1.3"
+	StudyRootFindSOPClass   = "1.2.840.10008.5.1.4.1.2.2.1"
+	StudyRootMoveSOPClass   = "1.2.840.10008.5.1.4.1.2.2.2"
+	StudyRootGetSOPClass    = "1.2.840.10008.5.1.4.1.2.2.3"
+
+	ModalityWorklistFindSOPClass           = "1.2.840.10008.5.1.4.31"
+	ModalityPerformedProcedureStepSOPClass = "1.2.840.10008.3.1.2.3.3"
+)
+
+// Transfer syntaxes of commands and identifiers
+const (
+	ImplicitVRLittleEndian = "1.2.840.10008.1.2"
+	ExplicitVRLittleEndian = "1.2.840.10008.1.2.1"
+	ExplicitVRBigEndian    = "1.2.840.10008.1.2.2"
+)
@@ -0,0 +1,166 @@
+package dimse
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Worklist is the Modality Worklist information model of a set of worklist
+// items (PS3.4 K.6), each a data set with a Scheduled Procedure Step
+// Sequence. It is read-only once built.
+type Worklist struct {
+	items []dicom.Dataset
+}
+
+// LoadWorklist reads the worklist items of a directory tree. Files that are
+// not DICOM, or have no Scheduled Procedure Step Sequence, are skipped.
+func LoadWorklist(dir string) (*Worklist, error) {
+	wl := &Worklist{}
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("worklist %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		ds, err := dicom.ParseFile(path, nil, dicom.SkipPixelData(), dicom.AllowUnknownSpecificCharacterSet())
+		if err != nil {
+			continue // Not a DICOM file
+		}
+		if _, err := ds.FindElementByTag(tag.ScheduledProcedureStepSequence); err != nil {
+			continue
+		}
+		var elements []*dicom.Element
+		for _, elem := range ds.Elements {
+			if elem.Tag.Group != 0x0002 {
+				elements = append(elements, elem)
+			}
+		}
+		wl.items = append(wl.items, dicom.Dataset{Elements: elements})
+	}
+	return wl, nil
+}
+
+// Len returns the number of worklist items.
+func (wl *Worklist) Len() int {
+	return len(wl.items)
+}
+
+// Find returns the C-FIND responses to a Modality Worklist identifier: one
+// data set per matching item, holding the requested attributes. Keys of the
+// Scheduled Procedure Step Sequence, and of any other sequence, match the
+// items of the sequence (PS3.4 C.2.2.2.6).
+func (wl *Worklist) Find(identifier dicom.Dataset) ([]dicom.Dataset, error) {
+	var results []dicom.Dataset
+	for _, item := range wl.items {
+		if matchElements(identifier.Elements, item.Elements) {
+			results = append(results, dicom.Dataset{Elements: worklistResponse(identifier.Elements, item.Elements)})
+		}
+	}
+	return results, nil
+}
+
+// findElement returns the element of a tag among elements, or nil.
+func findElement(elements []*dicom.Element, t tag.Tag) *dicom.Element {
+	for _, elem := range elements {
+		if elem.Tag == t {
+			return elem
+		}
+	}
+	return nil
+}
+
+// sequenceItems returns the items of a sequence element.
+func sequenceItems(elem *dicom.Element) [][]*dicom.Element {
+	if elem == nil || elem.Value == nil || elem.Value.ValueType() != dicom.Sequences {
+		return nil
+	}
+	var items [][]*dicom.Element
+	for _, item := range elem.Value.GetValue().([]*dicom.SequenceItemValue) {
+		items = append(items, item.GetValue().([]*dicom.Element))
+	}
+	return items
+}
+
+// matchElements reports whether the elements of a worklist item, or of a
+// sequence item, match the keys. A sequence key with an item matches when
+// any item of the sequence matches the keys of that item.
+func matchElements(keys, elements []*dicom.Element) bool {
+	for _, key := range keys {
+		if key.Tag == tag.SpecificCharacterSet || key.Tag.Group == 0x0002 || key.Value == nil {
+			continue
+		}
+		elem := findElement(elements, key.Tag)
+		if key.Value.ValueType() == dicom.Sequences {
+			keyItems := sequenceItems(key)
+			if len(keyItems) == 0 {
+				continue // Universal
+			}
+			matched := false
+			for _, item := range sequenceItems(elem) {
+				if matchElements(keyItems[0], item) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+		keyValues := elementStrings(key)
+		if isUniversal(keyValues) {
+			continue
+		}
+		if elem == nil || !matchValues(key.RawValueRepresentation, keyValues, elementStrings(elem)) {
+			return false
+		}
+	}
+	return true
+}
+
+// worklistResponse builds the response of a matching worklist item, or of a
+// sequence item: the requested attributes with the values of the item. The
+// items of a requested sequence are returned with the attributes requested
+// in its key item, or whole when the key is empty.
+func worklistResponse(keys, elements []*dicom.Element) []*dicom.Element {
+	var response []*dicom.Element
+	if cs := findElement(elements, tag.SpecificCharacterSet); cs != nil {
+		response = append(response, cs)
+	}
+	for _, key := range keys {
+		if key.Tag == tag.SpecificCharacterSet || key.Tag.Group == 0x0002 {
+			continue
+		}
+		elem := findElement(elements, key.Tag)
+		switch {
+		case elem == nil:
+			response = append(response, key) // Unknown attribute, returned empty
+		case key.Value != nil && key.Value.ValueType() == dicom.Sequences && len(sequenceItems(key)) > 0:
+			keyItem := sequenceItems(key)[0]
+			var items [][]*dicom.Element
+			for _, item := range sequenceItems(elem) {
+				if matchElements(keyItem, item) {
+					items = append(items, worklistResponse(keyItem, item))
+				}
+			}
+			response = append(response, mustNewElement(key.Tag, items))
+		default:
+			response = append(response, elem)
+		}
+	}
+	return response
+}
@@ -0,0 +1,148 @@
+package dimse
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/util"
+)
+
+// generateWorklist generates and organizes two studies, exports their
+// worklist items and loads them.
+func generateWorklist(t *testing.T) (*Worklist, []internaldicom.GeneratedFile) {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		TotalSize:       "1MB",
+		OutputDir:       dir,
+		Seed:            7,
+		NumStudies:      2,
+		ImagesPerSeries: util.ImageRange{Min: 2, Max: 2},
+		Quiet:           true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(dir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	n, err := internaldicom.ExportWorklist(dir, "SCANNER1", true)
+	if err != nil {
+		t.Fatalf("ExportWorklist failed: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("exported %d items, want 2", n)
+	}
+	wl, err := LoadWorklist(filepath.Join(dir, internaldicom.WorklistDir))
+	if err != nil {
+		t.Fatalf("LoadWorklist failed: %v", err)
+	}
+	if wl.Len() != n {
+		t.Fatalf("loaded %d items, want %d", wl.Len(), n)
+	}
+	return wl, files
+}
+
+// worklistKeys returns a worklist identifier with the keys of the Scheduled
+// Procedure Step Sequence in its item.
+func worklistKeys(keys map[tag.Tag][]string, stepKeys map[tag.Tag][]string) dicom.Dataset {
+	var item []*dicom.Element
+	for t, v := range stepKeys {
+		item = append(item, mustNewElement(t, v))
+	}
+	elements := []*dicom.Element{mustNewElement(tag.ScheduledProcedureStepSequence, [][]*dicom.Element{item})}
+	for t, v := range keys {
+		elements = append(elements, mustNewElement(t, v))
+	}
+	return dicom.Dataset{Elements: elements}
+}
+
+func TestWorklist_Find(t *testing.T) {
+	wl, files := generateWorklist(t)
+
+	tests := []struct {
+		name     string
+		keys     map[tag.Tag][]string
+		stepKeys map[tag.Tag][]string
+		want     int
+	}{
+		{"all", nil, map[tag.Tag][]string{tag.ScheduledStationAETitle: {""}}, 2},
+		{"by station", nil, map[tag.Tag][]string{tag.ScheduledStationAETitle: {"SCANNER1"}}, 2},
+		{"other station", nil, map[tag.Tag][]string{tag.ScheduledStationAETitle: {"SCANNER2"}}, 0},
+		{"by study", map[tag.Tag][]string{tag.StudyInstanceUID: {files[0].StudyUID}}, nil, 1},
+		{"by patient wildcard", map[tag.Tag][]string{tag.PatientID: {"*"}}, map[tag.Tag][]string{tag.Modality: {"MR"}}, 2},
+		{"other modality", nil, map[tag.Tag][]string{tag.Modality: {"CT"}}, 0},
+		{"by date range", nil, map[tag.Tag][]string{tag.ScheduledProcedureStepStartDate: {"19000101-"}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := wl.Find(worklistKeys(tt.keys, tt.stepKeys))
+			if err != nil {
+				t.Fatalf("Find failed: %v", err)
+			}
+			if len(matches) != tt.want {
+				t.Errorf("got %d matches, want %d", len(matches), tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_FindWorklist(t *testing.T) {
+	wl, files := generateWorklist(t)
+	addr, _ := startServer(t, &Server{Worklist: wl})
+
+	contexts := []PresentationContext{{AbstractSyntax: ModalityWorklistFindSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}}}
+	a, err := Dial(addr, "SCANNER1", "DICOMFORGE", contexts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+
+	matches, err := a.Find(ModalityWorklistFindSOPClass, worklistKeys(
+		map[tag.Tag][]string{tag.StudyInstanceUID: {files[0].StudyUID}, tag.PatientName: {""}, tag.AccessionNumber: {""}},
+		map[tag.Tag][]string{tag.ScheduledStationAETitle: {"SCANNER1"}, tag.ScheduledProcedureStepID: {""}, tag.ScheduledProcedureStepStartDate: {""}},
+	))
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	for _, key := range []tag.Tag{tag.PatientName, tag.AccessionNumber, tag.StudyInstanceUID} {
+		if elem, err := matches[0].FindElementByTag(key); err != nil || len(elementStrings(elem)) == 0 || elementStrings(elem)[0] == "" {
+			t.Errorf("response has no %v", key)
+		}
+	}
+	elem, err := matches[0].FindElementByTag(tag.ScheduledProcedureStepSequence)
+	if err != nil {
+		t.Fatal("response has no Scheduled Procedure Step Sequence")
+	}
+	steps := sequenceItems(elem)
+	if len(steps) != 1 {
+		t.Fatalf("got %d scheduled procedure steps, want 1", len(steps))
+	}
+	if id := findElement(steps[0], tag.ScheduledProcedureStepID); id == nil || elementStrings(id)[0] == "" {
+		t.Error("scheduled procedure step has no ID")
+	}
+	if date := findElement(steps[0], tag.ScheduledProcedureStepStartDate); date == nil || len(elementStrings(date)[0]) != 8 {
+		t.Error("scheduled procedure step has no start date")
+	}
+}
+
+func TestServer_NoWorklist(t *testing.T) {
+	addr, _ := startServer(t, &Server{})
+
+	contexts := append(queryContexts(), PresentationContext{AbstractSyntax: ModalityWorklistFindSOPClass, TransferSyntaxes: []string{ImplicitVRLittleEndian}})
+	a, err := Dial(addr, "SCANNER1", "DICOMFORGE", contexts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = a.Release() }()
+	if _, ok := a.FindContext(ModalityWorklistFindSOPClass, ""); ok {
+		t.Error("Modality Worklist context accepted without a worklist")
+	}
+}
@@ -0,0 +1,170 @@
+package image
+
+import (
+	"image"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Annotations are lines of text burnt into the corners of an image, as
+// scanners annotate their images (series, instance, slice location, date),
+// and the content of a QR code burnt into its bottom right corner, under the
+// lines of that corner.
+type Annotations struct {
+	TopLeft, TopRight, BottomLeft, BottomRight []string
+	QRCode                                     string
+}
+
+// IsZero reports whether a has no text and no QR code.
+func (a Annotations) IsZero() bool {
+	return len(a.TopLeft)+len(a.TopRight)+len(a.BottomLeft)+len(a.BottomRight) == 0 && a.QRCode == ""
+}
+
+// AnnotationBlock is the text of a corner as burnt into an image: the 8-bit
+// gray levels of its region of the image, row by row, -1 where the image
+// shows through.
+type AnnotationBlock struct {
+	Levels []int16
+	Region image.Rectangle
+}
+
+// RenderAnnotations renders a as it is burnt into a width x height image:
+// lines of the basic 7x13 font, enlarged by a pixel per 256 of the image
+// width, white with a black outline, the right corners aligned right, a
+// margin away from the edges. The QR code has modules of a pixel more than
+// the text, for it to survive lossy compression.
+func RenderAnnotations(width, height int, a Annotations) ([]AnnotationBlock, error) {
+	scale := max(width/256, 1)
+	margin := max(width/64, 4)
+	var blocks []AnnotationBlock
+	bottomRight := height - margin
+	if a.QRCode != "" {
+		levels, size, err := renderQRCode(a.QRCode, scale+1)
+		if err != nil {
+			return nil, err
+		}
+		x, y := width-margin-size, height-margin-size
+		blocks = append(blocks, AnnotationBlock{Levels: levels, Region: image.Rect(x, y, x+size, y+size)})
+		bottomRight = y - margin
+	}
+	for _, corner := range []struct {
+		lines         []string
+		right, bottom bool
+	}{
+		{a.TopLeft, false, false},
+		{a.TopRight, true, false},
+		{a.BottomLeft, false, true},
+		{a.BottomRight, true, true},
+	} {
+		if len(corner.lines) == 0 {
+			continue
+		}
+		levels, w, h := renderLines(corner.lines, scale, corner.right)
+		x, y := margin, margin
+		if corner.right {
+			x = width - margin - w
+		}
+		switch {
+		case corner.bottom && corner.right:
+			y = bottomRight - h
+		case corner.bottom:
+			y = height - margin - h
+		}
+		blocks = append(blocks, AnnotationBlock{Levels: levels, Region: image.Rect(x, y, x+w, y+h)})
+	}
+	return blocks, nil
+}
+
+// renderQRCode renders a QR code of content, with medium error correction
+// and its quiet zone, in modules of module pixels: black on white. It
+// returns the gray levels of the code and its size.
+func renderQRCode(content string, module int) (levels []int16, size int, err error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, 0, err
+	}
+	bitmap := code.Bitmap()
+	size = len(bitmap) * module
+	levels = make([]int16, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if !bitmap[y/module][x/module] {
+				levels[y*size+x] = 255
+			}
+		}
+	}
+	return levels, size, nil
+}
+
+// renderLines renders lines of text, scale times their base size, with an
+// outline of scale pixels, aligned left or right. It returns the gray
+// levels of the block, -1 where there is no text, and its size.
+func renderLines(lines []string, scale int, alignRight bool) (levels []int16, width, height int) {
+	face := basicfont.Face7x13
+	lineHeight := face.Height
+	baseWidth := 0
+	for _, line := range lines {
+		baseWidth = max(baseWidth, font.MeasureString(face, line).Ceil())
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, baseWidth, lineHeight*len(lines)))
+	for i, line := range lines {
+		x := 0
+		if alignRight {
+			x = baseWidth - font.MeasureString(face, line).Ceil()
+		}
+		drawer := &font.Drawer{Dst: mask, Src: image.Opaque, Face: face, Dot: fixed.P(x, lineHeight*i+face.Ascent)}
+		drawer.DrawString(line)
+	}
+
+	outline := scale
+	width, height = baseWidth*scale+2*outline, mask.Rect.Dy()*scale+2*outline
+	levels = make([]int16, width*height)
+	for i := range levels {
+		levels[i] = -1
+	}
+	hasText := func(x, y int) bool { return mask.AlphaAt(x/scale, y/scale).A > 0 }
+	for y := 0; y < mask.Rect.Dy()*scale; y++ {
+		for x := 0; x < baseWidth*scale; x++ {
+			if !hasText(x, y) {
+				continue
+			}
+			for dy := -outline; dy <= outline; dy++ {
+				for dx := -outline; dx <= outline; dx++ {
+					if i := (y+outline+dy)*width + x + outline + dx; levels[i] < 0 {
+						levels[i] = 0
+					}
+				}
+			}
+			levels[(y+outline)*width+x+outline] = 255
+		}
+	}
+	return levels, width, height
+}
+
+// AddAnnotatedTextOverlay adds text "File X/Y" to the image pixels, as
+// AddTextOverlay does, and burns the annotations a into their corners, in
+// the 12-bit range. Annotations larger than the image are cut.
+func AddAnnotatedTextOverlay(pixels []uint16, width, height, imageNum, totalImages int, a Annotations) error {
+	if err := AddTextOverlay(pixels, width, height, imageNum, totalImages); err != nil {
+		return err
+	}
+	blocks, err := RenderAnnotations(width, height, a)
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		stride := block.Region.Dx()
+		visible := block.Region.Intersect(image.Rect(0, 0, width, height))
+		for y := visible.Min.Y; y < visible.Max.Y; y++ {
+			for x := visible.Min.X; x < visible.Max.X; x++ {
+				if gray := block.Levels[(y-block.Region.Min.Y)*stride+x-block.Region.Min.X]; gray >= 0 {
+					pixels[y*width+x] = uint16(int(gray) * 4095 / 255)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package image
+
+import "testing"
+
+func TestRenderAnnotations(t *testing.T) {
+	const width, height = 512, 400
+	blocks, err := RenderAnnotations(width, height, Annotations{
+		TopLeft:     []string{"T2 AX", "Se: 3"},
+		BottomRight: []string{"SL: -12.5"},
+	})
+	if err != nil {
+		t.Fatalf("RenderAnnotations failed: %v", err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("%d blocks, want 2", len(blocks))
+	}
+
+	// Twice the base size for 512 pixels: 2 lines of 26 pixels, outlined
+	topLeft, bottomRight := blocks[0].Region, blocks[1].Region
+	if topLeft.Min.X != 8 || topLeft.Min.Y != 8 || topLeft.Dy() != 2*26+4 {
+		t.Errorf("top left block at %v, want 8, 8 and 56 pixels high", topLeft)
+	}
+	if bottomRight.Max.X != width-8 || bottomRight.Max.Y != height-8 {
+		t.Errorf("bottom right block at %v, want to end at %d, %d", bottomRight, width-8, height-8)
+	}
+
+	for _, block := range blocks {
+		if len(block.Levels) != block.Region.Dx()*block.Region.Dy() {
+			t.Fatalf("%d levels for region %v", len(block.Levels), block.Region)
+		}
+		var text, outline int
+		for _, gray := range block.Levels {
+			switch gray {
+			case 255:
+				text++
+			case 0:
+				outline++
+			}
+		}
+		if text == 0 || outline == 0 {
+			t.Errorf("block %v: %d text and %d outline pixels", block.Region, text, outline)
+		}
+	}
+
+	if blocks, _ := RenderAnnotations(width, height, Annotations{}); blocks != nil {
+		t.Error("blocks without annotations")
+	}
+}
+
+func TestRenderAnnotationsQRCode(t *testing.T) {
+	const width, height = 512, 400
+	blocks, err := RenderAnnotations(width, height, Annotations{
+		BottomRight: []string{"SL: -12.5"},
+		QRCode:      "1.2.826.0.1.3680043.8.498.12345678901234567890123456789012345678",
+	})
+	if err != nil {
+		t.Fatalf("RenderAnnotations failed: %v", err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("%d blocks, want 2", len(blocks))
+	}
+
+	// Modules of 3 pixels for 512 pixels, in the bottom right corner
+	code, text := blocks[0], blocks[1]
+	if code.Region.Dx() != code.Region.Dy() || code.Region.Dx()%3 != 0 {
+		t.Errorf("QR code at %v, want a square of 3-pixel modules", code.Region)
+	}
+	if code.Region.Max.X != width-8 || code.Region.Max.Y != height-8 {
+		t.Errorf("QR code at %v, want to end at %d, %d", code.Region, width-8, height-8)
+	}
+	if text.Region.Max.Y != code.Region.Min.Y-8 {
+		t.Errorf("bottom right block at %v, want above the QR code at %v", text.Region, code.Region)
+	}
+
+	// White quiet zone of 4 modules, then the black border of a finder pattern
+	stride := code.Region.Dx()
+	if quiet, finder := code.Levels[0], code.Levels[12*stride+12]; quiet != 255 || finder != 0 {
+		t.Errorf("quiet zone %d and finder pattern %d, want 255 and 0", quiet, finder)
+	}
+
+	if _, err := RenderAnnotations(width, height, Annotations{QRCode: string(make([]byte, 8000))}); err == nil {
+		t.Error("no error for content too long for a QR code")
+	}
+}
+
+func TestAddAnnotatedTextOverlay(t *testing.T) {
+	const width, height = 256, 256
+	pixels := make([]uint16, width*height)
+	err := AddAnnotatedTextOverlay(pixels, width, height, 1, 2, Annotations{TopRight: []string{"2024-01-31"}})
+	if err != nil {
+		t.Fatalf("AddAnnotatedTextOverlay failed: %v", err)
+	}
+	white := 0
+	for y := 0; y < 30; y++ {
+		for x := width * 3 / 4; x < width; x++ { // Right of "File 1/2"
+			if pixels[y*width+x] == 4095 {
+				white++
+			}
+		}
+	}
+	if white == 0 {
+		t.Error("no annotation in the top right corner")
+	}
+	if err := AddAnnotatedTextOverlay(pixels, width, height, 3, 2, Annotations{}); err == nil {
+		t.Error("no error for invalid image numbering")
+	}
+}
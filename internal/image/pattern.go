@@ -0,0 +1,67 @@
+package image
+
+// Test patterns return the gray level of pixel (x, y) of a width x height
+// image, from 0 (black) to 1 (white), without noise: displayed with a window
+// over the whole pixel range, each level shows as its share of the display
+// range.
+
+// Gradient is a horizontal ramp from black to white over the top half of
+// the image, and the same ramp in 11 steps of 10% over the bottom half.
+func Gradient(x, y, width, height int) float64 {
+	if y < height/2 {
+		return (float64(x) + 0.5) / float64(width)
+	}
+	return float64(x*11/width) / 10
+}
+
+// Checkerboard is a board of 16 squares on the smaller side of the image,
+// the top-left one black.
+func Checkerboard(x, y, width, height int) float64 {
+	cell := max(min(width, height)/16, 1)
+	return float64((x/cell + y/cell) % 2)
+}
+
+// ResolutionBars is five groups of black and white bars across the image,
+// 16, 8, 4, 2 and 1 pixels wide: vertical over the top half, horizontal
+// over the bottom half.
+func ResolutionBars(x, y, width, height int) float64 {
+	group := x * 5 / width
+	bar := 16 >> group
+	if y < height/2 {
+		return float64((x - group*width/5) / bar % 2)
+	}
+	return float64((y - height/2) / bar % 2)
+}
+
+// SMPTE is a test pattern after SMPTE RP 133: a 50% background with a 10 x
+// 10 grid of 70% lines, two strips of 11 patches from 0 to 100% (the lower
+// one reversed), a 5% square in a black one and a 95% square in a white one
+// to check the ends of the display range, and line pairs of one pixel in the
+// corners.
+func SMPTE(x, y, width, height int) float64 {
+	u, v := (float64(x)+0.5)/float64(width), (float64(y)+0.5)/float64(height)
+	switch {
+	case x%max(width/10, 1) == 0 || y%max(height/10, 1) == 0 || x == width-1 || y == height-1:
+		return 0.7
+	case (u < 0.1 || u >= 0.9) && (v < 0.1 || v >= 0.9):
+		if v < 0.5 {
+			return float64(x % 2)
+		}
+		return float64(y % 2)
+	case u >= 0.1 && u < 0.9 && v >= 0.2 && v < 0.3:
+		return float64(int((u-0.1)/0.8*11)) / 10
+	case u >= 0.1 && u < 0.9 && v >= 0.7 && v < 0.8:
+		return 1 - float64(int((u-0.1)/0.8*11))/10
+	case u >= 0.1 && u < 0.3 && v >= 0.4 && v < 0.6:
+		if u >= 0.15 && u < 0.25 && v >= 0.45 && v < 0.55 {
+			return 0.05
+		}
+		return 0
+	case u >= 0.7 && u < 0.9 && v >= 0.4 && v < 0.6:
+		if u >= 0.75 && u < 0.85 && v >= 0.45 && v < 0.55 {
+			return 0.95
+		}
+		return 1
+	}
+	return 0.5
+}
@@ -0,0 +1,39 @@
+package image
+
+import "testing"
+
+func TestPatterns(t *testing.T) {
+	const size = 200
+	tests := []struct {
+		name    string
+		pattern func(x, y, width, height int) float64
+		x, y    int
+		want    float64
+	}{
+		{"gradient start", Gradient, 0, 0, 0.0025},
+		{"gradient end", Gradient, size - 1, 0, 0.9975},
+		{"first step", Gradient, 0, size - 1, 0},
+		{"sixth step", Gradient, size / 2, size - 1, 0.5},
+		{"last step", Gradient, size - 1, size - 1, 1},
+		{"black square", Checkerboard, 0, 0, 0},
+		{"white square", Checkerboard, 12, 0, 1},
+		{"diagonal square", Checkerboard, 12, 12, 0},
+		{"16-pixel bar", ResolutionBars, 16, 0, 1},
+		{"1-pixel bars", ResolutionBars, size - 1, 0, 1},
+		{"horizontal bar", ResolutionBars, 0, size/2 + 16, 1},
+		{"background", SMPTE, size/2 + 5, size * 15 / 100, 0.5},
+		{"grid", SMPTE, size / 10, size / 2, 0.7},
+		{"black patch", SMPTE, size * 12 / 100, size / 4, 0},
+		{"white patch", SMPTE, size * 88 / 100, size / 4, 1},
+		{"5% square", SMPTE, size/5 + 5, size/2 + 5, 0.05},
+		{"95% square", SMPTE, size*4/5 + 5, size/2 + 5, 0.95},
+		{"corner line", SMPTE, 5, 5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pattern(tt.x, tt.y, size, size); got < tt.want-1e-9 || got > tt.want+1e-9 {
+				t.Errorf("level at (%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package image
+
+import "math"
+
+// Tissue is a class of tissue of the head phantom.
+type Tissue uint8
+
+// Tissues of the head phantom
+const (
+	Air    Tissue = iota // Outside the head
+	Bone                 // Skull
+	Brain                // Gray and white matter
+	CSF                  // Ventricles
+	Lesion               // Denser nodules
+	NumTissues
+)
+
+// ellipsoid of the phantom: semi-axes, center and rotation about the
+// superior axis, in phantom coordinates ([-1, 1], x to the patient's left,
+// y anterior, z superior)
+type ellipsoid struct {
+	tissue     Tissue
+	a, b, c    float64
+	x0, y0, z0 float64
+	phi        float64 // Degrees
+}
+
+// The 3D modified Shepp-Logan head phantom (Kak & Slaney, with the contrast
+// of Toft), by tissue: each ellipsoid is drawn over the previous ones, as
+// its density adds to theirs
+var sheppLogan = []ellipsoid{
+	{Bone, 0.6900, 0.920, 0.810, 0, 0, 0, 0},
+	{Brain, 0.6624, 0.874, 0.780, 0, -0.0184, 0, 0},
+	{CSF, 0.1100, 0.310, 0.220, 0.22, 0, 0, -18},
+	{CSF, 0.1600, 0.410, 0.280, -0.22, 0, 0, 18},
+	{Lesion, 0.2100, 0.250, 0.410, 0, 0.35, -0.15, 0},
+	{Lesion, 0.0460, 0.046, 0.050, 0, 0.1, 0.25, 0},
+	{Lesion, 0.0460, 0.046, 0.050, 0, -0.1, 0.25, 0},
+	{Lesion, 0.0460, 0.023, 0.050, -0.08, -0.605, 0, 0},
+	{Lesion, 0.0230, 0.023, 0.020, 0, -0.606, 0, 0},
+	{Lesion, 0.0230, 0.046, 0.020, 0.06, -0.605, 0, 0},
+}
+
+// Rotations of the ellipsoids, computed once
+var sheppLoganCos, sheppLoganSin = func() ([]float64, []float64) {
+	cos, sin := make([]float64, len(sheppLogan)), make([]float64, len(sheppLogan))
+	for i, e := range sheppLogan {
+		cos[i], sin[i] = math.Cos(e.phi*math.Pi/180), math.Sin(e.phi*math.Pi/180)
+	}
+	return cos, sin
+}()
+
+// SheppLogan returns the tissue of the head phantom at (x, y, z), in
+// phantom coordinates: [-1, 1] on each axis, x to the patient's left, y
+// anterior and z superior.
+func SheppLogan(x, y, z float64) Tissue {
+	tissue := Air
+	for i, e := range sheppLogan {
+		dx, dy, dz := x-e.x0, y-e.y0, z-e.z0
+		u := dx*sheppLoganCos[i] + dy*sheppLoganSin[i]
+		v := -dx*sheppLoganSin[i] + dy*sheppLoganCos[i]
+		if u*u/(e.a*e.a)+v*v/(e.b*e.b)+dz*dz/(e.c*e.c) <= 1 {
+			tissue = e.tissue
+		}
+	}
+	return tissue
+}
+
+// PhantomPlane is an image plane through the head phantom, filling the
+// image: the row and column directions of DICOM's ImageOrientationPatient
+// (LPS unit vectors), at an offset along their normal.
+type PhantomPlane struct {
+	row, col, normal [3]float64
+	offset           float64
+}
+
+// NewPhantomPlane returns the plane of orientation row and col (the
+// ImageOrientationPatient of the image) at offset along its normal, in
+// [-1, 1]: the position of the slice in its stack.
+func NewPhantomPlane(row, col [3]float64, offset float64) PhantomPlane {
+	return PhantomPlane{
+		row: row,
+		col: col,
+		normal: [3]float64{
+			row[1]*col[2] - row[2]*col[1],
+			row[2]*col[0] - row[0]*col[2],
+			row[0]*col[1] - row[1]*col[0],
+		},
+		offset: offset,
+	}
+}
+
+// Tissue returns the tissue at pixel (x, y) of a width x height image of
+// the plane.
+func (p PhantomPlane) Tissue(x, y, width, height int) Tissue {
+	u := (2*float64(x)+1)/float64(width) - 1
+	v := (2*float64(y)+1)/float64(height) - 1
+	var point [3]float64
+	for i := range point {
+		point[i] = u*p.row[i] + v*p.col[i] + p.offset*p.normal[i]
+	}
+	// LPS to phantom coordinates: anterior is -y
+	return SheppLogan(point[0], -point[1], point[2])
+}
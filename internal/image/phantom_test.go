@@ -0,0 +1,61 @@
+package image
+
+import "testing"
+
+func TestSheppLogan(t *testing.T) {
+	tests := []struct {
+		name    string
+		x, y, z float64
+		want    Tissue
+	}{
+		{"outside", 0.95, 0, 0, Air},
+		{"above the head", 0, 0, 0.9, Air},
+		{"skull", 0, 0.9, 0, Bone},
+		{"brain", 0, 0.5, 0.5, Brain},
+		{"left ventricle", 0.22, 0, 0, CSF},
+		{"right ventricle", -0.22, 0, 0, CSF},
+		{"anterior nodule", 0, 0.35, -0.15, Lesion},
+		{"posterior nodule", 0, -0.606, 0, Lesion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SheppLogan(tt.x, tt.y, tt.z); got != tt.want {
+				t.Errorf("SheppLogan(%v, %v, %v) = %d, want %d", tt.x, tt.y, tt.z, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhantomPlane(t *testing.T) {
+	axial := NewPhantomPlane([3]float64{1, 0, 0}, [3]float64{0, 1, 0}, 0)
+	const size = 100
+
+	// Head centered, ventricles on both sides of the center
+	if got := axial.Tissue(0, 0, size, size); got != Air {
+		t.Errorf("corner is %d, want air", got)
+	}
+	if got := axial.Tissue(size/2, size/2, size, size); got != Brain {
+		t.Errorf("center is %d, want brain", got)
+	}
+	if got := axial.Tissue(61, size/2, size, size); got != CSF {
+		t.Errorf("left of the center is %d, want a ventricle", got)
+	}
+
+	// Anterior at the top of an axial image: the nodules of the posterior
+	// fossa are in the bottom rows
+	if got := axial.Tissue(size/2, 80, size, size); got != Lesion {
+		t.Errorf("posterior pixel is %d, want a nodule", got)
+	}
+
+	// Out of the head at the ends of the stack
+	top := NewPhantomPlane([3]float64{1, 0, 0}, [3]float64{0, 1, 0}, 0.95)
+	if got := top.Tissue(size/2, size/2, size, size); got != Air {
+		t.Errorf("slice above the head is %d, want air", got)
+	}
+
+	// A sagittal plane cuts the skull top to bottom
+	sagittal := NewPhantomPlane([3]float64{0, 1, 0}, [3]float64{0, 0, -1}, 0)
+	if got := sagittal.Tissue(size/2, 10, size, size); got != Bone {
+		t.Errorf("top of the sagittal plane is %d, want the skull", got)
+	}
+}
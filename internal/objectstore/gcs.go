@@ -0,0 +1,44 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GCS is a Google Cloud Storage bucket, written with simple media uploads
+// of the JSON API.
+type GCS struct {
+	Endpoint   string // "" = https://storage.googleapis.com, or an emulator
+	Bucket     string
+	Token      string       // OAuth 2.0 access token, "" for an emulator
+	HTTPClient *http.Client // nil = http.DefaultClient
+}
+
+// URL returns the URL of the object key.
+func (g *GCS) URL(key string) string {
+	return "gs://" + g.Bucket + "/" + key
+}
+
+// Put writes data as the object key with a media upload.
+func (g *GCS) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	endpoint := "https://storage.googleapis.com"
+	if g.Endpoint != "" {
+		endpoint = strings.TrimSuffix(g.Endpoint, "/")
+	}
+	uploadURL := endpoint + "/upload/storage/v1/b/" + url.PathEscape(g.Bucket) + "/o?" +
+		url.Values{"uploadType": {"media"}, "name": {key}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	return do(g.HTTPClient, req, g.URL(key))
+}
@@ -0,0 +1,102 @@
+// Package objectstore writes objects to cloud object storage, S3 (and
+// S3-compatible stores) or Google Cloud Storage, over their HTTP APIs.
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Store is a bucket objects are written to.
+type Store interface {
+	// Put writes data as the object key, replacing any object of that key.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// URL returns the URL of the object key, for messages.
+	URL(key string) string
+}
+
+// Open returns the store of a bucket URL, s3://bucket/prefix or
+// gs://bucket/prefix, and its prefix without slashes at either end ("" for
+// the whole bucket). Credentials and endpoints come from the environment,
+// as the cloud command line tools read them:
+//
+//   - S3: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN,
+//     AWS_REGION or AWS_DEFAULT_REGION (default us-east-1), and
+//     AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL for S3-compatible stores
+//     (MinIO, Ceph, GCS interoperability...), addressed path-style.
+//   - GCS: GOOGLE_OAUTH_ACCESS_TOKEN, an OAuth 2.0 access token (e.g. from
+//     gcloud auth print-access-token), and STORAGE_EMULATOR_HOST for an
+//     emulator, without authentication.
+func Open(rawURL string, client *http.Client) (Store, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return nil, "", fmt.Errorf("invalid bucket URL %q, expected s3://bucket/prefix or gs://bucket/prefix", rawURL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		s := &S3{
+			Endpoint:        firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+			Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+			Bucket:          u.Host,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			HTTPClient:      client,
+		}
+		if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+			return nil, "", fmt.Errorf("%s: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required", rawURL)
+		}
+		return s, prefix, nil
+	case "gs":
+		g := &GCS{
+			Bucket:     u.Host,
+			Token:      os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+			HTTPClient: client,
+		}
+		if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+			g.Endpoint = host
+			if !strings.Contains(host, "://") {
+				g.Endpoint = "http://" + host
+			}
+		} else if g.Token == "" {
+			return nil, "", fmt.Errorf("%s: GOOGLE_OAUTH_ACCESS_TOKEN is required", rawURL)
+		}
+		return g, prefix, nil
+	}
+	return nil, "", fmt.Errorf("unsupported bucket URL %q, expected s3:// or gs://", rawURL)
+}
+
+// firstEnv returns the first set environment variable of names, or "".
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// do sends req and turns a non-2xx response into an error, with the start
+// of its body (the error document of the store).
+func do(client *http.Client, req *http.Request, object string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("put %s: %w", object, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("put %s: %s: %s", object, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
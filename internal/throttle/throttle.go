@@ -0,0 +1,136 @@
+// Package throttle simulates slow network links on the connections of the
+// mock servers: limited bandwidth, latency and jitter.
+package throttle
+
+import (
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// Config describes a simulated link. The zero Config does not throttle.
+type Config struct {
+	Bandwidth int64         // Bytes per second in each direction (0 = unlimited)
+	Latency   time.Duration // Delay of each write
+	Jitter    time.Duration // Random variation of the latency, in [-Jitter, +Jitter]
+}
+
+// Enabled reports whether the configuration throttles connections.
+func (c Config) Enabled() bool {
+	return c.Bandwidth > 0 || c.Latency > 0 || c.Jitter > 0
+}
+
+// chunksPerSecond sets the granularity of the bandwidth limit: transfers
+// progress in steps of a tenth of a second
+const chunksPerSecond = 10
+
+// Listener returns a listener whose accepted connections are throttled.
+func Listener(l net.Listener, c Config) net.Listener {
+	if !c.Enabled() {
+		return l
+	}
+	return &listener{Listener: l, config: c}
+}
+
+type listener struct {
+	net.Listener
+	config Config
+}
+
+// Accept waits for the next connection and throttles it.
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return Conn(conn, l.config), nil
+}
+
+// Conn returns a throttled connection: each write is delayed by the
+// latency and jitter, and reads and writes are limited to the bandwidth.
+func Conn(conn net.Conn, c Config) net.Conn {
+	if !c.Enabled() {
+		return conn
+	}
+	tc := &throttledConn{Conn: conn, config: c}
+	if c.Bandwidth > 0 {
+		tc.readLimit = &limiter{rate: c.Bandwidth}
+		tc.writeLimit = &limiter{rate: c.Bandwidth}
+	}
+	return tc
+}
+
+type throttledConn struct {
+	net.Conn
+	config     Config
+	readLimit  *limiter
+	writeLimit *limiter
+	writeMu    sync.Mutex // Writes are delayed one at a time, in order
+}
+
+// chunk returns the largest transfer between two bandwidth waits.
+func (c *throttledConn) chunk(n int) int {
+	if c.config.Bandwidth <= 0 {
+		return n
+	}
+	return min(n, max(1, int(c.config.Bandwidth/chunksPerSecond)))
+}
+
+// Read reads at most the bytes allowed by the bandwidth.
+func (c *throttledConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b[:c.chunk(len(b))])
+	c.readLimit.wait(n)
+	return n, err
+}
+
+// Write delays b by the latency, then writes it at the bandwidth.
+func (c *throttledConn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	time.Sleep(c.delay())
+	written := 0
+	for written < len(b) {
+		n, err := c.Conn.Write(b[written : written+c.chunk(len(b)-written)])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		c.writeLimit.wait(n)
+	}
+	return written, nil
+}
+
+// delay returns the latency of a write, with its jitter.
+func (c *throttledConn) delay() time.Duration {
+	d := c.config.Latency
+	if c.config.Jitter > 0 {
+		d += time.Duration(rand.Int64N(int64(2*c.config.Jitter)+1)) - c.config.Jitter
+	}
+	return max(d, 0)
+}
+
+// limiter paces transfers to a rate in bytes per second.
+type limiter struct {
+	rate int64
+	mu   sync.Mutex
+	due  time.Time // When the bytes transferred so far are paid for
+}
+
+// wait blocks until n more bytes fit in the rate. A nil limiter does not
+// wait.
+func (l *limiter) wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.due.Before(now) {
+		l.due = now
+	}
+	l.due = l.due.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	d := l.due.Sub(now)
+	l.mu.Unlock()
+	time.Sleep(d)
+}
@@ -0,0 +1,82 @@
+package throttle
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// transfer sends size bytes from a throttled server connection to a client
+// and returns the duration of the transfer.
+func transfer(t *testing.T, c Config, size int) time.Duration {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	tl := Listener(l, c)
+
+	go func() {
+		conn, err := tl.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = conn.Write(make([]byte, size))
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	start := time.Now()
+	n, err := io.Copy(io.Discard, conn)
+	if err != nil || n != int64(size) {
+		t.Fatalf("received %d bytes (%v), want %d", n, err, size)
+	}
+	return time.Since(start)
+}
+
+func TestConn_Bandwidth(t *testing.T) {
+	// 30 KB at 100 KB/s: the last of three 10 KB chunks is sent after 200 ms
+	elapsed := transfer(t, Config{Bandwidth: 100 * 1024}, 30*1024)
+	if elapsed < 180*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("transfer took %v, want about 200ms", elapsed)
+	}
+}
+
+func TestConn_Latency(t *testing.T) {
+	elapsed := transfer(t, Config{Latency: 200 * time.Millisecond, Jitter: 50 * time.Millisecond}, 1024)
+	if elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("transfer took %v, want 150-250ms", elapsed)
+	}
+}
+
+func TestListener_Disabled(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	if Listener(l, Config{}) != l {
+		t.Error("zero Config wraps the listener")
+	}
+}
+
+func TestConfig_Jitter(t *testing.T) {
+	c := &throttledConn{config: Config{Latency: 100 * time.Millisecond, Jitter: 20 * time.Millisecond}}
+	for i := 0; i < 100; i++ {
+		if d := c.delay(); d < 80*time.Millisecond || d > 120*time.Millisecond {
+			t.Fatalf("delay %v outside 80-120ms", d)
+		}
+	}
+	c.config = Config{Latency: 10 * time.Millisecond, Jitter: 50 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		if d := c.delay(); d < 0 {
+			t.Fatalf("negative delay %v", d)
+		}
+	}
+}
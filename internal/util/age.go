@@ -0,0 +1,53 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxAgeValue is the largest number an Age String (AS) can hold.
+const MaxAgeValue = 999
+
+// PatientAge returns the Age String (AS) of a patient at a study, from their
+// birth date and the study date (both DA, YYYYMMDD).
+//
+// The unit follows common scanner practice: days under one month ("012D"),
+// months under two years ("018M"), years otherwise ("045Y").
+func PatientAge(birthDate, studyDate string) (string, error) {
+	birth, err := ParseDA(birthDate)
+	if err != nil {
+		return "", fmt.Errorf("birth date: %w", err)
+	}
+	study, err := ParseDA(studyDate)
+	if err != nil {
+		return "", fmt.Errorf("study date: %w", err)
+	}
+	return FormatAge(birth, study)
+}
+
+// FormatAge returns the Age String (AS) of someone born at birth on date at.
+func FormatAge(birth, at time.Time) (string, error) {
+	if at.Before(birth) {
+		return "", fmt.Errorf("date %s is before birth date %s", FormatDA(at), FormatDA(birth))
+	}
+
+	months := completeMonths(birth, at)
+	switch {
+	case months < 1:
+		days := int(at.Sub(birth).Hours() / 24)
+		return fmt.Sprintf("%03dD", days), nil
+	case months < 24:
+		return fmt.Sprintf("%03dM", months), nil
+	default:
+		return fmt.Sprintf("%03dY", min(months/12, MaxAgeValue)), nil
+	}
+}
+
+// completeMonths returns the number of complete months between from and to.
+func completeMonths(from, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() < from.Day() {
+		months--
+	}
+	return months
+}
@@ -0,0 +1,56 @@
+package util
+
+import "testing"
+
+func TestPatientAge(t *testing.T) {
+	tests := []struct {
+		name      string
+		birthDate string
+		studyDate string
+		want      string
+	}{
+		{"same day", "20240315", "20240315", "000D"},
+		{"newborn", "20240301", "20240315", "014D"},
+		{"one day short of a month", "20240115", "20240214", "030D"},
+		{"exactly one month", "20240115", "20240215", "001M"},
+		{"infant", "20230610", "20240315", "009M"},
+		{"one day short of two years", "20220316", "20240315", "023M"},
+		{"exactly two years", "20220315", "20240315", "002Y"},
+		{"birthday not reached", "19800316", "20240315", "043Y"},
+		{"birthday reached", "19800315", "20240315", "044Y"},
+		{"leap day birth", "20000229", "20240228", "023Y"},
+		{"capped", "09000101", "20240101", "999Y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PatientAge(tt.birthDate, tt.studyDate)
+			if err != nil {
+				t.Fatalf("PatientAge(%s, %s) returned error: %v", tt.birthDate, tt.studyDate, err)
+			}
+			if got != tt.want {
+				t.Errorf("PatientAge(%s, %s) = %q, want %q", tt.birthDate, tt.studyDate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatientAge_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		birthDate string
+		studyDate string
+	}{
+		{"partial birth date", "1980", "20240315"},
+		{"empty study date", "19800101", ""},
+		{"study before birth", "20240315", "20240314"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if age, err := PatientAge(tt.birthDate, tt.studyDate); err == nil {
+				t.Errorf("PatientAge(%s, %s) = %q, want error", tt.birthDate, tt.studyDate, age)
+			}
+		})
+	}
+}
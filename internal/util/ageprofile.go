@@ -0,0 +1,171 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AgeProfile is an age group of patients: the ages of their studies, their
+// body size and the protocols they are imaged with.
+type AgeProfile string
+
+// Age profiles
+const (
+	AgeProfileNeonate   AgeProfile = "neonate"   // First 28 days of life
+	AgeProfilePediatric AgeProfile = "pediatric" // 0-17 years
+	AgeProfileAdult     AgeProfile = "adult"     // 18-64 years
+	AgeProfileGeriatric AgeProfile = "geriatric" // 65 years and older
+)
+
+// Age pyramids of the profiles, at the reference date of the demographics
+var ageProfilePyramids = map[AgeProfile][]AgeBand{
+	AgeProfileNeonate:   {{MinAge: 0, MaxAge: 0, Weight: 1}},
+	AgeProfilePediatric: agePyramidPresets["pediatric"],
+	AgeProfileAdult: {
+		{MinAge: 18, MaxAge: 39, Weight: 35},
+		{MinAge: 40, MaxAge: 64, Weight: 65},
+	},
+	AgeProfileGeriatric: {
+		{MinAge: 65, MaxAge: 79, Weight: 67},
+		{MinAge: 80, MaxAge: 99, Weight: 33},
+	},
+}
+
+// neonatalDays is the number of days of the neonatal period.
+const neonatalDays = 28
+
+// AgeProfiles returns the names of the age profiles.
+func AgeProfiles() []string {
+	names := make([]string, 0, len(ageProfilePyramids))
+	for profile := range ageProfilePyramids {
+		names = append(names, string(profile))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseAgeProfile parses an age profile: neonate, pediatric, adult or
+// geriatric, case-insensitive. "" is no profile.
+func ParseAgeProfile(s string) (AgeProfile, error) {
+	profile := AgeProfile(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := ageProfilePyramids[profile]; ok || profile == "" {
+		return profile, nil
+	}
+	return "", fmt.Errorf("invalid age profile %q (valid: %s)", s, strings.Join(AgeProfiles(), ", "))
+}
+
+// AgePyramid returns the age distribution of the patients of the profile.
+func (p AgeProfile) AgePyramid() []AgeBand {
+	return ageProfilePyramids[p]
+}
+
+// StudyDate returns the date of a study of a patient born on birthDate, in
+// the ages of the profile: studyDate when the patient has one of them at it,
+// else a date drawn among them. Neonates are imaged in their first 28 days.
+// Dates that do not parse are returned unchanged.
+func (p AgeProfile) StudyDate(birthDate, studyDate string, rng *rand.Rand) string {
+	birth, err := ParseDA(birthDate)
+	if err != nil {
+		return studyDate
+	}
+	study, err := ParseDA(studyDate)
+	if err != nil {
+		return studyDate
+	}
+
+	var earliest, latest time.Time
+	if p == AgeProfileNeonate {
+		earliest, latest = birth, birth.AddDate(0, 0, neonatalDays-1)
+	} else {
+		bands := p.AgePyramid()
+		if len(bands) == 0 {
+			return studyDate
+		}
+		minAge, maxAge := bands[0].MinAge, bands[0].MaxAge
+		for _, band := range bands[1:] {
+			minAge, maxAge = min(minAge, band.MinAge), max(maxAge, band.MaxAge)
+		}
+		earliest, latest = birth.AddDate(minAge, 0, 0), birth.AddDate(maxAge+1, 0, -1)
+	}
+	if !study.Before(earliest) && !study.After(latest) {
+		return studyDate
+	}
+	days := int(latest.Sub(earliest).Hours() / 24)
+	return FormatDA(earliest.AddDate(0, 0, rng.IntN(days+1)))
+}
+
+// ProtocolName returns the name of the protocol of the profile derived from
+// an adult protocol: neonates and children are imaged with dedicated
+// (low-dose, small field of view) protocols.
+func (p AgeProfile) ProtocolName(protocol string) string {
+	switch p {
+	case AgeProfileNeonate:
+		return "NEONATAL_" + protocol
+	case AgeProfilePediatric:
+		return "PEDS_" + protocol
+	}
+	return protocol
+}
+
+// Median height (cm) and weight (kg) of children by age in years, both sexes
+var (
+	childHeights = []float64{50, 75, 87, 96, 103, 110, 116, 122, 128, 133, 139, 144, 150, 157, 163, 167, 170, 172}
+	childWeights = []float64{3.4, 9.5, 12.2, 14.3, 16.3, 18.4, 20.7, 22.9, 25.6, 28.6, 32, 36, 40.5, 45.5, 51, 56, 60, 63}
+)
+
+// Median height (cm) and weight (kg) of adults, by sex, and what they lose
+// after 75
+var (
+	adultHeights                         = map[string]float64{"M": 176, "F": 163}
+	adultWeights                         = map[string]float64{"M": 80, "F": 66}
+	elderlyHeightLoss, elderlyWeightLoss = 3.0, 5.0
+)
+
+// SampleBodySize draws the weight (kg) and height (m) of a patient born on
+// birthDate of the given sex ("M", "F", else the mean of both) at a study,
+// around the medians of their age: interpolated over the growth of children,
+// lower after 75. ok is false when the dates do not parse.
+func SampleBodySize(birthDate, studyDate, sex string, rng *rand.Rand) (weightKg, heightM float64, ok bool) {
+	birth, err := ParseDA(birthDate)
+	if err != nil {
+		return 0, 0, false
+	}
+	study, err := ParseDA(studyDate)
+	if err != nil || study.Before(birth) {
+		return 0, 0, false
+	}
+	years := study.Sub(birth).Hours() / 24 / 365.25
+
+	adultHeight, adultWeight := (adultHeights["M"]+adultHeights["F"])/2, (adultWeights["M"]+adultWeights["F"])/2
+	if h, ok := adultHeights[sex]; ok {
+		adultHeight, adultWeight = h, adultWeights[sex]
+	}
+	var height, weight float64
+	switch last := len(childHeights) - 1; {
+	case years < float64(last):
+		i := int(years)
+		f := years - float64(i)
+		height = childHeights[i] + f*(childHeights[i+1]-childHeights[i])
+		weight = childWeights[i] + f*(childWeights[i+1]-childWeights[i])
+	case years < 18:
+		// Toward the adult size of the sex
+		f := years - float64(last)
+		height = childHeights[last] + f*(adultHeight-childHeights[last])
+		weight = childWeights[last] + f*(adultWeight-childWeights[last])
+	default:
+		height, weight = adultHeight, adultWeight
+		if years >= 75 {
+			height -= elderlyHeightLoss
+			weight -= elderlyWeightLoss
+		}
+	}
+
+	// Spread of about 4% of the height and 12% of the weight
+	height *= 1 + 0.04*math.Max(-2.5, math.Min(2.5, rng.NormFloat64()))
+	weight *= 1 + 0.12*math.Max(-2.5, math.Min(2.5, rng.NormFloat64()))
+	return math.Round(weight*10) / 10, math.Round(height) / 100, true
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestParseAgeProfile(t *testing.T) {
+	for _, input := range []string{"neonate", "Pediatric", " adult ", "GERIATRIC", ""} {
+		if _, err := ParseAgeProfile(input); err != nil {
+			t.Errorf("ParseAgeProfile(%q) returned error: %v", input, err)
+		}
+	}
+	if _, err := ParseAgeProfile("elderly"); err == nil {
+		t.Error("ParseAgeProfile(elderly) should return error")
+	}
+}
+
+func TestAgeProfile_StudyDate(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	tests := []struct {
+		profile          AgeProfile
+		birthDate, study string
+		minAge, maxAge   string // Bounds of the patient age at the study
+	}{
+		{AgeProfileNeonate, "20220310", "20231105", "000D", "027D"},
+		{AgeProfilePediatric, "20100101", "20231105", "013Y", "013Y"}, // Kept
+		{AgeProfileAdult, "20100101", "20231105", "018Y", "064Y"},
+		{AgeProfileGeriatric, "19500101", "20200101", "070Y", "070Y"}, // Kept
+		{AgeProfileGeriatric, "19700101", "20200101", "065Y", "099Y"},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			date := tt.profile.StudyDate(tt.birthDate, tt.study, rng)
+			age, err := PatientAge(tt.birthDate, date)
+			if err != nil {
+				t.Fatalf("%s: PatientAge(%s, %s) returned error: %v", tt.profile, tt.birthDate, date, err)
+			}
+			// Ages of a unit compare as strings
+			if age[3] != tt.minAge[3] || age < tt.minAge || age > tt.maxAge {
+				t.Fatalf("%s: study on %s at %s, want %s-%s", tt.profile, date, age, tt.minAge, tt.maxAge)
+			}
+		}
+	}
+
+	if got := AgeProfileAdult.StudyDate("1950", "20200101", rng); got != "20200101" {
+		t.Errorf("partial birth date: StudyDate = %s, want the study date unchanged", got)
+	}
+}
+
+func TestSampleBodySize(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	tests := []struct {
+		name                 string
+		birthDate, sex       string
+		minWeight, maxWeight float64
+		minHeight, maxHeight float64
+	}{
+		{"neonate", "20200101", "F", 2, 5, 0.44, 0.56},
+		{"5 years", "20150101", "M", 11, 26, 0.97, 1.23},
+		{"adult man", "19800101", "M", 50, 110, 1.58, 1.94},
+		{"adult woman", "19800101", "F", 41, 91, 1.46, 1.80},
+		{"geriatric", "19300101", "", 43, 99, 1.50, 1.85},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			weight, height, ok := SampleBodySize(tt.birthDate, "20200115", tt.sex, rng)
+			if !ok || weight < tt.minWeight || weight > tt.maxWeight || height < tt.minHeight || height > tt.maxHeight {
+				t.Fatalf("%s: SampleBodySize = %v kg, %v m, %v", tt.name, weight, height, ok)
+			}
+		}
+	}
+
+	if _, _, ok := SampleBodySize("20210101", "20200101", "M", rng); ok {
+		t.Error("study before birth: SampleBodySize should not be ok")
+	}
+}
+
+func TestAgeProfile_ProtocolName(t *testing.T) {
+	tests := []struct {
+		profile AgeProfile
+		want    string
+	}{
+		{AgeProfileNeonate, "NEONATAL_BRAIN_ROUTINE"},
+		{AgeProfilePediatric, "PEDS_BRAIN_ROUTINE"},
+		{AgeProfileAdult, "BRAIN_ROUTINE"},
+		{"", "BRAIN_ROUTINE"},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.ProtocolName("BRAIN_ROUTINE"); got != tt.want {
+			t.Errorf("%q.ProtocolName = %s, want %s", tt.profile, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Character sets of the text of DICOM files (SpecificCharacterSet)
+const (
+	CharacterSetLatin1 = "ISO_IR 100" // ISO 8859-1, Western European languages
+	CharacterSetUTF8   = "ISO_IR 192" // Unicode in UTF-8
+)
+
+// characterSetNames are the names of the character sets accepted by
+// ParseCharacterSet, besides their defined terms.
+var characterSetNames = map[string]string{
+	"latin1": CharacterSetLatin1,
+	"utf8":   CharacterSetUTF8,
+	"utf-8":  CharacterSetUTF8,
+}
+
+// ParseCharacterSet parses the character set of DICOM files, by name
+// (latin1, utf8, case-insensitive) or defined term (ISO_IR 100, ISO_IR 192).
+// "" is the default: ISO_IR 192 for the files with non-ASCII text only.
+func ParseCharacterSet(s string) (string, error) {
+	v := strings.TrimSpace(s)
+	if v == "" {
+		return "", nil
+	}
+	if charset, ok := characterSetNames[strings.ToLower(v)]; ok {
+		return charset, nil
+	}
+	if charset := strings.ToUpper(v); charset == CharacterSetLatin1 || charset == CharacterSetUTF8 {
+		return charset, nil
+	}
+	return "", fmt.Errorf("invalid character set %q (expected latin1, utf8, %s or %s)", s, CharacterSetLatin1, CharacterSetUTF8)
+}
+
+// textVRs are the VRs of the values that SpecificCharacterSet applies to
+var textVRs = map[string]bool{"SH": true, "LO": true, "ST": true, "LT": true, "PN": true, "UC": true, "UT": true}
+
+// EncodeText returns elements with their text, in sequences too, in their
+// SpecificCharacterSet: ISO 8859-1 bytes for ISO_IR 100, UTF-8 for the
+// others. Files without SpecificCharacterSet but with non-ASCII text declare
+// ISO_IR 192, as do ISO_IR 100 files with text ISO 8859-1 lacks (Ł, Š...).
+// Text already encoded (not UTF-8) is kept, so that elements can be encoded
+// twice.
+func EncodeText(elements []*dicom.Element) []*dicom.Element {
+	declared := ""
+	for _, elem := range elements {
+		if elem.Tag == tag.SpecificCharacterSet {
+			if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 {
+				declared = values[0]
+			}
+		}
+	}
+
+	charset := declared
+	switch {
+	case declared == "" && !textFits(elements, func(r rune) bool { return r < utf8.RuneSelf }):
+		charset = CharacterSetUTF8
+	case declared == CharacterSetLatin1 && !textFits(elements, func(r rune) bool { return r <= 0xFF }):
+		charset = CharacterSetUTF8
+	}
+	if charset == CharacterSetLatin1 {
+		elements = mapText(elements, toLatin1)
+	}
+	if charset != declared {
+		elements = DeclareCharacterSet(elements, charset)
+	}
+	return elements
+}
+
+// textFits reports whether every character of the UTF-8 text of elements,
+// in sequences too, fits (encoded text is skipped).
+func textFits(elements []*dicom.Element, fits func(rune) bool) bool {
+	for _, elem := range elements {
+		switch values := elem.Value.GetValue().(type) {
+		case []string:
+			if !textVRs[elem.RawValueRepresentation] {
+				continue
+			}
+			for _, v := range values {
+				if !utf8.ValidString(v) {
+					continue
+				}
+				for _, r := range v {
+					if !fits(r) {
+						return false
+					}
+				}
+			}
+		case []*dicom.SequenceItemValue:
+			for _, item := range values {
+				if !textFits(item.GetValue().([]*dicom.Element), fits) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// mapText returns elements with encode applied to their text, in sequences
+// too. Elements are copied, not modified: they may be shared by files.
+func mapText(elements []*dicom.Element, encode func(string) string) []*dicom.Element {
+	result := make([]*dicom.Element, len(elements))
+	for i, elem := range elements {
+		result[i] = elem
+		var value dicom.Value
+		var err error
+		switch values := elem.Value.GetValue().(type) {
+		case []string:
+			if !textVRs[elem.RawValueRepresentation] {
+				continue
+			}
+			encoded := make([]string, len(values))
+			for j, v := range values {
+				encoded[j] = encode(v)
+			}
+			value, err = dicom.NewValue(encoded)
+		case []*dicom.SequenceItemValue:
+			items := make([][]*dicom.Element, len(values))
+			for j, item := range values {
+				items[j] = mapText(item.GetValue().([]*dicom.Element), encode)
+			}
+			value, err = dicom.NewValue(items)
+		default:
+			continue
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to encode %v: %v", elem.Tag, err))
+		}
+		copied := *elem
+		copied.Value = value
+		result[i] = &copied
+	}
+	return result
+}
+
+// toLatin1 returns UTF-8 s encoded in ISO 8859-1, with '?' for the
+// characters it lacks. Other strings are returned unchanged.
+func toLatin1(s string) string {
+	if !utf8.ValidString(s) {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return string(b)
+}
+
+// DeclareCharacterSet returns elements with SpecificCharacterSet set to
+// charset, before the other elements of the data set, after the File Meta
+// Information.
+func DeclareCharacterSet(elements []*dicom.Element, charset string) []*dicom.Element {
+	declaration, err := dicom.NewElement(tag.SpecificCharacterSet, []string{charset})
+	if err != nil {
+		panic(fmt.Sprintf("failed to declare character set %q: %v", charset, err))
+	}
+	for i, elem := range elements {
+		if elem.Tag == tag.SpecificCharacterSet {
+			result := append([]*dicom.Element(nil), elements...)
+			result[i] = declaration
+			return result
+		}
+	}
+	at := 0
+	for at < len(elements) && elements[at].Tag.Group == 0x0002 {
+		at++
+	}
+	result := make([]*dicom.Element, 0, len(elements)+1)
+	result = append(append(append(result, elements[:at]...), declaration), elements[at:]...)
+	return result
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// newElement creates a DICOM element with a standard tag or fails the test.
+func newElement(t *testing.T, tg tag.Tag, data any) *dicom.Element {
+	t.Helper()
+	elem, err := dicom.NewElement(tg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return elem
+}
+
+// stringValue returns the first string value of an element of elements.
+func stringValue(elements []*dicom.Element, tg tag.Tag) string {
+	for _, elem := range elements {
+		if elem.Tag == tg {
+			if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 {
+				return values[0]
+			}
+		}
+	}
+	return ""
+}
+
+func TestParseCharacterSet(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"", ""},
+		{"latin1", CharacterSetLatin1},
+		{"UTF8", CharacterSetUTF8},
+		{"utf-8", CharacterSetUTF8},
+		{"iso_ir 100", CharacterSetLatin1},
+		{"ISO_IR 192", CharacterSetUTF8},
+	}
+	for _, tt := range tests {
+		got, err := ParseCharacterSet(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCharacterSet(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"ascii", "ISO_IR 101", "GB18030"} {
+		if _, err := ParseCharacterSet(input); err == nil {
+			t.Errorf("ParseCharacterSet(%q) should return error", input)
+		}
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	withName := func(t *testing.T, charset, name string) []*dicom.Element {
+		elements := []*dicom.Element{
+			newElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+			newElement(t, tag.PatientName, []string{name}),
+			newElement(t, tag.OtherPatientIDsSequence, [][]*dicom.Element{{newElement(t, tag.IssuerOfPatientID, []string{"Hôpital"})}}),
+		}
+		if charset != "" {
+			elements = DeclareCharacterSet(elements, charset)
+		}
+		return elements
+	}
+	tests := []struct {
+		name, charset, patientName string
+		wantCharset, wantName      string
+		wantIssuer                 string
+	}{
+		{"ASCII", "", "Martin^Jean", CharacterSetUTF8, "Martin^Jean", "Hôpital"}, // The issuer is not ASCII
+		{"UTF-8", CharacterSetUTF8, "Gérard^Éric", CharacterSetUTF8, "Gérard^Éric", "Hôpital"},
+		{"Latin-1", CharacterSetLatin1, "Gérard^Éric", CharacterSetLatin1, "G\xe9rard^\xc9ric", "H\xf4pital"},
+		{"beyond Latin-1", CharacterSetLatin1, "Škvorecký^Łukasz", CharacterSetUTF8, "Škvorecký^Łukasz", "Hôpital"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := withName(t, tt.charset, tt.patientName)
+			encoded := EncodeText(original)
+			if got := stringValue(encoded, tag.SpecificCharacterSet); got != tt.wantCharset {
+				t.Errorf("SpecificCharacterSet = %q, want %q", got, tt.wantCharset)
+			}
+			if encoded[1].Tag != tag.SpecificCharacterSet {
+				t.Errorf("SpecificCharacterSet is not the first element after the File Meta Information")
+			}
+			if got := stringValue(encoded, tag.PatientName); got != tt.wantName {
+				t.Errorf("PatientName = %q, want %q", got, tt.wantName)
+			}
+			seq, _ := (&dicom.Dataset{Elements: encoded}).FindElementByTag(tag.OtherPatientIDsSequence)
+			item := seq.Value.GetValue().([]*dicom.SequenceItemValue)[0].GetValue().([]*dicom.Element)
+			if got := stringValue(item, tag.IssuerOfPatientID); got != tt.wantIssuer {
+				t.Errorf("IssuerOfPatientID = %q, want %q", got, tt.wantIssuer)
+			}
+
+			// Encoding twice changes nothing, and the original elements are kept
+			if got := stringValue(EncodeText(encoded), tag.PatientName); got != tt.wantName {
+				t.Errorf("encoded twice: PatientName = %q, want %q", got, tt.wantName)
+			}
+			if got := stringValue(original, tag.PatientName); got != tt.patientName {
+				t.Errorf("original PatientName = %q, want %q", got, tt.patientName)
+			}
+		})
+	}
+
+	// ASCII files keep the default repertoire
+	ascii := []*dicom.Element{newElement(t, tag.PatientName, []string{"Martin^Jean"})}
+	if got := EncodeText(ascii); len(got) != 1 {
+		t.Errorf("ASCII text declared a character set: %v", got)
+	}
+}
@@ -11,6 +11,7 @@ var BodyPartsByModality = map[string][]string{
 	"DX": {"CHEST", "HAND", "FOOT", "KNEE", "SHOULDER", "SKULL", "SPINE", "PELVIS", "RIBS"},
 	"US": {"ABDOMEN", "PELVIS", "BREAST", "THYROID", "HEART", "LIVER", "KIDNEY", "UTERUS"},
 	"MG": {"BREAST"},
+	"RF": {"ESOPHAGUS", "STOMACH", "COLON", "ABDOMEN", "BLADDER", "SPINE"},
 }
 
 // DefaultBodyParts is used when modality is unknown
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CohortPatient is the demographic data of a generated patient.
+type CohortPatient struct {
+	Sex       string
+	BirthDate string
+	Locale    string
+}
+
+// DistributionRow compares the target and achieved fractions of a category.
+type DistributionRow struct {
+	Label    string
+	Target   float64 // 0-1
+	Achieved float64 // 0-1
+}
+
+// CohortSummary reports the achieved distributions of a generated cohort
+// against the requested demographics.
+type CohortSummary struct {
+	Patients int
+	Sex      []DistributionRow
+	Ages     []DistributionRow
+	Locales  []DistributionRow
+}
+
+// SummarizeCohort computes the distributions achieved by patients. Ages are
+// taken at the demographics reference date; patients whose birth date cannot
+// be parsed (e.g., partial dates) are left out of the age distribution.
+func SummarizeCohort(d Demographics, patients []CohortPatient) CohortSummary {
+	summary := CohortSummary{Patients: len(patients)}
+	if len(patients) == 0 {
+		return summary
+	}
+	n := float64(len(patients))
+
+	males := 0
+	localeCounts := make(map[string]int)
+	bandCounts := make([]int, len(d.AgePyramid))
+	aged := 0
+	for _, p := range patients {
+		if p.Sex == "M" {
+			males++
+		}
+		localeCounts[p.Locale]++
+
+		birth, err := ParseDA(p.BirthDate)
+		if err != nil || d.ReferenceDate.Before(birth) {
+			continue
+		}
+		age := completeMonths(birth, d.ReferenceDate) / 12
+		for i, band := range d.AgePyramid {
+			if age >= band.MinAge && age <= band.MaxAge {
+				bandCounts[i]++
+				aged++
+				break
+			}
+		}
+	}
+
+	summary.Sex = []DistributionRow{
+		{Label: "M", Target: d.MaleRatio, Achieved: float64(males) / n},
+		{Label: "F", Target: 1 - d.MaleRatio, Achieved: float64(len(patients)-males) / n},
+	}
+
+	total := totalWeight(d.AgePyramid)
+	for i, band := range d.AgePyramid {
+		row := DistributionRow{Label: fmt.Sprintf("%d-%d", band.MinAge, band.MaxAge), Target: band.Weight / total}
+		if aged > 0 {
+			row.Achieved = float64(bandCounts[i]) / float64(aged)
+		}
+		summary.Ages = append(summary.Ages, row)
+	}
+
+	var localeTotal float64
+	for _, l := range d.NameLocales {
+		localeTotal += l.Weight
+	}
+	for _, l := range d.NameLocales {
+		summary.Locales = append(summary.Locales, DistributionRow{
+			Label:    l.Locale,
+			Target:   l.Weight / localeTotal,
+			Achieved: float64(localeCounts[l.Locale]) / n,
+		})
+	}
+
+	return summary
+}
+
+// MaxDeviation returns the largest absolute difference between a target and
+// an achieved fraction, across all distributions.
+func (s CohortSummary) MaxDeviation() float64 {
+	deviation := 0.0
+	for _, rows := range [][]DistributionRow{s.Sex, s.Ages, s.Locales} {
+		for _, row := range rows {
+			deviation = math.Max(deviation, math.Abs(row.Target-row.Achieved))
+		}
+	}
+	return deviation
+}
+
+// String formats the summary as a table of target and achieved percentages.
+func (s CohortSummary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Cohort summary (%d patients):\n", s.Patients)
+	fmt.Fprintf(&sb, "  %-12s %-8s %8s %9s\n", "Distribution", "Value", "Target", "Achieved")
+
+	section := func(name string, rows []DistributionRow) {
+		for i, row := range rows {
+			if i > 0 {
+				name = ""
+			}
+			fmt.Fprintf(&sb, "  %-12s %-8s %7.1f%% %8.1f%%\n", name, row.Label, row.Target*100, row.Achieved*100)
+		}
+	}
+	section("Sex", s.Sex)
+	section("Age", s.Ages)
+	section("Name locale", s.Locales)
+
+	fmt.Fprintf(&sb, "  Max deviation: %.1f points\n", s.MaxDeviation()*100)
+	return sb.String()
+}
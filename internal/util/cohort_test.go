@@ -0,0 +1,45 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarizeCohort(t *testing.T) {
+	d := DefaultDemographics()
+	d.AgePyramid = []AgeBand{{0, 17, 1}, {18, 99, 3}}
+
+	patients := []CohortPatient{
+		{Sex: "M", BirthDate: "20100101", Locale: LocaleEnglish}, // 12 at the reference date
+		{Sex: "F", BirthDate: "19800101", Locale: LocaleEnglish},
+		{Sex: "F", BirthDate: "19600101", Locale: LocaleFrench},
+		{Sex: "F", BirthDate: "1950", Locale: LocaleEnglish}, // Partial date: no age
+	}
+
+	summary := SummarizeCohort(d, patients)
+	if summary.Sex[0].Achieved != 0.25 {
+		t.Errorf("male fraction = %v, want 0.25", summary.Sex[0].Achieved)
+	}
+	if summary.Ages[0].Target != 0.25 || summary.Ages[0].Achieved != 1.0/3 {
+		t.Errorf("0-17 band = %+v, want target 0.25 achieved 1/3", summary.Ages[0])
+	}
+	if summary.Locales[1].Achieved != 0.25 {
+		t.Errorf("French fraction = %v, want 0.25", summary.Locales[1].Achieved)
+	}
+	if dev := summary.MaxDeviation(); dev != 0.25 {
+		t.Errorf("MaxDeviation = %v, want 0.25 (sex)", dev)
+	}
+
+	report := summary.String()
+	for _, want := range []string{"4 patients", "18-99", "Name locale", "Max deviation"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestSummarizeCohort_Empty(t *testing.T) {
+	if summary := SummarizeCohort(DefaultDemographics(), nil); summary.Patients != 0 || summary.MaxDeviation() != 0 {
+		t.Errorf("empty cohort summary = %+v", summary)
+	}
+}
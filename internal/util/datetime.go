@@ -0,0 +1,171 @@
+package util
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"time"
+)
+
+// DICOM date/time layouts
+const (
+	LayoutDA = "20060102"
+	LayoutTM = "150405"
+	LayoutDT = "20060102150405"
+)
+
+var (
+	daPattern = regexp.MustCompile(`^\d{8}$`)
+	tmPattern = regexp.MustCompile(`^\d{2}(\d{2}(\d{2}(\.\d{1,6})?)?)?$`)
+	dtPattern = regexp.MustCompile(`^\d{4}(\d{2}(\d{2}(\d{2}(\d{2}(\d{2}(\.\d{1,6})?)?)?)?)?)?([+-]\d{4})?$`)
+)
+
+// FormatDA formats t as a DICOM Date (DA): YYYYMMDD.
+func FormatDA(t time.Time) string {
+	return t.Format(LayoutDA)
+}
+
+// FormatTM formats t as a DICOM Time (TM): HHMMSS, followed by microseconds
+// (HHMMSS.FFFFFF) when fractional is true.
+func FormatTM(t time.Time, fractional bool) string {
+	if fractional {
+		return t.Format(LayoutTM + ".000000")
+	}
+	return t.Format(LayoutTM)
+}
+
+// FormatDT formats t as a DICOM Date Time (DT): YYYYMMDDHHMMSS, followed by
+// microseconds when fractional is true. No UTC offset is written.
+func FormatDT(t time.Time, fractional bool) string {
+	return FormatDA(t) + FormatTM(t, fractional)
+}
+
+// ParseDA parses a DICOM Date (DA).
+func ParseDA(s string) (time.Time, error) {
+	if !daPattern.MatchString(s) {
+		return time.Time{}, fmt.Errorf("invalid DA %q (expected YYYYMMDD)", s)
+	}
+	t, err := time.Parse(LayoutDA, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DA %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// ParseTM parses a DICOM Time (TM): HH, HHMM, HHMMSS or HHMMSS.F to HHMMSS.FFFFFF.
+// The returned time is on January 1st, year 0.
+func ParseTM(s string) (time.Time, error) {
+	if !tmPattern.MatchString(s) {
+		return time.Time{}, fmt.Errorf("invalid TM %q (expected HHMMSS[.FFFFFF])", s)
+	}
+	layout := LayoutTM[:min(len(s), len(LayoutTM))]
+	if len(s) > len(LayoutTM) {
+		layout += ".000000"[:len(s)-len(LayoutTM)]
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid TM %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// ParseDT parses a DICOM Date Time (DT), from YYYY up to
+// YYYYMMDDHHMMSS.FFFFFF, with an optional &ZZXX UTC offset.
+func ParseDT(s string) (time.Time, error) {
+	if !dtPattern.MatchString(s) {
+		return time.Time{}, fmt.Errorf("invalid DT %q (expected YYYYMMDDHHMMSS[.FFFFFF][&ZZXX])", s)
+	}
+	value, offset := s, ""
+	if n := len(s); n > 5 && (s[n-5] == '+' || s[n-5] == '-') {
+		value, offset = s[:n-5], s[n-5:]
+	}
+	layout := LayoutDT[:min(len(value), len(LayoutDT))]
+	if len(value) > len(LayoutDT) {
+		layout += ".000000"[:len(value)-len(LayoutDT)]
+	}
+	if offset != "" {
+		layout += "-0700"
+	}
+	t, err := time.Parse(layout, value+offset)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DT %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// ValidateDateTime checks that value is a valid DA, TM or DT for the given VR.
+// Other VRs are not checked.
+func ValidateDateTime(vr, value string) error {
+	var err error
+	switch vr {
+	case "DA":
+		_, err = ParseDA(value)
+	case "TM":
+		_, err = ParseTM(value)
+	case "DT":
+		_, err = ParseDT(value)
+	}
+	return err
+}
+
+// CombineDATM combines a DICOM Date and Time into a single time.
+func CombineDATM(da, tm string) (time.Time, error) {
+	date, err := ParseDA(da)
+	if err != nil {
+		return time.Time{}, err
+	}
+	clock, err := ParseTM(tm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), time.UTC), nil
+}
+
+// GenerateDate generates a random DICOM Date (DA) between fromYear and toYear
+// (inclusive). Days are limited to 1-28 so every month is valid.
+func GenerateDate(rng *rand.Rand, fromYear, toYear int) string {
+	return fmt.Sprintf("%04d%02d%02d",
+		rng.IntN(toYear-fromYear+1)+fromYear,
+		rng.IntN(12)+1,
+		rng.IntN(28)+1)
+}
+
+// GenerateTime generates a random DICOM Time (TM), with microseconds when
+// fractional is true.
+func GenerateTime(rng *rand.Rand, fractional bool) string {
+	tm := fmt.Sprintf("%02d%02d%02d", rng.IntN(24), rng.IntN(60), rng.IntN(60))
+	if fractional {
+		tm += fmt.Sprintf(".%06d", rng.IntN(1000000))
+	}
+	return tm
+}
+
+// Timeline derives the date/times of the series and acquisitions of a study
+// from its start, so that StudyDate/Time <= SeriesDate/Time <=
+// AcquisitionDate/Time always holds, including across midnight.
+type Timeline struct {
+	Start            time.Time     // Study date/time
+	SeriesInterval   time.Duration // Delay between the starts of consecutive series
+	InstanceInterval time.Duration // Delay between consecutive acquisitions of a series
+}
+
+// NewTimeline returns a timeline starting at start with typical scanner pacing.
+func NewTimeline(start time.Time) Timeline {
+	return Timeline{
+		Start:            start,
+		SeriesInterval:   4 * time.Minute,
+		InstanceInterval: 1500 * time.Millisecond,
+	}
+}
+
+// Series returns the start of the series at the given 0-based index.
+func (t Timeline) Series(seriesIndex int) time.Time {
+	// The first series starts shortly after the patient is registered
+	return t.Start.Add(time.Minute + time.Duration(seriesIndex)*t.SeriesInterval)
+}
+
+// Acquisition returns the acquisition time of the 0-based instance of a series.
+func (t Timeline) Acquisition(seriesIndex, instanceIndex int) time.Time {
+	return t.Series(seriesIndex).Add(time.Duration(instanceIndex) * t.InstanceInterval)
+}
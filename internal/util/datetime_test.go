@@ -0,0 +1,117 @@
+package util
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestFormatDateTime(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 8, 7, 6, 123456000, time.UTC)
+
+	if got := FormatDA(ts); got != "20240305" {
+		t.Errorf("FormatDA = %q, want 20240305", got)
+	}
+	if got := FormatTM(ts, false); got != "080706" {
+		t.Errorf("FormatTM = %q, want 080706", got)
+	}
+	if got := FormatTM(ts, true); got != "080706.123456" {
+		t.Errorf("FormatTM(fractional) = %q, want 080706.123456", got)
+	}
+	if got := FormatDT(ts, true); got != "20240305080706.123456" {
+		t.Errorf("FormatDT(fractional) = %q, want 20240305080706.123456", got)
+	}
+}
+
+func TestValidateDateTime(t *testing.T) {
+	tests := []struct {
+		vr    string
+		value string
+		valid bool
+	}{
+		{"DA", "20240315", true},
+		{"DA", "20240229", true},
+		{"DA", "20230229", false},
+		{"DA", "2024-03-15", false},
+		{"DA", "202403", false},
+		{"TM", "08", true},
+		{"TM", "0830", true},
+		{"TM", "083015", true},
+		{"TM", "083015.1", true},
+		{"TM", "083015.123456", true},
+		{"TM", "083015.1234567", false},
+		{"TM", "240000", false},
+		{"TM", "08:30:15", false},
+		{"DT", "2024", true},
+		{"DT", "20240315083015", true},
+		{"DT", "20240315083015.5+0100", true},
+		{"DT", "20241315", false},
+		{"LO", "anything", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateDateTime(tt.vr, tt.value)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateDateTime(%s, %q) returned error: %v", tt.vr, tt.value, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("ValidateDateTime(%s, %q) should return error", tt.vr, tt.value)
+		}
+	}
+}
+
+func TestCombineDATM(t *testing.T) {
+	got, err := CombineDATM("20240315", "235959.5")
+	if err != nil {
+		t.Fatalf("CombineDATM failed: %v", err)
+	}
+	want := time.Date(2024, 3, 15, 23, 59, 59, 500000000, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("CombineDATM = %v, want %v", got, want)
+	}
+
+	if _, err := CombineDATM("20240315", ""); err == nil {
+		t.Error("CombineDATM should fail on an empty time")
+	}
+}
+
+func TestGenerateDateTime(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 100; i++ {
+		da := GenerateDate(rng, 2020, 2024)
+		parsed, err := ParseDA(da)
+		if err != nil {
+			t.Fatalf("GenerateDate produced invalid DA: %v", err)
+		}
+		if parsed.Year() < 2020 || parsed.Year() > 2024 {
+			t.Errorf("GenerateDate year %d out of range 2020-2024", parsed.Year())
+		}
+		if _, err := ParseTM(GenerateTime(rng, i%2 == 0)); err != nil {
+			t.Fatalf("GenerateTime produced invalid TM: %v", err)
+		}
+	}
+}
+
+func TestTimeline_Ordering(t *testing.T) {
+	// Starting just before midnight, later series roll over to the next day
+	start := time.Date(2024, 12, 31, 23, 55, 0, 0, time.UTC)
+	tl := NewTimeline(start)
+
+	prev := start
+	for series := 0; series < 3; series++ {
+		seriesStart := tl.Series(series)
+		if seriesStart.Before(prev) {
+			t.Errorf("series %d starts at %v, before %v", series, seriesStart, prev)
+		}
+		for instance := 0; instance < 5; instance++ {
+			if tl.Acquisition(series, instance).Before(seriesStart) {
+				t.Errorf("series %d instance %d acquired before the series start", series, instance)
+			}
+		}
+		prev = seriesStart
+	}
+
+	if got := FormatDA(tl.Series(2)); got != "20250101" {
+		t.Errorf("series 3 date = %s, want 20250101", got)
+	}
+}
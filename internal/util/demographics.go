@@ -0,0 +1,223 @@
+package util
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AgeBand is a range of ages (in years, inclusive) with its relative weight
+// in an age pyramid.
+type AgeBand struct {
+	MinAge int
+	MaxAge int
+	Weight float64
+}
+
+// LocaleWeight is a name locale with its relative weight.
+type LocaleWeight struct {
+	Locale string
+	Weight float64
+}
+
+// Demographics controls the distributions patients are drawn from, so that
+// large cohorts statistically resemble a hospital population.
+type Demographics struct {
+	MaleRatio     float64        // Fraction of male patients (0-1)
+	AgePyramid    []AgeBand      // Age distribution at ReferenceDate
+	NameLocales   []LocaleWeight // Name locale distribution
+	ReferenceDate time.Time      // Date at which ages follow the pyramid
+}
+
+// Age pyramid presets
+var agePyramidPresets = map[string][]AgeBand{
+	// Imaging workload of a general hospital: few children, mostly 40+
+	"hospital": {
+		{MinAge: 0, MaxAge: 17, Weight: 8},
+		{MinAge: 18, MaxAge: 39, Weight: 17},
+		{MinAge: 40, MaxAge: 64, Weight: 35},
+		{MinAge: 65, MaxAge: 79, Weight: 27},
+		{MinAge: 80, MaxAge: 99, Weight: 13},
+	},
+	"pediatric": {
+		{MinAge: 0, MaxAge: 1, Weight: 20},
+		{MinAge: 2, MaxAge: 11, Weight: 45},
+		{MinAge: 12, MaxAge: 17, Weight: 35},
+	},
+	"uniform": {
+		{MinAge: 0, MaxAge: 99, Weight: 1},
+	},
+}
+
+// AgePyramidPresets returns the names of the built-in age pyramids.
+func AgePyramidPresets() []string {
+	names := make([]string, 0, len(agePyramidPresets))
+	for name := range agePyramidPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultDemographics returns a hospital population: balanced sexes, the
+// "hospital" age pyramid and 80% English / 20% French names, with ages
+// taken mid-2022 (the middle of generated study dates).
+func DefaultDemographics() Demographics {
+	return Demographics{
+		MaleRatio:   0.5,
+		AgePyramid:  agePyramidPresets["hospital"],
+		NameLocales: []LocaleWeight{{LocaleEnglish, 1 - FrenchNameProbability}, {LocaleFrench, FrenchNameProbability}},
+		// Generated study dates span 2020-2024
+		ReferenceDate: time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// ParseSexRatio parses the fraction of male patients ("0.48") or a
+// percentage ("48%").
+func ParseSexRatio(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	scale := 1.0
+	if strings.HasSuffix(s, "%") {
+		s = strings.TrimSuffix(s, "%")
+		scale = 100
+	}
+	ratio, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sex ratio %q (expected a male fraction like 0.48 or 48%%)", s)
+	}
+	ratio /= scale
+	if ratio < 0 || ratio > 1 {
+		return 0, fmt.Errorf("sex ratio must be between 0 and 1 (or 0%% and 100%%), got %s", s)
+	}
+	return ratio, nil
+}
+
+// ParseAgePyramid parses a preset name ("hospital", "pediatric", "uniform")
+// or comma-separated weighted age bands ("0-17:10,18-64:50,65-99:40").
+func ParseAgePyramid(s string) ([]AgeBand, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if preset, ok := agePyramidPresets[s]; ok {
+		return preset, nil
+	}
+
+	var bands []AgeBand
+	for _, part := range strings.Split(s, ",") {
+		rangeStr, weightStr, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid age band %q (expected MIN-MAX:WEIGHT, or a preset: %s)",
+				part, strings.Join(AgePyramidPresets(), ", "))
+		}
+		minStr, maxStr, ok := strings.Cut(rangeStr, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid age range %q (expected MIN-MAX)", rangeStr)
+		}
+		minAge, err1 := strconv.Atoi(minStr)
+		maxAge, err2 := strconv.Atoi(maxStr)
+		weight, err3 := strconv.ParseFloat(weightStr, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("invalid age band %q (expected MIN-MAX:WEIGHT)", part)
+		}
+		if minAge < 0 || maxAge > 120 || minAge > maxAge {
+			return nil, fmt.Errorf("invalid age range %q (ages must be 0-120, MIN <= MAX)", rangeStr)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight in age band %q", part)
+		}
+		bands = append(bands, AgeBand{MinAge: minAge, MaxAge: maxAge, Weight: weight})
+	}
+	if totalWeight(bands) <= 0 {
+		return nil, fmt.Errorf("age pyramid %q has no positive weight", s)
+	}
+	return bands, nil
+}
+
+// ParseNameLocales parses comma-separated weighted name locales ("en:80,fr:20").
+func ParseNameLocales(s string) ([]LocaleWeight, error) {
+	var locales []LocaleWeight
+	for _, part := range strings.Split(s, ",") {
+		locale, weightStr, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid name locale %q (expected LOCALE:WEIGHT)", part)
+		}
+		locale = strings.ToLower(strings.TrimSpace(locale))
+		if !isNameLocale(locale) {
+			return nil, fmt.Errorf("unknown name locale %q (valid: %s)", locale, strings.Join(NameLocales(), ", "))
+		}
+		weight, err := strconv.ParseFloat(weightStr, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight in name locale %q", part)
+		}
+		locales = append(locales, LocaleWeight{Locale: locale, Weight: weight})
+	}
+
+	var total float64
+	for _, l := range locales {
+		total += l.Weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("name locales %q have no positive weight", s)
+	}
+	return locales, nil
+}
+
+// SampleSex draws a patient sex ("M" or "F").
+func (d Demographics) SampleSex(rng *rand.Rand) string {
+	if rng.Float64() < d.MaleRatio {
+		return "M"
+	}
+	return "F"
+}
+
+// SampleBirthDate draws a birth date (DA) so that ages at the reference date
+// follow the age pyramid.
+func (d Demographics) SampleBirthDate(rng *rand.Rand) string {
+	weights := make([]float64, len(d.AgePyramid))
+	for i, band := range d.AgePyramid {
+		weights[i] = band.Weight
+	}
+	band := d.AgePyramid[pickWeighted(weights, rng)]
+
+	age := band.MinAge + rng.IntN(band.MaxAge-band.MinAge+1)
+	// Uniform within the year of age: born between age+1 years and age years ago
+	latest := d.ReferenceDate.AddDate(-age, 0, 0)
+	earliest := d.ReferenceDate.AddDate(-age-1, 0, 1)
+	days := int(latest.Sub(earliest).Hours() / 24)
+	return FormatDA(earliest.AddDate(0, 0, rng.IntN(days+1)))
+}
+
+// SampleLocale draws a name locale.
+func (d Demographics) SampleLocale(rng *rand.Rand) string {
+	weights := make([]float64, len(d.NameLocales))
+	for i, l := range d.NameLocales {
+		weights[i] = l.Weight
+	}
+	return d.NameLocales[pickWeighted(weights, rng)].Locale
+}
+
+// totalWeight returns the sum of the weights of the age bands.
+func totalWeight(bands []AgeBand) float64 {
+	var total float64
+	for _, b := range bands {
+		total += b.Weight
+	}
+	return total
+}
+
+// pickWeighted returns the index drawn with probability proportional to its weight.
+func pickWeighted(weights []float64, rng *rand.Rand) int {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	r := rng.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return len(weights) - 1
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestParseSexRatio(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"0.48", 0.48},
+		{"48%", 0.48},
+		{"1", 1},
+		{"0%", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseSexRatio(tt.input)
+		if err != nil {
+			t.Errorf("ParseSexRatio(%q) returned error: %v", tt.input, err)
+		} else if got != tt.want {
+			t.Errorf("ParseSexRatio(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"abc", "1.5", "-0.1", "120%"} {
+		if _, err := ParseSexRatio(input); err == nil {
+			t.Errorf("ParseSexRatio(%q) should return error", input)
+		}
+	}
+}
+
+func TestParseAgePyramid(t *testing.T) {
+	bands, err := ParseAgePyramid("Hospital")
+	if err != nil || len(bands) != 5 {
+		t.Errorf("ParseAgePyramid(Hospital) = %v, %v; want the 5-band preset", bands, err)
+	}
+
+	bands, err = ParseAgePyramid("0-17:10, 18-64:50,65-99:40")
+	if err != nil {
+		t.Fatalf("ParseAgePyramid returned error: %v", err)
+	}
+	want := []AgeBand{{0, 17, 10}, {18, 64, 50}, {65, 99, 40}}
+	if len(bands) != len(want) {
+		t.Fatalf("got %d bands, want %d", len(bands), len(want))
+	}
+	for i := range want {
+		if bands[i] != want[i] {
+			t.Errorf("band %d = %+v, want %+v", i, bands[i], want[i])
+		}
+	}
+
+	for _, input := range []string{"elderly", "0-17", "17-0:10", "0-200:1", "0-17:-1", "0-17:0"} {
+		if _, err := ParseAgePyramid(input); err == nil {
+			t.Errorf("ParseAgePyramid(%q) should return error", input)
+		}
+	}
+}
+
+func TestParseNameLocales(t *testing.T) {
+	locales, err := ParseNameLocales("EN:70,fr:30")
+	if err != nil {
+		t.Fatalf("ParseNameLocales returned error: %v", err)
+	}
+	if len(locales) != 2 || locales[0] != (LocaleWeight{"en", 70}) || locales[1] != (LocaleWeight{"fr", 30}) {
+		t.Errorf("ParseNameLocales = %v", locales)
+	}
+	if _, err := ParseNameLocales("ja:40,ko:30,zh:30"); err != nil {
+		t.Errorf("ParseNameLocales with CJK locales returned error: %v", err)
+	}
+
+	for _, input := range []string{"de:10", "en", "en:x", "en:0"} {
+		if _, err := ParseNameLocales(input); err == nil {
+			t.Errorf("ParseNameLocales(%q) should return error", input)
+		}
+	}
+}
+
+func TestDemographics_LargeCohort(t *testing.T) {
+	d := DefaultDemographics()
+	d.MaleRatio = 0.45
+	d.NameLocales = []LocaleWeight{{LocaleEnglish, 60}, {LocaleFrench, 40}}
+
+	rng := rand.New(rand.NewPCG(42, 42))
+	patients := make([]CohortPatient, 10000)
+	for i := range patients {
+		patients[i] = CohortPatient{
+			Sex:       d.SampleSex(rng),
+			BirthDate: d.SampleBirthDate(rng),
+			Locale:    d.SampleLocale(rng),
+		}
+	}
+
+	summary := SummarizeCohort(d, patients)
+	if summary.Patients != 10000 {
+		t.Errorf("Patients = %d, want 10000", summary.Patients)
+	}
+	// 10,000 draws stay within 2 points of every target
+	if dev := summary.MaxDeviation(); dev > 0.02 {
+		t.Errorf("max deviation %.3f exceeds 0.02:\n%s", dev, summary)
+	}
+}
+
+func TestDemographics_SampleBirthDateAge(t *testing.T) {
+	d := DefaultDemographics()
+	d.AgePyramid = []AgeBand{{MinAge: 40, MaxAge: 40, Weight: 1}}
+
+	rng := rand.New(rand.NewPCG(1, 1))
+	for i := 0; i < 200; i++ {
+		birthDate := d.SampleBirthDate(rng)
+		age, err := PatientAge(birthDate, FormatDA(d.ReferenceDate))
+		if err != nil {
+			t.Fatalf("PatientAge(%s) failed: %v", birthDate, err)
+		}
+		if age != "040Y" {
+			t.Fatalf("birth date %s gives age %s at the reference date, want 040Y", birthDate, age)
+		}
+	}
+}
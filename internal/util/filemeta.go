@@ -0,0 +1,65 @@
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// FileMeta holds the UIDs of the File Meta Information of a DICOM file.
+type FileMeta struct {
+	SOPClassUID       string // MediaStorageSOPClassUID (0002,0002)
+	SOPInstanceUID    string // MediaStorageSOPInstanceUID (0002,0003)
+	TransferSyntaxUID string // TransferSyntaxUID (0002,0010)
+}
+
+// ParseFileMeta reads the File Meta Information of a DICOM file, always in
+// Explicit VR Little Endian after the 128-byte preamble and the "DICM"
+// prefix. It returns its UIDs ("" when missing) and the offset of the data
+// set that follows it.
+func ParseFileMeta(data []byte) (FileMeta, int, error) {
+	var meta FileMeta
+	if len(data) < 132 || string(data[128:132]) != "DICM" {
+		return meta, 0, fmt.Errorf("not a DICOM file (missing DICM prefix)")
+	}
+
+	pos := 132
+	for pos+8 <= len(data) && binary.LittleEndian.Uint16(data[pos:]) == 0x0002 {
+		element := binary.LittleEndian.Uint16(data[pos+2:])
+		vr := string(data[pos+4 : pos+6])
+		var length, header int
+		if HasLongLength(vr) {
+			if pos+12 > len(data) {
+				return meta, 0, fmt.Errorf("truncated File Meta Information")
+			}
+			length = int(binary.LittleEndian.Uint32(data[pos+8:]))
+			header = 12
+		} else {
+			length = int(binary.LittleEndian.Uint16(data[pos+6:]))
+			header = 8
+		}
+		if length < 0 || pos+header+length > len(data) {
+			return meta, 0, fmt.Errorf("truncated File Meta Information")
+		}
+		value := strings.TrimRight(string(data[pos+header:pos+header+length]), "\x00 ")
+		switch element {
+		case 0x0002:
+			meta.SOPClassUID = value
+		case 0x0003:
+			meta.SOPInstanceUID = value
+		case 0x0010:
+			meta.TransferSyntaxUID = value
+		}
+		pos += header + length
+	}
+	return meta, pos, nil
+}
+
+// HasLongLength reports whether an explicit VR has a 32-bit length.
+func HasLongLength(vr string) bool {
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UN", "UR", "UT", "UV":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// metaElement returns a File Meta Information element in Explicit VR Little
+// Endian.
+func metaElement(element uint16, vr, value string) []byte {
+	b := binary.LittleEndian.AppendUint16([]byte{0x02, 0x00}, element)
+	b = append(b, vr...)
+	if HasLongLength(vr) {
+		b = binary.LittleEndian.AppendUint32(append(b, 0, 0), uint32(len(value)))
+	} else {
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	}
+	return append(b, value...)
+}
+
+func TestParseFileMeta(t *testing.T) {
+	data := append(make([]byte, 128), "DICM"...)
+	data = append(data, metaElement(0x0002, "UI", "1.2.840.10008.5.1.4.1.1.4\x00")...)
+	data = append(data, metaElement(0x0003, "UI", "1.2.3.4")...)
+	// Long-length VRs of the later editions of the standard
+	for _, vr := range []string{"UC", "UR", "OD", "OL", "OV"} {
+		data = append(data, metaElement(0x0100, vr, "ABCD")...)
+	}
+	data = append(data, metaElement(0x0010, "UI", "1.2.840.10008.1.2.2\x00")...)
+	end := len(data)
+	data = append(data, 0x08, 0x00, 0x05, 0x00)
+
+	meta, pos, err := ParseFileMeta(data)
+	if err != nil {
+		t.Fatalf("ParseFileMeta failed: %v", err)
+	}
+	want := FileMeta{SOPClassUID: "1.2.840.10008.5.1.4.1.1.4", SOPInstanceUID: "1.2.3.4", TransferSyntaxUID: "1.2.840.10008.1.2.2"}
+	if meta != want || pos != end {
+		t.Errorf("ParseFileMeta() = %+v, %d, want %+v, %d", meta, pos, want, end)
+	}
+
+	if _, _, err := ParseFileMeta(data[:end-4]); err == nil {
+		t.Error("ParseFileMeta should fail on a truncated File Meta Information")
+	}
+	if _, _, err := ParseFileMeta(make([]byte, 200)); err == nil {
+		t.Error("ParseFileMeta should fail without the DICM prefix")
+	}
+}
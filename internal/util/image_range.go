@@ -0,0 +1,95 @@
+// internal/util/image_range.go
+package util
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// ImageRange represents a range of images per series (min-max)
+type ImageRange struct {
+	Min int
+	Max int
+}
+
+// ParseImageRange parses an images-per-series range string like "4" or "20-40"
+func ParseImageRange(s string) (ImageRange, error) {
+	min, max, err := parseCountRange(s, "images per series")
+	if err != nil {
+		return ImageRange{}, err
+	}
+	return ImageRange{Min: min, Max: max}, nil
+}
+
+// parseCountRange parses "N" or "N-M" with 1 <= N <= M; noun names the
+// counted items in error messages.
+func parseCountRange(s, noun string) (min, max int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, fmt.Errorf("empty %s range (expected N or N-M)", noun)
+	}
+
+	if strings.Contains(s, "-") {
+		parts := strings.SplitN(s, "-", 2)
+
+		min, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s range min: %s", noun, parts[0])
+		}
+
+		max, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s range max: %s", noun, parts[1])
+		}
+
+		if min < 1 {
+			return 0, 0, fmt.Errorf("%s range min must be >= 1, got %d", noun, min)
+		}
+
+		if max < min {
+			return 0, 0, fmt.Errorf("%s range max (%d) must be >= min (%d)", noun, max, min)
+		}
+
+		return min, max, nil
+	}
+
+	// Single number
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid %s count: %s", noun, s)
+	}
+
+	if n < 1 {
+		return 0, 0, fmt.Errorf("%s count must be >= 1, got %d", noun, n)
+	}
+
+	return n, n, nil
+}
+
+// IsSet returns true if the range was set (the zero value is unset)
+func (r ImageRange) IsSet() bool {
+	return r.Max > 0
+}
+
+// Count returns a random image count within the range
+func (r ImageRange) Count(rng *rand.Rand) int {
+	if r.Min == r.Max {
+		return r.Min
+	}
+	return r.Min + rng.IntN(r.Max-r.Min+1)
+}
+
+// Mean returns the average image count of the range
+func (r ImageRange) Mean() float64 {
+	return float64(r.Min+r.Max) / 2
+}
+
+// String returns the string representation of the range
+func (r ImageRange) String() string {
+	if r.Min == r.Max {
+		return strconv.Itoa(r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
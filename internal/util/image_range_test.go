@@ -0,0 +1,90 @@
+// internal/util/image_range_test.go
+package util
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestParseImageRange(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantMin int
+		wantMax int
+	}{
+		{"4", 4, 4},
+		{"20-40", 20, 40},
+		{" 1 - 30 ", 1, 30},
+		{"100-100", 100, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseImageRange(tt.input)
+			if err != nil {
+				t.Fatalf("ParseImageRange(%q) failed: %v", tt.input, err)
+			}
+			if r.Min != tt.wantMin || r.Max != tt.wantMax {
+				t.Errorf("ParseImageRange(%q) = {%d, %d}, want {%d, %d}", tt.input, r.Min, r.Max, tt.wantMin, tt.wantMax)
+			}
+			if !r.IsSet() {
+				t.Errorf("ParseImageRange(%q) should be set", tt.input)
+			}
+		})
+	}
+}
+
+func TestParseImageRange_Invalid(t *testing.T) {
+	for _, input := range []string{"", "0", "-5", "abc", "40-20", "0-10", "a-10", "10-b"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseImageRange(input); err == nil {
+				t.Errorf("ParseImageRange(%q) should fail", input)
+			}
+		})
+	}
+
+	if (ImageRange{}).IsSet() {
+		t.Error("zero ImageRange should not be set")
+	}
+}
+
+func TestImageRange_Count(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	r := ImageRange{Min: 20, Max: 40}
+	seen := make(map[int]bool)
+	for i := 0; i < 500; i++ {
+		n := r.Count(rng)
+		if n < r.Min || n > r.Max {
+			t.Fatalf("Count() = %d, want %d-%d", n, r.Min, r.Max)
+		}
+		seen[n] = true
+	}
+	if !seen[r.Min] || !seen[r.Max] {
+		t.Errorf("Count() never reached the bounds of %s", r)
+	}
+
+	fixed := ImageRange{Min: 4, Max: 4}
+	if n := fixed.Count(rng); n != 4 {
+		t.Errorf("fixed Count() = %d, want 4", n)
+	}
+}
+
+func TestImageRange_MeanAndString(t *testing.T) {
+	tests := []struct {
+		r        ImageRange
+		wantMean float64
+		wantStr  string
+	}{
+		{ImageRange{Min: 4, Max: 4}, 4, "4"},
+		{ImageRange{Min: 1, Max: 2}, 1.5, "1-2"},
+		{ImageRange{Min: 100, Max: 600}, 350, "100-600"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Mean(); got != tt.wantMean {
+			t.Errorf("%v.Mean() = %v, want %v", tt.r, got, tt.wantMean)
+		}
+		if got := tt.r.String(); got != tt.wantStr {
+			t.Errorf("String() = %q, want %q", got, tt.wantStr)
+		}
+	}
+}
@@ -97,13 +97,13 @@ var (
 		"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit",
 		"Durand", "Leroy", "Moreau", "Simon", "Laurent", "Lefebvre", "Michel",
 		"Garcia", "David", "Bertrand", "Roux", "Vincent", "Fournier", "Morel",
-		"Girard", "André", "Lefevre", "Mercier", "Dupont", "Lambert", "Bonnet",
+		"Girard", "André", "Lefèvre", "Mercier", "Dupont", "Lambert", "Bonnet",
 		"François", "Martinez", "Legrand", "Garnier", "Faure", "Rousseau", "Blanc",
-		"Guerin", "Muller", "Henry", "Roussel", "Nicolas", "Perrin", "Morin",
-		"Mathieu", "Clement", "Gauthier", "Dumont", "Lopez", "Fontaine", "Chevalier",
-		"Robin", "Masson", "Sanchez", "Gerard", "Nguyen", "Boyer", "Denis", "Lemaire",
+		"Guérin", "Muller", "Henry", "Roussel", "Nicolas", "Perrin", "Morin",
+		"Mathieu", "Clément", "Gauthier", "Dumont", "Lopez", "Fontaine", "Chevalier",
+		"Robin", "Masson", "Sanchez", "Gérard", "Nguyen", "Boyer", "Denis", "Lemaire",
 		"Dufour", "Renaud", "Barbier", "Arnaud", "Marchand", "Picard", "Leclerc", "Giraud",
-		"Brun", "Gaillard", "Renard", "Roy", "Noel", "Meyer", "Hubert", "Gautier",
+		"Brun", "Gaillard", "Renard", "Roy", "Noël", "Meyer", "Hubert", "Gautier",
 	}
 
 	// MaleFirstNames combines English and French names for backward compatibility
@@ -116,6 +116,38 @@ var (
 	LastNames = append(EnglishLastNames, FrenchLastNames...)
 )
 
+// Name locales
+const (
+	LocaleEnglish  = "en"
+	LocaleFrench   = "fr"
+	LocaleJapanese = "ja"
+	LocaleKorean   = "ko"
+	LocaleChinese  = "zh"
+)
+
+// nameList holds the name pools of a locale.
+type nameList struct {
+	male, female, last []string
+}
+
+// nameLists maps each supported locale to its name pools.
+var nameLists = map[string]nameList{
+	LocaleEnglish: {EnglishMaleFirstNames, EnglishFemaleFirstNames, EnglishLastNames},
+	LocaleFrench:  {FrenchMaleFirstNames, FrenchFemaleFirstNames, FrenchLastNames},
+}
+
+// NameLocales returns the supported name locales.
+func NameLocales() []string {
+	return []string{LocaleEnglish, LocaleFrench, LocaleJapanese, LocaleKorean, LocaleChinese}
+}
+
+// isNameLocale reports whether locale is a supported name locale.
+func isNameLocale(locale string) bool {
+	_, ok := nameLists[locale]
+	_, ideographic := ideographicNameLists[locale]
+	return ok || ideographic
+}
+
 // GeneratePatientName generates a realistic patient name based on sex.
 // Names are 80% English and 20% French.
 //
@@ -128,26 +160,47 @@ func GeneratePatientName(sex string, rng *rand.Rand) string {
 	}
 
 	// 20% chance of French name
-	useFrench := rng.Float64() < FrenchNameProbability
+	locale := LocaleEnglish
+	if rng.Float64() < FrenchNameProbability {
+		locale = LocaleFrench
+	}
 
-	var firstName string
-	var lastName string
+	return GeneratePatientNameLocale(sex, locale, rng)
+}
 
-	if useFrench {
-		if sex == "M" {
-			firstName = FrenchMaleFirstNames[rng.IntN(len(FrenchMaleFirstNames))]
-		} else {
-			firstName = FrenchFemaleFirstNames[rng.IntN(len(FrenchFemaleFirstNames))]
-		}
-		lastName = FrenchLastNames[rng.IntN(len(FrenchLastNames))]
-	} else {
+// GeneratePatientNameLocale generates a patient name of the given locale
+// ("en", "fr", "ja", "ko" or "zh"; unknown locales use English).
+//
+// Sex should be "M" or "F". Invalid values default to "F".
+// If rng is nil, uses shared default RNG.
+// Returns name in DICOM format: "LASTNAME^FIRSTNAME", with ideographic and
+// phonetic component groups for Japanese and Korean names
+// ("Yamada^Taro=山田^太郎=やまだ^たろう"), ideographic for Chinese ones.
+func GeneratePatientNameLocale(sex, locale string, rng *rand.Rand) string {
+	if rng == nil {
+		rng = defaultRNG
+	}
+
+	if names, ok := ideographicNameLists[locale]; ok {
+		givenNames := names.female
 		if sex == "M" {
-			firstName = EnglishMaleFirstNames[rng.IntN(len(EnglishMaleFirstNames))]
-		} else {
-			firstName = EnglishFemaleFirstNames[rng.IntN(len(EnglishFemaleFirstNames))]
+			givenNames = names.male
 		}
-		lastName = EnglishLastNames[rng.IntN(len(EnglishLastNames))]
+		given := givenNames[rng.IntN(len(givenNames))]
+		return personName(names.last[rng.IntN(len(names.last))], given)
+	}
+
+	names, ok := nameLists[locale]
+	if !ok {
+		names = nameLists[LocaleEnglish]
+	}
+
+	firstNames := names.female
+	if sex == "M" {
+		firstNames = names.male
 	}
+	firstName := firstNames[rng.IntN(len(firstNames))]
+	lastName := names.last[rng.IntN(len(names.last))]
 
 	// DICOM format: LASTNAME^FIRSTNAME
 	return lastName + "^" + firstName
@@ -0,0 +1,96 @@
+package util
+
+import "strings"
+
+// pnName is a name, or a name component, in the component groups of a DICOM
+// PN: alphabetic (romanized), ideographic (kanji, hanja, hanzi) and phonetic
+// (hiragana, hangul), empty for the groups its locale does not use.
+type pnName struct {
+	alphabetic, ideographic, phonetic string
+}
+
+// ideographicNameList holds the name pools of a locale written in ideographs.
+type ideographicNameList struct {
+	male, female, last []pnName
+}
+
+// ideographicNameLists maps the locales written in ideographs to their name
+// pools: Japanese and Korean names have the three component groups, Chinese
+// names no phonetic one.
+var ideographicNameLists = map[string]ideographicNameList{
+	LocaleJapanese: {
+		male: []pnName{
+			{"Hiroshi", "浩", "ひろし"}, {"Takeshi", "武", "たけし"}, {"Taro", "太郎", "たろう"},
+			{"Kenji", "健二", "けんじ"}, {"Makoto", "誠", "まこと"}, {"Daisuke", "大輔", "だいすけ"},
+			{"Shota", "翔太", "しょうた"}, {"Haruto", "陽翔", "はると"}, {"Ren", "蓮", "れん"},
+			{"Kazuya", "和也", "かずや"}, {"Naoki", "直樹", "なおき"}, {"Sota", "蒼太", "そうた"},
+		},
+		female: []pnName{
+			{"Yoko", "洋子", "ようこ"}, {"Keiko", "恵子", "けいこ"}, {"Yumi", "由美", "ゆみ"},
+			{"Akiko", "明子", "あきこ"}, {"Tomoko", "智子", "ともこ"}, {"Sakura", "桜", "さくら"},
+			{"Yui", "結衣", "ゆい"}, {"Aoi", "葵", "あおい"}, {"Misaki", "美咲", "みさき"},
+			{"Haruka", "遥", "はるか"}, {"Mai", "舞", "まい"}, {"Emi", "恵美", "えみ"},
+		},
+		last: []pnName{
+			{"Sato", "佐藤", "さとう"}, {"Suzuki", "鈴木", "すずき"}, {"Takahashi", "高橋", "たかはし"},
+			{"Tanaka", "田中", "たなか"}, {"Watanabe", "渡辺", "わたなべ"}, {"Ito", "伊藤", "いとう"},
+			{"Yamamoto", "山本", "やまもと"}, {"Nakamura", "中村", "なかむら"}, {"Kobayashi", "小林", "こばやし"},
+			{"Kato", "加藤", "かとう"}, {"Yoshida", "吉田", "よしだ"}, {"Yamada", "山田", "やまだ"},
+			{"Sasaki", "佐々木", "ささき"}, {"Yamaguchi", "山口", "やまぐち"}, {"Matsumoto", "松本", "まつもと"},
+			{"Inoue", "井上", "いのうえ"}, {"Kimura", "木村", "きむら"}, {"Hayashi", "林", "はやし"},
+			{"Shimizu", "清水", "しみず"}, {"Saito", "斎藤", "さいとう"},
+		},
+	},
+	LocaleKorean: {
+		male: []pnName{
+			{"Minjun", "敏俊", "민준"}, {"Jihoon", "志勳", "지훈"}, {"Hyunwoo", "賢宇", "현우"},
+			{"Dongwook", "東旭", "동욱"}, {"Sungmin", "成珉", "성민"}, {"Jaehyun", "在賢", "재현"},
+			{"Youngho", "永浩", "영호"}, {"Seojun", "瑞俊", "서준"}, {"Gildong", "吉洞", "길동"},
+		},
+		female: []pnName{
+			{"Jiyeon", "智妍", "지연"}, {"Minji", "敏智", "민지"}, {"Soyeon", "昭妍", "소연"},
+			{"Eunji", "恩智", "은지"}, {"Hyejin", "惠珍", "혜진"}, {"Yuna", "裕娜", "유나"},
+			{"Seoyeon", "瑞妍", "서연"}, {"Jiwoo", "智友", "지우"}, {"Sunhee", "善姬", "선희"},
+		},
+		last: []pnName{
+			{"Kim", "金", "김"}, {"Lee", "李", "이"}, {"Park", "朴", "박"}, {"Choi", "崔", "최"},
+			{"Jung", "鄭", "정"}, {"Kang", "姜", "강"}, {"Cho", "趙", "조"}, {"Yoon", "尹", "윤"},
+			{"Jang", "張", "장"}, {"Lim", "林", "임"}, {"Han", "韓", "한"}, {"Oh", "吳", "오"},
+			{"Seo", "徐", "서"}, {"Shin", "申", "신"}, {"Kwon", "權", "권"}, {"Hwang", "黃", "황"},
+			{"Hong", "洪", "홍"},
+		},
+	},
+	LocaleChinese: {
+		male: []pnName{
+			{"Wei", "伟", ""}, {"Qiang", "强", ""}, {"Lei", "磊", ""}, {"Jun", "军", ""},
+			{"Yong", "勇", ""}, {"Jie", "杰", ""}, {"Tao", "涛", ""}, {"Ming", "明", ""},
+			{"Xiaodong", "晓东", ""}, {"Haoran", "浩然", ""}, {"Zihao", "子豪", ""}, {"Yuxuan", "宇轩", ""},
+		},
+		female: []pnName{
+			{"Fang", "芳", ""}, {"Na", "娜", ""}, {"Min", "敏", ""}, {"Jing", "静", ""},
+			{"Li", "丽", ""}, {"Yan", "燕", ""}, {"Xiuying", "秀英", ""}, {"Xiaoyan", "晓燕", ""},
+			{"Yutong", "雨桐", ""}, {"Zihan", "梓涵", ""}, {"Xinyi", "欣怡", ""}, {"Shiyu", "诗雨", ""},
+		},
+		last: []pnName{
+			{"Wang", "王", ""}, {"Li", "李", ""}, {"Zhang", "张", ""}, {"Liu", "刘", ""},
+			{"Chen", "陈", ""}, {"Yang", "杨", ""}, {"Huang", "黄", ""}, {"Zhao", "赵", ""},
+			{"Wu", "吴", ""}, {"Zhou", "周", ""}, {"Xu", "徐", ""}, {"Sun", "孙", ""},
+			{"Ma", "马", ""}, {"Zhu", "朱", ""}, {"Hu", "胡", ""}, {"Guo", "郭", ""},
+		},
+	},
+}
+
+// personName returns the PN of a family and given name: the family^given
+// name of each component group, separated by "=", without trailing empty
+// groups ("Yamada^Taro=山田^太郎=やまだ^たろう").
+func personName(family, given pnName) string {
+	groups := []string{
+		family.alphabetic + "^" + given.alphabetic,
+		family.ideographic + "^" + given.ideographic,
+		family.phonetic + "^" + given.phonetic,
+	}
+	for len(groups) > 1 && groups[len(groups)-1] == "^" {
+		groups = groups[:len(groups)-1]
+	}
+	return strings.Join(groups, "=")
+}
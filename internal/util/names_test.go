@@ -4,6 +4,7 @@ import (
 	"math/rand/v2"
 	"strings"
 	"testing"
+	"unicode"
 )
 
 func TestGeneratePatientName_Format(t *testing.T) {
@@ -85,6 +86,29 @@ func TestGeneratePatientName_Sex(t *testing.T) {
 	}
 }
 
+func TestGeneratePatientNameLocale(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	contains := func(list []string, name string) bool {
+		for _, n := range list {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := 0; i < 20; i++ {
+		parts := strings.Split(GeneratePatientNameLocale("M", LocaleFrench, rng), "^")
+		if !contains(FrenchLastNames, parts[0]) || !contains(FrenchMaleFirstNames, parts[1]) {
+			t.Errorf("French male name %s^%s not from the French lists", parts[0], parts[1])
+		}
+		parts = strings.Split(GeneratePatientNameLocale("F", "xx", rng), "^")
+		if !contains(EnglishLastNames, parts[0]) || !contains(EnglishFemaleFirstNames, parts[1]) {
+			t.Errorf("unknown locale should fall back to English, got %s^%s", parts[0], parts[1])
+		}
+	}
+}
+
 func TestGeneratePhysicianName_Format(t *testing.T) {
 	name := GeneratePhysicianName(nil)
 
@@ -124,3 +148,45 @@ func TestGeneratePhysicianName_Deterministic(t *testing.T) {
 		t.Errorf("Same seed should produce same name: %s != %s", name1, name2)
 	}
 }
+
+func TestGeneratePatientNameLocale_Ideographic(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for _, tt := range []struct {
+		locale string
+		groups int
+	}{
+		{LocaleJapanese, 3},
+		{LocaleKorean, 3},
+		{LocaleChinese, 2},
+	} {
+		for i := 0; i < 20; i++ {
+			name := GeneratePatientNameLocale("M", tt.locale, rng)
+			groups := strings.Split(name, "=")
+			if len(groups) != tt.groups {
+				t.Fatalf("%s name %q has %d component groups, want %d", tt.locale, name, len(groups), tt.groups)
+			}
+			for g, group := range groups {
+				family, given, ok := strings.Cut(group, "^")
+				if !ok || family == "" || given == "" {
+					t.Errorf("%s name %q: group %d %q is not FAMILY^GIVEN", tt.locale, name, g, group)
+				}
+			}
+			for _, r := range groups[0] {
+				if r > unicode.MaxASCII {
+					t.Errorf("%s name %q: alphabetic group is not romanized", tt.locale, name)
+					break
+				}
+			}
+			if !strings.ContainsFunc(groups[1], func(r rune) bool { return unicode.Is(unicode.Han, r) }) {
+				t.Errorf("%s name %q: ideographic group has no ideographs", tt.locale, name)
+			}
+		}
+	}
+
+	if got := personName(pnName{"Yamada", "山田", "やまだ"}, pnName{"Taro", "太郎", "たろう"}); got != "Yamada^Taro=山田^太郎=やまだ^たろう" {
+		t.Errorf("personName = %q", got)
+	}
+	if got := personName(pnName{"Wang", "王", ""}, pnName{"Xiaodong", "晓东", ""}); got != "Wang^Xiaodong=王^晓东" {
+		t.Errorf("personName = %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DICOM numeric string limits
+const (
+	MaxDSLength = 16 // Decimal String (DS)
+	MaxISLength = 12 // Integer String (IS)
+)
+
+// FormatDS formats a float64 as a DICOM Decimal String (DS).
+//
+// The value is written in its shortest exact form without trailing zeros
+// ("2.5", not "2.500000"). Values that do not fit in 16 bytes are rounded to
+// as many decimals as fit, and switch to exponent notation when even the
+// integer part is too long. NaN and infinities, which DS cannot represent,
+// are written as "0".
+func FormatDS(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "0"
+	}
+
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if len(s) <= MaxDSLength {
+		return normalizeZero(s)
+	}
+
+	// Round to the decimals that fit after the integer part and the point
+	if intLen := strings.IndexByte(s, '.'); intLen >= 0 && intLen < MaxDSLength-1 {
+		s = strconv.FormatFloat(f, 'f', MaxDSLength-1-intLen, 64)
+		// Rounding may carry into the integer part (e.g., 9.99... -> 10.0...)
+		if len(s) > MaxDSLength {
+			s = strconv.FormatFloat(f, 'f', MaxDSLength-2-intLen, 64)
+		}
+		return normalizeZero(trimZeros(s))
+	}
+
+	// Integer part too long: exponent notation with the mantissa that fits
+	for prec := MaxDSLength; prec >= 0; prec-- {
+		s = strconv.FormatFloat(f, 'E', prec, 64)
+		if len(s) <= MaxDSLength {
+			break
+		}
+	}
+	return s
+}
+
+// FormatIS formats an int as a DICOM Integer String (IS). Values are clamped
+// to the signed 32-bit range allowed by IS.
+func FormatIS(i int) string {
+	if i > math.MaxInt32 {
+		i = math.MaxInt32
+	} else if i < math.MinInt32 {
+		i = math.MinInt32
+	}
+	return strconv.Itoa(i)
+}
+
+// trimZeros removes trailing zeros of the fractional part, and the decimal
+// point if nothing remains after it.
+func trimZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// normalizeZero rewrites negative zero ("-0", "-0.00") as "0".
+func normalizeZero(s string) string {
+	if strings.HasPrefix(s, "-") && strings.Trim(s, "-0.") == "" {
+		return "0"
+	}
+	return s
+}
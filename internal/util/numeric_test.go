@@ -0,0 +1,83 @@
+package util
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestFormatDS(t *testing.T) {
+	tests := []struct {
+		input float64
+		want  string
+	}{
+		{0, "0"},
+		{math.Copysign(0, -1), "0"},
+		{2.5, "2.5"},
+		{-2.5, "-2.5"},
+		{0.78125, "0.78125"},
+		{120, "120"},
+		{1e-17, "0"},
+		{-1e-17, "0"},
+		{0.1 + 0.2, "0.3"},
+		{-123.456789012345678, "-123.45678901235"},
+		{9.9999999999999999, "10"},
+		{99999.999999999999, "100000"},
+		{1234567890123456, "1234567890123456"},
+		{12345678901234567, "1.2345678901E+16"},
+		{-12345678901234567, "-1.234567890E+16"},
+		{math.NaN(), "0"},
+		{math.Inf(1), "0"},
+	}
+
+	for _, tt := range tests {
+		got := FormatDS(tt.input)
+		if got != tt.want {
+			t.Errorf("FormatDS(%v) = %q, want %q", tt.input, got, tt.want)
+		}
+		if len(got) > MaxDSLength {
+			t.Errorf("FormatDS(%v) = %q exceeds %d bytes", tt.input, got, MaxDSLength)
+		}
+	}
+}
+
+func TestFormatDS_RoundTrip(t *testing.T) {
+	for _, v := range []float64{0.5, -0.707107, 1.5, 3000, 0.000123, -254.123456, 1e15, 7.3e-9} {
+		s := FormatDS(v)
+		if len(s) > MaxDSLength {
+			t.Errorf("FormatDS(%v) = %q exceeds %d bytes", v, s, MaxDSLength)
+		}
+		parsed, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			t.Fatalf("FormatDS(%v) = %q is not parseable: %v", v, s, err)
+		}
+		if math.Abs(parsed-v) > math.Abs(v)*1e-9 {
+			t.Errorf("FormatDS(%v) = %q loses precision", v, s)
+		}
+	}
+}
+
+func TestFormatIS(t *testing.T) {
+	tests := []struct {
+		input int
+		want  string
+	}{
+		{0, "0"},
+		{42, "42"},
+		{-7, "-7"},
+		{math.MaxInt32, "2147483647"},
+		{math.MinInt32, "-2147483648"},
+		{math.MaxInt32 + 1, "2147483647"},
+		{math.MinInt32 - 1, "-2147483648"},
+	}
+
+	for _, tt := range tests {
+		got := FormatIS(tt.input)
+		if got != tt.want {
+			t.Errorf("FormatIS(%d) = %q, want %q", tt.input, got, tt.want)
+		}
+		if len(got) > MaxISLength {
+			t.Errorf("FormatIS(%d) = %q exceeds %d bytes", tt.input, got, MaxISLength)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package util
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// PrivateTag is a private data element: element Element of the block that
+// Creator reserves in the odd group Group, as (Group,xxElement) where xx is
+// the block.
+type PrivateTag struct {
+	Creator string // Private creator, e.g. "ACME_IMAGING 1.0"
+	Group   uint16 // Odd group, e.g. 0x0029
+	Element uint8  // Element within the block of the creator
+	VR      string // Value representation, e.g. "LO"
+	Value   string // Values separated by '\', hex bytes for OB and UN
+}
+
+// Value representations of private tags, by kind of value
+var (
+	privateStringVRs = map[string]bool{
+		"AE": true, "AS": true, "CS": true, "DA": true, "DS": true, "DT": true, "IS": true, "LO": true,
+		"LT": true, "PN": true, "SH": true, "ST": true, "TM": true, "UC": true, "UI": true, "UR": true, "UT": true,
+	}
+	privateIntBits   = map[string]int{"US": 16, "SS": 16, "UL": 32, "SL": 32}
+	privateFloatBits = map[string]int{"FL": 32, "FD": 64}
+	privateBytesVRs  = map[string]bool{"OB": true, "UN": true}
+	// Single-valued text, whose backslashes are characters
+	privateSingleValueVRs = map[string]bool{"LT": true, "ST": true, "UR": true, "UT": true}
+)
+
+// ParsePrivateTag parses a private tag in the format
+// "CREATOR,GGGG,EE,VR=VALUE": the private creator (which may hold commas),
+// the group and the element within the block of the creator in hex, the VR
+// and the value, e.g. "ACME_IMAGING 1.0,0029,10,LO=Fast protocol".
+func ParsePrivateTag(s string) (PrivateTag, error) {
+	spec, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return PrivateTag{}, fmt.Errorf("invalid private tag %q: missing '=' (expected CREATOR,GGGG,EE,VR=VALUE)", s)
+	}
+	fields := strings.Split(spec, ",")
+	if len(fields) < 4 {
+		return PrivateTag{}, fmt.Errorf("invalid private tag %q (expected CREATOR,GGGG,EE,VR=VALUE)", s)
+	}
+	n := len(fields)
+	group, err := strconv.ParseUint(strings.TrimSpace(fields[n-3]), 16, 16)
+	if err != nil {
+		return PrivateTag{}, fmt.Errorf("invalid group %q in private tag %q", fields[n-3], s)
+	}
+	element, err := strconv.ParseUint(strings.TrimSpace(fields[n-2]), 16, 8)
+	if err != nil {
+		return PrivateTag{}, fmt.Errorf("invalid element %q in private tag %q (expected 00-FF)", fields[n-2], s)
+	}
+	p := PrivateTag{
+		Creator: strings.TrimSpace(strings.Join(fields[:n-3], ",")),
+		Group:   uint16(group),
+		Element: uint8(element),
+		VR:      strings.ToUpper(strings.TrimSpace(fields[n-1])),
+		Value:   value,
+	}
+	if err := p.Validate(); err != nil {
+		return PrivateTag{}, fmt.Errorf("invalid private tag %q: %w", s, err)
+	}
+	return p, nil
+}
+
+// ParsePrivateTagFlags parses private tags in the format of ParsePrivateTag.
+func ParsePrivateTagFlags(flags []string) ([]PrivateTag, error) {
+	var tags []PrivateTag
+	for _, flag := range flags {
+		p, err := ParsePrivateTag(flag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, p)
+	}
+	return tags, nil
+}
+
+// Validate checks that p can be written: a private group (odd, above
+// 0x0008, not 0xFFFF), a creator that fits in its LO and a value of its VR.
+func (p PrivateTag) Validate() error {
+	if p.Group%2 == 0 || p.Group <= 0x0008 || p.Group == 0xFFFF {
+		return fmt.Errorf("group %04X is not a private group", p.Group)
+	}
+	if p.Creator == "" || len(p.Creator) > 64 || strings.Contains(p.Creator, `\`) {
+		return fmt.Errorf("private creator %q must be 1-64 characters without backslash", p.Creator)
+	}
+	_, err := p.data()
+	return err
+}
+
+// String returns p in the format of ParsePrivateTag.
+func (p PrivateTag) String() string {
+	return fmt.Sprintf("%s,%04X,%02X,%s=%s", p.Creator, p.Group, p.Element, p.VR, p.Value)
+}
+
+// data returns the value of p as dicom.NewValue takes it.
+func (p PrivateTag) data() (any, error) {
+	values := strings.Split(p.Value, `\`)
+	switch {
+	case privateSingleValueVRs[p.VR]:
+		return []string{p.Value}, nil
+	case privateStringVRs[p.VR]:
+		return values, nil
+	case privateIntBits[p.VR] > 0:
+		ints := make([]int, len(values))
+		for i, v := range values {
+			var err error
+			var n int64
+			if p.VR[0] == 'U' {
+				var u uint64
+				u, err = strconv.ParseUint(strings.TrimSpace(v), 10, privateIntBits[p.VR])
+				n = int64(u)
+			} else {
+				n, err = strconv.ParseInt(strings.TrimSpace(v), 10, privateIntBits[p.VR])
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q", p.VR, v)
+			}
+			ints[i] = int(n)
+		}
+		return ints, nil
+	case privateFloatBits[p.VR] > 0:
+		floats := make([]float64, len(values))
+		for i, v := range values {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), privateFloatBits[p.VR])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q", p.VR, v)
+			}
+			floats[i] = f
+		}
+		return floats, nil
+	case privateBytesVRs[p.VR]:
+		b, err := hex.DecodeString(strings.TrimSpace(p.Value))
+		if err != nil || len(b)%2 != 0 {
+			return nil, fmt.Errorf("invalid %s value %q (expected an even number of hex bytes)", p.VR, p.Value)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unsupported VR %q", p.VR)
+}
+
+// InsertPrivateTags returns elements with the private tags, and the private
+// creators reserving their blocks, sorted by tag. A creator already in a
+// group keeps its block; others take the first free one of the group. A
+// private tag replaces an element with its tag. The tags must pass Validate.
+func InsertPrivateTags(elements []*dicom.Element, tags []PrivateTag) ([]*dicom.Element, error) {
+	result := make([]*dicom.Element, len(elements), len(elements)+2*len(tags))
+	copy(result, elements)
+	set := func(elem *dicom.Element) {
+		for i, existing := range result {
+			if existing.Tag == elem.Tag {
+				result[i] = elem
+				return
+			}
+		}
+		result = append(result, elem)
+	}
+
+	for _, p := range tags {
+		data, err := p.data()
+		if err != nil {
+			return nil, fmt.Errorf("private tag %s: %w", p, err)
+		}
+
+		// Block of the creator in the group, reserved when missing
+		block, used := uint16(0), make(map[uint16]bool)
+		for _, elem := range result {
+			if elem.Tag.Group != p.Group || elem.Tag.Element < 0x0010 || elem.Tag.Element > 0x00FF {
+				continue
+			}
+			used[elem.Tag.Element] = true
+			if values, ok := elem.Value.GetValue().([]string); ok && len(values) > 0 && strings.TrimSpace(values[0]) == p.Creator {
+				block = elem.Tag.Element
+				break
+			}
+		}
+		if block == 0 {
+			for b := uint16(0x0010); b <= 0x00FF && block == 0; b++ {
+				if !used[b] {
+					block = b
+				}
+			}
+			if block == 0 {
+				return nil, fmt.Errorf("private tag %s: no free block in group %04X", p, p.Group)
+			}
+			creator, err := NewPrivateElement(tag.Tag{Group: p.Group, Element: block}, "LO", []string{p.Creator})
+			if err != nil {
+				return nil, err
+			}
+			set(creator)
+		}
+
+		elem, err := NewPrivateElement(tag.Tag{Group: p.Group, Element: block<<8 | uint16(p.Element)}, p.VR, data)
+		if err != nil {
+			return nil, fmt.Errorf("private tag %s: %w", p, err)
+		}
+		set(elem)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Tag.Group != result[j].Tag.Group {
+			return result[i].Tag.Group < result[j].Tag.Group
+		}
+		return result[i].Tag.Element < result[j].Tag.Element
+	})
+	return result, nil
+}
+
+// NewPrivateElement returns an element of a private tag, with an explicit VR
+// (dicom.NewElement fails on tags missing from the dictionary).
+func NewPrivateElement(t tag.Tag, rawVR string, data any) (*dicom.Element, error) {
+	value, err := dicom.NewValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return &dicom.Element{
+		Tag:                    t,
+		ValueRepresentation:    tag.GetVRKind(t, rawVR),
+		RawValueRepresentation: rawVR,
+		Value:                  value,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParsePrivateTag(t *testing.T) {
+	p, err := ParsePrivateTag("ACME, Inc. 1.0,0029,1a,lo=Fast protocol=2")
+	if err != nil {
+		t.Fatalf("ParsePrivateTag returned error: %v", err)
+	}
+	want := PrivateTag{Creator: "ACME, Inc. 1.0", Group: 0x0029, Element: 0x1A, VR: "LO", Value: "Fast protocol=2"}
+	if p != want {
+		t.Errorf("ParsePrivateTag = %+v, want %+v", p, want)
+	}
+	if got, err := ParsePrivateTag(p.String()); err != nil || got != p {
+		t.Errorf("ParsePrivateTag(%q) = %+v, %v", p.String(), got, err)
+	}
+
+	for _, input := range []string{
+		"ACME,0029,10,LO",       // No value
+		"0029,10,LO=x",          // No creator
+		"ACME,0028,10,LO=x",     // Even group
+		"ACME,0007,10,LO=x",     // Reserved group
+		"ACME,0029,100,LO=x",    // Element beyond the block
+		"ACME,0029,10,SQ=x",     // Unsupported VR
+		"ACME,0029,10,US=70000", // Out of range
+		"ACME,0029,10,FD=abc",
+		"ACME,0029,10,OB=0102z",
+		"ACME,0029,10,OB=010203", // Odd length
+		`AC\ME,0029,10,LO=x`,
+	} {
+		if _, err := ParsePrivateTag(input); err == nil {
+			t.Errorf("ParsePrivateTag(%q) should return error", input)
+		}
+	}
+}
+
+func TestInsertPrivateTags(t *testing.T) {
+	elements := []*dicom.Element{
+		newElement(t, tag.PatientName, []string{"DOE^JOHN"}),
+		newElement(t, tag.Modality, []string{"CT"}),
+		// Block 0x10 of group 0029 already reserved by another creator
+		{Tag: tag.Tag{Group: 0x0029, Element: 0x0010}, ValueRepresentation: tag.VRString, RawValueRepresentation: "LO", Value: mustValue(t, []string{"SIEMENS CSA HEADER"})},
+	}
+	tags := []PrivateTag{
+		{Creator: "ACME 1.0", Group: 0x0029, Element: 0x01, VR: "LO", Value: `a\b`},
+		{Creator: "ACME 1.0", Group: 0x0029, Element: 0x02, VR: "US", Value: `1\2`},
+		{Creator: "ACME 1.0", Group: 0x0009, Element: 0x03, VR: "OB", Value: "CAFE"},
+		{Creator: "SIEMENS CSA HEADER", Group: 0x0029, Element: 0x08, VR: "CS", Value: "IMAGE NUM 4"},
+	}
+	result, err := InsertPrivateTags(elements, tags)
+	if err != nil {
+		t.Fatalf("InsertPrivateTags returned error: %v", err)
+	}
+
+	byTag := make(map[tag.Tag]any)
+	for i, elem := range result {
+		byTag[elem.Tag] = elem.Value.GetValue()
+		if i > 0 {
+			prev := result[i-1].Tag
+			if prev.Group > elem.Tag.Group || prev.Group == elem.Tag.Group && prev.Element >= elem.Tag.Element {
+				t.Errorf("element %v after %v", elem.Tag, prev)
+			}
+		}
+	}
+	if len(result) != 9 {
+		t.Errorf("got %d elements, want 9", len(result))
+	}
+	checks := []struct {
+		tag  tag.Tag
+		want string
+	}{
+		{tag.Tag{Group: 0x0029, Element: 0x0011}, "[ACME 1.0]"},
+		{tag.Tag{Group: 0x0029, Element: 0x1101}, "[a b]"},
+		{tag.Tag{Group: 0x0029, Element: 0x1102}, "[1 2]"},
+		{tag.Tag{Group: 0x0009, Element: 0x0010}, "[ACME 1.0]"},
+		{tag.Tag{Group: 0x0029, Element: 0x1008}, "[IMAGE NUM 4]"},
+	}
+	for _, c := range checks {
+		if got := fmt.Sprint(byTag[c.tag]); got != c.want {
+			t.Errorf("%v = %s, want %s", c.tag, got, c.want)
+		}
+	}
+	if b, _ := byTag[tag.Tag{Group: 0x0009, Element: 0x1003}].([]byte); !bytes.Equal(b, []byte{0xCA, 0xFE}) {
+		t.Errorf("(0009,1003) = %v, want CA FE", b)
+	}
+
+	// Written and read back with their VRs
+	var buf bytes.Buffer
+	if err := dicom.Write(&buf, dicom.Dataset{Elements: append([]*dicom.Element{
+		newElement(t, tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+		newElement(t, tag.MediaStorageSOPInstanceUID, []string{"1.2.3"}),
+		newElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+	}, result...)}, dicom.SkipVRVerification()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ds, err := dicom.Parse(&buf, int64(buf.Len()), nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	elem, err := ds.FindElementByTag(tag.Tag{Group: 0x0029, Element: 0x1102})
+	if err != nil || elem.RawValueRepresentation != "US" || fmt.Sprint(elem.Value.GetValue()) != "[1 2]" {
+		t.Errorf("(0029,1102) read back as %v", elem)
+	}
+}
+
+// mustValue returns the dicom.Value of data or fails the test.
+func mustValue(t *testing.T, data any) dicom.Value {
+	t.Helper()
+	value, err := dicom.NewValue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
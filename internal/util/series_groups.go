@@ -0,0 +1,47 @@
+// internal/util/series_groups.go
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSeriesGroups parses groups of series numbers like "1,2;3": groups are
+// separated by ';' and the series numbers of a group by ','. A series number
+// may appear in one group only.
+func ParseSeriesGroups(s string) ([][]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	var groups [][]int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ";") {
+		var group []int
+		for _, field := range strings.Split(part, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid series number: %s", field)
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("series number must be >= 0, got %d", n)
+			}
+			if seen[n] {
+				return nil, fmt.Errorf("series %d appears in more than one group", n)
+			}
+			seen[n] = true
+			group = append(group, n)
+		}
+		if len(group) == 0 {
+			return nil, fmt.Errorf("empty series group in %q", s)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
@@ -0,0 +1,41 @@
+// internal/util/series_groups_test.go
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSeriesGroups(t *testing.T) {
+	tests := []struct {
+		input string
+		want  [][]int
+	}{
+		{"", nil},
+		{"2", [][]int{{2}}},
+		{"1,2;3", [][]int{{1, 2}, {3}}},
+		{" 1 , 3 ; 2 ", [][]int{{1, 3}, {2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSeriesGroups(tt.input)
+			if err != nil {
+				t.Fatalf("ParseSeriesGroups(%q) failed: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSeriesGroups(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSeriesGroups_Invalid(t *testing.T) {
+	for _, input := range []string{"a", "1;;2", "1,2;2", "-1", "1;"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseSeriesGroups(input); err == nil {
+				t.Errorf("ParseSeriesGroups(%q) should fail", input)
+			}
+		})
+	}
+}
@@ -35,3 +35,19 @@ func ParseSize(sizeStr string) (int64, error) {
 
 	return int64(value * float64(multiplier)), nil
 }
+
+// FormatSize formats a size in bytes with the largest binary unit that keeps
+// it at least 1 (e.g., "1.5 GB", "12.0 TB"), one decimal from KB on.
+func FormatSize(bytes int64) string {
+	units := []string{"KB", "MB", "GB", "TB", "PB"}
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value := float64(bytes) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
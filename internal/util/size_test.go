@@ -48,3 +48,21 @@ func TestParseSize_InvalidFormats(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{1610612736, "1.5 GB"},
+		{5 << 40, "5.0 TB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.input); got != tt.expected {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// internal/util/study_range.go
+package util
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+)
+
+// StudyRange represents a range of studies (min-max), for a whole run or per patient
+type StudyRange struct {
+	Min int
+	Max int
+}
+
+// ParseStudyRange parses a study range string like "5" or "10-20"
+func ParseStudyRange(s string) (StudyRange, error) {
+	min, max, err := parseCountRange(s, "studies")
+	if err != nil {
+		return StudyRange{}, err
+	}
+	return StudyRange{Min: min, Max: max}, nil
+}
+
+// IsSet returns true if the range was set (the zero value is unset)
+func (r StudyRange) IsSet() bool {
+	return r.Max > 0
+}
+
+// Count returns a random study count within the range
+func (r StudyRange) Count(rng *rand.Rand) int {
+	if r.Min == r.Max {
+		return r.Min
+	}
+	return r.Min + rng.IntN(r.Max-r.Min+1)
+}
+
+// String returns the string representation of the range
+func (r StudyRange) String() string {
+	if r.Min == r.Max {
+		return strconv.Itoa(r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
@@ -0,0 +1,59 @@
+// internal/util/study_range_test.go
+package util
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestParseStudyRange(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantMin int
+		wantMax int
+		wantErr bool
+	}{
+		{"5", 5, 5, false},
+		{"10-20", 10, 20, false},
+		{" 1 - 3 ", 1, 3, false},
+		{"", 0, 0, true},
+		{"0", 0, 0, true},
+		{"0-4", 0, 0, true},
+		{"5-2", 0, 0, true},
+		{"x", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseStudyRange(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseStudyRange(%q) should fail", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStudyRange(%q) failed: %v", tt.input, err)
+			}
+			if r.Min != tt.wantMin || r.Max != tt.wantMax {
+				t.Errorf("ParseStudyRange(%q) = {%d, %d}, want {%d, %d}", tt.input, r.Min, r.Max, tt.wantMin, tt.wantMax)
+			}
+			if r.String() != tt.input && tt.input != " 1 - 3 " {
+				t.Errorf("String() = %q, want %q", r.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestStudyRange_Count(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	r := StudyRange{Min: 1, Max: 5}
+	for i := 0; i < 200; i++ {
+		if n := r.Count(rng); n < 1 || n > 5 {
+			t.Fatalf("Count() = %d, want 1-5", n)
+		}
+	}
+	if (StudyRange{}).IsSet() {
+		t.Error("zero StudyRange should not be set")
+	}
+}
@@ -3,6 +3,8 @@ package util
 import (
 	"fmt"
 	"strings"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
 // ParsedTags represents a map of tag names to their values.
@@ -36,6 +38,15 @@ func ParseTagFlags(flags []string) (ParsedTags, error) {
 			return nil, err
 		}
 
+		// Date and time values must be valid DA/TM/DT
+		if value != "" {
+			if info, err := tag.Find(tagInfo.Tag); err == nil {
+				if err := ValidateDateTime(info.VRs[0], value); err != nil {
+					return nil, fmt.Errorf("invalid value for %s: %w", tagInfo.Name, err)
+				}
+			}
+		}
+
 		// Store with canonical name
 		result[tagInfo.Name] = value
 	}
@@ -86,3 +97,19 @@ func (pt ParsedTags) Keys() []string {
 	}
 	return keys
 }
+
+// With returns the tags overridden by those of overrides, leaving both
+// unchanged. It returns pt itself when overrides is empty.
+func (pt ParsedTags) With(overrides ParsedTags) ParsedTags {
+	if len(overrides) == 0 {
+		return pt
+	}
+	result := make(ParsedTags, len(pt)+len(overrides))
+	for name, value := range pt {
+		result[name] = value
+	}
+	for name, value := range overrides {
+		result[name] = value
+	}
+	return result
+}
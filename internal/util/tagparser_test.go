@@ -124,6 +124,34 @@ func TestParseTagFlags_UnknownTag(t *testing.T) {
 	}
 }
 
+func TestParseTagFlags_DateTimeValidation(t *testing.T) {
+	valid := [][]string{
+		{"StudyDate=20240315"},
+		{"StudyTime=083000"},
+		{"StudyTime=083000.123"},
+		{"PatientBirthDate=19700101"},
+		{"StudyDate="},
+	}
+	for _, flags := range valid {
+		if _, err := ParseTagFlags(flags); err != nil {
+			t.Errorf("ParseTagFlags(%v) returned error: %v", flags, err)
+		}
+	}
+
+	invalid := [][]string{
+		{"StudyDate=2024-03-15"},
+		{"StudyDate=20240230"},
+		{"StudyTime=25:00"},
+		{"StudyTime=256000"},
+		{"PatientBirthDate=1970"},
+	}
+	for _, flags := range invalid {
+		if _, err := ParseTagFlags(flags); err == nil {
+			t.Errorf("ParseTagFlags(%v) should return error for invalid date/time", flags)
+		}
+	}
+}
+
 func TestParseTagFlags_EmptyValue(t *testing.T) {
 	flags := []string{"PatientName="}
 	parsed, err := ParseTagFlags(flags)
@@ -310,6 +338,38 @@ func TestParsedTags_Keys(t *testing.T) {
 	}
 }
 
+func TestParsedTags_With(t *testing.T) {
+	base := ParsedTags{
+		"InstitutionName": "General Hospital",
+		"StationName":     "CT01",
+	}
+	overrides := ParsedTags{
+		"StationName":  "CT02",
+		"ProtocolName": "HEAD",
+	}
+
+	got := base.With(overrides)
+	want := ParsedTags{
+		"InstitutionName": "General Hospital",
+		"StationName":     "CT02",
+		"ProtocolName":    "HEAD",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("With() returned %d tags, want %d", len(got), len(want))
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("With()[%q] = %q, want %q", key, got[key], value)
+		}
+	}
+	if base["StationName"] != "CT01" || len(base) != 2 {
+		t.Errorf("With() modified the base tags: %v", base)
+	}
+	if got := ParsedTags(nil).With(nil); got != nil {
+		t.Errorf("nil.With(nil) = %v, want nil", got)
+	}
+}
+
 func TestParseTagFlags_EmptySlice(t *testing.T) {
 	parsed, err := ParseTagFlags([]string{})
 	if err != nil {
@@ -54,6 +54,8 @@ var tagRegistry = map[string]TagInfo{
 	"patientsex":       {Name: "PatientSex", Tag: tag.PatientSex, Scope: ScopePatient},
 
 	// Study level tags
+	"studydate":                    {Name: "StudyDate", Tag: tag.StudyDate, Scope: ScopeStudy},
+	"studytime":                    {Name: "StudyTime", Tag: tag.StudyTime, Scope: ScopeStudy},
 	"studydescription":             {Name: "StudyDescription", Tag: tag.StudyDescription, Scope: ScopeStudy},
 	"institutionname":              {Name: "InstitutionName", Tag: tag.InstitutionName, Scope: ScopeStudy},
 	"institutionaldepartmentname":  {Name: "InstitutionalDepartmentName", Tag: tag.InstitutionalDepartmentName, Scope: ScopeStudy},
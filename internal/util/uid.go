@@ -8,6 +8,9 @@ import (
 	"strings"
 )
 
+// DefaultUIDRoot is the root of the UIDs generated when no other is given.
+const DefaultUIDRoot = "1.2.826.0.1.3680043.8.498"
+
 // GenerateDeterministicUID generates a deterministic DICOM UID from a seed string.
 //
 // The UID is generated using SHA256 hash of the seed, ensuring the same seed
@@ -15,7 +18,7 @@ import (
 // no leading zeros in components).
 func GenerateDeterministicUID(seed string) string {
 	// DICOM UID prefix for compatibility
-	prefix := "1.2.826.0.1.3680043.8.498"
+	prefix := DefaultUIDRoot
 
 	// Generate SHA256 hash of seed
 	hash := sha256.Sum256([]byte(seed))
@@ -60,3 +63,54 @@ func GenerateDeterministicUID(seed string) string {
 
 	return uid
 }
+
+// minUIDSuffixDigits is the number of digits GenerateUIDUnderRoot keeps at
+// least after the root, so that UIDs derived from distinct seeds stay distinct.
+const minUIDSuffixDigits = 16
+
+// ValidateUIDRoot checks that root can prefix generated UIDs: digit
+// components separated by dots, without leading zeros, short enough to leave
+// room for a suffix within 64 characters.
+func ValidateUIDRoot(root string) error {
+	if root == "" {
+		return fmt.Errorf("UID root is empty")
+	}
+	if limit := 64 - 1 - minUIDSuffixDigits; len(root) > limit {
+		return fmt.Errorf("UID root %q is longer than %d characters", root, limit)
+	}
+	for _, component := range strings.Split(root, ".") {
+		if component == "" {
+			return fmt.Errorf("UID root %q has an empty component", root)
+		}
+		if strings.Trim(component, "0123456789") != "" {
+			return fmt.Errorf("UID root %q has a non-digit component %q", root, component)
+		}
+		if len(component) > 1 && component[0] == '0' {
+			return fmt.Errorf("UID root %q has a component with a leading zero %q", root, component)
+		}
+	}
+	return nil
+}
+
+// GenerateUIDUnderRoot generates a deterministic UID under root from a seed
+// string: root followed by a single component derived from the SHA256 hash of
+// the seed, filling up to 64 characters (at most 39 digits). The root must
+// pass ValidateUIDRoot.
+func GenerateUIDUnderRoot(root, seed string) string {
+	hash := sha256.Sum256([]byte(seed))
+	digits := new(big.Int).SetBytes(hash[:]).String()
+
+	n := min(64-len(root)-1, 39)
+	return root + "." + digits[:n]
+}
+
+// GenerateDeterministicUIDWithRoot generates a deterministic UID from a seed
+// string under root: GenerateDeterministicUID when root is empty or the
+// default root, GenerateUIDUnderRoot otherwise. The root must pass
+// ValidateUIDRoot.
+func GenerateDeterministicUIDWithRoot(root, seed string) string {
+	if root == "" || root == DefaultUIDRoot {
+		return GenerateDeterministicUID(seed)
+	}
+	return GenerateUIDUnderRoot(root, seed)
+}
@@ -52,3 +52,54 @@ func TestGenerateDeterministicUID_Format(t *testing.T) {
 		t.Errorf("UID should start with %s, got %s", expectedPrefix, uid)
 	}
 }
+
+func TestValidateUIDRoot(t *testing.T) {
+	for _, root := range []string{"1.2.3", "2.25", "1.2.826.0.1.3680043.8.498", "0.1"} {
+		if err := ValidateUIDRoot(root); err != nil {
+			t.Errorf("ValidateUIDRoot(%q) = %v, want nil", root, err)
+		}
+	}
+	for _, root := range []string{"", "1..2", "1.2.", ".1", "1.02", "1.2a", "1 2", strings.Repeat("1.", 24) + "1"} {
+		if err := ValidateUIDRoot(root); err == nil {
+			t.Errorf("ValidateUIDRoot(%q) should fail", root)
+		}
+	}
+}
+
+func TestGenerateUIDUnderRoot(t *testing.T) {
+	roots := []string{"1.2.3", "2.25", strings.Repeat("1.", 23) + "1"}
+	for _, root := range roots {
+		uid := GenerateUIDUnderRoot(root, "seed")
+		if !strings.HasPrefix(uid, root+".") {
+			t.Errorf("UID %s is not under root %s", uid, root)
+		}
+		if len(uid) > 64 {
+			t.Errorf("UID %s is %d characters long", uid, len(uid))
+		}
+		suffix := strings.TrimPrefix(uid, root+".")
+		if len(suffix) < minUIDSuffixDigits || suffix[0] == '0' || strings.Trim(suffix, "0123456789") != "" {
+			t.Errorf("UID %s has an invalid suffix %q", uid, suffix)
+		}
+		if GenerateUIDUnderRoot(root, "seed") != uid {
+			t.Errorf("same seed gives different UIDs under %s", root)
+		}
+		if GenerateUIDUnderRoot(root, "other") == uid {
+			t.Errorf("different seeds give the same UID under %s", root)
+		}
+	}
+}
+
+func TestGenerateDeterministicUIDWithRoot(t *testing.T) {
+	for _, root := range []string{"", DefaultUIDRoot} {
+		if got, want := GenerateDeterministicUIDWithRoot(root, "seed"), GenerateDeterministicUID("seed"); got != want {
+			t.Errorf("GenerateDeterministicUIDWithRoot(%q) = %s, want %s", root, got, want)
+		}
+	}
+	uid := GenerateDeterministicUIDWithRoot("1.2.3.4", "seed")
+	if uid != GenerateUIDUnderRoot("1.2.3.4", "seed") {
+		t.Errorf("UID %s is not the UID under the root", uid)
+	}
+	if uid == GenerateDeterministicUIDWithRoot("1.2.3.4", "other") {
+		t.Errorf("Different seeds should produce different UIDs")
+	}
+}
@@ -16,16 +16,15 @@ func TestErrors_InvalidNumImages(t *testing.T) {
 		errorMsg  string
 	}{
 		{
-			name:      "zero_images",
+			name:      "zero_images", // Typical count per series for the modality
 			numImages: 0,
-			wantError: true,
-			errorMsg:  "number of images must be > 0",
+			wantError: false,
 		},
 		{
 			name:      "negative_images",
 			numImages: -5,
 			wantError: true,
-			errorMsg:  "number of images must be > 0",
+			errorMsg:  "number of images must be >= 0",
 		},
 		{
 			name:      "one_image",
@@ -1,15 +1,26 @@
 package tests
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
 	"github.com/mrsinham/dicomforge/internal/dicom/corruption"
 	"github.com/mrsinham/dicomforge/internal/dicom/edgecases"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/dicom/sr"
+	"github.com/mrsinham/dicomforge/internal/dicom/variability"
 	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
@@ -575,6 +586,81 @@ func TestCustomTags(t *testing.T) {
 	}
 }
 
+// TestCustomTags_PerStudyAndSeries tests that the tag overrides of predefined
+// studies and series take precedence over the global ones
+func TestCustomTags_PerStudyAndSeries(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	opts := internaldicom.GeneratorOptions{
+		TotalSize:  "1MB",
+		OutputDir:  tmpDir,
+		Seed:       42,
+		CustomTags: util.ParsedTags{"InstitutionName": "Global Hospital", "StationName": "GLOBAL"},
+		PredefinedPatients: []internaldicom.PredefinedPatient{{
+			Name: "Doe^Jane",
+			ID:   "PAT001",
+			Studies: []internaldicom.PredefinedStudy{
+				{
+					CustomTags: util.ParsedTags{"InstitutionName": "Study Hospital"},
+					Series: []internaldicom.PredefinedSeries{
+						{ImageCount: 1, CustomTags: util.ParsedTags{"ProtocolName": "SERIES PROTOCOL", "BodyPartExamined": "KNEE"}},
+						{ImageCount: 1},
+					},
+				},
+				{Series: []internaldicom.PredefinedSeries{{ImageCount: 1}}},
+			},
+		}},
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %d", len(files))
+	}
+
+	value := func(path string, tg tag.Tag) string {
+		ds, err := dicom.ParseFile(path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse DICOM: %v", err)
+		}
+		elem, err := ds.FindElementByTag(tg)
+		if err != nil {
+			t.Fatalf("%v not found in %s", tg, path)
+		}
+		return elem.Value.GetValue().([]string)[0]
+	}
+
+	firstStudy := files[0].StudyUID
+	for _, f := range files {
+		wantInstitution := "Global Hospital"
+		if f.StudyUID == firstStudy {
+			wantInstitution = "Study Hospital"
+		}
+		if got := value(f.Path, tag.InstitutionName); got != wantInstitution {
+			t.Errorf("InstitutionName = %s, want %s", got, wantInstitution)
+		}
+		if got := value(f.Path, tag.StationName); got != "GLOBAL" {
+			t.Errorf("StationName = %s, want GLOBAL", got)
+		}
+	}
+
+	// Series overrides apply to their own series only
+	if got := value(files[0].Path, tag.ProtocolName); got != "SERIES PROTOCOL" {
+		t.Errorf("ProtocolName = %s, want SERIES PROTOCOL", got)
+	}
+	if got := value(files[0].Path, tag.BodyPartExamined); got != "KNEE" {
+		t.Errorf("BodyPartExamined = %s, want KNEE", got)
+	}
+	if files[1].SeriesUID == files[0].SeriesUID {
+		t.Fatal("Expected the second file in another series")
+	}
+	if got := value(files[1].Path, tag.ProtocolName); got == "SERIES PROTOCOL" {
+		t.Error("Series ProtocolName override leaked to the next series")
+	}
+}
+
 // TestEdgeCases_SpecialChars tests that special character names are generated
 func TestEdgeCases_SpecialChars(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -1075,12 +1161,2410 @@ func TestCorruption_NoRegression(t *testing.T) {
 	t.Logf("✓ No regression test passed")
 }
 
-// findElementByTag searches for an element with the given tag in a dataset
-func findElementByTag(ds dicom.Dataset, t tag.Tag) *dicom.Element {
-	for _, elem := range ds.Elements {
-		if elem.Tag == t {
-			return elem
+// TestUSMeasurementSR tests that US studies get a measurement SR referencing their images
+func TestUSMeasurementSR(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:       6,
+		TotalSize:       "1MB",
+		OutputDir:       tmpDir,
+		Seed:            42,
+		NumStudies:      2,
+		NumPatients:     1,
+		Modality:        "US",
+		BodyPart:        "PELVIS",
+		USMeasurementSR: true,
+		Quiet:           true,
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	// One SR per study on top of the images
+	if len(files) != 8 {
+		t.Fatalf("Expected 6 images + 2 SRs, got %d files", len(files))
+	}
+
+	imageUIDs := make(map[string]string) // SOPInstanceUID -> StudyUID
+	for _, f := range files[:6] {
+		imageUIDs[f.SOPInstanceUID] = f.StudyUID
+	}
+
+	for _, f := range files[6:] {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse SR %s: %v", f.Path, err)
+		}
+		if got := findElementByTag(ds, tag.Modality).Value.GetValue().([]string)[0]; got != "SR" {
+			t.Errorf("SR Modality = %s, want SR", got)
+		}
+
+		// Every IMAGE content item must reference an image of the same study
+		var refs []string
+		var walk func(items []*dicom.SequenceItemValue)
+		walk = func(items []*dicom.SequenceItemValue) {
+			for _, item := range items {
+				for _, elem := range item.GetValue().([]*dicom.Element) {
+					switch elem.Tag {
+					case tag.ReferencedSOPInstanceUID:
+						refs = append(refs, elem.Value.GetValue().([]string)[0])
+					case tag.ContentSequence, tag.ReferencedSOPSequence:
+						walk(elem.Value.GetValue().([]*dicom.SequenceItemValue))
+					}
+				}
+			}
+		}
+		walk(findElementByTag(ds, tag.ContentSequence).Value.GetValue().([]*dicom.SequenceItemValue))
+
+		if len(refs) == 0 {
+			t.Errorf("SR %s has no image references", f.Path)
+		}
+		for _, ref := range refs {
+			if studyUID, ok := imageUIDs[ref]; !ok || studyUID != f.StudyUID {
+				t.Errorf("SR %s references %s which is not an image of its study", f.Path, ref)
+			}
 		}
 	}
-	return nil
+
+	// DICOMDIR should index the SRs as SR DOCUMENT records
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(tmpDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	dicomdir, err := dicom.ParseFile(filepath.Join(tmpDir, "DICOMDIR"), nil)
+	if err != nil {
+		t.Fatalf("Failed to parse DICOMDIR: %v", err)
+	}
+	srRecords := 0
+	for _, item := range findElementByTag(dicomdir, tag.DirectoryRecordSequence).Value.GetValue().([]*dicom.SequenceItemValue) {
+		for _, elem := range item.GetValue().([]*dicom.Element) {
+			if elem.Tag == tag.DirectoryRecordType && elem.Value.GetValue().([]string)[0] == "SR DOCUMENT" {
+				srRecords++
+			}
+		}
+	}
+	if srRecords != 2 {
+		t.Errorf("Expected 2 SR DOCUMENT records in DICOMDIR, got %d", srRecords)
+	}
+
+	t.Logf("✓ US measurement SR test passed")
+}
+
+// TestTextSR tests that every study gets a Basic Text SR referencing its images
+func TestTextSR(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:   6,
+		TotalSize:   "1MB",
+		OutputDir:   tmpDir,
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 1,
+		Modality:    "CT",
+		TextSR:      true,
+		Quiet:       true,
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if len(files) != 8 {
+		t.Fatalf("Expected 6 images + 2 SRs, got %d files", len(files))
+	}
+
+	imageUIDs := make(map[string]string) // SOPInstanceUID -> StudyUID
+	for _, f := range files[:6] {
+		imageUIDs[f.SOPInstanceUID] = f.StudyUID
+	}
+
+	for _, f := range files[6:] {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse SR %s: %v", f.Path, err)
+		}
+		if got := findElementByTag(ds, tag.SOPClassUID).Value.GetValue().([]string)[0]; got != "1.2.840.10008.5.1.4.1.1.88.11" {
+			t.Errorf("SR SOPClassUID = %s, want Basic Text SR Storage", got)
+		}
+
+		// The evidence sequence must list the images of the same study
+		var refs []string
+		var walk func(items []*dicom.SequenceItemValue)
+		walk = func(items []*dicom.SequenceItemValue) {
+			for _, item := range items {
+				for _, elem := range item.GetValue().([]*dicom.Element) {
+					switch elem.Tag {
+					case tag.ReferencedSOPInstanceUID:
+						refs = append(refs, elem.Value.GetValue().([]string)[0])
+					case tag.ReferencedSeriesSequence, tag.ReferencedSOPSequence:
+						walk(elem.Value.GetValue().([]*dicom.SequenceItemValue))
+					}
+				}
+			}
+		}
+		walk(findElementByTag(ds, tag.CurrentRequestedProcedureEvidenceSequence).Value.GetValue().([]*dicom.SequenceItemValue))
+
+		if len(refs) != 3 {
+			t.Errorf("SR %s references %d images, want 3", f.Path, len(refs))
+		}
+		for _, ref := range refs {
+			if studyUID, ok := imageUIDs[ref]; !ok || studyUID != f.StudyUID {
+				t.Errorf("SR %s references %s which is not an image of its study", f.Path, ref)
+			}
+		}
+	}
+
+	t.Logf("✓ Text SR test passed")
+}
+
+func TestAIResults(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:   8,
+		TotalSize:   "2MB",
+		OutputDir:   tmpDir,
+		Seed:        42,
+		NumStudies:  1,
+		NumPatients: 1,
+		Modality:    "CT",
+		AIResults:   []string{internaldicom.AIResultSR, internaldicom.AIResultSC},
+		Quiet:       true,
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	// 8 images, 1 SR and 1-3 heatmaps (one per lesion central slice)
+	if len(files) < 10 || len(files) > 12 {
+		t.Fatalf("Expected 8 images + 1 SR + 1-3 SCs, got %d files", len(files))
+	}
+
+	imageUIDs := make(map[string]bool)
+	for _, f := range files[:8] {
+		imageUIDs[f.SOPInstanceUID] = true
+	}
+
+	// SR: every finding references a generated image
+	ds, err := dicom.ParseFile(files[8].Path, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse SR: %v", err)
+	}
+	var refs []string
+	scoords := 0
+	var walk func(items []*dicom.SequenceItemValue)
+	walk = func(items []*dicom.SequenceItemValue) {
+		for _, item := range items {
+			for _, elem := range item.GetValue().([]*dicom.Element) {
+				switch elem.Tag {
+				case tag.ReferencedSOPInstanceUID:
+					refs = append(refs, elem.Value.GetValue().([]string)[0])
+				case tag.GraphicType:
+					scoords++
+				case tag.ContentSequence, tag.ReferencedSOPSequence:
+					walk(elem.Value.GetValue().([]*dicom.SequenceItemValue))
+				}
+			}
+		}
+	}
+	walk(findElementByTag(ds, tag.ContentSequence).Value.GetValue().([]*dicom.SequenceItemValue))
+
+	if scoords < 1 || scoords > 3 {
+		t.Errorf("Expected 1-3 SCOORD outlines, got %d", scoords)
+	}
+	if len(refs) != 2*scoords {
+		t.Errorf("Expected 2 image references per finding, got %d for %d findings", len(refs), scoords)
+	}
+	for _, ref := range refs {
+		if !imageUIDs[ref] {
+			t.Errorf("SR references %s which is not a generated image", ref)
+		}
+	}
+
+	// SCs: RGB heatmaps derived from a generated image
+	for _, f := range files[9:] {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse SC %s: %v", f.Path, err)
+		}
+		if got := findElementByTag(ds, tag.PhotometricInterpretation).Value.GetValue().([]string)[0]; got != "RGB" {
+			t.Errorf("SC PhotometricInterpretation = %s, want RGB", got)
+		}
+		if got := findElementByTag(ds, tag.SOPClassUID).Value.GetValue().([]string)[0]; got != internaldicom.SecondaryCaptureImageStorage {
+			t.Errorf("SC SOPClassUID = %s, want Secondary Capture", got)
+		}
+		source := findElementByTag(ds, tag.SourceImageSequence).Value.GetValue().([]*dicom.SequenceItemValue)
+		for _, elem := range source[0].GetValue().([]*dicom.Element) {
+			if elem.Tag == tag.ReferencedSOPInstanceUID && !imageUIDs[elem.Value.GetValue().([]string)[0]] {
+				t.Errorf("SC %s is derived from an unknown image", f.Path)
+			}
+		}
+	}
+
+	t.Logf("✓ AI results test passed")
+}
+
+func TestVariability(t *testing.T) {
+	generate := func(dir string, pct int) []internaldicom.GeneratedFile {
+		files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+			NumImages:         12,
+			TotalSize:         "1MB",
+			OutputDir:         dir,
+			Seed:              42,
+			NumStudies:        6,
+			Modality:          "CT",
+			VariabilityConfig: variability.Config{Percentage: pct},
+			Quiet:             true,
+		})
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		return files
+	}
+
+	dir := filepath.Join(t.TempDir(), "varied")
+	files := generate(dir, 100)
+
+	// Studies are styled differently: DS precision and description casing vary
+	thicknesses := make(map[string]bool)
+	descriptions := make(map[string]bool)
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Path, err)
+		}
+		thicknesses[findElementByTag(ds, tag.SliceThickness).Value.GetValue().([]string)[0]] = true
+		desc := findElementByTag(ds, tag.StudyDescription).Value.GetValue().([]string)[0]
+		descriptions[strings.ToUpper(desc[:1])+desc[1:]] = true
+	}
+	if len(thicknesses) < 2 {
+		t.Errorf("Expected varied SliceThickness formatting across studies, got %v", thicknesses)
+	}
+	if len(descriptions) < 2 {
+		t.Errorf("Expected varied description casing, got %v", descriptions)
+	}
+
+	// Same seed and directory produce the same styles
+	again := generate(dir, 100)
+	for i := range files {
+		a, _ := os.ReadFile(files[i].Path)
+		b, _ := os.ReadFile(again[i].Path)
+		if string(a) != string(b) {
+			t.Errorf("File %s differs between runs", filepath.Base(files[i].Path))
+		}
+	}
+
+	t.Logf("✓ Variability test passed")
+}
+
+func TestCohortDemographics(t *testing.T) {
+	demographics := util.DefaultDemographics()
+	demographics.MaleRatio = 0.8
+	demographics.AgePyramid = []util.AgeBand{{MinAge: 0, MaxAge: 17, Weight: 1}}
+
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		NumImages:    200,
+		TotalSize:    "2MB",
+		OutputDir:    t.TempDir(),
+		Seed:         42,
+		NumStudies:   200,
+		NumPatients:  200,
+		Demographics: &demographics,
+		Quiet:        true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	males := 0
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Path, err)
+		}
+		if findElementByTag(ds, tag.PatientSex).Value.GetValue().([]string)[0] == "M" {
+			males++
+		}
+		// Pediatric pyramid: patients are under 18 mid-2022, studies span 2020-2024
+		age := findElementByTag(ds, tag.PatientAge).Value.GetValue().([]string)[0]
+		if strings.HasSuffix(age, "Y") && age > "020Y" {
+			t.Errorf("%s: PatientAge %s outside the pediatric pyramid", filepath.Base(f.Path), age)
+		}
+	}
+	if ratio := float64(males) / float64(len(files)); ratio < 0.7 || ratio > 0.9 {
+		t.Errorf("male ratio %.2f, want about 0.8", ratio)
+	}
+
+	t.Logf("✓ Cohort demographics test passed")
+}
+
+func TestModalityDefaultImageCounts(t *testing.T) {
+	tests := []struct {
+		modality modalities.Modality
+		min, max int
+	}{
+		{modalities.MG, 4, 4},
+		{modalities.DX, 1, 2},
+		{modalities.US, 1, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.modality), func(t *testing.T) {
+			files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+				TotalSize:      "5MB",
+				OutputDir:      t.TempDir(),
+				Seed:           42,
+				Modality:       tt.modality,
+				NumStudies:     3,
+				NumPatients:    1,
+				SeriesPerStudy: util.SeriesRange{Min: 2, Max: 2},
+				Quiet:          true,
+			})
+			if err != nil {
+				t.Fatalf("GenerateDICOMSeries failed: %v", err)
+			}
+
+			counts := make(map[string]int)
+			for _, f := range files {
+				counts[f.SeriesUID]++
+			}
+			if len(counts) != 6 {
+				t.Fatalf("expected 6 series, got %d", len(counts))
+			}
+			for uid, n := range counts {
+				if n < tt.min || n > tt.max {
+					t.Errorf("series %s has %d images, want %d-%d", uid, n, tt.min, tt.max)
+				}
+			}
+		})
+	}
+
+	t.Logf("✓ Modality default image counts test passed")
+}
+
+func TestImagesPerSeriesRange(t *testing.T) {
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		TotalSize:       "5MB",
+		OutputDir:       t.TempDir(),
+		Seed:            42,
+		NumStudies:      4,
+		NumPatients:     2,
+		SeriesPerStudy:  util.SeriesRange{Min: 2, Max: 2},
+		ImagesPerSeries: util.ImageRange{Min: 3, Max: 6},
+		Quiet:           true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, f := range files {
+		counts[f.SeriesUID]++
+	}
+	if len(counts) != 8 {
+		t.Fatalf("expected 8 series, got %d", len(counts))
+	}
+	distinct := make(map[int]bool)
+	for uid, n := range counts {
+		if n < 3 || n > 6 {
+			t.Errorf("series %s has %d images, want 3-6", uid, n)
+		}
+		distinct[n] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("all series have the same number of images, want random counts")
+	}
+
+	_, err = internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		NumImages:       10,
+		TotalSize:       "1MB",
+		OutputDir:       t.TempDir(),
+		NumStudies:      1,
+		ImagesPerSeries: util.ImageRange{Min: 3, Max: 6},
+		Quiet:           true,
+	})
+	if err == nil {
+		t.Error("expected an error when combining NumImages and ImagesPerSeries")
+	}
+
+	t.Logf("✓ Images per series range test passed")
+}
+
+func TestStudiesPerPatient(t *testing.T) {
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		TotalSize:         "5MB",
+		OutputDir:         t.TempDir(),
+		Seed:              42,
+		NumPatients:       12,
+		StudiesPerPatient: util.StudyRange{Min: 1, Max: 4},
+		ImagesPerSeries:   util.ImageRange{Min: 1, Max: 1},
+		Quiet:             true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	studies := make(map[string]map[string]bool)
+	for _, f := range files {
+		if studies[f.PatientID] == nil {
+			studies[f.PatientID] = make(map[string]bool)
+		}
+		studies[f.PatientID][f.StudyUID] = true
+	}
+	if len(studies) != 12 {
+		t.Fatalf("expected 12 patients, got %d", len(studies))
+	}
+	distinct := make(map[int]bool)
+	for patientID, uids := range studies {
+		if n := len(uids); n < 1 || n > 4 {
+			t.Errorf("patient %s has %d studies, want 1-4", patientID, n)
+		}
+		distinct[len(uids)] = true
+	}
+	if len(distinct) < 2 {
+		t.Error("every patient has the same number of studies")
+	}
+
+	t.Logf("✓ Studies per patient test passed")
+}
+
+// TestFromCSV tests generating one study per CSV row with its identifying tags
+func TestFromCSV(t *testing.T) {
+	outputDir := t.TempDir()
+	csvPath := filepath.Join(t.TempDir(), "studies.csv")
+	data := "MRN,Name,Accession,Date\n" +
+		"MRN001,DOE^JOHN,A1001,2024-01-15\n" +
+		"MRN002,MARTIN^CLAIRE,A1002,2024-03-20\n" +
+		"MRN001,DOE^JOHN,A1003,2024-06-02\n"
+	if err := os.WriteFile(csvPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	patients, err := internaldicom.LoadPatientsCSV(csvPath)
+	if err != nil {
+		t.Fatalf("LoadPatientsCSV failed: %v", err)
+	}
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		TotalSize:          "1MB",
+		OutputDir:          outputDir,
+		Seed:               42,
+		ImagesPerSeries:    util.ImageRange{Min: 1, Max: 1},
+		PredefinedPatients: patients,
+		Quiet:              true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files (one study per row), got %d", len(files))
+	}
+
+	want := map[string][3]string{ // AccessionNumber -> PatientID, PatientName, StudyDate
+		"A1001": {"MRN001", "DOE^JOHN", "20240115"},
+		"A1002": {"MRN002", "MARTIN^CLAIRE", "20240320"},
+		"A1003": {"MRN001", "DOE^JOHN", "20240602"},
+	}
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Path, err)
+		}
+		get := func(tg tag.Tag) string {
+			elem, err := ds.FindElementByTag(tg)
+			if err != nil {
+				t.Fatalf("%s: missing %v", f.Path, tg)
+			}
+			return dicom.MustGetStrings(elem.Value)[0]
+		}
+		accession := get(tag.AccessionNumber)
+		expected, ok := want[accession]
+		if !ok {
+			t.Errorf("unexpected AccessionNumber %q", accession)
+			continue
+		}
+		delete(want, accession)
+		got := [3]string{get(tag.PatientID), get(tag.PatientName), get(tag.StudyDate)}
+		if got != expected {
+			t.Errorf("study %s: got %v, want %v", accession, got, expected)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("studies not generated: %v", want)
+	}
+
+	t.Logf("✓ CSV import test passed")
+}
+
+// TestLinkageIDs tests the coherence of RIS/EMR linkage IDs across studies
+func TestLinkageIDs(t *testing.T) {
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		TotalSize:       "1MB",
+		OutputDir:       t.TempDir(),
+		Seed:            42,
+		ImagesPerSeries: util.ImageRange{Min: 2, Max: 2},
+		LinkageIDs:      true,
+		PredefinedPatients: []internaldicom.PredefinedPatient{
+			{ID: "P1", Studies: []internaldicom.PredefinedStudy{{Date: "20240101"}, {Date: "20240101"}, {Date: "20240301"}}},
+			{ID: "P2", Studies: []internaldicom.PredefinedStudy{{Date: "20240101"}}},
+		},
+		Quiet: true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	type studyIDs struct{ admission, placer, filler string }
+	studies := make(map[string]studyIDs) // StudyInstanceUID -> IDs
+	var order []string
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Path, err)
+		}
+		get := func(tg tag.Tag) string {
+			elem, err := ds.FindElementByTag(tg)
+			if err != nil {
+				t.Fatalf("%s: missing %v", f.Path, tg)
+			}
+			return dicom.MustGetStrings(elem.Value)[0]
+		}
+		ids := studyIDs{
+			get(tag.AdmissionID),
+			get(tag.PlacerOrderNumberImagingServiceRequest),
+			get(tag.FillerOrderNumberImagingServiceRequest),
+		}
+		prev, ok := studies[f.StudyUID]
+		if !ok {
+			studies[f.StudyUID] = ids
+			order = append(order, f.StudyUID)
+		} else if prev != ids {
+			t.Errorf("study %s: images have IDs %+v and %+v", f.StudyUID, prev, ids)
+		}
+	}
+	if len(order) != 4 {
+		t.Fatalf("expected 4 studies, got %d", len(order))
+	}
+
+	// P1 on 20240101 (twice), P1 on 20240301, P2 on 20240101
+	s := []studyIDs{studies[order[0]], studies[order[1]], studies[order[2]], studies[order[3]]}
+	if s[0].admission != s[1].admission {
+		t.Errorf("same-day studies of P1 have admissions %s and %s, want one visit", s[0].admission, s[1].admission)
+	}
+	if s[2].admission == s[0].admission || s[3].admission == s[0].admission {
+		t.Error("studies of another day or patient share the visit")
+	}
+	orders := make(map[string]bool)
+	for _, ids := range s {
+		if orders[ids.placer] || orders[ids.filler] || ids.placer == ids.filler {
+			t.Errorf("order numbers %+v are not unique", ids)
+		}
+		orders[ids.placer], orders[ids.filler] = true, true
+	}
+
+	t.Logf("✓ Linkage IDs test passed")
+}
+
+func TestIdempotentRerun(t *testing.T) {
+	outputDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:      12,
+		TotalSize:      "1MB",
+		OutputDir:      outputDir,
+		Seed:           42,
+		NumStudies:     2,
+		NumPatients:    2,
+		SeriesPerStudy: util.SeriesRange{Min: 2, Max: 2},
+		Quiet:          true,
+	}
+
+	run := func() internaldicom.OrganizeReport {
+		files, err := internaldicom.GenerateDICOMSeries(opts)
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		report, err := internaldicom.OrganizeFiles(outputDir, files, internaldicom.OrganizeOptions{Quiet: true})
+		if err != nil {
+			t.Fatalf("OrganizeFiles failed: %v", err)
+		}
+		return report
+	}
+
+	first := run()
+	if first.Written != 12 || first.Skipped != 0 {
+		t.Fatalf("first run: %+v, want 12 written", first)
+	}
+
+	// Age every file so that rewritten files are detected by their mtime
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err := filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, old, old)
+	})
+	if err != nil {
+		t.Fatalf("Failed to age files: %v", err)
+	}
+
+	second := run()
+	if second.Written != 0 || second.Skipped != 12 {
+		t.Errorf("rerun: %+v, want 12 skipped", second)
+	}
+	err = filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if !info.ModTime().Equal(old) {
+			t.Errorf("%s was rewritten on rerun", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk output: %v", err)
+	}
+
+	// A different profile rewrites the files
+	opts.Seed = 43
+	third := run()
+	if third.Written == 0 {
+		t.Errorf("new seed: %+v, want files written", third)
+	}
+
+	t.Logf("✓ Idempotent rerun test passed")
+}
+
+// TestExportJSON tests the DICOM JSON model export of generated instances
+func TestExportJSON(t *testing.T) {
+	outputDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "1MB",
+		OutputDir:   outputDir,
+		Seed:        42,
+		NumStudies:  1,
+		NumPatients: 1,
+		Modality:    modalities.CT,
+		Quiet:       true,
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(outputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+
+	n, err := internaldicom.ExportJSON(outputDir, true)
+	if err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("exported %d instances, want 4", n)
+	}
+
+	jsonFiles, _ := filepath.Glob(filepath.Join(outputDir, internaldicom.JSONDir, "PT*", "ST*", "SE*", "IM*.json"))
+	if len(jsonFiles) != 4 {
+		t.Fatalf("found %d JSON files, want 4", len(jsonFiles))
+	}
+
+	data, err := os.ReadFile(jsonFiles[0])
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", jsonFiles[0], err)
+	}
+	var obj map[string]struct {
+		VR    string            `json:"vr"`
+		Value []json.RawMessage `json:"Value"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		t.Fatalf("Invalid JSON in %s: %v", jsonFiles[0], err)
+	}
+
+	modality := obj["00080060"]
+	if modality.VR != "CS" || len(modality.Value) != 1 || string(modality.Value[0]) != `"CT"` {
+		t.Errorf("Modality = %+v, want CS \"CT\"", modality)
+	}
+	if patientID := obj["00100020"]; patientID.VR != "LO" || len(patientID.Value) != 1 {
+		t.Errorf("PatientID = %+v, want one LO value", patientID)
+	}
+	if _, ok := obj["7FE00010"]; ok {
+		t.Error("PixelData should be left out of the metadata")
+	}
+	if _, ok := obj["00020010"]; ok {
+		t.Error("File Meta Information should be left out of the model")
+	}
+
+	t.Logf("✓ JSON export test passed")
+}
+
+// findElementByTag searches for an element with the given tag in a dataset
+func findElementByTag(ds dicom.Dataset, t tag.Tag) *dicom.Element {
+	for _, elem := range ds.Elements {
+		if elem.Tag == t {
+			return elem
+		}
+	}
+	return nil
+}
+
+// TestPseudonymMap tests that CSV identities are replaced in the files and
+// recorded in the encrypted map
+func TestPseudonymMap(t *testing.T) {
+	tmpDir := t.TempDir()
+	mapPath := filepath.Join(tmpDir, "map.enc")
+	patients, err := internaldicom.ParsePatientsCSV(strings.NewReader(
+		"MRN,Name,Accession\nMRN001,DOE^JOHN,A1001\nMRN002,MARTIN^CLAIRE,A1002\n"))
+	if err != nil {
+		t.Fatalf("ParsePatientsCSV failed: %v", err)
+	}
+
+	opts := internaldicom.GeneratorOptions{
+		NumImages:          2,
+		TotalSize:          "1MB",
+		OutputDir:          filepath.Join(tmpDir, "out"),
+		Seed:               42,
+		Modality:           "CT",
+		PredefinedPatients: patients,
+		PseudonymMapPath:   mapPath,
+		PseudonymKey:       []byte("secret"),
+		Quiet:              true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	plain, err := internaldicom.ReadPseudonymMap(mapPath, []byte("secret"))
+	if err != nil {
+		t.Fatalf("ReadPseudonymMap failed: %v", err)
+	}
+	pseudoIDs := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(plain)), "\n")[1:] {
+		fields := strings.Split(line, ",")
+		pseudoIDs[fields[4]] = true
+	}
+
+	for _, f := range files {
+		if f.PatientID == "MRN001" || f.PatientID == "MRN002" {
+			t.Errorf("%s keeps the CSV PatientID %s", f.Path, f.PatientID)
+		}
+		if !pseudoIDs[f.PatientID] {
+			t.Errorf("%s PatientID %s is not in the map", f.Path, f.PatientID)
+		}
+	}
+}
+
+// TestDeidChallenge tests that each identified file has a de-identified
+// version, and that the answer file records the planted leaks
+func TestDeidChallenge(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:   4,
+		TotalSize:   "2MB",
+		OutputDir:   filepath.Join(tmpDir, internaldicom.DeidIdentifiedDir),
+		Seed:        42,
+		NumStudies:  2,
+		NumPatients: 2,
+		Quiet:       true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(opts.OutputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+
+	answers, err := internaldicom.BuildDeidChallenge(tmpDir, internaldicom.DeidChallengeOptions{Seed: 42, LeakRate: 0.5})
+	if err != nil {
+		t.Fatalf("BuildDeidChallenge failed: %v", err)
+	}
+	if len(answers.Files) != len(files) {
+		t.Fatalf("got %d answers, want %d", len(answers.Files), len(files))
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, internaldicom.DeidAnswersFile)); err != nil {
+		t.Errorf("answer file not written: %v", err)
+	}
+
+	for _, a := range answers.Files {
+		identified, err := dicom.ParseFile(filepath.Join(tmpDir, a.Identified), nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", a.Identified, err)
+		}
+		deidentified, err := dicom.ParseFile(filepath.Join(tmpDir, a.Deidentified), nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", a.Deidentified, err)
+		}
+
+		name := elementString(identified, tag.PatientName)
+		leakedName := len(a.Leaks) > 0 && a.Leaks[0].Keyword == "PatientName"
+		if got := elementString(deidentified, tag.PatientName); (got == name) != leakedName {
+			t.Errorf("%s: PatientName = %q (identified %q, leaks %+v)", a.Deidentified, got, name, a.Leaks)
+		}
+		if elementString(deidentified, tag.SOPInstanceUID) == elementString(identified, tag.SOPInstanceUID) {
+			t.Errorf("%s: SOPInstanceUID not replaced", a.Deidentified)
+		}
+
+		idPixels, _ := identified.FindElementByTag(tag.PixelData)
+		deidPixels, _ := deidentified.FindElementByTag(tag.PixelData)
+		if idPixels == nil || deidPixels == nil || idPixels.Value.String() != deidPixels.Value.String() {
+			t.Errorf("%s: pixel data differs from %s", a.Deidentified, a.Identified)
+		}
+	}
+}
+
+// elementString returns the first string value of a tag, or "".
+func elementString(ds dicom.Dataset, t tag.Tag) string {
+	elem, err := ds.FindElementByTag(t)
+	if err != nil {
+		return ""
+	}
+	values, ok := elem.Value.GetValue().([]string)
+	if !ok || len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// elementInt returns the first integer value of a tag, or -1.
+func elementInt(ds dicom.Dataset, t tag.Tag) int {
+	elem, err := ds.FindElementByTag(t)
+	if err != nil {
+		return -1
+	}
+	values, ok := elem.Value.GetValue().([]int)
+	if !ok || len(values) == 0 {
+		return -1
+	}
+	return values[0]
+}
+
+// TestRTDose tests that the RT Dose grid shares the frame of reference of the
+// CT images and covers them
+func TestRTDose(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  12,
+		TotalSize:  "5MB",
+		OutputDir:  tmpDir,
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.CT,
+		RTDose:     true,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	var image, rtDose dicom.Dataset
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		if elementString(ds, tag.SOPClassUID) == internaldicom.RTDoseStorage {
+			rtDose = ds
+		} else if f.InstanceNumber == 1 {
+			image = ds
+		}
+	}
+	if rtDose.Elements == nil {
+		t.Fatal("no RT Dose generated")
+	}
+
+	if got, want := elementString(rtDose, tag.FrameOfReferenceUID), elementString(image, tag.FrameOfReferenceUID); got != want {
+		t.Errorf("RT Dose FrameOfReferenceUID = %s, want %s", got, want)
+	}
+	if got, want := elementString(rtDose, tag.ImagePositionPatient), elementString(image, tag.ImagePositionPatient); got != want {
+		t.Errorf("RT Dose ImagePositionPatient = %s, want first slice %s", got, want)
+	}
+	if elementString(rtDose, tag.Modality) != "RTDOSE" || elementString(rtDose, tag.DoseUnits) != "GY" {
+		t.Error("missing RTDOSE modality or GY dose units")
+	}
+
+	offsets, _ := rtDose.FindElementByTag(tag.GridFrameOffsetVector)
+	pixels, _ := rtDose.FindElementByTag(tag.PixelData)
+	if offsets == nil || pixels == nil {
+		t.Fatal("missing GridFrameOffsetVector or PixelData")
+	}
+	numFrames := len(dicom.MustGetPixelDataInfo(pixels.Value).Frames)
+	if got := len(offsets.Value.GetValue().([]string)); got != numFrames || elementString(rtDose, tag.NumberOfFrames) != fmt.Sprint(numFrames) {
+		t.Errorf("%d frame offsets, NumberOfFrames %s, %d frames", got, elementString(rtDose, tag.NumberOfFrames), numFrames)
+	}
+}
+
+// TestRTPlan tests that the RT Plan, RT Structure Set and RT Dose of a CT
+// study reference each other and the CT images
+func TestRTPlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  12,
+		TotalSize:  "5MB",
+		OutputDir:  tmpDir,
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.CT,
+		RTPlan:     true,
+		RTDose:     true,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	images := make(map[string]bool)
+	byClass := make(map[string]dicom.Dataset)
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		switch class := elementString(ds, tag.SOPClassUID); class {
+		case internaldicom.RTPlanStorage, internaldicom.RTStructureSetStorage, internaldicom.RTDoseStorage:
+			byClass[class] = ds
+		default:
+			images[f.SOPInstanceUID] = true
+		}
+	}
+	plan, structureSet, dose := byClass[internaldicom.RTPlanStorage], byClass[internaldicom.RTStructureSetStorage], byClass[internaldicom.RTDoseStorage]
+	if plan.Elements == nil || structureSet.Elements == nil || dose.Elements == nil {
+		t.Fatalf("missing RT objects: %d of 3 generated", len(byClass))
+	}
+
+	firstItem := func(ds dicom.Dataset, seq tag.Tag) dicom.Dataset {
+		elem, err := ds.FindElementByTag(seq)
+		if err != nil {
+			t.Fatalf("missing %v", seq)
+		}
+		items := elem.Value.GetValue().([]*dicom.SequenceItemValue)
+		return dicom.Dataset{Elements: items[0].GetValue().([]*dicom.Element)}
+	}
+
+	if got, want := elementString(firstItem(plan, tag.ReferencedStructureSetSequence), tag.ReferencedSOPInstanceUID), elementString(structureSet, tag.SOPInstanceUID); got != want {
+		t.Errorf("RT Plan references structure set %s, want %s", got, want)
+	}
+	if got, want := elementString(firstItem(dose, tag.ReferencedRTPlanSequence), tag.ReferencedSOPInstanceUID), elementString(plan, tag.SOPInstanceUID); got != want {
+		t.Errorf("RT Dose references plan %s, want %s", got, want)
+	}
+	if elementString(plan, tag.Modality) != "RTPLAN" || elementString(structureSet, tag.Modality) != "RTSTRUCT" {
+		t.Error("missing RTPLAN or RTSTRUCT modality")
+	}
+
+	fractionGroup := firstItem(plan, tag.FractionGroupSequence)
+	beams, _ := plan.FindElementByTag(tag.BeamSequence)
+	if got := fmt.Sprint(len(beams.Value.GetValue().([]*dicom.SequenceItemValue))); got != elementString(fractionGroup, tag.NumberOfBeams) {
+		t.Errorf("%s beams, NumberOfBeams %s", got, elementString(fractionGroup, tag.NumberOfBeams))
+	}
+
+	// Every contour lies on a CT image
+	roiContour := firstItem(structureSet, tag.ROIContourSequence)
+	contours, _ := roiContour.FindElementByTag(tag.ContourSequence)
+	if contours == nil {
+		t.Fatal("missing ContourSequence")
+	}
+	for _, item := range contours.Value.GetValue().([]*dicom.SequenceItemValue) {
+		contour := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+		uid := elementString(firstItem(contour, tag.ContourImageSequence), tag.ReferencedSOPInstanceUID)
+		if !images[uid] {
+			t.Errorf("contour references %s, not a generated image", uid)
+		}
+	}
+}
+
+// TestSegmentation tests that the SEG frames reference the CT slices they
+// segment, and that the packed pixel data matches the frame count
+func TestSegmentation(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:   12,
+		TotalSize:   "5MB",
+		OutputDir:   tmpDir,
+		Seed:        42,
+		NumStudies:  1,
+		Modality:    modalities.CT,
+		SegSegments: 3,
+		Quiet:       true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	images := make(map[string]bool)
+	var seg dicom.Dataset
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		if elementString(ds, tag.SOPClassUID) == internaldicom.SegmentationStorage {
+			seg = ds
+		} else {
+			images[f.SOPInstanceUID] = true
+		}
+	}
+	if seg.Elements == nil {
+		t.Fatal("no SEG generated")
+	}
+	if elementString(seg, tag.Modality) != "SEG" || elementString(seg, tag.SegmentationType) != "BINARY" {
+		t.Error("missing SEG modality or BINARY segmentation type")
+	}
+
+	segments, _ := seg.FindElementByTag(tag.SegmentSequence)
+	if got := len(segments.Value.GetValue().([]*dicom.SequenceItemValue)); got != 3 {
+		t.Errorf("%d segments, want 3", got)
+	}
+
+	perFrame, _ := seg.FindElementByTag(tag.PerFrameFunctionalGroupsSequence)
+	frames := perFrame.Value.GetValue().([]*dicom.SequenceItemValue)
+	if elementString(seg, tag.NumberOfFrames) != fmt.Sprint(len(frames)) {
+		t.Errorf("NumberOfFrames %s, %d per-frame groups", elementString(seg, tag.NumberOfFrames), len(frames))
+	}
+	for _, item := range frames {
+		group := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+		derivation, _ := group.FindElementByTag(tag.DerivationImageSequence)
+		derivationItem := dicom.Dataset{Elements: derivation.Value.GetValue().([]*dicom.SequenceItemValue)[0].GetValue().([]*dicom.Element)}
+		source, _ := derivationItem.FindElementByTag(tag.SourceImageSequence)
+		sourceItem := dicom.Dataset{Elements: source.Value.GetValue().([]*dicom.SequenceItemValue)[0].GetValue().([]*dicom.Element)}
+		if uid := elementString(sourceItem, tag.ReferencedSOPInstanceUID); !images[uid] {
+			t.Errorf("frame references %s, not a generated image", uid)
+		}
+	}
+
+	// Every frame segments some pixels
+	pixels, _ := seg.FindElementByTag(tag.PixelData)
+	decoded := dicom.MustGetPixelDataInfo(pixels.Value).Frames
+	if len(decoded) != len(frames) {
+		t.Fatalf("%d decoded frames, want %d", len(decoded), len(frames))
+	}
+	for i, f := range decoded {
+		set := 0
+		for _, v := range f.NativeData.RawDataSlice().([]int) {
+			set += v
+		}
+		if set == 0 {
+			t.Errorf("frame %d is empty", i+1)
+		}
+	}
+}
+
+// TestGSPS tests that the presentation state references the images it
+// applies to, and carries the modality LUT of CT images
+func TestGSPS(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  12,
+		TotalSize:  "5MB",
+		OutputDir:  tmpDir,
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.CT,
+		GSPS:       true,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	images := make(map[string]bool)
+	var pr dicom.Dataset
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		if elementString(ds, tag.SOPClassUID) == internaldicom.GrayscaleSoftcopyPresentationStateStorage {
+			pr = ds
+		} else {
+			images[f.SOPInstanceUID] = true
+		}
+	}
+	if pr.Elements == nil {
+		t.Fatal("no presentation state generated")
+	}
+	if elementString(pr, tag.Modality) != "PR" || elementString(pr, tag.RescaleType) != "HU" {
+		t.Error("missing PR modality or CT modality LUT")
+	}
+
+	items := func(ds dicom.Dataset, seq tag.Tag) []dicom.Dataset {
+		elem, err := ds.FindElementByTag(seq)
+		if err != nil {
+			t.Fatalf("missing %v", seq)
+		}
+		var result []dicom.Dataset
+		for _, item := range elem.Value.GetValue().([]*dicom.SequenceItemValue) {
+			result = append(result, dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)})
+		}
+		return result
+	}
+
+	referenced := items(items(pr, tag.ReferencedSeriesSequence)[0], tag.ReferencedImageSequence)
+	for _, ref := range referenced {
+		if uid := elementString(ref, tag.ReferencedSOPInstanceUID); !images[uid] {
+			t.Errorf("presentation state references %s, not a generated image", uid)
+		}
+	}
+
+	layers := make(map[string]bool)
+	for _, layer := range items(pr, tag.GraphicLayerSequence) {
+		layers[elementString(layer, tag.GraphicLayer)] = true
+	}
+	for _, annotation := range items(pr, tag.GraphicAnnotationSequence) {
+		if layer := elementString(annotation, tag.GraphicLayer); !layers[layer] {
+			t.Errorf("annotation on undeclared layer %q", layer)
+		}
+	}
+	if len(items(pr, tag.SoftcopyVOILUTSequence)) != 1 || len(items(pr, tag.DisplayedAreaSelectionSequence)) != 1 {
+		t.Error("missing window or displayed area")
+	}
+}
+
+// TestReroot tests that re-rooting moves every instance UID under the new
+// root and keeps the references between files
+func TestReroot(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join(tmpDir, "input")
+	outputDir := filepath.Join(tmpDir, "output")
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  6,
+		TotalSize:  "2MB",
+		OutputDir:  inputDir,
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.CT,
+		GSPS:       true,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(inputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "notes.txt"), []byte("not DICOM\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const root = "1.2.3.4.5"
+	mapFile := filepath.Join(tmpDir, "uids.csv")
+	report, err := internaldicom.RerootDirectory(inputDir, outputDir, internaldicom.RerootOptions{Root: root, MapFile: mapFile})
+	if err != nil {
+		t.Fatalf("RerootDirectory failed: %v", err)
+	}
+	if report.Rewritten != len(files)+1 || report.Copied != 1 {
+		t.Errorf("rewrote %d and copied %d files, want %d and 1", report.Rewritten, report.Copied, len(files)+1)
+	}
+
+	parse := func(dir, rel string) dicom.Dataset {
+		ds, err := dicom.ParseFile(filepath.Join(dir, rel), nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", rel, err)
+		}
+		return ds
+	}
+	underRoot := func(uid string) bool { return strings.HasPrefix(uid, root+".") }
+
+	instances, err := filepath.Glob(filepath.Join(inputDir, "PT*", "ST*", "SE*", "IM*"))
+	if err != nil || len(instances) != len(files) {
+		t.Fatalf("found %d instances, want %d (%v)", len(instances), len(files), err)
+	}
+	images := make(map[string]bool)
+	var pr dicom.Dataset
+	for _, path := range instances {
+		rel, err := filepath.Rel(inputDir, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, after := parse(inputDir, rel), parse(outputDir, rel)
+		for _, uidTag := range []tag.Tag{tag.StudyInstanceUID, tag.SeriesInstanceUID, tag.SOPInstanceUID} {
+			if uid := elementString(after, uidTag); !underRoot(uid) {
+				t.Errorf("%s: %v = %s, not under %s", rel, uidTag, uid, root)
+			}
+		}
+		if elementString(after, tag.SOPClassUID) != elementString(before, tag.SOPClassUID) ||
+			elementString(after, tag.TransferSyntaxUID) != elementString(before, tag.TransferSyntaxUID) {
+			t.Errorf("%s: standard UIDs changed", rel)
+		}
+
+		if elementString(after, tag.SOPClassUID) == internaldicom.GrayscaleSoftcopyPresentationStateStorage {
+			pr = after
+			continue
+		}
+		images[elementString(after, tag.SOPInstanceUID)] = true
+		beforePixels, _ := before.FindElementByTag(tag.PixelData)
+		afterPixels, _ := after.FindElementByTag(tag.PixelData)
+		if beforePixels == nil || afterPixels == nil || beforePixels.Value.String() != afterPixels.Value.String() {
+			t.Errorf("%s: pixel data changed", rel)
+		}
+	}
+
+	// References in sequences follow the new UIDs
+	series, _ := pr.FindElementByTag(tag.ReferencedSeriesSequence)
+	for _, item := range series.Value.GetValue().([]*dicom.SequenceItemValue) {
+		seriesItem := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+		refs, _ := seriesItem.FindElementByTag(tag.ReferencedImageSequence)
+		for _, ref := range refs.Value.GetValue().([]*dicom.SequenceItemValue) {
+			if uid := elementString(dicom.Dataset{Elements: ref.GetValue().([]*dicom.Element)}, tag.ReferencedSOPInstanceUID); !images[uid] {
+				t.Errorf("presentation state references %s, not a re-rooted image", uid)
+			}
+		}
+	}
+
+	dicomdir := parse(outputDir, "DICOMDIR")
+	records, _ := dicomdir.FindElementByTag(tag.DirectoryRecordSequence)
+	for _, item := range records.Value.GetValue().([]*dicom.SequenceItemValue) {
+		record := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+		if uid := elementString(record, tag.ReferencedSOPInstanceUIDInFile); uid != "" && !underRoot(uid) {
+			t.Errorf("DICOMDIR references %s, not under %s", uid, root)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(outputDir, "notes.txt")); err != nil || string(data) != "not DICOM\n" {
+		t.Errorf("non-DICOM file not copied: %v", err)
+	}
+	mapping, err := os.ReadFile(mapFile)
+	if err != nil {
+		t.Fatalf("read UID map: %v", err)
+	}
+	if lines := strings.Count(string(mapping), "\n"); lines != report.UIDs+1 {
+		t.Errorf("UID map has %d lines, want %d", lines, report.UIDs+1)
+	}
+
+	if _, err := internaldicom.RerootDirectory(inputDir, filepath.Join(inputDir, "out"), internaldicom.RerootOptions{Root: root}); err == nil {
+		t.Error("re-rooting into the input directory should fail")
+	}
+	if _, err := internaldicom.RerootDirectory(inputDir, filepath.Join(tmpDir, "bad"), internaldicom.RerootOptions{Root: "1.02"}); err == nil {
+		t.Error("invalid root should fail")
+	}
+}
+
+// TestDoseSR tests that each CT study gets a dose report whose acquisitions
+// match the generated series
+func TestDoseSR(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  12,
+		TotalSize:  "5MB",
+		OutputDir:  tmpDir,
+		Seed:       42,
+		NumStudies: 2,
+		Modality:   modalities.CT,
+		DoseSR:     true,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	kvps := make(map[string]string)
+	var reports []dicom.Dataset
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		if elementString(ds, tag.SOPClassUID) == sr.XRayRadiationDoseSRStorage {
+			reports = append(reports, ds)
+		} else {
+			kvps[f.StudyUID] = elementString(ds, tag.KVP)
+		}
+	}
+	if len(reports) != opts.NumStudies {
+		t.Fatalf("got %d dose reports, want %d", len(reports), opts.NumStudies)
+	}
+
+	for _, report := range reports {
+		if elementString(report, tag.Modality) != "SR" {
+			t.Error("dose report modality is not SR")
+		}
+		template, _ := report.FindElementByTag(tag.ContentTemplateSequence)
+		item := dicom.Dataset{Elements: template.Value.GetValue().([]*dicom.SequenceItemValue)[0].GetValue().([]*dicom.Element)}
+		if got := elementString(item, tag.TemplateIdentifier); got != "10011" {
+			t.Errorf("template = %s, want 10011", got)
+		}
+
+		// KVP values reported in the content tree match the images
+		want := kvps[elementString(report, tag.StudyInstanceUID)]
+		found := 0
+		var walk func(elements []*dicom.Element)
+		walk = func(elements []*dicom.Element) {
+			ds := dicom.Dataset{Elements: elements}
+			concept, err := ds.FindElementByTag(tag.ConceptNameCodeSequence)
+			if err == nil {
+				code := dicom.Dataset{Elements: concept.Value.GetValue().([]*dicom.SequenceItemValue)[0].GetValue().([]*dicom.Element)}
+				if elementString(code, tag.CodeValue) == sr.CodeKVP.Value {
+					measured, _ := ds.FindElementByTag(tag.MeasuredValueSequence)
+					value := dicom.Dataset{Elements: measured.Value.GetValue().([]*dicom.SequenceItemValue)[0].GetValue().([]*dicom.Element)}
+					if got := elementString(value, tag.NumericValue); got != want {
+						t.Errorf("reported KVP %s, images use %s", got, want)
+					}
+					found++
+				}
+			}
+			if content, err := ds.FindElementByTag(tag.ContentSequence); err == nil {
+				for _, child := range content.Value.GetValue().([]*dicom.SequenceItemValue) {
+					walk(child.GetValue().([]*dicom.Element))
+				}
+			}
+		}
+		walk(report.Elements)
+		if found < 2 {
+			t.Errorf("found %d KVP values, want a localizer and at least one acquisition", found)
+		}
+	}
+}
+
+// TestSplit tests that splitting a study writes one new study per group of
+// series, with new UIDs and the original pixel data
+func TestSplit(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join(tmpDir, "input")
+	outputDir := filepath.Join(tmpDir, "output")
+	opts := internaldicom.GeneratorOptions{
+		NumImages:      9,
+		TotalSize:      "3MB",
+		OutputDir:      inputDir,
+		Seed:           42,
+		NumStudies:     1,
+		SeriesPerStudy: util.SeriesRange{Min: 3, Max: 3},
+		Modality:       modalities.CT,
+		Quiet:          true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(inputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+
+	originals := make(map[string]bool)
+	for _, f := range files {
+		originals[f.StudyUID] = true
+		originals[f.SeriesUID] = true
+		originals[f.SOPInstanceUID] = true
+	}
+
+	report, err := internaldicom.SplitStudies(inputDir, outputDir, internaldicom.SplitOptions{Groups: [][]int{{1, 3}, {2}, {7}}})
+	if err != nil {
+		t.Fatalf("SplitStudies failed: %v", err)
+	}
+	if report.Studies != 2 || report.Files != len(files) || report.Ignored != 1 {
+		t.Errorf("report = %+v, want 2 studies, %d files and the DICOMDIR ignored", report, len(files))
+	}
+
+	studies, _ := filepath.Glob(filepath.Join(outputDir, "PT000000", "ST*"))
+	if len(studies) != 2 {
+		t.Fatalf("found %d studies, want 2", len(studies))
+	}
+	wantSeries := [][]string{{"1", "3"}, {"2"}}
+	for i, study := range studies {
+		instances, _ := filepath.Glob(filepath.Join(study, "SE*", "IM*"))
+		studyUIDs := make(map[string]bool)
+		seriesNumbers := make(map[string]bool)
+		for _, path := range instances {
+			ds, err := dicom.ParseFile(path, nil)
+			if err != nil {
+				t.Fatalf("parse %s: %v", path, err)
+			}
+			studyUIDs[elementString(ds, tag.StudyInstanceUID)] = true
+			seriesNumbers[elementString(ds, tag.SeriesNumber)] = true
+			for _, uidTag := range []tag.Tag{tag.StudyInstanceUID, tag.SeriesInstanceUID, tag.SOPInstanceUID} {
+				if originals[elementString(ds, uidTag)] {
+					t.Errorf("%s: %v kept its original UID", path, uidTag)
+				}
+			}
+			if pixels, _ := ds.FindElementByTag(tag.PixelData); pixels == nil {
+				t.Errorf("%s: no pixel data", path)
+			}
+		}
+		if len(studyUIDs) != 1 {
+			t.Errorf("%s: %d Study Instance UIDs, want 1", study, len(studyUIDs))
+		}
+		if len(seriesNumbers) != len(wantSeries[i]) {
+			t.Errorf("%s: series %v, want %v", study, seriesNumbers, wantSeries[i])
+		}
+		for _, n := range wantSeries[i] {
+			if !seriesNumbers[n] {
+				t.Errorf("%s: series %s missing", study, n)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "DICOMDIR")); err != nil {
+		t.Errorf("no DICOMDIR: %v", err)
+	}
+
+	if _, err := internaldicom.SplitStudies(inputDir, filepath.Join(tmpDir, "none"), internaldicom.SplitOptions{Groups: [][]int{{9}}}); err == nil {
+		t.Error("groups matching no series should fail")
+	}
+}
+
+// TestMerge tests that merging writes one study with the series of all the
+// sources, renumbered, under the patient of the first study
+func TestMerge(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputDir := filepath.Join(tmpDir, "input")
+	outputDir := filepath.Join(tmpDir, "output")
+	opts := internaldicom.GeneratorOptions{
+		NumImages:      8,
+		TotalSize:      "3MB",
+		OutputDir:      inputDir,
+		Seed:           42,
+		NumStudies:     2,
+		NumPatients:    2,
+		SeriesPerStudy: util.SeriesRange{Min: 2, Max: 2},
+		Modality:       modalities.MR,
+		Quiet:          true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(inputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+
+	report, err := internaldicom.MergeStudies([]string{inputDir}, outputDir, internaldicom.MergeOptions{})
+	if err != nil {
+		t.Fatalf("MergeStudies failed: %v", err)
+	}
+	if report.Studies != 2 || report.Series != 4 || report.Files != len(files) || report.Ignored != 1 {
+		t.Errorf("report = %+v, want 2 studies, 4 series, %d files and the DICOMDIR ignored", report, len(files))
+	}
+
+	instances, _ := filepath.Glob(filepath.Join(outputDir, "PT*", "ST*", "SE*", "IM*"))
+	if len(instances) != len(files) {
+		t.Fatalf("found %d instances, want %d", len(instances), len(files))
+	}
+	studyUIDs := make(map[string]bool)
+	patientIDs := make(map[string]bool)
+	seriesUIDs := make(map[string]string)
+	for _, path := range instances {
+		ds, err := dicom.ParseFile(path, nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		studyUIDs[elementString(ds, tag.StudyInstanceUID)] = true
+		patientIDs[elementString(ds, tag.PatientID)] = true
+		seriesUIDs[elementString(ds, tag.SeriesInstanceUID)] = elementString(ds, tag.SeriesNumber)
+	}
+	if len(studyUIDs) != 1 || studyUIDs[files[0].StudyUID] {
+		t.Errorf("Study Instance UIDs %v, want one new UID", studyUIDs)
+	}
+	if len(patientIDs) != 1 || !patientIDs[files[0].PatientID] {
+		t.Errorf("Patient IDs %v, want %s only", patientIDs, files[0].PatientID)
+	}
+	numbers := make(map[string]bool)
+	for _, n := range seriesUIDs {
+		numbers[n] = true
+	}
+	for _, n := range []string{"1", "2", "3", "4"} {
+		if !numbers[n] {
+			t.Errorf("series numbers %v, want 1 to 4", numbers)
+			break
+		}
+	}
+
+	if _, err := internaldicom.MergeStudies([]string{filepath.Join(inputDir, "PT000000")}, filepath.Join(tmpDir, "single"), internaldicom.MergeOptions{}); err == nil {
+		t.Error("merging a single study should fail")
+	}
+}
+
+// TestUSCine tests that US cine loops are multi-frame instances with frame
+// timing and frames that change gradually
+func TestUSCine(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:       2,
+		TotalSize:       "2MB",
+		OutputDir:       tmpDir,
+		Seed:            42,
+		NumStudies:      1,
+		Modality:        modalities.US,
+		CineFrames:      10,
+		FrameTimeVector: true,
+		Quiet:           true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	ds, err := dicom.ParseFile(files[0].Path, nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := elementString(ds, tag.SOPClassUID); got != modalities.USMultiFrameSOPClassUID {
+		t.Errorf("SOPClassUID = %s, want Ultrasound Multi-frame Image Storage", got)
+	}
+	if got := elementString(ds, tag.NumberOfFrames); got != "10" {
+		t.Errorf("NumberOfFrames = %s, want 10", got)
+	}
+	if elementString(ds, tag.FrameTime) == "" || elementString(ds, tag.CineRate) == "" {
+		t.Error("missing FrameTime or CineRate")
+	}
+	vector, err := ds.FindElementByTag(tag.FrameTimeVector)
+	if err != nil || len(vector.Value.GetValue().([]string)) != 10 {
+		t.Errorf("FrameTimeVector should have one value per frame (%v)", err)
+	}
+
+	pixels, _ := ds.FindElementByTag(tag.PixelData)
+	frames := dicom.MustGetPixelDataInfo(pixels.Value).Frames
+	if len(frames) != 10 {
+		t.Fatalf("%d frames, want 10", len(frames))
+	}
+	// Mean absolute difference with the first frame grows with the distance
+	// between frames, consecutive frames differing slightly
+	difference := func(a, b int) float64 {
+		pa := frames[a].NativeData.RawDataSlice().([]uint8)
+		pb := frames[b].NativeData.RawDataSlice().([]uint8)
+		var sum int
+		for i := range pa {
+			if pa[i] > pb[i] {
+				sum += int(pa[i] - pb[i])
+			} else {
+				sum += int(pb[i] - pa[i])
+			}
+		}
+		return float64(sum) / float64(len(pa))
+	}
+	next, far := difference(0, 1), difference(0, 5)
+	if next == 0 {
+		t.Error("consecutive frames are identical")
+	}
+	if next >= far {
+		t.Errorf("difference with the next frame %.2f, want below the difference 5 frames later %.2f", next, far)
+	}
+}
+
+// TestUSColor tests that color US images carry color Doppler flow, in RGB
+// and in YBR_FULL_422 with two bytes per pixel
+func TestUSColor(t *testing.T) {
+	for _, photometric := range []string{internaldicom.PhotometricRGB, internaldicom.PhotometricYBRFull422} {
+		t.Run(photometric, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			opts := internaldicom.GeneratorOptions{
+				NumImages:  2,
+				TotalSize:  "2MB",
+				OutputDir:  tmpDir,
+				Seed:       42,
+				NumStudies: 1,
+				Modality:   modalities.US,
+				USColor:    photometric,
+				Quiet:      true,
+			}
+			files, err := internaldicom.GenerateDICOMSeries(opts)
+			if err != nil {
+				t.Fatalf("GenerateDICOMSeries failed: %v", err)
+			}
+
+			ds, err := dicom.ParseFile(files[0].Path, nil, dicom.SkipProcessingPixelDataValue())
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := elementString(ds, tag.PhotometricInterpretation); got != photometric {
+				t.Errorf("PhotometricInterpretation = %s, want %s", got, photometric)
+			}
+			if got := elementInt(ds, tag.SamplesPerPixel); got != 3 {
+				t.Errorf("SamplesPerPixel = %d, want 3", got)
+			}
+			if got := elementInt(ds, tag.PlanarConfiguration); got != 0 {
+				t.Errorf("PlanarConfiguration = %d, want 0", got)
+			}
+			if _, err := ds.FindElementByTag(tag.WindowCenter); err == nil {
+				t.Error("color image should have no WindowCenter")
+			}
+
+			pixels := elementInt(ds, tag.Rows) * elementInt(ds, tag.Columns)
+			elem, _ := ds.FindElementByTag(tag.PixelData)
+			data := dicom.MustGetPixelDataInfo(elem.Value).UnprocessedValueData
+			bytesPerPixel := 3
+			if photometric == internaldicom.PhotometricYBRFull422 {
+				bytesPerPixel = 2
+			}
+			if len(data) != pixels*bytesPerPixel {
+				t.Fatalf("pixel data of %d bytes, want %d", len(data), pixels*bytesPerPixel)
+			}
+			if photometric == internaldicom.PhotometricRGB {
+				colored := 0
+				for i := 0; i < len(data); i += 3 {
+					if data[i] != data[i+1] || data[i+1] != data[i+2] {
+						colored++
+					}
+				}
+				if colored == 0 {
+					t.Error("no color Doppler pixel")
+				}
+			}
+		})
+	}
+}
+
+func TestUSPaletteColor(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  2,
+		TotalSize:  "1MB",
+		OutputDir:  tmpDir,
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.US,
+		USColor:    internaldicom.PhotometricPaletteColor,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	ds, err := dicom.ParseFile(files[0].Path, nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := elementString(ds, tag.PhotometricInterpretation); got != "PALETTE COLOR" {
+		t.Errorf("PhotometricInterpretation = %s, want PALETTE COLOR", got)
+	}
+	if got := elementInt(ds, tag.SamplesPerPixel); got != 1 {
+		t.Errorf("SamplesPerPixel = %d, want 1", got)
+	}
+	entries := elementInt(ds, tag.RedPaletteColorLookupTableDescriptor)
+	for _, tg := range []tag.Tag{tag.RedPaletteColorLookupTableData, tag.GreenPaletteColorLookupTableData, tag.BluePaletteColorLookupTableData} {
+		elem, err := ds.FindElementByTag(tg)
+		if err != nil {
+			t.Fatalf("missing %v", tg)
+		}
+		if got := len(elem.Value.GetValue().([]byte)); got != 2*entries {
+			t.Errorf("%v has %d bytes, want %d 16-bit entries", tg, got, entries)
+		}
+	}
+
+	// Pixels are palette indices; the Doppler box uses the entries after the
+	// gray ramp
+	elem, _ := ds.FindElementByTag(tag.PixelData)
+	native, err := dicom.MustGetPixelDataInfo(elem.Value).Frames[0].GetNativeFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxIndex := 0
+	for _, v := range native.RawDataSlice().([]uint8) {
+		maxIndex = max(maxIndex, int(v))
+	}
+	if maxIndex >= entries {
+		t.Errorf("pixel value %d beyond the %d palette entries", maxIndex, entries)
+	}
+	if maxIndex < 192 {
+		t.Error("no color Doppler pixel")
+	}
+}
+
+func TestPhotometric(t *testing.T) {
+	// photometricValues returns the PhotometricInterpretation values of a
+	// file, by series number
+	photometricValues := func(t *testing.T, opts internaldicom.GeneratorOptions) map[int][]string {
+		t.Helper()
+		opts.OutputDir, opts.Seed, opts.NumStudies, opts.TotalSize, opts.Quiet = t.TempDir(), 42, 1, "2MB", true
+		files, err := internaldicom.GenerateDICOMSeries(opts)
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		values := make(map[int][]string)
+		for _, file := range files {
+			ds, err := dicom.ParseFile(file.Path, nil, dicom.SkipPixelData())
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			var fileValues []string
+			for _, elem := range ds.Elements {
+				if elem.Tag == tag.PhotometricInterpretation {
+					fileValues = append(fileValues, elem.Value.GetValue().([]string)...)
+				}
+			}
+			if len(fileValues) != 1 {
+				t.Fatalf("%s has PhotometricInterpretation %v, want a single value", file.Path, fileValues)
+			}
+			values[file.SeriesNumber] = append(values[file.SeriesNumber], fileValues[0])
+		}
+		return values
+	}
+
+	t.Run("MG default", func(t *testing.T) {
+		for _, values := range photometricValues(t, internaldicom.GeneratorOptions{NumImages: 4, Modality: modalities.MG}) {
+			for _, v := range values {
+				if v != "MONOCHROME1" {
+					t.Errorf("PhotometricInterpretation = %s, want MONOCHROME1", v)
+				}
+			}
+		}
+	})
+
+	t.Run("MG forced MONOCHROME2", func(t *testing.T) {
+		opts := internaldicom.GeneratorOptions{NumImages: 4, Modality: modalities.MG, Photometric: internaldicom.PhotometricMonochrome2}
+		for _, values := range photometricValues(t, opts) {
+			for _, v := range values {
+				if v != "MONOCHROME2" {
+					t.Errorf("PhotometricInterpretation = %s, want MONOCHROME2", v)
+				}
+			}
+		}
+	})
+
+	t.Run("CT mixed", func(t *testing.T) {
+		opts := internaldicom.GeneratorOptions{
+			Modality:        modalities.CT,
+			Photometric:     internaldicom.PhotometricMixed,
+			SeriesPerStudy:  util.SeriesRange{Min: 3, Max: 3},
+			ImagesPerSeries: util.ImageRange{Min: 2, Max: 2},
+		}
+		values := photometricValues(t, opts)
+		if len(values) < 2 {
+			t.Fatalf("got %d series, want at least 2", len(values))
+		}
+		for seriesNum, seriesValues := range values {
+			want := "MONOCHROME2"
+			if seriesNum%2 == 1 {
+				want = "MONOCHROME1"
+			}
+			for _, v := range seriesValues {
+				if v != want {
+					t.Errorf("series %d: PhotometricInterpretation = %s, want %s", seriesNum, v, want)
+				}
+			}
+		}
+	})
+}
+
+func TestJPEGBaseline(t *testing.T) {
+	// jpegFrames generates a series encoded as JPEG Baseline and returns the
+	// dataset of its first file and its decoded frames
+	jpegFrames := func(t *testing.T, opts internaldicom.GeneratorOptions) (dicom.Dataset, []image.Image) {
+		t.Helper()
+		opts.OutputDir, opts.Seed, opts.NumStudies, opts.Quiet = t.TempDir(), 42, 1, true
+		opts.TransferSyntax = internaldicom.TransferSyntaxJPEGBaseline
+		files, err := internaldicom.GenerateDICOMSeries(opts)
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		ds, err := dicom.ParseFile(files[0].Path, nil)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if got := elementString(ds, tag.TransferSyntaxUID); got != internaldicom.TransferSyntaxJPEGBaseline {
+			t.Errorf("TransferSyntaxUID = %s, want JPEG Baseline", got)
+		}
+		if got := elementString(ds, tag.LossyImageCompression); got != "01" {
+			t.Errorf("LossyImageCompression = %s, want 01", got)
+		}
+		if got := elementInt(ds, tag.BitsAllocated); got != 8 {
+			t.Errorf("BitsAllocated = %d, want 8", got)
+		}
+		elem, err := ds.FindElementByTag(tag.PixelData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := dicom.MustGetPixelDataInfo(elem.Value)
+		if !info.IsEncapsulated {
+			t.Fatal("pixel data is not encapsulated")
+		}
+		var images []image.Image
+		for i, f := range info.Frames {
+			img, err := jpeg.Decode(bytes.NewReader(f.EncapsulatedData.Data))
+			if err != nil {
+				t.Fatalf("frame %d: jpeg.Decode failed: %v", i+1, err)
+			}
+			images = append(images, img)
+		}
+		if err := internaldicom.OrganizeFilesIntoDICOMDIR(opts.OutputDir, files, false); err != nil {
+			t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+		}
+		dicomdir, err := os.ReadFile(filepath.Join(opts.OutputDir, "DICOMDIR"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(dicomdir, []byte(internaldicom.TransferSyntaxJPEGBaseline)) {
+			t.Error("DICOMDIR does not reference the JPEG Baseline transfer syntax")
+		}
+		return ds, images
+	}
+
+	t.Run("CT", func(t *testing.T) {
+		ds, images := jpegFrames(t, internaldicom.GeneratorOptions{NumImages: 2, TotalSize: "1MB", Modality: modalities.CT})
+		if len(images) != 1 {
+			t.Fatalf("got %d frames, want 1", len(images))
+		}
+		if got := elementInt(ds, tag.Columns); images[0].Bounds().Dx() != got {
+			t.Errorf("decoded width %d, want Columns %d", images[0].Bounds().Dx(), got)
+		}
+		if _, ok := images[0].(*image.Gray); !ok {
+			t.Errorf("decoded %T, want grayscale", images[0])
+		}
+		if elementString(ds, tag.RescaleSlope) == "1" {
+			t.Error("RescaleSlope not scaled to the 8-bit range")
+		}
+	})
+
+	t.Run("US color cine", func(t *testing.T) {
+		opts := internaldicom.GeneratorOptions{NumImages: 1, TotalSize: "2MB", Modality: modalities.US, CineFrames: 4, USColor: internaldicom.PhotometricYBRFull422}
+		ds, images := jpegFrames(t, opts)
+		if len(images) != 4 {
+			t.Fatalf("got %d frames, want 4", len(images))
+		}
+		if got := elementString(ds, tag.PhotometricInterpretation); got != "YBR_FULL_422" {
+			t.Errorf("PhotometricInterpretation = %s, want YBR_FULL_422", got)
+		}
+		if _, ok := images[0].(*image.YCbCr); !ok {
+			t.Errorf("decoded %T, want YCbCr", images[0])
+		}
+	})
+}
+
+func TestNumericJitter(t *testing.T) {
+	// seriesValues returns the XRayTubeCurrent and KVP values of the
+	// instances of a CT series
+	seriesValues := func(t *testing.T, jitter float64) (currents, kvps map[string]bool) {
+		t.Helper()
+		opts := internaldicom.GeneratorOptions{
+			NumImages:     10,
+			TotalSize:     "2MB",
+			OutputDir:     t.TempDir(),
+			Seed:          42,
+			NumStudies:    1,
+			Modality:      modalities.CT,
+			NumericJitter: jitter,
+			Quiet:         true,
+		}
+		files, err := internaldicom.GenerateDICOMSeries(opts)
+		if err != nil {
+			t.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+		currents, kvps = make(map[string]bool), make(map[string]bool)
+		for _, file := range files {
+			ds, err := dicom.ParseFile(file.Path, nil, dicom.SkipPixelData())
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			currents[elementString(ds, tag.XRayTubeCurrent)] = true
+			kvps[elementString(ds, tag.KVP)] = true
+		}
+		return currents, kvps
+	}
+
+	currents, _ := seriesValues(t, 0)
+	if len(currents) != 1 {
+		t.Errorf("got %d tube currents without jitter, want 1 per series", len(currents))
+	}
+
+	currents, kvps := seriesValues(t, 0.2)
+	if len(currents) < 5 {
+		t.Errorf("got %d distinct tube currents over 10 instances, want per instance variation", len(currents))
+	}
+	if len(kvps) != 1 {
+		t.Errorf("got %d KVP values, want the series value", len(kvps))
+	}
+
+	opts := internaldicom.GeneratorOptions{NumImages: 1, TotalSize: "1MB", OutputDir: t.TempDir(), NumStudies: 1, NumericJitter: 0.8, Quiet: true}
+	if _, err := internaldicom.GenerateDICOMSeries(opts); err == nil {
+		t.Error("a jitter above 50% should be rejected")
+	}
+}
+
+func TestRadiographyDoseIndicators(t *testing.T) {
+	for _, modality := range []modalities.Modality{modalities.CR, modalities.DX} {
+		t.Run(string(modality), func(t *testing.T) {
+			opts := internaldicom.GeneratorOptions{
+				NumImages:     4,
+				TotalSize:     "2MB",
+				OutputDir:     t.TempDir(),
+				Seed:          42,
+				NumStudies:    1,
+				Modality:      modality,
+				NumericJitter: 0.2,
+				Quiet:         true,
+			}
+			files, err := internaldicom.GenerateDICOMSeries(opts)
+			if err != nil {
+				t.Fatalf("GenerateDICOMSeries failed: %v", err)
+			}
+			for _, file := range files {
+				ds, err := dicom.ParseFile(file.Path, nil, dicom.SkipPixelData())
+				if err != nil {
+					t.Fatalf("parse: %v", err)
+				}
+				value := func(tg tag.Tag) float64 {
+					v, err := strconv.ParseFloat(elementString(ds, tg), 64)
+					if err != nil {
+						t.Fatalf("%v: %v", tg, err)
+					}
+					return v
+				}
+				kvp, ei, target, di, dap := value(tag.KVP), value(tag.ExposureIndex), value(tag.TargetExposureIndex), value(tag.DeviationIndex), value(tag.ImageAndFluoroscopyAreaDoseProduct)
+				if kvp < 60 || kvp > 140 || ei <= 0 || target <= 0 || dap <= 0 {
+					t.Errorf("KVP %g, EI %g, target %g, DAP %g", kvp, ei, target, dap)
+				}
+				if want := 10 * math.Log10(ei/target); math.Abs(di-want) > 0.02 {
+					t.Errorf("DeviationIndex = %g, want 10*log10(%g/%g) = %.2f", di, ei, target, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRadiographyGeometry(t *testing.T) {
+	for _, modality := range []modalities.Modality{modalities.CR, modalities.DX} {
+		t.Run(string(modality), func(t *testing.T) {
+			opts := internaldicom.GeneratorOptions{
+				NumImages:  2,
+				TotalSize:  "2MB",
+				OutputDir:  t.TempDir(),
+				Seed:       42,
+				NumStudies: 1,
+				Modality:   modality,
+				Quiet:      true,
+			}
+			files, err := internaldicom.GenerateDICOMSeries(opts)
+			if err != nil {
+				t.Fatalf("GenerateDICOMSeries failed: %v", err)
+			}
+			ds, err := dicom.ParseFile(files[0].Path, nil, dicom.SkipPixelData())
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if elementString(ds, tag.Grid) == "" || elementString(ds, tag.DetectorID) == "" {
+				t.Error("Grid and DetectorID should be set")
+			}
+			if got := elementString(ds, tag.CollimatorShape); got != "RECTANGULAR" {
+				t.Errorf("CollimatorShape = %s, want RECTANGULAR", got)
+			}
+			edge := func(tg tag.Tag) int {
+				v, err := strconv.Atoi(elementString(ds, tg))
+				if err != nil {
+					t.Fatalf("%v: %v", tg, err)
+				}
+				return v
+			}
+			rows, columns := elementInt(ds, tag.Rows), elementInt(ds, tag.Columns)
+			left, right := edge(tag.CollimatorLeftVerticalEdge), edge(tag.CollimatorRightVerticalEdge)
+			upper, lower := edge(tag.CollimatorUpperHorizontalEdge), edge(tag.CollimatorLowerHorizontalEdge)
+			if left < 1 || left >= right || right > columns || upper < 1 || upper >= lower || lower > rows {
+				t.Errorf("collimator %d-%d x %d-%d outside the %dx%d image", left, right, upper, lower, columns, rows)
+			}
+			if elementString(ds, tag.FieldOfViewDimensions) == "" {
+				t.Error("FieldOfViewDimensions should be set")
+			}
+		})
+	}
+}
+
+func TestImplicitVRLittleEndian(t *testing.T) {
+	for _, modality := range []modalities.Modality{modalities.CT, modalities.US} {
+		t.Run(string(modality), func(t *testing.T) {
+			// The same series in both transfer syntaxes
+			generate := func(transferSyntax string) dicom.Dataset {
+				opts := internaldicom.GeneratorOptions{
+					NumImages:        2,
+					TotalSize:        "1MB",
+					OutputDir:        t.TempDir(),
+					Seed:             42,
+					NumStudies:       1,
+					Modality:         modality,
+					TransferSyntax:   transferSyntax,
+					CorruptionConfig: corruption.Config{Types: []corruption.CorruptionType{corruption.SiemensCSA}},
+					Quiet:            true,
+				}
+				files, err := internaldicom.GenerateDICOMSeries(opts)
+				if err != nil {
+					t.Fatalf("GenerateDICOMSeries failed: %v", err)
+				}
+				ds, err := dicom.ParseFile(files[0].Path, nil)
+				if err != nil {
+					t.Fatalf("parse: %v", err)
+				}
+				return ds
+			}
+			explicit := generate(internaldicom.TransferSyntaxExplicitVRLittleEndian)
+			implicit := generate(internaldicom.TransferSyntaxImplicitVRLittleEndian)
+
+			if got := elementString(implicit, tag.TransferSyntaxUID); got != internaldicom.TransferSyntaxImplicitVRLittleEndian {
+				t.Errorf("TransferSyntaxUID = %s, want Implicit VR Little Endian", got)
+			}
+			for _, tg := range []tag.Tag{tag.PatientName, tag.StudyDate, tag.Modality, tag.PixelSpacing, tag.Rows, tag.Columns} {
+				want, _ := explicit.FindElementByTag(tg)
+				got, err := implicit.FindElementByTag(tg)
+				if err != nil || want.Value.String() != got.Value.String() {
+					t.Errorf("%v = %v, want %v", tg, got, want.Value)
+				}
+			}
+			want, _ := explicit.FindElementByTag(tag.PixelData)
+			got, err := implicit.FindElementByTag(tag.PixelData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Value.Equals(want.Value) {
+				t.Error("pixel data differs from the explicit VR file")
+			}
+		})
+	}
+
+	// Odd lengths in implicit VR: ImageComments, Tag(4) + VL(4), unpadded
+	opts := internaldicom.GeneratorOptions{
+		NumImages:        1,
+		TotalSize:        "1MB",
+		OutputDir:        t.TempDir(),
+		Seed:             42,
+		NumStudies:       1,
+		TransferSyntax:   internaldicom.TransferSyntaxImplicitVRLittleEndian,
+		CorruptionConfig: corruption.Config{Types: []corruption.CorruptionType{corruption.OddLengths}},
+		Quiet:            true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	data, err := os.ReadFile(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.Index(data, []byte{0x20, 0x00, 0x00, 0x40})
+	if i < 0 {
+		t.Fatal("no ImageComments element")
+	}
+	if vl := binary.LittleEndian.Uint32(data[i+4 : i+8]); vl%2 == 0 {
+		t.Errorf("ImageComments VL = %d, want odd", vl)
+	}
+
+	opts.OutputDir = t.TempDir()
+	opts.CorruptionConfig = corruption.Config{Types: []corruption.CorruptionType{corruption.MalformedLengths}}
+	if _, err := internaldicom.GenerateDICOMSeries(opts); err == nil {
+		t.Error("malformed-lengths corruption in implicit VR should fail")
+	}
+}
+
+func TestUltrasoundRegions(t *testing.T) {
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  2,
+		TotalSize:  "2MB",
+		OutputDir:  t.TempDir(),
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.US,
+		USColor:    internaldicom.PhotometricRGB,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ds, err := dicom.ParseFile(files[0].Path, nil, dicom.SkipPixelData())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	elem, err := ds.FindElementByTag(tag.SequenceOfUltrasoundRegions)
+	if err != nil {
+		t.Fatal("SequenceOfUltrasoundRegions is missing")
+	}
+	items := elem.Value.GetValue().([]*dicom.SequenceItemValue)
+	if len(items) != 2 {
+		t.Fatalf("got %d regions, want tissue and color flow", len(items))
+	}
+
+	spacing, err := strconv.ParseFloat(elementString(ds, tag.PixelSpacing), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, columns := elementInt(ds, tag.Rows), elementInt(ds, tag.Columns)
+	for i, item := range items {
+		region := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+		if elementInt(region, tag.RegionDataType) != i+1 {
+			t.Errorf("region %d: RegionDataType = %d, want %d", i+1, elementInt(region, tag.RegionDataType), i+1)
+		}
+		if right, bottom := elementInt(region, tag.RegionLocationMaxX1), elementInt(region, tag.RegionLocationMaxY1); right >= columns || bottom >= rows {
+			t.Errorf("region %d ends at (%d, %d), outside the %dx%d image", i+1, right, bottom, columns, rows)
+		}
+		delta, err := region.FindElementByTag(tag.PhysicalDeltaY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := delta.Value.GetValue().([]float64)[0]; math.Abs(got-spacing/10) > 1e-6 {
+			t.Errorf("region %d: PhysicalDeltaY = %g cm, want PixelSpacing %g mm", i+1, got, spacing)
+		}
+	}
+}
+
+func TestMRTechnique(t *testing.T) {
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  3,
+		TotalSize:  "2MB",
+		OutputDir:  t.TempDir(),
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.MR,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	ds, err := dicom.ParseFile(files[0].Path, nil, dicom.SkipPixelData())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for _, tg := range []tag.Tag{tag.ReceiveCoilName, tag.TransmitCoilName, tag.InPlanePhaseEncodingDirection, tag.ParallelAcquisition} {
+		if elementString(ds, tg) == "" {
+			t.Errorf("%v should be set", tg)
+		}
+	}
+	elem, err := ds.FindElementByTag(tag.AcquisitionMatrix)
+	if err != nil {
+		t.Fatal("AcquisitionMatrix is missing")
+	}
+	matrix := elem.Value.GetValue().([]int)
+	rows, columns := elementInt(ds, tag.Rows), elementInt(ds, tag.Columns)
+	if len(matrix) != 4 || matrix[0]+matrix[1] == 0 || matrix[0] > columns || matrix[1] > rows {
+		t.Errorf("AcquisitionMatrix = %v for a %dx%d image", matrix, columns, rows)
+	}
+}
+
+func TestTransferSyntaxMix(t *testing.T) {
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  20,
+		TotalSize:  "2MB",
+		OutputDir:  t.TempDir(),
+		Seed:       42,
+		NumStudies: 1,
+		Modality:   modalities.CT,
+		TransferSyntaxMix: []internaldicom.TransferSyntaxShare{
+			{UID: internaldicom.TransferSyntaxJPEGBaseline, Weight: 30},
+			{UID: internaldicom.TransferSyntaxExplicitVRLittleEndian, Weight: 70},
+		},
+		Quiet: true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	series := make(map[string]bool)
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		ts := elementString(ds, tag.TransferSyntaxUID)
+		counts[ts]++
+		series[elementString(ds, tag.SeriesInstanceUID)] = true
+
+		// Each instance is consistent with its own transfer syntax
+		elem, err := ds.FindElementByTag(tag.PixelData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encapsulated := dicom.MustGetPixelDataInfo(elem.Value).IsEncapsulated; encapsulated != (ts == internaldicom.TransferSyntaxJPEGBaseline) {
+			t.Errorf("%s: encapsulated pixel data %v in %s", f.Path, encapsulated, ts)
+		}
+	}
+	if len(series) != 1 {
+		t.Fatalf("got %d series, want 1", len(series))
+	}
+	if counts[internaldicom.TransferSyntaxJPEGBaseline] == 0 || counts[internaldicom.TransferSyntaxExplicitVRLittleEndian] == 0 {
+		t.Errorf("transfer syntaxes %v, want both in the series", counts)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(opts.OutputDir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+}
+
+func TestPatientAndTablePosition(t *testing.T) {
+	opts := internaldicom.GeneratorOptions{
+		NumImages:  12,
+		TotalSize:  "2MB",
+		OutputDir:  t.TempDir(),
+		Seed:       42,
+		NumStudies: 3,
+		Modality:   modalities.CT,
+		Quiet:      true,
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	positions := make(map[string]string) // study -> PatientPosition
+	origins := make(map[string]float64)  // study -> table position at z = 0
+	var slices int
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		study := elementString(ds, tag.StudyInstanceUID)
+		position := elementString(ds, tag.PatientPosition)
+		if position == "" {
+			t.Fatalf("%s: PatientPosition not set", f.Path)
+		}
+		if prev, ok := positions[study]; ok && prev != position {
+			t.Errorf("%s: PatientPosition %s, %s in the same study", f.Path, position, prev)
+		}
+		positions[study] = position
+		if elementString(ds, tag.TableHeight) == "" {
+			t.Errorf("%s: TableHeight not set", f.Path)
+		}
+
+		elem, err := ds.FindElementByTag(tag.TablePosition)
+		if err != nil {
+			continue
+		}
+		slices++
+		table := elem.Value.GetValue().([]float64)[0]
+		ipp, err := ds.FindElementByTag(tag.ImagePositionPatient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		z, err := strconv.ParseFloat(ipp.Value.GetValue().([]string)[2], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		origin := table + z
+		if strings.HasPrefix(position, "FF") {
+			origin = table - z
+		}
+		if prev, ok := origins[study]; ok && math.Abs(prev-origin) > 0.01 {
+			t.Errorf("%s: table position %v at z=%v does not follow the slices of its study", f.Path, table, z)
+		}
+		origins[study] = origin
+	}
+	if slices == 0 {
+		t.Error("no slice with a TablePosition")
+	}
+}
+
+func TestCorruption_CharsetMismatch(t *testing.T) {
+	opts := internaldicom.GeneratorOptions{
+		NumImages:   12,
+		TotalSize:   "1MB",
+		OutputDir:   t.TempDir(),
+		Seed:        42,
+		NumStudies:  1,
+		NumPatients: 1,
+		Quiet:       true,
+		CorruptionConfig: corruption.Config{
+			Types: []corruption.CorruptionType{corruption.CharsetMismatch},
+		},
+	}
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	// Decoded with the declared character set, names come out garbled: UTF-8
+	// read as Latin-1 or Latin-2, Latin-1 read as UTF-8
+	garbled := map[string]string{"ISO_IR 100": "Ã", "ISO_IR 101": "Ă", "ISO_IR 192": "�"}
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil, dicom.SkipPixelData())
+		if err != nil {
+			t.Fatalf("parse %s: %v", f.Path, err)
+		}
+		charset := elementString(ds, tag.SpecificCharacterSet)
+		marker, ok := garbled[charset]
+		if !ok {
+			t.Fatalf("%s: unexpected SpecificCharacterSet %q", f.Path, charset)
+		}
+		if name := elementString(ds, tag.PatientName); !strings.Contains(name, marker) {
+			t.Errorf("%s: PatientName %q declared %s should not decode cleanly", f.Path, name, charset)
+		}
+	}
+}
+
+// TestCorruption_Truncated tests that truncated corruption cuts the images at
+// the requested point, leaving the DICOMDIR readable.
+func TestCorruption_Truncated(t *testing.T) {
+	for _, point := range []string{corruption.TruncateMidPixelData, corruption.TruncateMidHeader, "40%"} {
+		t.Run(point, func(t *testing.T) {
+			truncation, err := corruption.ParseTruncation(point)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := internaldicom.GeneratorOptions{
+				NumImages:   3,
+				TotalSize:   "500KB",
+				OutputDir:   t.TempDir(),
+				Seed:        42,
+				NumStudies:  1,
+				NumPatients: 1,
+				Quiet:       true,
+			}
+			intact, err := internaldicom.GenerateDICOMSeries(opts)
+			if err != nil {
+				t.Fatalf("GenerateDICOMSeries failed: %v", err)
+			}
+			sizes := make([]int64, len(intact))
+			for i, f := range intact {
+				info, err := os.Stat(f.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sizes[i] = info.Size()
+			}
+
+			// Regenerated in place, so that the files only differ by the cut
+			opts.CorruptionConfig = corruption.Config{
+				Types:      []corruption.CorruptionType{corruption.Truncated},
+				Truncation: truncation,
+			}
+			files, err := internaldicom.GenerateDICOMSeries(opts)
+			if err != nil {
+				t.Fatalf("GenerateDICOMSeries with truncated failed: %v", err)
+			}
+			for i, f := range files {
+				data, err := os.ReadFile(f.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				pixelData := bytes.Index(data, []byte{0xE0, 0x7F, 0x10, 0x00, 'O', 'W'})
+				switch point {
+				case corruption.TruncateMidPixelData:
+					// Header intact, half of the pixel data value
+					if value := int64(pixelData + 12); pixelData < 0 || int64(len(data)) != value+(sizes[i]-value)/2 {
+						t.Errorf("%s: %d bytes of %d, PixelData at %d, want half of the pixel data", f.Path, len(data), sizes[i], pixelData)
+					}
+				case corruption.TruncateMidHeader:
+					if pixelData >= 0 || len(data) <= 132 {
+						t.Errorf("%s: %d bytes, PixelData at %d, want a cut before the pixel data", f.Path, len(data), pixelData)
+					}
+				default:
+					if want := sizes[i] * 40 / 100; int64(len(data)) != want {
+						t.Errorf("%s: %d bytes, want %d", f.Path, len(data), want)
+					}
+				}
+			}
+
+			if err := internaldicom.OrganizeFilesIntoDICOMDIR(opts.OutputDir, files, true); err != nil {
+				t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+			}
+			if _, err := dicom.ParseFile(filepath.Join(opts.OutputDir, "DICOMDIR"), nil); err != nil {
+				t.Errorf("parse DICOMDIR: %v", err)
+			}
+		})
+	}
+
+	opts := internaldicom.GeneratorOptions{
+		NumImages:        1,
+		TotalSize:        "500KB",
+		ExactSize:        true,
+		OutputDir:        t.TempDir(),
+		NumStudies:       1,
+		NumPatients:      1,
+		Quiet:            true,
+		CorruptionConfig: corruption.Config{Types: []corruption.CorruptionType{corruption.Truncated}},
+	}
+	if _, err := internaldicom.GenerateDICOMSeries(opts); err == nil {
+		t.Error("GenerateDICOMSeries should reject truncated with exact sizes")
+	}
 }
@@ -71,6 +71,29 @@ func BenchmarkGenerateSeries_Large(b *testing.B) {
 	}
 }
 
+// BenchmarkGenerateSeries_ManySmall benchmarks the per-instance cost of runs
+// of many small images, where allocations dominate
+func BenchmarkGenerateSeries_ManySmall(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		outputDir := b.TempDir()
+
+		opts := internaldicom.GeneratorOptions{
+			NumImages:  200,
+			TotalSize:  "7MB",
+			OutputDir:  outputDir,
+			Seed:       42,
+			NumStudies: 1,
+			Quiet:      true,
+		}
+
+		_, err := internaldicom.GenerateDICOMSeries(opts)
+		if err != nil {
+			b.Fatalf("GenerateDICOMSeries failed: %v", err)
+		}
+	}
+}
+
 // BenchmarkCalculateDimensions benchmarks dimension calculation
 func BenchmarkCalculateDimensions(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,10 @@
+// Package pipeline checks the whole generation pipeline end to end: a small
+// archive is generated, organized under a DICOMDIR, parsed back file by file
+// and, when an Orthanc server is available, stored into it and looked up by
+// UID. Its tests only build with the integration tag:
+//
+//	go test -tags integration ./tests/pipeline/
+//
+// ORTHANC_URL (e.g. http://localhost:8042) round-trips the archive through a
+// running Orthanc; ORTHANC_DOCKER=1 starts a throwaway one with docker.
+package pipeline
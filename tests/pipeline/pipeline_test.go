@@ -0,0 +1,315 @@
+//go:build integration
+
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/dicom/modalities"
+	"github.com/mrsinham/dicomforge/internal/util"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// orthancImage is the Orthanc image started by ORTHANC_DOCKER=1
+const orthancImage = "orthancteam/orthanc"
+
+// instance is what the archive must hold for each generated file
+type instance struct {
+	patientID, studyUID, seriesUID, sopInstanceUID string
+}
+
+// archive is the generated archive under test
+type archive struct {
+	dir       string
+	instances map[string]instance // By SOP Instance UID
+}
+
+// studies returns the number of instances of each study
+func (a archive) studies() map[string]int {
+	counts := make(map[string]int)
+	for _, inst := range a.instances {
+		counts[inst.studyUID]++
+	}
+	return counts
+}
+
+// generateArchive generates a small CT archive of several patients, studies
+// and series, with derived objects, and organizes it under a DICOMDIR.
+func generateArchive(t *testing.T) archive {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := internaldicom.GenerateDICOMSeries(internaldicom.GeneratorOptions{
+		NumImages:      24,
+		TotalSize:      "3MB",
+		OutputDir:      dir,
+		Seed:           42,
+		NumStudies:     3,
+		NumPatients:    2,
+		SeriesPerStudy: util.SeriesRange{Min: 2, Max: 2},
+		Modality:       modalities.CT,
+		TextSR:         true,
+		GSPS:           true,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+	if err := internaldicom.OrganizeFilesIntoDICOMDIR(dir, files, true); err != nil {
+		t.Fatalf("OrganizeFilesIntoDICOMDIR failed: %v", err)
+	}
+
+	a := archive{dir: dir, instances: make(map[string]instance)}
+	for _, f := range files {
+		if _, dup := a.instances[f.SOPInstanceUID]; dup {
+			t.Fatalf("SOP Instance UID %s generated twice", f.SOPInstanceUID)
+		}
+		a.instances[f.SOPInstanceUID] = instance{f.PatientID, f.StudyUID, f.SeriesUID, f.SOPInstanceUID}
+	}
+	return a
+}
+
+// archiveFiles returns the files of the PT*/ST*/SE* hierarchy of dir
+func archiveFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasPrefix(d.Name(), "IM") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func stringValue(t *testing.T, ds dicom.Dataset, tg tag.Tag) string {
+	t.Helper()
+	elem, err := ds.FindElementByTag(tg)
+	if err != nil {
+		t.Fatalf("%v: %v", tg, err)
+	}
+	values, ok := elem.Value.GetValue().([]string)
+	if !ok || len(values) == 0 {
+		t.Fatalf("%v has no string value", tg)
+	}
+	return values[0]
+}
+
+func TestPipeline(t *testing.T) {
+	a := generateArchive(t)
+
+	t.Run("files", func(t *testing.T) {
+		paths := archiveFiles(t, a.dir)
+		if len(paths) != len(a.instances) {
+			t.Fatalf("archive holds %d files, want %d", len(paths), len(a.instances))
+		}
+		seen := make(map[string]bool)
+		for _, path := range paths {
+			ds, err := dicom.ParseFile(path, nil)
+			if err != nil {
+				t.Fatalf("parse %s: %v", path, err)
+			}
+			uid := stringValue(t, ds, tag.SOPInstanceUID)
+			want, ok := a.instances[uid]
+			if !ok || seen[uid] {
+				t.Errorf("%s: unexpected SOP Instance UID %s", path, uid)
+				continue
+			}
+			seen[uid] = true
+			got := instance{
+				patientID:      stringValue(t, ds, tag.PatientID),
+				studyUID:       stringValue(t, ds, tag.StudyInstanceUID),
+				seriesUID:      stringValue(t, ds, tag.SeriesInstanceUID),
+				sopInstanceUID: uid,
+			}
+			if got != want {
+				t.Errorf("%s: file has %+v, generated %+v", path, got, want)
+			}
+			if meta, err := ds.FindElementByTag(tag.MediaStorageSOPInstanceUID); err == nil && meta.Value.GetValue().([]string)[0] != uid {
+				t.Errorf("%s: MediaStorageSOPInstanceUID %v, SOPInstanceUID %s", path, meta.Value, uid)
+			}
+		}
+	})
+
+	t.Run("dicomdir", func(t *testing.T) {
+		ds, err := dicom.ParseFile(filepath.Join(a.dir, "DICOMDIR"), nil)
+		if err != nil {
+			t.Fatalf("parse DICOMDIR: %v", err)
+		}
+		sequence, err := ds.FindElementByTag(tag.DirectoryRecordSequence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := make(map[string]int)
+		referenced := make(map[string]bool)
+		for _, item := range sequence.Value.GetValue().([]*dicom.SequenceItemValue) {
+			record := dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+			recordType := stringValue(t, record, tag.DirectoryRecordType)
+			switch recordType {
+			case "PATIENT", "STUDY", "SERIES":
+				counts[recordType]++
+				continue
+			}
+			// IMAGE, SR DOCUMENT, PRESENTATION... records of the instances
+			counts["instance"]++
+			uid := stringValue(t, record, tag.ReferencedSOPInstanceUIDInFile)
+			fileID, err := record.FindElementByTag(tag.ReferencedFileID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(append([]string{a.dir}, fileID.Value.GetValue().([]string)...)...)
+			file, err := dicom.ParseFile(path, nil, dicom.SkipPixelData())
+			if err != nil {
+				t.Fatalf("parse %s: %v", path, err)
+			}
+			if got := stringValue(t, file, tag.SOPInstanceUID); got != uid {
+				t.Errorf("%s: record references %s, file is %s", path, uid, got)
+			}
+			referenced[uid] = true
+		}
+
+		studies := a.studies()
+		series := make(map[string]bool)
+		patients := make(map[string]bool)
+		for _, inst := range a.instances {
+			series[inst.seriesUID] = true
+			patients[inst.patientID] = true
+		}
+		want := map[string]int{"PATIENT": len(patients), "STUDY": len(studies), "SERIES": len(series), "instance": len(a.instances)}
+		for recordType, n := range want {
+			if counts[recordType] != n {
+				t.Errorf("DICOMDIR has %d %s records, want %d", counts[recordType], recordType, n)
+			}
+		}
+		for uid := range a.instances {
+			if !referenced[uid] {
+				t.Errorf("DICOMDIR does not reference %s", uid)
+			}
+		}
+	})
+
+	t.Run("orthanc", func(t *testing.T) {
+		server := orthancURL(t)
+		for _, path := range archiveFiles(t, a.dir) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored struct{ Status string }
+			orthancRequest(t, http.MethodPost, server+"/instances", data, &stored)
+			if stored.Status != "Success" && stored.Status != "AlreadyStored" {
+				t.Fatalf("store %s: status %q", path, stored.Status)
+			}
+		}
+
+		for studyUID, n := range a.studies() {
+			id := orthancLookup(t, server, studyUID, "Study")
+			var instances []json.RawMessage
+			orthancRequest(t, http.MethodGet, server+"/studies/"+id+"/instances", nil, &instances)
+			if len(instances) != n {
+				t.Errorf("Orthanc has %d instances in study %s, want %d", len(instances), studyUID, n)
+			}
+		}
+		for uid, inst := range a.instances {
+			id := orthancLookup(t, server, uid, "Instance")
+			var tags struct{ PatientID, StudyInstanceUID, SeriesInstanceUID string }
+			orthancRequest(t, http.MethodGet, server+"/instances/"+id+"/simplified-tags", nil, &tags)
+			if tags.StudyInstanceUID != inst.studyUID || tags.SeriesInstanceUID != inst.seriesUID || tags.PatientID != inst.patientID {
+				t.Errorf("Orthanc has %s in patient %s, study %s, series %s, want %+v",
+					uid, tags.PatientID, tags.StudyInstanceUID, tags.SeriesInstanceUID, inst)
+			}
+		}
+	})
+}
+
+// orthancURL returns the URL of the Orthanc server of ORTHANC_URL, or of a
+// container started for the test with ORTHANC_DOCKER=1, and skips the test
+// without either.
+func orthancURL(t *testing.T) string {
+	t.Helper()
+	if url := os.Getenv("ORTHANC_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	if os.Getenv("ORTHANC_DOCKER") != "1" {
+		t.Skip("set ORTHANC_URL or ORTHANC_DOCKER=1 to round-trip through Orthanc")
+	}
+
+	out, err := exec.Command("docker", "run", "--rm", "-d", "-p", "127.0.0.1::8042",
+		"-e", "ORTHANC__AUTHENTICATION_ENABLED=false", orthancImage).Output()
+	if err != nil {
+		t.Fatalf("start Orthanc: %v", err)
+	}
+	container := strings.TrimSpace(string(out))
+	t.Cleanup(func() { _ = exec.Command("docker", "stop", container).Run() })
+
+	out, err = exec.Command("docker", "port", container, "8042").Output()
+	if err != nil {
+		t.Fatalf("Orthanc port: %v", err)
+	}
+	url := "http://" + strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+
+	// Wait for the REST API
+	for deadline := time.Now().Add(60 * time.Second); ; time.Sleep(500 * time.Millisecond) {
+		resp, err := http.Get(url + "/system")
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return url
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Orthanc at %s not ready: %v", url, err)
+		}
+	}
+}
+
+// orthancRequest sends body to url and decodes the JSON response into v
+func orthancRequest(t *testing.T, method, url string, body []byte, v any) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: %s", method, url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+}
+
+// orthancLookup returns the Orthanc identifier of the resource of uid
+func orthancLookup(t *testing.T, server, uid, resourceType string) string {
+	t.Helper()
+	var matches []struct{ ID, Type string }
+	orthancRequest(t, http.MethodPost, server+"/tools/lookup", []byte(uid), &matches)
+	for _, m := range matches {
+		if m.Type == resourceType {
+			return m.ID
+		}
+	}
+	t.Fatalf("Orthanc has no %s %s", resourceType, uid)
+	return ""
+}
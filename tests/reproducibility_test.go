@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
@@ -325,3 +326,47 @@ func TestReproducibility_StudyUIDs(t *testing.T) {
 
 	t.Logf("✓ All Study UIDs are valid DICOM UIDs")
 }
+
+// TestReproducibility_Workers tests that the number of parallel workers does
+// not change the generated files
+func TestReproducibility_Workers(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "series")
+	generate := func(workers int) map[string][]byte {
+		opts := internaldicom.GeneratorOptions{
+			NumImages:  12,
+			TotalSize:  "2MB",
+			OutputDir:  outputDir,
+			Seed:       42,
+			NumStudies: 2,
+			Workers:    workers,
+			Quiet:      true,
+		}
+		files, err := internaldicom.GenerateDICOMSeries(opts)
+		if err != nil {
+			t.Fatalf("Generation with %d workers failed: %v", workers, err)
+		}
+		contents := make(map[string][]byte, len(files))
+		for _, f := range files {
+			data, err := os.ReadFile(f.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents[f.Path] = data
+		}
+		if err := os.RemoveAll(outputDir); err != nil {
+			t.Fatal(err)
+		}
+		return contents
+	}
+
+	sequential := generate(1)
+	parallel := generate(4)
+	if len(parallel) != len(sequential) {
+		t.Fatalf("Generated %d files with 4 workers, %d with 1", len(parallel), len(sequential))
+	}
+	for path, data := range sequential {
+		if !bytes.Equal(parallel[path], data) {
+			t.Errorf("%s differs between 1 and 4 workers", path)
+		}
+	}
+}
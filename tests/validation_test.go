@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	internaldicom "github.com/mrsinham/dicomforge/internal/dicom"
+	"github.com/mrsinham/dicomforge/internal/util"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
@@ -400,6 +401,170 @@ func TestValidation_UIDUniqueness(t *testing.T) {
 	t.Logf("✓ UID uniqueness validation passed")
 }
 
+// TestValidation_LocalizerReference tests that axial series reference an
+// orthogonal image of the same study for scout reference lines
+func TestValidation_LocalizerReference(t *testing.T) {
+	outputDir := t.TempDir()
+
+	seriesPerStudy, err := util.ParseSeriesRange("6")
+	if err != nil {
+		t.Fatalf("ParseSeriesRange failed: %v", err)
+	}
+
+	opts := internaldicom.GeneratorOptions{
+		NumImages:      18,
+		TotalSize:      "2MB",
+		OutputDir:      outputDir,
+		Seed:           42,
+		NumStudies:     1,
+		Modality:       "MR",
+		BodyPart:       "HEAD",
+		SeriesPerStudy: seriesPerStudy,
+		Quiet:          true,
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	orientations := make(map[string]string) // SOPInstanceUID -> IOP
+	references := make(map[string]string)   // SOPInstanceUID -> referenced UID
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Path, err)
+		}
+		iop := findElementByTag(ds, tag.ImageOrientationPatient)
+		if iop == nil {
+			t.Fatalf("%s: missing ImageOrientationPatient", f.Path)
+		}
+		orientations[f.SOPInstanceUID] = iop.Value.String()
+
+		if ref := findElementByTag(ds, tag.ReferencedImageSequence); ref != nil {
+			items := ref.Value.GetValue().([]*dicom.SequenceItemValue)
+			for _, elem := range items[0].GetValue().([]*dicom.Element) {
+				if elem.Tag == tag.ReferencedSOPInstanceUID {
+					references[f.SOPInstanceUID] = elem.Value.GetValue().([]string)[0]
+				}
+			}
+		}
+	}
+
+	axial := "[1 0 0 0 1 0]"
+	for uid, iop := range orientations {
+		ref, hasRef := references[uid]
+		if iop != axial {
+			if hasRef {
+				t.Errorf("non-axial image %s should not reference a localizer", uid)
+			}
+			continue
+		}
+		if !hasRef {
+			t.Errorf("axial image %s is missing ReferencedImageSequence", uid)
+			continue
+		}
+		refIOP, ok := orientations[ref]
+		if !ok {
+			t.Errorf("axial image %s references unknown instance %s", uid, ref)
+		} else if refIOP == axial {
+			t.Errorf("axial image %s references another axial image", uid)
+		}
+	}
+}
+
+// TestValidation_DateTimeOrdering checks that every image has valid DA/TM
+// values with StudyDate/Time <= SeriesDate/Time <= AcquisitionDate/Time.
+func TestValidation_DateTimeOrdering(t *testing.T) {
+	seriesPerStudy, err := util.ParseSeriesRange("3")
+	if err != nil {
+		t.Fatalf("ParseSeriesRange failed: %v", err)
+	}
+	opts := internaldicom.GeneratorOptions{
+		NumImages:         18,
+		TotalSize:         "1MB",
+		OutputDir:         t.TempDir(),
+		Seed:              42,
+		NumStudies:        2,
+		Modality:          "CT",
+		SeriesPerStudy:    seriesPerStudy,
+		FractionalSeconds: true,
+		Quiet:             true,
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	dateTime := func(ds dicom.Dataset, daTag, tmTag tag.Tag) string {
+		da := findElementByTag(ds, daTag).Value.GetValue().([]string)[0]
+		tm := findElementByTag(ds, tmTag).Value.GetValue().([]string)[0]
+		parsed, err := util.CombineDATM(da, tm)
+		if err != nil {
+			t.Fatalf("invalid date/time %s %s: %v", da, tm, err)
+		}
+		if !strings.Contains(tm, ".") {
+			t.Errorf("time %s should have fractional seconds", tm)
+		}
+		return parsed.Format("20060102150405.000000")
+	}
+
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Path, err)
+		}
+		study := dateTime(ds, tag.StudyDate, tag.StudyTime)
+		series := dateTime(ds, tag.SeriesDate, tag.SeriesTime)
+		acquisition := dateTime(ds, tag.AcquisitionDate, tag.AcquisitionTime)
+		if study > series || series > acquisition {
+			t.Errorf("%s: expected study %s <= series %s <= acquisition %s",
+				filepath.Base(f.Path), study, series, acquisition)
+		}
+	}
+}
+
+// TestValidation_PatientAge checks that PatientAge matches the birth date and
+// the study date of every image.
+func TestValidation_PatientAge(t *testing.T) {
+	opts := internaldicom.GeneratorOptions{
+		NumImages:   6,
+		TotalSize:   "500KB",
+		OutputDir:   t.TempDir(),
+		Seed:        7,
+		NumStudies:  3,
+		NumPatients: 2,
+		Quiet:       true,
+	}
+
+	files, err := internaldicom.GenerateDICOMSeries(opts)
+	if err != nil {
+		t.Fatalf("GenerateDICOMSeries failed: %v", err)
+	}
+
+	for _, f := range files {
+		ds, err := dicom.ParseFile(f.Path, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", f.Path, err)
+		}
+		birthDate := findElementByTag(ds, tag.PatientBirthDate).Value.GetValue().([]string)[0]
+		studyDate := findElementByTag(ds, tag.StudyDate).Value.GetValue().([]string)[0]
+		want, err := util.PatientAge(birthDate, studyDate)
+		if err != nil {
+			t.Fatalf("PatientAge(%s, %s) failed: %v", birthDate, studyDate, err)
+		}
+
+		age := findElementByTag(ds, tag.PatientAge)
+		if age == nil {
+			t.Fatalf("%s: missing PatientAge", filepath.Base(f.Path))
+		}
+		if got := age.Value.GetValue().([]string)[0]; got != want {
+			t.Errorf("%s: PatientAge = %s, want %s", filepath.Base(f.Path), got, want)
+		}
+	}
+}
+
 // Helper function to pad integers
 func padInt(n, width int) string {
 	return fmt.Sprintf("%0*d", width, n)